@@ -0,0 +1,1620 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/udemy/docu-jarvis-cli/internal/agent"
+	"github.com/udemy/docu-jarvis-cli/internal/baseline"
+	"github.com/udemy/docu-jarvis-cli/internal/ci"
+	"github.com/udemy/docu-jarvis-cli/internal/dates"
+	"github.com/udemy/docu-jarvis-cli/internal/git"
+	"github.com/udemy/docu-jarvis-cli/internal/help"
+	"github.com/udemy/docu-jarvis-cli/internal/logging"
+	"github.com/udemy/docu-jarvis-cli/internal/netguard"
+	"github.com/udemy/docu-jarvis-cli/internal/settings"
+	"github.com/udemy/docu-jarvis-cli/internal/site"
+)
+
+type command struct {
+	name         string
+	aliases      []string
+	checkUpdates bool
+	help         func()
+	run          func(ctx context.Context, args []string) error
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{name: "update-docs", checkUpdates: true, help: help.PrintUpdateDocsHelp, run: cmdUpdateDocs},
+		{name: "write-docs", aliases: []string{"write"}, checkUpdates: true, help: help.PrintWriteDocsHelp, run: cmdWriteDocs},
+		{name: "write-tests", checkUpdates: true, help: help.PrintWriteTestsHelp, run: cmdWriteTests},
+		{name: "write-api-docs", checkUpdates: true, help: help.PrintWriteAPIDocsHelp, run: cmdWriteAPIDocs},
+		{name: "audit-docs", aliases: []string{"audit"}, checkUpdates: true, help: help.PrintAuditDocsHelp, run: cmdAuditDocs},
+		{name: "docs-gap", aliases: []string{"gap"}, checkUpdates: true, help: help.PrintDocsGapHelp, run: cmdDocsGap},
+		{name: "lint-docs", aliases: []string{"lint"}, checkUpdates: true, help: help.PrintLintDocsHelp, run: cmdLintDocs},
+		{name: "archive-docs", aliases: []string{"archive"}, help: help.PrintArchiveDocsHelp, run: cmdArchiveDocs},
+		{name: "check-assets", aliases: []string{"assets"}, help: help.PrintCheckAssetsHelp, run: cmdCheckAssets},
+		{name: "stale-docs", aliases: []string{"stale"}, help: help.PrintStaleDocsHelp, run: cmdStaleDocs},
+		{name: "export-site", aliases: []string{"site"}, help: help.PrintExportSiteHelp, run: cmdExportSite},
+		{name: "debug", checkUpdates: true, help: help.PrintDebugHelp, run: cmdDebug},
+		{name: "explain", checkUpdates: true, help: help.PrintExplainHelp, run: cmdExplain},
+		{name: "check-staging", aliases: []string{"check", "staging"}, checkUpdates: true, help: help.PrintCheckStagingHelp, run: cmdCheckStaging},
+		{name: "review-checklist", aliases: []string{"checklist"}, checkUpdates: true, help: help.PrintReviewChecklistHelp, run: cmdReviewChecklist},
+		{name: "review-pr", checkUpdates: true, help: help.PrintReviewPRHelp, run: cmdReviewPR},
+		{name: "triage", checkUpdates: true, help: help.PrintTriageHelp, run: cmdTriage},
+		{name: "check-commits", aliases: []string{"commits"}, checkUpdates: true, help: help.PrintCheckCommitsHelp, run: cmdCheckCommits},
+		{name: "squash-summary", aliases: []string{"squash"}, checkUpdates: true, help: help.PrintSquashSummaryHelp, run: cmdSquashSummary},
+		{name: "changelog", aliases: []string{"release-notes"}, checkUpdates: true, help: help.PrintChangelogHelp, run: cmdChangelog},
+		{name: "eval", help: help.PrintEvalHelp, run: cmdEval},
+		{name: "serve", aliases: []string{"server"}, help: help.PrintServeHelp, run: cmdServe},
+		{name: "schedule", help: help.PrintScheduleHelp, run: cmdSchedule},
+		{name: "daemon", help: help.PrintDaemonHelp, run: cmdDaemon},
+		{name: "config", help: help.PrintConfigHelp, run: cmdConfig},
+		{name: "auth", help: help.PrintAuthHelp, run: cmdAuth},
+		{name: "runs", help: help.PrintRunsHelp, run: cmdRuns},
+		{name: "cache", help: help.PrintCacheHelp, run: cmdCache},
+		{name: "conversations", aliases: []string{"convos"}, help: help.PrintConversationsHelp, run: cmdConversations},
+		{name: "rollback-run", aliases: []string{"rollback"}, help: help.PrintRollbackRunHelp, run: cmdRollbackRun},
+		{name: "purge", help: help.PrintPurgeHelp, run: cmdPurge},
+		{name: "usage", aliases: []string{"cost"}, help: help.PrintUsageCommandHelp, run: cmdUsage},
+		{name: "logs", help: help.PrintLogsHelp, run: cmdLogs},
+		{name: "version", help: help.PrintVersionHelp, run: cmdVersion},
+		{name: "update", help: help.PrintUpdateHelp, run: cmdUpdate},
+		{name: "help", help: help.PrintUsage, run: cmdHelp},
+	}
+}
+
+func findCommand(name string) *command {
+	name = strings.ToLower(name)
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+		for _, alias := range cmd.aliases {
+			if alias == name {
+				return cmd
+			}
+		}
+	}
+	return nil
+}
+
+// newFlagSet returns a quiet flag set; parse errors and -help are reported
+// through handleParseError instead.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+	return fs
+}
+
+// parseArgs parses flags that may appear before, between, or after positional
+// arguments and returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+
+		// Everything after a "--" terminator is positional
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+func addScopeFlag(fs *flag.FlagSet) *string {
+	return fs.String("scope", "", "Restrict the run to a directory within the repository (e.g. services/payments)")
+}
+
+func addBranchFlag(fs *flag.FlagSet) *string {
+	return fs.String("branch", "", "Branch to clone and analyze, and the base for PRs (default: config, then the default branch)")
+}
+
+func addRepoFlag(fs *flag.FlagSet) *string {
+	return fs.String("repo", "", "Configured repository to use, by name or URL (default: the first repo in the config)")
+}
+
+func addDocsDirFlag(fs *flag.FlagSet) *string {
+	return fs.String("docs-dir", "", "Documentation directories relative to the repository root, comma-separated (overrides docs_roots)")
+}
+
+func addConfirmEditsFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("confirm-edits", false, "Ask before each file edit; edits outside the docs directories are denied")
+}
+
+func addAutoApproveFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("auto-approve", false, "Open the PR with every change instead of asking to accept, reject, or edit each changed doc (for CI)")
+}
+
+// prFlags override the PR metadata of the config for the commands that open
+// a PR.
+type prFlags struct {
+	title, body, base, labels, assignees, reviewers *string
+	split                                           *string
+	draft, suggestReviewers, digestComments, yes    *bool
+}
+
+func addPRFlags(fs *flag.FlagSet) *prFlags {
+	return &prFlags{
+		title:     fs.String("pr-title", "", "PR title template (overrides pr_title)"),
+		body:      fs.String("pr-body", "", "PR body template, {summary} is the run summary (overrides pr_body)"),
+		base:      fs.String("pr-base", "", "Branch the PR targets (overrides -branch and base_branch)"),
+		labels:    fs.String("pr-labels", "", "PR labels, comma-separated (overrides pr_labels)"),
+		assignees: fs.String("pr-assignees", "", "PR assignees, comma-separated (overrides pr_assignees)"),
+		reviewers: fs.String("pr-reviewers", "", "PR reviewers, comma-separated, GitHub teams as org/team (overrides pr_reviewers)"),
+		split:     fs.String("split-prs", "", "Open one PR per docs subdirectory (dir) or per CODEOWNERS owners (codeowners)"),
+		draft:     fs.Bool("draft", false, "Open the PR as a draft"),
+		suggestReviewers: fs.Bool("suggest-reviewers", false,
+			"Request the top recent contributors to the code behind each changed doc as reviewers"),
+		digestComments: fs.Bool("digest-comments", false,
+			"Comment on each changed doc in the PR with what was changed and why"),
+		yes: fs.Bool("yes", false, "Push the PR's branch without asking first"),
+	}
+}
+
+func (f *prFlags) options() (git.PROptions, error) {
+	split, err := git.ParseSplit(*f.split)
+	if err != nil {
+		return git.PROptions{}, err
+	}
+	return git.PROptions{
+		Title:     *f.title,
+		Body:      *f.body,
+		Base:      *f.base,
+		Labels:    parseTopics(*f.labels),
+		Assignees: parseTopics(*f.assignees),
+		Reviewers: parseTopics(*f.reviewers),
+		Draft:     *f.draft,
+		Split:     split,
+
+		SuggestReviewers: *f.suggestReviewers,
+		DigestComments:   *f.digestComments,
+		Yes:              *f.yes,
+	}, nil
+}
+
+func addConcurrencyFlag(fs *flag.FlagSet) *int {
+	return fs.Int("concurrency", 0, "Process at most this many documents or topics at once (default: all)")
+}
+
+func addNoTUIFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("no-tui", false, "Print a line per started and finished item instead of the progress dashboard")
+}
+
+func addOutputFlag(fs *flag.FlagSet) *string {
+	return fs.String("output", "text", "Output format: text, or json for a summary of every file or topic on stdout")
+}
+
+// summaryOutput validates -output. For json, it returns the real stdout for
+// the summary and sends progress messages to stderr until restore is called.
+func summaryOutput(output string) (summaryOut io.Writer, restore func(), err error) {
+	switch output {
+	case "text":
+		return nil, func() {}, nil
+	case "json":
+		stdout := os.Stdout
+		os.Stdout = os.Stderr
+		return stdout, func() { os.Stdout = stdout }, nil
+	}
+	return nil, nil, fmt.Errorf("invalid -output %q (must be text or json)", output)
+}
+
+// handleParseError prints the command help for -help and otherwise points
+// the user at it.
+func handleParseError(fs *flag.FlagSet, err error) error {
+	cmd := findCommand(fs.Name())
+	if errors.Is(err, flag.ErrHelp) {
+		if cmd != nil {
+			cmd.help()
+		} else {
+			help.PrintUsage()
+		}
+		return nil
+	}
+	if cmd == nil || cmd.name == "help" {
+		return fmt.Errorf("%v (see 'docu-jarvis help')", err)
+	}
+	return fmt.Errorf("%v (see 'docu-jarvis help %s')", err, cmd.name)
+}
+
+func cmdUpdateDocs(ctx context.Context, args []string) error {
+	fs := newFlagSet("update-docs")
+	scope := addScopeFlag(fs)
+	branch := addBranchFlag(fs)
+	repoSel := addRepoFlag(fs)
+	allRepos := fs.Bool("all-repos", false, "Update docs in every configured repository, with a PR for each")
+	customPrompt := fs.String("custom", "", "Custom prompt for updating documentation")
+	localPath := fs.String("local", "", "Use an existing local checkout instead of cloning")
+	dryRun := fs.Bool("dry-run", false, "Show proposed changes without writing files or creating a PR")
+	docsDir := addDocsDirFlag(fs)
+	confirmEdits := addConfirmEditsFlag(fs)
+	autoApprove := addAutoApproveFlag(fs)
+	resume := fs.String("resume", "", "Retry the failed and pending documents of a previous run (see 'runs list')")
+	since := fs.String("since", "", "With 'changed': the commit, branch, tag, or date (e.g. 2024-06-01 or \"2 weeks ago\") to look for code changes since")
+	learnFromPR := fs.String("learn-from-pr", "", "Learn rules for later runs from the review comments on a past docu-jarvis PR (number or URL)")
+	compare := fs.Bool("compare", false, "Update one doc with two prompts or models and compare the results; nothing is kept")
+	promptA := fs.String("prompt-a", "", "With -compare: prompt of variant A, a file or an embedded prompt's name (default: the update prompt)")
+	promptB := fs.String("prompt-b", "", "With -compare: prompt of variant B, a file or an embedded prompt's name (default: the update prompt)")
+	modelA := fs.String("model-a", "", "With -compare: model of variant A (default: the configured model)")
+	modelB := fs.String("model-b", "", "With -compare: model of variant B (default: the configured model)")
+	compareFile := fs.String("file", "", "With -compare: the doc both variants update")
+	canary := fs.String("canary", "", "Update a fraction (e.g. 0.1 or 10%) or list of the docs first, and compare them with the previous run before the rest")
+	noCache := fs.Bool("no-cache", false, "Update every doc, even those whose content and sources are unchanged since their last update")
+	concurrency := addConcurrencyFlag(fs)
+	order := fs.String("order", agent.OrderGiven, "Order to process documents in: given, smallest, or stale")
+	noTUI := addNoTUIFlag(fs)
+	pr := addPRFlags(fs)
+	output := addOutputFlag(fs)
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+
+	if *dryRun && *confirmEdits {
+		return fmt.Errorf("-confirm-edits cannot be used with -dry-run, which makes no edits")
+	}
+	if *dryRun && *autoApprove {
+		return fmt.Errorf("-auto-approve cannot be used with -dry-run, which creates no PR")
+	}
+	if *learnFromPR != "" {
+		var conflicting []string
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "learn-from-pr", "dry-run", "local", "repo", "scope", "branch":
+			default:
+				conflicting = append(conflicting, "-"+f.Name)
+			}
+		})
+		if len(conflicting) > 0 {
+			return fmt.Errorf("-learn-from-pr cannot be combined with %s", strings.Join(conflicting, ", "))
+		}
+		if len(positional) > 0 {
+			return fmt.Errorf("-learn-from-pr takes no files; it only reads the PR's reviews")
+		}
+		repo, folder, err := prepareRepo(*localPath, *repoSel, *scope, *branch)
+		if err != nil {
+			return err
+		}
+		return runLearnMode(ctx, folder, repo, *learnFromPR, *dryRun)
+	}
+	if *compare {
+		var conflicting []string
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "compare", "prompt-a", "prompt-b", "model-a", "model-b", "file", "local", "repo", "scope", "branch", "docs-dir":
+			default:
+				conflicting = append(conflicting, "-"+f.Name)
+			}
+		})
+		if len(conflicting) > 0 {
+			return fmt.Errorf("-compare cannot be combined with %s", strings.Join(conflicting, ", "))
+		}
+		if *compareFile == "" || len(positional) > 0 {
+			return fmt.Errorf("-compare takes one doc, given with -file")
+		}
+		a, err := loadVariant("A", *promptA, *modelA)
+		if err != nil {
+			return err
+		}
+		b, err := loadVariant("B", *promptB, *modelB)
+		if err != nil {
+			return err
+		}
+		if a.prompt == b.prompt && a.model == b.model {
+			return fmt.Errorf("-compare needs two different variants: set -prompt-a and -prompt-b, or -model-a and -model-b")
+		}
+		repo, folder, err := prepareRepo(*localPath, *repoSel, *scope, *branch)
+		if err != nil {
+			return err
+		}
+		if err := applyDocsDir(repo, *docsDir); err != nil {
+			return err
+		}
+		return runCompareMode(ctx, folder, repo, *compareFile, []promptVariant{a, b})
+	}
+	if *promptA != "" || *promptB != "" || *modelA != "" || *modelB != "" || *compareFile != "" {
+		return fmt.Errorf("-prompt-a, -prompt-b, -model-a, -model-b, and -file only apply to -compare")
+	}
+	if *concurrency < 0 {
+		return fmt.Errorf("-concurrency must not be negative")
+	}
+	batchOrder, err := agent.ParseOrder(*order)
+	if err != nil {
+		return err
+	}
+	batch := agent.BatchOptions{Order: batchOrder, Concurrency: *concurrency, Dashboard: !*noTUI}
+	prOpts, err := pr.options()
+	if err != nil {
+		return err
+	}
+	prOpts.AutoApprove = *autoApprove
+	summaryOut, restoreStdout, err := summaryOutput(*output)
+	if err != nil {
+		return err
+	}
+	defer restoreStdout()
+	if !*dryRun {
+		if err := netguard.Check("opening a pull request"); err != nil {
+			return fmt.Errorf("%w; use -dry-run to preview the changes locally", err)
+		}
+	}
+
+	if *resume != "" {
+		// The run's files and settings are reused as they were
+		var conflicting []string
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "resume", "confirm-edits", "output", "concurrency", "order", "no-tui",
+				"pr-title", "pr-body", "pr-base", "pr-labels", "pr-assignees", "pr-reviewers", "split-prs", "draft", "suggest-reviewers",
+				"auto-approve":
+			default:
+				conflicting = append(conflicting, "-"+f.Name)
+			}
+		})
+		if len(conflicting) > 0 {
+			return fmt.Errorf("-resume cannot be combined with %s", strings.Join(conflicting, ", "))
+		}
+		if len(positional) > 0 {
+			return fmt.Errorf("-resume takes no files; the run's remaining documents are updated")
+		}
+		return runResumeMode(ctx, *resume, *confirmEdits, prOpts, batch, summaryOut)
+	}
+
+	if len(positional) == 0 {
+		help.PrintUpdateDocsHelp()
+		return fmt.Errorf("no files specified - use 'all' or specify file names")
+	}
+
+	files := parseTopics(strings.Join(positional, ","))
+	if len(files) == 0 {
+		help.PrintUpdateDocsHelp()
+		return fmt.Errorf("no files specified - use 'all' or specify file names")
+	}
+	changed := len(files) == 1 && strings.ToLower(files[0]) == "changed"
+	if changed && *since == "" {
+		return fmt.Errorf("update-docs changed requires -since <ref|date>")
+	}
+	if !changed && *since != "" {
+		return fmt.Errorf("-since only applies to 'update-docs changed'")
+	}
+	if *canary != "" {
+		if *dryRun {
+			return fmt.Errorf("-canary cannot be used with -dry-run; the canary docs are edited to compare them")
+		}
+		if *allRepos {
+			return fmt.Errorf("-canary cannot be used with -all-repos; try the new configuration on one repository")
+		}
+		if len(files) == 1 {
+			if selector := strings.ToLower(files[0]); selector == "changed" || selector == "queued" {
+				return fmt.Errorf("-canary only applies to 'all' or a list of docs, not '%s'", selector)
+			}
+		}
+	}
+
+	if *allRepos {
+		if *localPath != "" || *repoSel != "" {
+			return fmt.Errorf("-all-repos cannot be used with -local or -repo")
+		}
+		return runUpdateAllRepos(ctx, files, *since, *scope, *branch, *docsDir, *customPrompt, *dryRun, *confirmEdits, *noCache, prOpts, batch, summaryOut)
+	}
+
+	repo, folder, err := prepareRepo(*localPath, *repoSel, *scope, *branch)
+	if err != nil {
+		return err
+	}
+	if err := applyDocsDir(repo, *docsDir); err != nil {
+		return err
+	}
+	repo.SetPROptions(prOpts)
+
+	if *canary != "" {
+		_, err := runUpdateMode(ctx, folder, repo, files, *customPrompt, *canary, false, *confirmEdits, *noCache, nil, batch, summaryOut)
+		return err
+	}
+	return updateDocsIn(ctx, folder, repo, files, *since, *customPrompt, *dryRun, *confirmEdits, *noCache, batch, summaryOut)
+}
+
+// updateDocsIn runs update-docs for the given files, the queued ones, or
+// those covering the code changed since a commit or date.
+func updateDocsIn(ctx context.Context, folder string, repo *git.Repo, files []string, since, customPrompt string, dryRun, confirmEdits, noCache bool, batch agent.BatchOptions, summaryOut io.Writer) error {
+	if len(files) == 1 && strings.ToLower(files[0]) == "queued" {
+		return runQueuedUpdateMode(ctx, folder, repo, customPrompt, dryRun, confirmEdits, noCache, batch, summaryOut)
+	}
+	if len(files) == 1 && strings.ToLower(files[0]) == "changed" {
+		return runChangedUpdateMode(ctx, folder, repo, since, customPrompt, dryRun, confirmEdits, noCache, batch, summaryOut)
+	}
+	_, err := runUpdateMode(ctx, folder, repo, files, customPrompt, "", dryRun, confirmEdits, noCache, nil, batch, summaryOut)
+	return err
+}
+
+func cmdWriteDocs(ctx context.Context, args []string) error {
+	fs := newFlagSet("write-docs")
+	scope := addScopeFlag(fs)
+	branch := addBranchFlag(fs)
+	repoSel := addRepoFlag(fs)
+	localPath := fs.String("local", "", "Use an existing local checkout instead of cloning")
+	dryRun := fs.Bool("dry-run", false, "Show proposed documentation without writing files or creating a PR")
+	docsDir := addDocsDirFlag(fs)
+	confirmEdits := addConfirmEditsFlag(fs)
+	autoApprove := addAutoApproveFlag(fs)
+	concurrency := addConcurrencyFlag(fs)
+	noTUI := addNoTUIFlag(fs)
+	category := fs.String("category", categoryGeneral, "Kind of topic: general, events, webhooks, or queues")
+	pr := addPRFlags(fs)
+	output := addOutputFlag(fs)
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+
+	if *dryRun && *confirmEdits {
+		return fmt.Errorf("-confirm-edits cannot be used with -dry-run, which makes no edits")
+	}
+	if *dryRun && *autoApprove {
+		return fmt.Errorf("-auto-approve cannot be used with -dry-run, which creates no PR")
+	}
+	if *concurrency < 0 {
+		return fmt.Errorf("-concurrency must not be negative")
+	}
+	systemPrompt, err := writePrompt(*category)
+	if err != nil {
+		return err
+	}
+	prOpts, err := pr.options()
+	if err != nil {
+		return err
+	}
+	prOpts.AutoApprove = *autoApprove
+	summaryOut, restoreStdout, err := summaryOutput(*output)
+	if err != nil {
+		return err
+	}
+	defer restoreStdout()
+	if !*dryRun {
+		if err := netguard.Check("opening a pull request"); err != nil {
+			return fmt.Errorf("%w; use -dry-run to preview the changes locally", err)
+		}
+	}
+
+	if len(positional) == 0 {
+		help.PrintWriteDocsHelp()
+		return fmt.Errorf("no topics specified")
+	}
+
+	repo, folder, err := prepareRepo(*localPath, *repoSel, *scope, *branch)
+	if err != nil {
+		return err
+	}
+	if err := applyDocsDir(repo, *docsDir); err != nil {
+		return err
+	}
+	repo.SetPROptions(prOpts)
+
+	topics := parseTopics(strings.Join(positional, ","))
+	return runWriteMode(ctx, folder, repo, topics, systemPrompt, *dryRun, *confirmEdits, agent.BatchOptions{Concurrency: *concurrency, Dashboard: !*noTUI}, summaryOut)
+}
+
+func cmdWriteTests(ctx context.Context, args []string) error {
+	fs := newFlagSet("write-tests")
+	scope := addScopeFlag(fs)
+	branch := addBranchFlag(fs)
+	repoSel := addRepoFlag(fs)
+	localPath := fs.String("local", "", "Use an existing local checkout instead of cloning")
+	verify := fs.Bool("verify", false, "Run go test on the new Go tests and fix or drop the ones that fail")
+	pr := addPRFlags(fs)
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+
+	prOpts, err := pr.options()
+	if err != nil {
+		return err
+	}
+	if prOpts.Split != "" {
+		return fmt.Errorf("-split-prs splits docs PRs and cannot be used with write-tests")
+	}
+	if prOpts.Title == "" {
+		prOpts.Title = "Unit tests"
+	}
+	if prOpts.Body == "" {
+		prOpts.Body = "Automated docu-jarvis unit tests\n\n{summary}"
+	}
+	if err := netguard.Check("opening a pull request"); err != nil {
+		return err
+	}
+
+	if len(positional) == 0 {
+		help.PrintWriteTestsHelp()
+		return fmt.Errorf("no package or file specified")
+	}
+
+	repo, folder, err := prepareRepo(*localPath, *repoSel, *scope, *branch)
+	if err != nil {
+		return err
+	}
+	repo.SetPROptions(prOpts)
+
+	return runWriteTestsMode(ctx, folder, repo, positional, *verify)
+}
+
+func cmdWriteAPIDocs(ctx context.Context, args []string) error {
+	fs := newFlagSet("write-api-docs")
+	scope := addScopeFlag(fs)
+	branch := addBranchFlag(fs)
+	repoSel := addRepoFlag(fs)
+	localPath := fs.String("local", "", "Use an existing local checkout instead of cloning")
+	dryRun := fs.Bool("dry-run", false, "Show the proposed docs without writing files or creating a PR")
+	docsDir := addDocsDirFlag(fs)
+	autoApprove := addAutoApproveFlag(fs)
+	format := fs.String("format", apiFormatMarkdown, "Output format: markdown (endpoint docs) or openapi (OpenAPI 3 spec)")
+	output := fs.String("output", "", "File to write, relative to the first docs root (default: api-reference.md, or openapi.yaml)")
+	pr := addPRFlags(fs)
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("write-api-docs takes no arguments; it documents every route it finds")
+	}
+
+	if *dryRun && *autoApprove {
+		return fmt.Errorf("-auto-approve cannot be used with -dry-run, which creates no PR")
+	}
+	outputFile, err := apiDocsOutput(*format, *output)
+	if err != nil {
+		return err
+	}
+	prOpts, err := pr.options()
+	if err != nil {
+		return err
+	}
+	prOpts.AutoApprove = *autoApprove
+	if prOpts.Title == "" {
+		prOpts.Title = "API documentation"
+	}
+	if !*dryRun {
+		if err := netguard.Check("opening a pull request"); err != nil {
+			return fmt.Errorf("%w; use -dry-run to preview the changes locally", err)
+		}
+	}
+
+	repo, folder, err := prepareRepo(*localPath, *repoSel, *scope, *branch)
+	if err != nil {
+		return err
+	}
+	if err := applyDocsDir(repo, *docsDir); err != nil {
+		return err
+	}
+	repo.SetPROptions(prOpts)
+
+	return runWriteAPIDocsMode(ctx, folder, repo, *format, outputFile, *dryRun)
+}
+
+func cmdAuditDocs(ctx context.Context, args []string) error {
+	fs := newFlagSet("audit-docs")
+	scope := addScopeFlag(fs)
+	branch := addBranchFlag(fs)
+	repoSel := addRepoFlag(fs)
+	localPath := fs.String("local", "", "Use an existing local checkout instead of cloning")
+	docsDir := addDocsDirFlag(fs)
+	output := fs.String("output", "text", "Output format: text, or json for the report on stdout")
+
+	if _, err := parseArgs(fs, args); err != nil {
+		return handleParseError(fs, err)
+	}
+
+	reportOut, restoreStdout, err := summaryOutput(*output)
+	if err != nil {
+		return err
+	}
+	defer restoreStdout()
+
+	repo, folder, err := prepareRepo(*localPath, *repoSel, *scope, *branch)
+	if err != nil {
+		return err
+	}
+	if err := applyDocsDir(repo, *docsDir); err != nil {
+		return err
+	}
+
+	return runAuditDocsMode(ctx, folder, repo, reportOut)
+}
+
+func cmdDocsGap(ctx context.Context, args []string) error {
+	fs := newFlagSet("docs-gap")
+	scope := addScopeFlag(fs)
+	branch := addBranchFlag(fs)
+	repoSel := addRepoFlag(fs)
+	localPath := fs.String("local", "", "Use an existing local checkout instead of cloning")
+	docsDir := addDocsDirFlag(fs)
+	template := fs.String("template", "diataxis", "Reference layout: a preset ("+strings.Join(agent.LayoutPresets(), ", ")+"), a URL, or a path")
+	output := fs.String("output", "text", "Output format: text, or json for the report on stdout")
+
+	if _, err := parseArgs(fs, args); err != nil {
+		return handleParseError(fs, err)
+	}
+
+	reportOut, restoreStdout, err := summaryOutput(*output)
+	if err != nil {
+		return err
+	}
+	defer restoreStdout()
+
+	repo, folder, err := prepareRepo(*localPath, *repoSel, *scope, *branch)
+	if err != nil {
+		return err
+	}
+	if err := applyDocsDir(repo, *docsDir); err != nil {
+		return err
+	}
+
+	return runDocsGapMode(ctx, folder, repo, *template, reportOut)
+}
+
+func cmdLintDocs(ctx context.Context, args []string) error {
+	fs := newFlagSet("lint-docs")
+	scope := addScopeFlag(fs)
+	branch := addBranchFlag(fs)
+	repoSel := addRepoFlag(fs)
+	localPath := fs.String("local", "", "Use an existing local checkout instead of cloning")
+	docsDir := addDocsDirFlag(fs)
+	fix := fs.Bool("fix", false, "Fix the findings with Claude and create a PR")
+	confirmEdits := addConfirmEditsFlag(fs)
+	autoApprove := addAutoApproveFlag(fs)
+	concurrency := addConcurrencyFlag(fs)
+	noTUI := addNoTUIFlag(fs)
+	pr := addPRFlags(fs)
+	output := fs.String("output", "text", "Output format: text, or json for the report on stdout")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+
+	if *confirmEdits && !*fix {
+		return fmt.Errorf("-confirm-edits needs -fix, as linting makes no edits")
+	}
+	if *autoApprove && !*fix {
+		return fmt.Errorf("-auto-approve needs -fix, as linting creates no PR")
+	}
+	if *concurrency < 0 {
+		return fmt.Errorf("-concurrency must not be negative")
+	}
+	prOpts, err := pr.options()
+	if err != nil {
+		return err
+	}
+	prOpts.AutoApprove = *autoApprove
+	reportOut, restoreStdout, err := summaryOutput(*output)
+	if err != nil {
+		return err
+	}
+	defer restoreStdout()
+	if *fix {
+		if err := netguard.Check("opening a pull request"); err != nil {
+			return fmt.Errorf("%w; lint without -fix to get the report only", err)
+		}
+	}
+
+	if len(positional) == 0 {
+		help.PrintLintDocsHelp()
+		return fmt.Errorf("no files specified - use 'all' or specify file names")
+	}
+
+	repo, folder, err := prepareRepo(*localPath, *repoSel, *scope, *branch)
+	if err != nil {
+		return err
+	}
+	if err := applyDocsDir(repo, *docsDir); err != nil {
+		return err
+	}
+	repo.SetPROptions(prOpts)
+
+	files := parseTopics(strings.Join(positional, ","))
+	return runLintDocsMode(ctx, folder, repo, files, *fix, *confirmEdits, agent.BatchOptions{Concurrency: *concurrency, Dashboard: !*noTUI}, reportOut)
+}
+
+func cmdArchiveDocs(ctx context.Context, args []string) error {
+	fs := newFlagSet("archive-docs")
+	scope := addScopeFlag(fs)
+	branch := addBranchFlag(fs)
+	repoSel := addRepoFlag(fs)
+	localPath := fs.String("local", "", "Use an existing local checkout instead of cloning")
+	docsDir := addDocsDirFlag(fs)
+	reason := fs.String("reason", "", "Why the docs are archived, shown in the deprecation banner")
+	dryRun := fs.Bool("dry-run", false, "Show what would move and which links change without writing files or creating a PR")
+	pr := addPRFlags(fs)
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+
+	prOpts, err := pr.options()
+	if err != nil {
+		return err
+	}
+	if !*dryRun {
+		if err := netguard.Check("opening a pull request"); err != nil {
+			return fmt.Errorf("%w; use -dry-run to preview the changes locally", err)
+		}
+	}
+
+	if len(positional) == 0 {
+		help.PrintArchiveDocsHelp()
+		return fmt.Errorf("no files specified - specify file names or tag:<name>")
+	}
+
+	repo, folder, err := prepareRepo(*localPath, *repoSel, *scope, *branch)
+	if err != nil {
+		return err
+	}
+	if err := applyDocsDir(repo, *docsDir); err != nil {
+		return err
+	}
+	repo.SetPROptions(prOpts)
+
+	files := parseTopics(strings.Join(positional, ","))
+	return runArchiveDocsMode(folder, repo, files, *reason, *dryRun)
+}
+
+func cmdCheckAssets(ctx context.Context, args []string) error {
+	fs := newFlagSet("check-assets")
+	scope := addScopeFlag(fs)
+	branch := addBranchFlag(fs)
+	repoSel := addRepoFlag(fs)
+	localPath := fs.String("local", "", "Use an existing local checkout instead of cloning")
+	docsDir := addDocsDirFlag(fs)
+	strict := fs.Bool("strict", false, "Also fail when screenshot placeholders have no image")
+
+	if _, err := parseArgs(fs, args); err != nil {
+		return handleParseError(fs, err)
+	}
+
+	repo, _, err := prepareRepo(*localPath, *repoSel, *scope, *branch)
+	if err != nil {
+		return err
+	}
+	if err := applyDocsDir(repo, *docsDir); err != nil {
+		return err
+	}
+
+	return runCheckAssetsMode(repo, *strict)
+}
+
+func cmdStaleDocs(ctx context.Context, args []string) error {
+	fs := newFlagSet("stale-docs")
+	scope := addScopeFlag(fs)
+	branch := addBranchFlag(fs)
+	repoSel := addRepoFlag(fs)
+	localPath := fs.String("local", "", "Use an existing local checkout instead of cloning")
+	docsDir := addDocsDirFlag(fs)
+	strict := fs.Bool("strict", false, "Fail when any doc is stale")
+
+	if _, err := parseArgs(fs, args); err != nil {
+		return handleParseError(fs, err)
+	}
+
+	repo, folder, err := prepareRepo(*localPath, *repoSel, *scope, *branch)
+	if err != nil {
+		return err
+	}
+	if err := applyDocsDir(repo, *docsDir); err != nil {
+		return err
+	}
+
+	return runStaleDocsMode(folder, repo, *strict)
+}
+
+func cmdEval(ctx context.Context, args []string) error {
+	fs := newFlagSet("eval")
+	scenarios := fs.String("scenario", "", "Comma-separated scenarios to run (default: all)")
+	model := fs.String("model", "", "Model to run every scenario with (default: the configured models)")
+	baseline := fs.String("baseline", "", "Report of an earlier run; fail only on checks that passed in it")
+	save := fs.String("save", "", "Write the report of this run to a file")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+	if len(positional) > 1 {
+		return fmt.Errorf("eval takes one suite directory")
+	}
+	suite := "evals"
+	if len(positional) == 1 {
+		suite = positional[0]
+	}
+
+	var names []string
+	for _, name := range strings.Split(*scenarios, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return runEvalMode(ctx, suite, names, *model, *baseline, *save)
+}
+
+func cmdExportSite(ctx context.Context, args []string) error {
+	fs := newFlagSet("export-site")
+	scope := addScopeFlag(fs)
+	branch := addBranchFlag(fs)
+	repoSel := addRepoFlag(fs)
+	localPath := fs.String("local", "", "Use an existing local checkout instead of cloning")
+	docsDir := addDocsDirFlag(fs)
+	format := fs.String("format", site.FormatMkDocs, "Site generator: mkdocs or docusaurus")
+	name := fs.String("name", "", "Site title (default: the repository name)")
+	force := fs.Bool("force", false, "Replace site config files that export-site did not write")
+	dryRun := fs.Bool("dry-run", false, "Show the files that would be written without writing them")
+	openPR := fs.Bool("pr", false, "Open a PR with the site instead of leaving it in the checkout")
+	pr := addPRFlags(fs)
+
+	if _, err := parseArgs(fs, args); err != nil {
+		return handleParseError(fs, err)
+	}
+
+	if *format != site.FormatMkDocs && *format != site.FormatDocusaurus {
+		return fmt.Errorf("invalid -format %q (must be %s or %s)", *format, site.FormatMkDocs, site.FormatDocusaurus)
+	}
+	prOpts, err := pr.options()
+	if err != nil {
+		return err
+	}
+	if prOpts.Split != "" {
+		return fmt.Errorf("-split-prs cannot be used with export-site; the site is one PR")
+	}
+	switch {
+	case *dryRun:
+	case *openPR:
+		if err := netguard.Check("opening a pull request"); err != nil {
+			return fmt.Errorf("%w; use -local to write the site into your checkout", err)
+		}
+	case *localPath == "":
+		return fmt.Errorf("export-site needs -local <path> to write the site into your checkout, or -pr to open a PR with it")
+	}
+
+	repo, _, err := prepareRepo(*localPath, *repoSel, *scope, *branch)
+	if err != nil {
+		return err
+	}
+	if err := applyDocsDir(repo, *docsDir); err != nil {
+		return err
+	}
+	repo.SetPROptions(prOpts)
+
+	return runExportSiteMode(repo, *format, *name, *force, *dryRun, *openPR)
+}
+
+func cmdDebug(ctx context.Context, args []string) error {
+	fs := newFlagSet("debug")
+	scope := addScopeFlag(fs)
+	branch := addBranchFlag(fs)
+	repoSel := addRepoFlag(fs)
+	bisect := fs.Bool("bisect", false, "Binary-search the commits with Claude instead of analyzing every commit")
+	verify := fs.Bool("verify", false, "When the verdict is unsure, have Claude argue against the top suspects and adjust their confidence")
+	incident := fs.String("incident", "", "Run the whole incident workflow for this description: triage, verification, investigation, and a report")
+	since := fs.String("since", "", "With -incident: how far back to look for commits (e.g. \"6 hours ago\" or 2024-06-01T09:00)")
+	reportPath := fs.String("report", "", "With -incident: file to write the markdown report to (default: incident-<time>.md)")
+	branches := fs.String("branches", "", "Comma-separated branches to search instead of the selected one; globs such as release/* are allowed")
+	firstParent := fs.Bool("first-parent", false, "Follow only the first parent of merges, so a merged branch counts as its merge commit")
+	concurrency := fs.Int("concurrency", debugConcurrency, "Analyze at most this many commits at once, 0 for all of them")
+	stopAt := fs.Int("stop-at", 0, "Stop analyzing once a commit is the likely culprit with at least this confidence (1-100)")
+	var symptomFlags listFlag
+	fs.Var(&symptomFlags, "symptom", "Another symptom of the bug, analyzed in the same pass over the commits (repeatable)")
+	symptomsFile := fs.String("symptoms-file", "", "File with more symptoms of the bug, one per line")
+	noHistory := fs.Bool("no-history", false, "Neither consult nor record the outcomes of past debug runs")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+	extraSymptoms, err := loadSymptoms(symptomFlags, *symptomsFile)
+	if err != nil {
+		return err
+	}
+	if *concurrency < 0 {
+		return fmt.Errorf("-concurrency must not be negative")
+	}
+	if *stopAt < 0 || *stopAt > 100 {
+		return fmt.Errorf("-stop-at must be a confidence from 1 to 100")
+	}
+	suspects := agent.SuspectOptions{Concurrency: *concurrency, StopAt: *stopAt}
+
+	history := git.HistoryOptions{FirstParent: *firstParent}
+	for _, b := range strings.Split(*branches, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			history.Branches = append(history.Branches, b)
+		}
+	}
+
+	if *incident != "" {
+		if *since == "" {
+			return fmt.Errorf("-incident requires -since <time>")
+		}
+		if len(positional) > 0 || *bisect || *verify || *stopAt > 0 {
+			return fmt.Errorf("-incident cannot be combined with date arguments, -bisect, -verify, or -stop-at (it always verifies the top suspects)")
+		}
+		if len(extraSymptoms) > 0 {
+			suspects.Symptoms = append([]string{*incident}, extraSymptoms...)
+		}
+		start, err := dates.Parse(*since, time.Now(), time.Local, false)
+		if err != nil {
+			return fmt.Errorf("invalid -since: %w", err)
+		}
+		if *reportPath == "" {
+			*reportPath = "incident-" + time.Now().Format("20060102-1504") + ".md"
+		}
+		path, err := filepath.Abs(*reportPath)
+		if err != nil {
+			return fmt.Errorf("invalid -report: %w", err)
+		}
+		repo, folder, err := prepareRepo("", *repoSel, *scope, *branch)
+		if err != nil {
+			return err
+		}
+		return runIncidentMode(ctx, folder, repo, *incident, *since, start, history, path, !*noHistory, suspects)
+	}
+	if *since != "" || *reportPath != "" {
+		return fmt.Errorf("-since and -report only apply to -incident")
+	}
+
+	var symptoms []string
+	if len(positional) >= 3 {
+		symptoms = append(symptoms, positional[2])
+	}
+	symptoms = append(symptoms, extraSymptoms...)
+	if len(positional) < 2 || len(symptoms) == 0 {
+		help.PrintDebugHelp()
+		return fmt.Errorf("debug mode requires 3 arguments: <from-date> <to-date> <bug-description>")
+	}
+	if len(symptoms) > 1 {
+		if *bisect {
+			return fmt.Errorf("-bisect follows one symptom and cannot be combined with -symptom or -symptoms-file")
+		}
+		suspects.Symptoms = symptoms
+	}
+	if *bisect && len(history.Branches) > 0 {
+		return fmt.Errorf("-bisect needs a single line of history and cannot be combined with -branches")
+	}
+	if *bisect && *stopAt > 0 {
+		return fmt.Errorf("-stop-at does not apply to -bisect, which analyzes one commit at a time")
+	}
+	from, to, err := dates.Range(positional[0], positional[1])
+	if err != nil {
+		return err
+	}
+
+	repo, folder, err := prepareRepo("", *repoSel, *scope, *branch)
+	if err != nil {
+		return err
+	}
+
+	return runDebugMode(ctx, folder, repo, positional[0], positional[1], from, to, agent.DescribeSymptoms(symptoms), history, *bisect, *verify, !*noHistory, suspects)
+}
+
+// listFlag is a flag that may be given several times, collecting its values.
+type listFlag []string
+
+func (f *listFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *listFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// loadSymptoms returns the symptoms of the -symptom flags, then those of the
+// -symptoms-file, one per line, skipping blank lines and # comments.
+func loadSymptoms(flags []string, file string) ([]string, error) {
+	var symptoms []string
+	for _, symptom := range flags {
+		if symptom = strings.TrimSpace(symptom); symptom != "" {
+			symptoms = append(symptoms, symptom)
+		}
+	}
+	if file == "" {
+		return symptoms, nil
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read -symptoms-file: %w", err)
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			symptoms = append(symptoms, line)
+		}
+	}
+	return symptoms, nil
+}
+
+func cmdExplain(ctx context.Context, args []string) error {
+	fs := newFlagSet("explain")
+	scope := addScopeFlag(fs)
+	branch := addBranchFlag(fs)
+	repoSel := addRepoFlag(fs)
+	diffDeploys := fs.Bool("diff-deploys", false, "Explain what differs between two deployed commits: explain -diff-deploys <prod-sha> <staging-sha>")
+	resume := fs.Bool("resume", false, "Continue the saved conversation about the commits instead of starting over")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+
+	if *diffDeploys {
+		if len(positional) < 2 {
+			help.PrintExplainHelp()
+			return fmt.Errorf("-diff-deploys requires the production and staging commits")
+		}
+		if *branch != "" {
+			return fmt.Errorf("-diff-deploys cannot be combined with -branch")
+		}
+		repo, folder, err := prepareRepo("", *repoSel, *scope, "")
+		if err != nil {
+			return err
+		}
+		return runDiffDeploysMode(ctx, folder, repo, positional[0], positional[1], strings.Join(positional[2:], " "), *resume)
+	}
+
+	if len(positional) == 0 {
+		help.PrintExplainHelp()
+		return fmt.Errorf("explain requires a commit hash, range, or branch")
+	}
+
+	repo, folder, err := prepareRepo("", *repoSel, *scope, *branch)
+	if err != nil {
+		return err
+	}
+
+	initialQuestion := strings.Join(positional[1:], " ")
+	return runExplainMode(ctx, folder, repo, positional[0], initialQuestion, *resume)
+}
+
+func cmdCheckStaging(ctx context.Context, args []string) error {
+	fs := newFlagSet("check-staging")
+	scope := addScopeFlag(fs)
+	docsImpact := fs.Bool("docs-impact", false, "Report which documentation files the staged change affects")
+	queueDocs := fs.Bool("queue-docs", false, "Queue docs that need updating for the next 'update-docs queued' run (implies -docs-impact)")
+	output := fs.String("output", "text", "Output format: text or json")
+	docsDir := addDocsDirFlag(fs)
+	onlyNew := fs.Bool("only-new", false, "Report and fail only on violations the staged changes introduce, not those on unchanged lines or in the baseline")
+	updateBaseline := fs.Bool("update-baseline", false, "Record the violations the review finds in "+baseline.FileName+" as pre-existing")
+	inferStandards := fs.Bool("infer-standards", false, "Draft code standards from the codebase and recent review comments, to edit and save")
+	passes := fs.Int("passes", 1, "Run this many independent reviews and count only the findings most of them agree on")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+
+	if len(positional) > 0 && strings.ToLower(positional[0]) == "settings" {
+		return runCheckStagingSettings()
+	}
+
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid -output %q (must be text or json)", *output)
+	}
+	if *passes < 1 {
+		return fmt.Errorf("invalid -passes %d (must be at least 1)", *passes)
+	}
+	if *passes > 1 && (*inferStandards || *updateBaseline) {
+		return fmt.Errorf("-passes cannot be combined with -infer-standards or -update-baseline")
+	}
+	if *inferStandards {
+		if *updateBaseline || *onlyNew || *output == "json" || *docsImpact || *queueDocs {
+			return fmt.Errorf("-infer-standards cannot be combined with -update-baseline, -only-new, -output json, -docs-impact, or -queue-docs")
+		}
+		repo, folder, err := openWorkingRepo(*scope)
+		if err != nil {
+			return err
+		}
+		return runInferStandards(ctx, folder, repo)
+	}
+	if *updateBaseline {
+		if *onlyNew || *output == "json" || *docsImpact || *queueDocs {
+			return fmt.Errorf("-update-baseline cannot be combined with -only-new, -output json, -docs-impact, or -queue-docs")
+		}
+		repo, folder, err := openWorkingRepo(*scope)
+		if err != nil {
+			return err
+		}
+		return runUpdateBaseline(ctx, folder, repo)
+	}
+
+	if *output == "json" {
+		// Keep stdout for the JSON document; progress messages go to stderr
+		stdout := os.Stdout
+		os.Stdout = os.Stderr
+		defer func() { os.Stdout = stdout }()
+
+		repo, folder, err := openWorkingRepo(*scope)
+		if err != nil {
+			return err
+		}
+		if err := applyDocsDir(repo, *docsDir); err != nil {
+			return err
+		}
+		return runCheckStagingJSON(ctx, stdout, folder, repo, *docsImpact || *queueDocs, *queueDocs, *onlyNew, *passes)
+	}
+
+	repo, folder, err := openWorkingRepo(*scope)
+	if err != nil {
+		return err
+	}
+	if err := applyDocsDir(repo, *docsDir); err != nil {
+		return err
+	}
+
+	if ci.Enabled() || *onlyNew || *passes > 1 {
+		return runCheckStagingGate(ctx, folder, repo, *docsImpact || *queueDocs, *queueDocs, *onlyNew, *passes)
+	}
+	return runCheckStagingMode(ctx, folder, repo, *docsImpact || *queueDocs, *queueDocs)
+}
+
+func cmdReviewChecklist(ctx context.Context, args []string) error {
+	fs := newFlagSet("review-checklist")
+	scope := addScopeFlag(fs)
+	post := fs.Bool("post", false, "Post the review checklist as a PR comment")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+
+	if len(positional) == 0 {
+		help.PrintReviewChecklistHelp()
+		return fmt.Errorf("review-checklist requires a source (staged, branch, or pr)")
+	}
+
+	repo, folder, err := openWorkingRepo(*scope)
+	if err != nil {
+		return err
+	}
+
+	return runReviewChecklistMode(ctx, folder, repo, positional[0], positional[1:], *post)
+}
+
+func cmdReviewPR(ctx context.Context, args []string) error {
+	fs := newFlagSet("review-pr")
+	scope := addScopeFlag(fs)
+	dryRun := fs.Bool("dry-run", false, "Print the findings without posting a review")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+
+	if len(positional) == 0 {
+		help.PrintReviewPRHelp()
+		return fmt.Errorf("review-pr requires a PR number or URL")
+	}
+
+	repo, folder, err := openWorkingRepo(*scope)
+	if err != nil {
+		return err
+	}
+
+	return runReviewPRMode(ctx, folder, repo, positional[0], *dryRun)
+}
+
+func cmdTriage(ctx context.Context, args []string) error {
+	fs := newFlagSet("triage")
+	scope := addScopeFlag(fs)
+	since := fs.String("since", "30 days ago", "How far back to look for related commits")
+	dryRun := fs.Bool("dry-run", false, "Print the triage without commenting on the issue")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+
+	if len(positional) == 0 {
+		help.PrintTriageHelp()
+		return fmt.Errorf("triage requires an issue number or URL")
+	}
+	start, err := dates.Parse(*since, time.Now(), time.Local, false)
+	if err != nil {
+		return fmt.Errorf("invalid -since: %w", err)
+	}
+
+	repo, folder, err := openWorkingRepo(*scope)
+	if err != nil {
+		return err
+	}
+
+	return runTriageMode(ctx, folder, repo, positional[0], start, *dryRun)
+}
+
+func cmdCheckCommits(ctx context.Context, args []string) error {
+	fs := newFlagSet("check-commits")
+	scope := addScopeFlag(fs)
+	fixup := fs.Bool("fixup", false, "Offer to rewrite non-compliant commit messages via git rebase")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+
+	if len(positional) == 0 {
+		help.PrintCheckCommitsHelp()
+		return fmt.Errorf("check-commits requires a revision range (e.g. main..HEAD)")
+	}
+
+	repo, folder, err := openWorkingRepo(*scope)
+	if err != nil {
+		return err
+	}
+
+	return runCheckCommitsMode(ctx, folder, repo, positional[0], *fixup)
+}
+
+func cmdSquashSummary(ctx context.Context, args []string) error {
+	fs := newFlagSet("squash-summary")
+	scope := addScopeFlag(fs)
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+
+	baseBranch := "main"
+	if len(positional) > 0 {
+		baseBranch = positional[0]
+	}
+
+	repo, folder, err := openWorkingRepo(*scope)
+	if err != nil {
+		return err
+	}
+
+	return runSquashSummaryMode(ctx, folder, repo, baseBranch)
+}
+
+func cmdChangelog(ctx context.Context, args []string) error {
+	fs := newFlagSet("changelog")
+	scope := addScopeFlag(fs)
+	stdout := fs.Bool("stdout", false, "Print the entry instead of writing it to the changelog file")
+	file := fs.String("file", "CHANGELOG.md", "Changelog file to prepend the entry to")
+	title := fs.String("title", "", "Release heading for the entry (default: <to-ref>)")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+
+	if len(positional) < 2 {
+		help.PrintChangelogHelp()
+		return fmt.Errorf("changelog requires 2 arguments: <from-ref> <to-ref>")
+	}
+
+	repo, folder, err := openWorkingRepo(*scope)
+	if err != nil {
+		return err
+	}
+
+	return runChangelogMode(ctx, folder, repo, positional[0], positional[1], *title, *file, *stdout)
+}
+
+func cmdServe(ctx context.Context, args []string) error {
+	fs := newFlagSet("serve")
+	addr := fs.String("addr", "localhost:8080", "Address to listen on; use :8080 to accept connections from other hosts")
+	paths := fs.String("paths", "", "Comma-separated directories or globs whose changes trigger an update; overrides watch_paths")
+	dryRun := fs.Bool("dry-run", false, "Show the docs updates without writing files or creating PRs")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("serve takes no arguments")
+	}
+	if !*dryRun {
+		if err := netguard.Check("opening pull requests"); err != nil {
+			return fmt.Errorf("%w; use -dry-run to preview the changes locally", err)
+		}
+	}
+
+	var watchPaths []string
+	for _, p := range strings.Split(*paths, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			watchPaths = append(watchPaths, p)
+		}
+	}
+	return runServeMode(ctx, *addr, watchPaths, *dryRun)
+}
+
+// unschedulable are the commands a schedule cannot run: they run forever,
+// manage the schedule, or need someone to answer.
+var unschedulable = map[string]bool{"schedule": true, "daemon": true, "serve": true, "config": true, "auth": true, "help": true}
+
+func cmdSchedule(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] == "list" {
+		return runScheduleList()
+	}
+
+	switch args[0] {
+	case "add":
+		// The scheduled command's flags are its own, so they are not parsed here
+		if len(args) < 3 {
+			help.PrintScheduleHelp()
+			return fmt.Errorf("usage: docu-jarvis schedule add \"<cron>\" <command> [args...]")
+		}
+		cmd := findCommand(args[2])
+		if cmd == nil {
+			return fmt.Errorf("unknown command: %s", args[2])
+		}
+		if unschedulable[cmd.name] {
+			return fmt.Errorf("%s cannot be scheduled", cmd.name)
+		}
+		return runScheduleAdd(args[1], args[2:])
+	case "remove", "rm":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: docu-jarvis schedule remove <id>")
+		}
+		return runScheduleRemove(args[1])
+	case "export":
+		fs := newFlagSet("schedule export")
+		format := fs.String("format", defaultScheduleFormat(), "Definitions to export: crontab, systemd, or launchd")
+		dir := fs.String("dir", "", "Write the definitions to files in this directory instead of stdout")
+		positional, err := parseArgs(fs, args[1:])
+		if err != nil {
+			return handleParseError(fs, err)
+		}
+		if len(positional) > 0 {
+			return fmt.Errorf("schedule export takes no arguments")
+		}
+		return runScheduleExport(*format, *dir)
+	}
+
+	help.PrintScheduleHelp()
+	return fmt.Errorf("usage: docu-jarvis schedule add | schedule list | schedule remove <id> | schedule export")
+}
+
+func cmdDaemon(ctx context.Context, args []string) error {
+	fs := newFlagSet("daemon")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("daemon takes no arguments")
+	}
+	return runDaemonMode(ctx)
+}
+
+func cmdConfig(ctx context.Context, args []string) error {
+	fs := newFlagSet("config")
+	if _, err := parseArgs(fs, args); err != nil {
+		return handleParseError(fs, err)
+	}
+	return runConfigMode()
+}
+
+func cmdAuth(ctx context.Context, args []string) error {
+	fs := newFlagSet("auth")
+	provider := fs.String("provider", "github", "Token to log in or out: github, gitlab, bitbucket, anthropic, or webhook")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+
+	if len(positional) == 0 || positional[0] == "status" {
+		return runAuthStatus()
+	}
+	secret, ok := settings.FindSecret(*provider)
+	if !ok {
+		return fmt.Errorf("unknown -provider %q: use github, gitlab, bitbucket, anthropic, or webhook", *provider)
+	}
+	if len(positional) == 1 {
+		switch positional[0] {
+		case "login":
+			return runAuthLogin(secret)
+		case "logout":
+			return runAuthLogout(secret)
+		}
+	}
+
+	help.PrintAuthHelp()
+	return fmt.Errorf("usage: docu-jarvis auth login | auth logout | auth status")
+}
+
+func cmdUsage(ctx context.Context, args []string) error {
+	fs := newFlagSet("usage")
+	days := fs.Int("days", 30, "Number of days of history to show")
+	if _, err := parseArgs(fs, args); err != nil {
+		return handleParseError(fs, err)
+	}
+	if *days < 1 {
+		return fmt.Errorf("-days must be at least 1")
+	}
+	return runUsageMode(*days)
+}
+
+func cmdRuns(ctx context.Context, args []string) error {
+	fs := newFlagSet("runs")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+
+	if len(positional) == 0 || positional[0] == "list" {
+		return runRunsList()
+	}
+	if positional[0] == "show" && len(positional) == 2 {
+		return runRunsShow(positional[1])
+	}
+
+	help.PrintRunsHelp()
+	return fmt.Errorf("usage: docu-jarvis runs list | runs show <id>")
+}
+
+func cmdCache(ctx context.Context, args []string) error {
+	fs := newFlagSet("cache")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+
+	if len(positional) == 0 || positional[0] == "status" {
+		return runCacheStatus()
+	}
+	if positional[0] == "clear" && len(positional) == 1 {
+		return runCacheClear()
+	}
+
+	help.PrintCacheHelp()
+	return fmt.Errorf("usage: docu-jarvis cache status | cache clear")
+}
+
+func cmdConversations(ctx context.Context, args []string) error {
+	fs := newFlagSet("conversations")
+	format := fs.String("format", "markdown", "With export: markdown or json")
+	file := fs.String("file", "", "With export: write the conversation to this file instead of stdout")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+
+	if len(positional) == 0 || positional[0] == "list" {
+		return runConversationsList()
+	}
+	if positional[0] == "show" && len(positional) == 2 {
+		return runConversationsShow(positional[1])
+	}
+	if positional[0] == "export" && len(positional) == 2 {
+		if *format != "markdown" && *format != "json" {
+			return fmt.Errorf("invalid -format %q (must be markdown or json)", *format)
+		}
+		return runConversationsExport(positional[1], *format, *file)
+	}
+
+	help.PrintConversationsHelp()
+	return fmt.Errorf("usage: docu-jarvis conversations list | conversations show <id> | conversations export <id>")
+}
+
+func cmdRollbackRun(ctx context.Context, args []string) error {
+	fs := newFlagSet("rollback-run")
+	dryRun := fs.Bool("dry-run", false, "Check that the run's changes still revert cleanly without writing files or creating a PR")
+	pr := addPRFlags(fs)
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+	if len(positional) != 1 {
+		help.PrintRollbackRunHelp()
+		return fmt.Errorf("usage: docu-jarvis rollback-run <id>")
+	}
+
+	prOpts, err := pr.options()
+	if err != nil {
+		return err
+	}
+	if !*dryRun {
+		if err := netguard.Check("opening a pull request"); err != nil {
+			return fmt.Errorf("%w; use -dry-run to check the rollback locally", err)
+		}
+	}
+	return runRollbackMode(positional[0], *dryRun, prOpts)
+}
+
+func cmdPurge(ctx context.Context, args []string) error {
+	fs := newFlagSet("purge")
+	all := fs.Bool("all", false, "Remove all logs, run state, conversations, usage history, caches, clones, and sessions")
+	dryRun := fs.Bool("dry-run", false, "List what would be removed without removing it")
+	if _, err := parseArgs(fs, args); err != nil {
+		return handleParseError(fs, err)
+	}
+	return runPurgeMode(*all, *dryRun)
+}
+
+func cmdLogs(ctx context.Context, args []string) error {
+	fs := newFlagSet("logs")
+	lines := fs.Int("n", 50, "Number of records to show")
+	follow := fs.Bool("f", false, "Keep printing records as they are written")
+	level := fs.String("level", "debug", "Only show records at this level or above: debug, info, warn, or error")
+	raw := fs.Bool("json", false, "Print the records as they are stored")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+
+	if len(positional) == 0 || positional[0] != "tail" || len(positional) > 2 {
+		help.PrintLogsHelp()
+		return fmt.Errorf("usage: docu-jarvis logs tail [file]")
+	}
+	if *lines < 1 {
+		return fmt.Errorf("invalid -n %d (must be at least 1)", *lines)
+	}
+	minLevel, err := logging.ParseLevel(*level)
+	if err != nil {
+		return err
+	}
+	var file string
+	if len(positional) == 2 {
+		file = positional[1]
+	}
+	return runLogsTail(ctx, file, *lines, *follow, minLevel, *raw)
+}
+
+func cmdVersion(ctx context.Context, args []string) error {
+	fs := newFlagSet("version")
+	if _, err := parseArgs(fs, args); err != nil {
+		return handleParseError(fs, err)
+	}
+	return runVersionCheck()
+}
+
+func cmdUpdate(ctx context.Context, args []string) error {
+	fs := newFlagSet("update")
+	if _, err := parseArgs(fs, args); err != nil {
+		return handleParseError(fs, err)
+	}
+	return runUpdate()
+}
+
+func cmdHelp(ctx context.Context, args []string) error {
+	fs := newFlagSet("help")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseError(fs, err)
+	}
+
+	if len(args) == 0 {
+		help.PrintUsage()
+		return nil
+	}
+
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Printf("Unknown help topic: %s\n\n", args[0])
+		help.PrintUsage()
+		return nil
+	}
+
+	cmd.help()
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/udemy/docu-jarvis-cli/internal/help"
+)
+
+// legacyModes maps the old mutually exclusive mode flags to subcommands.
+// String modes carry their value as the first positional argument; args are
+// flags the mode implies.
+var legacyModes = []struct {
+	flag     string
+	command  string
+	hasValue bool
+	args     []string
+}{
+	{"update-docs", "update-docs", true, nil},
+	{"write-docs", "write-docs", true, nil},
+	{"debug", "debug", false, nil},
+	{"debug-bisect", "debug", false, []string{"-bisect"}},
+	{"check-staging", "check-staging", false, nil},
+	{"config", "config", false, nil},
+	{"help", "help", false, nil},
+	{"explain", "explain", true, nil},
+	{"update", "update", false, nil},
+	{"version", "version", false, nil},
+	{"review-checklist", "review-checklist", true, nil},
+	{"check-commits", "check-commits", true, nil},
+}
+
+// Option flags are passed through to the subcommand unchanged.
+var legacyStringOptions = []string{"custom", "local"}
+var legacyBoolOptions = []string{"dry-run", "post", "fixup"}
+
+// translateLegacyArgs rewrites an old flag-style invocation such as
+// "-update-docs all -custom x" into "update-docs -custom=x all". It returns
+// nil when the invocation was fully handled (e.g. help was printed).
+func translateLegacyArgs(args []string) ([]string, error) {
+	fs := newFlagSet("docu-jarvis")
+
+	values := make(map[string]*string)
+	bools := make(map[string]*bool)
+	for _, mode := range legacyModes {
+		if mode.hasValue {
+			values[mode.flag] = fs.String(mode.flag, "", "")
+		} else {
+			bools[mode.flag] = fs.Bool(mode.flag, false, "")
+		}
+	}
+	for _, name := range legacyStringOptions {
+		values[name] = fs.String(name, "", "")
+	}
+	for _, name := range legacyBoolOptions {
+		bools[name] = fs.Bool(name, false, "")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, handleParseError(fs, err)
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	var active []string
+	for _, mode := range legacyModes {
+		if set[mode.flag] {
+			active = append(active, mode.flag)
+		}
+	}
+
+	if len(active) == 0 {
+		help.PrintUsage()
+		return nil, fmt.Errorf("please specify a command")
+	}
+
+	if len(active) > 1 {
+		return nil, fmt.Errorf("cannot use multiple modes at the same time")
+	}
+
+	mode := legacyModes[0]
+	for _, m := range legacyModes {
+		if m.flag == active[0] {
+			mode = m
+		}
+	}
+
+	newArgs := append([]string{mode.command}, mode.args...)
+	if mode.command != "help" {
+		fmt.Fprintf(os.Stderr, "Warning: -%s is deprecated, use 'docu-jarvis %s' instead\n\n", mode.flag, strings.Join(newArgs, " "))
+	}
+
+	for _, name := range legacyStringOptions {
+		if set[name] {
+			newArgs = append(newArgs, fmt.Sprintf("-%s=%s", name, *values[name]))
+		}
+	}
+	for _, name := range legacyBoolOptions {
+		if set[name] {
+			newArgs = append(newArgs, fmt.Sprintf("-%s=%t", name, *bools[name]))
+		}
+	}
+
+	if mode.hasValue {
+		value := *values[mode.flag]
+		if value == "-help" || value == "help" {
+			findCommand(mode.command).help()
+			return nil, nil
+		}
+		newArgs = append(newArgs, value)
+	}
+
+	// Keep trailing arguments from being parsed as subcommand flags
+	rest := fs.Args()
+	if len(rest) > 0 {
+		newArgs = append(newArgs, "--")
+		newArgs = append(newArgs, rest...)
+	}
+
+	return newArgs, nil
+}
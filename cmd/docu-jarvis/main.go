@@ -1,200 +1,576 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
-	"flag"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
 	"os"
+	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/udemy/docu-jarvis-cli/internal/agent"
+	"github.com/udemy/docu-jarvis-cli/internal/approval"
+	"github.com/udemy/docu-jarvis-cli/internal/archive"
+	"github.com/udemy/docu-jarvis-cli/internal/assets"
+	"github.com/udemy/docu-jarvis-cli/internal/baseline"
+	"github.com/udemy/docu-jarvis-cli/internal/cache"
+	"github.com/udemy/docu-jarvis-cli/internal/ci"
 	"github.com/udemy/docu-jarvis-cli/internal/config"
+	"github.com/udemy/docu-jarvis-cli/internal/conversations"
+	"github.com/udemy/docu-jarvis-cli/internal/dates"
+	"github.com/udemy/docu-jarvis-cli/internal/debughistory"
+	"github.com/udemy/docu-jarvis-cli/internal/docmetrics"
+	"github.com/udemy/docu-jarvis-cli/internal/docqueue"
+	"github.com/udemy/docu-jarvis-cli/internal/eval"
+	"github.com/udemy/docu-jarvis-cli/internal/feedback"
+	"github.com/udemy/docu-jarvis-cli/internal/freshness"
 	"github.com/udemy/docu-jarvis-cli/internal/git"
 	"github.com/udemy/docu-jarvis-cli/internal/help"
+	"github.com/udemy/docu-jarvis-cli/internal/logging"
+	"github.com/udemy/docu-jarvis-cli/internal/mdlint"
+	"github.com/udemy/docu-jarvis-cli/internal/netguard"
+	"github.com/udemy/docu-jarvis-cli/internal/redact"
+	"github.com/udemy/docu-jarvis-cli/internal/retention"
+	"github.com/udemy/docu-jarvis-cli/internal/routes"
+	"github.com/udemy/docu-jarvis-cli/internal/runstate"
+	"github.com/udemy/docu-jarvis-cli/internal/samples"
+	"github.com/udemy/docu-jarvis-cli/internal/schedule"
+	"github.com/udemy/docu-jarvis-cli/internal/secrets"
+	"github.com/udemy/docu-jarvis-cli/internal/server"
 	"github.com/udemy/docu-jarvis-cli/internal/settings"
+	"github.com/udemy/docu-jarvis-cli/internal/site"
+	"github.com/udemy/docu-jarvis-cli/internal/standards"
 	"github.com/udemy/docu-jarvis-cli/internal/system_prompts"
 	"github.com/udemy/docu-jarvis-cli/internal/updater"
+	"github.com/udemy/docu-jarvis-cli/internal/usage"
 )
 
+// updateCheckTimeout is how long a finished command waits for the background
+// update check before exiting.
+const updateCheckTimeout = 3 * time.Second
+
 func main() {
-	if err := run(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	updater.RemoveOldBinary()
+	if err := run(os.Args[1:]); err != nil {
+		if errors.Is(err, git.ErrPushDeclined) {
+			// Declining is the user's choice, not a failure
+			fmt.Println("Nothing was pushed")
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Error: %s\n", redact.String(err.Error()))
+		var exitErr *exitCodeError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
 		os.Exit(1)
 	}
 }
 
-func run() error {
-	var updateDocsFiles string
-	var writeDocsTopics string
-	var debugMode bool
-	var checkStagingMode bool
-	var configMode bool
-	var showHelp bool
-	var explainCommit string
-	var doUpdate bool
-	var checkVersion bool
-	var customPrompt string
-
-	flag.StringVar(&updateDocsFiles, "update-docs", "", "Update existing documentation (files or 'all')")
-	flag.StringVar(&writeDocsTopics, "write-docs", "", "Write new documentation for specified topics (comma-separated)")
-	flag.BoolVar(&debugMode, "debug", false, "Debug mode: find which commit caused a bug")
-	flag.BoolVar(&checkStagingMode, "check-staging", false, "Review staged code quality")
-	flag.BoolVar(&configMode, "config", false, "Edit configuration (repo URL, code standards)")
-	flag.BoolVar(&showHelp, "help", false, "Show help message")
-	flag.StringVar(&explainCommit, "explain", "", "Explain a specific commit interactively")
-	flag.BoolVar(&doUpdate, "update", false, "Update to the latest version")
-	flag.BoolVar(&checkVersion, "version", false, "Show version and check for updates")
-	flag.StringVar(&customPrompt, "custom", "", "Custom prompt for updating documentation (use with -update-docs)")
-	flag.Parse()
-
-	if showHelp {
-		args := flag.Args()
-		if len(args) > 0 {
-			topic := strings.ToLower(args[0])
-			switch topic {
-			case "update-docs", "update":
-				help.PrintUpdateDocsHelp()
-				return nil
-			case "write-docs", "write":
-				help.PrintWriteDocsHelp()
-				return nil
-			case "debug":
-				help.PrintDebugHelp()
-				return nil
-			case "check-staging", "check", "staging":
-				help.PrintCheckStagingHelp()
-				return nil
-			case "explain":
-				help.PrintExplainHelp()
-				return nil
-			default:
-				fmt.Printf("Unknown help topic: %s\n\n", topic)
-				help.PrintUsage()
-				return nil
-			}
+// exitCodeError lets a command report a result (rather than a failure)
+// through a specific exit status, e.g. for CI gating.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitCodeError) Unwrap() error {
+	return e.err
+}
+
+func run(args []string) error {
+	args = stripGlobalFlags(args)
+	if netguard.Disabled() {
+		netguard.Disable()
+	}
+
+	if len(args) == 0 {
+		help.PrintUsage()
+		return fmt.Errorf("please specify a command")
+	}
+
+	// Claude Code starts the edit approval server as a subprocess
+	if args[0] == approval.ServerCommand {
+		return approval.Serve(os.Stdin, os.Stdout, args[1:])
+	}
+
+	// Old-style invocations (docu-jarvis -update-docs all) start with a flag
+	if strings.HasPrefix(args[0], "-") {
+		legacyArgs, err := translateLegacyArgs(args)
+		if err != nil {
+			return err
+		}
+		if legacyArgs == nil {
+			return nil
 		}
+		args = legacyArgs
+	}
+
+	cmd := findCommand(args[0])
+	if cmd == nil {
 		help.PrintUsage()
-		return nil
+		return fmt.Errorf("unknown command: %s", args[0])
 	}
 
-	if updateDocsFiles == "-help" || updateDocsFiles == "help" {
-		help.PrintUpdateDocsHelp()
-		return nil
+	if cmd.checkUpdates {
+		pruneOnStartup()
+		if err := selectProvider(); err != nil {
+			return err
+		}
 	}
-	if writeDocsTopics == "-help" || writeDocsTopics == "help" {
-		help.PrintWriteDocsHelp()
-		return nil
+
+	var updateCheck *updater.BackgroundCheck
+	if cmd.checkUpdates && !ci.Enabled() && updater.ShouldCheckForUpdates() {
+		updateCheck = updater.StartBackgroundCheck(updater.GetCurrentVersion())
+	}
+
+	err := cmd.run(context.Background(), args[1:])
+
+	// On stderr, so JSON and -stdout output stays clean
+	if usageErr := usage.Finish(os.Stderr, cmd.name); usageErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record usage: %v\n", usageErr)
 	}
+	if updateCheck != nil {
+		updateCheck.Wait(os.Stderr, updateCheckTimeout)
+	}
+	if cmd.name != "update" && updater.BinaryReplaced() {
+		fmt.Fprintln(os.Stderr, "\nOH NO!!!!  docu-jarvis was updated while this command was running. Restart it to use the new version.")
+	}
+
+	return err
+}
 
-	if configMode {
-		return runConfigMode()
+// stripGlobalFlags removes the global -no-network, -ci, -v, and -vv flags,
+// which may appear anywhere before a "--", and turns on what they ask for.
+func stripGlobalFlags(args []string) []string {
+	var rest []string
+	for i, arg := range args {
+		if arg == "--" {
+			return append(rest, args[i:]...)
+		}
+		switch arg {
+		case "-no-network", "--no-network":
+			netguard.Disable()
+			continue
+		case "-ci", "--ci":
+			ci.Enable()
+			continue
+		case "-v", "--verbose":
+			logging.SetVerbosity(1)
+			continue
+		case "-vv":
+			logging.SetVerbosity(2)
+			continue
+		}
+		rest = append(rest, arg)
 	}
+	return rest
+}
 
-	if checkVersion {
-		return runVersionCheck()
+// ask reads the answer to a prompt. In CI mode nobody can answer, so it
+// prints and returns ciAnswer instead.
+func ask(ciAnswer string) string {
+	if ci.Enabled() {
+		fmt.Println(strings.TrimSpace(ciAnswer + " (CI mode)"))
+		return ciAnswer
 	}
+	var answer string
+	fmt.Scanln(&answer)
+	return answer
+}
 
-	if doUpdate {
-		return runUpdate()
+// dataStore returns where docu-jarvis keeps data, including the clones of the
+// configured repositories.
+func dataStore(s *settings.Settings) (*retention.Store, error) {
+	cloneDir := s.CloneDir
+	if cloneDir == "" {
+		cloneDir = git.DefaultCloneDir()
 	}
 
-	if updater.ShouldCheckForUpdates() {
-		go func() {
-			updater.AutoCheckForUpdates(updater.GetCurrentVersion(), true)
-			updater.UpdateLastCheckTime()
-		}()
+	var clones []string
+	for _, repoURL := range s.Repos {
+		if repoURL != "" {
+			clones = append(clones, filepath.Join(cloneDir, config.RepoName(repoURL)))
+		}
 	}
+	return retention.NewStore(clones)
+}
 
-	modesActive := 0
-	if updateDocsFiles != "" {
-		modesActive++
+func retentionPolicy(s *settings.Settings) retention.Policy {
+	return retention.Policy{
+		MaxAge:     time.Duration(s.RetentionDays) * 24 * time.Hour,
+		MaxLogSize: int64(s.RetentionLogMB) << 20,
 	}
-	if writeDocsTopics != "" {
-		modesActive++
+}
+
+// pruneOnStartup applies the retention settings, at most once a day. Failures
+// only warn, as they must not stop the command.
+func pruneOnStartup() {
+	s, err := settings.Load()
+	if err != nil {
+		return
 	}
-	if debugMode {
-		modesActive++
+	store, err := dataStore(s)
+	if err != nil {
+		return
 	}
-	if checkStagingMode {
-		modesActive++
+
+	report, err := store.PruneIfDue(retentionPolicy(s))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to prune old data: %v\n", err)
 	}
-	if explainCommit != "" {
-		modesActive++
+	if report != nil && len(report.Removals) > 0 {
+		fmt.Fprintf(os.Stderr, "Pruned %s of old docu-jarvis data (retention_days = %d, retention_log_mb = %d)\n",
+			retention.FormatSize(report.Freed()), s.RetentionDays, s.RetentionLogMB)
 	}
+}
 
-	if modesActive == 0 {
-		help.PrintUsage()
-		return fmt.Errorf("please specify a command")
+// selectProvider makes the agents use the configured model provider, and the
+// model and limits of each [modes.<name>] section. When the settings cannot be
+// loaded, Claude Code stays the provider and the command reports the settings
+// error itself.
+func selectProvider() error {
+	s, err := settings.Load()
+	if err != nil {
+		return nil
 	}
+	ignored, err := agent.Configure(s)
+	if err != nil {
+		return err
+	}
+	for _, name := range ignored {
+		fmt.Fprintf(os.Stderr, "Warning: Claude Code ignores max_tokens in [modes.%s]; set CLAUDE_CODE_MAX_OUTPUT_TOKENS to limit its responses\n", name)
+	}
+	return nil
+}
 
-	if modesActive > 1 {
-		return fmt.Errorf("cannot use multiple modes at the same time")
+func runPurgeMode(all, dryRun bool) error {
+	s, err := settings.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+	store, err := dataStore(s)
+	if err != nil {
+		return err
 	}
 
-	if customPrompt != "" && updateDocsFiles == "" {
-		return fmt.Errorf("-custom flag can only be used with -update-docs")
+	fmt.Println("\n=== PURGE ===")
+	if dryRun {
+		fmt.Println("Dry run: nothing will be removed")
 	}
 
-	ctx := context.Background()
+	var report *retention.Report
+	if all {
+		fmt.Println("Removing all logs, run state, conversations, usage history, caches, debug history, clones, and Claude Code sessions")
+		report, err = store.PurgeAll(dryRun)
+	} else {
+		fmt.Printf("Applying retention: data older than %d days, log capped at %d MB (0 = no limit)\n", s.RetentionDays, s.RetentionLogMB)
+		report, err = store.Prune(retentionPolicy(s), dryRun)
+	}
 
-	if checkStagingMode {
-		args := flag.Args()
-		if len(args) > 0 && strings.ToLower(args[0]) == "settings" {
-			return runCheckStagingSettings()
+	if report != nil && len(report.Removals) > 0 {
+		fmt.Println()
+		for _, removal := range report.Removals {
+			fmt.Printf("  %-14s %10s  %s\n", removal.Kind, retention.FormatSize(removal.Size), removal.Path)
 		}
-		return runCheckStagingMode(ctx)
+	}
+	if err != nil {
+		return err
 	}
 
-	if explainCommit != "" {
-		args := flag.Args()
-		var initialQuestion string
-		if len(args) > 0 {
-			initialQuestion = strings.Join(args, " ")
+	if len(report.Removals) == 0 {
+		fmt.Println("\nNothing to remove")
+		return nil
+	}
+	if dryRun {
+		fmt.Printf("\nWould free %s\n", retention.FormatSize(report.Freed()))
+		return nil
+	}
+	fmt.Printf("\n✓ Purge completed! Freed %s\n", retention.FormatSize(report.Freed()))
+	return nil
+}
+
+// prepareRepo opens localPath, or clones the configured repository when it is
+// empty; repoSel picks one of several configured repositories by name or URL.
+// For local checkouts the branch only sets the PR base; the working tree is
+// used as it is.
+func prepareRepo(localPath, repoSel, scope, branch string) (*git.Repo, string, error) {
+	scope, err := cleanScope(scope)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if localPath != "" && repoSel != "" {
+		return nil, "", fmt.Errorf("-repo cannot be used with -local")
+	}
+
+	if localPath != "" {
+		fmt.Println("Using local repository...")
+		repo, err := git.OpenLocal(localPath)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open local repository: %w", err)
+		}
+		repo.SetScope(scope)
+		repo.SetBranch(branch)
+		fmt.Printf("Local path set to: %s\n", repo.GetLocalPath())
+
+		repo, folder, err := scopedRepo(repo)
+		if err != nil {
+			return nil, "", err
 		}
-		return runExplainMode(ctx, explainCommit, initialQuestion)
+		if branch != "" {
+			repo.SetPRBase(branch)
+		}
+		return repo, folder, nil
 	}
 
 	fmt.Println("Loading configuration...")
 	cfg, err := config.Load()
 	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
+		return nil, "", fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := cfg.SelectRepo(repoSel); err != nil {
+		return nil, "", err
+	}
+	if len(cfg.Repos) > 1 || repoSel != "" {
+		fmt.Printf("Repository: %s\n", redact.String(cfg.RepoURL))
 	}
 
 	fmt.Println("Cloning repository...")
+	cloneBranch := branch
+	if cloneBranch == "" {
+		cloneBranch = cfg.Branch
+	}
+
 	repo := git.NewRepo(cfg.RepoURL)
+	repo.SetScope(scope)
+	repo.SetBranch(cloneBranch)
+	if cloneBranch != "" {
+		fmt.Printf("Using branch: %s\n", cloneBranch)
+	}
+	repo.SetCloneOptions(git.CloneOptions{
+		Dir:   cfg.CloneDir,
+		Depth: cfg.CloneDepth,
+		Reuse: cfg.ReuseClone,
+	})
 	repoName := cfg.GetRepoName()
 
-	folder, err := repo.Clone(repoName)
+	if cfg.ReuseClone && cloneBranch != "" {
+		// The cached clone stays on the default branch, and the branch gets a
+		// worktree of it, so runs on different branches share one clone
+		repo.SetBranch("")
+		if _, err := repo.Clone(repoName); err != nil {
+			return nil, "", fmt.Errorf("failed to clone repository: %w", err)
+		}
+		workspace, err := git.NewWorkspace(repo)
+		if err != nil {
+			return nil, "", err
+		}
+		if repo, err = workspace.Checkout(cloneBranch); err != nil {
+			return nil, "", err
+		}
+		fmt.Printf("Checked out %s in worktree: %s\n", cloneBranch, repo.GetLocalPath())
+	} else if _, err := repo.Clone(repoName); err != nil {
+		return nil, "", fmt.Errorf("failed to clone repository: %w", err)
+	}
+
+	repo, folder, err := scopedRepo(repo)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// -branch beats a base_branch from the repo or global config
+	if branch != "" {
+		repo.SetPRBase(branch)
+	}
+	return repo, folder, nil
+}
+
+// openWorkingRepo uses the checkout containing the current directory, for
+// modes that work on the user's own changes.
+func openWorkingRepo(scope string) (*git.Repo, string, error) {
+	scope, err := cleanScope(scope)
+	if err != nil {
+		return nil, "", err
+	}
+
+	repo, err := git.OpenLocal(".")
+	if err != nil {
+		return nil, "", err
+	}
+	repo.SetScope(scope)
+
+	return scopedRepo(repo)
+}
+
+func scopedRepo(repo *git.Repo) (*git.Repo, string, error) {
+	folder := repo.GetScopedPath()
+	if repo.GetScope() != "" {
+		info, err := os.Stat(folder)
+		if err != nil || !info.IsDir() {
+			return nil, "", fmt.Errorf("scope directory does not exist: %s", repo.GetScope())
+		}
+		fmt.Printf("Scoped to: %s\n", repo.GetScope())
+	}
+
+	s, err := settings.LoadForRepo(repo.GetLocalPath())
 	if err != nil {
-		return fmt.Errorf("failed to clone repository: %w", err)
+		return nil, "", fmt.Errorf("failed to load settings: %w", err)
+	}
+	if s.GetRepoConfigPath() != "" {
+		fmt.Printf("Using repo config: %s\n", s.GetRepoConfigPath())
+	}
+	repo.SetDocsRoots(s.DocsRoots)
+	repo.SetEditAllowlist(s.EditAllowlist)
+	if s.BaseBranch != "" {
+		repo.SetPRBase(s.BaseBranch)
+	}
+
+	return repo, folder, nil
+}
+
+// resolveDocFile finds a doc given by name (with or without .md or .mdx) in
+// the docs roots. Names may be paths relative to a docs root or to the working
+// folder, as reported by docs impact analysis; bare names also match docs in
+// nested folders. Unknown names resolve to the first root so the caller
+// reports them as missing.
+func resolveDocFile(folder string, docsDirs []string, name string) string {
+	candidates := []string{name}
+	if !agent.IsDocFile(name) {
+		candidates = []string{name + ".md", name + ".mdx"}
+	}
+
+	for _, candidate := range candidates {
+		if strings.Contains(candidate, "/") {
+			path := filepath.Join(folder, candidate)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+
+		for _, dir := range docsDirs {
+			path := filepath.Join(dir, candidate)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+
+	if !strings.Contains(name, "/") {
+		docs, _ := agent.FindDocs(docsDirs)
+		for _, candidate := range candidates {
+			for _, doc := range docs {
+				if filepath.Base(doc) == candidate {
+					return doc
+				}
+			}
+		}
 	}
 
-	if debugMode {
-		args := flag.Args()
-		if len(args) < 3 {
-			help.PrintDebugHelp()
-			return fmt.Errorf("debug mode requires 3 arguments: <from-date> <to-date> <bug-description>")
+	return filepath.Join(docsDirs[0], candidates[0])
+}
+
+// resolveDocFiles resolves each name with resolveDocFile, and each tag:<name>
+// selector to the docs whose front matter has the tag. A doc selected twice
+// is listed once.
+func resolveDocFiles(folder string, docsDirs []string, names []string) ([]string, error) {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		var resolved []string
+		if tag, ok := strings.CutPrefix(name, agent.TagSelector); ok {
+			tag = strings.TrimSpace(tag)
+			if tag == "" {
+				return nil, fmt.Errorf("invalid selector %q: no tag given", name)
+			}
+			tagged, err := agent.DocsWithTag(docsDirs, tag)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find docs tagged %s: %w", tag, err)
+			}
+			if len(tagged) == 0 {
+				return nil, fmt.Errorf("no docs are tagged %s", tag)
+			}
+			fmt.Printf("%s%s: %d docs\n", agent.TagSelector, tag, len(tagged))
+			resolved = tagged
+		} else {
+			resolved = []string{resolveDocFile(folder, docsDirs, name)}
+		}
+
+		for _, path := range resolved {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
 		}
-		fromDate := args[0]
-		toDate := args[1]
-		bugDescription := args[2]
-		return runDebugMode(ctx, folder, repo, fromDate, toDate, bugDescription)
+	}
+	return paths, nil
+}
+
+// applyDocsDir replaces the configured docs roots with the -docs-dir value.
+// The roots are relative to the repository root, like docs_roots, and must be
+// inside the scope.
+func applyDocsDir(repo *git.Repo, docsDir string) error {
+	if docsDir == "" {
+		return nil
 	}
 
-	if updateDocsFiles != "" {
-		files := parseTopics(updateDocsFiles)
-		return runUpdateMode(ctx, folder, repo, files, customPrompt)
+	roots, err := settings.ParseDocsRoots(docsDir)
+	if err != nil {
+		return fmt.Errorf("invalid -docs-dir: %w", err)
+	}
+	if len(roots) == 0 {
+		return fmt.Errorf("invalid -docs-dir: no directories given")
 	}
 
-	if writeDocsTopics != "" {
-		topics := parseTopics(writeDocsTopics)
-		return runWriteMode(ctx, folder, repo, topics)
+	if scope := repo.GetScope(); scope != "" {
+		for _, root := range roots {
+			if root != scope && !strings.HasPrefix(root, scope+"/") {
+				return fmt.Errorf("-docs-dir %s is outside the scope %s (paths are relative to the repository root)", root, scope)
+			}
+		}
 	}
 
+	repo.SetDocsRoots(roots)
+	fmt.Printf("Using docs directories: %s\n", strings.Join(roots, ", "))
 	return nil
 }
 
+// cleanScope normalizes a -scope value to a slash-separated path relative to
+// the repository root.
+func cleanScope(scope string) (string, error) {
+	if scope == "" {
+		return "", nil
+	}
+
+	cleaned := filepath.ToSlash(filepath.Clean(scope))
+	if filepath.IsAbs(scope) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("scope must be a directory inside the repository: %s", scope)
+	}
+	if cleaned == "." {
+		return "", nil
+	}
+
+	return strings.TrimSuffix(cleaned, "/"), nil
+}
+
 func parseTopics(topicsStr string) []string {
 	parts := strings.Split(topicsStr, ",")
 	var topics []string
@@ -207,11 +583,38 @@ func parseTopics(topicsStr string) []string {
 	return topics
 }
 
-func runUpdateMode(ctx context.Context, folder string, repo *git.Repo, files []string, customPrompt string) error {
+// runUpdateMode updates the files and records each result in run, which is
+// started when nil, so that the run can be resumed. Dry runs are not recorded.
+// Unless noCache, the docs whose content and sources are unchanged since their
+// last update are skipped. It returns the files, as given, that need no
+// further update: their changes went into the PR, or they needed none. After
+// a dry run or a failed document, none do.
+func runUpdateMode(ctx context.Context, folder string, repo *git.Repo, files []string, customPrompt, canary string, dryRun, confirmEdits, noCache bool, run *runstate.Run, batch agent.BatchOptions, summaryOut io.Writer) ([]string, error) {
 	fmt.Println("\n=== UPDATE DOCUMENTATION MODE ===")
+	if dryRun {
+		fmt.Println("Dry run: no files will be modified and no PR will be created")
+	}
+	if confirmEdits {
+		fmt.Println("Confirm edits: each change will be shown for your approval before it is applied")
+	}
 
 	if len(files) == 0 {
-		return fmt.Errorf("no files specified - use 'all' or specify file names")
+		return nil, fmt.Errorf("no files specified - use 'all' or specify file names")
+	}
+
+	requested := files
+	config := updateConfig(customPrompt)
+	useCache := !noCache && canary == ""
+	if useCache {
+		var err error
+		files, err = skipCachedDocs(folder, repo, files, config)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			fmt.Println("\n✓ All documents are unchanged since their last update, nothing to do")
+			return requested, nil
+		}
 	}
 
 	var systemPrompt string
@@ -225,421 +628,5814 @@ func runUpdateMode(ctx context.Context, folder string, repo *git.Repo, files []s
 	fmt.Println("Initializing agent for documentation updates...")
 	ag, err := agent.New(systemPrompt, folder)
 	if err != nil {
-		return fmt.Errorf("failed to create agent: %w", err)
+		return nil, fmt.Errorf("failed to create agent: %w", err)
+	}
+	ag.SetDryRun(dryRun)
+	ag.SetConfirmEdits(confirmEdits)
+	ag.SetDocsDirs(repo.GetDocsDirs())
+	ag.SetEditRoots(repo.EditRoots())
+	ag.SetReviewerRules(reviewerRules(repo))
+	batch.LastChanged = repo.LastChanged
+	ag.SetBatchOptions(batch)
+	if s, err := settings.Load(); err == nil && s.KeepAnchors {
+		fmt.Println("Keep anchors: renamed or removed headings keep their old anchors")
+		ag.SetKeepAnchors(true)
+	}
+
+	if !dryRun {
+		if run == nil {
+			run, err = startUpdateRun(repo, customPrompt)
+		} else {
+			err = repo.MarkRun(run.ID)
+		}
+		if err != nil {
+			fmt.Printf("Warning: failed to save run state, this run cannot be resumed: %v\n", err)
+			run = nil
+		} else {
+			ag.SetRunState(run)
+			fmt.Printf("Run ID: %s\n", run.ID)
+		}
 	}
 
+	checkEdits, err := guardEdits(repo, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	defer checkEdits()
+
 	var successCount, totalFiles int
+	var settled []string
 
 	// Check if user wants to update all files
-	if len(files) == 1 && strings.ToLower(files[0]) == "all" {
+	if canary != "" {
+		successCount, totalFiles, err = updateWithCanary(ctx, ag, folder, repo, files, canary, config, run)
+		if errors.Is(err, errCanaryStopped) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else if len(files) == 1 && strings.ToLower(files[0]) == "all" {
 		fmt.Println("Updating ALL documentation files...")
 		successCount, totalFiles, err = ag.ProcessDocuments(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to process documents: %w", err)
+			return nil, fmt.Errorf("failed to process documents: %w", err)
 		}
 	} else {
 		// Update specific files
-		fmt.Printf("Updating %d specific files...\n", len(files))
-
-		docsDir := filepath.Join(folder, "documentation")
-		var filePaths []string
-		for _, file := range files {
-			if !strings.HasSuffix(file, ".md") {
-				file = file + ".md"
-			}
-			filePaths = append(filePaths, filepath.Join(docsDir, file))
+		filePaths, err := resolveDocFiles(folder, repo.GetDocsDirs(), files)
+		if err != nil {
+			return nil, err
 		}
+		fmt.Printf("Updating %d specific files...\n", len(filePaths))
 
 		successCount, totalFiles, err = ag.UpdateSpecificDocuments(ctx, filePaths)
 		if err != nil {
-			return fmt.Errorf("failed to update documents: %w", err)
+			return nil, fmt.Errorf("failed to update documents: %w", err)
 		}
 	}
 
+	if err := checkEdits(); err != nil {
+		return nil, err
+	}
+
+	if err := writeBatchSummary(summaryOut, "update-docs", repo, run, dryRun, ag.Batch()); err != nil {
+		return nil, err
+	}
+	archiveNote := archiveProposals(ag.ArchiveProposals()) + anchorRedirects(ag.AnchorRedirects())
+
+	if dryRun {
+		fmt.Printf("\nDry run complete (%d/%d files analyzed)\n", successCount, totalFiles)
+		return nil, nil
+	}
+
 	if successCount == totalFiles && totalFiles > 0 {
 		fmt.Println("\nAll documents processed successfully")
+		stampFreshness(folder, repo, ag.DocSources())
 
 		hasChanges, err := repo.HasChanges()
 		if err != nil {
-			return fmt.Errorf("failed to check for changes: %w", err)
+			return nil, fmt.Errorf("failed to check for changes: %w", err)
 		}
 
 		if hasChanges {
-			fmt.Println("\nCreating pull request...")
-			if err := repo.CreatePR(); err != nil {
-				return fmt.Errorf("failed to create PR: %w", err)
+			if err := fixDocStructure(repo); err != nil {
+				return nil, err
+			}
+			cached := updateCacheEntries(folder, repo, config, ag.DocSources())
+			pr := prRun("update-docs", run, ag.Batch())
+			pr.Digests = repoPaths(repo, ag.ChangeDigests())
+			changed, err := repo.PendingDocs()
+			if err != nil {
+				return nil, fmt.Errorf("failed to check for changes: %w", err)
+			}
+			approved, err := approveDocChanges(repo, &pr)
+			if err != nil {
+				return nil, err
+			}
+			settled = settledDocs(folder, repo, requested, rejectedDocs(repo, changed))
+			if approved {
+				// Edited or rejected docs are left for the next run
+				cached = unchangedCacheEntries(folder, repo, config, cached)
+				snapshotRun(repo, run)
+				pr.Summary += checkDocSamples(ctx, repo) + assetChecklist(repo) + trackDocMetrics(repo, run) + archiveNote
+				fmt.Println("\nCreating pull request...")
+				if err := repo.CreatePR(pr); err != nil {
+					return nil, fmt.Errorf("failed to create PR: %w", err)
+				}
+				if useCache {
+					storeUpdateCache(cached)
+				}
 			}
 		} else {
 			fmt.Println("\nNo changes detected in documentation")
+			if useCache {
+				storeUpdateCache(updateCacheEntries(folder, repo, config, ag.DocSources()))
+			}
+			settled = requested
 		}
 	} else {
 		fmt.Printf("\nSome documents failed to process (%d/%d successful)\n", successCount, totalFiles)
+		if run != nil {
+			fmt.Println("Retry only the failed documents with:")
+			fmt.Printf("  docu-jarvis update-docs -resume %s\n", run.ID)
+		}
 	}
 
 	fmt.Println("\n✓ Documentation update completed!")
-	return nil
+	return settled, nil
 }
 
-func runWriteMode(ctx context.Context, folder string, repo *git.Repo, topics []string) error {
-	fmt.Printf("\n=== WRITE DOCUMENTATION MODE ===\n")
-	fmt.Printf("Topics to document: %v\n", topics)
+// rejectedDocs returns the docs among changed that no longer have changes,
+// as the review rejected them.
+func rejectedDocs(repo *git.Repo, changed []string) []string {
+	pending, err := repo.PendingDocs()
+	if err != nil {
+		// Keep every doc rather than lose one
+		return changed
+	}
+	stillPending := make(map[string]bool)
+	for _, doc := range pending {
+		stillPending[doc] = true
+	}
+	var rejected []string
+	for _, doc := range changed {
+		if !stillPending[doc] {
+			rejected = append(rejected, doc)
+		}
+	}
+	return rejected
+}
 
-	systemPrompt := system_prompts.DocumentationWrite
+// settledDocs returns the files, as given to update-docs, whose docs were
+// not rejected; rejected holds paths relative to the repository root.
+func settledDocs(folder string, repo *git.Repo, files, rejected []string) []string {
+	rejectedPaths := make(map[string]bool)
+	for _, doc := range rejected {
+		rejectedPaths[filepath.Join(repo.GetLocalPath(), filepath.FromSlash(doc))] = true
+	}
 
-	fmt.Println("\nInitializing agent...")
-	ag, err := agent.New(systemPrompt, folder)
-	if err != nil {
-		return fmt.Errorf("failed to create agent: %w", err)
+	var settled []string
+	for _, file := range files {
+		paths, err := resolveDocFiles(folder, repo.GetDocsDirs(), []string{file})
+		if err != nil {
+			continue
+		}
+		done := true
+		for _, path := range paths {
+			if rejectedPaths[filepath.Clean(path)] {
+				done = false
+			}
+		}
+		if done {
+			settled = append(settled, file)
+		}
 	}
+	return settled
+}
 
-	fmt.Println("Checking for existing documentation...")
-	matches, err := ag.CheckExistingDocs(ctx, topics)
+// skipCachedDocs drops the docs whose content and sources are unchanged since
+// update-docs last updated them with config from files. files is returned as
+// is when there are none, and otherwise as the remaining docs relative to
+// folder.
+func skipCachedDocs(folder string, repo *git.Repo, files []string, config string) ([]string, error) {
+	var docs []string
+	var err error
+	if len(files) == 1 && strings.ToLower(files[0]) == "all" {
+		docs, err = agent.FindDocs(repo.GetDocsDirs())
+	} else {
+		docs, err = resolveDocFiles(folder, repo.GetDocsDirs(), files)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to check existing docs: %w", err)
+		return nil, err
 	}
 
-	var topicsToWrite []string
-	var topicsToUpdate []string
-	var topicsToSkip []string
-
-	hasConflicts := false
-	for _, match := range matches {
-		if match.IsMatch {
-			hasConflicts = true
-			fmt.Printf("\nOH NO!!!!  Topic '%s' already documented in: %s\n", match.Topic, match.ExistingFile)
+	var remaining []string
+	for _, doc := range docs {
+		key, err := updateCacheKey(folder, repo, config, doc)
+		if err != nil || !cache.Fresh(key, folder) {
+			remaining = append(remaining, relDoc(folder, doc))
 		}
 	}
+	if len(remaining) == len(docs) {
+		return files, nil
+	}
+	fmt.Printf("Skipping %d of %d documents unchanged since their last update (use -no-cache to update them anyway)\n", len(docs)-len(remaining), len(docs))
+	return remaining, nil
+}
 
-	if hasConflicts {
-		fmt.Println("\nWhat would you like to do with existing documentation?")
-		fmt.Println("  1. Write new files (keep existing)")
-		fmt.Println("  2. Update existing files")
-		fmt.Println("  3. Skip existing topics")
-		fmt.Print("\nChoice (1/2/3): ")
+// updateCacheKey returns the cache key of doc as it is now: the configuration,
+// the repository, the doc's path in it, and its content.
+func updateCacheKey(folder string, repo *git.Repo, config, doc string) (string, error) {
+	content, err := os.ReadFile(doc)
+	if err != nil {
+		return "", err
+	}
+	origin, err := repo.GetRemoteURL()
+	if err != nil || origin == "" {
+		origin = repo.GetLocalPath()
+	}
+	path := filepath.ToSlash(filepath.Join(repo.GetScope(), relDoc(folder, doc)))
+	return cache.Key("update-docs", config, origin, path, string(content)), nil
+}
 
-		var choice string
-		fmt.Scanln(&choice)
+// updateCacheEntries returns the cache entries of the docs Claude read sources
+// for, by key. Docs without sources are not cached, as there is nothing to
+// tell when they are out of date.
+func updateCacheEntries(folder string, repo *git.Repo, config string, sources map[string][]string) map[string]cache.Entry {
+	entries := make(map[string]cache.Entry, len(sources))
+	now := time.Now().UTC().Truncate(time.Second)
+	for doc, files := range sources {
+		if len(files) == 0 {
+			continue
+		}
+		key, err := updateCacheKey(folder, repo, config, doc)
+		if err != nil {
+			continue
+		}
+		entry := cache.Entry{Doc: relDoc(folder, doc), Updated: now}
+		for _, file := range files {
+			entry.Sources = append(entry.Sources, relDoc(folder, file))
+		}
+		entry.SourcesHash = cache.HashSources(folder, entry.Sources)
+		entries[key] = entry
+	}
+	return entries
+}
 
-		for _, match := range matches {
-			if match.IsMatch {
-				switch choice {
-				case "1":
-					topicsToWrite = append(topicsToWrite, match.Topic)
-				case "2":
-					topicsToUpdate = append(topicsToUpdate, match.Topic)
-				case "3":
-					topicsToSkip = append(topicsToSkip, match.Topic)
-					fmt.Printf("  Skipping: %s\n", match.Topic)
-				default:
-					return fmt.Errorf("invalid choice: %s", choice)
-				}
+// unchangedCacheEntries returns the entries whose doc is still as it was when
+// they were made.
+func unchangedCacheEntries(folder string, repo *git.Repo, config string, entries map[string]cache.Entry) map[string]cache.Entry {
+	unchanged := make(map[string]cache.Entry, len(entries))
+	for key, entry := range entries {
+		if current, err := updateCacheKey(folder, repo, config, filepath.Join(folder, filepath.FromSlash(entry.Doc))); err == nil && current == key {
+			unchanged[key] = entry
+		}
+	}
+	return unchanged
+}
+
+func storeUpdateCache(entries map[string]cache.Entry) {
+	for key, entry := range entries {
+		if err := cache.Store(key, entry); err != nil {
+			fmt.Printf("Warning: failed to cache the update of %s: %v\n", entry.Doc, err)
+			return
+		}
+	}
+}
+
+// errCanaryStopped is returned by updateWithCanary when the remaining docs
+// were not updated after the canary report.
+var errCanaryStopped = errors.New("canary stopped")
+
+// canaryGrowth is how many times the lines its previous update changed a
+// canary doc's update may change before the report flags it.
+const canaryGrowth = 3
+
+// updateConfig describes the configuration update-docs runs with, so that a
+// canary report can tell what changed since an earlier run: the docu-jarvis
+// version, a digest of the prompt, and the model when one is configured.
+func updateConfig(customPrompt string) string {
+	prompt := system_prompts.DocumentationUpdate
+	if customPrompt != "" {
+		prompt = customPrompt
+	}
+	digest := sha256.Sum256([]byte(prompt))
+	config := fmt.Sprintf("docu-jarvis %s, prompt %x", updater.GetCurrentVersion(), digest[:4])
+	if s, err := settings.Load(); err == nil {
+		if model := s.Modes[agent.ModeUpdateDocs].Model; model != "" {
+			config += ", model " + model
+		}
+	}
+	return config
+}
+
+// updateWithCanary updates a fraction or list of the docs first, and shows
+// how their updates compare with those of the previous runs before asking to
+// update the rest with config. When the answer is no, the canary edits are undone and
+// errCanaryStopped is returned.
+func updateWithCanary(ctx context.Context, ag *agent.Agent, folder string, repo *git.Repo, files []string, spec, config string, run *runstate.Run) (int, int, error) {
+	var docs []string
+	var err error
+	if len(files) == 1 && strings.ToLower(files[0]) == "all" {
+		docs, err = agent.FindDocs(repo.GetDocsDirs())
+	} else {
+		docs, err = resolveDocFiles(folder, repo.GetDocsDirs(), files)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(docs) == 0 {
+		return 0, 0, fmt.Errorf("no .md or .mdx files found in: %s", strings.Join(repo.GetDocsDirs(), ", "))
+	}
+
+	previous := previousOutputs(repo, run)
+	picked, rest, err := pickCanary(folder, repo.GetDocsDirs(), docs, spec, previous)
+	if err != nil {
+		return 0, 0, err
+	}
+	fmt.Printf("Canary: updating %d of %d docs first to compare them with the previous runs\n", len(picked), len(docs))
+
+	canarySuccess, canaryTotal, err := ag.UpdateSpecificDocuments(ctx, picked)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to update the canary docs: %w", err)
+	}
+	flagged := printCanaryReport(repo, folder, config, picked, ag.Batch(), previous)
+
+	if flagged > 0 {
+		fmt.Printf("\nOH NO!!!!  %d canary docs look worse than after their previous update\n", flagged)
+	}
+	fmt.Printf("\nUpdate the remaining %d docs with this configuration? [y/N]: ", len(rest))
+	if answer := strings.ToLower(strings.TrimSpace(ask("n"))); answer != "y" && answer != "yes" {
+		var undo []string
+		for _, doc := range picked {
+			if rel, err := filepath.Rel(repo.GetLocalPath(), doc); err == nil {
+				undo = append(undo, filepath.ToSlash(rel))
+			}
+		}
+		repo.DiscardDocs(undo)
+		if run != nil {
+			if err := run.Reset(); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+		}
+		fmt.Println("Canary edits undone; no PR created. Run again without -canary to update every doc.")
+		return 0, 0, errCanaryStopped
+	}
+
+	fmt.Printf("\nUpdating the remaining %d docs...\n", len(rest))
+	restSuccess, restTotal, err := ag.UpdateSpecificDocuments(ctx, rest)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to update documents: %w", err)
+	}
+	return canarySuccess + restSuccess, canaryTotal + restTotal, nil
+}
+
+// previousOutput is what the latest earlier update-docs run on the repository
+// that updated a doc did to it.
+type previousOutput struct {
+	run    *runstate.Run
+	result *runstate.FileResult
+}
+
+// previousOutputs returns the previous output of each doc, by its path
+// relative to the folder, from the saved runs.
+func previousOutputs(repo *git.Repo, current *runstate.Run) map[string]previousOutput {
+	runs, err := runstate.List()
+	if err != nil {
+		fmt.Printf("Warning: the canary is not compared with earlier runs: %v\n", err)
+		return nil
+	}
+	repoURL, _ := repo.GetRemoteURL()
+
+	previous := make(map[string]previousOutput)
+	for _, run := range runs {
+		if (current != nil && run.ID == current.ID) || run.Command != "update-docs" || run.RepoURL != repoURL || run.Scope != repo.GetScope() {
+			continue
+		}
+		for file, result := range run.Files {
+			if _, found := previous[file]; !found && result.Status == runstate.Succeeded {
+				previous[file] = previousOutput{run: run, result: result}
+			}
+		}
+	}
+	return previous
+}
+
+// pickCanary splits docs into the canary docs and the rest. spec is a
+// fraction of the docs, as 0.1 or 10%, spread over the docs with a previous
+// output first; or a comma-separated list of docs.
+func pickCanary(folder string, docsDirs, docs []string, spec string, previous map[string]previousOutput) ([]string, []string, error) {
+	sort.Strings(docs)
+	var picked []string
+	if fraction, ok, err := parseFraction(spec); err != nil {
+		return nil, nil, err
+	} else if ok {
+		n := int(math.Ceil(fraction * float64(len(docs))))
+		var compared, others []string
+		for _, doc := range docs {
+			if _, found := previous[relDoc(folder, doc)]; found {
+				compared = append(compared, doc)
 			} else {
-				topicsToWrite = append(topicsToWrite, match.Topic)
+				others = append(others, doc)
+			}
+		}
+		picked = spread(compared, n)
+		picked = append(picked, spread(others, n-len(picked))...)
+	} else {
+		listed, err := resolveDocFiles(folder, docsDirs, parseTopics(spec))
+		if err != nil {
+			return nil, nil, err
+		}
+		valid := make(map[string]bool, len(docs))
+		for _, doc := range docs {
+			valid[filepath.Clean(doc)] = true
+		}
+		for _, doc := range listed {
+			if !valid[filepath.Clean(doc)] {
+				return nil, nil, fmt.Errorf("canary doc %s is not one of the docs to update", relDoc(folder, doc))
+			}
+			picked = append(picked, filepath.Clean(doc))
+		}
+	}
+
+	chosen := make(map[string]bool, len(picked))
+	for _, doc := range picked {
+		chosen[doc] = true
+	}
+	var rest []string
+	for _, doc := range docs {
+		if !chosen[filepath.Clean(doc)] {
+			rest = append(rest, doc)
+		}
+	}
+	if len(rest) == 0 {
+		return nil, nil, fmt.Errorf("-canary %s picks every doc to update; pick fewer, so that the rest waits for the report", spec)
+	}
+	return picked, rest, nil
+}
+
+// parseFraction parses a -canary fraction; ok is false when spec is not a
+// number, and so a list of docs.
+func parseFraction(spec string) (fraction float64, ok bool, err error) {
+	number, percent := strings.CutSuffix(strings.TrimSpace(spec), "%")
+	fraction, err = strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, false, nil
+	}
+	if percent {
+		fraction /= 100
+	}
+	if fraction <= 0 || fraction >= 1 {
+		return 0, false, fmt.Errorf("-canary %s must be a fraction of the docs between 0 and 1, or a percentage below 100%%", spec)
+	}
+	return fraction, true, nil
+}
+
+// spread picks n docs spaced evenly over docs, all of them when there are
+// fewer.
+func spread(docs []string, n int) []string {
+	if n >= len(docs) {
+		return docs
+	}
+	var picked []string
+	for i := 0; i < n; i++ {
+		picked = append(picked, docs[i*len(docs)/n])
+	}
+	return picked
+}
+
+func relDoc(folder, path string) string {
+	if rel, err := filepath.Rel(folder, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// printCanaryReport compares the update of each canary doc, made with config,
+// with what the previous run that updated it did, and flags the docs whose update failed,
+// shrank them or lost sections, or changed canaryGrowth times as many lines.
+// It returns how many docs were flagged.
+func printCanaryReport(repo *git.Repo, folder, config string, picked []string, items []agent.BatchItem, previous map[string]previousOutput) int {
+	fmt.Println("\n=== CANARY REPORT ===")
+	fmt.Printf("Configuration: %s\n", config)
+	fmt.Println(strings.Repeat("=", 70))
+
+	results := make(map[string]agent.BatchItem, len(items))
+	for _, item := range items {
+		results[item.Name] = item
+	}
+
+	flagged := 0
+	for _, doc := range picked {
+		name := relDoc(folder, doc)
+		file := filepath.ToSlash(filepath.Join(repo.GetScope(), name))
+		var problems []string
+		fmt.Printf("\n  %s\n", name)
+
+		prev, hadPrevious := previous[name]
+		prevLines := -1
+		if hadPrevious {
+			line := fmt.Sprintf("    previous: run %s", prev.run.ID)
+			if prev.run.Config != "" {
+				line += " (" + prev.run.Config + ")"
+			}
+			if prev.run.Changes != "" {
+				added, removed := diffStats(patchOf(prev.run.Changes, file))
+				prevLines = added + removed
+				line += fmt.Sprintf(", +%d -%d lines", added, removed)
+			}
+			if prev.result.Summary != "" {
+				line += ": " + prev.result.Summary
+			}
+			fmt.Println(line)
+		} else {
+			fmt.Println("    previous: none, no earlier run updated this doc")
+		}
+
+		item := results[name]
+		if item.Status != "succeeded" {
+			fmt.Printf("    canary:   ✗ failed: %s\n", item.Error)
+			flagged++
+			continue
+		}
+		diff, err := repo.DocDiff(file)
+		if err != nil {
+			fmt.Printf("    Warning: %v\n", err)
+			continue
+		}
+		added, removed := diffStats(diff)
+		line := fmt.Sprintf("    canary:   +%d -%d lines", added, removed)
+		if item.Summary != "" {
+			line += ": " + item.Summary
+		}
+		fmt.Println(line)
+
+		if before, err := repo.HeadVersion(file); err == nil && before != "" {
+			if after, err := os.ReadFile(doc); err == nil {
+				problems = append(problems, docmetrics.Regressions(docmetrics.Measure(before), docmetrics.Measure(string(after)))...)
+			}
+		}
+		if prevLines > 0 && added+removed >= canaryGrowth*prevLines {
+			problems = append(problems, fmt.Sprintf("changed %d lines, %dx as many as its previous update", added+removed, (added+removed)/prevLines))
+		}
+		for _, problem := range problems {
+			fmt.Printf("    Warning: %s\n", problem)
+		}
+		if len(problems) > 0 {
+			flagged++
+		}
+	}
+	fmt.Println(strings.Repeat("=", 70))
+	return flagged
+}
+
+// patchOf returns the part of a git patch that changes file.
+func patchOf(patch, file string) string {
+	var out []string
+	in := false
+	for _, line := range strings.Split(patch, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			in = strings.HasSuffix(line, " b/"+file)
+		}
+		if in {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// diffStats counts the added and removed lines of a unified diff.
+func diffStats(diff string) (added, removed int) {
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// promptVariant is one side of update-docs -compare.
+type promptVariant struct {
+	name   string // A or B
+	prompt string
+	source string // where the prompt came from
+	model  string // "" for the configured model
+}
+
+func (v promptVariant) String() string {
+	model := v.model
+	if model == "" {
+		model = "the configured model"
+	}
+	return fmt.Sprintf("%s, %s", v.source, model)
+}
+
+// loadVariant resolves the -prompt-a or -prompt-b value of variant name: the
+// update prompt when empty, an embedded prompt when it names one, or else a
+// file.
+func loadVariant(name, prompt, model string) (promptVariant, error) {
+	v := promptVariant{name: name, prompt: system_prompts.DocumentationUpdate, source: "the update prompt", model: model}
+	if prompt == "" {
+		return v, nil
+	}
+	if embedded := system_prompts.GetPrompt(prompt); embedded != "" {
+		v.prompt, v.source = embedded, "embedded "+prompt
+		return v, nil
+	}
+	content, err := os.ReadFile(prompt)
+	if err != nil {
+		return v, fmt.Errorf("failed to read the prompt of variant %s: %w", name, err)
+	}
+	v.prompt, v.source = string(content), prompt
+	return v, nil
+}
+
+// runCompareMode updates file with each variant in turn, restoring it after
+// each, then shows how their results differ and has Claude judge which is
+// better. The results are kept in a temporary directory; nothing is committed.
+func runCompareMode(ctx context.Context, folder string, repo *git.Repo, file string, variants []promptVariant) error {
+	fmt.Println("\n=== COMPARE PROMPTS MODE ===")
+	fmt.Println("The doc is restored after each variant and no PR will be created")
+	for _, v := range variants {
+		fmt.Printf("Variant %s: %s\n", v.name, v)
+	}
+
+	path := resolveDocFile(folder, repo.GetDocsDirs(), file)
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	name := relDoc(folder, path)
+
+	outDir, err := os.MkdirTemp("", "docu-jarvis-compare-")
+	if err != nil {
+		return fmt.Errorf("failed to create the output directory: %w", err)
+	}
+	stem := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	originalPath := filepath.Join(outDir, stem+".original"+filepath.Ext(path))
+	if err := os.WriteFile(originalPath, original, 0644); err != nil {
+		return fmt.Errorf("failed to save the original: %w", err)
+	}
+
+	outputs := make([]string, len(variants))
+	paths := make([]string, len(variants))
+	for i, v := range variants {
+		fmt.Printf("\nUpdating %s with variant %s...\n", name, v.name)
+		output, err := runVariant(ctx, folder, repo, path, original, v)
+		if err != nil {
+			return fmt.Errorf("variant %s failed: %w", v.name, err)
+		}
+		outputs[i] = output
+		paths[i] = filepath.Join(outDir, stem+"."+strings.ToLower(v.name)+filepath.Ext(path))
+		if err := os.WriteFile(paths[i], []byte(output), 0644); err != nil {
+			return fmt.Errorf("failed to save variant %s: %w", v.name, err)
+		}
+		diff, err := git.DiffFiles(originalPath, paths[i])
+		if err != nil {
+			return err
+		}
+		added, removed := diffStats(diff)
+		fmt.Printf("✓ Variant %s: +%d -%d lines\n", v.name, added, removed)
+	}
+
+	diff, err := git.DiffFiles(paths[0], paths[1])
+	if err != nil {
+		return err
+	}
+	fmt.Println("\n=== DIFF: A -> B ===")
+	if diff == "" {
+		fmt.Println("Both variants left the doc the same")
+	} else {
+		fmt.Println(diff)
+	}
+
+	judgement := ""
+	if diff != "" {
+		fmt.Println("\nJudging the variants...")
+		judge, err := agent.New(system_prompts.CompareJudge, folder)
+		if err != nil {
+			return fmt.Errorf("failed to create agent: %w", err)
+		}
+		judgement, err = judge.JudgeVariants(ctx, name, string(original), outputs[0], outputs[1])
+		if err != nil {
+			return fmt.Errorf("failed to judge the variants: %w", err)
+		}
+		fmt.Println("\n=== JUDGEMENT ===")
+		fmt.Println(judgement)
+	}
+	fmt.Println(strings.Repeat("=", 70))
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "# Prompt comparison: %s\n\n", name)
+	for _, v := range variants {
+		fmt.Fprintf(&report, "- Variant %s: %s\n", v.name, v)
+	}
+	fmt.Fprintf(&report, "\n## Diff: A -> B\n\n```diff\n%s\n```\n", diff)
+	if judgement != "" {
+		fmt.Fprintf(&report, "\n%s\n", judgement)
+	}
+	reportPath := filepath.Join(outDir, "report.md")
+	if err := os.WriteFile(reportPath, []byte(report.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write the report: %w", err)
+	}
+
+	fmt.Printf("\n✓ Comparison saved to %s\n", outDir)
+	fmt.Printf("  %s\n  %s\n  %s\n", filepath.Base(paths[0]), filepath.Base(paths[1]), filepath.Base(reportPath))
+	return nil
+}
+
+// runVariant updates path with v and returns the result, then puts back
+// original and undoes the variant's edits to any other doc. Failing to put
+// back original is an error, as the next variant would start from the edit.
+func runVariant(ctx context.Context, folder string, repo *git.Repo, path string, original []byte, v promptVariant) (_ string, err error) {
+	ag, err := agent.New(v.prompt, folder)
+	if err != nil {
+		return "", fmt.Errorf("failed to create agent: %w", err)
+	}
+	ag.SetModel(v.model)
+	ag.SetDocsDirs(repo.GetDocsDirs())
+	ag.SetEditRoots(repo.EditRoots())
+	ag.SetReviewerRules(reviewerRules(repo))
+
+	pending := make(map[string]bool)
+	docs, err := repo.PendingDocs()
+	if err != nil {
+		return "", fmt.Errorf("failed to check for changes: %w", err)
+	}
+	for _, doc := range docs {
+		pending[doc] = true
+	}
+	checkEdits, err := guardEdits(repo, false)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if restoreErr := os.WriteFile(path, original, 0644); restoreErr != nil {
+			if err == nil {
+				err = fmt.Errorf("failed to restore %s: %w", path, restoreErr)
+			} else {
+				fmt.Printf("Warning: failed to restore %s: %v\n", path, restoreErr)
+			}
+		}
+		if now, err := repo.PendingDocs(); err == nil {
+			var extra []string
+			for _, doc := range now {
+				if !pending[doc] && filepath.Join(repo.GetLocalPath(), doc) != path {
+					extra = append(extra, doc)
+				}
+			}
+			repo.DiscardDocs(extra)
+		}
+	}()
+
+	succeeded, _, err := ag.UpdateSpecificDocuments(ctx, []string{path})
+	if err != nil {
+		return "", err
+	}
+	if err := checkEdits(); err != nil {
+		return "", err
+	}
+	if succeeded == 0 {
+		for _, item := range ag.Batch() {
+			if item.Error != "" {
+				return "", errors.New(item.Error)
 			}
 		}
-	} else {
-		topicsToWrite = topics
+		return "", fmt.Errorf("the doc was not updated")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the result: %w", err)
+	}
+	return string(content), nil
+}
+
+// guardEdits records the working tree before Claude edits it. The returned
+// func reverts the edits outside the repository's edit allow-list and lists
+// them; it only checks once, so it can also be deferred for early returns.
+// Dry runs make no edits and are not checked.
+func guardEdits(repo *git.Repo, dryRun bool) (func() error, error) {
+	if dryRun {
+		return func() error { return nil }, nil
+	}
+	guard, err := repo.GuardEdits()
+	if err != nil {
+		return nil, fmt.Errorf("failed to record the working tree before editing: %w", err)
+	}
+
+	checked := false
+	return func() error {
+		if checked {
+			return nil
+		}
+		checked = true
+
+		return enforceEdits(repo, guard)
+	}, nil
+}
+
+// enforceEdits reverts the edits outside the allowed paths since guard was
+// started and lists them in a warning.
+func enforceEdits(repo *git.Repo, guard *git.EditGuard) error {
+	reverted, err := guard.Enforce()
+	if len(reverted) > 0 {
+		fmt.Printf("\nWarning: Claude edited %d files outside the allowed paths (%s), reverted them:\n", len(reverted), strings.Join(repo.EditAllowlist(), ", "))
+		for _, edit := range reverted {
+			fmt.Printf("  %s (%s)\n", edit.Path, edit.Change)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to revert edits outside the allowed paths: %w", err)
+	}
+	return nil
+}
+
+// writeBatchSummary writes the JSON summary of a batch run to summaryOut,
+// which is nil unless -output json was given.
+func writeBatchSummary(summaryOut io.Writer, command string, repo *git.Repo, run *runstate.Run, dryRun bool, items []agent.BatchItem) error {
+	if summaryOut == nil {
+		return nil
+	}
+
+	summary := agent.NewBatchSummary(command, items)
+	if repoURL, err := repo.GetRemoteURL(); err == nil {
+		summary.Repository = redact.String(repoURL)
+	}
+	if run != nil {
+		summary.RunID = run.ID
+	}
+	summary.DryRun = dryRun
+
+	encoder := json.NewEncoder(summaryOut)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(summary); err != nil {
+		return fmt.Errorf("failed to write summary: %w", err)
+	}
+	return nil
+}
+
+// prRun describes a batch run for the PR title and body templates.
+func prRun(command string, run *runstate.Run, items []agent.BatchItem) git.PRRun {
+	summary := agent.NewBatchSummary(command, items)
+	pr := git.PRRun{
+		Command:   command,
+		Succeeded: summary.Succeeded,
+		Failed:    summary.Failed,
+		Summary:   agent.MarkdownSummary(items),
+	}
+	if run != nil {
+		pr.RunID = run.ID
+	}
+	return pr
+}
+
+// repoPaths keys the digests, by doc relative to the scope, by their path
+// relative to the repository root.
+func repoPaths(repo *git.Repo, digests map[string]string) map[string]string {
+	byPath := make(map[string]string, len(digests))
+	for doc, digest := range digests {
+		byPath[filepath.ToSlash(filepath.Join(repo.GetScope(), doc))] = digest
+	}
+	return byPath
+}
+
+// markdownDocs returns the markdown files among the changed docs, which also
+// list the config files and images in the docs roots.
+func markdownDocs(docs []string) []string {
+	var markdown []string
+	for _, doc := range docs {
+		if agent.IsDocFile(doc) {
+			markdown = append(markdown, doc)
+		}
+	}
+	return markdown
+}
+
+// fixDocStructure fixes the markdown structure of the changed docs before
+// they are reviewed and committed: skipped heading levels, tables without a
+// proper delimiter row, and unclosed code blocks. It fails when problems
+// without a sure fix are left, such as broken front matter, so that docs
+// that would not render never reach the PR.
+func fixDocStructure(repo *git.Repo) error {
+	fmt.Println("\nChecking markdown structure of the changed documentation...")
+	docs, err := repo.ChangedDocs()
+	if err != nil {
+		return fmt.Errorf("failed to list changed docs: %w", err)
+	}
+	fixed, problems, err := mdlint.FixFiles(repo.GetLocalPath(), markdownDocs(docs))
+	if err != nil {
+		return err
+	}
+	for _, file := range fixed {
+		fmt.Printf("✓ Fixed the markdown structure of %s\n", file)
+	}
+	if len(problems) == 0 {
+		fmt.Printf("✓ %d documents checked\n", len(docs))
+		return nil
+	}
+
+	fmt.Printf("OH NO!!!!  %d markdown problems could not be fixed automatically:\n", len(problems))
+	for _, problem := range problems {
+		fmt.Printf("  ✗ %s\n", problem)
+	}
+	return fmt.Errorf("the changed docs would not render correctly; fix them in %s and run again, no PR was created", repo.GetLocalPath())
+}
+
+// checkDocSamples checks the Go, Python, and shell samples of the changed
+// docs before the PR is opened. Broken samples are listed, and returned as a
+// note for the PR body so reviewers see them; it returns "" when there are none.
+func checkDocSamples(ctx context.Context, repo *git.Repo) string {
+	fmt.Println("\nChecking code samples in the changed documentation...")
+	docs, err := repo.ChangedDocs()
+	if err != nil {
+		fmt.Printf("Warning: failed to check code samples: %v\n", err)
+		return ""
+	}
+	blocks, err := samples.ExtractFiles(repo.GetLocalPath(), docs)
+	if err != nil {
+		fmt.Printf("Warning: failed to check code samples: %v\n", err)
+		return ""
+	}
+
+	report := samples.Check(ctx, blocks)
+	for _, lang := range report.Skipped {
+		fmt.Printf("Warning: %s samples were not checked, as the tools to check them are not installed\n", lang)
+	}
+	if len(report.Problems) == 0 {
+		fmt.Printf("✓ %d code samples checked\n", report.Checked)
+		return ""
+	}
+
+	fmt.Printf("OH NO!!!!  %d of %d code samples look broken:\n", len(report.Problems), report.Checked)
+	var note strings.Builder
+	note.WriteString("\n\n**Code samples that failed to check** (please fix before merging):\n")
+	for _, problem := range report.Problems {
+		fmt.Printf("  ✗ %s\n", problem)
+		fmt.Fprintf(&note, "- `%s:%d` (%s): %s\n", problem.File, problem.Line, problem.Language, problem.Message)
+	}
+	return strings.TrimSuffix(note.String(), "\n")
+}
+
+// stampFreshness stamps each doc Claude changed with the source files Claude
+// read for it and the commit they were read at, for stale-docs. A doc Claude
+// read no sources for keeps those of its previous stamp. Docs Claude left
+// alone keep their stamp, so a run without changes leaves no diff.
+func stampFreshness(folder string, repo *git.Repo, sources map[string][]string) {
+	if len(sources) == 0 {
+		return
+	}
+	commit, err := repo.HeadCommit()
+	if err != nil {
+		fmt.Printf("Warning: failed to stamp the docs with their sources: %v\n", err)
+		return
+	}
+	pending, err := repo.PendingDocs()
+	if err != nil {
+		fmt.Printf("Warning: failed to stamp the docs with their sources: %v\n", err)
+		return
+	}
+	changed := make(map[string]bool, len(pending))
+	for _, doc := range pending {
+		changed[doc] = true
+	}
+	repoPath := func(path string) string {
+		return filepath.ToSlash(filepath.Join(repo.GetScope(), relDoc(folder, path)))
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	stamped := 0
+	for doc, files := range sources {
+		content, err := os.ReadFile(doc)
+		if err != nil {
+			continue
+		}
+		if !changed[repoPath(doc)] {
+			continue
+		}
+		previous, _ := freshness.Read(string(content))
+
+		stamp := freshness.Stamp{Commit: commit, GeneratedAt: now}
+		for _, file := range files {
+			stamp.Sources = append(stamp.Sources, repoPath(file))
+		}
+		if len(stamp.Sources) == 0 {
+			stamp.Sources = previous.Sources
+		}
+		if err := os.WriteFile(doc, []byte(freshness.Apply(string(content), stamp)), 0644); err != nil {
+			fmt.Printf("Warning: failed to stamp %s with its sources: %v\n", relDoc(folder, doc), err)
+			continue
+		}
+		stamped++
+	}
+	if stamped > 0 {
+		fmt.Printf("✓ Stamped %d docs with the sources they cover\n", stamped)
+	}
+}
+
+// trackDocMetrics measures the changed docs before and after the update,
+// shows how they changed and their word counts over past runs, and saves the
+// measures for later runs. Docs that shrank drastically or lost sections are
+// returned as a note for the PR body; it returns "" when there are none.
+func trackDocMetrics(repo *git.Repo, run *runstate.Run) string {
+	docs, err := repo.ChangedDocs()
+	// Images and other assets under the docs roots have no metrics
+	docs = markdownDocs(docs)
+	if err != nil || len(docs) == 0 {
+		return ""
+	}
+	verified, err := repo.LastChanged(docs)
+	if err != nil {
+		fmt.Printf("Warning: failed to read when the docs were last verified: %v\n", err)
+	}
+
+	snapshot := docmetrics.Snapshot{Time: time.Now(), Before: make(map[string]docmetrics.Metrics), After: make(map[string]docmetrics.Metrics)}
+	if run != nil {
+		snapshot.RunID = run.ID
+	}
+	for _, doc := range docs {
+		before, err := repo.HeadVersion(doc)
+		if err != nil {
+			fmt.Printf("Warning: failed to measure the docs: %v\n", err)
+			return ""
+		}
+		after, err := os.ReadFile(filepath.Join(repo.GetLocalPath(), doc))
+		if err != nil {
+			fmt.Printf("Warning: failed to measure the docs: %v\n", err)
+			return ""
+		}
+		if before != "" {
+			m := docmetrics.Measure(before)
+			m.Verified = verified[doc]
+			snapshot.Before[doc] = m
+		}
+		snapshot.After[doc] = docmetrics.Measure(string(after))
+	}
+
+	repoURL, _ := repo.GetRemoteURL()
+	store, err := docmetrics.Load()
+	if err != nil {
+		fmt.Printf("Warning: doc metrics of past runs are not shown or saved: %v\n", err)
+	} else {
+		store.Record(repoURL, snapshot)
+		if err := store.Save(); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+
+	fmt.Println("\nDocumentation metrics (before -> after):")
+	var note strings.Builder
+	for _, doc := range docs {
+		before, existed := snapshot.Before[doc]
+		after := snapshot.After[doc]
+		if !existed {
+			fmt.Printf("  %s: new, %d words, %d sections, %d code samples\n", doc, after.Words, after.Sections, after.CodeSamples)
+			continue
+		}
+		line := fmt.Sprintf("  %s: %d -> %d words, %d -> %d sections, %d -> %d code samples", doc, before.Words, after.Words, before.Sections, after.Sections, before.CodeSamples, after.CodeSamples)
+		if !before.Verified.IsZero() {
+			line += fmt.Sprintf(", last verified %d days ago", int(time.Since(before.Verified).Hours()/24))
+		}
+		fmt.Println(line)
+		if store != nil {
+			if trend := store.Trend(repoURL, doc); len(trend) > 2 {
+				words := make([]string, len(trend))
+				for i, n := range trend {
+					words[i] = strconv.Itoa(n)
+				}
+				fmt.Printf("    words over the last %d runs: %s\n", len(trend)-1, strings.Join(words, " -> "))
+			}
+		}
+
+		for _, regression := range docmetrics.Regressions(before, after) {
+			fmt.Printf("Warning: %s %s\n", doc, regression)
+			fmt.Fprintf(&note, "- `%s` %s\n", doc, regression)
+		}
+	}
+	if note.Len() == 0 {
+		return ""
+	}
+	return "\n\n**Quality regressions** (these docs shrank drastically or lost sections in this update; check nothing was dropped by mistake):\n" + strings.TrimSuffix(note.String(), "\n")
+}
+
+// assetChecklist returns the screenshots the changed docs need, and the images
+// they reference that do not exist, as a checklist for the PR body; it
+// returns "" when there are none.
+func assetChecklist(repo *git.Repo) string {
+	docs, err := repo.ChangedDocs()
+	if err != nil {
+		fmt.Printf("Warning: failed to check images: %v\n", err)
+		return ""
+	}
+	report, err := assets.Scan(repo.GetLocalPath(), repo.GetDocsRoots(), docs)
+	if err != nil {
+		fmt.Printf("Warning: failed to check images: %v\n", err)
+		return ""
+	}
+
+	pending := report.Pending()
+	if len(pending) == 0 && len(report.Missing) == 0 {
+		return ""
+	}
+	fmt.Printf("Screenshots to add: %d, missing images: %d (listed in the PR)\n", len(pending), len(report.Missing))
+
+	var note strings.Builder
+	note.WriteString("\n\n**Assets checklist:**\n")
+	for _, p := range pending {
+		fmt.Fprintf(&note, "- [ ] Add `%s`", p.Asset)
+		if p.Description != "" {
+			fmt.Fprintf(&note, ": %s", p.Description)
+		}
+		fmt.Fprintf(&note, " (`%s:%d`)\n", p.File, p.Line)
+	}
+	for _, ref := range report.Missing {
+		fmt.Fprintf(&note, "- [ ] Add `%s`, referenced by `%s:%d`\n", ref.Path, ref.File, ref.Line)
+	}
+	return strings.TrimSuffix(note.String(), "\n")
+}
+
+// archiveProposals lists the docs Claude found describing removed features,
+// with the command that archives them, and returns them as a note for the PR
+// body; it returns "" when there are none.
+func archiveProposals(proposals []agent.ArchiveProposal) string {
+	if len(proposals) == 0 {
+		return ""
+	}
+
+	fmt.Printf("\n%d docs describe features that were removed and should be archived:\n", len(proposals))
+	var files []string
+	var note strings.Builder
+	note.WriteString("\n\n**Proposed for archiving** (these docs describe removed features and were left unchanged):\n")
+	for _, p := range proposals {
+		fmt.Printf("  - %s: %s\n", p.File, p.Reason)
+		fmt.Fprintf(&note, "- `%s`: %s\n", p.File, p.Reason)
+		files = append(files, p.File)
+	}
+	command := fmt.Sprintf("docu-jarvis archive-docs %q", strings.Join(files, ","))
+	fmt.Println("Archive them with:")
+	fmt.Printf("  %s\n", command)
+	fmt.Fprintf(&note, "\nArchive them with `%s`.", command)
+	return note.String()
+}
+
+// anchorRedirects lists the anchors kept for renamed or removed headings as a
+// note for the PR body; it returns "" when there are none.
+func anchorRedirects(redirects []agent.AnchorRedirect) string {
+	if len(redirects) == 0 {
+		return ""
+	}
+	var note strings.Builder
+	note.WriteString("\n\n**Anchors kept** (these headings were renamed or removed; an HTML anchor keeps their links working):\n")
+	for _, r := range redirects {
+		fmt.Fprintf(&note, "- `%s#%s` now leads to `#%s`\n", r.File, r.From, r.To)
+	}
+	return strings.TrimSuffix(note.String(), "\n")
+}
+
+// runArchiveDocsMode moves the docs to the archive folder of their docs root
+// with a deprecation banner, points the links and index entries to them at the
+// new location, and opens a PR.
+func runArchiveDocsMode(folder string, repo *git.Repo, files []string, reason string, dryRun bool) error {
+	fmt.Println("\n=== ARCHIVE DOCS MODE ===")
+	if dryRun {
+		fmt.Println("Dry run: no files will be modified and no PR will be created")
+	}
+
+	paths, err := resolveDocFiles(folder, repo.GetDocsDirs(), files)
+	if err != nil {
+		return err
+	}
+	var docs []string
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("file does not exist: %s", path)
+		}
+		rel, err := filepath.Rel(repo.GetLocalPath(), path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		docs = append(docs, filepath.ToSlash(rel))
+	}
+
+	moves, err := archive.Plan(repo.GetDocsRoots(), docs, reason)
+	if err != nil {
+		return err
+	}
+	result, err := archive.Apply(repo.GetLocalPath(), repo.GetDocsRoots(), moves, time.Now(), dryRun)
+	if err != nil {
+		return fmt.Errorf("failed to archive docs: %w", err)
+	}
+
+	verb := "Archived"
+	if dryRun {
+		verb = "Would archive"
+	}
+	var summary strings.Builder
+	fmt.Printf("\n%s %d docs:\n", verb, len(result.Moved))
+	for _, move := range result.Moved {
+		fmt.Printf("  ✓ %s → %s\n", move.File, move.To)
+		fmt.Fprintf(&summary, "- `%s` → `%s`\n", move.File, move.To)
+	}
+	if len(result.Links) > 0 {
+		updated := make([]string, 0, len(result.Links))
+		for file := range result.Links {
+			updated = append(updated, file)
+		}
+		sort.Strings(updated)
+		fmt.Println("\nLinks updated:")
+		for _, file := range updated {
+			fmt.Printf("  - %s (%d)\n", file, result.Links[file])
+		}
+	}
+	for _, index := range result.Indexes {
+		fmt.Printf("  - %s: entries moved to its Archived section\n", index)
+	}
+
+	if dryRun {
+		fmt.Println("\nDry run complete")
+		return nil
+	}
+
+	pr := git.PRRun{Command: "archive-docs", Succeeded: len(result.Moved), Summary: strings.TrimSuffix(summary.String(), "\n")}
+	if reason != "" {
+		pr.Summary += "\n\nReason: " + reason
+	}
+	fmt.Println("\nCreating pull request...")
+	if err := repo.CreatePR(pr); err != nil {
+		return fmt.Errorf("failed to create PR: %w", err)
+	}
+
+	fmt.Println("\n✓ Docs archived!")
+	return nil
+}
+
+// staleDoc is a stamped doc whose sources changed since it was generated.
+type staleDoc struct {
+	name    string // relative to the working folder
+	stamp   freshness.Stamp
+	changes *git.SourceChanges
+}
+
+// runStaleDocsMode lists the docs whose sources, as recorded in their
+// freshness stamps, changed since they were generated. With strict, having
+// any fails, for CI.
+func runStaleDocsMode(folder string, repo *git.Repo, strict bool) error {
+	fmt.Println("\n=== STALE DOCS MODE ===")
+	fmt.Printf("Docs directories: %s\n", strings.Join(repo.GetDocsRoots(), ", "))
+
+	paths, err := agent.FindDocs(repo.GetDocsDirs())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Checking %d documents...\n", len(paths))
+
+	stamps := make(map[string]freshness.Stamp)
+	var unstamped []string
+	var oldest time.Time
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", relDoc(folder, path), err)
+		}
+		stamp, ok := freshness.Read(string(content))
+		if !ok {
+			unstamped = append(unstamped, relDoc(folder, path))
+			continue
+		}
+		stamps[path] = stamp
+		if oldest.IsZero() || stamp.GeneratedAt.Before(oldest) {
+			oldest = stamp.GeneratedAt
+		}
+	}
+	if len(stamps) > 0 {
+		if err := repo.EnsureHistorySince(dates.Git(oldest)); err != nil {
+			return err
+		}
+	}
+
+	var stale []staleDoc
+	fresh := 0
+	for _, path := range paths {
+		stamp, ok := stamps[path]
+		if !ok {
+			continue
+		}
+		changes, err := repo.SourceChangesSince(stamp.Commit, stamp.GeneratedAt, stamp.Sources)
+		if err != nil {
+			return fmt.Errorf("failed to check %s: %w", relDoc(folder, path), err)
+		}
+		if len(changes.Files) == 0 {
+			fresh++
+			continue
+		}
+		stale = append(stale, staleDoc{name: relDoc(folder, path), stamp: stamp, changes: changes})
+	}
+
+	if len(stale) > 0 {
+		fmt.Printf("\nStale: %d\n", len(stale))
+		for _, doc := range stale {
+			fmt.Printf("  ✗ %s, generated %s from %s\n", doc.name, doc.stamp.GeneratedAt.Local().Format("2006-01-02"), shortHash(doc.stamp.Commit))
+			fmt.Printf("      %d commits changed %d of its %d sources since", len(doc.changes.Commits), len(doc.changes.Files), len(doc.stamp.Sources))
+			if doc.changes.ByDate {
+				fmt.Print(" it was generated (its commit is not in this branch's history)")
+			}
+			fmt.Println(":")
+			for _, file := range doc.changes.Files {
+				fmt.Printf("        %s\n", file)
+			}
+			latest := doc.changes.Commits[0]
+			fmt.Printf("      latest: %s %s\n", shortHash(latest.Hash), latest.Subject)
+		}
+	}
+	fmt.Printf("\nFresh: %d\n", fresh)
+	if len(unstamped) > 0 {
+		fmt.Printf("Not stamped: %d (update-docs and write-docs stamp the docs they change)\n", len(unstamped))
+		for _, name := range unstamped {
+			fmt.Printf("  · %s\n", name)
+		}
+	}
+
+	if len(stale) > 0 {
+		names := make([]string, len(stale))
+		for i, doc := range stale {
+			names[i] = doc.name
+		}
+		fmt.Println("\nUpdate the stale docs with:")
+		fmt.Printf("  docu-jarvis update-docs %s\n", strings.Join(names, ","))
+		if strict {
+			return fmt.Errorf("%d docs are stale", len(stale))
+		}
+	}
+	fmt.Println("\n✓ Stale docs check completed!")
+	return nil
+}
+
+// runEvalMode runs the scenarios of a suite and scores them. Without a
+// baseline it fails when any check fails; with one, only when a check that
+// passed in the baseline fails now.
+func runEvalMode(ctx context.Context, suite string, names []string, model, baselinePath, savePath string) error {
+	fmt.Println("\n=== EVAL MODE ===")
+	fmt.Printf("Suite: %s\n", suite)
+	if model != "" {
+		fmt.Printf("Model: %s\n", model)
+	}
+
+	var baseline *eval.Report
+	if baselinePath != "" {
+		var err error
+		if baseline, err = eval.LoadReport(baselinePath); err != nil {
+			return err
+		}
+		fmt.Printf("Baseline: %s (docu-jarvis %s, %s)\n", baselinePath, baseline.Version, baseline.Time.Local().Format("2006-01-02 15:04"))
+	}
+
+	scenarios, err := eval.Load(suite, names)
+	if err != nil {
+		return err
+	}
+	if len(scenarios) == 0 {
+		return fmt.Errorf("no scenarios in %s (each needs a directory with a %s)", suite, eval.ScenarioFileName)
+	}
+	fmt.Printf("Running %d scenarios...\n", len(scenarios))
+
+	report := &eval.Report{Time: time.Now().UTC(), Version: updater.GetCurrentVersion(), Model: model}
+	for _, s := range scenarios {
+		fmt.Printf("\n--- %s (%s) ---\n", s.Name, s.Kind)
+		result, err := eval.Run(ctx, s, eval.Options{Model: model})
+		if err != nil {
+			return fmt.Errorf("scenario %s failed to run: %w", s.Name, err)
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Println("EVAL RESULTS")
+	fmt.Println(strings.Repeat("=", 70))
+	for _, result := range report.Results {
+		passed, total := result.Score()
+		fmt.Printf("\n%s  %d/%d\n", result.Scenario, passed, total)
+		for _, c := range result.Checks {
+			if c.Passed {
+				fmt.Printf("  ✓ %s\n", c.Name)
+			} else {
+				fmt.Printf("  ✗ %s: %s\n", c.Name, c.Detail)
+			}
+		}
+	}
+	passed, total := report.Score()
+	fmt.Printf("\nScore: %d/%d checks passed\n", passed, total)
+	fmt.Println(strings.Repeat("=", 70))
+
+	if savePath != "" {
+		if err := report.Save(savePath); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Report saved to %s\n", savePath)
+	}
+
+	if baseline != nil {
+		regressions := eval.Regressions(baseline, report)
+		if len(regressions) > 0 {
+			fmt.Println("\nRegressions since the baseline:")
+			count := 0
+			for _, result := range report.Results {
+				for _, name := range regressions[result.Scenario] {
+					fmt.Printf("  ✗ %s: %s\n", result.Scenario, name)
+					count++
+				}
+			}
+			return fmt.Errorf("%d checks regressed since the baseline", count)
+		}
+		basePassed, baseTotal := baseline.Score()
+		fmt.Printf("\nNo regressions (baseline scored %d/%d)\n", basePassed, baseTotal)
+	} else if passed < total {
+		return fmt.Errorf("%d of %d checks failed", total-passed, total)
+	}
+	fmt.Println("\n✓ Eval completed!")
+	return nil
+}
+
+// runCheckAssetsMode checks that every image the docs reference exists, and
+// lists the screenshot placeholders that still need an image, or that can be
+// replaced by the image now in the assets folder.
+func runCheckAssetsMode(repo *git.Repo, strict bool) error {
+	fmt.Println("\n=== CHECK ASSETS MODE ===")
+	fmt.Printf("Docs directories: %s\n", strings.Join(repo.GetDocsRoots(), ", "))
+
+	paths, err := agent.FindDocs(repo.GetDocsDirs())
+	if err != nil {
+		return err
+	}
+	var docs []string
+	for _, path := range paths {
+		rel, err := filepath.Rel(repo.GetLocalPath(), path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		docs = append(docs, filepath.ToSlash(rel))
+	}
+	fmt.Printf("Scanning %d documents...\n", len(docs))
+
+	report, err := assets.Scan(repo.GetLocalPath(), repo.GetDocsRoots(), docs)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nImages referenced: %d, missing: %d\n", report.References, len(report.Missing))
+	for _, ref := range report.Missing {
+		fmt.Printf("  ✗ %s:%d: %s not found\n", ref.File, ref.Line, ref.Path)
+	}
+
+	pending := report.Pending()
+	fmt.Printf("\nScreenshot placeholders: %d, without an image: %d\n", len(report.Placeholders), len(pending))
+	for _, p := range report.Placeholders {
+		if p.Captured {
+			fmt.Printf("  ✓ %s:%d: %s exists, replace the placeholder with ![%s](%s)\n", p.File, p.Line, p.Asset, p.Description, relativeTo(p.File, p.Asset))
+			continue
+		}
+		fmt.Printf("  · %s:%d: add %s", p.File, p.Line, p.Asset)
+		if p.Description != "" {
+			fmt.Printf(" (%s)", p.Description)
+		}
+		fmt.Println()
+	}
+
+	if len(report.Missing) > 0 {
+		return fmt.Errorf("%d referenced images are missing", len(report.Missing))
+	}
+	if strict && len(pending) > 0 {
+		return fmt.Errorf("%d screenshot placeholders have no image", len(pending))
+	}
+	fmt.Println("\n✓ Assets check completed!")
+	return nil
+}
+
+// runExportSiteMode writes the MkDocs or Docusaurus site of the first docs
+// root, and opens a PR with it when openPR is set.
+func runExportSiteMode(repo *git.Repo, format, name string, force, dryRun, openPR bool) error {
+	fmt.Println("\n=== EXPORT SITE MODE ===")
+	roots := repo.GetDocsRoots()
+	root := roots[0]
+	if len(roots) > 1 {
+		fmt.Printf("Warning: exporting %s only; choose another docs root with -docs-dir\n", root)
+	}
+	if name == "" {
+		name = filepath.Base(repo.GetLocalPath())
+	}
+	fmt.Printf("Format: %s\n", format)
+	fmt.Printf("Docs directory: %s\n", root)
+
+	paths, err := agent.FindDocs([]string{filepath.Join(repo.GetLocalPath(), root)})
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no documentation files found in %s", root)
+	}
+	docs := make([]string, 0, len(paths))
+	for _, path := range paths {
+		rel, err := filepath.Rel(repo.GetLocalPath(), path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		docs = append(docs, rel)
+	}
+
+	files, err := site.Build(repo.GetLocalPath(), site.Options{Format: format, Name: name, DocsRoot: root, Docs: docs, Force: force})
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Println("\n✓ The site is up to date")
+		return nil
+	}
+
+	verb := "Writing"
+	if dryRun {
+		verb = "Would write"
+	}
+	var summary strings.Builder
+	fmt.Printf("\n%s %d files:\n", verb, len(files))
+	for _, file := range files {
+		change := "new"
+		if file.Exists {
+			change = "updated"
+		}
+		fmt.Printf("  ✓ %s (%s)\n", file.Path, change)
+		fmt.Fprintf(&summary, "- `%s` (%s)\n", file.Path, change)
+	}
+	if dryRun {
+		fmt.Println("\nDry run complete")
+		return nil
+	}
+	if err := site.Write(repo.GetLocalPath(), files); err != nil {
+		return err
+	}
+
+	build := "pip install mkdocs && mkdocs serve"
+	extra := "mkdocs.yml"
+	if format == site.FormatDocusaurus {
+		build = "cd website && npm install && npm start"
+		extra = "website"
+	}
+
+	if openPR {
+		pr := git.PRRun{
+			Command:   "export-site",
+			Succeeded: len(docs),
+			Summary:   strings.TrimSuffix(summary.String(), "\n") + "\n\nTo preview the site: `" + build + "`",
+			Paths:     []string{extra},
+		}
+		fmt.Println("\nCreating pull request...")
+		if err := repo.CreatePR(pr); err != nil {
+			return fmt.Errorf("failed to create PR: %w", err)
+		}
+	} else {
+		fmt.Printf("\nTo preview the site: %s\n", build)
+	}
+
+	fmt.Println("\n✓ Site exported!")
+	return nil
+}
+
+// relativeTo returns target as a link from the document file.
+func relativeTo(file, target string) string {
+	rel, err := filepath.Rel(filepath.Dir(file), target)
+	if err != nil {
+		return target
+	}
+	return filepath.ToSlash(rel)
+}
+
+// startUpdateRun saves the state of a new update-docs run, with what is needed
+// to check the repository out the same way again on -resume.
+func startUpdateRun(repo *git.Repo, customPrompt string) (*runstate.Run, error) {
+	run, err := runstate.New("update-docs")
+	if err != nil {
+		return nil, err
+	}
+
+	run.RepoURL, _ = repo.GetRemoteURL()
+	run.LocalPath = repo.GetLocalPath()
+	run.Local = repo.IsLocal()
+	run.Scope = repo.GetScope()
+	run.Branch = repo.GetBranch()
+	run.DocsRoots = repo.GetDocsRoots()
+	run.CustomPrompt = customPrompt
+	run.Config = updateConfig(customPrompt)
+	run.BaseCommit, _ = repo.HeadCommit()
+	if err := run.Save(); err != nil {
+		return nil, err
+	}
+
+	return run, repo.MarkRun(run.ID)
+}
+
+// runResumeMode updates the documents of a previous run that failed or never
+// finished. The run's checkout is reused when no later run has touched it, as
+// it still holds the edits of the documents that succeeded; otherwise the
+// repository is cloned again and every document is updated.
+func runResumeMode(ctx context.Context, id string, confirmEdits bool, pr git.PROptions, batch agent.BatchOptions, summaryOut io.Writer) error {
+	run, err := runstate.Load(id)
+	if err != nil {
+		return err
+	}
+
+	files := run.Remaining()
+	if len(files) == 0 {
+		fmt.Printf("Run %s has no failed or pending documents\n", run.ID)
+		return nil
+	}
+
+	fmt.Printf("\n=== RESUMING RUN %s ===\n", run.ID)
+
+	intact := false
+	if checkout, err := git.OpenLocal(run.LocalPath); err == nil {
+		intact = checkout.MarkedRun() == run.ID
+	}
+
+	localPath, repoSel := run.LocalPath, ""
+	if !intact {
+		fmt.Println("OH NO!!!!  The run's checkout is gone or was reused by a later run, so the")
+		fmt.Println("edits of the documents that succeeded are lost. Updating every document again.")
+		if err := run.Reset(); err != nil {
+			return err
+		}
+		files = run.Remaining()
+		if !run.Local {
+			localPath, repoSel = "", run.RepoURL
+		}
+	}
+	fmt.Printf("Documents to update: %s\n", strings.Join(files, ", "))
+
+	repo, folder, err := prepareRepo(localPath, repoSel, run.Scope, run.Branch)
+	if err != nil {
+		return err
+	}
+	if len(run.DocsRoots) > 0 {
+		repo.SetDocsRoots(run.DocsRoots)
+	}
+	repo.SetPROptions(pr)
+	if !intact {
+		run.BaseCommit, _ = repo.HeadCommit()
+	}
+
+	_, err = runUpdateMode(ctx, folder, repo, files, run.CustomPrompt, "", false, confirmEdits, false, run, batch, summaryOut)
+	return err
+}
+
+// approveDocChanges shows the diff of each changed doc before the PR is
+// opened, to accept, reject, or edit it. Rejected changes are undone and
+// dropped from pr. It reports whether any changes are left for the PR;
+// with -auto-approve, all of them are.
+func approveDocChanges(repo *git.Repo, pr *git.PRRun) (bool, error) {
+	if repo.GetPROptions().AutoApprove {
+		return true, nil
+	}
+	files, err := repo.PendingDocs()
+	if err != nil {
+		return false, err
+	}
+
+	fmt.Println("\n=== REVIEW CHANGES ===")
+	fmt.Printf("%d changed documentation files. Only the accepted ones go into the PR.\n", len(files))
+
+	var accepted, rejected []string
+	for i, file := range files {
+		invalid := 0
+		for decided := false; !decided; {
+			diff, err := repo.DocDiff(file)
+			if err != nil {
+				return false, err
+			}
+			if diff == "" {
+				fmt.Printf("\n[%d/%d] %s: no changes left\n", i+1, len(files), file)
+				break
+			}
+			fmt.Printf("\n[%d/%d] %s\n", i+1, len(files), file)
+			fmt.Println(strings.Repeat("=", 70))
+			fmt.Println(diff)
+			fmt.Println(strings.Repeat("=", 70))
+			fmt.Print("Accept, reject, or edit this change? [a/r/e]: ")
+
+			switch strings.ToLower(strings.TrimSpace(ask("a"))) {
+			case "a", "accept", "y", "yes":
+				accepted = append(accepted, file)
+				decided = true
+			case "r", "reject", "n", "no":
+				rejected = append(rejected, file)
+				decided = true
+			case "e", "edit":
+				path := filepath.Join(repo.GetLocalPath(), file)
+				if _, err := os.Stat(path); err != nil {
+					fmt.Println("The change deletes the file, there is nothing to edit")
+					continue
+				}
+				if err := settings.EditFile(path); err != nil {
+					fmt.Printf("Warning: %v\n", err)
+				}
+			default:
+				// Without a terminal the answers run out
+				if invalid++; invalid == 3 {
+					return false, fmt.Errorf("no answer to the review of %s; use -auto-approve to open the PR without reviewing", file)
+				}
+				fmt.Println("Please answer a, r, or e")
+			}
+		}
+	}
+
+	repo.DiscardDocs(rejected)
+	for _, file := range rejected {
+		delete(pr.Digests, file)
+	}
+	fmt.Printf("\n✓ %d changes accepted, %d rejected\n", len(accepted), len(rejected))
+	if len(accepted) == 0 {
+		fmt.Println("No changes accepted, no PR will be created")
+		return false, nil
+	}
+	return true, nil
+}
+
+// snapshotRun saves the docs changes of a run before its PR is opened, so
+// rollback-run can revert them after the PR is merged.
+func snapshotRun(repo *git.Repo, run *runstate.Run) {
+	if run == nil || run.BaseCommit == "" {
+		return
+	}
+	changes, err := repo.DocsChanges(run.BaseCommit)
+	if err == nil {
+		run.Changes = changes
+		err = run.Save()
+	}
+	if err != nil {
+		fmt.Printf("Warning: failed to save the run's changes, it cannot be rolled back: %v\n", err)
+	}
+}
+
+// runRollbackMode opens a PR that reverts the docs changes of a run, for when
+// its generated content turns out to be wrong after the PR was merged.
+func runRollbackMode(id string, dryRun bool, pr git.PROptions) error {
+	run, err := runstate.Load(id)
+	if err != nil {
+		return err
+	}
+	if run.Changes == "" {
+		return fmt.Errorf("run %s has no saved changes to roll back (it opened no PR, or predates rollback support)", run.ID)
+	}
+
+	fmt.Printf("\n=== ROLLBACK RUN %s ===\n", run.ID)
+	if dryRun {
+		fmt.Println("Dry run: no files will be modified and no PR will be created")
+	}
+	fmt.Printf("Run started: %s\n", run.Started.Local().Format("2006-01-02 15:04"))
+
+	localPath, repoSel := "", run.RepoURL
+	if run.Local {
+		localPath, repoSel = run.LocalPath, ""
+	}
+	repo, _, err := prepareRepo(localPath, repoSel, run.Scope, run.Branch)
+	if err != nil {
+		return err
+	}
+	if len(run.DocsRoots) > 0 {
+		repo.SetDocsRoots(run.DocsRoots)
+	}
+	repo.SetPROptions(pr)
+
+	hasChanges, err := repo.HasChanges()
+	if err != nil {
+		return fmt.Errorf("failed to check for changes: %w", err)
+	}
+	if hasChanges {
+		return fmt.Errorf("the documentation has uncommitted changes; commit or stash them first")
+	}
+
+	files, err := repo.RevertChanges(run.Changes, dryRun)
+	if err != nil {
+		return fmt.Errorf("cannot roll back run %s: %w", run.ID, err)
+	}
+
+	fmt.Printf("\nReverting %d documentation files:\n", len(files))
+	for _, file := range files {
+		fmt.Printf("  %s\n", file)
+	}
+
+	if dryRun {
+		fmt.Println("\nDry run complete")
+		return nil
+	}
+
+	fmt.Println("\nCreating pull request...")
+	summary := fmt.Sprintf("Reverts the documentation changes of docu-jarvis run %s (started %s), restoring these files to their state before the run:\n\n",
+		run.ID, run.Started.Local().Format("2006-01-02 15:04"))
+	for _, file := range files {
+		summary += fmt.Sprintf("- `%s`\n", file)
+	}
+	if err := repo.CreatePR(git.PRRun{Command: "rollback-run", RunID: run.ID, Succeeded: len(files), Summary: summary}); err != nil {
+		return fmt.Errorf("failed to create PR: %w", err)
+	}
+
+	fmt.Println("\n✓ Rollback PR created!")
+	return nil
+}
+
+// reviewerRules returns the rules learned from the reviews of the
+// repository's PRs with update-docs -learn-from-pr.
+func reviewerRules(repo *git.Repo) []string {
+	repoURL, err := repo.GetRemoteURL()
+	if err != nil {
+		return nil
+	}
+	store, err := feedback.Load()
+	if err != nil {
+		fmt.Printf("Warning: ignoring the rules learned from PR reviews: %v\n", err)
+		return nil
+	}
+	rules := store.Rules(repoURL)
+	if len(rules) > 0 {
+		fmt.Printf("Following %d rules learned from PR reviews\n", len(rules))
+	}
+	return rules
+}
+
+// runLearnMode distills the review comments on a docu-jarvis PR into rules
+// that later runs on the repository follow.
+func runLearnMode(ctx context.Context, folder string, repo *git.Repo, pr string, dryRun bool) error {
+	fmt.Println("\n=== LEARN FROM PR MODE ===")
+	if dryRun {
+		fmt.Println("Dry run: the rules will be shown but not saved")
+	}
+
+	repoURL, err := repo.GetRemoteURL()
+	if err != nil {
+		return err
+	}
+	info, err := repo.GetPRInfo(pr)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Reading the reviews of PR #%d: %s\n", info.Number, info.URL)
+
+	comments, err := repo.GetPRComments(info)
+	if err != nil {
+		return err
+	}
+	if len(comments) == 0 {
+		fmt.Printf("No review comments from people on PR #%d, nothing to learn\n", info.Number)
+		return nil
+	}
+	fmt.Printf("Found %d review comments\n", len(comments))
+
+	store, err := feedback.Load()
+	if err != nil {
+		return err
+	}
+	var known []string
+	for _, lesson := range store.Repos[repoURL] {
+		if lesson.PR != info.Number {
+			known = append(known, lesson.Rule)
+		}
+	}
+
+	var feedbackComments []agent.FeedbackComment
+	for _, c := range comments {
+		feedbackComments = append(feedbackComments, agent.FeedbackComment{Author: c.Author, Path: c.Path, Line: c.Line, Body: c.Body})
+	}
+
+	fmt.Println("Distilling the feedback with Claude AI...")
+	ag, err := agent.New(system_prompts.ReviewFeedback, folder)
+	if err != nil {
+		return fmt.Errorf("failed to create agent: %w", err)
+	}
+	lessons, err := ag.LearnFromReview(ctx, feedbackComments, known)
+	if err != nil {
+		return fmt.Errorf("failed to learn from the review: %w", err)
+	}
+
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Println("LEARNED FROM REVIEW")
+	fmt.Println(strings.Repeat("=", 70))
+	if lessons.Summary != "" {
+		fmt.Printf("\n%s\n", lessons.Summary)
+	}
+	if len(lessons.Rules) == 0 {
+		fmt.Println("\nNo lasting lessons in the comments")
+	} else {
+		fmt.Println()
+		for _, rule := range lessons.Rules {
+			fmt.Printf("  - %s\n", rule)
+		}
+	}
+	fmt.Println("\n" + strings.Repeat("=", 70))
+
+	if dryRun {
+		fmt.Printf("\nDry run: not saving %d rules\n", len(lessons.Rules))
+		return nil
+	}
+
+	store.Learn(repoURL, info.Number, lessons.Rules)
+	if err := store.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("\n✓ Saved %d rules from PR #%d to %s\n", len(lessons.Rules), info.Number, store.Path())
+	fmt.Printf("update-docs, write-docs, and lint-docs now follow %d learned rules in this repository\n", len(store.Rules(repoURL)))
+	return nil
+}
+
+func runRunsList() error {
+	runs, err := runstate.List()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n=== RUNS ===")
+	if len(runs) == 0 {
+		fmt.Println("No runs recorded")
+		return nil
+	}
+
+	fmt.Printf("\n  %-22s %-17s %-28s %s\n", "ID", "STARTED", "REPOSITORY", "DOCUMENTS")
+	for _, run := range runs {
+		succeeded, failed, pending := run.Counts()
+		fmt.Printf("  %-22s %-17s %-28s %d succeeded, %d failed, %d pending\n",
+			run.ID, run.Started.Local().Format("2006-01-02 15:04"), config.RepoName(run.RepoURL), succeeded, failed, pending)
+	}
+
+	fmt.Println("\nShow a run's documents with 'docu-jarvis runs show <id>', and retry the")
+	fmt.Println("failed and pending ones with 'docu-jarvis update-docs -resume <id>'")
+	return nil
+}
+
+func runCacheStatus() error {
+	entries, size, err := cache.Stats()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n=== UPDATE CACHE ===")
+	fmt.Printf("Directory: %s\n", cache.Dir())
+	fmt.Printf("%d cached documents, %.1f KB\n", entries, float64(size)/1024)
+	fmt.Println("\nupdate-docs skips the cached docs whose content and sources are unchanged;")
+	fmt.Println("use -no-cache to update them anyway, or 'docu-jarvis cache clear' to start over")
+	return nil
+}
+
+func runCacheClear() error {
+	entries, err := cache.Clear()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✓ Cleared %d cached documents\n", entries)
+	return nil
+}
+
+func runRunsShow(id string) error {
+	run, err := runstate.Load(id)
+	if err != nil {
+		return err
+	}
+
+	succeeded, failed, pending := run.Counts()
+	fmt.Printf("\n=== RUN %s ===\n", run.ID)
+	fmt.Printf("Repository: %s\n", redact.String(run.RepoURL))
+	fmt.Printf("Checkout:   %s\n", run.LocalPath)
+	if run.Config != "" {
+		fmt.Printf("Config:     %s\n", run.Config)
+	}
+	fmt.Printf("Started:    %s\n", run.Started.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Updated:    %s\n", run.Updated.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Documents:  %d succeeded, %d failed, %d pending\n\n", succeeded, failed, pending)
+
+	for _, file := range run.FileNames() {
+		result := run.Files[file]
+		switch result.Status {
+		case runstate.Succeeded:
+			if result.Summary != "" {
+				fmt.Printf("  ✓ %s - %s\n", file, result.Summary)
+			} else {
+				fmt.Printf("  ✓ %s\n", file)
+			}
+		case runstate.Failed:
+			fmt.Printf("  ✗ %s - %s\n", file, result.Error)
+		default:
+			fmt.Printf("  … %s (pending)\n", file)
+		}
+	}
+	return nil
+}
+
+func runConversationsList() error {
+	saved, err := conversations.List()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n=== CONVERSATIONS ===")
+	if len(saved) == 0 {
+		fmt.Println("No conversations saved")
+		return nil
+	}
+
+	fmt.Printf("\n  %-27s %-17s %-28s %-9s %s\n", "ID", "UPDATED", "REPOSITORY", "MESSAGES", "SUBJECT")
+	for _, c := range saved {
+		fmt.Printf("  %-27s %-17s %-28s %-9d %s\n",
+			c.ID, c.Updated.Local().Format("2006-01-02 15:04"), config.RepoName(c.Repository), len(c.Messages), c.Subject)
+	}
+
+	fmt.Println("\nRead one with 'docu-jarvis conversations show <id>', share it with")
+	fmt.Println("'docu-jarvis conversations export <id>', and continue it with")
+	fmt.Println("'docu-jarvis explain <commit> -resume'")
+	return nil
+}
+
+func runConversationsShow(id string) error {
+	c, err := conversations.Load(id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n=== CONVERSATION %s ===\n", c.ID)
+	fmt.Printf("Subject:    %s\n", c.Subject)
+	if c.Repository != "" {
+		fmt.Printf("Repository: %s\n", c.Repository)
+	}
+	fmt.Printf("Started:    %s\n", c.Started.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Updated:    %s\n", c.Updated.Local().Format("2006-01-02 15:04:05"))
+
+	for _, msg := range c.Messages {
+		if msg.Role == conversations.RoleUser {
+			fmt.Printf("\nYou: %s\n", redact.String(msg.Content))
+		} else {
+			fmt.Printf("\nClaude: %s\n", redact.String(conversations.FinalAnswer(msg.Content)))
+		}
+	}
+	return nil
+}
+
+// runConversationsExport writes a conversation as markdown, to share, or as
+// its saved JSON.
+func runConversationsExport(id, format, file string) error {
+	c, err := conversations.Load(id)
+	if err != nil {
+		return err
+	}
+
+	var content string
+	if format == "json" {
+		data, err := json.MarshalIndent(c, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode conversation: %w", err)
+		}
+		content = string(data) + "\n"
+	} else {
+		content = c.Markdown()
+	}
+	content = redact.String(content)
+
+	if file == "" {
+		fmt.Print(content)
+		return nil
+	}
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	fmt.Printf("✓ Wrote %s\n", file)
+	return nil
+}
+
+// runUpdateAllRepos runs update-docs in every configured repository in turn,
+// carrying on past failures, and reports which ones failed.
+func runUpdateAllRepos(ctx context.Context, files []string, since, scope, branch, docsDir, customPrompt string, dryRun, confirmEdits, noCache bool, pr git.PROptions, batch agent.BatchOptions, summaryOut io.Writer) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	fmt.Printf("Updating documentation in %d repositories\n", len(cfg.Repos))
+
+	// The summaries are collected and written as one JSON array at the end
+	var summaries []json.RawMessage
+	defer func() {
+		if summaryOut == nil {
+			return
+		}
+		encoder := json.NewEncoder(summaryOut)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(append([]json.RawMessage{}, summaries...)); err != nil {
+			fmt.Printf("Warning: failed to write summary: %v\n", err)
+		}
+	}()
+
+	var failed []string
+	for i, repoURL := range cfg.Repos {
+		name := config.RepoName(repoURL)
+		fmt.Println("\n" + strings.Repeat("=", 70))
+		fmt.Printf("[%d/%d] %s\n", i+1, len(cfg.Repos), name)
+		fmt.Println(strings.Repeat("=", 70))
+
+		var summary bytes.Buffer
+		err := func() error {
+			repo, folder, err := prepareRepo("", repoURL, scope, branch)
+			if err != nil {
+				return err
+			}
+			if err := applyDocsDir(repo, docsDir); err != nil {
+				return err
+			}
+			repo.SetPROptions(pr)
+			var repoSummaryOut io.Writer
+			if summaryOut != nil {
+				repoSummaryOut = &summary
+			}
+			return updateDocsIn(ctx, folder, repo, files, since, customPrompt, dryRun, confirmEdits, noCache, batch, repoSummaryOut)
+		}()
+		if summary.Len() > 0 {
+			summaries = append(summaries, json.RawMessage(bytes.TrimSpace(summary.Bytes())))
+		}
+		if errors.Is(err, git.ErrPushDeclined) {
+			fmt.Printf("\nNothing was pushed for %s\n", name)
+		} else if err != nil {
+			fmt.Printf("\nOH NO!!!!  %s failed: %v\n", name, err)
+			failed = append(failed, name)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Printf("Repositories updated: %d/%d\n", len(cfg.Repos)-len(failed), len(cfg.Repos))
+	if len(failed) > 0 {
+		return fmt.Errorf("update-docs failed in: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// runServeMode serves the GitHub webhook and runs a docs update, with a PR,
+// for each push that changes watched code in a configured repository.
+func runServeMode(ctx context.Context, addr string, watchPaths []string, dryRun bool) error {
+	fmt.Println("\n=== SERVE MODE ===")
+	if dryRun {
+		fmt.Println("Dry run: docs updates will be shown but not written, and no PRs created")
+	}
+	// Nobody is at the terminal to answer prompts
+	ci.Enable()
+
+	s, err := settings.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if len(watchPaths) == 0 {
+		watchPaths = s.WatchPaths
+	}
+
+	srv, err := server.New(server.Options{
+		Secret:     s.GetWebhookSecret(),
+		Repos:      cfg.Repos,
+		Branch:     cfg.Branch,
+		WatchPaths: watchPaths,
+	}, func(ctx context.Context, job server.Job) (string, error) {
+		return runServeJob(ctx, job, dryRun)
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           srv.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       time.Minute, // long enough for a 25 MB payload
+		WriteTimeout:      time.Minute,
+		IdleTimeout:       2 * time.Minute,
+	}
+	errs := make(chan error, 1)
+	go func() { errs <- httpServer.ListenAndServe() }()
+	go srv.Work(ctx)
+
+	fmt.Printf("Repositories: %s\n", redact.String(strings.Join(cfg.Repos, ", ")))
+	if len(watchPaths) > 0 {
+		fmt.Printf("Watching: %s\n", strings.Join(watchPaths, ", "))
+	}
+	fmt.Printf("Listening on %s: POST /webhook, GET /status, /jobs, /jobs/<id>\n", addr)
+	fmt.Println("Press Ctrl+C to stop")
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	fmt.Println("\nStopping the server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to stop the server: %w", err)
+	}
+	return nil
+}
+
+// runServeJob updates the docs that the pushes of a serve job affect.
+func runServeJob(ctx context.Context, job server.Job, dryRun bool) (string, error) {
+	repo, folder, err := prepareRepo("", job.Repo, "", job.Branch)
+	if err != nil {
+		return "", err
+	}
+
+	var commits []string
+	if job.NewBranch() {
+		for _, hash := range job.Commits {
+			full, err := repo.EnsureCommit(hash)
+			if err != nil {
+				return "", err
+			}
+			commits = append(commits, full)
+		}
+	} else {
+		for _, hash := range []string{job.Before, job.After} {
+			if _, err := repo.EnsureCommit(hash); err != nil {
+				return "", err
+			}
+		}
+		if commits, err = repo.ResolveCommits(job.Before + ".." + job.After); err != nil {
+			return "", err
+		}
+	}
+	if len(commits) == 0 {
+		return "No new commits", nil
+	}
+
+	diff, err := repo.GetCommitsDiff(commits)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the pushed changes: %w", err)
+	}
+	impact, err := runDocsImpact(ctx, folder, repo, diff, false)
+	if err != nil {
+		return "", err
+	}
+	required := impact.RequiredUpdates()
+	if len(required) == 0 {
+		return "No docs need updating", nil
+	}
+
+	batch := agent.BatchOptions{Order: agent.OrderGiven}
+	if _, err := runUpdateMode(ctx, folder, repo, required, "", "", dryRun, false, false, nil, batch, nil); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Updated %d docs: %s", len(required), strings.Join(required, ", ")), nil
+}
+
+// Topic categories of write-docs. Interfaces with other teams or systems get
+// prompts that look for their schemas, producers, and consumers.
+const (
+	categoryGeneral  = "general"
+	categoryEvents   = "events"
+	categoryWebhooks = "webhooks"
+	categoryQueues   = "queues"
+)
+
+func runScheduleAdd(spec string, args []string) error {
+	dir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	sched, err := schedule.Load()
+	if err != nil {
+		return err
+	}
+	job, err := sched.Add(spec, args, dir)
+	if err != nil {
+		return err
+	}
+	if err := sched.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Scheduled job %s: docu-jarvis %s\n", job.ID, strings.Join(job.Args, " "))
+	fmt.Printf("  Runs in %s, next at %s\n", job.Dir, job.Next(time.Now()).Format("2006-01-02 15:04"))
+	fmt.Println("\nRun the schedule with 'docu-jarvis daemon', or install it with")
+	fmt.Println("'docu-jarvis schedule export'")
+	return nil
+}
+
+func runScheduleList() error {
+	sched, err := schedule.Load()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n=== SCHEDULE ===")
+	if len(sched.Jobs) == 0 {
+		fmt.Println("No jobs scheduled")
+		fmt.Println("\nAdd one with 'docu-jarvis schedule add \"0 9 * * 1\" update-docs all'")
+		return nil
+	}
+
+	now := time.Now()
+	fmt.Printf("\n  %-4s %-16s %-17s %-22s %s\n", "ID", "SCHEDULE", "NEXT RUN", "LAST RUN", "COMMAND")
+	for _, job := range sched.Jobs {
+		next := "never"
+		if t := job.Next(now); !t.IsZero() {
+			next = t.Format("2006-01-02 15:04")
+		}
+		last := "-"
+		if !job.LastRun.IsZero() {
+			last = job.LastRun.Local().Format("2006-01-02 15:04")
+			if job.LastError != "" {
+				last += " ✗"
+			} else {
+				last += " ✓"
+			}
+		}
+		fmt.Printf("  %-4s %-16s %-17s %-22s %s\n", job.ID, job.Spec, next, last, strings.Join(job.Args, " "))
+	}
+	for _, job := range sched.Jobs {
+		if job.LastError != "" {
+			fmt.Printf("\nJob %s last failed: %s\n", job.ID, job.LastError)
+		}
+	}
+	return nil
+}
+
+func runScheduleRemove(id string) error {
+	sched, err := schedule.Load()
+	if err != nil {
+		return err
+	}
+	if !sched.Remove(id) {
+		return fmt.Errorf("no scheduled job %s (see 'docu-jarvis schedule list')", id)
+	}
+	if err := sched.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Removed job %s\n", id)
+	return nil
+}
+
+// defaultScheduleFormat is launchd on macOS and crontab elsewhere.
+func defaultScheduleFormat() string {
+	if runtime.GOOS == "darwin" {
+		return schedule.FormatLaunchd
+	}
+	return schedule.FormatCrontab
+}
+
+// runScheduleExport prints the schedule as crontab lines, systemd units, or
+// launchd agents, or writes them to dir.
+func runScheduleExport(format, dir string) error {
+	sched, err := schedule.Load()
+	if err != nil {
+		return err
+	}
+	if len(sched.Jobs) == 0 {
+		return fmt.Errorf("no jobs scheduled - add one with 'docu-jarvis schedule add'")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the docu-jarvis binary: %w", err)
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	logDir := filepath.Join(homeDir, ".docu-jarvis", "logs")
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	files, err := schedule.Export(format, sched.Jobs, exe, os.Getenv("PATH"), logDir)
+	if err != nil {
+		return err
+	}
+
+	if dir == "" {
+		for i, file := range files {
+			if len(files) > 1 {
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Printf("# ==> %s <==\n", file.Name)
+			}
+			fmt.Print(file.Content)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	for _, file := range files {
+		path := filepath.Join(dir, file.Name)
+		if err := os.WriteFile(path, []byte(file.Content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		fmt.Printf("✓ Wrote %s\n", path)
+	}
+
+	fmt.Println("\nTo install them:")
+	switch format {
+	case schedule.FormatCrontab:
+		fmt.Printf("  (crontab -l; cat %s) | crontab -\n", filepath.Join(dir, "crontab"))
+	case schedule.FormatSystemd:
+		fmt.Printf("  cp %s/*.service %s/*.timer ~/.config/systemd/user/\n", dir, dir)
+		fmt.Println("  systemctl --user daemon-reload")
+		for _, job := range sched.Jobs {
+			fmt.Printf("  systemctl --user enable --now docu-jarvis-schedule-%s.timer\n", job.ID)
+		}
+	case schedule.FormatLaunchd:
+		for _, file := range files {
+			fmt.Printf("  cp %s ~/Library/LaunchAgents/ && launchctl load ~/Library/LaunchAgents/%s\n", filepath.Join(dir, file.Name), file.Name)
+		}
+	}
+	return nil
+}
+
+// runDaemonMode runs the scheduled jobs as they come due, one at a time,
+// until interrupted. The schedule is read again every minute, so jobs added
+// or removed meanwhile are picked up.
+func runDaemonMode(ctx context.Context) error {
+	fmt.Println("\n=== DAEMON MODE ===")
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the docu-jarvis binary: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Println("Running scheduled jobs, press Ctrl+C to stop")
+	// Keyed by ID and spec, so a job replaced under the same ID starts over
+	next := make(map[string]time.Time)
+	for {
+		sched, err := schedule.Load()
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			sched = &schedule.Schedule{}
+		}
+
+		for _, job := range sched.Jobs {
+			key := job.ID + " " + job.Spec
+			due, ok := next[key]
+			if !ok {
+				next[key] = job.Next(time.Now())
+				fmt.Printf("Job %s (docu-jarvis %s) next runs at %s\n", job.ID, strings.Join(job.Args, " "), next[key].Format("2006-01-02 15:04"))
+				continue
+			}
+			if due.IsZero() || time.Now().Before(due) {
+				continue
+			}
+
+			runScheduledJob(ctx, exe, job)
+			if ctx.Err() != nil {
+				break
+			}
+			next[key] = job.Next(time.Now())
+			fmt.Printf("Job %s next runs at %s\n", job.ID, next[key].Format("2006-01-02 15:04"))
+		}
+
+		now := time.Now()
+		select {
+		case <-ctx.Done():
+			fmt.Println("\nStopping the daemon...")
+			return nil
+		case <-time.After(now.Truncate(time.Minute).Add(time.Minute).Sub(now)):
+		}
+	}
+}
+
+// runScheduledJob runs a job as a docu-jarvis process of its own, in CI mode
+// as nobody is there to answer, and records the result in the schedule.
+func runScheduledJob(ctx context.Context, exe string, job schedule.Job) {
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Printf("[%s] Job %s: docu-jarvis %s\n", time.Now().Format("2006-01-02 15:04"), job.ID, strings.Join(job.Args, " "))
+	fmt.Println(strings.Repeat("=", 70))
+
+	cmd := exec.CommandContext(ctx, exe, append([]string{"-ci"}, job.Args...)...)
+	cmd.Dir = job.Dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	started := time.Now()
+	runErr := cmd.Run()
+
+	if runErr != nil {
+		fmt.Printf("\nOH NO!!!!  Job %s failed: %v\n", job.ID, runErr)
+	} else {
+		fmt.Printf("\n✓ Job %s finished in %s\n", job.ID, time.Since(started).Round(time.Second))
+	}
+
+	sched, err := schedule.Load()
+	if err == nil {
+		if saved := sched.Find(job.ID); saved != nil {
+			saved.LastRun = started
+			saved.LastError = ""
+			if runErr != nil {
+				saved.LastError = runErr.Error()
+			}
+			err = sched.Save()
+		}
+	}
+	if err != nil {
+		fmt.Printf("Warning: failed to record the job's result: %v\n", err)
+	}
+}
+
+// writePrompt returns the write-docs prompt for a topic category.
+func writePrompt(category string) (string, error) {
+	switch category {
+	case categoryGeneral:
+		return system_prompts.DocumentationWrite, nil
+	case categoryEvents:
+		return system_prompts.InterfaceEvents, nil
+	case categoryWebhooks:
+		return system_prompts.InterfaceWebhooks, nil
+	case categoryQueues:
+		return system_prompts.InterfaceQueues, nil
+	}
+	return "", fmt.Errorf("invalid -category %q (must be %s, %s, %s, or %s)", category, categoryGeneral, categoryEvents, categoryWebhooks, categoryQueues)
+}
+
+func runWriteMode(ctx context.Context, folder string, repo *git.Repo, topics []string, systemPrompt string, dryRun, confirmEdits bool, batch agent.BatchOptions, summaryOut io.Writer) error {
+	fmt.Printf("\n=== WRITE DOCUMENTATION MODE ===\n")
+	if dryRun {
+		fmt.Println("Dry run: no files will be written and no PR will be created")
+	}
+	if confirmEdits {
+		fmt.Println("Confirm edits: each change will be shown for your approval before it is applied")
+	}
+	fmt.Printf("Topics to document: %v\n", topics)
+
+	fmt.Println("\nInitializing agent...")
+	ag, err := agent.New(systemPrompt, folder)
+	if err != nil {
+		return fmt.Errorf("failed to create agent: %w", err)
+	}
+	ag.SetDryRun(dryRun)
+	ag.SetConfirmEdits(confirmEdits)
+	ag.SetDocsDirs(repo.GetDocsDirs())
+	ag.SetEditRoots(repo.EditRoots())
+	rules := reviewerRules(repo)
+	ag.SetReviewerRules(rules)
+	ag.SetBatchOptions(batch)
+
+	fmt.Println("Checking for existing documentation...")
+	matches, err := ag.CheckExistingDocs(ctx, topics)
+	if err != nil {
+		return fmt.Errorf("failed to check existing docs: %w", err)
+	}
+
+	var topicsToWrite []string
+	var topicsToUpdate []string
+	var topicsToSkip []string
+
+	hasConflicts := false
+	for _, match := range matches {
+		if match.IsMatch {
+			hasConflicts = true
+			fmt.Printf("\nOH NO!!!!  Topic '%s' already documented in: %s\n", match.Topic, match.ExistingFile)
+		}
+	}
+
+	if hasConflicts {
+		fmt.Println("\nWhat would you like to do with existing documentation?")
+		fmt.Println("  1. Write new files (keep existing)")
+		fmt.Println("  2. Update existing files")
+		fmt.Println("  3. Skip existing topics")
+		fmt.Print("\nChoice (1/2/3): ")
+
+		choice := ask("3")
+
+		for _, match := range matches {
+			if match.IsMatch {
+				switch choice {
+				case "1":
+					topicsToWrite = append(topicsToWrite, match.Topic)
+				case "2":
+					topicsToUpdate = append(topicsToUpdate, match.Topic)
+				case "3":
+					topicsToSkip = append(topicsToSkip, match.Topic)
+					fmt.Printf("  Skipping: %s\n", match.Topic)
+				default:
+					return fmt.Errorf("invalid choice: %s", choice)
+				}
+			} else {
+				topicsToWrite = append(topicsToWrite, match.Topic)
+			}
+		}
+	} else {
+		topicsToWrite = topics
+	}
+
+	var writeSuccess, writeTotal int
+	var updateSuccess, updateTotal int
+	var updateAgent *agent.Agent
+
+	checkEdits, err := guardEdits(repo, dryRun)
+	if err != nil {
+		return err
+	}
+	defer checkEdits()
+
+	if len(topicsToWrite) > 0 {
+		fmt.Printf("\nWriting documentation for %d new topics...\n", len(topicsToWrite))
+		writeSuccess, writeTotal, err = ag.WriteDocumentation(ctx, topicsToWrite)
+		if err != nil {
+			return fmt.Errorf("failed to write documentation: %w", err)
+		}
+	}
+
+	if len(topicsToUpdate) > 0 {
+		fmt.Printf("\nUpdating documentation for %d existing topics...\n", len(topicsToUpdate))
+
+		updatePrompt := system_prompts.DocumentationUpdate
+
+		updateAgent, err = agent.New(updatePrompt, folder)
+		if err != nil {
+			return fmt.Errorf("failed to create update agent: %w", err)
+		}
+		updateAgent.SetDryRun(dryRun)
+		updateAgent.SetConfirmEdits(confirmEdits)
+		updateAgent.SetDocsDirs(repo.GetDocsDirs())
+		updateAgent.SetEditRoots(repo.EditRoots())
+		updateAgent.SetReviewerRules(rules)
+		updateAgent.SetBatchOptions(batch)
+
+		var filesToUpdate []string
+		for _, match := range matches {
+			if match.IsMatch {
+				for _, topic := range topicsToUpdate {
+					if topic == match.Topic {
+						filePath := filepath.Join(folder, match.ExistingFile)
+						filesToUpdate = append(filesToUpdate, filePath)
+						break
+					}
+				}
+			}
+		}
+
+		updateSuccess, updateTotal, err = updateAgent.UpdateSpecificDocuments(ctx, filesToUpdate)
+		if err != nil {
+			return fmt.Errorf("failed to update documentation: %w", err)
+		}
+	}
+
+	if err := checkEdits(); err != nil {
+		return err
+	}
+
+	items := ag.Batch()
+	if updateAgent != nil {
+		items = append(items, updateAgent.Batch()...)
+	}
+	if err := writeBatchSummary(summaryOut, "write-docs", repo, nil, dryRun, items); err != nil {
+		return err
+	}
+
+	successCount := writeSuccess + updateSuccess
+	totalTopics := writeTotal + updateTotal + len(topicsToSkip)
+
+	if dryRun {
+		fmt.Printf("\nDry run complete (%d/%d topics analyzed)\n", successCount, totalTopics)
+		return nil
+	}
+
+	if successCount > 0 {
+		if successCount == totalTopics {
+			fmt.Println("\nAll topics documented successfully")
+		} else {
+			fmt.Printf("\nSome topics failed, but %d/%d succeeded\n", successCount, totalTopics)
+		}
+		sources := ag.DocSources()
+		if updateAgent != nil {
+			for doc, files := range updateAgent.DocSources() {
+				sources[doc] = files
+			}
+		}
+		stampFreshness(folder, repo, sources)
+
+		hasChanges, err := repo.HasChanges()
+		if err != nil {
+			return fmt.Errorf("failed to check for changes: %w", err)
+		}
+
+		if hasChanges {
+			if err := fixDocStructure(repo); err != nil {
+				return err
+			}
+			pr := prRun("write-docs", nil, items)
+			approved, err := approveDocChanges(repo, &pr)
+			if err != nil {
+				return err
+			}
+			if approved {
+				pr.Summary += checkDocSamples(ctx, repo) + assetChecklist(repo)
+				fmt.Println("\nCreating pull request with new documentation...")
+				if err := repo.CreatePR(pr); err != nil {
+					return fmt.Errorf("failed to create PR: %w", err)
+				}
+			}
+		} else {
+			fmt.Println("\nNo new documentation files were created")
+		}
+	} else {
+		fmt.Println("\nAll topics failed - no documentation created")
+	}
+
+	fmt.Println("\n✓ Documentation writing completed!")
+	return nil
+}
+
+// Output formats of write-api-docs.
+const (
+	apiFormatMarkdown = "markdown"
+	apiFormatOpenAPI  = "openapi"
+)
+
+// apiDocsOutput returns the file write-api-docs writes in format, output or
+// the format's default, relative to the first docs root.
+func apiDocsOutput(format, output string) (string, error) {
+	switch format {
+	case apiFormatMarkdown:
+		if output == "" {
+			return "api-reference.md", nil
+		}
+		if !agent.IsDocFile(output) {
+			return "", fmt.Errorf("-output must be a .md or .mdx file with -format markdown")
+		}
+	case apiFormatOpenAPI:
+		if output == "" {
+			return "openapi.yaml", nil
+		}
+		if ext := filepath.Ext(output); ext != ".yaml" && ext != ".yml" && ext != ".json" {
+			return "", fmt.Errorf("-output must be a .yaml, .yml, or .json file with -format openapi")
+		}
+	default:
+		return "", fmt.Errorf("invalid -format %q (must be %s or %s)", format, apiFormatMarkdown, apiFormatOpenAPI)
+	}
+	if filepath.IsAbs(output) || strings.HasPrefix(filepath.Clean(output), "..") {
+		return "", fmt.Errorf("-output must be inside the docs root: %s", output)
+	}
+	return output, nil
+}
+
+// runWriteAPIDocsMode finds the HTTP routes of the codebase and has Claude
+// document them in output, under the first docs root, as endpoint docs or an
+// OpenAPI 3 spec. A spec is checked before the PR is opened, and the
+// detected routes it leaves out are listed in the PR body.
+func runWriteAPIDocsMode(ctx context.Context, folder string, repo *git.Repo, format, output string, dryRun bool) error {
+	fmt.Println("\n=== WRITE API DOCS MODE ===")
+	if dryRun {
+		fmt.Println("Dry run: no files will be written and no PR will be created")
+	}
+
+	fmt.Println("Looking for HTTP routes...")
+	found, err := routes.Detect(folder)
+	if err != nil {
+		return err
+	}
+	if len(found) == 0 {
+		return fmt.Errorf("no HTTP routes found (looked for %s, %s, %s, %s, and %s routes)", routes.Gin, routes.Echo, routes.NetHTTP, routes.Express, routes.FastAPI)
+	}
+	counts := make(map[string]int)
+	var frameworks []string
+	for _, r := range found {
+		if counts[r.Framework] == 0 {
+			frameworks = append(frameworks, r.Framework)
+		}
+		counts[r.Framework]++
+	}
+	for i, framework := range frameworks {
+		frameworks[i] = fmt.Sprintf("%d %s", counts[framework], framework)
+	}
+	fmt.Printf("✓ Found %d routes: %s\n", len(found), strings.Join(frameworks, ", "))
+
+	systemPrompt, kind := system_prompts.APIReference, "endpoint docs"
+	if format == apiFormatOpenAPI {
+		systemPrompt, kind = system_prompts.OpenAPISpec, "OpenAPI spec"
+	}
+	docsDir := repo.GetDocsDirs()[0]
+	path := filepath.Join(docsDir, output)
+	file := filepath.ToSlash(filepath.Join(repo.GetDocsRoots()[0], output))
+	if !dryRun {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create documentation directory: %w", err)
+		}
+	}
+
+	fmt.Println("\nInitializing agent...")
+	ag, err := agent.New(systemPrompt, folder)
+	if err != nil {
+		return fmt.Errorf("failed to create agent: %w", err)
+	}
+	ag.SetDryRun(dryRun)
+	ag.SetDocsDirs(repo.GetDocsDirs())
+	ag.SetEditRoots(repo.EditRoots())
+	ag.SetReviewerRules(reviewerRules(repo))
+
+	checkEdits, err := guardEdits(repo, dryRun)
+	if err != nil {
+		return err
+	}
+	defer checkEdits()
+
+	fmt.Printf("\nWriting the %s to %s...\n", kind, file)
+	if err := ag.WriteAPIDocs(ctx, found, path); err != nil {
+		return fmt.Errorf("failed to write the %s: %w", kind, err)
+	}
+	if err := checkEdits(); err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Println("\nDry run complete")
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("the %s was not written to %s: %w", kind, file, err)
+	}
+	summary := fmt.Sprintf("- `%s`: %s of the %d routes found", file, kind, len(found))
+	if format == apiFormatOpenAPI {
+		if err := routes.CheckSpec(string(content)); err != nil {
+			return fmt.Errorf("%w; fix %s in %s and run again, no PR was created", err, file, repo.GetLocalPath())
+		}
+		fmt.Printf("✓ %s is an OpenAPI 3 spec\n", file)
+		if missing := routes.Missing(string(content), found); len(missing) > 0 {
+			fmt.Printf("Warning: %d of the routes found are not in the spec:\n", len(missing))
+			summary += "\n\n**Routes not in the spec** (check whether they are internal or were missed):\n"
+			for _, r := range missing {
+				fmt.Printf("  - %s\n", r)
+				summary += fmt.Sprintf("- `%s %s` (`%s:%d`)\n", r.Method, r.Path, r.File, r.Line)
+			}
+			summary = strings.TrimSuffix(summary, "\n")
+		}
+	}
+
+	hasChanges, err := repo.HasChanges()
+	if err != nil {
+		return fmt.Errorf("failed to check for changes: %w", err)
+	}
+	if !hasChanges {
+		fmt.Printf("\nNo changes: %s is up to date\n", file)
+		return nil
+	}
+	if format == apiFormatMarkdown {
+		if err := fixDocStructure(repo); err != nil {
+			return err
+		}
+	}
+	pr := git.PRRun{Command: "write-api-docs", Succeeded: 1, Summary: summary}
+	approved, err := approveDocChanges(repo, &pr)
+	if err != nil {
+		return err
+	}
+	if approved {
+		if format == apiFormatMarkdown {
+			pr.Summary += checkDocSamples(ctx, repo)
+		}
+		fmt.Println("\nCreating pull request with the API documentation...")
+		if err := repo.CreatePR(pr); err != nil {
+			return fmt.Errorf("failed to create PR: %w", err)
+		}
+	}
+
+	fmt.Println("\n✓ API documentation completed!")
+	return nil
+}
+
+// testOutputLimit is the most of a failed test run's output, from its end,
+// that is shown to Claude to fix the tests.
+const testOutputLimit = 8000
+
+// runWriteTestsMode has Claude write unit tests for each target, following
+// the configured test conventions. With verify, Go tests are run with go test
+// and the ones that still fail after one fix are reverted. The tests are
+// opened as a PR on a tests/ branch.
+func runWriteTestsMode(ctx context.Context, folder string, repo *git.Repo, targets []string, verify bool) error {
+	fmt.Println("\n=== WRITE TESTS MODE ===")
+	fmt.Printf("Targets: %v\n", targets)
+	for _, target := range targets {
+		if _, err := os.Stat(filepath.Join(folder, target)); err != nil {
+			return fmt.Errorf("target does not exist: %s", target)
+		}
+	}
+
+	s, err := settings.LoadForRepo(repo.GetLocalPath())
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+	conventions := s.GetTestConventions()
+
+	fmt.Println("\nInitializing agent...")
+	ag, err := agent.New(system_prompts.TestWrite, folder)
+	if err != nil {
+		return fmt.Errorf("failed to create agent: %w", err)
+	}
+	repo.SetEditAllowlist(agent.TestFilePatterns)
+	ag.SetEditRoots(repo.EditRoots())
+
+	var written, unverified []string
+	var summary strings.Builder
+	failed := 0
+	for _, target := range targets {
+		fmt.Printf("\nWriting tests for %s...\n", target)
+		files, verified, err := writeTests(ctx, ag, repo, target, conventions, verify)
+		if err != nil {
+			fmt.Printf("OH NO!!!!  Failed to write tests for %s: %v\n", target, err)
+			failed++
+			continue
+		}
+		for _, file := range files {
+			fmt.Printf("  ✓ %s\n", file)
+		}
+		fmt.Fprintf(&summary, "- `%s`: `%s`\n", target, strings.Join(files, "`, `"))
+		written = append(written, files...)
+		if !verified {
+			unverified = append(unverified, "`"+target+"`")
+		}
+	}
+
+	if len(written) == 0 {
+		fmt.Println("\nNo tests were written - no PR created")
+		return nil
+	}
+	if failed > 0 {
+		fmt.Printf("\nSome targets failed, but %d/%d succeeded\n", len(targets)-failed, len(targets))
+	}
+
+	pr := git.PRRun{
+		Command:      "write-tests",
+		Succeeded:    len(targets) - failed,
+		Failed:       failed,
+		Summary:      strings.TrimSuffix(summary.String(), "\n"),
+		Paths:        written,
+		PathsOnly:    true,
+		BranchPrefix: "tests/",
+
+		CommitMessage: "test: add unit tests written by docu-jarvis",
+	}
+	if len(unverified) > 0 {
+		pr.Summary += fmt.Sprintf("\n\nThe tests of %s were not run; check that they pass before merging.", strings.Join(unverified, ", "))
+	}
+	fmt.Println("\nCreating pull request with the new tests...")
+	if err := repo.CreatePR(pr); err != nil {
+		return fmt.Errorf("failed to create PR: %w", err)
+	}
+
+	fmt.Println("\n✓ Test writing completed!")
+	return nil
+}
+
+// writeTests writes the tests of one target and returns them, relative to the
+// repository root, and whether they were run. Edits to anything but test
+// files are reverted. With verify, failing Go tests are sent back to Claude
+// once, and reverted when they still fail.
+func writeTests(ctx context.Context, ag *agent.Agent, repo *git.Repo, target, conventions string, verify bool) ([]string, bool, error) {
+	guard, err := repo.GuardEdits()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to record the working tree before editing: %w", err)
+	}
+
+	paths, err := ag.WriteTests(ctx, target, conventions)
+	if err := enforceEdits(repo, guard); err != nil {
+		return nil, false, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	files := repoRelative(repo, paths)
+	if len(files) == 0 {
+		return nil, false, fmt.Errorf("no test files were written")
+	}
+	if !verify {
+		return files, false, nil
+	}
+
+	for attempt := 0; ; attempt++ {
+		output, err := runGoTests(ctx, repo, files)
+		if err == nil {
+			return files, true, nil
+		}
+		if errors.Is(err, errNoGoTests) {
+			fmt.Printf("  Warning: -verify runs go test, so the tests of %s were not run\n", target)
+			return files, false, nil
+		}
+		if attempt > 0 {
+			if revertErr := guard.Revert(files); revertErr != nil {
+				return nil, false, revertErr
+			}
+			return nil, false, fmt.Errorf("%w; reverted the tests", err)
+		}
+
+		fmt.Printf("  Warning: %v, asking Claude to fix the tests\n", err)
+		if len(output) > testOutputLimit {
+			output = "..." + output[len(output)-testOutputLimit:]
+		}
+		fixed, fixErr := ag.FixTests(ctx, target, files, output)
+		if err := enforceEdits(repo, guard); err != nil {
+			return nil, false, err
+		}
+		if fixErr != nil {
+			if revertErr := guard.Revert(files); revertErr != nil {
+				return nil, false, revertErr
+			}
+			return nil, false, fmt.Errorf("failed to fix the tests: %w; reverted them", fixErr)
+		}
+		files = appendMissing(files, repoRelative(repo, fixed)...)
+	}
+}
+
+// repoRelative returns absolute paths of the checkout relative to the
+// repository root, with forward slashes.
+func repoRelative(repo *git.Repo, paths []string) []string {
+	var rel []string
+	for _, path := range paths {
+		if r, err := filepath.Rel(repo.GetLocalPath(), path); err == nil && !strings.HasPrefix(r, "..") {
+			rel = append(rel, filepath.ToSlash(r))
+		}
+	}
+	return rel
+}
+
+// appendMissing appends the values that list does not have yet.
+func appendMissing(list []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, existing := range list {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// errNoGoTests is returned by runGoTests when none of the files are Go tests.
+var errNoGoTests = errors.New("no Go tests to run")
+
+// runGoTests runs go test for the packages of the Go test files among files,
+// given relative to the repository root, from the module each belongs to. It
+// returns the output of the runs that failed.
+func runGoTests(ctx context.Context, repo *git.Repo, files []string) (string, error) {
+	root := repo.GetLocalPath()
+	var packages []string
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			packages = appendMissing(packages, filepath.Dir(filepath.Join(root, file)))
+		}
+	}
+	if len(packages) == 0 {
+		return "", errNoGoTests
+	}
+
+	var output strings.Builder
+	var failed []string
+	for _, dir := range packages {
+		module := dir
+		for {
+			if _, err := os.Stat(filepath.Join(module, "go.mod")); err == nil {
+				break
+			}
+			if module == root || filepath.Dir(module) == module {
+				return "", fmt.Errorf("no go.mod found for %s", dir)
+			}
+			module = filepath.Dir(module)
+		}
+		rel, _ := filepath.Rel(module, dir)
+		pkg := "./" + filepath.ToSlash(rel)
+
+		fmt.Printf("  Running go test %s...\n", pkg)
+		cmd := exec.CommandContext(ctx, "go", "test", pkg)
+		cmd.Dir = module
+		cmd.Env = samples.GoEnv()
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			failed = append(failed, pkg)
+			output.Write(out)
+		}
+	}
+	if len(failed) > 0 {
+		return output.String(), fmt.Errorf("go test failed for %s", strings.Join(failed, ", "))
+	}
+	return "", nil
+}
+
+func runAuditDocsMode(ctx context.Context, folder string, repo *git.Repo, reportOut io.Writer) error {
+	fmt.Println("\n=== DOCS AUDIT MODE ===")
+	fmt.Printf("Docs directories: %s\n", strings.Join(repo.GetDocsRoots(), ", "))
+
+	fmt.Println("Auditing documentation coverage with Claude AI...")
+	ag, err := agent.New(system_prompts.DocsAudit, folder)
+	if err != nil {
+		return fmt.Errorf("failed to create agent: %w", err)
+	}
+	ag.SetDocsDirs(repo.GetDocsDirs())
+
+	audit, err := ag.AuditDocs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to audit docs: %w", err)
+	}
+
+	if reportOut != nil {
+		encoder := json.NewEncoder(reportOut)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(audit); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		return nil
+	}
+
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Println("DOCUMENTATION COVERAGE")
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Printf("Coverage: %d%% (%d of %d areas documented, %d doc files)\n",
+		audit.Coverage(), audit.AreasDocumented, audit.AreasTotal, audit.DocFiles)
+	if audit.Summary != "" {
+		fmt.Printf("\n%s\n", audit.Summary)
+	}
+
+	if len(audit.Undocumented) > 0 {
+		fmt.Printf("\nUndocumented areas (%d):\n", len(audit.Undocumented))
+		for _, area := range audit.Undocumented {
+			fmt.Printf("  - [%s] %s (%s)\n", area.Kind, area.Area, area.Location)
+			if area.Reason != "" {
+				fmt.Printf("      %s\n", area.Reason)
+			}
+		}
+	}
+
+	if len(audit.Stale) > 0 {
+		fmt.Printf("\nStale references (%d):\n", len(audit.Stale))
+		for _, stale := range audit.Stale {
+			fmt.Printf("  - %s: %s\n", stale.File, stale.Reference)
+			if stale.Reason != "" {
+				fmt.Printf("      %s\n", stale.Reason)
+			}
+		}
+	}
+
+	topics := audit.TopicsByPriority()
+	if len(topics) > 0 {
+		fmt.Printf("\nSuggested topics (%d):\n", len(topics))
+		var names []string
+		for i, topic := range topics {
+			fmt.Printf("  %d. [%s] %s\n", i+1, topic.Priority, topic.Topic)
+			if topic.Reason != "" {
+				fmt.Printf("      %s\n", topic.Reason)
+			}
+			names = append(names, topic.Topic)
+		}
+		fmt.Println("\nWrite them with:")
+		fmt.Printf("  docu-jarvis write-docs %q\n", strings.Join(names, ","))
+	}
+
+	if len(audit.Stale) > 0 {
+		var files []string
+		seen := make(map[string]bool)
+		for _, stale := range audit.Stale {
+			if !seen[stale.File] {
+				seen[stale.File] = true
+				files = append(files, stale.File)
+			}
+		}
+		fmt.Println("\nFix the stale docs with:")
+		fmt.Printf("  docu-jarvis update-docs %q\n", strings.Join(files, ","))
+	}
+	fmt.Println(strings.Repeat("=", 70))
+
+	fmt.Println("\n✓ Docs audit completed!")
+	return nil
+}
+
+func runLintDocsMode(ctx context.Context, folder string, repo *git.Repo, files []string, fix, confirmEdits bool, batch agent.BatchOptions, reportOut io.Writer) error {
+	fmt.Println("\n=== LINT DOCS MODE ===")
+	fmt.Printf("Docs directories: %s\n", strings.Join(repo.GetDocsRoots(), ", "))
+	if fix {
+		fmt.Println("Fix: documents with findings will be fixed and a PR created")
+	}
+	if confirmEdits {
+		fmt.Println("Confirm edits: each change will be shown for your approval before it is applied")
+	}
+
+	s, err := settings.LoadForRepo(repo.GetLocalPath())
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+	if s.DocsStyle == "" {
+		fmt.Println("No docs style guide configured, using the default")
+	} else {
+		fmt.Printf("Loaded docs style guide from: %s\n", s.GetPath())
+	}
+
+	var paths []string
+	if len(files) == 1 && strings.ToLower(files[0]) == "all" {
+		paths, err = agent.FindDocs(repo.GetDocsDirs())
+		if err != nil {
+			return fmt.Errorf("failed to scan documentation directory: %w", err)
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no .md or .mdx files found in: %s", strings.Join(repo.GetDocsRoots(), ", "))
+		}
+	} else {
+		paths, err = resolveDocFiles(folder, repo.GetDocsDirs(), files)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("Initializing agent for docs linting...")
+	ag, err := agent.New(system_prompts.DocsLint, folder)
+	if err != nil {
+		return fmt.Errorf("failed to create agent: %w", err)
+	}
+	ag.SetConfirmEdits(confirmEdits)
+	ag.SetDocsDirs(repo.GetDocsDirs())
+	ag.SetEditRoots(repo.EditRoots())
+	ag.SetBatchOptions(batch)
+
+	style := s.GetDocsStyle()
+	if rules := reviewerRules(repo); len(rules) > 0 {
+		style += "\n" + strings.Join(rules, "\n")
+	}
+	checkEdits, err := guardEdits(repo, !fix)
+	if err != nil {
+		return err
+	}
+	defer checkEdits()
+
+	lint, err := ag.LintDocs(ctx, paths, style, fix)
+	if err != nil {
+		return fmt.Errorf("failed to lint docs: %w", err)
+	}
+	if err := checkEdits(); err != nil {
+		return err
+	}
+	errs, warnings := lint.Counts()
+	failed := lint.Failed()
+
+	if reportOut != nil {
+		encoder := json.NewEncoder(reportOut)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(lint); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	} else {
+		fmt.Println("\n" + strings.Repeat("=", 70))
+		fmt.Println("DOCS LINT REPORT")
+		fmt.Println(strings.Repeat("=", 70))
+		for _, doc := range lint.Files {
+			switch {
+			case doc.Error != "":
+				fmt.Printf("\n✗ %s: %s\n", doc.File, doc.Error)
+			case len(doc.Findings) == 0:
+				fmt.Printf("\n✓ %s\n", doc.File)
+				continue
+			case doc.Fixed:
+				fmt.Printf("\n✓ %s (%d findings fixed)\n", doc.File, len(doc.Findings))
+			default:
+				fmt.Printf("\n%s (%d findings)\n", doc.File, len(doc.Findings))
+			}
+			for _, finding := range doc.Findings {
+				location := "document"
+				if finding.Line > 0 {
+					location = fmt.Sprintf("line %d", finding.Line)
+				}
+				fmt.Printf("  - [%s] %s: %s\n", finding.Severity, location, finding.Issue)
+				fmt.Printf("      Rule: %s\n", finding.Rule)
+				if finding.Suggestion != "" {
+					fmt.Printf("      Fix: %s\n", finding.Suggestion)
+				}
+			}
+		}
+		fmt.Println("\n" + strings.Repeat("=", 70))
+		fmt.Printf("%d files, %d errors, %d warnings", len(lint.Files), errs, warnings)
+		if fix {
+			fmt.Print(" remaining")
+		}
+		fmt.Println()
+		fmt.Println(strings.Repeat("=", 70))
+	}
+
+	if fix {
+		hasChanges, err := repo.HasChanges()
+		if err != nil {
+			return fmt.Errorf("failed to check for changes: %w", err)
+		}
+		if hasChanges {
+			if err := fixDocStructure(repo); err != nil {
+				return err
+			}
+			pr := prRun("lint-docs", nil, ag.Batch())
+			approved, err := approveDocChanges(repo, &pr)
+			if err != nil {
+				return err
+			}
+			if approved {
+				pr.Summary += checkDocSamples(ctx, repo) + assetChecklist(repo)
+				fmt.Println("\nCreating pull request...")
+				if err := repo.CreatePR(pr); err != nil {
+					return fmt.Errorf("failed to create PR: %w", err)
+				}
+			}
+		} else {
+			fmt.Println("\nNo changes detected in documentation")
+		}
+	} else if errs+warnings > 0 {
+		fmt.Println("\nFix them with:")
+		fmt.Printf("  docu-jarvis lint-docs -fix %q\n", strings.Join(files, ","))
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d documents could not be linted or fixed", len(failed))
+	}
+	if errs > 0 {
+		return fmt.Errorf("%d style guide errors remain", errs)
+	}
+	fmt.Println("\n✓ Docs lint completed!")
+	return nil
+}
+
+func runDocsGapMode(ctx context.Context, folder string, repo *git.Repo, templateRef string, reportOut io.Writer) error {
+	fmt.Println("\n=== DOCS GAP MODE ===")
+	fmt.Printf("Docs directories: %s\n", strings.Join(repo.GetDocsRoots(), ", "))
+
+	template, err := agent.LoadLayoutTemplate(ctx, templateRef)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Template: %s\n", template.Name)
+
+	fmt.Println("Comparing the documentation structure with Claude AI...")
+	ag, err := agent.New(system_prompts.DocsGap, folder)
+	if err != nil {
+		return fmt.Errorf("failed to create agent: %w", err)
+	}
+	ag.SetDocsDirs(repo.GetDocsDirs())
+
+	gap, err := ag.CompareDocsLayout(ctx, template)
+	if err != nil {
+		return fmt.Errorf("failed to compare docs: %w", err)
+	}
+
+	if reportOut != nil {
+		encoder := json.NewEncoder(reportOut)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(gap); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		return nil
+	}
+
+	gaps := gap.Gaps()
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Println("DOCUMENTATION GAPS")
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Printf("Template: %s (%d of %d categories covered, %d doc files)\n",
+		gap.Template, len(gap.Categories)-len(gaps), len(gap.Categories), gap.DocFiles)
+	if gap.Summary != "" {
+		fmt.Printf("\n%s\n", gap.Summary)
+	}
+
+	fmt.Println("\nCategories:")
+	for _, category := range gap.Categories {
+		mark := "✓"
+		switch category.Status {
+		case "partial":
+			mark = "~"
+		case "missing":
+			mark = "✗"
+		}
+		fmt.Printf("  %s %s (%s)", mark, category.Name, category.Status)
+		if len(category.Docs) > 0 {
+			fmt.Printf(": %s", strings.Join(category.Docs, ", "))
+		}
+		fmt.Println()
+		if category.Notes != "" && category.Status != "covered" {
+			fmt.Printf("      %s\n", category.Notes)
+		}
+	}
+
+	var topics []string
+	for _, category := range gaps {
+		if len(category.SuggestedTopics) == 0 {
+			continue
+		}
+		fmt.Printf("\nSuggested topics for %s:\n", category.Name)
+		for i, topic := range category.SuggestedTopics {
+			fmt.Printf("  %d. [%s] %s\n", i+1, topic.Priority, topic.Topic)
+			if topic.Reason != "" {
+				fmt.Printf("      %s\n", topic.Reason)
+			}
+			topics = append(topics, topic.Topic)
+		}
+	}
+	if len(topics) > 0 {
+		fmt.Println("\nWrite them with:")
+		fmt.Printf("  docu-jarvis write-docs %q\n", strings.Join(topics, ","))
+	}
+	fmt.Println(strings.Repeat("=", 70))
+
+	fmt.Println("\n✓ Docs gap analysis completed!")
+	return nil
+}
+
+// runDebugMode looks for the commit that caused a bug among those from from
+// to to; fromDate and toDate are the dates as given, echoed with the exact
+// times they resolved to.
+func runDebugMode(ctx context.Context, folder string, repo *git.Repo, fromDate, toDate string, from, to time.Time, bugDescription string, history git.HistoryOptions, bisect, verify, pastCases bool, opts agent.SuspectOptions) error {
+	fmt.Println("\n=== DEBUG MODE ===")
+	fmt.Printf("From: %s (%s)\n", dates.Format(from), fromDate)
+	fmt.Printf("To:   %s (%s)\n", dates.Format(to), toDate)
+	if len(history.Branches) > 0 {
+		fmt.Printf("Branches: %s\n", strings.Join(history.Branches, ", "))
+	}
+	if len(opts.Symptoms) > 1 {
+		fmt.Println("Symptoms:")
+		for i, symptom := range opts.Symptoms {
+			fmt.Printf("  %d. %s\n", i+1, symptom)
+		}
+		fmt.Println()
+	} else {
+		fmt.Printf("Bug: %s\n\n", bugDescription)
+	}
+
+	if err := repo.EnsureHistorySince(dates.Git(from)); err != nil {
+		return err
+	}
+
+	fmt.Println("Fetching commits in date range...")
+	commits, err := repo.GetCommitsBetweenDates(dates.Git(from), dates.Git(to), history)
+	if err != nil {
+		return fmt.Errorf("failed to get commits: %w", err)
+	}
+
+	if len(commits) == 0 {
+		fmt.Println("No commits found in the specified date range")
+		return nil
+	}
+
+	fmt.Printf("Found %d commits to analyze\n", len(commits))
+
+	systemPrompt := system_prompts.DebugAnalysis
+
+	ag, err := agent.New(systemPrompt, folder)
+	if err != nil {
+		return fmt.Errorf("failed to create agent: %w", err)
+	}
+	if pastCases {
+		ag.SetPastCases(similarDebugCases(repo, bugDescription))
+	}
+
+	var suspects []*agent.CommitAnalysis
+	if bisect {
+		analysis, err := runDebugBisect(ctx, folder, repo, ag, commits, bugDescription)
+		if err != nil {
+			return err
+		}
+		suspects = []*agent.CommitAnalysis{analysis}
+	} else {
+		fmt.Printf("\nAnalyzing commits with Claude AI (%s)...\n", suspectsInFlight(opts, len(commits)))
+		ag.SetSuspectOptions(opts)
+		suspects, err = ag.AnalyzeBugSuspects(ctx, commits, bugDescription)
+		if err != nil {
+			return fmt.Errorf("failed to analyze commits: %w", err)
+		}
+	}
+
+	var challenges map[string]*agent.CommitChallenge
+	if verify {
+		if suspects[0].Confidence < verifyBelow {
+			fmt.Printf("\nConfidence is only %d%%\n", suspects[0].Confidence)
+			challenges, err = runDebugVerify(ctx, folder, repo, suspects, bugDescription)
+			if err != nil {
+				return err
+			}
+		} else {
+			fmt.Printf("\nConfidence is %d%%, no need to verify the verdict\n", suspects[0].Confidence)
+		}
+	}
+	analysis := suspects[0]
+
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Println("DEBUG ANALYSIS RESULTS!!!")
+	fmt.Println(strings.Repeat("=", 70))
+
+	if !analysis.IsLikely {
+		fmt.Println("\nOH NO!!!!  Could not definitively identify the bug-causing commit")
+		if bisect {
+			fmt.Printf("\nBisect narrowed it down to: %s %s\n", analysis.CommitHash, analysis.CommitMsg)
+		}
+		fmt.Printf("\nExplanation:\n%s\n", analysis.Explanation)
+		printChallenge(challenges[analysis.CommitHash])
+	} else {
+		fmt.Println("\n✓ Likely bug-causing commit identified:")
+		fmt.Println()
+		fmt.Printf("Commit Hash:    %s\n", analysis.CommitHash)
+		fmt.Printf("Author:         %s\n", analysis.Author)
+		fmt.Printf("Date:           %s\n", analysis.Date)
+		fmt.Printf("Message:        %s\n", analysis.CommitMsg)
+		fmt.Printf("Confidence:     %d%%\n", analysis.Confidence)
+		fmt.Println()
+		fmt.Println("Explanation:")
+		fmt.Println(strings.Repeat("-", 70))
+		fmt.Println(analysis.Explanation)
+		fmt.Println(strings.Repeat("-", 70))
+		printChallenge(challenges[analysis.CommitHash])
+		fmt.Println()
+		fmt.Printf("To view the commit:\n  git show %s\n", analysis.CommitHash)
+		fmt.Println()
+	}
+	if len(opts.Symptoms) > 1 {
+		printSymptomSuspects(suspects, opts.Symptoms)
+	}
+
+	fmt.Println(strings.Repeat("=", 70))
+	if pastCases && analysis.IsLikely {
+		recordDebugOutcome(repo, bugDescription, analysis)
+	}
+	fmt.Println("\n✓ Debug analysis completed!")
+	return nil
+}
+
+// debugHistoryCases is how many similar past bugs the debug prompts mention.
+const debugHistoryCases = 3
+
+// similarDebugCases returns the bugs debugged before in the repository that
+// are most like this one, with the outcomes the user confirmed or denied.
+func similarDebugCases(repo *git.Repo, bugDescription string) []agent.PastCase {
+	repoURL, err := repo.GetRemoteURL()
+	if err != nil {
+		return nil
+	}
+	store, err := debughistory.Load()
+	if err != nil {
+		fmt.Printf("Warning: ignoring the debug history: %v\n", err)
+		return nil
+	}
+
+	var cases []agent.PastCase
+	for _, c := range store.Similar(repoURL, bugDescription, debugHistoryCases) {
+		cases = append(cases, agent.PastCase{
+			Bug:         c.Bug,
+			Commit:      c.Commit,
+			Subject:     c.Subject,
+			Explanation: c.Explanation,
+			Files:       c.Files,
+			Confirmed:   c.Confirmed,
+		})
+	}
+	if len(cases) > 0 {
+		fmt.Printf("Consulting %d similar bugs debugged before:\n", len(cases))
+		for _, c := range cases {
+			outcome := "confirmed"
+			if !c.Confirmed {
+				outcome = "not the cause"
+			}
+			fmt.Printf("  %.8s %s (%s): %s\n", c.Commit, c.Subject, outcome, c.Bug)
+		}
+	}
+	return cases
+}
+
+// recordDebugOutcome asks whether the blamed commit caused the bug and keeps
+// the answer, so later runs on similar bugs can learn from it. Without an
+// answer, as in CI mode, nothing is recorded.
+func recordDebugOutcome(repo *git.Repo, bugDescription string, analysis *agent.CommitAnalysis) {
+	repoURL, err := repo.GetRemoteURL()
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("\nWas %.8s the cause? Your answer helps future debug runs [y/n, Enter to skip]: ", analysis.CommitHash)
+	var confirmed bool
+	switch strings.ToLower(strings.TrimSpace(ask(""))) {
+	case "y", "yes":
+		confirmed = true
+	case "n", "no":
+	default:
+		return
+	}
+
+	store, err := debughistory.Load()
+	if err != nil {
+		fmt.Printf("Warning: the outcome was not recorded: %v\n", err)
+		return
+	}
+	files, err := repo.CommitFiles(analysis.CommitHash)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	store.Record(repoURL, debughistory.Case{
+		Bug:         bugDescription,
+		Commit:      analysis.CommitHash,
+		Subject:     analysis.CommitMsg,
+		Explanation: analysis.Explanation,
+		Files:       files,
+		Confirmed:   confirmed,
+	})
+	if err := store.Save(); err != nil {
+		fmt.Printf("Warning: the outcome was not recorded: %v\n", err)
+		return
+	}
+	fmt.Printf("✓ Recorded the outcome in %s\n", store.Path())
+}
+
+// printSymptomSuspects shows the top suspects of each symptom, and which
+// symptoms the likeliest commit overall does not explain.
+func printSymptomSuspects(suspects []*agent.CommitAnalysis, symptoms []string) {
+	fmt.Println("Suspects by symptom:")
+	for i, symptom := range symptoms {
+		fmt.Printf("\n%d. %s\n", i+1, symptom)
+		ranked := agent.RankBySymptom(suspects, i)
+		if len(ranked) == 0 || !ranked[0].Symptoms[i].IsLikely {
+			fmt.Println("   No likely commit: it may have another cause, or one outside the range")
+		}
+		for j, suspect := range ranked {
+			if j == verifyTop {
+				break
+			}
+			verdict := suspect.Symptoms[i]
+			mark := "·"
+			if verdict.IsLikely {
+				mark = "✓"
+			}
+			fmt.Printf("   %s %.8s %s (%d%%)\n", mark, suspect.CommitHash, suspect.CommitMsg, verdict.Confidence)
+		}
+	}
+
+	top := suspects[0]
+	if !top.IsLikely || len(top.Symptoms) == 0 {
+		fmt.Println()
+		return
+	}
+	var unexplained []string
+	for i, verdict := range top.Symptoms {
+		if !verdict.IsLikely {
+			unexplained = append(unexplained, strconv.Itoa(i+1))
+		}
+	}
+	if len(unexplained) == 0 {
+		fmt.Printf("\n✓ %.8s explains every symptom\n\n", top.CommitHash)
+	} else {
+		fmt.Printf("\n%.8s does not explain symptom %s, which may have a separate cause (see its suspects above)\n\n", top.CommitHash, strings.Join(unexplained, ", "))
+	}
+}
+
+// runIncidentMode chains the debug steps for an outage: it collects the
+// commits since a time, ranks them as suspects, argues against the top ones,
+// opens a conversation about them, and writes it all to a markdown report.
+func runIncidentMode(ctx context.Context, folder string, repo *git.Repo, description, sinceArg string, since time.Time, history git.HistoryOptions, reportPath string, pastCases bool, opts agent.SuspectOptions) error {
+	fmt.Println("\n=== INCIDENT MODE ===")
+	fmt.Printf("Incident: %s\n", description)
+	bugDescription := description
+	if len(opts.Symptoms) > 1 {
+		for i, symptom := range opts.Symptoms[1:] {
+			fmt.Printf("  %d. %s\n", i+2, symptom)
+		}
+		bugDescription = agent.DescribeSymptoms(opts.Symptoms)
+	}
+	fmt.Printf("Since:    %s (%s)\n", dates.Format(since), sinceArg)
+	fmt.Printf("Report:   %s\n\n", reportPath)
+
+	report := &incidentReport{Description: description, Symptoms: opts.Symptoms, Since: dates.Format(since), Started: time.Now()}
+	if repoURL, err := repo.GetRemoteURL(); err == nil {
+		report.Repository = redact.String(repoURL)
+	}
+
+	fmt.Println("[1/5] Collecting commits...")
+	if err := repo.EnsureHistorySince(dates.Git(since)); err != nil {
+		return err
+	}
+	commits, err := repo.GetCommitsBetweenDates(dates.Git(since), dates.Git(report.Started), history)
+	if err != nil {
+		return fmt.Errorf("failed to get commits: %w", err)
+	}
+	report.Commits = len(commits)
+	if len(commits) == 0 {
+		fmt.Printf("No commits since %s; the cause is probably not a code change\n", report.Since)
+		return writeIncidentReport(reportPath, report)
+	}
+	fmt.Printf("Found %d commits\n", len(commits))
+
+	fmt.Printf("\n[2/5] Triaging commits with Claude AI (%s)...\n", suspectsInFlight(opts, len(commits)))
+	ag, err := agent.New(system_prompts.DebugAnalysis, folder)
+	if err != nil {
+		return fmt.Errorf("failed to create agent: %w", err)
+	}
+	ag.SetSuspectOptions(opts)
+	if pastCases {
+		ag.SetPastCases(similarDebugCases(repo, bugDescription))
+	}
+	report.Suspects, err = ag.AnalyzeBugSuspects(ctx, commits, bugDescription)
+	if err != nil {
+		return fmt.Errorf("failed to analyze commits: %w", err)
+	}
+
+	fmt.Println("\n[3/5] Analyzing the top suspects in depth...")
+	report.Challenges, err = runDebugVerify(ctx, folder, repo, report.Suspects, bugDescription)
+	if err != nil {
+		return err
+	}
+	top := report.Suspects
+	if len(top) > verifyTop {
+		top = top[:verifyTop]
+	}
+	for i, suspect := range top {
+		fmt.Printf("  %d. %.8s %s (%d%%)\n", i+1, suspect.CommitHash, suspect.CommitMsg, suspect.Confidence)
+	}
+
+	fmt.Println("\n[4/5] Starting the investigation...")
+	var hashes []string
+	for _, suspect := range top {
+		hashes = append(hashes, suspect.CommitHash)
+	}
+	diff, err := repo.GetCommitsDiff(hashes)
+	if err != nil {
+		return fmt.Errorf("failed to get commit diff: %w", err)
+	}
+	explainerAgent, err := agent.New(system_prompts.CommitExplainer, folder)
+	if err != nil {
+		return fmt.Errorf("failed to create agent: %w", err)
+	}
+	explainer := agent.NewCommitExplainer(explainerAgent, "the top incident suspects", len(hashes), diff)
+
+	var question strings.Builder
+	fmt.Fprintf(&question, "We are in the middle of an incident: %s\n\nTriage ranked these commits as the likeliest causes:\n", bugDescription)
+	for _, suspect := range top {
+		fmt.Fprintf(&question, "- %.8s %s (confidence %d%%): %s\n", suspect.CommitHash, suspect.CommitMsg, suspect.Confidence, suspect.Explanation)
+	}
+	question.WriteString("\nHow could the likeliest of them cause the incident, what should we check to confirm it, and is reverting it safe or should we fix forward?")
+
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Println("Investigating the top suspects")
+	fmt.Println(strings.Repeat("=", 70))
+	conversationErr := explainer.StartConversation(ctx, question.String())
+	report.Investigation = explainer.History()
+
+	fmt.Println("\n[5/5] Writing the incident report...")
+	if err := writeIncidentReport(reportPath, report); err != nil {
+		return err
+	}
+	if pastCases && len(report.Suspects) > 0 && report.Suspects[0].IsLikely {
+		recordDebugOutcome(repo, bugDescription, report.Suspects[0])
+	}
+	if conversationErr != nil {
+		return fmt.Errorf("conversation error: %w", conversationErr)
+	}
+
+	fmt.Println("\n✓ Incident analysis completed!")
+	return nil
+}
+
+// incidentReport is what runIncidentMode found, for the markdown report.
+type incidentReport struct {
+	Description   string
+	Symptoms      []string // with several, the description is the first
+	Since         string
+	Repository    string
+	Started       time.Time
+	Commits       int
+	Suspects      []*agent.CommitAnalysis // ranked
+	Challenges    map[string]*agent.CommitChallenge
+	Investigation []agent.ConversationMessage
+}
+
+func writeIncidentReport(path string, report *incidentReport) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Incident report: %s\n\n", report.Description)
+	fmt.Fprintf(&b, "- **Generated:** %s\n", report.Started.Format("2006-01-02 15:04 MST"))
+	if report.Repository != "" {
+		fmt.Fprintf(&b, "- **Repository:** %s\n", report.Repository)
+	}
+	fmt.Fprintf(&b, "- **Commits since %s:** %d\n", report.Since, report.Commits)
+
+	if len(report.Suspects) == 0 {
+		b.WriteString("\nNo commits were made in the window, so the incident is probably not caused by a code change.\n")
+	} else {
+		b.WriteString("\n## Suspects\n\n")
+		b.WriteString("| Rank | Commit | Subject | Author | Date | Confidence | Verdict |\n")
+		b.WriteString("|------|--------|---------|--------|------|------------|---------|\n")
+		for i, suspect := range report.Suspects {
+			verdict := "unlikely"
+			if suspect.IsLikely {
+				verdict = "likely"
+			}
+			if challenge := report.Challenges[suspect.CommitHash]; challenge != nil && challenge.Refuted {
+				verdict = "refuted"
+			}
+			fmt.Fprintf(&b, "| %d | `%.8s` | %s | %s | %s | %d%% | %s |\n", i+1, suspect.CommitHash, tableCell(suspect.CommitMsg),
+				tableCell(suspect.Author), tableCell(suspect.Date), suspect.Confidence, verdict)
+		}
+
+		if len(report.Symptoms) > 1 {
+			b.WriteString("\n## Suspects by Symptom\n")
+			for i, symptom := range report.Symptoms {
+				fmt.Fprintf(&b, "\n### %d. %s\n\n", i+1, symptom)
+				ranked := agent.RankBySymptom(report.Suspects, i)
+				if len(ranked) == 0 || !ranked[0].Symptoms[i].IsLikely {
+					b.WriteString("No likely commit: this symptom may have another cause.\n\n")
+				}
+				for j, suspect := range ranked {
+					if j == verifyTop {
+						break
+					}
+					verdict := suspect.Symptoms[i]
+					likely := "unlikely"
+					if verdict.IsLikely {
+						likely = "likely"
+					}
+					fmt.Fprintf(&b, "- `%.8s` %s: %d%%, %s. %s\n", suspect.CommitHash, suspect.CommitMsg, verdict.Confidence, likely, verdict.Explanation)
+				}
+			}
+		}
+
+		b.WriteString("\n## Top Suspects\n")
+		for i, suspect := range report.Suspects {
+			if i == verifyTop {
+				break
+			}
+			fmt.Fprintf(&b, "\n### %d. `%.8s` %s\n\n%s\n", i+1, suspect.CommitHash, suspect.CommitMsg, suspect.Explanation)
+			if challenge := report.Challenges[suspect.CommitHash]; challenge != nil {
+				outcome := "holds up"
+				if challenge.Refuted {
+					outcome = "refuted"
+				}
+				fmt.Fprintf(&b, "\n**Verification (%s):** %s\n", outcome, challenge.Argument)
+			}
+		}
+
+		if len(report.Investigation) > 0 {
+			b.WriteString("\n## Investigation\n")
+			for i, msg := range report.Investigation {
+				if msg.Role != "user" {
+					fmt.Fprintf(&b, "\n%s\n", conversations.FinalAnswer(msg.Content))
+					continue
+				}
+				if i == 0 {
+					b.WriteString("\n**Q:** What caused it, how do we confirm it, and should we revert?\n")
+				} else {
+					fmt.Fprintf(&b, "\n**Q:** %s\n", msg.Content)
+				}
+			}
+		}
+
+		likeliest := report.Suspects[0]
+		b.WriteString("\n## Next Steps\n\n")
+		fmt.Fprintf(&b, "- Inspect the likeliest commit: `git show %s`\n", likeliest.CommitHash)
+		if likeliest.IsLikely {
+			fmt.Fprintf(&b, "- Revert it if the investigation confirms it: `git revert %s`\n", likeliest.CommitHash)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(redact.String(b.String())), 0644); err != nil {
+		return fmt.Errorf("failed to write incident report: %w", err)
+	}
+	fmt.Printf("✓ Wrote the incident report to %s\n", path)
+	return nil
+}
+
+// debugConcurrency is how many commits debug analyzes at once by default.
+const debugConcurrency = 8
+
+// suspectsInFlight describes how many commits are analyzed at once, e.g. "8
+// at a time".
+func suspectsInFlight(opts agent.SuspectOptions, commits int) string {
+	if opts.Concurrency > 0 && opts.Concurrency < commits {
+		return fmt.Sprintf("%d at a time", opts.Concurrency)
+	}
+	return "concurrently"
+}
+
+// verifyBelow is the confidence under which -verify challenges the verdict,
+// and verifyTop how many of the top suspects are challenged.
+const (
+	verifyBelow = 80
+	verifyTop   = 3
+)
+
+// runDebugVerify has Claude argue that each of the top suspects is not the
+// cause. Their confidence becomes what survives the argument, a refuted
+// suspect is no longer likely, and suspects is ranked again. It returns the
+// challenges by commit hash.
+func runDebugVerify(ctx context.Context, folder string, repo *git.Repo, suspects []*agent.CommitAnalysis, bugDescription string) (map[string]*agent.CommitChallenge, error) {
+	skeptic, err := agent.New(system_prompts.DebugVerify, folder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create agent: %w", err)
+	}
+
+	top := suspects
+	if len(top) > verifyTop {
+		top = top[:verifyTop]
+	}
+	fmt.Printf("\nVerifying the top %d suspects by arguing against them...\n", len(top))
+
+	challenges := make(map[string]*agent.CommitChallenge)
+	for _, suspect := range top {
+		diff, err := repo.GetCommitDiff(suspect.CommitHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get commit diff: %w", err)
+		}
+		challenge, err := skeptic.ChallengeCommit(ctx, suspect, diff, bugDescription)
+		if err != nil {
+			fmt.Printf("Warning: failed to verify %.8s: %v\n", suspect.CommitHash, err)
+			continue
+		}
+
+		outcome := "holds up"
+		if challenge.Refuted {
+			outcome = "refuted"
+			suspect.IsLikely = false
+		}
+		fmt.Printf("  %.8s %s: confidence %d%% -> %d%%, %s\n", suspect.CommitHash, suspect.CommitMsg, suspect.Confidence, challenge.Confidence, outcome)
+		suspect.Confidence = challenge.Confidence
+		challenges[suspect.CommitHash] = challenge
+	}
+
+	agent.RankSuspects(suspects)
+	return challenges, nil
+}
+
+// printChallenge shows the case against a verdict, when -verify made one.
+func printChallenge(challenge *agent.CommitChallenge) {
+	if challenge == nil {
+		return
+	}
+	fmt.Println()
+	if challenge.Refuted {
+		fmt.Println("Verification: refuted, the case that this commit is not the cause holds")
+	} else {
+		fmt.Println("Verification: holds up, the case that this commit is not the cause fails")
+	}
+	fmt.Println(strings.Repeat("-", 70))
+	fmt.Println(challenge.Argument)
+	fmt.Println(strings.Repeat("-", 70))
+}
+
+// bisectConfirmBelow is the confidence under which a bisect verdict is shown
+// to the user for confirmation.
+const bisectConfirmBelow = 60
+
+// runDebugBisect narrows the commits down with an AI-guided binary search,
+// then explains the culprit with the regular single-commit analysis.
+func runDebugBisect(ctx context.Context, folder string, repo *git.Repo, ag *agent.Agent, commits []string, bugDescription string) (*agent.CommitAnalysis, error) {
+	bisector, err := agent.New(system_prompts.DebugBisect, folder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create agent: %w", err)
+	}
+
+	fmt.Printf("\nBisecting with Claude AI (about %d steps instead of %d analyses)...\n", bisectSteps(len(commits)), len(commits))
+
+	review := func(commit string, verdict *agent.BisectVerdict) bool {
+		answer := "absent (introduced later)"
+		if verdict.BugPresent {
+			answer = "present"
+		}
+		fmt.Printf("  Claude: bug %s, confidence %d%%\n", answer, verdict.Confidence)
+		if verdict.Explanation != "" {
+			fmt.Printf("  %s\n", verdict.Explanation)
+		}
+
+		if verdict.Confidence >= bisectConfirmBelow {
+			return verdict.BugPresent
+		}
+
+		fmt.Print("  Low confidence. Is the bug present at this commit? (y/n, Enter to accept): ")
+		choice := ask("")
+		switch strings.ToLower(strings.TrimSpace(choice)) {
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+		return verdict.BugPresent
+	}
+
+	culprit, steps, err := bisector.BisectBug(ctx, commits, repo.GetCommitDiff, bugDescription, review)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bisect commits: %w", err)
+	}
+
+	fmt.Printf("\nBisect finished after %d steps, explaining the culprit...\n", steps)
+
+	analysis, err := ag.AnalyzeSingleCommit(ctx, culprit, bugDescription)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze commit: %w", err)
+	}
+	return analysis, nil
+}
+
+// bisectSteps is the number of midpoints a binary search over n commits checks.
+func bisectSteps(n int) int {
+	steps := 0
+	for n > 1 {
+		n = (n + 1) / 2
+		steps++
+	}
+	return steps
+}
+
+func runConfigMode() error {
+	s, err := settings.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	if err := s.InteractiveEdit(); err != nil {
+		return fmt.Errorf("failed to edit config: %w", err)
+	}
+
+	return nil
+}
+
+// runAuthLogin stores a token in the OS keychain and comments out its
+// plaintext copy in the config file.
+func runAuthLogin(secret settings.Secret) error {
+	s, err := settings.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+	if err := secrets.Available(); err != nil {
+		return fmt.Errorf("cannot use the %s: %w; %s can stay in %s instead", secrets.Name(), err, secret.Key, s.GetPath())
+	}
+
+	fmt.Println("\n=== AUTH LOGIN ===")
+	fmt.Printf("Paste the %s token (%s), then press Enter: ", secret.Name, secret.Key)
+	token, err := readSecret()
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return fmt.Errorf("no token entered")
+	}
+	redact.AddSecret(token)
+
+	if err := secrets.Store(secret.Key, token); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Stored %s in the %s\n", secret.Key, secrets.Name())
+
+	removed, err := s.RemovePlaintext(secret)
+	if err != nil {
+		fmt.Printf("Warning: remove the plaintext %s from %s yourself: %v\n", secret.Key, s.GetPath(), err)
+	} else if removed {
+		fmt.Printf("✓ Removed the plaintext %s from %s\n", secret.Key, s.GetPath())
+	}
+	if os.Getenv(secret.EnvVar) != "" {
+		fmt.Printf("Warning: %s is set and is used instead of the keychain\n", secret.EnvVar)
+	}
+	return nil
+}
+
+func runAuthLogout(secret settings.Secret) error {
+	fmt.Println("\n=== AUTH LOGOUT ===")
+	if err := secrets.Delete(secret.Key); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Removed %s from the %s\n", secret.Key, secrets.Name())
+
+	s, err := settings.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+	switch s.SecretSource(secret) {
+	case settings.SourceEnv:
+		fmt.Printf("%s is still set in the environment (%s)\n", secret.Key, secret.EnvVar)
+	case settings.SourceFile:
+		fmt.Printf("%s is still set in %s\n", secret.Key, s.GetPath())
+	}
+	return nil
+}
+
+func runAuthStatus() error {
+	s, err := settings.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	fmt.Println("\n=== AUTH STATUS ===")
+	if err := secrets.Available(); err != nil {
+		fmt.Printf("Keychain: %s (unavailable: %v)\n", secrets.Name(), err)
+	} else {
+		fmt.Printf("Keychain: %s\n", secrets.Name())
+	}
+
+	plaintext := false
+	fmt.Println()
+	for _, secret := range settings.Secrets {
+		source := s.SecretSource(secret)
+		if source == settings.SourceEnv {
+			source += " (" + secret.EnvVar + ")"
+		}
+		fmt.Printf("  %-10s %-18s %s\n", secret.Name, secret.Key, source)
+		plaintext = plaintext || source == settings.SourceFile
+	}
+
+	if plaintext {
+		fmt.Printf("\nSome tokens are in plaintext in %s; move them to the keychain\n", s.GetPath())
+		fmt.Println("with 'docu-jarvis auth login -provider <name>'")
+	}
+	return nil
+}
+
+// readSecret reads a line from stdin, hidden when stdin is a terminal.
+func readSecret() (string, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		defer fmt.Println()
+	} else {
+		if runtime.GOOS == "windows" {
+			cmd := exec.Command("powershell", "-NoProfile", "-Command",
+				"[Runtime.InteropServices.Marshal]::PtrToStringBSTR([Runtime.InteropServices.Marshal]::SecureStringToBSTR((Read-Host -AsSecureString)))")
+			cmd.Stdin = os.Stdin
+			cmd.Stderr = os.Stderr
+			out, err := cmd.Output()
+			if err != nil {
+				return "", fmt.Errorf("failed to read token: %w", err)
+			}
+			return strings.TrimSpace(string(out)), nil
+		}
+
+		stty := func(arg string) error {
+			cmd := exec.Command("stty", arg)
+			cmd.Stdin = os.Stdin
+			return cmd.Run()
+		}
+		if err := stty("-echo"); err == nil {
+			defer func() {
+				stty("echo")
+				fmt.Println()
+			}()
+		}
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read token: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func runUsageMode(days int) error {
+	since := time.Now().AddDate(0, 0, -days)
+	runs, err := usage.LoadHistory(since)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n=== USAGE (last %d days) ===\n", days)
+	if len(runs) == 0 {
+		fmt.Println("No Claude usage recorded")
+		return nil
+	}
+
+	var total usage.ModelUsage
+	byCommand := make(map[string]*usage.ModelUsage)
+	byModel := make(map[string]*usage.ModelUsage)
+	byDay := make(map[string]*usage.ModelUsage)
+	for _, run := range runs {
+		runTotal := run.Total()
+		total.Add(runTotal)
+		addUsage(byCommand, run.Command, runTotal)
+		addUsage(byDay, run.Time.Local().Format("2006-01-02"), runTotal)
+		for model, m := range run.Models {
+			addUsage(byModel, model, *m)
+		}
+	}
+
+	printUsageTable("By day", byDay)
+	printUsageTable("By command", byCommand)
+	printUsageTable("By model", byModel)
+
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Printf("Total: %d runs, %d requests, %d input / %d output tokens, %s\n",
+		len(runs), total.Requests, total.InputTokens, total.OutputTokens, usage.FormatCost(total))
+	if total.Estimated {
+		fmt.Println("Estimated costs use list prices for requests Claude Code did not report a cost for.")
+	}
+	fmt.Println(strings.Repeat("=", 70))
+
+	return nil
+}
+
+// runLogsTail prints the last records of a run's log at minLevel and above,
+// the latest run's unless file is given. With follow, it then prints the
+// records as they are written, across rotations, until ctx is done.
+func runLogsTail(ctx context.Context, file string, count int, follow bool, minLevel slog.Level, raw bool) error {
+	if file == "" {
+		files, err := logging.Files()
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			fmt.Println("No logs yet - a run logs once it calls Claude")
+			return nil
+		}
+		file = files[0]
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open log: %w", err)
+	}
+	defer func() { f.Close() }()
+
+	// Lines from before the log was structured have no level
+	format := func(line string) (string, bool) {
+		record, ok := logging.ParseRecord(line)
+		if !ok {
+			return line, minLevel <= slog.LevelDebug
+		}
+		if record.Level < minLevel {
+			return "", false
+		}
+		if raw {
+			return line, true
+		}
+		return record.String(), true
+	}
+	reader := bufio.NewReader(f)
+	var partial string
+	next := func() (string, bool) {
+		chunk, err := reader.ReadString('\n')
+		partial += chunk
+		if err != nil {
+			return "", false
+		}
+		line := strings.TrimSuffix(partial, "\n")
+		partial = ""
+		return line, true
+	}
+
+	fmt.Printf("==> %s <==\n", file)
+	var last []string
+	for line, ok := next(); ok; line, ok = next() {
+		if out, show := format(line); show {
+			if last = append(last, out); len(last) > count {
+				last = last[1:]
+			}
+		}
+	}
+	for _, out := range last {
+		fmt.Println(out)
+	}
+	if !follow {
+		return nil
+	}
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		for line, ok := next(); ok; line, ok = next() {
+			if out, show := format(line); show {
+				fmt.Println(out)
+			}
+		}
+
+		// The file was rotated: the rest of the run logs to a new one
+		current, err := os.Stat(file)
+		opened, openErr := f.Stat()
+		if err != nil || openErr != nil || os.SameFile(current, opened) {
+			continue
+		}
+		rotated, err := os.Open(file)
+		if err != nil {
+			continue
+		}
+		f.Close()
+		f, partial = rotated, ""
+		reader = bufio.NewReader(f)
+	}
+}
+
+func addUsage(totals map[string]*usage.ModelUsage, key string, m usage.ModelUsage) {
+	if totals[key] == nil {
+		totals[key] = &usage.ModelUsage{}
+	}
+	totals[key].Add(m)
+}
+
+func printUsageTable(title string, totals map[string]*usage.ModelUsage) {
+	var keys []string
+	for key := range totals {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Printf("\n%s:\n", title)
+	for _, key := range keys {
+		m := totals[key]
+		fmt.Printf("  %-32s %5d requests  %10d tokens  %s\n", key, m.Requests, m.InputTokens+m.OutputTokens, usage.FormatCost(*m))
+	}
+}
+
+func runCheckStagingSettings() error {
+	fmt.Println("\n=== CODE STANDARDS SETTINGS ===")
+	fmt.Println("Note: Use 'docu-jarvis config' to edit all settings including code standards")
+	fmt.Println()
+
+	return runConfigMode()
+}
+
+func runCheckStagingMode(ctx context.Context, folder string, repo *git.Repo, docsImpact, queueDocs bool) error {
+	fmt.Println("\n=== CHECK STAGING MODE ===")
+
+	settings, err := settings.LoadForRepo(repo.GetLocalPath())
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	if settings.IsEmpty() {
+		fmt.Println("OH NO!!!!  No code standards configured!")
+		fmt.Println("\nPlease configure your code standards first:")
+		fmt.Println("  docu-jarvis check-staging settings")
+		fmt.Println()
+		return fmt.Errorf("code standards not configured")
+	}
+	if err := loadCodeStandards(settings); err != nil {
+		return err
+	}
+
+	fmt.Printf("Loaded code standards from: %s\n", settings.GetPath())
+
+	fmt.Println("Getting staged changes...")
+	stagedDiff, err := repo.GetStagedDiff()
+	if err != nil {
+		return fmt.Errorf("failed to get staged changes: %w", err)
+	}
+
+	if strings.TrimSpace(stagedDiff) == "" {
+		fmt.Println("No staged changes found!")
+		fmt.Println("\nStage some changes first:")
+		fmt.Println("  git add <files>")
+		return nil
+	}
+
+	fmt.Printf("Found staged changes (%d bytes)\n", len(stagedDiff))
+
+	systemPrompt := system_prompts.AssertCodeQuality
+
+	fmt.Println("Reviewing code with Claude AI...")
+	ag, err := agent.New(systemPrompt, folder)
+	if err != nil {
+		return fmt.Errorf("failed to create agent: %w", err)
+	}
+
+	review, err := ag.ReviewStagedCode(ctx, stagedDiff, settings.CodeStandards)
+	if err != nil {
+		return fmt.Errorf("failed to review code: %w", err)
+	}
+
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Println("CODE QUALITY REVIEW")
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println()
+
+	fmt.Println(review.FullResponse)
+	fmt.Println()
+
+	if review.ComplianceStatus != "" {
+		fmt.Println(strings.Repeat("=", 70))
+		fmt.Printf("COMPLIANCE STATUS: %s\n", review.ComplianceStatus)
+		fmt.Println(strings.Repeat("=", 70))
+	}
+
+	if review.Recommendations != "" {
+		fmt.Println("\nRECOMMENDATIONS:")
+		fmt.Println(strings.Repeat("-", 70))
+		fmt.Println(review.Recommendations)
+		fmt.Println(strings.Repeat("-", 70))
+	}
+
+	if docsImpact {
+		if _, err := runDocsImpact(ctx, folder, repo, stagedDiff, queueDocs); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("\n✓ Code review completed!")
+	return nil
+}
+
+// runCheckStagingJSON writes the review as a QualityReport to out. Progress
+// output still goes to stdout, so callers point stdout elsewhere first. A
+// review that is not compliant exits with status 2.
+func runCheckStagingJSON(ctx context.Context, out io.Writer, folder string, repo *git.Repo, docsImpact, queueDocs, onlyNew bool, passes int) error {
+	report, err := reviewStagedJSON(ctx, folder, repo, docsImpact, queueDocs, onlyNew, passes)
+	if err != nil {
+		return err
+	}
+	if ci.Enabled() {
+		reportToCI(report)
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	if !report.Compliant {
+		return &exitCodeError{code: 2, err: fmt.Errorf("staged code is not compliant: %s", report.ComplianceStatus)}
+	}
+	return nil
+}
+
+// runCheckStagingGate prints the structured review, for a CI log, with
+// -only-new or with -passes, annotates the findings in CI, and exits with
+// status 2 when the review is not compliant.
+func runCheckStagingGate(ctx context.Context, folder string, repo *git.Repo, docsImpact, queueDocs, onlyNew bool, passes int) error {
+	switch {
+	case ci.Enabled():
+		fmt.Println("\n=== CHECK STAGING MODE (CI) ===")
+	case onlyNew:
+		fmt.Println("\n=== CHECK STAGING MODE (NEW VIOLATIONS ONLY) ===")
+	default:
+		fmt.Println("\n=== CHECK STAGING MODE (CONSENSUS) ===")
+	}
+
+	report, err := reviewStagedJSON(ctx, folder, repo, docsImpact, queueDocs, onlyNew, passes)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Printf("COMPLIANCE STATUS: %s\n", report.ComplianceStatus)
+	fmt.Println(strings.Repeat("=", 70))
+	if report.Summary != "" {
+		fmt.Printf("\n%s\n", report.Summary)
+	}
+	for _, finding := range report.Findings {
+		printFinding(finding, report.Passes)
+	}
+	fmt.Println()
+	if report.Passes > 1 {
+		printMinorityFindings(report)
+	}
+	if report.Baselined > 0 {
+		fmt.Printf("%d pre-existing findings left out (see %s)\n\n", report.Baselined, baseline.FileName)
+	}
+
+	reportToCI(report)
+
+	if !report.Compliant {
+		return &exitCodeError{code: 2, err: fmt.Errorf("staged code is not compliant: %s", report.ComplianceStatus)}
+	}
+	fmt.Println("✓ Code review completed!")
+	return nil
+}
+
+func printFinding(finding agent.QualityFinding, passes int) {
+	if passes > 1 {
+		fmt.Printf("\n[%s] %s (%d/%d reviews)\n", finding.Severity, findingLocation(finding), finding.Votes, passes)
+	} else {
+		fmt.Printf("\n[%s] %s\n", finding.Severity, findingLocation(finding))
+	}
+	fmt.Printf("  %s\n", finding.Issue)
+	if finding.Recommendation != "" {
+		fmt.Printf("  Recommendation: %s\n", finding.Recommendation)
+	}
+}
+
+// printMinorityFindings prints the rest of the union view: the findings too
+// few of the reviews agreed on to count.
+func printMinorityFindings(report *agent.QualityReport) {
+	var minority []agent.QualityFinding
+	for _, finding := range report.Union {
+		if finding.Votes*2 <= report.Passes {
+			minority = append(minority, finding)
+		}
+	}
+	if len(minority) == 0 {
+		fmt.Printf("All %d reviews agreed on every finding\n\n", report.Passes)
+		return
+	}
+
+	fmt.Println(strings.Repeat("-", 70))
+	fmt.Printf("WITHOUT A MAJORITY (%d, not counted toward compliance)\n", len(minority))
+	fmt.Println(strings.Repeat("-", 70))
+	for _, finding := range minority {
+		printFinding(finding, report.Passes)
+	}
+	fmt.Println()
+}
+
+// reviewStagedJSON reviews the staged changes against the code standards.
+// With onlyNew, the findings the changes did not introduce are left out. With
+// more than one pass, that many reviews run and only the findings most of
+// them agree on are kept.
+func reviewStagedJSON(ctx context.Context, folder string, repo *git.Repo, docsImpact, queueDocs, onlyNew bool, passes int) (*agent.QualityReport, error) {
+	settings, err := settings.LoadForRepo(repo.GetLocalPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	if settings.IsEmpty() {
+		return nil, fmt.Errorf("code standards not configured (run 'docu-jarvis check-staging settings')")
+	}
+	if err := loadCodeStandards(settings); err != nil {
+		return nil, err
+	}
+
+	stagedDiff, err := repo.GetStagedDiff()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get staged changes: %w", err)
+	}
+
+	if strings.TrimSpace(stagedDiff) == "" {
+		return nil, fmt.Errorf("no staged changes found")
+	}
+
+	fmt.Println("Reviewing code with Claude AI...")
+	ag, err := agent.New(system_prompts.AssertCodeQuality, folder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create agent: %w", err)
+	}
+
+	var report *agent.QualityReport
+	if passes > 1 {
+		fmt.Printf("Running %d independent reviews...\n", passes)
+		report, err = ag.ReviewStagedCodeConsensus(ctx, stagedDiff, settings.CodeStandards, passes)
+	} else {
+		report, err = ag.ReviewStagedCodeJSON(ctx, stagedDiff, settings.CodeStandards)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to review code: %w", err)
+	}
+	if onlyNew {
+		if err := keepNewFindings(repo, report, stagedDiff); err != nil {
+			return nil, err
+		}
+	}
+
+	if docsImpact {
+		if report.DocsImpact, err = runDocsImpact(ctx, folder, repo, stagedDiff, queueDocs); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// keepNewFindings leaves out of the report the findings the staged changes
+// did not introduce: those on lines the changes did not add, and those the
+// baseline records.
+func keepNewFindings(repo *git.Repo, report *agent.QualityReport, stagedDiff string) error {
+	known, err := baseline.Load(repo.GetLocalPath())
+	if err != nil {
+		return err
+	}
+	isKnown := known.Known()
+	added := git.AddedLines(stagedDiff)
+	report.Keep(func(finding agent.QualityFinding) bool {
+		if finding.Line > 0 && !added[strings.TrimPrefix(finding.File, "b/")][finding.Line] {
+			return false
+		}
+		return !isKnown(finding)
+	})
+	return nil
+}
+
+// runUpdateBaseline records the findings of the staged review in the
+// baseline, so -only-new stops failing on them.
+func runUpdateBaseline(ctx context.Context, folder string, repo *git.Repo) error {
+	fmt.Println("\n=== UPDATE BASELINE ===")
+
+	known, err := baseline.Load(repo.GetLocalPath())
+	if err != nil {
+		return err
+	}
+	report, err := reviewStagedJSON(ctx, folder, repo, false, false, false, 1)
+	if err != nil {
+		return err
+	}
+
+	for _, finding := range report.Findings {
+		fmt.Printf("  [%s] %s: %s\n", finding.Severity, findingLocation(finding), finding.Standard)
+	}
+	added := known.Record(report.Findings)
+	if added == 0 {
+		fmt.Printf("\nNo new violations to record; %s is unchanged\n", baseline.FileName)
+		return nil
+	}
+	if err := known.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("\n✓ Recorded %d violations in %s\n", added, known.Path())
+	fmt.Println("Commit it so 'check-staging -only-new' ignores them everywhere")
+	return nil
+}
+
+// loadCodeStandards puts the shared standards of code_standards_source, if
+// set, ahead of the configured ones.
+func loadCodeStandards(s *settings.Settings) error {
+	if s.StandardsSource == "" {
+		return nil
+	}
+	source, err := standards.ParseSource(s.StandardsSource)
+	if err != nil {
+		return err
+	}
+	shared, err := standards.Load(source)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d shared code standards from %s\n", len(shared), source)
+
+	seen := make(map[string]bool)
+	var merged []string
+	for _, standard := range append(shared, strings.Split(s.CodeStandards, "\n")...) {
+		standard = strings.TrimSpace(standard)
+		if standard != "" && !seen[standard] {
+			seen[standard] = true
+			merged = append(merged, standard)
+		}
+	}
+	s.CodeStandards = strings.Join(merged, "\n")
+	return nil
+}
+
+// runInferStandards drafts code standards for a repository without any, from
+// its code and the comments its reviewers leave, and saves them once the user
+// has edited them.
+func runInferStandards(ctx context.Context, folder string, repo *git.Repo) error {
+	fmt.Println("\n=== INFER STANDARDS MODE ===")
+
+	s, err := settings.LoadForRepo(repo.GetLocalPath())
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	fmt.Println("Reading recent review comments...")
+	var comments []agent.FeedbackComment
+	prComments, err := repo.RecentReviewComments(ctx, 100)
+	if err != nil {
+		fmt.Printf("Warning: drafting from the code alone, review comments unavailable: %v\n", err)
+	}
+	for _, c := range prComments {
+		comments = append(comments, agent.FeedbackComment{Author: c.Author, Path: c.Path, Line: c.Line, Body: c.Body})
+	}
+	if err == nil {
+		fmt.Printf("Found %d review comments\n", len(comments))
+	}
+
+	fmt.Println("Analyzing the codebase with Claude AI...")
+	ag, err := agent.New(system_prompts.InferStandards, folder)
+	if err != nil {
+		return fmt.Errorf("failed to create agent: %w", err)
+	}
+	inferred, err := ag.InferStandards(ctx, comments)
+	if err != nil {
+		return fmt.Errorf("failed to infer code standards: %w", err)
+	}
+
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Println("DRAFT CODE STANDARDS")
+	fmt.Println(strings.Repeat("=", 70))
+	if inferred.Summary != "" {
+		fmt.Printf("\n%s\n", inferred.Summary)
+	}
+	fmt.Println()
+	for _, standard := range inferred.Standards {
+		fmt.Printf("  - %s\n", standard)
+	}
+	fmt.Println("\n" + strings.Repeat("=", 70))
+
+	standards := inferred.Standards
+	if !ci.Enabled() {
+		if standards, err = editStandards(standards); err != nil {
+			return err
+		}
+	}
+	if len(standards) == 0 {
+		fmt.Println("\nNo standards left after editing, nothing saved")
+		return nil
+	}
+
+	existing := 0
+	if strings.TrimSpace(s.CodeStandards) != "" {
+		existing = len(strings.Split(s.CodeStandards, "\n"))
+	}
+	if existing > 0 {
+		fmt.Printf("\nSave %d standards to %s (replacing %d existing)? (y/n): ", len(standards), s.GetPath(), existing)
+	} else {
+		fmt.Printf("\nSave %d standards to %s? (y/n): ", len(standards), s.GetPath())
+	}
+	if answer := ask("n"); strings.ToLower(answer) != "y" && strings.ToLower(answer) != "yes" {
+		fmt.Println("Standards not saved")
+		return nil
+	}
+
+	if err := s.SaveCodeStandards(standards); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Saved %d code standards to %s\n", len(standards), s.GetPath())
+	if rc, err := settings.LoadRepoConfig(repo.GetLocalPath()); err == nil && rc != nil && len(rc.CodeStandards) > 0 {
+		fmt.Printf("Warning: the code_standards in %s override them in this repository\n", s.GetRepoConfigPath())
+	}
+	fmt.Println("Review staged code with 'docu-jarvis check-staging'")
+	return nil
+}
+
+// editStandards opens the standards in the editor, one per line, and returns
+// them as saved. Blank lines and lines starting with # are dropped.
+func editStandards(standards []string) ([]string, error) {
+	file, err := os.CreateTemp("", "docu-jarvis-standards-*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create standards file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	content := "# Edit the code standards, one per line. Lines starting with # are ignored.\n" +
+		"# Delete a line to drop a standard; save and exit when done.\n" +
+		strings.Join(standards, "\n") + "\n"
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write standards file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write standards file: %w", err)
+	}
+
+	fmt.Printf("\nOpening the draft in %s...\n", settings.Editor())
+	if err := settings.EditFile(file.Name()); err != nil {
+		return nil, err
+	}
+
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read standards file: %w", err)
+	}
+	var result []string
+	for _, line := range strings.Split(string(edited), "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "- "))
+		if line != "" && !strings.HasPrefix(line, "#") {
+			result = append(result, line)
+		}
+	}
+	return result, nil
+}
+
+// reportToCI annotates the findings and, on GitHub Actions, adds the review
+// to the job summary. Critical and major findings are errors, the rest
+// warnings.
+func reportToCI(report *agent.QualityReport) {
+	if !ci.GitHubActions() {
+		return
+	}
+
+	var annotations []ci.Annotation
+	for _, finding := range report.Findings {
+		level := "warning"
+		if finding.Severity == "critical" || finding.Severity == "major" {
+			level = "error"
+		}
+		message := finding.Issue
+		if finding.Recommendation != "" {
+			message += "\n\nRecommendation: " + finding.Recommendation
+		}
+		annotations = append(annotations, ci.Annotation{
+			Level:   level,
+			File:    finding.File,
+			Line:    finding.Line,
+			Title:   finding.Standard,
+			Message: message,
+		})
+	}
+	ci.Annotate(os.Stdout, annotations)
+
+	if err := ci.WriteSummary(reviewSummary(report)); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+}
+
+// reviewSummary returns the review as markdown for the job summary.
+func reviewSummary(report *agent.QualityReport) string {
+	var b strings.Builder
+	icon := "✅"
+	if !report.Compliant {
+		icon = "❌"
+	}
+	fmt.Fprintf(&b, "## %s docu-jarvis check-staging: %s\n\n", icon, report.ComplianceStatus)
+	if report.Summary != "" {
+		fmt.Fprintf(&b, "%s\n\n", report.Summary)
+	}
+
+	if len(report.Findings) > 0 {
+		b.WriteString("| Severity | Location | Standard | Issue |\n|---|---|---|---|\n")
+		for _, finding := range report.Findings {
+			fmt.Fprintf(&b, "| %s | `%s` | %s | %s |\n", finding.Severity, findingLocation(finding),
+				tableCell(finding.Standard), tableCell(finding.Issue))
+		}
+		b.WriteString("\n")
+	}
+
+	if impact := report.DocsImpact; impact != nil && len(impact.AffectedDocs) > 0 {
+		b.WriteString("### Docs impact\n\n")
+		for _, doc := range impact.AffectedDocs {
+			status := "related"
+			if doc.RequiresUpdate {
+				status = "needs an update"
+			}
+			fmt.Fprintf(&b, "- `%s` %s: %s\n", doc.File, status, doc.Reason)
+		}
+	}
+	return b.String()
+}
+
+func findingLocation(finding agent.QualityFinding) string {
+	if finding.Line > 0 {
+		return fmt.Sprintf("%s:%d", finding.File, finding.Line)
+	}
+	return finding.File
+}
+
+// tableCell keeps text on one line of a markdown table.
+func tableCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "\r", "", "\n", " ").Replace(s)
+}
+
+func runDocsImpact(ctx context.Context, folder string, repo *git.Repo, stagedDiff string, queueDocs bool) (*agent.DocsImpact, error) {
+	fmt.Println("\nAnalyzing documentation impact...")
+
+	ag, err := agent.New(system_prompts.DocsImpact, folder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create agent: %w", err)
+	}
+	ag.SetDocsDirs(repo.GetDocsDirs())
+
+	impact, err := ag.AnalyzeDocsImpact(ctx, stagedDiff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze docs impact: %w", err)
+	}
+
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Println("DOCS IMPACT")
+	fmt.Println(strings.Repeat("=", 70))
+
+	if impact.Summary != "" {
+		fmt.Printf("\n%s\n", impact.Summary)
+	}
+
+	if len(impact.AffectedDocs) == 0 {
+		fmt.Println("\nNo documentation files are affected by this change")
+	}
+
+	for _, doc := range impact.AffectedDocs {
+		if doc.RequiresUpdate {
+			fmt.Printf("\nOH NO!!!!  %s needs an update\n", doc.File)
+		} else {
+			fmt.Printf("\n  %s is related\n", doc.File)
+		}
+		fmt.Printf("    %s\n", doc.Reason)
+	}
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 70))
+
+	required := impact.RequiredUpdates()
+	if !queueDocs || len(required) == 0 {
+		return impact, nil
+	}
+
+	repoURL, err := repo.GetRemoteURL()
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue docs: %w", err)
+	}
+
+	queue, err := docqueue.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load doc queue: %w", err)
+	}
+	queue.Add(repoURL, required...)
+	if err := queue.Save(); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("\nQueued %d doc(s) for the next update run:\n", len(required))
+	fmt.Println("  docu-jarvis update-docs queued")
+	return impact, nil
+}
+
+// runChangedUpdateMode updates only the docs covering the code changed since
+// a commit or date: those the repository's docs map lists for it or, when it
+// has none, those Claude finds the changes require updating.
+func runChangedUpdateMode(ctx context.Context, folder string, repo *git.Repo, since, customPrompt string, dryRun, confirmEdits, noCache bool, batch agent.BatchOptions, summaryOut io.Writer) error {
+	fmt.Printf("Finding code changed since %s...\n", since)
+	changes, err := repo.ChangesSince(since)
+	if err != nil {
+		return err
+	}
+
+	// Docs edited directly need no update for it
+	var code []string
+	for _, file := range changes.Files {
+		if !agent.IsDocFile(file) {
+			code = append(code, file)
+		}
+	}
+	if len(code) == 0 {
+		fmt.Printf("No code changed since %s\n", since)
+		return nil
+	}
+	fmt.Printf("%d file(s) changed since %s\n", len(code), since)
+
+	docMap, err := repo.LoadDocMap()
+	if err != nil {
+		return err
+	}
+
+	var docs []string
+	if docMap != nil {
+		mapped, unmapped := docMap.Docs(code)
+		fmt.Printf("Using %s: %d doc(s) cover the changes\n", git.DocMapFileName, len(mapped))
+		if len(unmapped) > 0 {
+			fmt.Printf("Warning: %s covers none of: %s\n", git.DocMapFileName, strings.Join(unmapped, ", "))
+		}
+		for _, doc := range mapped {
+			path := filepath.Join(repo.GetLocalPath(), filepath.FromSlash(doc))
+			rel, err := filepath.Rel(folder, path)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				fmt.Printf("Warning: skipping %s, which is outside the scope\n", doc)
+				continue
+			}
+			if _, err := os.Stat(path); err != nil {
+				fmt.Printf("Warning: skipping %s, listed in %s: it does not exist\n", doc, git.DocMapFileName)
+				continue
+			}
+			docs = append(docs, filepath.ToSlash(rel))
+		}
+	} else {
+		impact, err := runDocsImpact(ctx, folder, repo, changes.Diff, false)
+		if err != nil {
+			return err
+		}
+		docs = impact.RequiredUpdates()
+	}
+
+	if len(docs) == 0 {
+		fmt.Println("No docs need updating for these changes")
+		return nil
+	}
+	fmt.Printf("Found %d doc(s) affected by the changes: %s\n", len(docs), strings.Join(docs, ", "))
+
+	_, err = runUpdateMode(ctx, folder, repo, docs, customPrompt, "", dryRun, confirmEdits, noCache, nil, batch, summaryOut)
+	return err
+}
+
+func runQueuedUpdateMode(ctx context.Context, folder string, repo *git.Repo, customPrompt string, dryRun, confirmEdits, noCache bool, batch agent.BatchOptions, summaryOut io.Writer) error {
+	repoURL, err := repo.GetRemoteURL()
+	if err != nil {
+		return err
+	}
+
+	queue, err := docqueue.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load doc queue: %w", err)
+	}
+
+	files := queue.Get(repoURL)
+	if len(files) == 0 {
+		fmt.Println("No queued documentation updates for this repository")
+		return nil
+	}
+
+	fmt.Printf("Found %d queued docs: %s\n", len(files), strings.Join(files, ", "))
+
+	settled, err := runUpdateMode(ctx, folder, repo, files, customPrompt, "", dryRun, confirmEdits, noCache, nil, batch, summaryOut)
+	if err != nil {
+		return err
+	}
+
+	// Failed and rejected docs stay queued for the next run
+	if len(settled) > 0 {
+		queue.Remove(repoURL, settled...)
+		if err := queue.Save(); err != nil {
+			return err
+		}
+	}
+	if left := len(queue.Get(repoURL)); left > 0 {
+		fmt.Printf("%d docs are still queued\n", left)
+	}
+
+	return nil
+}
+
+func runVersionCheck() error {
+	currentVersion := updater.GetCurrentVersion()
+	fmt.Printf("Docu-Jarvis version: %s\n", currentVersion)
+	fmt.Printf("Installed via: %s\n", updater.DetectInstallMethod())
+	if netguard.Disabled() {
+		fmt.Println("\nSkipping update check (network access is disabled)")
+		return nil
+	}
+	fmt.Println("\nChecking for updates...")
+
+	updater.AutoCheckForUpdates(currentVersion, false)
+	return nil
+}
+
+func runUpdate() error {
+	currentVersion := updater.GetCurrentVersion()
+	fmt.Printf("Current version: %s\n", currentVersion)
+	fmt.Println("Checking for updates...")
+
+	err := updater.UpdateToLatest(currentVersion)
+	if err != nil {
+		return fmt.Errorf("update failed: %w", err)
+	}
+
+	fmt.Println("\n✓ Update completed successfully!")
+	fmt.Println("Please restart docu-jarvis to use the new version")
+	return nil
+}
+
+func runExplainMode(ctx context.Context, folder string, repo *git.Repo, commitHash, initialQuestion string, resume bool) error {
+	fmt.Println("\n=== COMMIT EXPLAINER MODE ===")
+	fmt.Printf("Commit: %s\n", commitHash)
+
+	fmt.Println("Fetching commit details...")
+	commits, err := repo.ResolveCommits(commitHash)
+	if err != nil {
+		return err
+	}
+	if len(commits) > 1 {
+		fmt.Printf("Range: %d commits\n", len(commits))
+	}
+
+	commitDiff, err := repo.GetCommitsDiff(commits)
+	if err != nil {
+		return fmt.Errorf("failed to get commit diff: %w", err)
+	}
+
+	// Ranges and branches are resolved against the selected branch already
+	if repo.GetBranch() != "" && len(commits) == 1 {
+		onBranch, err := repo.ContainsCommit(commits[0])
+		if err != nil {
+			return err
+		}
+		if !onBranch {
+			fmt.Printf("OH NO!!!!  Commit %s is not on branch %s; the codebase shown is that branch\n", commitHash, repo.GetBranch())
+		}
+	}
+
+	systemPrompt := system_prompts.CommitExplainer
+
+	fmt.Println("Initializing AI agent...")
+	ag, err := agent.New(systemPrompt, folder)
+	if err != nil {
+		return fmt.Errorf("failed to create agent: %w", err)
+	}
+
+	explainer := agent.NewCommitExplainer(ag, commitHash, len(commits), commitDiff)
+
+	id := shortHash(commits[0])
+	if len(commits) > 1 {
+		id = shortHash(commits[0]) + ".." + shortHash(commits[len(commits)-1])
+	}
+	transcript, err := explainTranscript(repo, id, commitHash, resume)
+	if err != nil {
+		return err
+	}
+	explainer.SetTranscript(transcript)
+
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Printf("Explaining commit: %s\n", commitHash)
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println()
+
+	if resume {
+		err = explainer.ResumeConversation(ctx, initialQuestion)
+	} else {
+		err = explainer.StartConversation(ctx, initialQuestion)
+	}
+	if err != nil {
+		return fmt.Errorf("conversation error: %w", err)
+	}
+
+	fmt.Printf("\nConversation saved, continue it with:\n  docu-jarvis explain %s -resume\n", commitHash)
+	return nil
+}
+
+// explainTranscript returns the saved conversation to resume, or a new one
+// to save the conversation to.
+func explainTranscript(repo *git.Repo, id, subject string, resume bool) (*conversations.Conversation, error) {
+	if resume {
+		if !conversations.Exists(id) {
+			return nil, fmt.Errorf("no saved conversation about %s to resume (see 'docu-jarvis conversations list')", subject)
+		}
+		transcript, err := conversations.Load(id)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Resuming the conversation of %s (%d messages)\n", transcript.Updated.Local().Format("2006-01-02 15:04"), len(transcript.Messages))
+		return transcript, nil
+	}
+
+	if conversations.Exists(id) {
+		fmt.Printf("Warning: starting over replaces the saved conversation about %s; use -resume to continue it\n", subject)
+	}
+	var repoURL string
+	if url, err := repo.GetRemoteURL(); err == nil {
+		repoURL = redact.String(url)
+	}
+	return conversations.New(id, subject, repoURL)
+}
+
+// shortHash abbreviates a full commit hash for a conversation ID.
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+func runDiffDeploysMode(ctx context.Context, folder string, repo *git.Repo, prod, staging, initialQuestion string, resume bool) error {
+	fmt.Println("\n=== DEPLOY DIFF MODE ===")
+	fmt.Printf("Production: %s\n", prod)
+	fmt.Printf("Staging:    %s\n", staging)
+
+	fmt.Println("Comparing deployed commits...")
+	divergence, err := repo.DeployDivergence(prod, staging)
+	if err != nil {
+		return err
+	}
+	if divergence.StagingOnly == 0 && divergence.ProdOnly == 0 {
+		fmt.Println("\n✓ Production and staging run the same code")
+		return nil
+	}
+	fmt.Printf("Staging is %d commit(s) ahead of production", divergence.StagingOnly)
+	if divergence.ProdOnly > 0 {
+		fmt.Printf(", and production has %d commit(s) staging doesn't", divergence.ProdOnly)
+	}
+	fmt.Println()
+	if divergence.DiffTruncated {
+		fmt.Println("Warning: the net diff is too large and was truncated; every commit is still listed")
+	}
+
+	fmt.Println("Initializing AI agent...")
+	ag, err := agent.New(system_prompts.DeployDiff, folder)
+	if err != nil {
+		return fmt.Errorf("failed to create agent: %w", err)
+	}
+
+	explainer := agent.NewDeployExplainer(ag, divergence.Prod, divergence.Staging, divergence.Report)
+
+	id := shortHash(divergence.Prod) + "..." + shortHash(divergence.Staging)
+	transcript, err := explainTranscript(repo, id, prod+" (production) vs "+staging+" (staging)", resume)
+	if err != nil {
+		return err
+	}
+	explainer.SetTranscript(transcript)
+
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Printf("Explaining production %s vs staging %s\n", prod, staging)
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println()
+
+	if resume {
+		err = explainer.ResumeConversation(ctx, initialQuestion)
+	} else {
+		err = explainer.StartConversation(ctx, initialQuestion)
+	}
+	if err != nil {
+		return fmt.Errorf("conversation error: %w", err)
+	}
+
+	fmt.Printf("\nConversation saved, continue it with:\n  docu-jarvis explain -diff-deploys %s %s -resume\n", prod, staging)
+	return nil
+}
+
+func runReviewChecklistMode(ctx context.Context, folder string, repo *git.Repo, source string, args []string, post bool) error {
+	fmt.Println("\n=== REVIEW CHECKLIST MODE ===")
+
+	var diff string
+	var prNumber string
+	var err error
+
+	switch strings.ToLower(source) {
+	case "staged":
+		if post {
+			return fmt.Errorf("-post cannot be used with staged changes (no PR to comment on)")
+		}
+		fmt.Println("Getting staged changes...")
+		diff, err = repo.GetStagedDiff()
+	case "branch":
+		baseBranch := "main"
+		if len(args) > 0 {
+			baseBranch = args[0]
+		}
+		fmt.Printf("Getting changes on this branch against %s...\n", baseBranch)
+		diff, err = repo.GetBranchDiff(baseBranch)
+	case "pr":
+		if len(args) < 1 {
+			help.PrintReviewChecklistHelp()
+			return fmt.Errorf("pr source requires a PR number")
+		}
+		prNumber = args[0]
+		fmt.Printf("Getting changes for PR #%s...\n", prNumber)
+		diff, err = repo.GetPRDiff(prNumber)
+	default:
+		help.PrintReviewChecklistHelp()
+		return fmt.Errorf("invalid checklist source: %s (use staged, branch, or pr)", source)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get diff: %w", err)
+	}
+
+	fmt.Printf("Found changes (%d bytes)\n", len(diff))
+
+	systemPrompt := system_prompts.ReviewChecklist
+
+	fmt.Println("Generating checklist with Claude AI...")
+	ag, err := agent.New(systemPrompt, folder)
+	if err != nil {
+		return fmt.Errorf("failed to create agent: %w", err)
+	}
+
+	checklist, err := ag.GenerateReviewChecklist(ctx, diff)
+	if err != nil {
+		return fmt.Errorf("failed to generate checklist: %w", err)
+	}
+
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Println("REVIEWER CHECKLIST")
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println()
+	fmt.Println(checklist.Checklist)
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 70))
+
+	if post {
+		fmt.Println("\nPosting checklist as PR comment...")
+		body := "## Reviewer Checklist\n\n" + checklist.Checklist + "\n\n_Generated by docu-jarvis_"
+		if err := repo.CommentOnPR(prNumber, body); err != nil {
+			return fmt.Errorf("failed to post checklist: %w", err)
+		}
+	}
+
+	fmt.Println("\n✓ Review checklist completed!")
+	return nil
+}
+
+func runReviewPRMode(ctx context.Context, folder string, repo *git.Repo, pr string, dryRun bool) error {
+	fmt.Println("\n=== REVIEW PR MODE ===")
+
+	s, err := settings.LoadForRepo(repo.GetLocalPath())
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+	if s.IsEmpty() {
+		return fmt.Errorf("code standards not configured (run 'docu-jarvis check-staging settings')")
+	}
+	if err := loadCodeStandards(s); err != nil {
+		return err
+	}
+
+	info, err := repo.GetPRInfo(pr)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Reviewing PR #%d: %s\n", info.Number, info.URL)
+
+	diff, err := repo.GetPRDiff(info.URL)
+	if err != nil {
+		return fmt.Errorf("failed to get diff: %w", err)
+	}
+	fmt.Printf("Found changes (%d bytes)\n", len(diff))
+
+	fmt.Println("Reviewing code with Claude AI...")
+	ag, err := agent.New(system_prompts.AssertCodeQuality, folder)
+	if err != nil {
+		return fmt.Errorf("failed to create agent: %w", err)
+	}
+
+	report, err := ag.ReviewStagedCodeJSON(ctx, diff, s.CodeStandards)
+	if err != nil {
+		return fmt.Errorf("failed to review code: %w", err)
+	}
+
+	// Only lines in the diff can carry inline comments; the rest go in the body
+	diffLines := git.DiffLines(diff)
+	var comments []git.ReviewComment
+	var general []agent.QualityFinding
+	for _, finding := range report.Findings {
+		path := strings.TrimPrefix(finding.File, "b/")
+		if finding.Line > 0 && diffLines[path][finding.Line] {
+			comments = append(comments, git.ReviewComment{Path: path, Line: finding.Line, Body: formatFinding(finding)})
+		} else {
+			general = append(general, finding)
+		}
+	}
+
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Println("PR REVIEW RESULTS")
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Printf("\nStatus: %s\n%s\n", report.ComplianceStatus, report.Summary)
+	for _, finding := range report.Findings {
+		location := finding.File
+		if finding.Line > 0 {
+			location = fmt.Sprintf("%s:%d", finding.File, finding.Line)
+		}
+		fmt.Printf("\n[%s] %s\n  %s\n  Fix: %s\n", finding.Severity, location, finding.Issue, finding.Recommendation)
+	}
+	fmt.Println("\n" + strings.Repeat("=", 70))
+
+	if dryRun {
+		fmt.Printf("\nDry run: not posting (%d inline comments, %d in the review body)\n", len(comments), len(general))
+		return nil
+	}
+
+	var body strings.Builder
+	body.WriteString("## Code Quality Review\n\n")
+	body.WriteString(fmt.Sprintf("**Status:** %s\n\n%s\n", report.ComplianceStatus, report.Summary))
+	if len(general) > 0 {
+		body.WriteString("\n### Other findings\n")
+		for _, finding := range general {
+			location := finding.File
+			if finding.Line > 0 {
+				location = fmt.Sprintf("%s:%d", finding.File, finding.Line)
+			}
+			body.WriteString(fmt.Sprintf("\n- `%s` %s\n", location, strings.ReplaceAll(formatFinding(finding), "\n\n", " ")))
+		}
+	}
+	body.WriteString("\n_Generated by docu-jarvis_")
+
+	fmt.Printf("\nPosting review with %d inline comments...\n", len(comments))
+	if err := repo.PostPRReview(info, body.String(), comments); err != nil {
+		return err
+	}
+
+	fmt.Println("\n✓ PR review completed!")
+	return nil
+}
+
+// triageCommits caps the recent commits given to Claude when triaging.
+const triageCommits = 200
+
+// runTriageMode has Claude correlate an issue with the code and the commits
+// since since, and comments on the issue with the suspected areas, related
+// commits, and suggested labels.
+func runTriageMode(ctx context.Context, folder string, repo *git.Repo, issueArg string, since time.Time, dryRun bool) error {
+	fmt.Println("\n=== TRIAGE MODE ===")
+
+	issue, err := repo.GetIssue(issueArg)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Triaging issue #%d: %s\n", issue.Number, issue.URL)
+	fmt.Printf("Title: %s\n", issue.Title)
+
+	labels, err := repo.RepoLabels(issue)
+	if err != nil {
+		fmt.Printf("Warning: %v; no labels will be suggested\n", err)
+	}
+
+	if err := repo.EnsureHistorySince(dates.Git(since)); err != nil {
+		return err
+	}
+	commits, err := repo.GetCommitsBetweenDates(dates.Git(since), dates.Git(time.Now()), git.HistoryOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get commits: %w", err)
+	}
+	if len(commits) > triageCommits {
+		commits = commits[:triageCommits]
+	}
+	fmt.Printf("Found %d commits since %s\n", len(commits), dates.Format(since))
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "#%d %s (%s, opened by %s)\n", issue.Number, issue.Title, strings.ToLower(issue.State), issue.Author)
+	if len(issue.Labels) > 0 {
+		fmt.Fprintf(&text, "Labels: %s\n", strings.Join(issue.Labels, ", "))
+	}
+	fmt.Fprintf(&text, "\n%s\n", issue.Body)
+	for _, c := range issue.Comments {
+		fmt.Fprintf(&text, "\n--- comment by %s ---\n%s\n", c.Author, c.Body)
+	}
+
+	fmt.Println("Triaging the issue with Claude AI...")
+	ag, err := agent.New(system_prompts.IssueTriage, folder)
+	if err != nil {
+		return fmt.Errorf("failed to create agent: %w", err)
+	}
+	triage, err := ag.TriageIssue(ctx, redact.String(text.String()), commits, labels)
+	if err != nil {
+		return fmt.Errorf("failed to triage issue: %w", err)
+	}
+
+	// Only labels the repository has can be applied
+	known := make(map[string]string, len(labels))
+	for _, label := range labels {
+		known[strings.ToLower(label)] = label
+	}
+	var suggested []string
+	for _, label := range triage.Labels {
+		if name, ok := known[strings.ToLower(label)]; ok {
+			suggested = append(suggested, name)
+		}
 	}
+	triage.Labels = suggested
 
-	var writeSuccess, writeTotal int
-	var updateSuccess, updateTotal int
+	body := triageComment(triage)
 
-	if len(topicsToWrite) > 0 {
-		fmt.Printf("\nWriting documentation for %d new topics...\n", len(topicsToWrite))
-		writeSuccess, writeTotal, err = ag.WriteDocumentation(ctx, topicsToWrite)
-		if err != nil {
-			return fmt.Errorf("failed to write documentation: %w", err)
-		}
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Println("TRIAGE RESULTS")
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println(body)
+	fmt.Println(strings.Repeat("=", 70))
+
+	if dryRun {
+		fmt.Println("\nDry run: not commenting on the issue")
+		return nil
 	}
 
-	if len(topicsToUpdate) > 0 {
-		fmt.Printf("\nUpdating documentation for %d existing topics...\n", len(topicsToUpdate))
+	fmt.Printf("\nCommenting on issue #%d...\n", issue.Number)
+	if err := repo.PostIssueComment(issue, body); err != nil {
+		return err
+	}
 
-		updatePrompt := system_prompts.DocumentationUpdate
+	fmt.Println("\n✓ Issue triage completed!")
+	return nil
+}
 
-		updateAgent, err := agent.New(updatePrompt, folder)
-		if err != nil {
-			return fmt.Errorf("failed to create update agent: %w", err)
+// triageComment renders a triage as the markdown comment posted on the issue.
+func triageComment(triage *agent.IssueTriage) string {
+	var body strings.Builder
+	body.WriteString("## Issue Triage\n\n")
+	body.WriteString(triage.Summary + "\n")
+
+	body.WriteString("\n### Suspected root-cause areas\n")
+	if len(triage.Areas) == 0 {
+		body.WriteString("\nNone found.\n")
+	}
+	for _, area := range triage.Areas {
+		location := "`" + area.File + "`"
+		if area.Location != "" {
+			location += fmt.Sprintf(" (%s)", area.Location)
 		}
+		body.WriteString(fmt.Sprintf("\n- %s, %d%% confidence: %s\n", location, area.Confidence, area.Reason))
+	}
 
-		var filesToUpdate []string
-		for _, match := range matches {
-			if match.IsMatch {
-				for _, topic := range topicsToUpdate {
-					if topic == match.Topic {
-						filePath := filepath.Join(folder, "documentation", match.ExistingFile)
-						filesToUpdate = append(filesToUpdate, filePath)
-						break
-					}
-				}
+	if len(triage.RelatedCommits) > 0 {
+		body.WriteString("\n### Related commits\n")
+		for _, commit := range triage.RelatedCommits {
+			body.WriteString(fmt.Sprintf("\n- %s %s\n", commit.Hash, commit.Reason))
+		}
+	}
+	if len(triage.Labels) > 0 {
+		body.WriteString("\n### Suggested labels\n\n")
+		for i, label := range triage.Labels {
+			if i > 0 {
+				body.WriteString(", ")
 			}
+			body.WriteString("`" + label + "`")
 		}
-
-		updateSuccess, updateTotal, err = updateAgent.UpdateSpecificDocuments(ctx, filesToUpdate)
-		if err != nil {
-			return fmt.Errorf("failed to update documentation: %w", err)
+		body.WriteString("\n")
+	}
+	if len(triage.Questions) > 0 {
+		body.WriteString("\n### Questions for the reporter\n")
+		for _, question := range triage.Questions {
+			body.WriteString("\n- " + question + "\n")
 		}
 	}
+	body.WriteString("\n_Generated by docu-jarvis_")
+	return body.String()
+}
 
-	successCount := writeSuccess + updateSuccess
-	totalTopics := writeTotal + updateTotal + len(topicsToSkip)
+func formatFinding(finding agent.QualityFinding) string {
+	text := fmt.Sprintf("**%s** (%s): %s", finding.Severity, finding.Standard, finding.Issue)
+	if finding.Recommendation != "" {
+		text += "\n\n**Recommendation:** " + finding.Recommendation
+	}
+	return text
+}
 
-	if successCount > 0 {
-		if successCount == totalTopics {
-			fmt.Println("\nAll topics documented successfully")
-		} else {
-			fmt.Printf("\nSome topics failed, but %d/%d succeeded\n", successCount, totalTopics)
-		}
+func runCheckCommitsMode(ctx context.Context, folder string, repo *git.Repo, revRange string, fixup bool) error {
+	fmt.Println("\n=== CHECK COMMITS MODE ===")
+	fmt.Printf("Range: %s\n", revRange)
 
-		hasChanges, err := repo.HasChanges()
-		if err != nil {
-			return fmt.Errorf("failed to check for changes: %w", err)
-		}
+	s, err := settings.LoadForRepo(repo.GetLocalPath())
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
 
-		if hasChanges {
-			fmt.Println("\nCreating pull request with new documentation...")
-			if err := repo.CreatePR(); err != nil {
-				return fmt.Errorf("failed to create PR: %w", err)
-			}
-		} else {
-			fmt.Println("\nNo new documentation files were created")
-		}
+	if s.CommitConventions == "" {
+		fmt.Println("No commit conventions configured, using Conventional Commits")
 	} else {
-		fmt.Println("\nAll topics failed - no documentation created")
+		fmt.Printf("Loaded commit conventions from: %s\n", s.GetPath())
 	}
 
-	fmt.Println("\n✓ Documentation writing completed!")
-	return nil
-}
-
-func runDebugMode(ctx context.Context, folder string, repo *git.Repo, fromDate, toDate, bugDescription string) error {
-	fmt.Println("\n=== DEBUG MODE ===")
-	fmt.Printf("Date range: %s to %s\n", fromDate, toDate)
-	fmt.Printf("Bug: %s\n\n", bugDescription)
+	// Check the range before asking Claude, as only some ranges can be rewritten
+	var fixupBase string
+	if fixup {
+		if fixupBase, err = repo.FixupBase(revRange); err != nil {
+			return err
+		}
+	}
 
-	fmt.Println("Fetching commits in date range...")
-	commits, err := repo.GetCommitsBetweenDates(fromDate, toDate)
+	fmt.Println("Fetching commits...")
+	commits, err := repo.GetCommitMessages(revRange)
 	if err != nil {
 		return fmt.Errorf("failed to get commits: %w", err)
 	}
 
 	if len(commits) == 0 {
-		fmt.Println("No commits found in the specified date range")
+		fmt.Println("No commits found in the specified range")
 		return nil
 	}
 
-	fmt.Printf("Found %d commits to analyze\n", len(commits))
+	fmt.Printf("Found %d commits to check\n", len(commits))
 
-	systemPrompt := system_prompts.DebugAnalysis
+	var messages []agent.CommitMessage
+	for _, commit := range commits {
+		messages = append(messages, agent.CommitMessage{
+			Hash:    commit.Hash,
+			Message: commit.Message(),
+		})
+	}
+
+	systemPrompt := system_prompts.CommitConventions
 
-	fmt.Println("\nAnalyzing commits with Claude AI (concurrently)...")
+	fmt.Println("Checking commit messages with Claude AI...")
 	ag, err := agent.New(systemPrompt, folder)
 	if err != nil {
 		return fmt.Errorf("failed to create agent: %w", err)
 	}
 
-	analysis, err := ag.AnalyzeBugInCommits(ctx, commits, bugDescription)
+	reviews, err := ag.ReviewCommitMessages(ctx, messages, s.GetCommitConventions())
 	if err != nil {
-		return fmt.Errorf("failed to analyze commits: %w", err)
+		return fmt.Errorf("failed to check commits: %w", err)
 	}
 
 	fmt.Println("\n" + strings.Repeat("=", 70))
-	fmt.Println("DEBUG ANALYSIS RESULTS!!!")
+	fmt.Println("COMMIT CONVENTION RESULTS")
 	fmt.Println(strings.Repeat("=", 70))
 
-	if !analysis.IsLikely {
-		fmt.Println("\nOH NO!!!!  Could not definitively identify the bug-causing commit")
-		fmt.Printf("\nExplanation:\n%s\n", analysis.Explanation)
-	} else {
-		fmt.Println("\n✓ Likely bug-causing commit identified:")
-		fmt.Println()
-		fmt.Printf("Commit Hash:    %s\n", analysis.CommitHash)
-		fmt.Printf("Author:         %s\n", analysis.Author)
-		fmt.Printf("Date:           %s\n", analysis.Date)
-		fmt.Printf("Message:        %s\n", analysis.CommitMsg)
-		fmt.Printf("Confidence:     %d%%\n", analysis.Confidence)
-		fmt.Println()
-		fmt.Println("Explanation:")
-		fmt.Println(strings.Repeat("-", 70))
-		fmt.Println(analysis.Explanation)
-		fmt.Println(strings.Repeat("-", 70))
-		fmt.Println()
-		fmt.Printf("To view the commit:\n  git show %s\n", analysis.CommitHash)
+	rewrites := make(map[string]string)
+	for _, commit := range commits {
+		review := findCommitReview(reviews, commit.Hash)
 		fmt.Println()
+		if review == nil {
+			fmt.Printf("? %s %s\n", commit.Hash[:8], commit.Subject)
+			fmt.Println("    (not reviewed)")
+			continue
+		}
+
+		if review.Compliant {
+			fmt.Printf("✓ %s %s\n", commit.Hash[:8], commit.Subject)
+			continue
+		}
+
+		fmt.Printf("✗ %s %s\n", commit.Hash[:8], commit.Subject)
+		for _, issue := range review.Issues {
+			fmt.Printf("    - %s\n", issue)
+		}
+		if review.SuggestedMessage != "" {
+			fmt.Println("    Suggested:")
+			for _, line := range strings.Split(review.SuggestedMessage, "\n") {
+				fmt.Printf("      %s\n", line)
+			}
+			rewrites[commit.Hash] = review.SuggestedMessage
+		}
 	}
 
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 70))
+	nonCompliant := 0
+	for _, review := range reviews {
+		if !review.Compliant {
+			nonCompliant++
+		}
+	}
+	fmt.Printf("%d/%d commits follow the conventions\n", len(commits)-nonCompliant, len(commits))
 	fmt.Println(strings.Repeat("=", 70))
-	fmt.Println("\n✓ Debug analysis completed!")
-	return nil
-}
 
-func runConfigMode() error {
-	s, err := settings.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load settings: %w", err)
+	if nonCompliant == 0 {
+		fmt.Println("\n✓ Commit check completed!")
+		return nil
 	}
 
-	if err := s.InteractiveEdit(); err != nil {
-		return fmt.Errorf("failed to edit config: %w", err)
+	if fixup && len(rewrites) > 0 {
+		return runCommitFixup(repo, fixupBase, rewrites)
 	}
 
-	return nil
-}
+	if len(rewrites) > 0 {
+		fmt.Println("\nRun again with -fixup to rewrite these messages with git rebase")
+	}
 
-func runCheckStagingSettings() error {
-	fmt.Println("\n=== CODE STANDARDS SETTINGS ===")
-	fmt.Println("Note: Use 'docu-jarvis -config' to edit all settings including code standards")
-	fmt.Println()
+	return fmt.Errorf("%d commit(s) do not follow the conventions", nonCompliant)
+}
 
-	return runConfigMode()
+func findCommitReview(reviews []agent.CommitMessageReview, hash string) *agent.CommitMessageReview {
+	for i := range reviews {
+		if reviews[i].Hash != "" && strings.HasPrefix(hash, reviews[i].Hash) {
+			return &reviews[i]
+		}
+	}
+	return nil
 }
 
-func runCheckStagingMode(ctx context.Context) error {
-	fmt.Println("\n=== CHECK STAGING MODE ===")
+func runCommitFixup(repo *git.Repo, base string, rewrites map[string]string) error {
+	// The rebase todo must list every commit it replays, not only those in the
+	// range or touching the scope, or they would be dropped
+	unscoped, err := git.OpenLocal(repo.GetLocalPath())
+	if err != nil {
+		return err
+	}
+	commits, err := unscoped.GetCommitMessages(base + "..HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to get commits: %w", err)
+	}
 
-	settings, err := settings.Load()
+	todoDir, err := os.MkdirTemp("", "docu-jarvis-fixup-")
 	if err != nil {
-		return fmt.Errorf("failed to load settings: %w", err)
+		return fmt.Errorf("failed to create temp directory: %w", err)
 	}
 
-	if settings.IsEmpty() {
-		fmt.Println("OH NO!!!!  No code standards configured!")
-		fmt.Println("\nPlease configure your code standards first:")
-		fmt.Println("  docu-jarvis -check-staging settings")
-		fmt.Println()
-		return fmt.Errorf("code standards not configured")
+	todoPath, err := git.WriteRewordTodo(commits, rewrites, todoDir)
+	if err != nil {
+		return fmt.Errorf("failed to generate rebase todo: %w", err)
 	}
 
-	fmt.Printf("Loaded code standards from: %s\n", settings.GetPath())
+	fmt.Printf("\nGenerated rebase todo: %s\n", todoPath)
+	fmt.Printf("Rewrite %d commit message(s) with git rebase onto %.8s? (y/n): ", len(rewrites), base)
+
+	choice := ask("n")
+	if strings.ToLower(strings.TrimSpace(choice)) != "y" {
+		fmt.Println("Skipping rewrite. To apply it manually:")
+		fmt.Printf("  GIT_SEQUENCE_EDITOR=\"cp %s\" git rebase -i %s\n", todoPath, base)
+		return nil
+	}
+
+	if err := repo.RebaseWithTodo(base, todoPath); err != nil {
+		return err
+	}
+	os.RemoveAll(todoDir)
+
+	fmt.Println("\n✓ Commit messages rewritten!")
+	return nil
+}
 
-	cwd, err := os.Getwd()
+func runSquashSummaryMode(ctx context.Context, folder string, repo *git.Repo, baseBranch string) error {
+	fmt.Println("\n=== SQUASH SUMMARY MODE ===")
+
+	s, err := settings.LoadForRepo(repo.GetLocalPath())
 	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
+		return fmt.Errorf("failed to load settings: %w", err)
 	}
 
-	repo := git.NewRepo("")
-	repo.SetLocalPath(cwd)
+	branch, err := repo.GetCurrentBranch()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Branch: %s (against %s)\n", branch, baseBranch)
 
-	fmt.Println("Getting staged changes...")
-	stagedDiff, err := repo.GetStagedDiff()
+	fmt.Println("Fetching commits...")
+	commits, err := repo.GetCommitMessages(baseBranch + "..HEAD")
 	if err != nil {
-		return fmt.Errorf("failed to get staged changes: %w", err)
+		return fmt.Errorf("failed to get commits: %w", err)
 	}
 
-	if strings.TrimSpace(stagedDiff) == "" {
-		fmt.Println("No staged changes found!")
-		fmt.Println("\nStage some changes first:")
-		fmt.Println("  git add <files>")
+	if len(commits) == 0 {
+		fmt.Printf("No commits found on %s that are not on %s\n", branch, baseBranch)
 		return nil
 	}
 
-	fmt.Printf("Found staged changes (%d bytes)\n", len(stagedDiff))
+	fmt.Printf("Found %d commits to combine\n", len(commits))
 
-	systemPrompt := system_prompts.AssertCodeQuality
+	var messages []agent.CommitMessage
+	for _, commit := range commits {
+		messages = append(messages, agent.CommitMessage{
+			Hash:    commit.Hash,
+			Message: commit.Message(),
+		})
+	}
 
-	fmt.Println("Reviewing code with Claude AI...")
-	ag, err := agent.New(systemPrompt, cwd)
+	systemPrompt := system_prompts.SquashSummary
+
+	fmt.Println("Writing squash commit message with Claude AI...")
+	ag, err := agent.New(systemPrompt, folder)
 	if err != nil {
 		return fmt.Errorf("failed to create agent: %w", err)
 	}
 
-	review, err := ag.ReviewStagedCode(ctx, stagedDiff, settings.CodeStandards)
+	summary, err := ag.GenerateSquashSummary(ctx, branch, messages, s.GetCommitConventions())
 	if err != nil {
-		return fmt.Errorf("failed to review code: %w", err)
+		return fmt.Errorf("failed to generate squash summary: %w", err)
+	}
+
+	if len(summary.Tickets) > 0 {
+		fmt.Printf("Tickets referenced: %s\n", strings.Join(summary.Tickets, ", "))
 	}
 
 	fmt.Println("\n" + strings.Repeat("=", 70))
-	fmt.Println("CODE QUALITY REVIEW")
+	fmt.Println("SQUASH COMMIT MESSAGE")
 	fmt.Println(strings.Repeat("=", 70))
 	fmt.Println()
-
-	fmt.Println(review.FullResponse)
+	fmt.Println(summary.Message)
 	fmt.Println()
+	fmt.Println(strings.Repeat("=", 70))
 
-	if review.ComplianceStatus != "" {
-		fmt.Println(strings.Repeat("=", 70))
-		fmt.Printf("COMPLIANCE STATUS: %s\n", review.ComplianceStatus)
-		fmt.Println(strings.Repeat("=", 70))
-	}
-
-	if review.Recommendations != "" {
-		fmt.Println("\nRECOMMENDATIONS:")
-		fmt.Println(strings.Repeat("-", 70))
-		fmt.Println(review.Recommendations)
-		fmt.Println(strings.Repeat("-", 70))
-	}
-
-	fmt.Println("\n✓ Code review completed!")
-	return nil
-}
-
-func runVersionCheck() error {
-	currentVersion := updater.GetCurrentVersion()
-	fmt.Printf("Docu-Jarvis version: %s\n", currentVersion)
-	fmt.Println("\nChecking for updates...")
-
-	updater.AutoCheckForUpdates(currentVersion, false)
+	fmt.Println("\n✓ Squash summary completed!")
 	return nil
 }
 
-func runUpdate() error {
-	currentVersion := updater.GetCurrentVersion()
-	fmt.Printf("Current version: %s\n", currentVersion)
-	fmt.Println("Checking for updates...")
+func runChangelogMode(ctx context.Context, folder string, repo *git.Repo, fromRef, toRef, title, file string, stdout bool) error {
+	fmt.Println("\n=== CHANGELOG MODE ===")
+	fmt.Printf("Range: %s..%s\n", fromRef, toRef)
 
-	err := updater.UpdateToLatest(currentVersion)
+	fmt.Println("Fetching commits...")
+	commits, err := repo.GetCommitMessages(fromRef + ".." + toRef)
 	if err != nil {
-		return fmt.Errorf("update failed: %w", err)
+		return fmt.Errorf("failed to get commits: %w", err)
 	}
 
-	fmt.Println("\n✓ Update completed successfully!")
-	fmt.Println("Please restart docu-jarvis to use the new version")
-	return nil
-}
+	if len(commits) == 0 {
+		fmt.Println("No commits found in the specified range")
+		return nil
+	}
 
-func runExplainMode(ctx context.Context, commitHash, initialQuestion string) error {
-	fmt.Println("\n=== COMMIT EXPLAINER MODE ===")
-	fmt.Printf("Commit: %s\n", commitHash)
+	fmt.Printf("Found %d commits\n", len(commits))
 
-	fmt.Println("Loading configuration...")
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
+	var messages []agent.CommitMessage
+	for _, commit := range commits {
+		messages = append(messages, agent.CommitMessage{
+			Hash:    commit.Hash,
+			Message: commit.Message(),
+		})
 	}
 
-	fmt.Println("Cloning repository...")
-	repo := git.NewRepo(cfg.RepoURL)
-	repoName := cfg.GetRepoName()
+	systemPrompt := system_prompts.ChangelogWriter
 
-	folder, err := repo.Clone(repoName)
+	fmt.Println("Writing changelog with Claude AI...")
+	ag, err := agent.New(systemPrompt, folder)
 	if err != nil {
-		return fmt.Errorf("failed to clone repository: %w", err)
+		return fmt.Errorf("failed to create agent: %w", err)
 	}
 
-	fmt.Println("Fetching commit details...")
-	commitDiff, err := repo.GetCommitDiff(commitHash)
+	changelog, err := ag.WriteChangelog(ctx, fromRef, toRef, messages)
 	if err != nil {
-		return fmt.Errorf("failed to get commit diff: %w", err)
+		return fmt.Errorf("failed to write changelog: %w", err)
 	}
 
-	systemPrompt := system_prompts.CommitExplainer
+	if title == "" {
+		title = toRef
+	}
+	entry := fmt.Sprintf("## %s (%s)\n\n%s\n", title, time.Now().Format("2006-01-02"), changelog.Entry)
 
-	fmt.Println("Initializing AI agent...")
-	ag, err := agent.New(systemPrompt, folder)
-	if err != nil {
-		return fmt.Errorf("failed to create agent: %w", err)
+	if stdout {
+		fmt.Println("\n" + strings.Repeat("=", 70))
+		fmt.Println(entry)
+		fmt.Println(strings.Repeat("=", 70))
+		return nil
 	}
 
-	explainer := agent.NewCommitExplainer(ag, commitHash, commitDiff)
+	if err := prependChangelogEntry(file, entry); err != nil {
+		return fmt.Errorf("failed to update %s: %w", file, err)
+	}
 
-	fmt.Println("\n" + strings.Repeat("=", 70))
-	fmt.Printf("Explaining commit: %s\n", commitHash)
-	fmt.Println(strings.Repeat("=", 70))
-	fmt.Println()
+	fmt.Printf("\n✓ Changelog entry added to %s\n", file)
+	return nil
+}
 
-	if err := explainer.StartConversation(ctx, initialQuestion); err != nil {
-		return fmt.Errorf("conversation error: %w", err)
+// prependChangelogEntry inserts entry below the file's top-level heading, or
+// creates the file with a heading if it does not exist yet.
+func prependChangelogEntry(path, entry string) error {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return os.WriteFile(path, []byte("# Changelog\n\n"+entry), 0644)
+	}
+	if err != nil {
+		return err
 	}
 
-	return nil
+	existing := string(content)
+	if strings.HasPrefix(existing, "# ") {
+		headingEnd := strings.Index(existing, "\n")
+		if headingEnd < 0 {
+			return os.WriteFile(path, []byte(existing+"\n\n"+entry), 0644)
+		}
+		heading := existing[:headingEnd+1]
+		rest := strings.TrimLeft(existing[headingEnd+1:], "\n")
+		return os.WriteFile(path, []byte(heading+"\n"+entry+"\n"+rest), 0644)
+	}
+
+	return os.WriteFile(path, []byte(entry+"\n"+existing), 0644)
 }
@@ -0,0 +1,17 @@
+package billing
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+)
+
+// Open connects to the billing database.
+func Open() (*sql.DB, error) {
+	dsn := fmt.Sprintf("postgres://billing:%s@db:5432/billing", os.Getenv("BILLING_DB_PASSWORD"))
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening billing database: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "Print the invoices that would be sent without sending them")
+	flag.Parse()
+
+	if *dryRun {
+		fmt.Println("Dry run: no invoices sent")
+		return
+	}
+	fmt.Println("Invoices sent")
+}
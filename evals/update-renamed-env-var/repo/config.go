@@ -0,0 +1,25 @@
+package billing
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config controls how failed invoice charges are retried.
+type Config struct {
+	RetryLimit   int
+	RetryBackoff time.Duration
+}
+
+// LoadConfig reads the configuration from the environment.
+func LoadConfig() Config {
+	cfg := Config{RetryLimit: 5, RetryBackoff: 30 * time.Second}
+	if v, err := strconv.Atoi(os.Getenv("BILLING_RETRY_LIMIT")); err == nil {
+		cfg.RetryLimit = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("BILLING_RETRY_BACKOFF")); err == nil {
+		cfg.RetryBackoff = v
+	}
+	return cfg
+}
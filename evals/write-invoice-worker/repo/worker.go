@@ -0,0 +1,66 @@
+package billing
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+)
+
+// Charger charges an invoice with the payment provider.
+type Charger interface {
+	Charge(ctx context.Context, invoiceID string) error
+}
+
+// Store lists the invoices whose last charge failed.
+type Store interface {
+	FailedInvoices(ctx context.Context) ([]string, error)
+	MarkPaid(ctx context.Context, invoiceID string) error
+}
+
+// RetryWorker charges failed invoices again on an interval.
+type RetryWorker struct {
+	store    Store
+	charger  Charger
+	interval time.Duration
+}
+
+// NewRetryWorker runs every BILLING_WORKER_INTERVAL, or every minute.
+func NewRetryWorker(store Store, charger Charger) *RetryWorker {
+	interval := time.Minute
+	if v, err := time.ParseDuration(os.Getenv("BILLING_WORKER_INTERVAL")); err == nil {
+		interval = v
+	}
+	return &RetryWorker{store: store, charger: charger, interval: interval}
+}
+
+// Run retries failed invoices until ctx is done.
+func (w *RetryWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.retry(ctx)
+		}
+	}
+}
+
+func (w *RetryWorker) retry(ctx context.Context) {
+	ids, err := w.store.FailedInvoices(ctx)
+	if err != nil {
+		log.Printf("listing failed invoices: %v", err)
+		return
+	}
+	for _, id := range ids {
+		if err := w.charger.Charge(ctx, id); err != nil {
+			log.Printf("charging invoice %s: %v", id, err)
+			continue
+		}
+		if err := w.store.MarkPaid(ctx, id); err != nil {
+			log.Printf("marking invoice %s paid: %v", id, err)
+		}
+	}
+}
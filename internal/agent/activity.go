@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+
+	"github.com/udemy/docu-jarvis-cli/internal/redact"
+)
+
+// maxActivityArg caps the argument shown in an activity line, such as a long
+// search pattern or command.
+const maxActivityArg = 80
+
+type itemKey struct{}
+
+// item is the batch item that the requests of a context work on.
+type item struct {
+	name   string
+	report progress
+}
+
+// withItem makes the requests sent with ctx stream their messages and
+// report each tool call, as a line such as "Reading api/invoice.go", and
+// their tokens for the item name.
+func withItem(ctx context.Context, name string, report progress) context.Context {
+	return context.WithValue(ctx, itemKey{}, &item{name: name, report: report})
+}
+
+func itemFrom(ctx context.Context) *item {
+	it, _ := ctx.Value(itemKey{}).(*item)
+	return it
+}
+
+// itemProgress reports a single document or topic processed outside a batch.
+func (a *Agent) itemProgress() progress {
+	if a.events != nil {
+		return eventProgress{a}
+	}
+	return plainProgress{}
+}
+
+// queryStream sends the request like Query, reporting the tool calls of the
+// messages as they arrive.
+func (a *Agent) queryStream(ctx context.Context, request claudecode.QueryRequest, it *item) ([]claudecode.Message, error) {
+	messageChan, errorChan := a.provider.QueryStream(ctx, request)
+
+	var messages []claudecode.Message
+	var err error
+	for messageChan != nil {
+		select {
+		case message, ok := <-messageChan:
+			if !ok {
+				messageChan = nil
+				break
+			}
+			messages = append(messages, message)
+			if message.Type() != claudecode.MessageTypeAssistant {
+				continue
+			}
+			for _, block := range message.Content() {
+				if tool, ok := block.(*claudecode.ToolUseBlock); ok {
+					it.report.activity(it.name, tool.Name, a.describeToolUse(tool))
+				}
+			}
+		case streamErr, ok := <-errorChan:
+			if !ok {
+				errorChan = nil
+			} else if streamErr != nil && err == nil {
+				err = streamErr
+			}
+		}
+	}
+
+	// Claude Code sends the error of a failed run after its last message
+	select {
+	case streamErr := <-errorChan:
+		if streamErr != nil && err == nil {
+			err = streamErr
+		}
+	default:
+	}
+	return messages, err
+}
+
+// describeToolUse is the activity line of a tool call, with paths relative
+// to the workspace.
+func (a *Agent) describeToolUse(tool *claudecode.ToolUseBlock) string {
+	arg := func(key string) string {
+		value, _ := tool.Input[key].(string)
+		if filepath.IsAbs(value) {
+			if rel, err := filepath.Rel(a.folder, value); err == nil && !strings.HasPrefix(rel, "..") {
+				value = rel
+			}
+		}
+		if runes := []rune(value); len(runes) > maxActivityArg {
+			value = string(runes[:maxActivityArg-3]) + "..."
+		}
+		return redact.String(value)
+	}
+
+	switch tool.Name {
+	case "Read":
+		return "Reading " + arg("file_path")
+	case "Write":
+		return "Writing " + arg("file_path")
+	case "Edit", "MultiEdit":
+		return "Editing " + arg("file_path")
+	case "Grep":
+		if path := arg("path"); path != "" {
+			return fmt.Sprintf("Searching %s for %q", path, arg("pattern"))
+		}
+		return fmt.Sprintf("Searching for %q", arg("pattern"))
+	case "Glob":
+		return "Finding " + arg("pattern")
+	case "LS":
+		return "Listing " + arg("path")
+	case "WebFetch":
+		return "Fetching " + arg("url")
+	case "WebSearch":
+		return fmt.Sprintf("Searching the web for %q", arg("query"))
+	case "Bash":
+		return "Running " + arg("command")
+	}
+	return "Using " + tool.Name
+}
@@ -2,152 +2,411 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	claudecode "github.com/yukifoo/claude-code-sdk-go"
+
+	"github.com/udemy/docu-jarvis-cli/internal/archive"
+	"github.com/udemy/docu-jarvis-cli/internal/logging"
+	"github.com/udemy/docu-jarvis-cli/internal/runstate"
 )
 
 type Agent struct {
 	systemPrompt string
 	folder       string
-	logger       *log.Logger
+	docsDirs     []string
+	editRoots    []string // where the edit tools may write, default docsDirs
+	logger       *logging.Logger
+	dryRun       bool
+	confirmEdits bool
+	run          *runstate.Run
+	batch        []BatchItem
+	breaker      *breaker
+	batchOpts    BatchOptions
+	suspectOpts  SuspectOptions
+	keepAnchors  bool
+	model        string // overrides the model of every mode
+	outputMu     sync.Mutex
+	events       func(Event)
+	eventMu      sync.Mutex
+	provider     Provider
+	proposals    []ArchiveProposal   // guarded by outputMu
+	digests      map[string]string   // guarded by outputMu
+	sources      map[string][]string // guarded by outputMu
+	redirects    []AnchorRedirect    // guarded by outputMu
+	rules        []string            // learned from PR reviews
+	pastCases    []PastCase          // similar bugs debugged before
+	tags         []string            // the docs' tags, read once per run
+	tagsOnce     sync.Once
 }
 
+const dryRunInstructions = `
+
+IMPORTANT: This is a dry run. Do NOT create, modify, or delete any files.
+Instead, respond with the changes you would make:
+1. The proposed changes as a unified diff inside a ` + "```diff" + ` code block (for a new file, diff against /dev/null)
+2. A short summary of what would change and why`
+
+// imagePlaceholderInstructions have Claude mark where screenshots belong, for
+// the PR's assets checklist and check-assets.
+const imagePlaceholderInstructions = `
+
+When the documentation walks through a UI flow (a page, dialog, or sequence of screens), mark where a screenshot belongs instead of describing an image that does not exist, with a placeholder on its own line:
+<!-- image: settings-page | The Settings page with the SSO section expanded -->
+Name each placeholder in lowercase-kebab-case after what it shows, unique within the document. Keep existing placeholders and images unless the flow they show is gone.`
+
 type ProcessResult struct {
-	FileName string
-	Success  bool
-	Error    error
+	FileName     string
+	Success      bool
+	Error        error
+	Duration     time.Duration
+	Turns        int
+	InputTokens  int
+	OutputTokens int
+	Summary      string // what was changed, in one sentence
 }
 
 func New(systemPromptContent, folder string) (*Agent, error) {
-	systemPrompt := systemPromptContent
-	systemPrompt += fmt.Sprintf("\n\nHere is the codebase path where you should look for the relevant code files:\n<codebase_path>\n%s\n</codebase_path>", folder)
-
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	provider := currentProvider()
+	if err := provider.Check(); err != nil {
+		return nil, err
 	}
 
-	logDir := filepath.Join(homeDir, ".docu-jarvis", "logs")
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create log directory: %w", err)
-	}
+	systemPrompt := systemPromptContent
+	systemPrompt += fmt.Sprintf("\n\nHere is the codebase path where you should look for the relevant code files:\n<codebase_path>\n%s\n</codebase_path>", folder)
 
-	logPath := filepath.Join(logDir, "docu-jarvis.log")
-	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	logger, err := logging.New()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create log file: %w", err)
+		return nil, err
 	}
 
-	logger := log.New(logFile, "", log.LstdFlags)
-
 	return &Agent{
 		systemPrompt: systemPrompt,
 		folder:       folder,
+		docsDirs:     []string{filepath.Join(folder, "documentation")},
 		logger:       logger,
+		provider:     provider,
 	}, nil
 }
 
+// SetDocsDirs sets the documentation directories the agent reads and updates.
+// New documentation is written to the first one.
+func (a *Agent) SetDocsDirs(dirs []string) {
+	if len(dirs) > 0 {
+		a.docsDirs = dirs
+	}
+}
+
+// listDocs returns the markdown files in every docs directory.
+func (a *Agent) listDocs() ([]string, error) {
+	return FindDocs(a.docsDirs)
+}
+
+// FindDocs returns the .md and .mdx files under the docs directories,
+// including nested folders. Hidden directories, node_modules, and the archive
+// folder of each docs directory are skipped, as are directories that do not
+// exist.
+func FindDocs(dirs []string) ([]string, error) {
+	var files []string
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if path == dir && errors.Is(err, fs.ErrNotExist) {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if d.IsDir() {
+				if path != dir && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules" || path == filepath.Join(dir, archive.Dir)) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if IsDocFile(path) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list documentation files: %w", err)
+		}
+	}
+	return files, nil
+}
+
+// IsDocFile reports whether path has a documentation extension (.md or .mdx).
+func IsDocFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".md" || ext == ".mdx"
+}
+
+// docName is the path of a doc relative to the codebase folder, which keeps
+// files with the same name in different docs roots apart.
+func (a *Agent) docName(path string) string {
+	if rel, err := filepath.Rel(a.folder, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.Base(path)
+}
+
+// SetDryRun restricts the agent to read-only tools and makes it print the
+// changes it would make instead of writing them.
+func (a *Agent) SetDryRun(dryRun bool) {
+	a.dryRun = dryRun
+}
+
+func (a *Agent) IsDryRun() bool {
+	return a.dryRun
+}
+
+// SetRunState makes the agent record the result of every document it updates
+// in run, so the run can be resumed. The agent's log records name the run.
+func (a *Agent) SetRunState(run *runstate.Run) {
+	a.run = run
+	a.logger = a.logger.With("run", run.ID)
+}
+
+// docNames returns the docName of each path.
+func (a *Agent) docNames(paths []string) []string {
+	var names []string
+	for _, path := range paths {
+		names = append(names, a.docName(path))
+	}
+	return names
+}
+
+func (a *Agent) startRun(paths []string) {
+	if a.run == nil {
+		return
+	}
+	if err := a.run.Start(a.docNames(paths)); err != nil {
+		a.logger.Warnf("Failed to save run state: %v", err)
+	}
+}
+
+func (a *Agent) recordRun(path, summary string, err error) {
+	if a.run == nil {
+		return
+	}
+	if saveErr := a.run.Record(a.docName(path), summary, err); saveErr != nil {
+		a.logger.Warnf("Failed to save run state: %v", saveErr)
+	}
+}
+
+// allowedTools drops file-modifying tools when running in dry-run mode.
+func (a *Agent) allowedTools(tools ...string) []string {
+	if !a.dryRun {
+		return tools
+	}
+
+	var readOnly []string
+	for _, tool := range tools {
+		if tool != "Write" && tool != "Edit" {
+			readOnly = append(readOnly, tool)
+		}
+	}
+	return readOnly
+}
+
+func (a *Agent) editPermissionMode() *string {
+	// Edits are allowed by the sandbox's path rules only, unlike in
+	// acceptEdits mode; non-interactive runs deny the rest
+	return stringPtr("default")
+}
+
+// printProposal writes a dry-run proposal in one block so concurrent
+// workers don't interleave their output.
+func (a *Agent) printProposal(name string, messages []claudecode.Message) {
+	proposal := resultText(messages)
+	if proposal == "" {
+		proposal = "(no proposal returned)"
+	}
+	if a.events != nil {
+		a.emit(Event{Kind: EventProposal, Name: name, Message: proposal})
+		return
+	}
+
+	a.outputMu.Lock()
+	defer a.outputMu.Unlock()
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Printf("PROPOSED CHANGES: %s\n", name)
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println(proposal)
+	fmt.Println(strings.Repeat("=", 70))
+}
+
+// resultText returns the final result text, falling back to the assistant's
+// text blocks when no result message was received.
+func resultText(messages []claudecode.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if resultMsg, ok := messages[i].(*claudecode.ResultMessage); ok && resultMsg.Result != nil {
+			return strings.TrimSpace(*resultMsg.Result)
+		}
+	}
+
+	var text strings.Builder
+	for _, message := range messages {
+		if message.Type() != claudecode.MessageTypeAssistant {
+			continue
+		}
+		for _, block := range message.Content() {
+			if textBlock, ok := block.(*claudecode.TextBlock); ok {
+				text.WriteString(textBlock.Text)
+				text.WriteString("\n")
+			}
+		}
+	}
+	return strings.TrimSpace(text.String())
+}
+
 func (a *Agent) ProcessFile(ctx context.Context, filePath string) error {
-	fileName := filepath.Base(filePath)
+	ctx = withItem(ctx, a.docName(filePath), a.itemProgress())
+	_, err := a.processFile(ctx, filePath)
+	return err
+}
+
+// processFile updates one document and returns the messages of the request,
+// for the run summary.
+func (a *Agent) processFile(ctx context.Context, filePath string) ([]claudecode.Message, error) {
+	fileName := a.docName(filePath)
 
 	prompt := fmt.Sprintf(`%s
 
 Here is the documentation file that you need to analyze:
 
 <documentation>
-%s/documentation/%s
+%s
 </documentation>
-`, a.systemPrompt, a.folder, fileName)
+`, a.systemPrompt, filePath)
+	prompt += a.reviewerRulesPrompt() + imagePlaceholderInstructions + a.tagPrompt() + archiveInstructions + digestInstructions
+	if a.keepAnchors {
+		prompt += anchorInstructions
+	}
+
+	if a.dryRun {
+		prompt += dryRunInstructions
+	}
 
-	a.logger.Printf("Starting processing: %s", fileName)
-	a.logger.Printf("Prompt length: %d characters", len(prompt))
+	a.logger.Infof("Starting processing: %s", fileName)
+	a.logger.Debugf("Prompt length: %d characters", len(prompt))
 
 	request := claudecode.QueryRequest{
 		Prompt: prompt,
 		Options: &claudecode.Options{
-			AllowedTools:   []string{"Read", "Write"},
-			PermissionMode: stringPtr("acceptEdits"),
+			AllowedTools:   a.allowedTools("Read", "Write"),
+			PermissionMode: a.editPermissionMode(),
 			Cwd:            stringPtr(a.folder),
 			OutputFormat:   outputFormatPtr(claudecode.OutputFormatJSON),
 			Verbose:        boolPtr(false),
 		},
 	}
 
-	messages, err := claudecode.QueryWithRequest(ctx, request)
+	before := snapshotDocs([]string{filePath}, "")
+	messages, err := a.query(ctx, ModeUpdateDocs, request)
 	if err != nil {
-		a.logger.Printf("Error processing %s: %v", fileName, err)
-		return fmt.Errorf("query error: %w", err)
+		a.logger.Errorf("Error processing %s: %v", fileName, err)
+		return messages, fmt.Errorf("query error: %w", err)
+	}
+	if !a.dryRun {
+		if messages, err = a.checkOutput(ctx, ModeUpdateDocs, fileName, request, before, messages); err != nil {
+			a.logger.Errorf("Error processing %s: %v", fileName, err)
+			return messages, err
+		}
+		if original, ok := before.content[filepath.Clean(filePath)]; ok && a.keepAnchors {
+			if err := a.restoreAnchors(filePath, original); err != nil {
+				return messages, err
+			}
+		}
 	}
 
-	a.logger.Printf("Completed processing: %s (received %d messages)", fileName, len(messages))
+	a.logger.Infof("Completed processing: %s (received %d messages)", fileName, len(messages))
+	a.proposeArchive(fileName, resultText(messages))
+	a.recordDigest(fileName, resultText(messages))
+	if !a.dryRun {
+		a.recordSources([]string{filePath}, messages)
+	}
 	for _, message := range messages {
 		a.logMessage(fileName, message)
 	}
 
-	return nil
+	if a.dryRun {
+		a.printProposal(fileName, messages)
+	}
+
+	return messages, nil
 }
 
 func (a *Agent) ProcessDocuments(ctx context.Context) (int, int, error) {
-	docsDir := filepath.Join(a.folder, "documentation")
-
-	if _, err := os.Stat(docsDir); os.IsNotExist(err) {
-		return 0, 0, fmt.Errorf("documentation directory does not exist: %s", docsDir)
+	dirExists := false
+	for _, dir := range a.docsDirs {
+		if _, err := os.Stat(dir); err == nil {
+			dirExists = true
+			break
+		}
+	}
+	if !dirExists {
+		return 0, 0, fmt.Errorf("documentation directory does not exist: %s", strings.Join(a.docsDirs, ", "))
 	}
 
-	files, err := filepath.Glob(filepath.Join(docsDir, "*.md"))
+	files, err := a.listDocs()
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to glob markdown files: %w", err)
+		return 0, 0, err
 	}
 
 	if len(files) == 0 {
-		return 0, 0, fmt.Errorf("no .md files found in: %s", docsDir)
+		return 0, 0, fmt.Errorf("no .md or .mdx files found in: %s", strings.Join(a.docsDirs, ", "))
 	}
 
 	totalFiles := len(files)
-	a.logger.Printf("Found %d markdown files to process", totalFiles)
-	fmt.Printf("Processing %d documentation files concurrently...\n", totalFiles)
+	a.logger.Infof("Found %d markdown files to process", totalFiles)
+	files = a.orderFiles(files)
+	a.startRun(files)
+	defer a.startBreaker(totalFiles)()
+	a.status("Processing %d documentation files %s...\n", totalFiles, a.inFlight(totalFiles))
+	report := a.startProgress(fmt.Sprintf("Processing %d documentation files", totalFiles), a.docNames(files))
 
 	resultChan := make(chan ProcessResult, totalFiles)
-	var wg sync.WaitGroup
-
-	for _, filePath := range files {
-		wg.Add(1)
-		go func(path string) {
-			defer wg.Done()
 
-			fileName := filepath.Base(path)
-			fmt.Printf("  → Started: %s\n", fileName)
+	go func() {
+		a.forEach(totalFiles, func(i int) {
+			path := files[i]
+			fileName := a.docName(path)
+			report.started(fileName)
+			itemCtx := withItem(ctx, fileName, report)
 
-			err := a.ProcessFile(ctx, path)
+			start := time.Now()
+			messages, err := a.processFile(itemCtx, path)
 
 			result := ProcessResult{
 				FileName: fileName,
 				Success:  err == nil,
 				Error:    err,
+				Duration: time.Since(start),
 			}
-
-			resultChan <- result
-
+			result.Turns, result.InputTokens, result.OutputTokens = resultStats(messages)
 			if err == nil {
-				fmt.Printf("  ✓ Completed: %s\n", fileName)
-			} else {
-				fmt.Printf("  ✗ Failed: %s - %v\n", fileName, err)
+				result.Summary = changeSummary(resultText(messages))
 			}
-		}(filePath)
-	}
+			a.recordRun(path, result.Summary, err)
 
-	go func() {
-		wg.Wait()
+			report.finished(result)
+			resultChan <- result
+		})
 		close(resultChan)
 	}()
 
 	successCount := 0
 	var failedFiles []string
+	var items []BatchItem
 
 	for result := range resultChan {
 		if result.Success {
@@ -155,14 +414,17 @@ func (a *Agent) ProcessDocuments(ctx context.Context) (int, int, error) {
 		} else {
 			failedFiles = append(failedFiles, result.FileName)
 		}
+		items = append(items, batchItem("file", result))
 	}
+	report.stop()
 
-	a.logger.Printf("Processing complete: %d/%d succeeded", successCount, totalFiles)
+	a.logger.Infof("Processing complete: %d/%d succeeded", successCount, totalFiles)
 	if len(failedFiles) > 0 {
-		a.logger.Printf("Failed files: %v", failedFiles)
+		a.logger.Warnf("Failed files: %v", failedFiles)
 	}
 
-	fmt.Printf("\nSummary: %d/%d files processed successfully\n", successCount, totalFiles)
+	a.status("\nSummary: %d/%d files processed successfully\n", successCount, totalFiles)
+	a.finishBatch(os.Stdout, items)
 
 	return successCount, totalFiles, nil
 }
@@ -179,45 +441,46 @@ func (a *Agent) UpdateSpecificDocuments(ctx context.Context, filePaths []string)
 	}
 
 	totalFiles := len(filePaths)
-	a.logger.Printf("Updating %d specific markdown files", totalFiles)
-	fmt.Printf("Updating %d documentation files concurrently...\n", totalFiles)
+	a.logger.Infof("Updating %d specific markdown files", totalFiles)
+	filePaths = a.orderFiles(filePaths)
+	a.startRun(filePaths)
+	defer a.startBreaker(totalFiles)()
+	a.status("Updating %d documentation files %s...\n", totalFiles, a.inFlight(totalFiles))
+	report := a.startProgress(fmt.Sprintf("Updating %d documentation files", totalFiles), a.docNames(filePaths))
 
 	resultChan := make(chan ProcessResult, totalFiles)
-	var wg sync.WaitGroup
 
-	for _, filePath := range filePaths {
-		wg.Add(1)
-		go func(path string) {
-			defer wg.Done()
-
-			fileName := filepath.Base(path)
-			fmt.Printf("  → Started: %s\n", fileName)
+	go func() {
+		a.forEach(totalFiles, func(i int) {
+			path := filePaths[i]
+			fileName := a.docName(path)
+			report.started(fileName)
+			itemCtx := withItem(ctx, fileName, report)
 
-			err := a.ProcessFile(ctx, path)
+			start := time.Now()
+			messages, err := a.processFile(itemCtx, path)
 
 			result := ProcessResult{
 				FileName: fileName,
 				Success:  err == nil,
 				Error:    err,
+				Duration: time.Since(start),
 			}
-
-			resultChan <- result
-
+			result.Turns, result.InputTokens, result.OutputTokens = resultStats(messages)
 			if err == nil {
-				fmt.Printf("  ✓ Completed: %s\n", fileName)
-			} else {
-				fmt.Printf("  ✗ Failed: %s - %v\n", fileName, err)
+				result.Summary = changeSummary(resultText(messages))
 			}
-		}(filePath)
-	}
+			a.recordRun(path, result.Summary, err)
 
-	go func() {
-		wg.Wait()
+			report.finished(result)
+			resultChan <- result
+		})
 		close(resultChan)
 	}()
 
 	successCount := 0
 	var failedFiles []string
+	var items []BatchItem
 
 	for result := range resultChan {
 		if result.Success {
@@ -225,14 +488,17 @@ func (a *Agent) UpdateSpecificDocuments(ctx context.Context, filePaths []string)
 		} else {
 			failedFiles = append(failedFiles, result.FileName)
 		}
+		items = append(items, batchItem("file", result))
 	}
+	report.stop()
 
-	a.logger.Printf("Update complete: %d/%d succeeded", successCount, totalFiles)
+	a.logger.Infof("Update complete: %d/%d succeeded", successCount, totalFiles)
 	if len(failedFiles) > 0 {
-		a.logger.Printf("Failed files: %v", failedFiles)
+		a.logger.Warnf("Failed files: %v", failedFiles)
 	}
 
-	fmt.Printf("\nSummary: %d/%d files updated successfully\n", successCount, totalFiles)
+	a.status("\nSummary: %d/%d files updated successfully\n", successCount, totalFiles)
+	a.finishBatch(os.Stdout, items)
 
 	return successCount, totalFiles, nil
 }
@@ -250,36 +516,42 @@ func (a *Agent) logMessage(fileName string, msg claudecode.Message) {
 				if len(text) > 100 {
 					text = text[:100] + "..."
 				}
-				a.logger.Printf("[%s] %s: %s", fileName, msgType, text)
+				a.logger.Debug("Message", "doc", fileName, "type", msgType, "text", text)
 
 			case *claudecode.ToolUseBlock:
-				a.logger.Printf("[%s] Tool use: %s (ID: %s)", fileName, b.Name, b.ID)
+				a.logger.Debug("Tool use", "doc", fileName, "tool", b.Name, "id", b.ID)
 
 			case *claudecode.ToolResultBlock:
-				a.logger.Printf("[%s] Tool result (ID: %s)", fileName, b.ToolUseID)
+				a.logger.Debug("Tool result", "doc", fileName, "id", b.ToolUseID)
 			}
 		}
 
 	case claudecode.MessageTypeSystem:
 		if sysMsg, ok := msg.(*claudecode.SystemMessage); ok {
-			a.logger.Printf("[%s] System - Session: %s", fileName, sysMsg.SessionID)
+			a.logger.Debug("Session", "doc", fileName, "session", sysMsg.SessionID)
 		}
 
 	case claudecode.MessageTypeResult:
 		if resultMsg, ok := msg.(*claudecode.ResultMessage); ok {
-			a.logger.Printf("[%s] Result - Duration: %dms, Turns: %d, Success: %v",
-				fileName, resultMsg.DurationMs, resultMsg.NumTurns, !resultMsg.IsError)
-
+			args := []any{"doc", fileName, "duration_ms", resultMsg.DurationMs, "turns", resultMsg.NumTurns, "success", !resultMsg.IsError}
 			if resultMsg.Usage != nil {
-				a.logger.Printf("[%s] Tokens - Input: %d, Output: %d",
-					fileName, resultMsg.Usage.InputTokens, resultMsg.Usage.OutputTokens)
+				args = append(args, "input_tokens", resultMsg.Usage.InputTokens, "output_tokens", resultMsg.Usage.OutputTokens)
 			}
+			a.logger.Info("Result", args...)
 		}
 	}
 }
 
 func (a *Agent) WriteTopic(ctx context.Context, topic string) error {
-	a.logger.Printf("Starting documentation writing for topic: %s", topic)
+	ctx = withItem(ctx, topic, a.itemProgress())
+	_, err := a.writeTopic(ctx, topic)
+	return err
+}
+
+// writeTopic documents one topic and returns the messages of the request, for
+// the run summary.
+func (a *Agent) writeTopic(ctx context.Context, topic string) ([]claudecode.Message, error) {
+	a.logger.Infof("Starting documentation writing for topic: %s", topic)
 
 	prompt := fmt.Sprintf(`%s
 
@@ -287,87 +559,105 @@ The topic you need to document is: %s
 
 The codebase you will be reading through is located at: %s
 
-IMPORTANT: You must write the documentation file in the documentation/ folder within the codebase directory.
+IMPORTANT: You must write the documentation file in the documentation folder for this codebase.
 Create a markdown file with an appropriate filename based on the topic (e.g., "api-authentication.md", "database-schema.md").
-The documentation should be saved to: %s/documentation/
+The documentation should be saved to: %s/
+
+Please analyze the codebase and create comprehensive documentation for this topic following the structure and guidelines provided in the system prompt.`, a.systemPrompt, topic, a.folder, a.docsDirs[0])
+	prompt += a.reviewerRulesPrompt() + imagePlaceholderInstructions + a.tagPrompt()
 
-Please analyze the codebase and create comprehensive documentation for this topic following the structure and guidelines provided in the system prompt.`, a.systemPrompt, topic, a.folder, a.folder)
+	if a.dryRun {
+		prompt += dryRunInstructions
+	}
 
-	a.logger.Printf("Topic: %s - Prompt length: %d characters", topic, len(prompt))
+	a.logger.Debugf("Topic: %s - Prompt length: %d characters", topic, len(prompt))
 
 	request := claudecode.QueryRequest{
 		Prompt: prompt,
 		Options: &claudecode.Options{
-			AllowedTools:   []string{"Read", "Write", "LS", "Grep"},
-			PermissionMode: stringPtr("acceptEdits"),
+			AllowedTools:   a.allowedTools("Read", "Write", "LS", "Grep"),
+			PermissionMode: a.editPermissionMode(),
 			Cwd:            stringPtr(a.folder),
 			OutputFormat:   outputFormatPtr(claudecode.OutputFormatJSON),
 			Verbose:        boolPtr(false),
 		},
 	}
 
+	existing, _ := FindDocs(a.docsDirs[:1])
+	before := snapshotDocs(existing, a.docsDirs[0])
+
 	// Use non-streaming query to avoid buffer overflow
-	messages, err := claudecode.QueryWithRequest(ctx, request)
+	messages, err := a.query(ctx, ModeWriteDocs, request)
 	if err != nil {
-		a.logger.Printf("Error writing documentation for topic %s: %v", topic, err)
-		return fmt.Errorf("query error: %w", err)
+		a.logger.Errorf("Error writing documentation for topic %s: %v", topic, err)
+		return messages, fmt.Errorf("query error: %w", err)
+	}
+	if !a.dryRun {
+		if messages, err = a.checkOutput(ctx, ModeWriteDocs, topic, request, before, messages); err != nil {
+			a.logger.Errorf("Error writing documentation for topic %s: %v", topic, err)
+			return messages, err
+		}
 	}
 
-	a.logger.Printf("Completed writing documentation for topic: %s (received %d messages)", topic, len(messages))
+	a.logger.Infof("Completed writing documentation for topic: %s (received %d messages)", topic, len(messages))
+	if !a.dryRun {
+		a.recordSources(before.written(a, messages), messages)
+	}
 	for _, message := range messages {
 		a.logTopicMessage(topic, message)
 	}
 
-	return nil
+	if a.dryRun {
+		a.printProposal(topic, messages)
+	}
+
+	return messages, nil
 }
 
 func (a *Agent) WriteDocumentation(ctx context.Context, topics []string) (int, int, error) {
 	totalTopics := len(topics)
-	a.logger.Printf("Starting documentation writing for %d topics", totalTopics)
+	a.logger.Infof("Starting documentation writing for %d topics", totalTopics)
 
-	docsDir := filepath.Join(a.folder, "documentation")
-	if err := os.MkdirAll(docsDir, 0755); err != nil {
-		return 0, 0, fmt.Errorf("failed to create documentation directory: %w", err)
+	if !a.dryRun {
+		docsDir := a.docsDirs[0]
+		if err := os.MkdirAll(docsDir, 0755); err != nil {
+			return 0, 0, fmt.Errorf("failed to create documentation directory: %w", err)
+		}
+		a.logger.Infof("Documentation directory ready: %s", docsDir)
 	}
-	a.logger.Printf("Documentation directory ready: %s", docsDir)
 
-	fmt.Printf("Writing documentation for %d topics concurrently...\n", totalTopics)
+	defer a.startBreaker(totalTopics)()
+	a.status("Writing documentation for %d topics %s...\n", totalTopics, a.inFlight(totalTopics))
+	report := a.startProgress(fmt.Sprintf("Writing documentation for %d topics", totalTopics), topics)
 
 	resultChan := make(chan ProcessResult, totalTopics)
-	var wg sync.WaitGroup
 
-	for _, topic := range topics {
-		wg.Add(1)
-		go func(t string) {
-			defer wg.Done()
-
-			fmt.Printf("  → Started: %s\n", t)
+	go func() {
+		a.forEach(totalTopics, func(i int) {
+			t := topics[i]
+			report.started(t)
+			itemCtx := withItem(ctx, t, report)
 
-			err := a.WriteTopic(ctx, t)
+			start := time.Now()
+			messages, err := a.writeTopic(itemCtx, t)
 
 			result := ProcessResult{
 				FileName: t,
 				Success:  err == nil,
 				Error:    err,
+				Duration: time.Since(start),
 			}
+			result.Turns, result.InputTokens, result.OutputTokens = resultStats(messages)
 
+			report.finished(result)
 			resultChan <- result
-
-			if err == nil {
-				fmt.Printf("  ✓ Completed: %s\n", t)
-			} else {
-				fmt.Printf("  ✗ Failed: %s - %v\n", t, err)
-			}
-		}(topic)
-	}
-
-	go func() {
-		wg.Wait()
+		})
 		close(resultChan)
 	}()
 
 	successCount := 0
 	var failedTopics []string
+	var items []BatchItem
 
 	for result := range resultChan {
 		if result.Success {
@@ -375,14 +665,17 @@ func (a *Agent) WriteDocumentation(ctx context.Context, topics []string) (int, i
 		} else {
 			failedTopics = append(failedTopics, result.FileName)
 		}
+		items = append(items, batchItem("topic", result))
 	}
+	report.stop()
 
-	a.logger.Printf("Documentation writing complete: %d/%d succeeded", successCount, totalTopics)
+	a.logger.Infof("Documentation writing complete: %d/%d succeeded", successCount, totalTopics)
 	if len(failedTopics) > 0 {
-		a.logger.Printf("Failed topics: %v", failedTopics)
+		a.logger.Warnf("Failed topics: %v", failedTopics)
 	}
 
-	fmt.Printf("\nSummary: %d/%d topics documented successfully\n", successCount, totalTopics)
+	a.status("\nSummary: %d/%d topics documented successfully\n", successCount, totalTopics)
+	a.finishBatch(os.Stdout, items)
 
 	return successCount, totalTopics, nil
 }
@@ -399,30 +692,28 @@ func (a *Agent) logTopicMessage(topic string, msg claudecode.Message) {
 				if len(text) > 100 {
 					text = text[:100] + "..."
 				}
-				a.logger.Printf("[%s] %s: %s", topic, msgType, text)
+				a.logger.Debug("Message", "topic", topic, "type", msgType, "text", text)
 
 			case *claudecode.ToolUseBlock:
-				a.logger.Printf("[%s] Tool use: %s (ID: %s)", topic, b.Name, b.ID)
+				a.logger.Debug("Tool use", "topic", topic, "tool", b.Name, "id", b.ID)
 
 			case *claudecode.ToolResultBlock:
-				a.logger.Printf("[%s] Tool result (ID: %s)", topic, b.ToolUseID)
+				a.logger.Debug("Tool result", "topic", topic, "id", b.ToolUseID)
 			}
 		}
 
 	case claudecode.MessageTypeSystem:
 		if sysMsg, ok := msg.(*claudecode.SystemMessage); ok {
-			a.logger.Printf("[%s] System - Session: %s", topic, sysMsg.SessionID)
+			a.logger.Debug("Session", "topic", topic, "session", sysMsg.SessionID)
 		}
 
 	case claudecode.MessageTypeResult:
 		if resultMsg, ok := msg.(*claudecode.ResultMessage); ok {
-			a.logger.Printf("[%s] Result - Duration: %dms, Turns: %d, Success: %v",
-				topic, resultMsg.DurationMs, resultMsg.NumTurns, !resultMsg.IsError)
-
+			args := []any{"topic", topic, "duration_ms", resultMsg.DurationMs, "turns", resultMsg.NumTurns, "success", !resultMsg.IsError}
 			if resultMsg.Usage != nil {
-				a.logger.Printf("[%s] Tokens - Input: %d, Output: %d",
-					topic, resultMsg.Usage.InputTokens, resultMsg.Usage.OutputTokens)
+				args = append(args, "input_tokens", resultMsg.Usage.InputTokens, "output_tokens", resultMsg.Usage.OutputTokens)
 			}
+			a.logger.Info("Result", args...)
 		}
 	}
 }
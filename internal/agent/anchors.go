@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/udemy/docu-jarvis-cli/internal/links"
+)
+
+// anchorInstructions ask Claude to keep the headings that docs sites and
+// other docs deep-link to.
+const anchorInstructions = `
+
+Other pages link to the headings of this document by their anchors, so keep the text of existing headings unless it is wrong. When a heading must be renamed, keep its old anchor with an HTML anchor on the line before it, e.g. <a id="old-heading-text"></a>. When a section is removed, put its anchor before the section its content moved to.`
+
+// AnchorRedirect is a heading anchor that an update removed and that was
+// kept, pointing to the heading that took its place.
+type AnchorRedirect struct {
+	File string // relative to the folder
+	From string
+	To   string
+}
+
+// SetKeepAnchors makes document updates keep the anchors of existing
+// headings. Anchors that Claude drops anyway are added back as HTML anchors.
+func (a *Agent) SetKeepAnchors(keep bool) {
+	a.keepAnchors = keep
+}
+
+// AnchorRedirects returns the anchors kept for renamed or removed headings.
+func (a *Agent) AnchorRedirects() []AnchorRedirect {
+	a.outputMu.Lock()
+	defer a.outputMu.Unlock()
+	return append([]AnchorRedirect(nil), a.redirects...)
+}
+
+// restoreAnchors adds back the heading anchors of before that the update of
+// path dropped.
+func (a *Agent) restoreAnchors(path string, before []byte) error {
+	after, err := os.ReadFile(path)
+	if err != nil || string(after) == string(before) {
+		return nil
+	}
+	content, redirects := keepAnchors(string(before), string(after))
+	if len(redirects) == 0 {
+		return nil
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to keep the anchors of %s: %w", a.docName(path), err)
+	}
+
+	name := a.docName(path)
+	var moved []string
+	for i := range redirects {
+		redirects[i].File = name
+		moved = append(moved, "#"+redirects[i].From+" -> #"+redirects[i].To)
+	}
+	a.logger.Infof("Kept %d anchors of %s: %s", len(redirects), name, strings.Join(moved, ", "))
+	a.status("  Kept %d anchors of %s that the update removed: %s\n", len(redirects), name, strings.Join(moved, ", "))
+
+	a.outputMu.Lock()
+	a.redirects = append(a.redirects, redirects...)
+	a.outputMu.Unlock()
+	return nil
+}
+
+// keepAnchors puts an HTML anchor for each heading anchor of before that
+// after no longer has above the heading that replaced it: the heading of the
+// same level at the same position when that one is new, else where the
+// nearest earlier heading is now, else the first heading.
+func keepAnchors(before, after string) (string, []AnchorRedirect) {
+	oldHeadings, newHeadings := links.Headings(before), links.Headings(after)
+	oldAnchors, newAnchors := links.Anchors(before), links.Anchors(after)
+	if len(newHeadings) == 0 {
+		return after, nil
+	}
+
+	byAnchor := make(map[string]links.Heading, len(newHeadings))
+	byLevel := make(map[int][]links.Heading)
+	for _, h := range newHeadings {
+		byAnchor[h.Anchor] = h
+		byLevel[h.Level] = append(byLevel[h.Level], h)
+	}
+
+	inserts := make(map[int][]string) // by line of the heading
+	var redirects []AnchorRedirect
+	moved := make(map[string]links.Heading) // old anchor to the heading it now leads to
+	position := make(map[int]int)
+	for i, old := range oldHeadings {
+		n := position[old.Level]
+		position[old.Level]++
+		if newAnchors[old.Anchor] {
+			continue
+		}
+
+		target, found := links.Heading{}, false
+		if same := byLevel[old.Level]; n < len(same) && !oldAnchors[same[n].Anchor] {
+			target, found = same[n], true
+		}
+		for j := i - 1; j >= 0 && !found; j-- {
+			if target, found = byAnchor[oldHeadings[j].Anchor]; !found {
+				target, found = moved[oldHeadings[j].Anchor]
+			}
+		}
+		if !found {
+			target = newHeadings[0]
+		}
+
+		moved[old.Anchor] = target
+		inserts[target.Line] = append(inserts[target.Line], fmt.Sprintf(`<a id="%s"></a>`, old.Anchor))
+		redirects = append(redirects, AnchorRedirect{From: old.Anchor, To: target.Anchor})
+	}
+	if len(redirects) == 0 {
+		return after, nil
+	}
+
+	var out []string
+	for i, line := range strings.Split(after, "\n") {
+		out = append(out, inserts[i+1]...)
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n"), redirects
+}
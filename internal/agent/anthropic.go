@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/udemy/docu-jarvis-cli/internal/netguard"
+	"github.com/udemy/docu-jarvis-cli/internal/redact"
+)
+
+const (
+	defaultAnthropicModel   = "claude-sonnet-4-5"
+	defaultAnthropicBaseURL = "https://api.anthropic.com"
+	anthropicVersion        = "2023-06-01"
+)
+
+// anthropicTransport calls the Anthropic Messages API, or the backend that
+// ANTHROPIC_BASE_URL points at.
+type anthropicTransport struct {
+	apiKey  string
+	baseURL string
+}
+
+func newAnthropicTransport(cfg ProviderConfig) *anthropicTransport {
+	t := &anthropicTransport{
+		apiKey:  firstSet(os.Getenv("ANTHROPIC_API_KEY"), cfg.APIKey),
+		baseURL: strings.TrimSuffix(firstSet(os.Getenv("ANTHROPIC_BASE_URL"), defaultAnthropicBaseURL), "/"),
+	}
+	redact.AddSecret(t.apiKey)
+	return t
+}
+
+func (t *anthropicTransport) check() error {
+	if err := netguard.CheckClaude(); err != nil {
+		return err
+	}
+	if t.apiKey == "" {
+		return fmt.Errorf("the anthropic provider needs an API key: set anthropic_api_key in the config, or ANTHROPIC_API_KEY")
+	}
+	return nil
+}
+
+func (t *anthropicTransport) send(ctx context.Context, model string, body map[string]interface{}) ([]byte, error) {
+	body["model"] = model
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	return postAPI(ctx, ProviderAnthropic, t.baseURL+"/v1/messages", data, map[string]string{
+		"x-api-key":         t.apiKey,
+		"anthropic-version": anthropicVersion,
+	})
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+
+	"github.com/udemy/docu-jarvis-cli/internal/routes"
+)
+
+// maxPromptRoutes is the most detected routes listed in the prompt; Claude
+// finds the rest by reading the routers.
+const maxPromptRoutes = 300
+
+// WriteAPIDocs has Claude document the HTTP API whose detected routes are
+// given in output, an absolute path: an endpoint reference when it is a
+// markdown file, an OpenAPI spec otherwise, following the system prompt.
+func (a *Agent) WriteAPIDocs(ctx context.Context, found []routes.Route, output string) error {
+	name := a.docName(output)
+	a.logger.Infof("Writing API docs for %d detected routes to %s", len(found), name)
+
+	var list strings.Builder
+	for i, r := range found {
+		if i == maxPromptRoutes {
+			fmt.Fprintf(&list, "... and %d more in the same files\n", len(found)-maxPromptRoutes)
+			break
+		}
+		fmt.Fprintf(&list, "- %s\n", r)
+	}
+
+	prompt := fmt.Sprintf(`%s
+
+The HTTP routes found in the codebase (method, path as written, file:line, framework):
+%s
+The codebase you will be reading through is located at: %s
+
+IMPORTANT: Write the result to: %s
+If the file exists, update it to match the code, keeping what is still right.`, a.systemPrompt, list.String(), a.folder, output)
+	if IsDocFile(output) {
+		prompt += a.reviewerRulesPrompt()
+	}
+	if a.dryRun {
+		prompt += dryRunInstructions
+	}
+
+	request := claudecode.QueryRequest{
+		Prompt: prompt,
+		Options: &claudecode.Options{
+			AllowedTools:   a.allowedTools("Read", "Write", "Edit", "LS", "Grep", "Glob"),
+			PermissionMode: a.editPermissionMode(),
+			Cwd:            stringPtr(a.folder),
+			OutputFormat:   outputFormatPtr(claudecode.OutputFormatJSON),
+			Verbose:        boolPtr(false),
+		},
+	}
+
+	before := snapshotDocs([]string{output}, filepath.Dir(output))
+	messages, err := a.query(ctx, ModeWriteAPIDocs, request)
+	if err != nil {
+		a.logger.Errorf("Error writing API docs to %s: %v", name, err)
+		return fmt.Errorf("query error: %w", err)
+	}
+	if !a.dryRun {
+		if messages, err = a.checkOutput(ctx, ModeWriteAPIDocs, name, request, before, messages); err != nil {
+			a.logger.Errorf("Error writing API docs to %s: %v", name, err)
+			return err
+		}
+	}
+	for _, message := range messages {
+		a.logMessage(name, message)
+	}
+
+	if a.dryRun {
+		a.printProposal(name, messages)
+	}
+	a.logger.Infof("Completed API docs %s", name)
+	return nil
+}
@@ -0,0 +1,214 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+)
+
+// DocsAuditSchemaVersion is bumped whenever DocsAudit changes in a way that
+// could break consumers parsing it.
+const DocsAuditSchemaVersion = 1
+
+// maxLayoutEntries caps the codebase layout given to Claude; it explores the
+// rest itself.
+const maxLayoutEntries = 200
+
+type UndocumentedArea struct {
+	Area     string `json:"area"`
+	Kind     string `json:"kind"`
+	Location string `json:"location"`
+	Reason   string `json:"reason"`
+}
+
+type StaleReference struct {
+	File      string `json:"file"`
+	Reference string `json:"reference"`
+	Reason    string `json:"reason"`
+}
+
+type SuggestedTopic struct {
+	Topic    string   `json:"topic"`
+	Priority string   `json:"priority"`
+	Covers   []string `json:"covers"`
+	Reason   string   `json:"reason"`
+}
+
+// DocsAudit is the coverage report of audit-docs.
+type DocsAudit struct {
+	SchemaVersion   int                `json:"schema_version"`
+	Summary         string             `json:"summary"`
+	DocFiles        int                `json:"doc_files"`
+	AreasTotal      int                `json:"areas_total"`
+	AreasDocumented int                `json:"areas_documented"`
+	Undocumented    []UndocumentedArea `json:"undocumented"`
+	Stale           []StaleReference   `json:"stale"`
+	SuggestedTopics []SuggestedTopic   `json:"suggested_topics"`
+}
+
+// Coverage is the share of the audited areas that are documented, in percent.
+func (d *DocsAudit) Coverage() int {
+	if d.AreasTotal == 0 {
+		return 0
+	}
+	return d.AreasDocumented * 100 / d.AreasTotal
+}
+
+// TopicsByPriority returns the suggested topics, high priority first.
+func (d *DocsAudit) TopicsByPriority() []SuggestedTopic {
+	rank := map[string]int{"high": 0, "medium": 1, "low": 2}
+	topics := append([]SuggestedTopic(nil), d.SuggestedTopics...)
+	sort.SliceStable(topics, func(i, j int) bool {
+		ri, ok := rank[topics[i].Priority]
+		if !ok {
+			ri = len(rank)
+		}
+		rj, ok := rank[topics[j].Priority]
+		if !ok {
+			rj = len(rank)
+		}
+		return ri < rj
+	})
+	return topics
+}
+
+func (a *Agent) AuditDocs(ctx context.Context) (*DocsAudit, error) {
+	docFiles, err := a.listDocs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan documentation directory: %w", err)
+	}
+
+	a.logger.Infof("Auditing documentation coverage with %d docs", len(docFiles))
+
+	var fileList strings.Builder
+	for _, file := range docFiles {
+		fileList.WriteString(fmt.Sprintf("- %s\n", a.docName(file)))
+	}
+	if len(docFiles) == 0 {
+		fileList.WriteString("(none)\n")
+	}
+
+	prompt := fmt.Sprintf(`%s
+
+The codebase is located at: %s
+
+Top-level layout of the codebase (directories with the number of files directly in them):
+%s
+Documentation files (paths relative to the codebase root):
+%s`, a.systemPrompt, a.folder, codebaseLayout(a.folder, a.docsDirs), fileList.String())
+
+	request := claudecode.QueryRequest{
+		Prompt: prompt,
+		Options: &claudecode.Options{
+			AllowedTools:   []string{"Read", "Grep", "Glob", "LS"},
+			PermissionMode: stringPtr("acceptEdits"),
+			Cwd:            stringPtr(a.folder),
+			OutputFormat:   outputFormatPtr(claudecode.OutputFormatJSON),
+			Verbose:        boolPtr(false),
+			MaxTurns:       intPtr(40),
+		},
+	}
+
+	messages, err := a.query(ctx, ModeAuditDocs, request)
+	if err != nil {
+		a.logger.Errorf("Error auditing docs: %v", err)
+		return nil, fmt.Errorf("docs audit error: %w", err)
+	}
+
+	var audit *DocsAudit
+	for _, candidate := range jsonObjectCandidates(resultText(messages)) {
+		var parsed DocsAudit
+		if err := json.Unmarshal([]byte(candidate), &parsed); err == nil {
+			audit = &parsed
+			break
+		}
+	}
+
+	if audit == nil {
+		a.logger.Errorf("Could not extract JSON from docs audit")
+		return nil, fmt.Errorf("Claude did not return expected JSON response")
+	}
+
+	audit.SchemaVersion = DocsAuditSchemaVersion
+	audit.DocFiles = len(docFiles)
+	if audit.AreasDocumented > audit.AreasTotal {
+		audit.AreasDocumented = audit.AreasTotal
+	}
+	if audit.Undocumented == nil {
+		audit.Undocumented = []UndocumentedArea{}
+	}
+	if audit.Stale == nil {
+		audit.Stale = []StaleReference{}
+	}
+	if audit.SuggestedTopics == nil {
+		audit.SuggestedTopics = []SuggestedTopic{}
+	}
+
+	a.logger.Infof("Docs audit: %d/%d areas documented, %d stale references, %d suggested topics",
+		audit.AreasDocumented, audit.AreasTotal, len(audit.Stale), len(audit.SuggestedTopics))
+	return audit, nil
+}
+
+// codebaseLayout lists the directories two levels deep, without hidden ones,
+// dependencies, and the docs directories.
+func codebaseLayout(root string, docsDirs []string) string {
+	skip := map[string]bool{"node_modules": true, "vendor": true, "dist": true, "build": true, "target": true}
+	isDocsDir := func(path string) bool {
+		for _, dir := range docsDirs {
+			if filepath.Clean(dir) == path {
+				return true
+			}
+		}
+		return false
+	}
+
+	var layout strings.Builder
+	entries := 0
+	var walk func(dir string, depth int)
+	walk = func(dir string, depth int) {
+		items, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		files := 0
+		var subdirs []string
+		for _, item := range items {
+			if strings.HasPrefix(item.Name(), ".") {
+				continue
+			}
+			if !item.IsDir() {
+				files++
+				continue
+			}
+			path := filepath.Join(dir, item.Name())
+			if !skip[item.Name()] && !isDocsDir(path) {
+				subdirs = append(subdirs, path)
+			}
+		}
+
+		if entries >= maxLayoutEntries {
+			return
+		}
+		rel, _ := filepath.Rel(root, dir)
+		layout.WriteString(fmt.Sprintf("- %s/ (%d files)\n", filepath.ToSlash(rel), files))
+		entries++
+
+		if depth < 2 {
+			for _, subdir := range subdirs {
+				walk(subdir, depth+1)
+			}
+		}
+	}
+	walk(root, 0)
+
+	if entries >= maxLayoutEntries {
+		layout.WriteString("- ... (more directories not listed)\n")
+	}
+	return layout.String()
+}
@@ -0,0 +1,184 @@
+package agent
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/udemy/docu-jarvis-cli/internal/netguard"
+	"github.com/udemy/docu-jarvis-cli/internal/redact"
+)
+
+const (
+	defaultBedrockModel = "us.anthropic.claude-sonnet-4-5-20250929-v1:0"
+	bedrockVersion      = "bedrock-2023-05-31"
+)
+
+// bedrockTransport calls Claude on AWS Bedrock, signing requests with the
+// credentials of the environment or the AWS CLI (profiles, SSO).
+type bedrockTransport struct {
+	region string
+}
+
+type awsCredentials struct {
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+func newBedrockTransport(cfg ProviderConfig) *bedrockTransport {
+	return &bedrockTransport{region: firstSet(cfg.Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))}
+}
+
+func (t *bedrockTransport) check() error {
+	if err := netguard.Check("calling Claude on AWS Bedrock"); err != nil {
+		return err
+	}
+	if t.region == "" {
+		return fmt.Errorf("the bedrock provider needs a region: set aws_region in the config, or AWS_REGION")
+	}
+	return nil
+}
+
+func (t *bedrockTransport) send(ctx context.Context, model string, body map[string]interface{}) ([]byte, error) {
+	body["anthropic_version"] = bedrockVersion
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	creds, err := awsCredentialsFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	host := fmt.Sprintf("bedrock-runtime.%s.amazonaws.com", t.region)
+	path := "/model/" + awsEscape(model) + "/invoke"
+	headers := signAWSRequest(creds, t.region, "bedrock", host, path, data, time.Now().UTC())
+	return postAPI(ctx, ProviderBedrock, "https://"+host+path, data, headers)
+}
+
+// awsCredentialsFor returns the credentials of the environment, or those the
+// AWS CLI resolves for the current profile.
+func awsCredentialsFor(ctx context.Context) (awsCredentials, error) {
+	creds := awsCredentials{
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.accessKey == "" || creds.secretKey == "" {
+		output, err := exec.CommandContext(ctx, "aws", "configure", "export-credentials", "--format", "env-no-export").Output()
+		if err != nil {
+			return awsCredentials{}, fmt.Errorf("failed to get AWS credentials (set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, or log in with the AWS CLI): %w", err)
+		}
+		creds = awsCredentials{}
+		for _, line := range strings.Split(string(output), "\n") {
+			key, value, _ := strings.Cut(strings.TrimSpace(line), "=")
+			switch key {
+			case "AWS_ACCESS_KEY_ID":
+				creds.accessKey = value
+			case "AWS_SECRET_ACCESS_KEY":
+				creds.secretKey = value
+			case "AWS_SESSION_TOKEN":
+				creds.sessionToken = value
+			}
+		}
+		if creds.accessKey == "" || creds.secretKey == "" {
+			return awsCredentials{}, fmt.Errorf("the AWS CLI returned no credentials")
+		}
+	}
+	redact.AddSecret(creds.secretKey)
+	redact.AddSecret(creds.sessionToken)
+	return creds, nil
+}
+
+// signAWSRequest returns the headers that sign a JSON POST with Signature
+// Version 4. path must already be escaped.
+func signAWSRequest(creds awsCredentials, region, service, host, path string, body []byte, now time.Time) map[string]string {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	headers := map[string]string{
+		"content-type":         "application/json",
+		"host":                 host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if creds.sessionToken != "" {
+		headers["x-amz-security-token"] = creds.sessionToken
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	// Services other than S3 escape each path segment a second time
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = awsEscape(segment)
+	}
+	canonicalRequest := strings.Join([]string{
+		http.MethodPost,
+		strings.Join(segments, "/"),
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, region, service)
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	delete(headers, "host")
+	headers["authorization"] = fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.accessKey, scope, signedHeaders, signature)
+	return headers
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsEscape escapes everything but the unreserved characters, as SigV4
+// requires (url.PathEscape keeps characters such as ':').
+func awsEscape(s string) string {
+	var escaped strings.Builder
+	for _, b := range []byte(s) {
+		if 'A' <= b && b <= 'Z' || 'a' <= b && b <= 'z' || '0' <= b && b <= '9' || strings.IndexByte("-_.~", b) >= 0 {
+			escaped.WriteByte(b)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
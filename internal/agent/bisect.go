@@ -0,0 +1,183 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+)
+
+// maxBisectDiff keeps very large commits from crowding out the instructions.
+const maxBisectDiff = 100 * 1024
+
+// BisectVerdict is Claude's decision for one bisect step.
+type BisectVerdict struct {
+	BugPresent  bool
+	Confidence  int // 0-100
+	Explanation string
+}
+
+// BisectReview lets the caller confirm or override a verdict before the range
+// is narrowed. It returns whether the bug is present at the commit.
+type BisectReview func(commit string, verdict *BisectVerdict) bool
+
+// BisectBug binary-searches commits (newest first, as from git log, in
+// hash|author|date|subject form) for the first one with the bug, asking Claude
+// about one midpoint per step instead of analyzing every commit. The newest
+// commit is assumed to have the bug. It returns the culprit commit and the
+// number of steps taken.
+func (a *Agent) BisectBug(ctx context.Context, commits []string, diffFor func(hash string) (string, error), bugDescription string, review BisectReview) (string, int, error) {
+	if len(commits) == 0 {
+		return "", 0, fmt.Errorf("no commits to bisect")
+	}
+
+	// Oldest first, so "bug present" means the culprit is at or before mid
+	ordered := make([]string, len(commits))
+	for i, commit := range commits {
+		ordered[len(commits)-1-i] = commit
+	}
+
+	a.logger.Infof("Bisecting %d commits for bug: %s", len(ordered), bugDescription)
+
+	lo, hi := 0, len(ordered)-1
+	steps := 0
+	for lo < hi {
+		if err := ctx.Err(); err != nil {
+			return "", steps, err
+		}
+
+		mid := (lo + hi) / 2
+		steps++
+		hash := strings.SplitN(ordered[mid], "|", 2)[0]
+		fmt.Printf("\nStep %d: %d commits left, checking %s\n", steps, hi-lo+1, describeCommit(ordered[mid]))
+
+		diff, err := diffFor(hash)
+		if err != nil {
+			return "", steps, fmt.Errorf("failed to get diff for %s: %w", shortHash(hash), err)
+		}
+
+		verdict, err := a.JudgeBisectCommit(ctx, ordered[mid], diff, bugDescription, ordered[lo:hi+1])
+		if err != nil {
+			return "", steps, err
+		}
+
+		present := verdict.BugPresent
+		if review != nil {
+			present = review(ordered[mid], verdict)
+		}
+
+		if present {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+		a.logger.Infof("Bisect step %d: %s present=%v (confidence %d), range now %d-%d", steps, shortHash(hash), present, verdict.Confidence, lo, hi)
+	}
+
+	return ordered[lo], steps, nil
+}
+
+// JudgeBisectCommit asks whether the bug is present once commit is applied.
+// window is the remaining range, oldest first, given for context.
+func (a *Agent) JudgeBisectCommit(ctx context.Context, commit, diff, bugDescription string, window []string) (*BisectVerdict, error) {
+	if len(diff) > maxBisectDiff {
+		diff = diff[:maxBisectDiff] + "\n... (diff truncated)"
+	}
+
+	var rangeList strings.Builder
+	for _, c := range window {
+		marker := "  "
+		if c == commit {
+			marker = "->"
+		}
+		rangeList.WriteString(fmt.Sprintf("%s %s\n", marker, describeCommit(c)))
+	}
+
+	prompt := fmt.Sprintf(`%s
+
+Codebase location: %s
+
+Bug description:
+%s
+
+Remaining commits, oldest first (the marked one is being checked):
+%s
+Diff of the commit being checked:
+<diff>
+%s
+</diff>`, a.systemPrompt, a.folder, bugDescription, rangeList.String(), diff)
+	prompt += a.pastCasesPrompt()
+
+	request := claudecode.QueryRequest{
+		Prompt: prompt,
+		Options: &claudecode.Options{
+			AllowedTools:   []string{"Read", "Grep", "LS"},
+			PermissionMode: stringPtr("acceptEdits"),
+			Cwd:            stringPtr(a.folder),
+			OutputFormat:   outputFormatPtr(claudecode.OutputFormatJSON),
+			Verbose:        boolPtr(false),
+			MaxTurns:       intPtr(15),
+		},
+	}
+
+	messages, err := a.query(ctx, ModeDebug, request)
+	if err != nil {
+		a.logger.Errorf("Error judging bisect commit: %v", err)
+		return nil, fmt.Errorf("bisect analysis error: %w", err)
+	}
+
+	text := resultText(messages)
+	var lastErr error = fmt.Errorf("no JSON object found")
+	for _, candidate := range jsonObjectCandidates(text) {
+		verdict, err := decodeBisectVerdict(candidate)
+		if err == nil {
+			return verdict, nil
+		}
+		lastErr = err
+	}
+
+	a.logger.Errorf("Could not extract JSON from bisect verdict: %v", lastErr)
+	return nil, fmt.Errorf("Claude did not return expected JSON response: %w", lastErr)
+}
+
+func decodeBisectVerdict(data string) (*BisectVerdict, error) {
+	var resp struct {
+		BugPresent  *bool       `json:"bug_present"`
+		Confidence  json.Number `json:"confidence"`
+		Explanation string      `json:"explanation"`
+	}
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if resp.BugPresent == nil {
+		return nil, fmt.Errorf("missing required field: bug_present")
+	}
+
+	verdict := &BisectVerdict{BugPresent: *resp.BugPresent, Explanation: resp.Explanation}
+	if resp.Confidence != "" {
+		confidence, err := resp.Confidence.Float64()
+		if err != nil || confidence < 0 || confidence > 100 {
+			return nil, fmt.Errorf("invalid confidence %q", resp.Confidence)
+		}
+		verdict.Confidence = int(confidence)
+	}
+	return verdict, nil
+}
+
+// describeCommit turns hash|author|date|subject into "abc1234 subject".
+func describeCommit(commit string) string {
+	parts := strings.SplitN(commit, "|", 4)
+	if len(parts) < 4 {
+		return commit
+	}
+	return shortHash(parts[0]) + " " + parts[3]
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+	return hash
+}
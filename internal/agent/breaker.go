@@ -0,0 +1,220 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+)
+
+const (
+	// breakerThreshold consecutive failures pause the batch.
+	breakerThreshold = 5
+	// breakerCooldown is the first pause; each further one doubles it.
+	breakerCooldown = 30 * time.Second
+	// breakerMaxPauses is how many pauses a batch sits through before it is
+	// aborted (30s, 1m, 2m, and 4m).
+	breakerMaxPauses = 4
+	// maxItemRetries is how often one file or topic is retried after a
+	// transient failure, on top of the batch-wide retry budget.
+	maxItemRetries = 2
+	retryDelay     = 5 * time.Second
+)
+
+// errProviderOutage fails the requests of a batch that was aborted because
+// Claude kept failing.
+var errProviderOutage = errors.New("batch aborted: Claude looks unavailable")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerProbing // one request checks whether Claude is back
+)
+
+// breaker is a circuit breaker shared by the requests of one batch. When
+// breakerThreshold requests in a row fail, the batch is paused; after the
+// pause a single request probes Claude, and the batch resumes if it succeeds
+// or pauses again, for longer, if it fails. Retries of transient failures are
+// limited by a budget for the whole batch, so an outage does not turn into
+// hundreds of retries.
+type breaker struct {
+	mu          sync.Mutex
+	state       breakerState
+	consecutive int
+	pauses      int
+	until       time.Time
+	retries     int
+	lastErr     error
+	aborted     bool
+	changed     chan struct{}
+	print       func(format string, args ...interface{})
+}
+
+func newBreaker(items int, print func(format string, args ...interface{})) *breaker {
+	budget := items / 4
+	if budget < 3 {
+		budget = 3
+	}
+	return &breaker{retries: budget, changed: make(chan struct{}), print: print}
+}
+
+// do runs query, waiting while the breaker is open, and retries it after
+// transient failures while the retry budget lasts.
+func (b *breaker) do(ctx context.Context, query func() ([]claudecode.Message, error)) ([]claudecode.Message, error) {
+	for attempt := 0; ; attempt++ {
+		if err := b.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		messages, err := query()
+		if err == nil || !countsAsOutage(err) {
+			b.succeeded()
+			return messages, err
+		}
+		b.failed(err)
+
+		if attempt >= maxItemRetries || !retryable(err) || !b.takeRetry() {
+			return messages, err
+		}
+		select {
+		case <-time.After(retryDelay * time.Duration(attempt+1)):
+		case <-ctx.Done():
+			return messages, err
+		}
+	}
+}
+
+// wait returns once a request may be sent: the breaker is closed, or it is
+// this request's turn to probe.
+func (b *breaker) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		if b.aborted {
+			b.mu.Unlock()
+			return errProviderOutage
+		}
+
+		var timer <-chan time.Time
+		switch b.state {
+		case breakerClosed:
+			b.mu.Unlock()
+			return nil
+		case breakerOpen:
+			if !time.Now().Before(b.until) {
+				b.state = breakerProbing
+				b.print("Pause over, checking whether Claude is back...\n")
+				b.mu.Unlock()
+				return nil
+			}
+			timer = time.After(time.Until(b.until))
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (b *breaker) succeeded() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consecutive = 0
+	if b.state == breakerProbing {
+		b.print("✓ Claude is responding again, resuming the batch\n")
+		b.state = breakerClosed
+		b.pauses = 0
+		b.notify()
+	}
+}
+
+func (b *breaker) failed(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consecutive++
+	b.lastErr = err
+	if b.state == breakerProbing || b.state == breakerClosed && b.consecutive >= breakerThreshold {
+		b.trip()
+	}
+}
+
+// trip pauses the batch, or aborts it once it has been paused
+// breakerMaxPauses times in a row.
+func (b *breaker) trip() {
+	defer b.notify()
+
+	if b.pauses >= breakerMaxPauses {
+		b.aborted = true
+		b.print("\n%s\n", strings.Repeat("=", 70))
+		b.print("INCIDENT: Claude is unavailable, batch aborted\n")
+		b.print("%s\n", strings.Repeat("=", 70))
+		b.print("Requests kept failing through %d pauses (%d failures in a row).\n", b.pauses, b.consecutive)
+		b.print("Last error: %v\n", b.lastErr)
+		b.print("Documents that were not finished are marked failed. Check the provider's\n")
+		b.print("status page, then retry them (update-docs: -resume <run id>).\n")
+		b.print("%s\n", strings.Repeat("=", 70))
+		return
+	}
+
+	cooldown := breakerCooldown << b.pauses
+	b.pauses++
+	b.state = breakerOpen
+	b.until = time.Now().Add(cooldown)
+	b.print("\nOH NO!!!!  %d requests in a row failed (%s). Pausing the batch for %s (pause %d/%d)...\n",
+		b.consecutive, errorClass(b.lastErr), cooldown, b.pauses, breakerMaxPauses)
+}
+
+func (b *breaker) takeRetry() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.retries == 0 || b.aborted {
+		return false
+	}
+	b.retries--
+	return true
+}
+
+// notify wakes the requests waiting in wait. The caller holds mu.
+func (b *breaker) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// countsAsOutage reports whether a failure says something about Claude's
+// availability. Denied tools and cancelled runs do not.
+func countsAsOutage(err error) bool {
+	switch errorClass(err) {
+	case "permission", "canceled", "outage":
+		return false
+	}
+	return true
+}
+
+// retryable reports whether the same request may succeed if sent again.
+func retryable(err error) bool {
+	switch errorClass(err) {
+	case "rate_limit", "timeout", "error":
+		return true
+	}
+	return false
+}
+
+// startBreaker guards the requests of a batch of items with a breaker until
+// the returned stop is called.
+func (a *Agent) startBreaker(items int) (stop func()) {
+	a.breaker = newBreaker(items, a.status)
+	a.logger.Infof("Circuit breaker armed: threshold %d, retry budget %d", breakerThreshold, a.breaker.retries)
+	return func() { a.breaker = nil }
+}
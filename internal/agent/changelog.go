@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+)
+
+type Changelog struct {
+	Entry        string
+	FullResponse string
+}
+
+func (a *Agent) WriteChangelog(ctx context.Context, fromRef, toRef string, commits []CommitMessage) (*Changelog, error) {
+	a.logger.Infof("Writing changelog for %d commits (%s..%s)", len(commits), fromRef, toRef)
+
+	var commitList strings.Builder
+	for _, commit := range commits {
+		commitList.WriteString(fmt.Sprintf("<commit hash=\"%s\">\n%s\n</commit>\n\n", commit.Hash, commit.Message))
+	}
+
+	prompt := fmt.Sprintf(`%s
+
+Release range: %s..%s
+
+Here are the commits in the release, oldest first:
+
+<commits>
+%s</commits>`, a.systemPrompt, fromRef, toRef, commitList.String())
+
+	request := claudecode.QueryRequest{
+		Prompt: prompt,
+		Options: &claudecode.Options{
+			AllowedTools:   []string{"Read", "Grep", "LS"},
+			PermissionMode: stringPtr("acceptEdits"),
+			Cwd:            stringPtr(a.folder),
+			OutputFormat:   outputFormatPtr(claudecode.OutputFormatJSON),
+			Verbose:        boolPtr(false),
+			MaxTurns:       intPtr(15),
+		},
+	}
+
+	messages, err := a.query(ctx, ModeChangelog, request)
+	if err != nil {
+		a.logger.Errorf("Error writing changelog: %v", err)
+		return nil, fmt.Errorf("changelog error: %w", err)
+	}
+
+	fullResponse := resultText(messages)
+
+	entry := fullResponse
+	start := strings.Index(fullResponse, "<changelog>")
+	end := strings.Index(fullResponse, "</changelog>")
+	if start >= 0 && end > start {
+		entry = strings.TrimSpace(fullResponse[start+11 : end])
+	}
+
+	a.logger.Infof("Changelog entry written, length: %d characters", len(entry))
+
+	return &Changelog{
+		Entry:        entry,
+		FullResponse: fullResponse,
+	}, nil
+}
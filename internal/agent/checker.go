@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"path/filepath"
 	"strings"
 
 	claudecode "github.com/yukifoo/claude-code-sdk-go"
@@ -17,9 +16,7 @@ type TopicMatch struct {
 }
 
 func (a *Agent) CheckExistingDocs(ctx context.Context, topics []string) ([]TopicMatch, error) {
-	docsDir := filepath.Join(a.folder, "documentation")
-	
-	files, err := filepath.Glob(filepath.Join(docsDir, "*.md"))
+	files, err := a.listDocs()
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan documentation directory: %w", err)
 	}
@@ -38,7 +35,7 @@ func (a *Agent) CheckExistingDocs(ctx context.Context, topics []string) ([]Topic
 
 	var fileList strings.Builder
 	for _, file := range files {
-		fileList.WriteString(fmt.Sprintf("- %s\n", filepath.Base(file)))
+		fileList.WriteString(fmt.Sprintf("- %s\n", a.docName(file)))
 	}
 
 	var topicsList strings.Builder
@@ -48,7 +45,7 @@ func (a *Agent) CheckExistingDocs(ctx context.Context, topics []string) ([]Topic
 
 	prompt := fmt.Sprintf(`You are analyzing a documentation directory to match requested topics with existing documentation files.
 
-Existing documentation files in %s/documentation/:
+Existing documentation files (paths relative to %s):
 %s
 
 Topics the user wants to document:
@@ -64,12 +61,12 @@ Respond with ONLY a JSON array in this exact format, no other text:
 
 Rules:
 - Use the exact topic names from the list above
-- For existing_file, use only the filename (not full path)
+- For existing_file, use the path exactly as listed above
 - Set is_match to true only if you're confident the file covers that topic
 - If no match exists, set existing_file to empty string and is_match to false
 - Return ONLY the JSON array, no explanations`, a.folder, fileList.String(), topicsList.String())
 
-	a.logger.Printf("Checking existing documentation for %d topics", len(topics))
+	a.logger.Infof("Checking existing documentation for %d topics", len(topics))
 
 	request := claudecode.QueryRequest{
 		Prompt: prompt,
@@ -83,7 +80,7 @@ Rules:
 		},
 	}
 
-	messages, err := claudecode.QueryWithRequest(ctx, request)
+	messages, err := a.query(ctx, ModeCheckExisting, request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check existing docs: %w", err)
 	}
@@ -115,11 +112,11 @@ Rules:
 	}
 
 	if jsonResponse == "" {
-		a.logger.Printf("ERROR: Could not extract JSON from Claude response")
+		a.logger.Errorf("Could not extract JSON from Claude response")
 		return nil, fmt.Errorf("Claude did not return expected JSON response")
 	}
 
-	a.logger.Printf("Found JSON response, length: %d", len(jsonResponse))
+	a.logger.Debugf("Found JSON response, length: %d", len(jsonResponse))
 
 	type jsonMatch struct {
 		Topic        string `json:"topic"`
@@ -130,8 +127,8 @@ Rules:
 	var jsonMatches []jsonMatch
 	err = json.Unmarshal([]byte(jsonResponse), &jsonMatches)
 	if err != nil {
-		a.logger.Printf("JSON parse error: %v", err)
-		a.logger.Printf("JSON content: %s", jsonResponse)
+		a.logger.Debugf("JSON parse error: %v", err)
+		a.logger.Debugf("JSON content: %s", jsonResponse)
 		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
 	}
 
@@ -144,7 +141,7 @@ Rules:
 		}
 	}
 
-	a.logger.Printf("Successfully parsed %d topic matches", len(matches))
+	a.logger.Infof("Successfully parsed %d topic matches", len(matches))
 	return matches, nil
 }
 
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+)
+
+type ReviewChecklist struct {
+	Checklist    string
+	FullResponse string
+}
+
+func (a *Agent) GenerateReviewChecklist(ctx context.Context, diff string) (*ReviewChecklist, error) {
+	a.logger.Infof("Generating review checklist")
+	a.logger.Debugf("Diff length: %d characters", len(diff))
+
+	prompt := fmt.Sprintf(`%s
+
+Here is the diff of the change that will be reviewed:
+
+<diff>
+%s
+</diff>`, a.systemPrompt, diff)
+
+	request := claudecode.QueryRequest{
+		Prompt: prompt,
+		Options: &claudecode.Options{
+			AllowedTools:   []string{"Read", "Grep", "LS"},
+			PermissionMode: stringPtr("acceptEdits"),
+			Cwd:            stringPtr(a.folder),
+			OutputFormat:   outputFormatPtr(claudecode.OutputFormatJSON),
+			Verbose:        boolPtr(false),
+			MaxTurns:       intPtr(15),
+		},
+	}
+
+	messages, err := a.query(ctx, ModeReviewChecklist, request)
+	if err != nil {
+		a.logger.Errorf("Error generating review checklist: %v", err)
+		return nil, fmt.Errorf("checklist error: %w", err)
+	}
+
+	fullResponse := resultText(messages)
+
+	checklist := fullResponse
+	start := strings.Index(fullResponse, "<checklist>")
+	end := strings.Index(fullResponse, "</checklist>")
+	if start >= 0 && end > start {
+		checklist = strings.TrimSpace(fullResponse[start+11 : end])
+	}
+
+	a.logger.Infof("Review checklist generated, length: %d characters", len(checklist))
+
+	return &ReviewChecklist{
+		Checklist:    checklist,
+		FullResponse: fullResponse,
+	}, nil
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+)
+
+type CommitMessage struct {
+	Hash    string
+	Message string
+}
+
+type CommitMessageReview struct {
+	Hash             string   `json:"hash"`
+	Compliant        bool     `json:"compliant"`
+	Issues           []string `json:"issues"`
+	SuggestedMessage string   `json:"suggested_message"`
+}
+
+func (a *Agent) ReviewCommitMessages(ctx context.Context, commits []CommitMessage, conventions string) ([]CommitMessageReview, error) {
+	a.logger.Infof("Reviewing %d commit messages against conventions", len(commits))
+
+	var commitList strings.Builder
+	for _, commit := range commits {
+		commitList.WriteString(fmt.Sprintf("<commit hash=\"%s\">\n%s\n</commit>\n\n", commit.Hash, commit.Message))
+	}
+
+	prompt := fmt.Sprintf(`%s
+
+Here are the commit message conventions:
+
+<conventions>
+%s
+</conventions>
+
+Here are the commits to review, oldest first:
+
+<commits>
+%s</commits>`, a.systemPrompt, conventions, commitList.String())
+
+	request := claudecode.QueryRequest{
+		Prompt: prompt,
+		Options: &claudecode.Options{
+			AllowedTools:   []string{"Read", "Grep", "LS"},
+			PermissionMode: stringPtr("acceptEdits"),
+			Cwd:            stringPtr(a.folder),
+			OutputFormat:   outputFormatPtr(claudecode.OutputFormatJSON),
+			Verbose:        boolPtr(false),
+			MaxTurns:       intPtr(10),
+		},
+	}
+
+	messages, err := a.query(ctx, ModeCheckCommits, request)
+	if err != nil {
+		a.logger.Errorf("Error reviewing commit messages: %v", err)
+		return nil, fmt.Errorf("commit review error: %w", err)
+	}
+
+	text := resultText(messages)
+	startIdx := strings.Index(text, "[")
+	endIdx := strings.LastIndex(text, "]")
+	if startIdx < 0 || endIdx <= startIdx {
+		a.logger.Errorf("Could not extract JSON from commit review")
+		return nil, fmt.Errorf("Claude did not return expected JSON response")
+	}
+
+	var reviews []CommitMessageReview
+	if err := json.Unmarshal([]byte(text[startIdx:endIdx+1]), &reviews); err != nil {
+		a.logger.Debugf("JSON parse error: %v", err)
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+
+	a.logger.Infof("Commit message review completed: %d results", len(reviews))
+	return reviews, nil
+}
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+)
+
+// JudgeVariants has Claude compare two updates of the doc named name, as
+// variants A and B left it, with the original and the codebase. It returns
+// Claude's judgement, starting with its verdict.
+func (a *Agent) JudgeVariants(ctx context.Context, name, original, variantA, variantB string) (string, error) {
+	a.logger.Infof("Judging the variants of %s", name)
+
+	prompt := fmt.Sprintf(`%s
+
+The document is %s. The original:
+<original>
+%s
+</original>
+
+As variant A left it:
+<variant_a>
+%s
+</variant_a>
+
+As variant B left it:
+<variant_b>
+%s
+</variant_b>
+
+The codebase the document describes is located at: %s`, a.systemPrompt, name, original, variantA, variantB, a.folder)
+
+	request := claudecode.QueryRequest{
+		Prompt: prompt,
+		Options: &claudecode.Options{
+			AllowedTools:   []string{"Read", "Grep", "Glob", "LS"},
+			PermissionMode: stringPtr("acceptEdits"),
+			Cwd:            stringPtr(a.folder),
+			OutputFormat:   outputFormatPtr(claudecode.OutputFormatJSON),
+			Verbose:        boolPtr(false),
+			MaxTurns:       intPtr(20),
+		},
+	}
+
+	messages, err := a.query(ctx, ModeCompareJudge, request)
+	if err != nil {
+		a.logger.Errorf("Error judging the variants of %s: %v", name, err)
+		return "", fmt.Errorf("judge error: %w", err)
+	}
+	return resultText(messages), nil
+}
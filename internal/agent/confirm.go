@@ -0,0 +1,46 @@
+package agent
+
+import (
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+
+	"github.com/udemy/docu-jarvis-cli/internal/approval"
+)
+
+// SetConfirmEdits makes the agent ask the user before each file edit instead
+// of accepting edits unattended. Edits outside the docs directories (or the
+// edit roots) are denied without asking.
+func (a *Agent) SetConfirmEdits(confirm bool) {
+	a.confirmEdits = confirm
+}
+
+// requireApproval moves the request's edit tools from the allowed list to the
+// approval server, when edits need confirming.
+func (a *Agent) requireApproval(request *claudecode.QueryRequest) error {
+	if !a.confirmEdits || a.dryRun || request.Options == nil {
+		return nil
+	}
+
+	var allowed []string
+	editing := false
+	for _, tool := range request.Options.AllowedTools {
+		if tool == "Write" || tool == "Edit" || tool == "MultiEdit" {
+			editing = true
+			continue
+		}
+		allowed = append(allowed, tool)
+	}
+	if !editing {
+		return nil
+	}
+
+	mcpConfig, err := approval.MCPConfig(a.editDirs())
+	if err != nil {
+		return err
+	}
+
+	request.Options.AllowedTools = allowed
+	request.Options.PermissionMode = stringPtr("default")
+	request.Options.MCPConfig = &mcpConfig
+	request.Options.PermissionPromptTool = stringPtr(approval.PromptTool)
+	return nil
+}
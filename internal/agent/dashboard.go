@@ -0,0 +1,395 @@
+package agent
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/udemy/docu-jarvis-cli/internal/ci"
+)
+
+// dashboardRefresh is how often the dashboard is redrawn.
+const dashboardRefresh = 250 * time.Millisecond
+
+// progress reports the items of a batch as they start and finish.
+type progress interface {
+	started(name string)
+	// activity reports what Claude is doing for a running item.
+	activity(name, tool, line string)
+	// tokens reports the tokens of one request of a running item.
+	tokens(name string, input, output int)
+	finished(result ProcessResult)
+	// stop ends the report before the batch summary is printed.
+	stop()
+}
+
+// plainProgress prints a line per event, for logs and pipes.
+type plainProgress struct{}
+
+func (plainProgress) started(name string) {
+	fmt.Printf("  → Started: %s\n", name)
+}
+
+func (plainProgress) activity(name, tool, line string) {
+	fmt.Printf("    %s: %s\n", name, line)
+}
+
+// tokens is left to the run summary.
+func (plainProgress) tokens(name string, input, output int) {}
+
+func (plainProgress) finished(result ProcessResult) {
+	if result.Success {
+		fmt.Println(completedLine(result))
+	} else {
+		fmt.Printf("  ✗ Failed: %s - %v\n", result.FileName, result.Error)
+	}
+}
+
+func (plainProgress) stop() {}
+
+// completedLine reports a finished item with the summary of its changes.
+func completedLine(result ProcessResult) string {
+	if result.Summary == "" {
+		return "  ✓ Completed: " + result.FileName
+	}
+	return fmt.Sprintf("  ✓ Completed: %s - %s", result.FileName, result.Summary)
+}
+
+// startProgress returns the event handler's report when there is one, the
+// dashboard when it is enabled and stdout is a terminal, and plain output
+// otherwise. Edits that need confirming keep plain
+// output, since their prompts need the terminal.
+func (a *Agent) startProgress(title string, names []string) progress {
+	if a.events != nil {
+		a.emit(Event{Kind: EventBatchStarted, Title: title, Names: names})
+		return eventProgress{a}
+	}
+	if !a.batchOpts.Dashboard || a.confirmEdits || ci.Enabled() || !isTerminal(os.Stdout) {
+		return plainProgress{}
+	}
+	d, err := startDashboard(title, names)
+	if err != nil {
+		a.logger.Warnf("Falling back to plain output: %v", err)
+		return plainProgress{}
+	}
+	return d
+}
+
+type itemState int
+
+const (
+	itemQueued itemState = iota
+	itemRunning
+	itemDone
+	itemFailed
+)
+
+type dashboardItem struct {
+	name     string
+	state    itemState
+	start    time.Time
+	duration time.Duration
+	input    int
+	output   int
+	err      string
+	activity string // the last thing Claude did, while running
+}
+
+// dashboard is a full-screen view of a batch: the status of each item, and
+// the last lines the batch printed. Everything printed while it runs is
+// printed again when it stops, so nothing is lost.
+type dashboard struct {
+	mu      sync.Mutex
+	title   string
+	begun   time.Time
+	items   []dashboardItem
+	byName  map[string]int
+	log     []string
+	width   int
+	height  int
+	term    *os.File
+	pipe    *os.File
+	read    chan struct{} // closed when the captured output is read
+	quit    chan struct{}
+	drawn   chan struct{} // closed when the last frame is drawn
+	stopped sync.Once
+}
+
+func startDashboard(title string, names []string) (*dashboard, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture output: %w", err)
+	}
+
+	d := &dashboard{
+		title:  title,
+		begun:  time.Now(),
+		byName: make(map[string]int, len(names)),
+		term:   os.Stdout,
+		pipe:   w,
+		read:   make(chan struct{}),
+		quit:   make(chan struct{}),
+		drawn:  make(chan struct{}),
+	}
+	d.width, d.height = terminalSize()
+	for i, name := range names {
+		d.items = append(d.items, dashboardItem{name: name})
+		d.byName[name] = i
+	}
+
+	// Enter the alternate screen, so the shell's scrollback is left as it was
+	fmt.Fprint(d.term, "\x1b[?1049h\x1b[?25l")
+	os.Stdout = w
+
+	go d.capture(r)
+	go d.refresh()
+	return d, nil
+}
+
+func (d *dashboard) capture(r io.ReadCloser) {
+	defer close(d.read)
+	defer r.Close()
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			d.addLog(strings.TrimSuffix(line, "\n"))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (d *dashboard) refresh() {
+	defer close(d.drawn)
+	ticker := time.NewTicker(dashboardRefresh)
+	defer ticker.Stop()
+	for {
+		d.draw()
+		select {
+		case <-ticker.C:
+		case <-d.quit:
+			return
+		}
+	}
+}
+
+func (d *dashboard) addLog(line string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.log = append(d.log, line)
+}
+
+func (d *dashboard) started(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.log = append(d.log, "  → Started: "+name)
+	if i, ok := d.byName[name]; ok {
+		d.items[i].state = itemRunning
+		d.items[i].start = time.Now()
+	}
+}
+
+func (d *dashboard) activity(name, tool, line string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if i, ok := d.byName[name]; ok {
+		d.items[i].activity = line
+	}
+}
+
+// tokens is shown once the item is done.
+func (d *dashboard) tokens(name string, input, output int) {}
+
+func (d *dashboard) finished(result ProcessResult) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	i, ok := d.byName[result.FileName]
+	if !ok {
+		return
+	}
+	item := &d.items[i]
+	item.duration = result.Duration
+	item.input, item.output = result.InputTokens, result.OutputTokens
+	if result.Success {
+		item.state = itemDone
+		d.log = append(d.log, completedLine(result))
+	} else {
+		item.state = itemFailed
+		item.err = fmt.Sprint(result.Error)
+		d.log = append(d.log, fmt.Sprintf("  ✗ Failed: %s - %v", result.FileName, result.Error))
+	}
+}
+
+// stop restores the screen and stdout, and prints what the batch printed.
+func (d *dashboard) stop() {
+	d.stopped.Do(func() {
+		os.Stdout = d.term
+		d.pipe.Close()
+		<-d.read
+		close(d.quit)
+		<-d.drawn
+
+		fmt.Fprint(d.term, "\x1b[?25h\x1b[?1049l")
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		for _, line := range d.log {
+			fmt.Fprintln(d.term, line)
+		}
+	})
+}
+
+func (d *dashboard) draw() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	done, failed, running, input, output := 0, 0, 0, 0, 0
+	for _, item := range d.items {
+		switch item.state {
+		case itemDone:
+			done++
+		case itemFailed:
+			failed++
+		case itemRunning:
+			running++
+		}
+		input += item.input
+		output += item.output
+	}
+
+	var lines []string
+	lines = append(lines,
+		d.title,
+		fmt.Sprintf("%d/%d finished, %d running, %d failed   elapsed %s   tokens %s in / %s out",
+			done+failed, len(d.items), running, failed, formatElapsed(time.Since(d.begun)), formatTokens(input), formatTokens(output)),
+		strings.Repeat("─", d.width))
+
+	logRows := d.height / 3
+	if logRows < 3 {
+		logRows = 3
+	}
+	itemRows := d.height - len(lines) - logRows - 2
+	if itemRows < 1 {
+		itemRows = 1
+	}
+	lines = append(lines, d.itemLines(itemRows)...)
+
+	lines = append(lines, strings.Repeat("─", d.width), "Log")
+	start := len(d.log) - logRows
+	if start < 0 {
+		start = 0
+	}
+	lines = append(lines, d.log[start:]...)
+
+	if len(lines) > d.height {
+		lines = lines[:d.height]
+	}
+	var frame strings.Builder
+	frame.WriteString("\x1b[H")
+	for i, line := range lines {
+		frame.WriteString(truncate(line, d.width))
+		frame.WriteString("\x1b[K")
+		if i < len(lines)-1 {
+			frame.WriteString("\r\n")
+		}
+	}
+	frame.WriteString("\x1b[J")
+	fmt.Fprint(d.term, frame.String())
+}
+
+// itemLines lists the items in order, or, when they do not fit, the running
+// ones first, then the failed, queued, and finished ones.
+func (d *dashboard) itemLines(rows int) []string {
+	order := make([]int, 0, len(d.items))
+	if len(d.items) <= rows {
+		for i := range d.items {
+			order = append(order, i)
+		}
+	} else {
+		for _, state := range []itemState{itemRunning, itemFailed, itemQueued, itemDone} {
+			for i, item := range d.items {
+				if item.state == state {
+					order = append(order, i)
+				}
+			}
+		}
+	}
+
+	var lines []string
+	for n, i := range order {
+		if n == rows-1 && len(order) > rows {
+			lines = append(lines, fmt.Sprintf("  ... and %d more", len(order)-n))
+			break
+		}
+		item := d.items[i]
+		switch item.state {
+		case itemQueued:
+			lines = append(lines, fmt.Sprintf("  · %-40s queued", item.name))
+		case itemRunning:
+			lines = append(lines, strings.TrimSpace(fmt.Sprintf("  → %-40s %s   %s", item.name, formatElapsed(time.Since(item.start)), item.activity)))
+		case itemDone:
+			lines = append(lines, fmt.Sprintf("  ✓ %-40s %s   %s in / %s out",
+				item.name, formatElapsed(item.duration), formatTokens(item.input), formatTokens(item.output)))
+		case itemFailed:
+			lines = append(lines, fmt.Sprintf("  ✗ %-40s %s   %s", item.name, formatElapsed(item.duration), item.err))
+		}
+	}
+	return lines
+}
+
+func formatElapsed(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// truncate shortens a line to the terminal width, counting runes.
+func truncate(line string, width int) string {
+	runes := []rune(line)
+	if len(runes) <= width {
+		return line
+	}
+	return string(runes[:width])
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalSize returns the size of the terminal, from stty where it is
+// available, then $COLUMNS and $LINES, then 80x24.
+func terminalSize() (width, height int) {
+	width, height = 80, 24
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		width = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("LINES")); err == nil && n > 0 {
+		height = n
+	}
+
+	cmd := exec.Command("stty", "size")
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	if err != nil {
+		return width, height
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 2 {
+		rows, rerr := strconv.Atoi(fields[0])
+		cols, cerr := strconv.Atoi(fields[1])
+		if rerr == nil && cerr == nil && rows > 0 && cols > 0 {
+			return cols, rows
+		}
+	}
+	return width, height
+}
@@ -2,7 +2,9 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	claudecode "github.com/yukifoo/claude-code-sdk-go"
@@ -15,7 +17,8 @@ type CommitAnalysis struct {
 	Date        string
 	Explanation string
 	IsLikely    bool
-	Confidence  int // 0-100
+	Confidence  int              // 0-100
+	Symptoms    []SymptomVerdict // one per symptom, when several were analyzed
 }
 
 type CommitAnalysisResult struct {
@@ -26,7 +29,7 @@ type CommitAnalysisResult struct {
 
 func (a *Agent) AnalyzeSingleCommit(ctx context.Context, commit, bugDescription string) (*CommitAnalysis, error) {
 	// Parse commit info: hash|author|date|subject
-	parts := strings.Split(commit, "|")
+	parts := strings.SplitN(commit, "|", 4)
 	if len(parts) < 4 {
 		return nil, fmt.Errorf("invalid commit format")
 	}
@@ -36,7 +39,7 @@ func (a *Agent) AnalyzeSingleCommit(ctx context.Context, commit, bugDescription
 	commitDate := parts[2]
 	commitMsg := parts[3]
 
-	a.logger.Printf("Analyzing commit %s for bug", commitHash[:8])
+	a.logger.Infof("Analyzing commit %s for bug", commitHash[:8])
 
 	prompt := fmt.Sprintf(`%s
 
@@ -50,8 +53,13 @@ Commit to analyze:
 
 Bug description:
 %s`, a.systemPrompt, a.folder, commitHash, commitAuthor, commitDate, commitMsg, bugDescription)
+	symptoms := len(a.suspectOpts.Symptoms)
+	if symptoms > 1 {
+		prompt += symptomsInstructions
+	}
+	prompt += a.pastCasesPrompt()
 
-	a.logger.Printf("Debug analysis prompt length: %d characters", len(prompt))
+	a.logger.Debugf("Debug analysis prompt length: %d characters", len(prompt))
 
 	request := claudecode.QueryRequest{
 		Prompt: prompt,
@@ -65,210 +73,301 @@ Bug description:
 		},
 	}
 
-	messages, err := claudecode.QueryWithRequest(ctx, request)
+	messages, err := a.query(ctx, ModeDebug, request)
 	if err != nil {
-		a.logger.Printf("Error analyzing commits: %v", err)
+		a.logger.Errorf("Error analyzing commits: %v", err)
 		return nil, fmt.Errorf("analysis error: %w", err)
 	}
 
-	var jsonResponse string
+	var analysis *CommitAnalysis
+	var parseErr error
 	for _, message := range messages {
 		for _, block := range message.Content() {
 			if textBlock, ok := block.(*claudecode.TextBlock); ok {
-				text := strings.TrimSpace(textBlock.Text)
-
-				// Handle markdown code blocks
-				if strings.Contains(text, "```json") {
-					start := strings.Index(text, "```json")
-					end := strings.Index(text[start+7:], "```")
-					if start >= 0 && end > 0 {
-						jsonResponse = strings.TrimSpace(text[start+7 : start+7+end])
-						break
-					}
-				}
-
-				// Handle plain JSON objects
-				if strings.HasPrefix(text, "{") && strings.HasSuffix(text, "}") {
-					jsonResponse = text
+				analysis, parseErr = parseCommitAnalysis(textBlock.Text, symptoms)
+				if parseErr == nil {
 					break
 				}
-
-				// Try to extract JSON from anywhere in text
-				startIdx := strings.Index(text, "{")
-				endIdx := strings.LastIndex(text, "}")
-				if startIdx >= 0 && endIdx > startIdx {
-					potentialJSON := strings.TrimSpace(text[startIdx : endIdx+1])
-					if strings.HasPrefix(potentialJSON, "{") && strings.HasSuffix(potentialJSON, "}") {
-						jsonResponse = potentialJSON
-						break
-					}
-				}
 			}
 		}
-		if jsonResponse != "" {
+		if analysis != nil {
 			break
 		}
 	}
 
-	if jsonResponse == "" {
-		a.logger.Printf("ERROR: Could not extract JSON from debug analysis")
-		return nil, fmt.Errorf("Claude did not return expected JSON response")
-	}
-
-	a.logger.Printf("Found JSON response, length: %d", len(jsonResponse))
-
-	analysis := &CommitAnalysis{}
-
-	jsonResponse = strings.TrimSpace(jsonResponse)
-	jsonResponse = strings.Trim(jsonResponse, "{}")
-
-	pairs := splitJSONPairs(jsonResponse)
-	for _, pair := range pairs {
-		parts := strings.SplitN(pair, ":", 2)
-		if len(parts) != 2 {
-			continue
+	if analysis == nil {
+		if parseErr == nil {
+			parseErr = fmt.Errorf("no text in response")
 		}
+		a.logger.Errorf("Could not extract JSON from debug analysis: %v", parseErr)
+		return nil, fmt.Errorf("Claude did not return expected JSON response: %w", parseErr)
+	}
 
-		key := strings.Trim(strings.TrimSpace(parts[0]), "\"")
-		value := strings.TrimSpace(parts[1])
-
-		value = strings.TrimSuffix(value, ",")
-		value = strings.Trim(value, "\"")
-
-		switch key {
-		case "commit_hash":
-			analysis.CommitHash = value
-		case "commit_message":
-			analysis.CommitMsg = value
-		case "author":
-			analysis.Author = value
-		case "date":
-			analysis.Date = value
-		case "explanation":
-			analysis.Explanation = value
-		case "is_likely":
-			analysis.IsLikely = value == "true"
-		case "confidence":
-			fmt.Sscanf(value, "%d", &analysis.Confidence)
-		}
+	// Fall back to the git metadata for anything the model left out
+	if analysis.CommitHash == "" {
+		analysis.CommitHash = commitHash
+	}
+	if analysis.CommitMsg == "" {
+		analysis.CommitMsg = commitMsg
+	}
+	if analysis.Author == "" {
+		analysis.Author = commitAuthor
+	}
+	if analysis.Date == "" {
+		analysis.Date = commitDate
 	}
 
-	a.logger.Printf("Parsed commit analysis: hash=%s, likely=%v, confidence=%d", analysis.CommitHash, analysis.IsLikely, analysis.Confidence)
+	a.logger.Infof("Parsed commit analysis: hash=%s, likely=%v, confidence=%d", analysis.CommitHash, analysis.IsLikely, analysis.Confidence)
 
 	return analysis, nil
 }
 
-func (a *Agent) AnalyzeBugInCommits(ctx context.Context, commits []string, bugDescription string) (*CommitAnalysis, error) {
-	a.logger.Printf("Analyzing %d commits concurrently for bug: %s", len(commits), bugDescription)
-	
+// SuspectOptions controls how AnalyzeBugSuspects schedules the commits.
+type SuspectOptions struct {
+	Concurrency int // commits analyzed at once, 0 for all of them
+	// StopAt ends the analysis once a commit is the likely culprit with at
+	// least this confidence, canceling the outstanding requests; 0 analyzes
+	// every commit.
+	StopAt int
+	// Symptoms, when there are several, are judged one by one in the same
+	// request for each commit, besides the overall verdict. The bug
+	// description describes them all (see DescribeSymptoms).
+	Symptoms []string
+	// Progress is called with each commit's result as it completes, instead
+	// of printing the count.
+	Progress func(completed, total int, result CommitAnalysisResult)
+}
+
+func (a *Agent) SetSuspectOptions(opts SuspectOptions) {
+	a.suspectOpts = opts
+}
+
+// AnalyzeBugSuspects analyzes the commits concurrently and returns the
+// analyses, likely culprits first, then by confidence. With StopAt set, it
+// returns as soon as one is confident enough, with the analyses done so far.
+func (a *Agent) AnalyzeBugSuspects(ctx context.Context, commits []string, bugDescription string) ([]*CommitAnalysis, error) {
 	totalCommits := len(commits)
+	limit := a.suspectOpts.Concurrency
+	if limit <= 0 || limit > totalCommits {
+		limit = totalCommits
+	}
+	a.logger.Infof("Analyzing %d commits, %d at a time, for bug: %s", totalCommits, limit, bugDescription)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// buffered for every commit, so workers never block once we stop reading
 	resultChan := make(chan CommitAnalysisResult, totalCommits)
-	
+	next := make(chan string, totalCommits)
 	for _, commit := range commits {
-		go func(c string) {
-			analysis, err := a.AnalyzeSingleCommit(ctx, c, bugDescription)
-			
-			result := CommitAnalysisResult{
-				Commit:   c,
-				Analysis: analysis,
-				Error:    err,
+		next <- commit
+	}
+	close(next)
+
+	for w := 0; w < limit; w++ {
+		go func() {
+			for c := range next {
+				if runCtx.Err() != nil {
+					resultChan <- CommitAnalysisResult{Commit: c, Error: runCtx.Err()}
+					continue
+				}
+				analysis, err := a.AnalyzeSingleCommit(runCtx, c, bugDescription)
+				resultChan <- CommitAnalysisResult{
+					Commit:   c,
+					Analysis: analysis,
+					Error:    err,
+				}
 			}
-			
-			resultChan <- result
-		}(commit)
+		}()
 	}
-	
+
 	var analyses []*CommitAnalysis
 	completed := 0
-	
+
 	for completed < totalCommits {
 		select {
 		case result := <-resultChan:
 			completed++
-			fmt.Printf("\r  Analyzed: %d/%d commits", completed, totalCommits)
-			
+			if progress := a.suspectOpts.Progress; progress != nil {
+				progress(completed, totalCommits, result)
+			} else {
+				fmt.Printf("\r  Analyzed: %d/%d commits", completed, totalCommits)
+			}
+
 			if result.Error != nil {
-				a.logger.Printf("Error analyzing commit: %v", result.Error)
+				a.logger.Errorf("Error analyzing commit: %v", result.Error)
 				continue
 			}
-			
-			if result.Analysis != nil {
-				analyses = append(analyses, result.Analysis)
+
+			if result.Analysis == nil {
+				continue
 			}
-			
+			analyses = append(analyses, result.Analysis)
+
+			if stopAt := a.suspectOpts.StopAt; stopAt > 0 && result.Analysis.IsLikely && result.Analysis.Confidence >= stopAt {
+				cancel()
+				if a.suspectOpts.Progress == nil {
+					fmt.Printf("\n  Stopping early: %s is the likely culprit with %d%% confidence, skipping the other %d commits\n",
+						shortHash(result.Analysis.CommitHash), result.Analysis.Confidence, totalCommits-completed)
+				}
+				a.logger.Infof("Stopped after %d/%d commits: %s at %d%% confidence", completed, totalCommits, result.Analysis.CommitHash, result.Analysis.Confidence)
+				RankSuspects(analyses)
+				return analyses, nil
+			}
+
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		}
 	}
-	
-	fmt.Println()
-	
+
+	if a.suspectOpts.Progress == nil {
+		fmt.Println()
+	}
+
 	if len(analyses) == 0 {
 		return nil, fmt.Errorf("no commits could be analyzed")
 	}
-	
-	var bestMatch *CommitAnalysis
-	for _, analysis := range analyses {
-		if analysis.IsLikely {
-			if bestMatch == nil || analysis.Confidence > bestMatch.Confidence {
-				bestMatch = analysis
-			}
+
+	RankSuspects(analyses)
+	return analyses, nil
+}
+
+// RankSuspects sorts analyses with the likely culprits first, then by
+// confidence.
+func RankSuspects(analyses []*CommitAnalysis) {
+	sort.SliceStable(analyses, func(i, j int) bool {
+		if analyses[i].IsLikely != analyses[j].IsLikely {
+			return analyses[i].IsLikely
 		}
+		return analyses[i].Confidence > analyses[j].Confidence
+	})
+}
+
+type commitAnalysisResponse struct {
+	CommitHash    string            `json:"commit_hash"`
+	CommitMessage string            `json:"commit_message"`
+	Author        string            `json:"author"`
+	Date          string            `json:"date"`
+	Explanation   string            `json:"explanation"`
+	IsLikely      *bool             `json:"is_likely"`
+	Confidence    json.Number       `json:"confidence"`
+	Symptoms      []symptomResponse `json:"symptoms"`
+}
+
+// parseCommitAnalysis decodes the model's JSON verdict, with one verdict per
+// symptom when there are several. The object may be the whole text, inside a
+// ```json fence, or embedded in surrounding prose.
+func parseCommitAnalysis(text string, symptoms int) (*CommitAnalysis, error) {
+	candidates := jsonObjectCandidates(text)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no JSON object found")
 	}
-	
-	if bestMatch == nil {
-		for _, analysis := range analyses {
-			if bestMatch == nil || analysis.Confidence > bestMatch.Confidence {
-				bestMatch = analysis
-			}
+
+	var lastErr error
+	for _, candidate := range candidates {
+		analysis, err := decodeCommitAnalysis(candidate, symptoms)
+		if err == nil {
+			return analysis, nil
 		}
+		lastErr = err
 	}
-	
-	a.logger.Printf("Best match found: commit=%s, confidence=%d", bestMatch.CommitHash, bestMatch.Confidence)
-	return bestMatch, nil
+
+	return nil, lastErr
 }
 
-func splitJSONPairs(jsonContent string) []string {
-	var pairs []string
-	var current strings.Builder
-	inQuotes := false
-	depth := 0
+func jsonObjectCandidates(text string) []string {
+	text = strings.TrimSpace(text)
+	var candidates []string
 
-	for i := 0; i < len(jsonContent); i++ {
-		char := jsonContent[i]
+	if strings.HasPrefix(text, "{") && strings.HasSuffix(text, "}") {
+		candidates = append(candidates, text)
+	}
 
-		switch char {
-		case '"':
-			if i == 0 || jsonContent[i-1] != '\\' {
-				inQuotes = !inQuotes
-			}
-			current.WriteByte(char)
-		case '{', '[':
-			if !inQuotes {
-				depth++
-			}
-			current.WriteByte(char)
-		case '}', ']':
-			if !inQuotes {
-				depth--
+	if start := strings.Index(text, "```json"); start >= 0 {
+		rest := text[start+7:]
+		if end := strings.Index(rest, "```"); end > 0 {
+			candidates = append(candidates, strings.TrimSpace(rest[:end]))
+		}
+	}
+
+	startIdx := strings.Index(text, "{")
+	endIdx := strings.LastIndex(text, "}")
+	if startIdx >= 0 && endIdx > startIdx {
+		candidates = append(candidates, text[startIdx:endIdx+1])
+	}
+
+	// With several objects in the text, the span above covers them all, so
+	// each balanced object is tried on its own too
+	candidates = append(candidates, balancedObjects(text)...)
+
+	return candidates
+}
+
+// balancedObjects returns the top-level {...} spans of text, skipping braces
+// inside JSON strings.
+func balancedObjects(text string) []string {
+	var objects []string
+	depth, start := 0, 0
+	inString, escaped := false, false
+	for i, c := range text {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"' && depth > 0:
+			inString = !inString
+		case inString:
+		case c == '{':
+			if depth == 0 {
+				start = i
 			}
-			current.WriteByte(char)
-		case ',':
-			if !inQuotes && depth == 0 {
-				pairs = append(pairs, strings.TrimSpace(current.String()))
-				current.Reset()
-			} else {
-				current.WriteByte(char)
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+			if depth == 0 {
+				objects = append(objects, text[start:i+1])
 			}
-		default:
-			current.WriteByte(char)
 		}
 	}
+	return objects
+}
 
-	if current.Len() > 0 {
-		pairs = append(pairs, strings.TrimSpace(current.String()))
+func decodeCommitAnalysis(data string, symptoms int) (*CommitAnalysis, error) {
+	var resp commitAnalysisResponse
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
 	}
 
-	return pairs
-}
+	if resp.IsLikely == nil {
+		return nil, fmt.Errorf("missing required field: is_likely")
+	}
+	if resp.Confidence == "" {
+		return nil, fmt.Errorf("missing required field: confidence")
+	}
+	if strings.TrimSpace(resp.Explanation) == "" {
+		return nil, fmt.Errorf("missing required field: explanation")
+	}
+
+	confidence, err := resp.Confidence.Float64()
+	if err != nil {
+		return nil, fmt.Errorf("invalid confidence %q: %w", resp.Confidence, err)
+	}
+	if confidence < 0 || confidence > 100 {
+		return nil, fmt.Errorf("confidence out of range (0-100): %v", confidence)
+	}
 
+	analysis := &CommitAnalysis{
+		CommitHash:  resp.CommitHash,
+		CommitMsg:   resp.CommitMessage,
+		Author:      resp.Author,
+		Date:        resp.Date,
+		Explanation: resp.Explanation,
+		IsLikely:    *resp.IsLikely,
+		Confidence:  int(confidence),
+	}
+	if symptoms > 1 {
+		analysis.Symptoms = symptomVerdicts(resp.Symptoms, symptoms)
+	}
+	return analysis, nil
+}
@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCommitAnalysis(t *testing.T) {
+	const verdict = `{"commit_hash": "abc123", "commit_message": "Cache sessions", "author": "dev", "date": "2024-11-02",
+"explanation": "The cache returns nil on a miss", "is_likely": true, "confidence": 85}`
+
+	tests := []struct {
+		name       string
+		text       string
+		wantErr    string
+		hash       string
+		likely     bool
+		confidence int
+	}{
+		{
+			name:       "bare JSON",
+			text:       verdict,
+			hash:       "abc123",
+			likely:     true,
+			confidence: 85,
+		},
+		{
+			name:       "fenced JSON",
+			text:       "Here is my verdict:\n```json\n" + verdict + "\n```\n",
+			hash:       "abc123",
+			likely:     true,
+			confidence: 85,
+		},
+		{
+			name:       "prose before and after",
+			text:       "After reading the diff I conclude: " + verdict + " Let me know if you need more.",
+			hash:       "abc123",
+			likely:     true,
+			confidence: 85,
+		},
+		{
+			name:       "multiple objects",
+			text:       `The config was {"retries": 3} before. Verdict: {"explanation": "Unrelated {braces} in text", "is_likely": false, "confidence": 10}`,
+			likely:     false,
+			confidence: 10,
+		},
+		{
+			name:       "confidence as a decimal",
+			text:       `{"explanation": "Maybe", "is_likely": false, "confidence": 42.5}`,
+			confidence: 42,
+		},
+		{
+			name:    "no JSON",
+			text:    "I could not determine the cause.",
+			wantErr: "no JSON object found",
+		},
+		{
+			name:    "malformed JSON",
+			text:    `{"explanation": "x", "is_likely": true, "confidence": }`,
+			wantErr: "invalid JSON",
+		},
+		{
+			name:    "truncated JSON",
+			text:    `{"explanation": "The cache returns nil", "is_likely": tr`,
+			wantErr: "no JSON object found",
+		},
+		{
+			name:    "missing is_likely",
+			text:    `{"explanation": "x", "confidence": 50}`,
+			wantErr: "missing required field: is_likely",
+		},
+		{
+			name:    "missing confidence",
+			text:    `{"explanation": "x", "is_likely": true}`,
+			wantErr: "missing required field: confidence",
+		},
+		{
+			name:    "missing explanation",
+			text:    `{"explanation": " ", "is_likely": true, "confidence": 50}`,
+			wantErr: "missing required field: explanation",
+		},
+		{
+			name:    "confidence out of range",
+			text:    `{"explanation": "x", "is_likely": true, "confidence": 150}`,
+			wantErr: "confidence out of range",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis, err := parseCommitAnalysis(tt.text, 1)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if analysis.CommitHash != tt.hash || analysis.IsLikely != tt.likely || analysis.Confidence != tt.confidence {
+				t.Errorf("got hash=%q likely=%v confidence=%d, want hash=%q likely=%v confidence=%d",
+					analysis.CommitHash, analysis.IsLikely, analysis.Confidence, tt.hash, tt.likely, tt.confidence)
+			}
+		})
+	}
+}
+
+func TestParseCommitAnalysisSymptoms(t *testing.T) {
+	text := `{"explanation": "x", "is_likely": true, "confidence": 80,
+"symptoms": [{"symptom": 1, "is_likely": true, "confidence": 90, "explanation": "a"}, {"symptom": 2, "is_likely": false, "confidence": 5, "explanation": "b"}]}`
+	analysis, err := parseCommitAnalysis(text, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(analysis.Symptoms) != 2 || !analysis.Symptoms[0].IsLikely || analysis.Symptoms[1].IsLikely {
+		t.Errorf("symptoms = %+v", analysis.Symptoms)
+	}
+}
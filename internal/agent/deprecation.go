@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// archiveInstructions have Claude flag docs whose feature is gone from the
+// code, as proposals for archive-docs, instead of rewriting them.
+const archiveInstructions = `
+
+If the feature this document describes has been removed from the codebase entirely (not renamed or moved), do not rewrite the document to describe its absence. Leave it unchanged, and end your final response with one line:
+ARCHIVE: <what was removed, and the commit or code that replaced it if you found one>`
+
+var archivePattern = regexp.MustCompile(`(?m)^\s*\**ARCHIVE:\**\s*(.+?)\s*$`)
+
+// ArchiveProposal is a doc Claude found describing a removed feature.
+type ArchiveProposal struct {
+	File   string `json:"file"`
+	Reason string `json:"reason"`
+}
+
+// proposeArchive records the archive proposal in Claude's response, if any.
+func (a *Agent) proposeArchive(fileName, response string) {
+	m := archivePattern.FindStringSubmatch(response)
+	if m == nil {
+		return
+	}
+	a.logger.Infof("[%s] Proposed for archiving: %s", fileName, m[1])
+	a.outputMu.Lock()
+	defer a.outputMu.Unlock()
+	a.proposals = append(a.proposals, ArchiveProposal{File: fileName, Reason: strings.TrimSpace(m[1])})
+}
+
+// ArchiveProposals returns the docs proposed for archiving so far, by file.
+func (a *Agent) ArchiveProposals() []ArchiveProposal {
+	a.outputMu.Lock()
+	defer a.outputMu.Unlock()
+	proposals := append([]ArchiveProposal(nil), a.proposals...)
+	sort.Slice(proposals, func(i, j int) bool { return proposals[i].File < proposals[j].File })
+	return proposals
+}
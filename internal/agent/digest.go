@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"regexp"
+	"strings"
+)
+
+// digestInstructions have Claude sum up its edits of a doc, for a comment on
+// the doc in the PR, so reviewers know what to look for in long diffs.
+const digestInstructions = `
+
+End your final response with a digest of your edits for the reviewers of the pull request: a line starting with SUMMARY: and one sentence saying what you changed, then a line with only CHANGES:, then up to five bullet points saying what you changed in the document and why (for example, which code it no longer matched). Write "SUMMARY: No changes needed" and "CHANGES: none" if you left the document unchanged.`
+
+var digestPattern = regexp.MustCompile(`(?ms)^\s*\**CHANGES:\**[ \t]*(.*?)\s*(?:^\s*\**ARCHIVE:|\z)`)
+
+var summaryPattern = regexp.MustCompile(`(?m)^\s*\**SUMMARY:\**[ \t]*(.+?)\s*$`)
+
+// maxSummaryLength caps a change summary, which is printed on one line.
+const maxSummaryLength = 160
+
+// changeSummary returns the one-sentence summary of Claude's edits in its
+// response, or the first point of its digest when it wrote no summary.
+func changeSummary(response string) string {
+	summary := ""
+	if m := summaryPattern.FindStringSubmatch(response); m != nil {
+		summary = m[1]
+	} else if m := digestPattern.FindStringSubmatch(response); m != nil {
+		first, _, _ := strings.Cut(strings.TrimSpace(m[1]), "\n")
+		summary = strings.TrimSpace(strings.TrimLeft(first, "-*• "))
+		if strings.EqualFold(strings.TrimSuffix(summary, "."), "none") {
+			summary = "No changes needed"
+		}
+	}
+	summary = strings.Trim(summary, "* ")
+	if runes := []rune(summary); len(runes) > maxSummaryLength {
+		summary = string(runes[:maxSummaryLength-3]) + "..."
+	}
+	return summary
+}
+
+// recordDigest records the digest of Claude's edits in its response, if any.
+func (a *Agent) recordDigest(fileName, response string) {
+	m := digestPattern.FindStringSubmatch(response)
+	if m == nil {
+		return
+	}
+	digest := strings.TrimSpace(m[1])
+	if digest == "" || strings.EqualFold(strings.TrimSuffix(digest, "."), "none") {
+		return
+	}
+	a.outputMu.Lock()
+	defer a.outputMu.Unlock()
+	if a.digests == nil {
+		a.digests = make(map[string]string)
+	}
+	a.digests[fileName] = digest
+}
+
+// ChangeDigests returns what Claude changed in each doc and why, by file.
+func (a *Agent) ChangeDigests() map[string]string {
+	a.outputMu.Lock()
+	defer a.outputMu.Unlock()
+	digests := make(map[string]string, len(a.digests))
+	for file, digest := range a.digests {
+		digests[file] = digest
+	}
+	return digests
+}
@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EventKind is the kind of an Event.
+type EventKind string
+
+const (
+	// EventBatchStarted starts a batch of documents or topics.
+	EventBatchStarted EventKind = "batch_started"
+	// EventStarted starts one item of a batch.
+	EventStarted EventKind = "started"
+	// EventToolUse is a tool call of Claude while an item runs.
+	EventToolUse EventKind = "tool_use"
+	// EventTokens reports the tokens of one request of an item.
+	EventTokens EventKind = "tokens"
+	// EventFinished ends an item, with its result.
+	EventFinished EventKind = "finished"
+	// EventBatchFinished ends a batch, with the summary of its items.
+	EventBatchFinished EventKind = "batch_finished"
+	// EventProposal is the changes a dry run proposes for an item.
+	EventProposal EventKind = "proposal"
+	// EventNotice is a line of output, such as the order of a batch or a
+	// pause while Claude is unavailable.
+	EventNotice EventKind = "notice"
+)
+
+// Event is a step of the agent's work, for SetEventHandler. Only the fields
+// of its kind are set.
+type Event struct {
+	Kind EventKind
+	Time time.Time
+	// Name is the document or topic of the item events
+	Name string
+	// Title and Names describe the batch of EventBatchStarted
+	Title string
+	Names []string
+	// Tool and Activity describe the call of EventToolUse, e.g. "Read" and
+	// "Reading internal/billing/invoice.go"
+	Tool     string
+	Activity string
+	// InputTokens and OutputTokens are the tokens of EventTokens
+	InputTokens  int
+	OutputTokens int
+	Result       *ProcessResult // EventFinished
+	Items        []BatchItem    // EventBatchFinished
+	Message      string         // EventNotice, or the proposal of EventProposal
+}
+
+// SetEventHandler makes the agent report its batches as events to handler
+// instead of printing their progress, so another program can show them its
+// own way. Calls to handler are serialized.
+func (a *Agent) SetEventHandler(handler func(Event)) {
+	a.events = handler
+}
+
+func (a *Agent) emit(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	a.eventMu.Lock()
+	defer a.eventMu.Unlock()
+	a.events(e)
+}
+
+// status prints a line of batch output, or sends it as an EventNotice when
+// there is an event handler.
+func (a *Agent) status(format string, args ...interface{}) {
+	if a.events == nil {
+		a.outputMu.Lock()
+		defer a.outputMu.Unlock()
+		fmt.Printf(format, args...)
+		return
+	}
+	a.emit(Event{Kind: EventNotice, Message: strings.TrimSpace(fmt.Sprintf(format, args...))})
+}
+
+// eventProgress reports a batch to the event handler.
+type eventProgress struct {
+	a *Agent
+}
+
+func (p eventProgress) started(name string) {
+	p.a.emit(Event{Kind: EventStarted, Name: name})
+}
+
+func (p eventProgress) activity(name, tool, line string) {
+	p.a.emit(Event{Kind: EventToolUse, Name: name, Tool: tool, Activity: line})
+}
+
+func (p eventProgress) tokens(name string, input, output int) {
+	p.a.emit(Event{Kind: EventTokens, Name: name, InputTokens: input, OutputTokens: output})
+}
+
+func (p eventProgress) finished(result ProcessResult) {
+	p.a.emit(Event{Kind: EventFinished, Name: result.FileName, Result: &result})
+}
+
+func (p eventProgress) stop() {}
@@ -5,9 +5,15 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	claudecode "github.com/yukifoo/claude-code-sdk-go"
+
+	"github.com/udemy/docu-jarvis-cli/internal/ci"
+	"github.com/udemy/docu-jarvis-cli/internal/conversations"
+	"github.com/udemy/docu-jarvis-cli/internal/redact"
 )
 
 type ConversationMessage struct {
@@ -18,28 +24,68 @@ type ConversationMessage struct {
 type CommitExplainer struct {
 	agent               *Agent
 	commitHash          string
+	commitCount         int
 	commitDiff          string
+	deploys             bool // commitDiff is a DeployDivergence report
 	conversationHistory []ConversationMessage
+	transcript          *conversations.Conversation // saved after every answer, when set
+	started             time.Time
 }
 
-func NewCommitExplainer(agent *Agent, commitHash, commitDiff string) *CommitExplainer {
+// NewCommitExplainer explains the commits named by commitHash, a commit or a
+// range, whose diffs are in commitDiff.
+func NewCommitExplainer(agent *Agent, commitHash string, commitCount int, commitDiff string) *CommitExplainer {
 	return &CommitExplainer{
 		agent:               agent,
 		commitHash:          commitHash,
+		commitCount:         commitCount,
 		commitDiff:          commitDiff,
 		conversationHistory: []ConversationMessage{},
+		started:             time.Now(),
+	}
+}
+
+// NewDeployExplainer explains what differs between the commits deployed to
+// production and staging, from their DeployDivergence report.
+func NewDeployExplainer(agent *Agent, prod, staging, report string) *CommitExplainer {
+	return &CommitExplainer{
+		agent:               agent,
+		commitHash:          prod + "..." + staging,
+		commitDiff:          report,
+		deploys:             true,
+		conversationHistory: []ConversationMessage{},
+		started:             time.Now(),
+	}
+}
+
+// subject is what the conversation is about, for the prompts.
+func (ce *CommitExplainer) subject() string {
+	if ce.deploys {
+		return "the difference between production and staging"
+	}
+	if ce.commitCount > 1 {
+		return "these commits"
+	}
+	return "this commit"
+}
+
+// SetTranscript saves the conversation to transcript after every answer.
+// The messages it already has are the history, so a saved conversation
+// carries on where it stopped.
+func (ce *CommitExplainer) SetTranscript(transcript *conversations.Conversation) {
+	ce.transcript = transcript
+	ce.conversationHistory = nil
+	for _, msg := range transcript.Messages {
+		ce.conversationHistory = append(ce.conversationHistory, ConversationMessage{Role: msg.Role, Content: msg.Content})
 	}
 }
 
 func (ce *CommitExplainer) StartConversation(ctx context.Context, initialQuestion string) error {
-	ce.agent.logger.Printf("Starting commit explanation conversation for commit: %s", ce.commitHash)
+	ce.agent.logger.Infof("Starting commit explanation conversation for commit: %s", ce.commitHash)
 
 	if initialQuestion != "" {
-		fmt.Printf("\n> %s\n\n", initialQuestion)
-		ce.conversationHistory = append(ce.conversationHistory, ConversationMessage{
-			Role:    "user",
-			Content: initialQuestion,
-		})
+		fmt.Printf("\n> %s\n\n", redact.String(initialQuestion))
+		ce.addMessage("user", initialQuestion)
 
 		fmt.Print("Claude: ")
 		_, err := ce.getResponse(ctx)
@@ -49,11 +95,11 @@ func (ce *CommitExplainer) StartConversation(ctx context.Context, initialQuestio
 
 		fmt.Println()
 	} else {
-		initialPrompt := "Please provide a comprehensive explanation of this commit. What changes were made and why?"
-		ce.conversationHistory = append(ce.conversationHistory, ConversationMessage{
-			Role:    "user",
-			Content: initialPrompt,
-		})
+		initialPrompt := fmt.Sprintf("Please provide a comprehensive explanation of %s. What changes were made and why?", ce.subject())
+		if ce.deploys {
+			initialPrompt = "What's different between production and staging right now? Summarize every commit between them, grouped by risk area."
+		}
+		ce.addMessage("user", initialPrompt)
 
 		fmt.Print("Claude: ")
 		_, err := ce.getResponse(ctx)
@@ -64,15 +110,62 @@ func (ce *CommitExplainer) StartConversation(ctx context.Context, initialQuestio
 		fmt.Println()
 	}
 
+	// Nobody can ask follow-up questions in CI
+	if ci.Enabled() {
+		return nil
+	}
 	return ce.interactiveLoop(ctx)
 }
 
+// ResumeConversation carries on a conversation restored with SetTranscript:
+// it shows the last answer, answers question if there is one, and goes on
+// with follow-up questions.
+func (ce *CommitExplainer) ResumeConversation(ctx context.Context, question string) error {
+	ce.agent.logger.Infof("Resuming commit explanation conversation for commit: %s (%d messages)", ce.commitHash, len(ce.conversationHistory))
+
+	for i := len(ce.conversationHistory) - 1; i >= 0; i-- {
+		if ce.conversationHistory[i].Role == "assistant" {
+			fmt.Printf("Last answer:\n\n%s\n\n", redact.String(ce.conversationHistory[i].Content))
+			break
+		}
+	}
+
+	if question != "" {
+		fmt.Printf("> %s\n\n", redact.String(question))
+		ce.addMessage("user", question)
+
+		fmt.Print("Claude: ")
+		if _, err := ce.getResponse(ctx); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+
+	if ci.Enabled() {
+		return nil
+	}
+	return ce.interactiveLoop(ctx)
+}
+
+// addMessage adds a message to the history and the transcript.
+func (ce *CommitExplainer) addMessage(role, content string) {
+	ce.conversationHistory = append(ce.conversationHistory, ConversationMessage{Role: role, Content: content})
+	if ce.transcript != nil {
+		ce.transcript.Add(role, content)
+	}
+}
+
+// History returns the conversation so far.
+func (ce *CommitExplainer) History() []ConversationMessage {
+	return ce.conversationHistory
+}
+
 func (ce *CommitExplainer) interactiveLoop(ctx context.Context) error {
 	reader := bufio.NewReader(os.Stdin)
 
 	fmt.Println(strings.Repeat("=", 70))
-	fmt.Println("Interactive conversation mode - Ask questions about the commit")
-	fmt.Println("Type 'exit', 'quit', or press Ctrl+C to end the conversation")
+	fmt.Printf("Interactive conversation mode - Ask questions about %s\n", ce.subject())
+	fmt.Println("Type /help for commands, or 'exit', 'quit', or Ctrl+C to end the conversation")
 	fmt.Println(strings.Repeat("=", 70))
 	fmt.Println()
 
@@ -92,11 +185,13 @@ func (ce *CommitExplainer) interactiveLoop(ctx context.Context) error {
 			fmt.Println("\n✓ Conversation ended")
 			return nil
 		}
+		if strings.HasPrefix(userInput, "/") {
+			ce.runCommand(userInput)
+			fmt.Println()
+			continue
+		}
 
-		ce.conversationHistory = append(ce.conversationHistory, ConversationMessage{
-			Role:    "user",
-			Content: userInput,
-		})
+		ce.addMessage("user", userInput)
 
 		fmt.Print("\nClaude: ")
 		_, err = ce.getResponse(ctx)
@@ -108,10 +203,142 @@ func (ce *CommitExplainer) interactiveLoop(ctx context.Context) error {
 	}
 }
 
+// runCommand runs a slash command of the interactive loop. Its output is for
+// the user only; none of it goes into the conversation.
+func (ce *CommitExplainer) runCommand(input string) {
+	command, arg, _ := strings.Cut(input, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch strings.ToLower(command) {
+	case "/help":
+		fmt.Println("Commands:")
+		fmt.Println("  /diff           Print the diff again")
+		fmt.Println("  /files          List the changed files")
+		fmt.Println("  /show <file>    Print a changed file as it is in the checkout")
+		fmt.Println("  /save <path>    Save the conversation as markdown")
+		fmt.Println("  /clear          Forget the conversation so far and start over")
+		fmt.Println("  /help           Show this list")
+	case "/diff":
+		fmt.Println(redact.String(ce.commitDiff))
+	case "/files":
+		files := ce.changedFiles()
+		if len(files) == 0 {
+			fmt.Println("No changed files in the diff")
+			return
+		}
+		for _, file := range files {
+			fmt.Printf("  %s\n", file)
+		}
+	case "/show":
+		ce.showFile(arg)
+	case "/save":
+		if arg == "" {
+			fmt.Println("Usage: /save <path>")
+			return
+		}
+		if err := os.WriteFile(arg, []byte(redact.String(ce.markdown())), 0644); err != nil {
+			fmt.Printf("OH NO!!!! Failed to save the conversation: %v\n", err)
+			return
+		}
+		fmt.Printf("✓ Conversation saved to %s\n", arg)
+	case "/clear":
+		ce.conversationHistory = []ConversationMessage{}
+		if ce.transcript != nil {
+			ce.transcript.Messages = nil
+			if err := ce.transcript.Save(); err != nil {
+				fmt.Printf("Warning: failed to save the conversation: %v\n", err)
+			}
+		}
+		fmt.Println("✓ Conversation history cleared")
+	default:
+		fmt.Printf("Unknown command %s (type /help for the list)\n", command)
+	}
+}
+
+// changedFiles lists the files of the diff, in the order they appear.
+func (ce *CommitExplainer) changedFiles() []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(ce.commitDiff, "\n") {
+		if !strings.HasPrefix(line, "diff --git a/") {
+			continue
+		}
+		i := strings.LastIndex(line, " b/")
+		if i < 0 {
+			continue
+		}
+		file := line[i+len(" b/"):]
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// showFile prints a changed file, given by its path or the end of it.
+func (ce *CommitExplainer) showFile(name string) {
+	if name == "" {
+		fmt.Println("Usage: /show <file>")
+		return
+	}
+
+	var matches []string
+	for _, file := range ce.changedFiles() {
+		if file == name {
+			matches = []string{file}
+			break
+		}
+		if strings.HasSuffix(file, "/"+name) {
+			matches = append(matches, file)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		fmt.Printf("%s is not one of the changed files (see /files)\n", name)
+		return
+	case 1:
+	default:
+		fmt.Printf("%s matches several changed files:\n", name)
+		for _, file := range matches {
+			fmt.Printf("  %s\n", file)
+		}
+		return
+	}
+
+	content, err := os.ReadFile(filepath.Join(ce.agent.folder, filepath.FromSlash(matches[0])))
+	if os.IsNotExist(err) {
+		fmt.Printf("%s is not in the checkout; it was deleted or renamed since\n", matches[0])
+		return
+	}
+	if err != nil {
+		fmt.Printf("OH NO!!!! Failed to read %s: %v\n", matches[0], err)
+		return
+	}
+	fmt.Println(strings.Repeat("-", 70))
+	fmt.Println(matches[0])
+	fmt.Println(strings.Repeat("-", 70))
+	fmt.Println(redact.String(strings.TrimRight(string(content), "\n")))
+	fmt.Println(strings.Repeat("-", 70))
+}
+
+// markdown renders the conversation for /save, from the transcript when
+// there is one.
+func (ce *CommitExplainer) markdown() string {
+	if ce.transcript != nil {
+		return ce.transcript.Markdown()
+	}
+	c := &conversations.Conversation{ID: ce.commitHash, Subject: ce.commitHash, Started: ce.started, Updated: time.Now()}
+	for _, msg := range ce.conversationHistory {
+		c.Add(msg.Role, msg.Content)
+	}
+	return c.Markdown()
+}
+
 func (ce *CommitExplainer) getResponse(ctx context.Context) (string, error) {
 	prompt := ce.buildPromptWithHistory()
 
-	ce.agent.logger.Printf("Sending conversation turn to Claude (history length: %d)", len(ce.conversationHistory))
+	ce.agent.logger.Debugf("Sending conversation turn to Claude (history length: %d)", len(ce.conversationHistory))
 
 	request := claudecode.QueryRequest{
 		Prompt: prompt,
@@ -125,26 +352,35 @@ func (ce *CommitExplainer) getResponse(ctx context.Context) (string, error) {
 		},
 	}
 
-	messageChan, errorChan := claudecode.QueryStreamWithRequest(ctx, request)
+	ctx = ce.agent.withMode(ctx, ModeExplain, &request)
+	ce.agent.sandbox(&request)
+	messageChan, errorChan := ce.agent.provider.QueryStream(ctx, request)
 
 	var responseText strings.Builder
 	var lastPrintedLength int
+	var received []claudecode.Message
 
 	for {
 		select {
 		case message, ok := <-messageChan:
 			if !ok {
 				fmt.Println()
+				ce.agent.auditToolUse(received)
+				recordUsage(received)
 				response := strings.TrimSpace(responseText.String())
 
-				ce.conversationHistory = append(ce.conversationHistory, ConversationMessage{
-					Role:    "assistant",
-					Content: response,
-				})
+				ce.addMessage("assistant", response)
+				if ce.transcript != nil {
+					if err := ce.transcript.Save(); err != nil {
+						ce.agent.logger.Warnf("Failed to save conversation: %v", err)
+						fmt.Printf("Warning: failed to save the conversation: %v\n", err)
+					}
+				}
 
-				ce.agent.logger.Printf("Response received, length: %d characters", len(response))
+				ce.agent.logger.Infof("Response received, length: %d characters", len(response))
 				return response, nil
 			}
+			received = append(received, message)
 
 			if message.Type() == claudecode.MessageTypeAssistant {
 				for _, block := range message.Content() {
@@ -163,12 +399,12 @@ func (ce *CommitExplainer) getResponse(ctx context.Context) (string, error) {
 
 		case err := <-errorChan:
 			if err != nil {
-				ce.agent.logger.Printf("Error getting response: %v", err)
+				ce.agent.logger.Errorf("Error getting response: %v", err)
 				return "", fmt.Errorf("failed to get response: %w", err)
 			}
 
 		case <-ctx.Done():
-			ce.agent.logger.Printf("Context cancelled")
+			ce.agent.logger.Warnf("Context cancelled")
 			return "", ctx.Err()
 		}
 	}
@@ -180,10 +416,20 @@ func (ce *CommitExplainer) buildPromptWithHistory() string {
 	prompt.WriteString(ce.agent.systemPrompt)
 	prompt.WriteString("\n\n")
 
-	prompt.WriteString("Here is the commit you need to analyze:\n\n")
-	prompt.WriteString("<commit_code>\n")
+	tag := "commit_code"
+	switch {
+	case ce.deploys:
+		prompt.WriteString("Here is what differs between the commits deployed to production and staging:\n\n")
+		tag = "deploy_divergence"
+	case ce.commitCount > 1:
+		prompt.WriteString(fmt.Sprintf("Here are the %d commits of %s you need to analyze, oldest first. ", ce.commitCount, ce.commitHash))
+		prompt.WriteString("Together they make up one change, such as a feature branch: explain them as a whole, and refer to individual commits by their short hash where it helps.\n\n")
+	default:
+		prompt.WriteString("Here is the commit you need to analyze:\n\n")
+	}
+	prompt.WriteString("<" + tag + ">\n")
 	prompt.WriteString(ce.commitDiff)
-	prompt.WriteString("\n</commit_code>\n\n")
+	prompt.WriteString("\n</" + tag + ">\n\n")
 
 	prompt.WriteString(fmt.Sprintf("The codebase can be found at: %s\n\n", ce.agent.folder))
 
@@ -0,0 +1,122 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+)
+
+// FeedbackComment is a reviewer's comment on a docu-jarvis PR. Path is empty
+// for comments that are not on a file.
+type FeedbackComment struct {
+	Author string
+	Path   string
+	Line   int
+	Body   string
+}
+
+// ReviewLessons are the rules distilled from the review comments on a PR.
+type ReviewLessons struct {
+	Rules   []string `json:"rules"`
+	Summary string   `json:"summary"`
+}
+
+// SetReviewerRules sets the rules learned from earlier PR reviews, which
+// update-docs and write-docs follow.
+func (a *Agent) SetReviewerRules(rules []string) {
+	a.rules = rules
+}
+
+func (a *Agent) reviewerRulesPrompt() string {
+	if len(a.rules) == 0 {
+		return ""
+	}
+	var prompt strings.Builder
+	prompt.WriteString("\n\nReviewers of earlier documentation pull requests in this repository asked for the following. Follow these rules; they take precedence over the general guidelines above:\n")
+	for _, rule := range a.rules {
+		prompt.WriteString("- " + rule + "\n")
+	}
+	return prompt.String()
+}
+
+// LearnFromReview distills review comments into rules for later runs, given
+// the rules already known so they are not repeated.
+func (a *Agent) LearnFromReview(ctx context.Context, comments []FeedbackComment, known []string) (*ReviewLessons, error) {
+	a.logger.Infof("Learning from %d review comments", len(comments))
+
+	knownList := "(none yet)"
+	if len(known) > 0 {
+		knownList = "- " + strings.Join(known, "\n- ")
+	}
+
+	prompt := fmt.Sprintf(`%s
+
+Rules already learned from earlier pull requests:
+%s
+
+Here are the review comments on the pull request:
+
+<comments>
+%s</comments>`, a.systemPrompt, knownList, formatComments(comments))
+
+	request := claudecode.QueryRequest{
+		Prompt: prompt,
+		Options: &claudecode.Options{
+			AllowedTools:   []string{"Read", "Grep", "LS"},
+			PermissionMode: stringPtr("acceptEdits"),
+			Cwd:            stringPtr(a.folder),
+			OutputFormat:   outputFormatPtr(claudecode.OutputFormatJSON),
+			Verbose:        boolPtr(false),
+			MaxTurns:       intPtr(10),
+		},
+	}
+
+	messages, err := a.query(ctx, ModeReviewFeedback, request)
+	if err != nil {
+		a.logger.Errorf("Error learning from review: %v", err)
+		return nil, fmt.Errorf("review feedback error: %w", err)
+	}
+
+	var lessons *ReviewLessons
+	for _, candidate := range jsonObjectCandidates(resultText(messages)) {
+		var parsed ReviewLessons
+		if err := json.Unmarshal([]byte(candidate), &parsed); err == nil {
+			lessons = &parsed
+			break
+		}
+	}
+
+	if lessons == nil {
+		a.logger.Errorf("Could not extract JSON from review feedback")
+		return nil, fmt.Errorf("Claude did not return expected JSON response")
+	}
+
+	var rules []string
+	for _, rule := range lessons.Rules {
+		if rule = strings.TrimSpace(rule); rule != "" {
+			rules = append(rules, rule)
+		}
+	}
+	lessons.Rules = rules
+
+	a.logger.Infof("Learned %d rules from review", len(lessons.Rules))
+	return lessons, nil
+}
+
+func formatComments(comments []FeedbackComment) string {
+	var commentList strings.Builder
+	for _, comment := range comments {
+		switch {
+		case comment.Path != "" && comment.Line > 0:
+			commentList.WriteString(fmt.Sprintf("<comment author=\"%s\" file=\"%s\" line=\"%d\">\n%s\n</comment>\n\n", comment.Author, comment.Path, comment.Line, comment.Body))
+		case comment.Path != "":
+			commentList.WriteString(fmt.Sprintf("<comment author=\"%s\" file=\"%s\">\n%s\n</comment>\n\n", comment.Author, comment.Path, comment.Body))
+		default:
+			commentList.WriteString(fmt.Sprintf("<comment author=\"%s\">\n%s\n</comment>\n\n", comment.Author, comment.Body))
+		}
+	}
+	return commentList.String()
+}
@@ -0,0 +1,315 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+
+	"github.com/udemy/docu-jarvis-cli/internal/frontmatter"
+	"github.com/udemy/docu-jarvis-cli/internal/netguard"
+)
+
+// DocsGapSchemaVersion is bumped whenever DocsGap changes in a way that could
+// break consumers parsing it.
+const DocsGapSchemaVersion = 1
+
+const (
+	// maxTemplateBytes caps a reference template read from a URL or file.
+	maxTemplateBytes = 256 * 1024
+	// templateTimeout caps fetching a reference template.
+	templateTimeout = 30 * time.Second
+)
+
+// LayoutTemplate is the reference information architecture docs-gap compares
+// the docs to.
+type LayoutTemplate struct {
+	Name      string
+	Structure string // the categories, or the reference's table of contents
+}
+
+// layoutPresets are the built-in templates, by name.
+var layoutPresets = map[string]LayoutTemplate{
+	"diataxis": {Name: "Diátaxis", Structure: `The Diátaxis framework (https://diataxis.fr) has four kinds of documentation, each for a different need:
+- Tutorials: learning-oriented lessons that take a newcomer through a complete, working result step by step
+- How-to guides: goal-oriented directions for a competent user solving a specific real-world problem
+- Reference: information-oriented, accurate and complete technical descriptions of the machinery (APIs, commands, configuration)
+- Explanation: understanding-oriented discussion of background, design decisions, and alternatives`},
+	"good-docs": {Name: "The Good Docs Project", Structure: `The Good Docs Project templates (https://thegooddocsproject.dev) expect these kinds of documentation:
+- README: what the project is, who it is for, and where to start
+- Getting started / Quickstart: the shortest path to a first success
+- Installation: requirements and setup on each supported platform
+- How-to guides: tasks a user needs to perform
+- Concepts: the ideas and terms needed to use the project well
+- Reference: APIs, commands, and configuration
+- Troubleshooting: known problems, their symptoms, and fixes
+- Contributing: how to set up a development environment and submit changes
+- Release notes / Changelog: what changed in each release`},
+	"service": {Name: "Service docs", Structure: `Documentation a team owning a backend service is expected to keep:
+- Overview: what the service does, who owns it, and its dependencies
+- Architecture: components, data stores, and how requests flow through them
+- API reference: every endpoint, event, or queue the service exposes, with examples
+- Configuration: environment variables, feature flags, and their defaults
+- Local development: running and testing the service on a laptop
+- Deployment: how releases reach each environment and how to roll back
+- Runbooks: alerts, their likely causes, and the steps to resolve them
+- Decision records: why the significant design choices were made`},
+}
+
+// LayoutPresets returns the names of the built-in templates, sorted.
+func LayoutPresets() []string {
+	var names []string
+	for name := range layoutPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadLayoutTemplate returns the preset of that name, or the reference read
+// from an http(s) URL or a local path. A URL or file is the reference's table
+// of contents, e.g. another project's SUMMARY.md, mkdocs.yml, or sidebars
+// file; a directory is described by its tree of markdown files.
+func LoadLayoutTemplate(ctx context.Context, ref string) (*LayoutTemplate, error) {
+	if preset, ok := layoutPresets[strings.ToLower(ref)]; ok {
+		return &preset, nil
+	}
+
+	if strings.HasPrefix(ref, "https://") || strings.HasPrefix(ref, "http://") {
+		if err := netguard.Check("fetching the reference template"); err != nil {
+			return nil, err
+		}
+		structure, err := fetchTemplate(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		return &LayoutTemplate{Name: ref, Structure: structure}, nil
+	}
+
+	info, err := os.Stat(ref)
+	if err != nil {
+		return nil, fmt.Errorf("unknown template %q: not a preset (%s), URL, or existing path", ref, strings.Join(LayoutPresets(), ", "))
+	}
+	if info.IsDir() {
+		docs, err := FindDocs([]string{ref})
+		if err != nil {
+			return nil, err
+		}
+		if len(docs) == 0 {
+			return nil, fmt.Errorf("no .md or .mdx files found in template directory: %s", ref)
+		}
+		var tree strings.Builder
+		for _, doc := range docs {
+			rel, _ := filepath.Rel(ref, doc)
+			fmt.Fprintf(&tree, "- %s", filepath.ToSlash(rel))
+			if title := docTitle(doc); title != "" {
+				fmt.Fprintf(&tree, ": %s", title)
+			}
+			tree.WriteString("\n")
+		}
+		return &LayoutTemplate{Name: filepath.Base(filepath.Clean(ref)), Structure: tree.String()}, nil
+	}
+
+	data, err := readLimited(ref)
+	if err != nil {
+		return nil, err
+	}
+	return &LayoutTemplate{Name: filepath.Base(ref), Structure: data}, nil
+}
+
+func fetchTemplate(ctx context.Context, url string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, templateTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid template URL: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch template: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch template: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTemplateBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to read template: %w", err)
+	}
+	return string(data), nil
+}
+
+func readLimited(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open template: %w", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxTemplateBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to read template: %w", err)
+	}
+	return string(data), nil
+}
+
+// docTitle returns the first top-level heading of a doc, or its front-matter
+// title.
+func docTitle(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	inFront := false
+	for n := 0; scanner.Scan() && n < 50; n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if n == 0 && line == "---" {
+			inFront = true
+			continue
+		}
+		if inFront {
+			if line == "---" {
+				inFront = false
+			} else if title, ok := strings.CutPrefix(line, "title:"); ok {
+				return strings.Trim(strings.TrimSpace(title), `"'`)
+			}
+			continue
+		}
+		if title, ok := strings.CutPrefix(line, "# "); ok {
+			return strings.TrimSpace(title)
+		}
+	}
+	return ""
+}
+
+type GapTopic struct {
+	Topic    string `json:"topic"`
+	Priority string `json:"priority"`
+	Reason   string `json:"reason"`
+}
+
+type GapCategory struct {
+	Name            string     `json:"name"`
+	Purpose         string     `json:"purpose"`
+	Status          string     `json:"status"` // covered, partial, or missing
+	Docs            []string   `json:"docs"`
+	Notes           string     `json:"notes,omitempty"`
+	SuggestedTopics []GapTopic `json:"suggested_topics"`
+}
+
+// DocsGap is the report of docs-gap.
+type DocsGap struct {
+	SchemaVersion int           `json:"schema_version"`
+	Template      string        `json:"template"`
+	Summary       string        `json:"summary"`
+	DocFiles      int           `json:"doc_files"`
+	Categories    []GapCategory `json:"categories"`
+}
+
+// Gaps returns the categories that are not covered.
+func (d *DocsGap) Gaps() []GapCategory {
+	var gaps []GapCategory
+	for _, category := range d.Categories {
+		if category.Status != "covered" {
+			gaps = append(gaps, category)
+		}
+	}
+	return gaps
+}
+
+// CompareDocsLayout compares the structure of the docs to the template.
+func (a *Agent) CompareDocsLayout(ctx context.Context, template *LayoutTemplate) (*DocsGap, error) {
+	docFiles, err := a.listDocs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan documentation directory: %w", err)
+	}
+
+	a.logger.Infof("Comparing %d docs to the %s template", len(docFiles), template.Name)
+
+	var fileList strings.Builder
+	for _, file := range docFiles {
+		fmt.Fprintf(&fileList, "- %s", a.docName(file))
+		if title := docTitle(file); title != "" {
+			fmt.Fprintf(&fileList, ": %s", title)
+		}
+		if content, err := os.ReadFile(file); err == nil {
+			if tags := frontmatter.Tags(string(content)); len(tags) > 0 {
+				fmt.Fprintf(&fileList, " [tags: %s]", strings.Join(tags, ", "))
+			}
+		}
+		fileList.WriteString("\n")
+	}
+	if len(docFiles) == 0 {
+		fileList.WriteString("(none)\n")
+	}
+
+	prompt := fmt.Sprintf(`%s
+
+The codebase is located at: %s
+
+Reference (%s):
+<reference>
+%s
+</reference>
+
+Documentation files (paths relative to the codebase root, with their titles):
+%s`, a.systemPrompt, a.folder, template.Name, template.Structure, fileList.String())
+
+	request := claudecode.QueryRequest{
+		Prompt: prompt,
+		Options: &claudecode.Options{
+			AllowedTools:   []string{"Read", "Grep", "Glob", "LS"},
+			PermissionMode: stringPtr("acceptEdits"),
+			Cwd:            stringPtr(a.folder),
+			OutputFormat:   outputFormatPtr(claudecode.OutputFormatJSON),
+			Verbose:        boolPtr(false),
+			MaxTurns:       intPtr(40),
+		},
+	}
+
+	messages, err := a.query(ctx, ModeDocsGap, request)
+	if err != nil {
+		a.logger.Errorf("Error comparing docs layout: %v", err)
+		return nil, fmt.Errorf("docs gap error: %w", err)
+	}
+
+	var gap *DocsGap
+	for _, candidate := range jsonObjectCandidates(resultText(messages)) {
+		var parsed DocsGap
+		if err := json.Unmarshal([]byte(candidate), &parsed); err == nil && len(parsed.Categories) > 0 {
+			gap = &parsed
+			break
+		}
+	}
+
+	if gap == nil {
+		a.logger.Errorf("Could not extract JSON from docs gap analysis")
+		return nil, fmt.Errorf("Claude did not return expected JSON response")
+	}
+
+	gap.SchemaVersion = DocsGapSchemaVersion
+	gap.Template = template.Name
+	gap.DocFiles = len(docFiles)
+	for i := range gap.Categories {
+		if gap.Categories[i].Docs == nil {
+			gap.Categories[i].Docs = []string{}
+		}
+		if gap.Categories[i].SuggestedTopics == nil {
+			gap.Categories[i].SuggestedTopics = []GapTopic{}
+		}
+	}
+
+	a.logger.Infof("Docs gap: %d of %d categories not covered", len(gap.Gaps()), len(gap.Categories))
+	return gap, nil
+}
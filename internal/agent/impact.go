@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+)
+
+type AffectedDoc struct {
+	File           string `json:"file"`
+	Reason         string `json:"reason"`
+	RequiresUpdate bool   `json:"requires_update"`
+}
+
+type DocsImpact struct {
+	AffectedDocs []AffectedDoc `json:"affected_docs"`
+	Summary      string        `json:"summary"`
+}
+
+// RequiredUpdates returns the files that the change obviously requires updating.
+func (d *DocsImpact) RequiredUpdates() []string {
+	var files []string
+	for _, doc := range d.AffectedDocs {
+		if doc.RequiresUpdate {
+			files = append(files, doc.File)
+		}
+	}
+	return files
+}
+
+func (a *Agent) AnalyzeDocsImpact(ctx context.Context, stagedCode string) (*DocsImpact, error) {
+	docFiles, err := a.listDocs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan documentation directory: %w", err)
+	}
+
+	a.logger.Infof("Analyzing documentation impact of staged code against %d docs", len(docFiles))
+
+	if len(docFiles) == 0 {
+		return &DocsImpact{Summary: "No documentation files found"}, nil
+	}
+
+	var fileList strings.Builder
+	for _, file := range docFiles {
+		fileList.WriteString(fmt.Sprintf("- %s\n", a.docName(file)))
+	}
+
+	prompt := fmt.Sprintf(`%s
+
+Here is the code currently in git staging:
+
+<staged_code>
+%s
+</staged_code>
+
+Documentation files (paths relative to %s):
+%s`, a.systemPrompt, stagedCode, a.folder, fileList.String())
+
+	request := claudecode.QueryRequest{
+		Prompt: prompt,
+		Options: &claudecode.Options{
+			AllowedTools:   []string{"Read", "Grep", "LS"},
+			PermissionMode: stringPtr("acceptEdits"),
+			Cwd:            stringPtr(a.folder),
+			OutputFormat:   outputFormatPtr(claudecode.OutputFormatJSON),
+			Verbose:        boolPtr(false),
+			MaxTurns:       intPtr(15),
+		},
+	}
+
+	messages, err := a.query(ctx, ModeDocsImpact, request)
+	if err != nil {
+		a.logger.Errorf("Error analyzing docs impact: %v", err)
+		return nil, fmt.Errorf("docs impact error: %w", err)
+	}
+
+	var impact *DocsImpact
+	for _, candidate := range jsonObjectCandidates(resultText(messages)) {
+		var parsed DocsImpact
+		if err := json.Unmarshal([]byte(candidate), &parsed); err == nil {
+			impact = &parsed
+			break
+		}
+	}
+
+	if impact == nil {
+		a.logger.Errorf("Could not extract JSON from docs impact analysis")
+		return nil, fmt.Errorf("Claude did not return expected JSON response")
+	}
+
+	a.logger.Infof("Docs impact: %d affected, %d require updates", len(impact.AffectedDocs), len(impact.RequiredUpdates()))
+	return impact, nil
+}
@@ -0,0 +1,264 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+
+	"github.com/udemy/docu-jarvis-cli/internal/links"
+)
+
+// DocsLintSchemaVersion is bumped whenever DocsLint changes in a way that
+// could break consumers parsing it.
+const DocsLintSchemaVersion = 1
+
+// linkRule is the rule of the broken links found without Claude.
+const linkRule = "internal links resolve"
+
+type LintFinding struct {
+	Line       int    `json:"line"` // 0 for the whole document
+	Rule       string `json:"rule"`
+	Severity   string `json:"severity"` // "error" or "warning"
+	Issue      string `json:"issue"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// DocLint is the lint result of one document.
+type DocLint struct {
+	File     string        `json:"file"`
+	Findings []LintFinding `json:"findings"`
+	Fixed    bool          `json:"fixed"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// DocsLint is the report of lint-docs.
+type DocsLint struct {
+	SchemaVersion int       `json:"schema_version"`
+	Files         []DocLint `json:"files"`
+}
+
+// Counts returns the number of error and warning findings, without those of
+// fixed documents.
+func (d *DocsLint) Counts() (errors, warnings int) {
+	for _, file := range d.Files {
+		if file.Fixed {
+			continue
+		}
+		for _, finding := range file.Findings {
+			if finding.Severity == "error" {
+				errors++
+			} else {
+				warnings++
+			}
+		}
+	}
+	return errors, warnings
+}
+
+// Failed returns the documents that could not be linted or fixed.
+func (d *DocsLint) Failed() []DocLint {
+	var failed []DocLint
+	for _, file := range d.Files {
+		if file.Error != "" {
+			failed = append(failed, file)
+		}
+	}
+	return failed
+}
+
+const lintFixInstructions = `
+
+Fix the style guide findings below in the document, editing it in place. Change only what the findings need; keep the rest of the document as it is. For broken links, link to the file or heading that was meant, or remove the link when nothing fits. Do not add content you cannot verify in the codebase; where a required section needs it, add the section with what the codebase supports.
+
+Findings (JSON):
+%s
+`
+
+// LintDocs checks each document against the style guide with Claude and
+// checks its internal links. With fix, documents with findings are then
+// fixed in place.
+func (a *Agent) LintDocs(ctx context.Context, paths []string, styleGuide string, fix bool) (*DocsLint, error) {
+	for _, path := range paths {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, fmt.Errorf("file does not exist: %s", path)
+		}
+	}
+
+	total := len(paths)
+	a.logger.Infof("Linting %d markdown files (fix: %v)", total, fix)
+	paths = a.orderFiles(paths)
+	defer a.startBreaker(total)()
+	a.status("Linting %d documentation files %s...\n", total, a.inFlight(total))
+	report := a.startProgress(fmt.Sprintf("Linting %d documentation files", total), a.docNames(paths))
+
+	lint := &DocsLint{SchemaVersion: DocsLintSchemaVersion, Files: make([]DocLint, total)}
+	items := make([]BatchItem, total)
+	a.forEach(total, func(i int) {
+		path := paths[i]
+		fileName := a.docName(path)
+		report.started(fileName)
+		itemCtx := withItem(ctx, fileName, report)
+
+		start := time.Now()
+		doc, messages, err := a.lintFile(itemCtx, path, styleGuide)
+		if err == nil && fix && len(doc.Findings) > 0 {
+			var fixMessages []claudecode.Message
+			fixMessages, err = a.fixLintFindings(itemCtx, path, doc.Findings)
+			messages = append(messages, fixMessages...)
+			doc.Fixed = err == nil
+		}
+		if err != nil {
+			doc.Error = err.Error()
+		}
+
+		result := ProcessResult{
+			FileName: fileName,
+			Success:  err == nil,
+			Error:    err,
+			Duration: time.Since(start),
+		}
+		result.Turns, result.InputTokens, result.OutputTokens = lintStats(messages)
+
+		report.finished(result)
+		lint.Files[i] = doc
+		items[i] = batchItem("file", result)
+	})
+	report.stop()
+
+	errs, warnings := lint.Counts()
+	a.logger.Infof("Lint complete: %d errors, %d warnings, %d files failed", errs, warnings, len(lint.Failed()))
+	a.finishBatch(os.Stdout, items)
+	return lint, nil
+}
+
+// lintFile returns the broken links of a document and the findings of Claude.
+func (a *Agent) lintFile(ctx context.Context, path, styleGuide string) (DocLint, []claudecode.Message, error) {
+	fileName := a.docName(path)
+	doc := DocLint{File: fileName, Findings: []LintFinding{}}
+
+	broken, err := links.Check(a.folder, fileName)
+	if err != nil {
+		return doc, nil, err
+	}
+	for _, link := range broken {
+		doc.Findings = append(doc.Findings, LintFinding{
+			Line:     link.Line,
+			Rule:     linkRule,
+			Severity: "error",
+			Issue:    fmt.Sprintf("%s: %s", link.Target, link.Reason),
+		})
+	}
+
+	prompt := fmt.Sprintf(`%s
+
+Style guide:
+%s
+
+Here is the documentation file that you need to check:
+
+<documentation>
+%s
+</documentation>
+`, a.systemPrompt, styleGuide, path)
+
+	request := claudecode.QueryRequest{
+		Prompt: prompt,
+		Options: &claudecode.Options{
+			AllowedTools:   []string{"Read", "Grep", "Glob", "LS"},
+			PermissionMode: stringPtr("acceptEdits"),
+			Cwd:            stringPtr(a.folder),
+			OutputFormat:   outputFormatPtr(claudecode.OutputFormatJSON),
+			Verbose:        boolPtr(false),
+			MaxTurns:       intPtr(15),
+		},
+	}
+
+	messages, err := a.query(ctx, ModeLintDocs, request)
+	if err != nil {
+		a.logger.Errorf("Error linting %s: %v", fileName, err)
+		return doc, messages, fmt.Errorf("query error: %w", err)
+	}
+
+	var findings []LintFinding
+	found := false
+	for _, candidate := range jsonObjectCandidates(resultText(messages)) {
+		var parsed struct {
+			Findings []LintFinding `json:"findings"`
+		}
+		if err := json.Unmarshal([]byte(candidate), &parsed); err == nil {
+			findings, found = parsed.Findings, true
+			break
+		}
+	}
+	if !found {
+		a.logger.Errorf("Could not extract JSON from lint of %s", fileName)
+		return doc, messages, fmt.Errorf("Claude did not return expected JSON response")
+	}
+
+	for _, finding := range findings {
+		if finding.Severity != "error" {
+			finding.Severity = "warning"
+		}
+		doc.Findings = append(doc.Findings, finding)
+	}
+	a.logger.Infof("Linted %s: %d findings", fileName, len(doc.Findings))
+	return doc, messages, nil
+}
+
+// fixLintFindings has Claude fix the findings in the document.
+func (a *Agent) fixLintFindings(ctx context.Context, path string, findings []LintFinding) ([]claudecode.Message, error) {
+	fileName := a.docName(path)
+	data, err := json.MarshalIndent(findings, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode findings: %w", err)
+	}
+
+	prompt := fmt.Sprintf(`You are fixing a documentation file so it follows the project's style guide.
+
+Here is the documentation file that you need to fix:
+
+<documentation>
+%s
+</documentation>
+`, path)
+	prompt += fmt.Sprintf(lintFixInstructions, data)
+
+	request := claudecode.QueryRequest{
+		Prompt: prompt,
+		Options: &claudecode.Options{
+			AllowedTools:   a.allowedTools("Read", "Edit", "Write", "Grep", "Glob"),
+			PermissionMode: a.editPermissionMode(),
+			Cwd:            stringPtr(a.folder),
+			OutputFormat:   outputFormatPtr(claudecode.OutputFormatJSON),
+			Verbose:        boolPtr(false),
+		},
+	}
+
+	messages, err := a.query(ctx, ModeLintDocs, request)
+	if err != nil {
+		a.logger.Errorf("Error fixing %s: %v", fileName, err)
+		return messages, fmt.Errorf("fix error: %w", err)
+	}
+	for _, message := range messages {
+		a.logMessage(fileName, message)
+	}
+	return messages, nil
+}
+
+// lintStats adds up the stats of the lint and fix queries of a document.
+func lintStats(messages []claudecode.Message) (turns, input, output int) {
+	for _, msg := range messages {
+		if m, ok := msg.(*claudecode.ResultMessage); ok {
+			turns += m.NumTurns
+			if m.Usage != nil {
+				input += m.Usage.InputTokens
+				output += m.Usage.OutputTokens
+			}
+		}
+	}
+	return turns, input, output
+}
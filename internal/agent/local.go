@@ -0,0 +1,345 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+
+	"github.com/udemy/docu-jarvis-cli/internal/netguard"
+	"github.com/udemy/docu-jarvis-cli/internal/redact"
+)
+
+const (
+	defaultLocalModel = "llama3.1"
+	// defaultLocalURL is Ollama's OpenAI-compatible endpoint.
+	defaultLocalURL = "http://localhost:11434/v1"
+)
+
+// Tool modes of the local provider, for the local_tools setting.
+const (
+	// LocalToolsNative passes the tools to the model's tool calling.
+	LocalToolsNative = "native"
+	// LocalToolsPrompt is for models without tool calling: the files the
+	// prompt mentions are read into it, and the tools are described in the
+	// system prompt and called with <tool> blocks in the reply.
+	LocalToolsPrompt = "prompt"
+)
+
+const (
+	// inlineFileLimit and inlineTotalLimit cap the files read into prompts.
+	inlineFileLimit  = 100 << 10
+	inlineTotalLimit = 400 << 10
+)
+
+// localProvider runs requests on a model served on the network, e.g. by
+// Ollama, vLLM, or LM Studio, through the OpenAI Chat Completions API.
+type localProvider struct {
+	*messagesProvider
+	prompt bool
+}
+
+func newLocalProvider(cfg ProviderConfig) (*localProvider, error) {
+	prompt := false
+	switch cfg.Tools {
+	case "", LocalToolsPrompt:
+		prompt = true
+	case LocalToolsNative:
+	default:
+		return nil, fmt.Errorf("invalid local tools mode %q (must be %s or %s)", cfg.Tools, LocalToolsNative, LocalToolsPrompt)
+	}
+
+	t := &localTransport{
+		baseURL: strings.TrimSuffix(firstSet(cfg.BaseURL, defaultLocalURL), "/"),
+		apiKey:  os.Getenv("OPENAI_API_KEY"),
+		prompt:  prompt,
+	}
+	redact.AddSecret(t.apiKey)
+
+	p := newMessagesProvider(ProviderLocal, firstSet(cfg.Model, defaultLocalModel), t)
+	p.free = true
+	return &localProvider{messagesProvider: p, prompt: prompt}, nil
+}
+
+func (p *localProvider) Query(ctx context.Context, request claudecode.QueryRequest) ([]claudecode.Message, error) {
+	return p.messagesProvider.Query(ctx, p.withFiles(request))
+}
+
+func (p *localProvider) QueryStream(ctx context.Context, request claudecode.QueryRequest) (<-chan claudecode.Message, <-chan error) {
+	return p.messagesProvider.QueryStream(ctx, p.withFiles(request))
+}
+
+// mentionedPath matches what looks like a file path with an extension.
+var mentionedPath = regexp.MustCompile(`[\w./\\-]+\.[A-Za-z0-9]+`)
+
+// withFiles adds the files the prompt mentions to it in prompt mode, so a
+// model that does not call tools still sees them. Only files the request's
+// Read rules allow are read.
+func (p *localProvider) withFiles(request claudecode.QueryRequest) claudecode.QueryRequest {
+	if !p.prompt {
+		return request
+	}
+	options := request.Options
+	if options == nil {
+		options = &claudecode.Options{}
+	}
+	tools := newToolbox(options)
+	if !tools.offered("Read") {
+		return request
+	}
+
+	var files strings.Builder
+	seen := make(map[string]bool)
+	total := 0
+	for _, mention := range mentionedPath.FindAllString(request.Prompt, -1) {
+		path := tools.resolve(strings.Trim(mention, "."))
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Size() > inlineFileLimit || total+int(info.Size()) > inlineTotalLimit {
+			continue
+		}
+		if ok, _ := tools.permitted("Read", path, nil); !ok {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		total += len(content)
+		fmt.Fprintf(&files, "<file path=\"%s\">\n%s\n</file>\n\n", path, strings.TrimSuffix(string(content), "\n"))
+	}
+	if files.Len() == 0 {
+		return request
+	}
+
+	request.Prompt += "\n\nThe files mentioned above, read for you:\n\n" + files.String()
+	return request
+}
+
+// localTransport translates Messages API requests to the OpenAI Chat
+// Completions API and the responses back.
+type localTransport struct {
+	baseURL string
+	apiKey  string // optional, for servers that require one
+	prompt  bool
+	calls   atomic.Int64 // numbers the tool calls of prompt mode
+}
+
+func (t *localTransport) check() error {
+	return netguard.CheckLocal("calling the local model", t.baseURL)
+}
+
+// chatMessage is a message of the Chat Completions API.
+type chatMessage struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []chatCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+type chatCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message      chatMessage `json:"message"`
+		FinishReason string      `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (t *localTransport) send(ctx context.Context, model string, body map[string]interface{}) ([]byte, error) {
+	system, _ := body["system"].(string)
+	conversation, _ := body["messages"].([]apiMessage)
+	defs, _ := body["tools"].([]map[string]interface{})
+
+	if t.prompt && len(defs) > 0 {
+		system += "\n\n" + promptToolInstructions(defs)
+	}
+	messages := []chatMessage{{Role: "system", Content: system}}
+	for _, msg := range conversation {
+		messages = append(messages, t.chatMessages(msg)...)
+	}
+
+	request := map[string]interface{}{
+		"model":      model,
+		"messages":   messages,
+		"max_tokens": body["max_tokens"],
+	}
+	if !t.prompt && len(defs) > 0 {
+		var tools []map[string]interface{}
+		for _, def := range defs {
+			tools = append(tools, map[string]interface{}{
+				"type": "function",
+				"function": map[string]interface{}{
+					"name":        def["name"],
+					"description": def["description"],
+					"parameters":  def["input_schema"],
+				},
+			})
+		}
+		request["tools"] = tools
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	headers := map[string]string{}
+	if t.apiKey != "" {
+		headers["Authorization"] = "Bearer " + t.apiKey
+	}
+	raw, err := postAPI(ctx, ProviderLocal, t.baseURL+"/chat/completions", data, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var chat chatResponse
+	if err := json.Unmarshal(raw, &chat); err != nil {
+		return nil, fmt.Errorf("%s returned an invalid response: %w", ProviderLocal, err)
+	}
+	if len(chat.Choices) == 0 {
+		return nil, fmt.Errorf("%s returned no choices", ProviderLocal)
+	}
+	reply := chat.Choices[0].Message
+
+	var resp apiResponse
+	resp.StopReason = "end_turn"
+	resp.Usage.InputTokens = chat.Usage.PromptTokens
+	resp.Usage.OutputTokens = chat.Usage.CompletionTokens
+
+	text := reply.Content
+	var uses []apiContent
+	if t.prompt {
+		text, uses = t.parseToolBlocks(text)
+	}
+	for _, call := range reply.ToolCalls {
+		input := map[string]interface{}{}
+		json.Unmarshal([]byte(call.Function.Arguments), &input)
+		uses = append(uses, apiContent{Type: "tool_use", ID: call.ID, Name: call.Function.Name, Input: input})
+	}
+
+	if strings.TrimSpace(text) != "" {
+		resp.Content = append(resp.Content, apiContent{Type: "text", Text: text})
+	}
+	if len(uses) > 0 {
+		resp.Content = append(resp.Content, uses...)
+		resp.StopReason = "tool_use"
+	}
+	return json.Marshal(resp)
+}
+
+// chatMessages converts a Messages API message. Tool calls and results
+// become <tool> and <tool_result> blocks in prompt mode.
+func (t *localTransport) chatMessages(msg apiMessage) []chatMessage {
+	var messages []chatMessage
+	var text strings.Builder
+	var calls []chatCall
+	for _, block := range msg.Content {
+		switch c := block.(type) {
+		case apiContent:
+			switch {
+			case c.Type == "text":
+				text.WriteString(c.Text)
+			case c.Type == "tool_use" && t.prompt:
+				input, _ := json.Marshal(c.Input)
+				fmt.Fprintf(&text, "\n<tool name=\"%s\">%s</tool>", c.Name, input)
+			case c.Type == "tool_use":
+				call := chatCall{ID: c.ID, Type: "function"}
+				input, _ := json.Marshal(c.Input)
+				call.Function.Name = c.Name
+				call.Function.Arguments = string(input)
+				calls = append(calls, call)
+			}
+		case map[string]interface{}:
+			id, _ := c["tool_use_id"].(string)
+			out, _ := c["content"].(string)
+			if t.prompt {
+				isErr, _ := c["is_error"].(bool)
+				fmt.Fprintf(&text, "<tool_result id=\"%s\" error=\"%t\">\n%s\n</tool_result>\n", id, isErr, out)
+				continue
+			}
+			messages = append(messages, chatMessage{Role: "tool", ToolCallID: id, Content: out})
+		}
+	}
+	if text.Len() > 0 || len(calls) > 0 {
+		messages = append(messages, chatMessage{Role: msg.Role, Content: strings.TrimSpace(text.String()), ToolCalls: calls})
+	}
+	return messages
+}
+
+var toolBlock = regexp.MustCompile(`(?s)<tool name="(\w+)">(.*?)</tool>`)
+
+// parseToolBlocks takes the <tool> blocks out of a prompt mode reply and
+// returns the rest of the text and the tool calls. Blocks that are not valid
+// JSON are left in the text.
+func (t *localTransport) parseToolBlocks(text string) (string, []apiContent) {
+	var uses []apiContent
+	rest := toolBlock.ReplaceAllStringFunc(text, func(block string) string {
+		match := toolBlock.FindStringSubmatch(block)
+		input := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(match[2])), &input); err != nil {
+			return block
+		}
+		id := fmt.Sprintf("call_%d", t.calls.Add(1))
+		uses = append(uses, apiContent{Type: "tool_use", ID: id, Name: match[1], Input: input})
+		return ""
+	})
+	return rest, uses
+}
+
+// promptToolInstructions describes the tools to a model without tool calling.
+func promptToolInstructions(defs []map[string]interface{}) string {
+	var b strings.Builder
+	b.WriteString("You can use the tools below. To call a tool, reply with one or more blocks like\n")
+	b.WriteString("<tool name=\"Read\">{\"file_path\": \"/absolute/path\"}</tool>\n")
+	b.WriteString("with the tool's parameters as a JSON object, and stop; the results come back in <tool_result> blocks. ")
+	b.WriteString("To change a file, call Write with its full new content or Edit with the exact text to replace. ")
+	b.WriteString("When you are done, reply with your final answer and no <tool> blocks.\n\nTools:\n")
+	for _, def := range defs {
+		fmt.Fprintf(&b, "\n%s: %s\n", def["name"], def["description"])
+		schema, _ := def["input_schema"].(map[string]interface{})
+		properties, _ := schema["properties"].(map[string]interface{})
+		required := make(map[string]bool)
+		if names, ok := schema["required"].([]string); ok {
+			for _, name := range names {
+				required[name] = true
+			}
+		}
+		var names []string
+		for name := range properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, _ := properties[name].(map[string]interface{})
+			kind, _ := property["type"].(string)
+			description, _ := property["description"].(string)
+			optional := ", optional"
+			if required[name] {
+				optional = ""
+			}
+			fmt.Fprintf(&b, "  - %s (%s%s): %s\n", name, kind, optional, description)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,290 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+
+	"github.com/udemy/docu-jarvis-cli/internal/redact"
+)
+
+const (
+	// apiMaxTokens caps each response, which has room for a whole document.
+	apiMaxTokens = 16384
+	// apiMaxTurns caps the tool loop of requests that set no MaxTurns.
+	apiMaxTurns = 100
+	// apiTimeout caps one API call.
+	apiTimeout = 10 * time.Minute
+)
+
+// apiTransport sends a Messages API request to one of the providers, which
+// differ in their endpoint, authentication, and how the model is named.
+type apiTransport interface {
+	check() error
+	// send posts the request body and returns the response body.
+	send(ctx context.Context, model string, body map[string]interface{}) ([]byte, error)
+}
+
+// messagesProvider runs requests on the Messages API, with the tool loop that
+// Claude Code would otherwise run in docu-jarvis.
+type messagesProvider struct {
+	name      string
+	model     string
+	transport apiTransport
+	free      bool // requests cost nothing, e.g. on a local model
+}
+
+func newMessagesProvider(name, model string, transport apiTransport) *messagesProvider {
+	return &messagesProvider{name: name, model: model, transport: transport}
+}
+
+func (p *messagesProvider) Name() string {
+	return p.name
+}
+
+func (p *messagesProvider) Check() error {
+	return p.transport.check()
+}
+
+func (p *messagesProvider) Query(ctx context.Context, request claudecode.QueryRequest) ([]claudecode.Message, error) {
+	var messages []claudecode.Message
+	err := p.run(ctx, request, func(msg claudecode.Message) {
+		messages = append(messages, msg)
+	})
+	return messages, err
+}
+
+func (p *messagesProvider) QueryStream(ctx context.Context, request claudecode.QueryRequest) (<-chan claudecode.Message, <-chan error) {
+	messages := make(chan claudecode.Message)
+	errs := make(chan error)
+	go func() {
+		defer close(messages)
+		err := p.run(ctx, request, func(msg claudecode.Message) {
+			select {
+			case messages <- msg:
+			case <-ctx.Done():
+			}
+		})
+		if err != nil {
+			// Sent before the messages are closed, so the error is not missed
+			select {
+			case errs <- err:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return messages, errs
+}
+
+// apiMessage is a message of the conversation sent to the API.
+type apiMessage struct {
+	Role    string        `json:"role"`
+	Content []interface{} `json:"content"`
+}
+
+type apiContent struct {
+	Type  string                 `json:"type"`
+	Text  string                 `json:"text,omitempty"`
+	ID    string                 `json:"id,omitempty"`
+	Name  string                 `json:"name,omitempty"`
+	Input map[string]interface{} `json:"input,omitempty"`
+}
+
+type apiResponse struct {
+	Content    []apiContent `json:"content"`
+	StopReason string       `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// run sends the request and runs the tools Claude asks for until it is done,
+// passing each message to emit as Claude Code would report it.
+func (p *messagesProvider) run(ctx context.Context, request claudecode.QueryRequest, emit func(claudecode.Message)) error {
+	options := request.Options
+	if options == nil {
+		options = &claudecode.Options{}
+	}
+	model := p.model
+	if options.Model != nil && *options.Model != "" {
+		model = *options.Model
+	}
+	maxTurns := apiMaxTurns
+	if options.MaxTurns != nil && *options.MaxTurns > 0 {
+		maxTurns = *options.MaxTurns
+	}
+
+	tools := newToolbox(options)
+	sessionID := fmt.Sprintf("%s-%d", p.name, time.Now().UnixNano())
+	start := time.Now()
+	emit(&claudecode.SystemMessage{
+		Subtype:        "init",
+		SessionID:      sessionID,
+		Cwd:            options.Cwd,
+		Tools:          tools.names(),
+		Model:          stringPtr(model),
+		PermissionMode: options.PermissionMode,
+		CreatedAt:      start,
+	})
+
+	system := fmt.Sprintf("You are running inside docu-jarvis, a documentation tool. The working directory is %s; "+
+		"use the tools to read and edit files, with absolute paths or paths relative to it.", tools.cwd)
+	if options.SystemPrompt != nil {
+		system = *options.SystemPrompt
+	}
+	if options.AppendSystemPrompt != nil {
+		system += "\n\n" + *options.AppendSystemPrompt
+	}
+
+	conversation := []apiMessage{{Role: "user", Content: []interface{}{apiContent{Type: "text", Text: request.Prompt}}}}
+	var input, output, turns int
+	var apiTime time.Duration
+	var result string
+	subtype := "success"
+
+	for {
+		if turns == maxTurns {
+			subtype = "error_max_turns"
+			break
+		}
+		turns++
+
+		body := map[string]interface{}{
+			"max_tokens": maxTokens(ctx),
+			"system":     system,
+			"messages":   conversation,
+		}
+		if defs := tools.definitions(); len(defs) > 0 {
+			body["tools"] = defs
+		}
+
+		sent := time.Now()
+		raw, err := p.transport.send(ctx, model, body)
+		apiTime += time.Since(sent)
+		if err != nil {
+			return err
+		}
+		var resp apiResponse
+		if err := json.Unmarshal(raw, &resp); err != nil {
+			return fmt.Errorf("%s returned an invalid response: %w", p.name, err)
+		}
+		input += resp.Usage.InputTokens
+		output += resp.Usage.OutputTokens
+
+		var blocks []claudecode.ContentBlock
+		var content []interface{}
+		var text strings.Builder
+		var uses []*claudecode.ToolUseBlock
+		for _, c := range resp.Content {
+			switch c.Type {
+			case "text":
+				blocks = append(blocks, &claudecode.TextBlock{Text: c.Text})
+				text.WriteString(c.Text)
+			case "tool_use":
+				if c.Input == nil {
+					c.Input = map[string]interface{}{}
+				}
+				use := &claudecode.ToolUseBlock{ID: c.ID, Name: c.Name, Input: c.Input}
+				blocks = append(blocks, use)
+				uses = append(uses, use)
+			default:
+				continue
+			}
+			content = append(content, c)
+		}
+		emit(&claudecode.AssistantMessage{ContentBlocks: blocks, SessionID: sessionID, CreatedAt: time.Now()})
+		if len(content) > 0 {
+			conversation = append(conversation, apiMessage{Role: "assistant", Content: content})
+		}
+
+		if resp.StopReason != "tool_use" || len(uses) == 0 {
+			result = strings.TrimSpace(text.String())
+			break
+		}
+
+		var results []claudecode.ContentBlock
+		var resultContent []interface{}
+		for _, use := range uses {
+			out, isErr := tools.run(use.Name, use.Input)
+			results = append(results, &claudecode.ToolResultBlock{ToolUseID: use.ID, Content: out, IsError: isErr})
+			resultContent = append(resultContent, map[string]interface{}{
+				"type":        "tool_result",
+				"tool_use_id": use.ID,
+				"content":     out,
+				"is_error":    isErr,
+			})
+		}
+		emit(&claudecode.UserMessage{ContentBlocks: results, SessionID: sessionID, CreatedAt: time.Now()})
+		conversation = append(conversation, apiMessage{Role: "user", Content: resultContent})
+	}
+
+	var cost *float64
+	if p.free {
+		cost = new(float64)
+	}
+	emit(&claudecode.ResultMessage{
+		Subtype:       subtype,
+		DurationMs:    int(time.Since(start).Milliseconds()),
+		DurationAPIMs: int(apiTime.Milliseconds()),
+		IsError:       subtype != "success",
+		NumTurns:      turns,
+		SessionID:     sessionID,
+		Usage:         &claudecode.Usage{InputTokens: input, OutputTokens: output},
+		Result:        stringPtr(result),
+		TotalCostUSD:  cost,
+		CreatedAt:     time.Now(),
+	})
+	return nil
+}
+
+var apiClient = &http.Client{Timeout: apiTimeout}
+
+// postAPI posts a JSON body with the headers and returns the response body,
+// or an error naming the provider, the status, and the API's message.
+func postAPI(ctx context.Context, provider, endpoint string, body []byte, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s request: %w", provider, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request failed: %w", provider, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s response: %w", provider, err)
+	}
+	if resp.StatusCode >= 300 {
+		// Anthropic and Vertex nest the error, Bedrock has a plain message
+		var apiErr struct {
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(data, &apiErr)
+		message := firstSet(apiErr.Error.Message, apiErr.Message, strings.TrimSpace(string(data)))
+		kind := firstSet(apiErr.Error.Type, resp.Header.Get("X-Amzn-Errortype"))
+		if kind != "" {
+			return nil, fmt.Errorf("%s API error (%d %s): %s", provider, resp.StatusCode, kind, redact.String(message))
+		}
+		return nil, fmt.Errorf("%s API error (%d): %s", provider, resp.StatusCode, redact.String(message))
+	}
+	return data, nil
+}
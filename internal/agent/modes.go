@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"sync"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+)
+
+// Modes name the kinds of requests whose model and limits can be configured
+// apart, in [modes.<name>] sections of the config.
+const (
+	ModeUpdateDocs      = "update-docs"
+	ModeWriteDocs       = "write-docs"
+	ModeCheckExisting   = "check-existing-docs" // whether write-docs topics are documented already
+	ModeAuditDocs       = "audit-docs"
+	ModeDocsGap         = "docs-gap"
+	ModeLintDocs        = "lint-docs"
+	ModeDebug           = "debug"
+	ModeExplain         = "explain"
+	ModeReviewCode      = "review-code" // check-staging and review-pr
+	ModeDocsImpact      = "docs-impact"
+	ModeReviewChecklist = "review-checklist"
+	ModeCheckCommits    = "check-commits"
+	ModeSquashSummary   = "squash-summary"
+	ModeChangelog       = "changelog"
+	ModeReviewFeedback  = "review-feedback" // learning from review comments
+	ModeInferStandards  = "infer-standards"
+	ModeWriteTests      = "write-tests"
+	ModeWriteAPIDocs    = "write-api-docs"
+	ModeCompareJudge    = "compare-judge" // judging update-docs -compare variants
+	ModeTriage          = "triage"
+)
+
+// ModeConfig overrides the model and limits of one mode's requests. Zero
+// values keep the request's own.
+type ModeConfig struct {
+	Model     string // in the provider's naming
+	MaxTurns  int
+	MaxTokens int // per response; only the API providers can limit it
+}
+
+var (
+	modeMu      sync.Mutex
+	modeConfigs map[string]ModeConfig
+)
+
+// SetModeConfigs makes the requests of each mode use its config.
+func SetModeConfigs(configs map[string]ModeConfig) {
+	modeMu.Lock()
+	defer modeMu.Unlock()
+	modeConfigs = configs
+}
+
+func modeConfig(mode string) ModeConfig {
+	modeMu.Lock()
+	defer modeMu.Unlock()
+	return modeConfigs[mode]
+}
+
+// SetModel makes every request of the agent use model, whatever its mode
+// config, for comparing models on the same input.
+func (a *Agent) SetModel(model string) {
+	a.model = model
+}
+
+type maxTokensKey struct{}
+
+// withMode applies the config of mode, and the agent's model, to the
+// request's options, and returns ctx carrying its token limit.
+func (a *Agent) withMode(ctx context.Context, mode string, request *claudecode.QueryRequest) context.Context {
+	cfg := modeConfig(mode)
+	if a.model != "" {
+		cfg.Model = a.model
+	}
+	if cfg == (ModeConfig{}) {
+		return ctx
+	}
+
+	options := claudecode.Options{}
+	if request.Options != nil {
+		options = *request.Options
+	}
+	if cfg.Model != "" {
+		options.Model = stringPtr(cfg.Model)
+	}
+	if cfg.MaxTurns > 0 {
+		options.MaxTurns = intPtr(cfg.MaxTurns)
+	}
+	request.Options = &options
+
+	if cfg.MaxTokens > 0 {
+		ctx = context.WithValue(ctx, maxTokensKey{}, cfg.MaxTokens)
+	}
+	return ctx
+}
+
+// maxTokens returns the token limit of a response to the request of ctx,
+// apiMaxTokens unless its mode sets one.
+func maxTokens(ctx context.Context) int {
+	if n, ok := ctx.Value(maxTokensKey{}).(int); ok {
+		return n
+	}
+	return apiMaxTokens
+}
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PastCase is a bug debugged before in the same repository, with the commit
+// debug blamed and whether the user confirmed it.
+type PastCase struct {
+	Bug         string
+	Commit      string
+	Subject     string
+	Explanation string
+	Files       []string
+	Confirmed   bool
+}
+
+// maxPastCaseFiles is how many of a past culprit's files the prompt lists.
+const maxPastCaseFiles = 5
+
+// SetPastCases sets the similar bugs debugged before, which the debug prompts
+// mention as hints about where such bugs come from.
+func (a *Agent) SetPastCases(cases []PastCase) {
+	a.pastCases = cases
+}
+
+func (a *Agent) pastCasesPrompt() string {
+	if len(a.pastCases) == 0 {
+		return ""
+	}
+	var prompt strings.Builder
+	prompt.WriteString("\n\nSimilar bugs were debugged in this repository before. Use them as hints about which parts of the code such bugs come from, not as evidence about this commit; judge it on its own diff:\n")
+	for _, c := range a.pastCases {
+		outcome := "was confirmed as the cause"
+		if !c.Confirmed {
+			outcome = "was blamed, but the user said it was NOT the cause"
+		}
+		fmt.Fprintf(&prompt, "- Bug: %s\n  Commit %.8s (%s) %s.", c.Bug, c.Commit, c.Subject, outcome)
+		if c.Explanation != "" {
+			fmt.Fprintf(&prompt, " Analysis: %s", c.Explanation)
+		}
+		if len(c.Files) > 0 {
+			files := c.Files
+			if len(files) > maxPastCaseFiles {
+				files = files[:maxPastCaseFiles]
+			}
+			fmt.Fprintf(&prompt, " Files it changed: %s", strings.Join(files, ", "))
+		}
+		prompt.WriteString("\n")
+	}
+	return prompt.String()
+}
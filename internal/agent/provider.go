@@ -0,0 +1,159 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+
+	"github.com/udemy/docu-jarvis-cli/internal/netguard"
+	"github.com/udemy/docu-jarvis-cli/internal/settings"
+)
+
+// Model providers, for the provider setting.
+const (
+	ProviderClaudeCode = "claude-code" // the Claude Code CLI, the default
+	ProviderAnthropic  = "anthropic"   // the Anthropic Messages API
+	ProviderBedrock    = "bedrock"     // Claude on AWS Bedrock
+	ProviderVertex     = "vertex"      // Claude on Google Cloud Vertex AI
+	ProviderLocal      = "local"       // a local model, see local.go
+)
+
+// Provider runs Claude requests. Claude Code runs the tools of a request
+// itself; the API providers run them in docu-jarvis (see tools.go), with the
+// same allowed tools, path rules, and edit approval.
+type Provider interface {
+	// Name is the provider setting that selects it.
+	Name() string
+	// Check fails when the provider cannot be used in this run, e.g. when
+	// network access is disabled or credentials are missing.
+	Check() error
+	Query(ctx context.Context, request claudecode.QueryRequest) ([]claudecode.Message, error)
+	// QueryStream sends the messages of the request as they arrive. The
+	// message channel is closed when the request is done.
+	QueryStream(ctx context.Context, request claudecode.QueryRequest) (<-chan claudecode.Message, <-chan error)
+}
+
+// ProviderConfig selects and configures a Provider. Empty fields fall back to
+// the environment variables Claude Code uses, then to the provider's default.
+type ProviderConfig struct {
+	Name    string // one of the Provider* constants, "" for Claude Code
+	Model   string
+	APIKey  string // Anthropic API key
+	Region  string // AWS or Google Cloud region
+	Project string // Google Cloud project
+	BaseURL string // OpenAI-compatible endpoint of the local model
+	Tools   string // tool mode of the local model, LocalToolsNative or LocalToolsPrompt
+}
+
+// Configure selects the provider and the per-mode settings of s, as every
+// docu-jarvis command does at startup. It returns the modes whose max_tokens
+// the provider ignores.
+func Configure(s *settings.Settings) ([]string, error) {
+	region := s.AWSRegion
+	if s.Provider == ProviderVertex {
+		region = s.VertexRegion
+	}
+	p, err := NewProvider(ProviderConfig{
+		Name:    s.Provider,
+		Model:   s.Model,
+		APIKey:  s.AnthropicAPIKey,
+		Region:  region,
+		Project: s.VertexProject,
+		BaseURL: s.LocalURL,
+		Tools:   s.LocalTools,
+	})
+	if err != nil {
+		return nil, err
+	}
+	SetProvider(p)
+
+	var ignored []string
+	modes := make(map[string]ModeConfig, len(s.Modes))
+	for name, m := range s.Modes {
+		modes[name] = ModeConfig{Model: m.Model, MaxTurns: m.MaxTurns, MaxTokens: m.MaxTokens}
+		if m.MaxTokens > 0 && p.Name() == ProviderClaudeCode {
+			ignored = append(ignored, name)
+		}
+	}
+	SetModeConfigs(modes)
+	sort.Strings(ignored)
+	return ignored, nil
+}
+
+// NewProvider returns the provider for the config.
+func NewProvider(cfg ProviderConfig) (Provider, error) {
+	switch cfg.Name {
+	case "", ProviderClaudeCode:
+		return claudeCodeProvider{model: cfg.Model}, nil
+	case ProviderAnthropic:
+		return newMessagesProvider(ProviderAnthropic, firstSet(cfg.Model, defaultAnthropicModel), newAnthropicTransport(cfg)), nil
+	case ProviderBedrock:
+		return newMessagesProvider(ProviderBedrock, firstSet(cfg.Model, defaultBedrockModel), newBedrockTransport(cfg)), nil
+	case ProviderVertex:
+		return newMessagesProvider(ProviderVertex, firstSet(cfg.Model, defaultVertexModel), newVertexTransport(cfg)), nil
+	case ProviderLocal:
+		return newLocalProvider(cfg)
+	}
+	return nil, fmt.Errorf("invalid provider %q (must be %s, %s, %s, %s, or %s)",
+		cfg.Name, ProviderClaudeCode, ProviderAnthropic, ProviderBedrock, ProviderVertex, ProviderLocal)
+}
+
+var (
+	providerMu      sync.Mutex
+	defaultProvider Provider = claudeCodeProvider{}
+)
+
+// SetProvider makes the agents created from now on use p.
+func SetProvider(p Provider) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	defaultProvider = p
+}
+
+func currentProvider() Provider {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	return defaultProvider
+}
+
+// claudeCodeProvider runs requests through the Claude Code CLI.
+type claudeCodeProvider struct {
+	model string // "" for Claude Code's own default
+}
+
+func (p claudeCodeProvider) Name() string {
+	return ProviderClaudeCode
+}
+
+func (p claudeCodeProvider) Check() error {
+	return netguard.CheckClaude()
+}
+
+func (p claudeCodeProvider) Query(ctx context.Context, request claudecode.QueryRequest) ([]claudecode.Message, error) {
+	return claudecode.QueryWithRequest(ctx, p.withModel(request))
+}
+
+func (p claudeCodeProvider) QueryStream(ctx context.Context, request claudecode.QueryRequest) (<-chan claudecode.Message, <-chan error) {
+	return claudecode.QueryStreamWithRequest(ctx, p.withModel(request))
+}
+
+func (p claudeCodeProvider) withModel(request claudecode.QueryRequest) claudecode.QueryRequest {
+	if p.model != "" && request.Options != nil && request.Options.Model == nil {
+		options := *request.Options
+		options.Model = stringPtr(p.model)
+		request.Options = &options
+	}
+	return request
+}
+
+func firstSet(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -2,8 +2,10 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 
 	claudecode "github.com/yukifoo/claude-code-sdk-go"
 )
@@ -14,10 +16,120 @@ type QualityReview struct {
 	FullResponse     string
 }
 
+// QualityReportSchemaVersion is bumped whenever QualityReport changes in a
+// way that could break consumers parsing it.
+const QualityReportSchemaVersion = 1
+
+const qualityJSONInstructions = `
+
+IMPORTANT: Instead of the tag format described above, respond with ONLY a JSON object in this exact format (no other text):
+{
+  "compliance_status": "COMPLIANT" | "MINOR_ISSUES" | "MAJOR_ISSUES" | "NON_COMPLIANT",
+  "summary": "one or two sentences describing the overall assessment",
+  "findings": [
+    {
+      "file": "path/to/file as shown in the diff",
+      "line": 42,
+      "severity": "critical" | "major" | "minor",
+      "standard": "the standard that is violated",
+      "issue": "what is wrong",
+      "recommendation": "how to fix it"
+    }
+  ]
+}
+Use the line number in the new version of the file, or 0 if the finding is not tied to a line. Quote "standard" exactly as it is written in the code standards, so findings can be matched across reviews. Use an empty findings array when there are no issues.`
+
+var complianceStatuses = map[string]bool{
+	"COMPLIANT":     true,
+	"MINOR_ISSUES":  true,
+	"MAJOR_ISSUES":  true,
+	"NON_COMPLIANT": true,
+}
+
+var findingSeverities = map[string]bool{
+	"critical": true,
+	"major":    true,
+	"minor":    true,
+}
+
+type QualityFinding struct {
+	File           string `json:"file"`
+	Line           int    `json:"line"`
+	Severity       string `json:"severity"`
+	Standard       string `json:"standard"`
+	Issue          string `json:"issue"`
+	Recommendation string `json:"recommendation"`
+	Votes          int    `json:"votes,omitempty"` // reviews that reported it, with -passes
+}
+
+// QualityReport is the machine-readable form of a staged code review.
+// Compliant is false only for MAJOR_ISSUES and NON_COMPLIANT, matching the
+// statuses the review prompt says must be fixed before merging.
+type QualityReport struct {
+	SchemaVersion    int              `json:"schema_version"`
+	ComplianceStatus string           `json:"compliance_status"`
+	Compliant        bool             `json:"compliant"`
+	Summary          string           `json:"summary"`
+	Findings         []QualityFinding `json:"findings"`
+	Baselined        int              `json:"baselined,omitempty"` // pre-existing findings left out by Keep
+	Passes           int              `json:"passes,omitempty"`    // independent reviews merged by Consensus
+	Union            []QualityFinding `json:"union,omitempty"`     // findings any of the Passes reported
+	DocsImpact       *DocsImpact      `json:"docs_impact,omitempty"`
+}
+
+// Keep leaves out the findings keep rejects, counting them as baselined. When
+// it leaves any out, the report is rated again on the findings that are left:
+// critical ones make it NON_COMPLIANT, major ones MAJOR_ISSUES, and minor ones
+// MINOR_ISSUES.
+func (r *QualityReport) Keep(keep func(QualityFinding) bool) {
+	if r.Union != nil {
+		union := []QualityFinding{}
+		for _, finding := range r.Union {
+			if keep(finding) {
+				union = append(union, finding)
+			}
+		}
+		r.Union = union
+	}
+
+	kept := []QualityFinding{}
+	for _, finding := range r.Findings {
+		if keep(finding) {
+			kept = append(kept, finding)
+		}
+	}
+	if len(kept) == len(r.Findings) {
+		return
+	}
+
+	r.Baselined += len(r.Findings) - len(kept)
+	r.Findings = kept
+	r.rate()
+}
+
+// rate sets the compliance status from the most severe finding.
+func (r *QualityReport) rate() {
+	severities := make(map[string]bool)
+	for _, finding := range r.Findings {
+		severities[finding.Severity] = true
+	}
+	switch {
+	case severities["critical"]:
+		r.ComplianceStatus = "NON_COMPLIANT"
+	case severities["major"]:
+		r.ComplianceStatus = "MAJOR_ISSUES"
+	case severities["minor"]:
+		r.ComplianceStatus = "MINOR_ISSUES"
+	default:
+		r.ComplianceStatus = "COMPLIANT"
+	}
+	r.Compliant = r.ComplianceStatus == "COMPLIANT" || r.ComplianceStatus == "MINOR_ISSUES"
+}
+
 func (a *Agent) ReviewStagedCode(ctx context.Context, stagedCode, codeStandards string) (*QualityReview, error) {
-	a.logger.Printf("Reviewing staged code against standards")
-	a.logger.Printf("Staged code length: %d characters", len(stagedCode))
-	a.logger.Printf("Code standards length: %d characters", len(codeStandards))
+	a.logger.Infof("Reviewing staged code against standards")
+	a.logger.Debugf("Staged code length: %d characters", len(stagedCode))
+	a.logger.Debugf("Code standards length: %d characters", len(codeStandards))
 
 	prompt := fmt.Sprintf(`%s
 
@@ -45,9 +157,9 @@ Here are the code standards that the staged code must comply with:
 		},
 	}
 
-	messages, err := claudecode.QueryWithRequest(ctx, request)
+	messages, err := a.query(ctx, ModeReviewCode, request)
 	if err != nil {
-		a.logger.Printf("Error reviewing staged code: %v", err)
+		a.logger.Errorf("Error reviewing staged code: %v", err)
 		return nil, fmt.Errorf("review error: %w", err)
 	}
 
@@ -87,8 +199,211 @@ Here are the code standards that the staged code must comply with:
 		FullResponse:     fullResponse.String(),
 	}
 
-	a.logger.Printf("Quality review completed. Compliance: %s", complianceStatus)
+	a.logger.Infof("Quality review completed. Compliance: %s", complianceStatus)
 
 	return review, nil
 }
 
+// ReviewStagedCodeJSON is ReviewStagedCode with the result returned as a
+// QualityReport instead of free-form text.
+func (a *Agent) ReviewStagedCodeJSON(ctx context.Context, stagedCode, codeStandards string) (*QualityReport, error) {
+	a.logger.Infof("Reviewing staged code against standards (structured output)")
+	a.logger.Debugf("Staged code length: %d characters", len(stagedCode))
+
+	prompt := fmt.Sprintf(`%s
+
+Here is the code currently in git staging that needs to be reviewed:
+
+<staged_code>
+%s
+</staged_code>
+
+Here are the code standards that the staged code must comply with:
+
+<code_standards>
+%s
+</code_standards>%s`, a.systemPrompt, stagedCode, codeStandards, qualityJSONInstructions)
+
+	request := claudecode.QueryRequest{
+		Prompt: prompt,
+		Options: &claudecode.Options{
+			AllowedTools:   []string{"Read"},
+			PermissionMode: stringPtr("acceptEdits"),
+			Cwd:            stringPtr(a.folder),
+			OutputFormat:   outputFormatPtr(claudecode.OutputFormatJSON),
+			Verbose:        boolPtr(false),
+			MaxTurns:       intPtr(10),
+		},
+	}
+
+	messages, err := a.query(ctx, ModeReviewCode, request)
+	if err != nil {
+		a.logger.Errorf("Error reviewing staged code: %v", err)
+		return nil, fmt.Errorf("review error: %w", err)
+	}
+
+	var lastErr error
+	for _, candidate := range jsonObjectCandidates(resultText(messages)) {
+		report, err := decodeQualityReport(candidate)
+		if err == nil {
+			a.logger.Infof("Quality review completed. Compliance: %s, findings: %d", report.ComplianceStatus, len(report.Findings))
+			return report, nil
+		}
+		lastErr = err
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no JSON object found")
+	}
+	a.logger.Errorf("Could not parse structured quality review: %v", lastErr)
+	return nil, fmt.Errorf("Claude did not return expected JSON response: %w", lastErr)
+}
+
+func decodeQualityReport(data string) (*QualityReport, error) {
+	var report QualityReport
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	report.ComplianceStatus = strings.ToUpper(strings.TrimSpace(report.ComplianceStatus))
+	if !complianceStatuses[report.ComplianceStatus] {
+		return nil, fmt.Errorf("invalid compliance_status: %q", report.ComplianceStatus)
+	}
+
+	for i := range report.Findings {
+		finding := &report.Findings[i]
+		finding.Severity = strings.ToLower(strings.TrimSpace(finding.Severity))
+		if !findingSeverities[finding.Severity] {
+			return nil, fmt.Errorf("invalid severity for finding %d: %q", i, finding.Severity)
+		}
+		if finding.Line < 0 {
+			finding.Line = 0
+		}
+	}
+
+	if report.Findings == nil {
+		report.Findings = []QualityFinding{}
+	}
+
+	report.SchemaVersion = QualityReportSchemaVersion
+	report.Compliant = report.ComplianceStatus == "COMPLIANT" || report.ComplianceStatus == "MINOR_ISSUES"
+	return &report, nil
+}
+
+// consensusLineSlack is how far apart two reviews may place the same finding.
+const consensusLineSlack = 3
+
+var severityRank = map[string]int{"minor": 1, "major": 2, "critical": 3}
+
+// ReviewStagedCodeConsensus runs passes independent structured reviews at once
+// and merges them with Consensus, so a finding only one review makes up does
+// not fail the change.
+func (a *Agent) ReviewStagedCodeConsensus(ctx context.Context, stagedCode, codeStandards string, passes int) (*QualityReport, error) {
+	a.logger.Infof("Running %d independent reviews of the staged code", passes)
+
+	reports := make([]*QualityReport, passes)
+	errs := make([]error, passes)
+	var wg sync.WaitGroup
+	for i := 0; i < passes; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			reports[i], errs[i] = a.ReviewStagedCodeJSON(ctx, stagedCode, codeStandards)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("review %d of %d: %w", i+1, passes, err)
+		}
+	}
+
+	report := Consensus(reports)
+	a.logger.Infof("Consensus review completed. Compliance: %s, findings: %d of %d", report.ComplianceStatus, len(report.Findings), len(report.Union))
+	return report, nil
+}
+
+// Consensus merges independent reviews of the same change. Findings match
+// when they are in the same file, break the same standard, and are at most
+// consensusLineSlack lines apart. The report keeps the findings more than half
+// of the reviews agree on, rated again on those alone, and lists every finding
+// in Union, with Votes counting the reviews that reported each.
+func Consensus(reports []*QualityReport) *QualityReport {
+	type group struct {
+		finding    QualityFinding
+		severities map[string]int
+		voters     map[int]bool
+	}
+	var groups []*group
+
+	for i, report := range reports {
+		for _, finding := range report.Findings {
+			var match *group
+			for _, g := range groups {
+				if !g.voters[i] && sameFinding(g.finding, finding) {
+					match = g
+					break
+				}
+			}
+			if match == nil {
+				match = &group{finding: finding, severities: make(map[string]int), voters: make(map[int]bool)}
+				groups = append(groups, match)
+			}
+			match.severities[finding.Severity]++
+			match.voters[i] = true
+		}
+	}
+
+	merged := &QualityReport{
+		SchemaVersion: QualityReportSchemaVersion,
+		Passes:        len(reports),
+		Findings:      []QualityFinding{},
+		Union:         []QualityFinding{},
+	}
+	for _, g := range groups {
+		finding := g.finding
+		finding.Votes = len(g.voters)
+		// The severity most reviews gave, the more severe one on a tie
+		for severity, count := range g.severities {
+			best := g.severities[finding.Severity]
+			if count > best || (count == best && severityRank[severity] > severityRank[finding.Severity]) {
+				finding.Severity = severity
+			}
+		}
+		merged.Union = append(merged.Union, finding)
+		if finding.Votes*2 > len(reports) {
+			merged.Findings = append(merged.Findings, finding)
+		}
+	}
+	merged.rate()
+
+	for _, report := range reports {
+		if report.ComplianceStatus == merged.ComplianceStatus {
+			merged.Summary = report.Summary
+			break
+		}
+	}
+	return merged
+}
+
+func sameFinding(a, b QualityFinding) bool {
+	if consensusFile(a.File) != consensusFile(b.File) {
+		return false
+	}
+	if !strings.EqualFold(strings.Join(strings.Fields(a.Standard), " "), strings.Join(strings.Fields(b.Standard), " ")) {
+		return false
+	}
+	if a.Line == 0 || b.Line == 0 {
+		return a.Line == b.Line
+	}
+	distance := a.Line - b.Line
+	if distance < 0 {
+		distance = -distance
+	}
+	return distance <= consensusLineSlack
+}
+
+func consensusFile(file string) string {
+	return strings.TrimPrefix(strings.TrimSpace(file), "b/")
+}
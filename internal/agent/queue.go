@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Batch orders: the order the items are started in, which with a
+// concurrency limit is the order their results land in.
+const (
+	OrderGiven    = "given"    // as listed on the command line, or in the docs directory
+	OrderSmallest = "smallest" // smallest documents first
+	OrderStale    = "stale"    // documents changed longest ago first
+)
+
+// BatchOptions controls how the items of a batch are scheduled.
+type BatchOptions struct {
+	Order       string
+	Concurrency int // items in flight at once, 0 for all of them
+	// LastChanged returns when each path was last changed, for OrderStale.
+	// Paths that are missing have never been committed.
+	LastChanged func(paths []string) (map[string]time.Time, error)
+	// Dashboard shows the progress on a full-screen view when stdout is a
+	// terminal, instead of a line per started and finished item.
+	Dashboard bool
+}
+
+// ParseOrder validates a batch order, defaulting to OrderGiven.
+func ParseOrder(order string) (string, error) {
+	switch order {
+	case "":
+		return OrderGiven, nil
+	case OrderGiven, OrderSmallest, OrderStale:
+		return order, nil
+	}
+	return "", fmt.Errorf("invalid order %q (must be %s, %s, or %s)", order, OrderGiven, OrderSmallest, OrderStale)
+}
+
+func (a *Agent) SetBatchOptions(opts BatchOptions) {
+	a.batchOpts = opts
+}
+
+// inFlight describes how many items run at once, e.g. "4 at a time".
+func (a *Agent) inFlight(items int) string {
+	if limit := a.batchOpts.Concurrency; limit > 0 && limit < items {
+		return fmt.Sprintf("%d at a time", limit)
+	}
+	return "concurrently"
+}
+
+// orderFiles returns the documents in the configured order. When the order
+// cannot be determined the given order is kept.
+func (a *Agent) orderFiles(paths []string) []string {
+	ordered := append([]string(nil), paths...)
+
+	switch a.batchOpts.Order {
+	case OrderSmallest:
+		sizes := make(map[string]int64, len(paths))
+		for _, path := range paths {
+			if info, err := os.Stat(path); err == nil {
+				sizes[path] = info.Size()
+			}
+		}
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return sizes[ordered[i]] < sizes[ordered[j]]
+		})
+		a.status("Order: smallest documents first\n")
+
+	case OrderStale:
+		if a.batchOpts.LastChanged == nil {
+			return ordered
+		}
+		changed, err := a.batchOpts.LastChanged(paths)
+		if err != nil {
+			a.status("Warning: could not order documents by staleness, keeping the given order: %v\n", err)
+			return ordered
+		}
+		// Uncommitted documents are new, so they go last
+		sort.SliceStable(ordered, func(i, j int) bool {
+			ti, iok := changed[ordered[i]]
+			tj, jok := changed[ordered[j]]
+			if iok != jok {
+				return iok
+			}
+			return ti.Before(tj)
+		})
+		a.status("Order: documents changed longest ago first\n")
+
+	default:
+		return ordered
+	}
+
+	a.logger.Infof("Batch order (%s): %v", a.batchOpts.Order, ordered)
+	return ordered
+}
+
+// forEach calls work for the items 0..n-1, starting them in order, with at
+// most the configured number running at once, and returns when all are done.
+func (a *Agent) forEach(n int, work func(i int)) {
+	limit := a.batchOpts.Concurrency
+	if limit <= 0 || limit > n {
+		limit = n
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < limit; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				work(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
@@ -0,0 +1,161 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+
+	"github.com/udemy/docu-jarvis-cli/internal/netguard"
+)
+
+// fileTools are the tools whose access can be limited with path rules; the
+// search tools (Grep, Glob, LS) follow the Read rules.
+var fileTools = map[string]bool{"Read": true, "Edit": true, "Write": true, "MultiEdit": true}
+
+// sensitivePaths are denied outright, on top of the workspace allow rules, as
+// they hold credentials (including docu-jarvis's own config).
+var sensitivePaths = []string{"~/.ssh/**", "~/.aws/**", "~/.gnupg/**", "~/.config/gh/**", "~/.docu-jarvis/**", "~/.kube/**", "~/.netrc"}
+
+// SetEditRoots limits the edit tools to these directories, absolute paths,
+// instead of the docs directories.
+func (a *Agent) SetEditRoots(roots []string) {
+	a.editRoots = roots
+}
+
+// sandbox limits the request's file tools to the agent's workspace: file
+// tools get path rules for the workspace instead of blanket access, the edit
+// tools only for the docs directories (or the edit roots), and credential
+// directories are denied.
+func (a *Agent) sandbox(request *claudecode.QueryRequest) {
+	if request.Options == nil {
+		return
+	}
+
+	var allowed []string
+	for _, tool := range request.Options.AllowedTools {
+		if !fileTools[tool] {
+			allowed = append(allowed, tool)
+			continue
+		}
+		roots := a.workspaceRoots()
+		if tool != "Read" {
+			roots = a.editDirs()
+		}
+		for _, root := range roots {
+			allowed = append(allowed, pathRule(tool, root))
+		}
+	}
+	request.Options.AllowedTools = allowed
+
+	home, _ := os.UserHomeDir()
+	for _, pattern := range sensitivePaths {
+		abs := strings.Replace(strings.TrimSuffix(pattern, "/**"), "~", home, 1)
+		if home != "" && a.inWorkspace(abs) {
+			continue
+		}
+		request.Options.DisallowedTools = append(request.Options.DisallowedTools,
+			fmt.Sprintf("Read(%s)", pattern), fmt.Sprintf("Edit(%s)", pattern))
+	}
+
+	if netguard.Disabled() {
+		request.Options.DisallowedTools = append(request.Options.DisallowedTools, "WebFetch", "WebSearch")
+	}
+}
+
+// pathRule is the rule giving tool access to the tree under root, in Claude
+// Code's form: forward slashes behind the // absolute prefix, so a Windows
+// root becomes Read(//C:/repo/**).
+func pathRule(tool, root string) string {
+	return fmt.Sprintf("%s(//%s/**)", tool, strings.TrimPrefix(filepath.ToSlash(root), "/"))
+}
+
+// workspaceRoots is the workspace folder, plus its resolved path when it goes
+// through a symlink (e.g. /tmp on macOS).
+func (a *Agent) workspaceRoots() []string {
+	roots := []string{filepath.Clean(a.folder)}
+	if resolved, err := filepath.EvalSymlinks(a.folder); err == nil && resolved != roots[0] {
+		roots = append(roots, resolved)
+	}
+	return roots
+}
+
+// editDirs are the directories the edit tools may write to, plus their
+// resolved paths when they go through a symlink.
+func (a *Agent) editDirs() []string {
+	dirs := a.editRoots
+	if len(dirs) == 0 {
+		dirs = a.docsDirs
+	}
+	var roots []string
+	for _, dir := range dirs {
+		roots = append(roots, filepath.Clean(dir))
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil && resolved != filepath.Clean(dir) {
+			roots = append(roots, resolved)
+		}
+	}
+	return roots
+}
+
+func (a *Agent) inWorkspace(path string) bool {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	for _, root := range a.workspaceRoots() {
+		if within(root, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// within reports whether path is dir or inside it.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// auditToolUse logs every tool call in messages that targeted a path outside
+// the workspace, and whether Claude Code let it through.
+func (a *Agent) auditToolUse(messages []claudecode.Message) {
+	failed := make(map[string]bool)
+	for _, msg := range messages {
+		for _, block := range msg.Content() {
+			if result, ok := block.(*claudecode.ToolResultBlock); ok && result.IsError {
+				failed[result.ToolUseID] = true
+			}
+		}
+	}
+
+	for _, msg := range messages {
+		for _, block := range msg.Content() {
+			use, ok := block.(*claudecode.ToolUseBlock)
+			if !ok {
+				continue
+			}
+			for _, key := range []string{"file_path", "path", "notebook_path"} {
+				path, ok := use.Input[key].(string)
+				if !ok || path == "" {
+					continue
+				}
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(a.folder, path)
+				}
+				if a.inWorkspace(path) {
+					continue
+				}
+
+				outcome := "blocked"
+				if !failed[use.ID] {
+					outcome = "NOT blocked"
+				}
+				a.logger.Warn("Access outside workspace", "tool", use.Name, "path", path, "workspace", a.folder, "outcome", outcome)
+				if outcome != "blocked" {
+					fmt.Fprintf(os.Stderr, "Warning: %s accessed %s outside the workspace\n", use.Name, path)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"path/filepath"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+)
+
+// recordSources records the files outside the docs directories that Claude
+// read in messages as the sources of docs, for their freshness stamps.
+func (a *Agent) recordSources(docs []string, messages []claudecode.Message) {
+	var sources []string
+	seen := make(map[string]bool)
+	for _, msg := range messages {
+		for _, block := range msg.Content() {
+			use, ok := block.(*claudecode.ToolUseBlock)
+			if !ok || use.Name != "Read" {
+				continue
+			}
+			path, _ := use.Input["file_path"].(string)
+			if path == "" {
+				continue
+			}
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(a.folder, path)
+			}
+			path = filepath.Clean(path)
+			if seen[path] || !within(a.folder, path) || a.inDocsDirs(path) {
+				continue
+			}
+			seen[path] = true
+			sources = append(sources, path)
+		}
+	}
+
+	a.outputMu.Lock()
+	defer a.outputMu.Unlock()
+	if a.sources == nil {
+		a.sources = make(map[string][]string)
+	}
+	for _, doc := range docs {
+		a.sources[filepath.Clean(doc)] = sources
+	}
+}
+
+func (a *Agent) inDocsDirs(path string) bool {
+	for _, dir := range a.docsDirs {
+		if within(dir, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// DocSources returns the source files Claude read for each doc it updated or
+// wrote, as absolute paths, by doc.
+func (a *Agent) DocSources() map[string][]string {
+	a.outputMu.Lock()
+	defer a.outputMu.Unlock()
+	sources := make(map[string][]string, len(a.sources))
+	for doc, files := range a.sources {
+		sources[doc] = files
+	}
+	return sources
+}
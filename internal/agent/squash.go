@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+)
+
+// ticketPattern matches Jira-style keys (PAY-123) and GitHub issue references (#42).
+var ticketPattern = regexp.MustCompile(`\b[A-Z][A-Z0-9]+-[0-9]+\b|#[0-9]+\b`)
+
+type SquashSummary struct {
+	Message      string
+	Tickets      []string
+	FullResponse string
+}
+
+// FindTicketRefs returns the unique ticket references in text, in the order
+// they first appear.
+func FindTicketRefs(text string) []string {
+	seen := make(map[string]bool)
+	var refs []string
+	for _, ref := range ticketPattern.FindAllString(text, -1) {
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+func (a *Agent) GenerateSquashSummary(ctx context.Context, branch string, commits []CommitMessage, conventions string) (*SquashSummary, error) {
+	a.logger.Infof("Generating squash summary for %d commits on branch %s", len(commits), branch)
+
+	var commitList strings.Builder
+	var allText strings.Builder
+	allText.WriteString(branch + "\n")
+	for _, commit := range commits {
+		commitList.WriteString(fmt.Sprintf("<commit hash=\"%s\">\n%s\n</commit>\n\n", commit.Hash, commit.Message))
+		allText.WriteString(commit.Message + "\n")
+	}
+
+	tickets := FindTicketRefs(allText.String())
+	ticketList := "(none found)"
+	if len(tickets) > 0 {
+		ticketList = strings.Join(tickets, ", ")
+	}
+
+	prompt := fmt.Sprintf(`%s
+
+Branch: %s
+
+Here are the commit message conventions:
+
+<conventions>
+%s
+</conventions>
+
+Ticket references found in the branch: %s
+
+Here are the commits on the branch, oldest first:
+
+<commits>
+%s</commits>`, a.systemPrompt, branch, conventions, ticketList, commitList.String())
+
+	request := claudecode.QueryRequest{
+		Prompt: prompt,
+		Options: &claudecode.Options{
+			AllowedTools:   []string{"Read", "Grep", "LS"},
+			PermissionMode: stringPtr("acceptEdits"),
+			Cwd:            stringPtr(a.folder),
+			OutputFormat:   outputFormatPtr(claudecode.OutputFormatJSON),
+			Verbose:        boolPtr(false),
+			MaxTurns:       intPtr(10),
+		},
+	}
+
+	messages, err := a.query(ctx, ModeSquashSummary, request)
+	if err != nil {
+		a.logger.Errorf("Error generating squash summary: %v", err)
+		return nil, fmt.Errorf("squash summary error: %w", err)
+	}
+
+	fullResponse := resultText(messages)
+
+	message := fullResponse
+	start := strings.Index(fullResponse, "<commit_message>")
+	end := strings.Index(fullResponse, "</commit_message>")
+	if start >= 0 && end > start {
+		message = strings.TrimSpace(fullResponse[start+16 : end])
+	}
+
+	a.logger.Infof("Squash summary generated, length: %d characters", len(message))
+
+	return &SquashSummary{
+		Message:      message,
+		Tickets:      tickets,
+		FullResponse: fullResponse,
+	}, nil
+}
@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+)
+
+// InferredStandards is a draft of code standards for a team that has none.
+type InferredStandards struct {
+	Standards []string `json:"standards"`
+	Summary   string   `json:"summary"`
+}
+
+// InferStandards drafts code standards from the conventions the codebase
+// follows and what its reviewers ask for in the given comments.
+func (a *Agent) InferStandards(ctx context.Context, comments []FeedbackComment) (*InferredStandards, error) {
+	a.logger.Infof("Inferring code standards from the codebase and %d review comments", len(comments))
+
+	reviews := "No review comments are available; base the standards on the codebase alone."
+	if len(comments) > 0 {
+		reviews = fmt.Sprintf("Here are recent comments from the team's code reviews:\n\n<comments>\n%s</comments>", formatComments(comments))
+	}
+
+	prompt := fmt.Sprintf(`%s
+
+%s`, a.systemPrompt, reviews)
+
+	request := claudecode.QueryRequest{
+		Prompt: prompt,
+		Options: &claudecode.Options{
+			AllowedTools:   []string{"Read", "Grep", "Glob", "LS"},
+			PermissionMode: stringPtr("acceptEdits"),
+			Cwd:            stringPtr(a.folder),
+			OutputFormat:   outputFormatPtr(claudecode.OutputFormatJSON),
+			Verbose:        boolPtr(false),
+			MaxTurns:       intPtr(30),
+		},
+	}
+
+	messages, err := a.query(ctx, ModeInferStandards, request)
+	if err != nil {
+		a.logger.Errorf("Error inferring standards: %v", err)
+		return nil, fmt.Errorf("standards inference error: %w", err)
+	}
+
+	var inferred *InferredStandards
+	for _, candidate := range jsonObjectCandidates(resultText(messages)) {
+		var parsed InferredStandards
+		if err := json.Unmarshal([]byte(candidate), &parsed); err == nil && len(parsed.Standards) > 0 {
+			inferred = &parsed
+			break
+		}
+	}
+
+	if inferred == nil {
+		a.logger.Errorf("Could not extract JSON from standards inference")
+		return nil, fmt.Errorf("Claude did not return expected JSON response")
+	}
+
+	var standards []string
+	for _, standard := range inferred.Standards {
+		if standard = strings.TrimSpace(standard); standard != "" {
+			standards = append(standards, standard)
+		}
+	}
+	inferred.Standards = standards
+
+	a.logger.Infof("Inferred %d code standards", len(inferred.Standards))
+	return inferred, nil
+}
@@ -0,0 +1,205 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+)
+
+// BatchSummarySchemaVersion is bumped whenever BatchSummary changes in a way
+// that could break consumers parsing it.
+const BatchSummarySchemaVersion = 1
+
+// BatchItem is the outcome of one file or topic of a batch run.
+type BatchItem struct {
+	Kind         string `json:"kind"` // "file" or "topic"
+	Name         string `json:"name"`
+	Status       string `json:"status"` // "succeeded" or "failed"
+	DurationMs   int64  `json:"duration_ms"`
+	Turns        int    `json:"turns"`
+	InputTokens  int    `json:"input_tokens"`
+	OutputTokens int    `json:"output_tokens"`
+	ErrorClass   string `json:"error_class,omitempty"`
+	Error        string `json:"error,omitempty"`
+	Summary      string `json:"summary,omitempty"` // what was changed, in one sentence
+}
+
+// BatchSummary is the machine-readable end-of-run summary of update-docs and
+// write-docs.
+type BatchSummary struct {
+	SchemaVersion int         `json:"schema_version"`
+	Command       string      `json:"command"`
+	Repository    string      `json:"repository,omitempty"`
+	RunID         string      `json:"run_id,omitempty"`
+	DryRun        bool        `json:"dry_run"`
+	Succeeded     int         `json:"succeeded"`
+	Failed        int         `json:"failed"`
+	Items         []BatchItem `json:"items"`
+}
+
+// NewBatchSummary counts the items and sorts them as the table shows them.
+func NewBatchSummary(command string, items []BatchItem) *BatchSummary {
+	summary := &BatchSummary{SchemaVersion: BatchSummarySchemaVersion, Command: command, Items: sortBatch(items)}
+	for _, item := range items {
+		if item.Status == "succeeded" {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+		}
+	}
+	if summary.Items == nil {
+		summary.Items = []BatchItem{}
+	}
+	return summary
+}
+
+// Batch returns the items of every batch the agent has run.
+func (a *Agent) Batch() []BatchItem {
+	return a.batch
+}
+
+// batchItem turns the result of one file or topic into a summary item.
+func batchItem(kind string, result ProcessResult) BatchItem {
+	item := BatchItem{
+		Kind:         kind,
+		Name:         result.FileName,
+		Status:       "succeeded",
+		DurationMs:   result.Duration.Milliseconds(),
+		Turns:        result.Turns,
+		InputTokens:  result.InputTokens,
+		OutputTokens: result.OutputTokens,
+		Summary:      result.Summary,
+	}
+	if result.Error != nil {
+		item.Status = "failed"
+		item.ErrorClass = errorClass(result.Error)
+		item.Error = result.Error.Error()
+	}
+	return item
+}
+
+// finishBatch keeps a batch's items for Batch and prints them as a table, or
+// sends them to the event handler.
+func (a *Agent) finishBatch(w io.Writer, items []BatchItem) {
+	a.batch = append(a.batch, items...)
+	if a.events != nil {
+		a.emit(Event{Kind: EventBatchFinished, Items: sortBatch(items)})
+		return
+	}
+	PrintBatchTable(w, items)
+}
+
+// MarkdownSummary lists the items as a markdown list, failures first, for PR
+// descriptions.
+func MarkdownSummary(items []BatchItem) string {
+	var b strings.Builder
+	for _, item := range sortBatch(items) {
+		if item.Status == "succeeded" && item.Summary != "" {
+			fmt.Fprintf(&b, "- ✓ `%s`: %s\n", item.Name, item.Summary)
+		} else if item.Status == "succeeded" {
+			fmt.Fprintf(&b, "- ✓ `%s`\n", item.Name)
+		} else {
+			fmt.Fprintf(&b, "- ✗ `%s` (%s)\n", item.Name, item.ErrorClass)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// PrintBatchTable prints one row per item, failures first.
+func PrintBatchTable(w io.Writer, items []BatchItem) {
+	if len(items) == 0 {
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tNAME\tDURATION\tTURNS\tTOKENS (IN/OUT)\tERROR")
+	for _, item := range sortBatch(items) {
+		status := "✓ ok"
+		if item.Status != "succeeded" {
+			status = "✗ failed"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s/%s\t%s\n", status, item.Name,
+			formatDuration(time.Duration(item.DurationMs)*time.Millisecond), item.Turns,
+			formatTokens(item.InputTokens), formatTokens(item.OutputTokens), item.ErrorClass)
+	}
+	tw.Flush()
+}
+
+func sortBatch(items []BatchItem) []BatchItem {
+	sorted := append([]BatchItem(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if failedI, failedJ := sorted[i].Status != "succeeded", sorted[j].Status != "succeeded"; failedI != failedJ {
+			return failedI
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+// resultStats returns the turns and tokens reported in a request's result
+// message.
+func resultStats(messages []claudecode.Message) (turns, input, output int) {
+	for _, msg := range messages {
+		if m, ok := msg.(*claudecode.ResultMessage); ok {
+			turns = m.NumTurns
+			if m.Usage != nil {
+				input, output = m.Usage.InputTokens, m.Usage.OutputTokens
+			}
+		}
+	}
+	return turns, input, output
+}
+
+// errorClass groups errors by what a retry would need: "timeout" and
+// "rate_limit" are worth retrying as they are, "auth", "permission", and
+// "claude_cli" need fixing first.
+func errorClass(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, errProviderOutage):
+		return "outage"
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, class := range []struct {
+		name    string
+		matches []string
+	}{
+		{"rate_limit", []string{"rate limit", "rate_limit", "429", "overloaded", "529"}},
+		{"timeout", []string{"timed out", "timeout", "deadline exceeded"}},
+		{"auth", []string{"401", "unauthorized", "authentication", "api key", "invalid x-api-key"}},
+		{"permission", []string{"permission", "not allowed", "rejected"}},
+		{"claude_cli", []string{"executable file not found", "claude: not found", "exit status"}},
+	} {
+		for _, match := range class.matches {
+			if strings.Contains(msg, match) {
+				return class.name
+			}
+		}
+	}
+	return "error"
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	return d.Round(time.Second).String()
+}
+
+func formatTokens(n int) string {
+	if n >= 1000 {
+		return fmt.Sprintf("%.1fk", float64(n)/1000)
+	}
+	return fmt.Sprintf("%d", n)
+}
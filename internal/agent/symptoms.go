@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SymptomVerdict is whether a commit causes one of several symptoms.
+type SymptomVerdict struct {
+	IsLikely    bool
+	Confidence  int // 0-100
+	Explanation string
+}
+
+type symptomResponse struct {
+	Symptom     int         `json:"symptom"` // 1-based
+	IsLikely    bool        `json:"is_likely"`
+	Confidence  json.Number `json:"confidence"`
+	Explanation string      `json:"explanation"`
+}
+
+// symptomsInstructions extend the debug prompt when a commit is analyzed for
+// several symptoms at once.
+const symptomsInstructions = `
+
+The bug has several symptoms, numbered above. They may share one cause or have different ones. Besides the overall verdict, which is whether this commit explains the incident as a whole, judge the commit against each symptom on its own, and add to the JSON object:
+  "symptoms": [{"symptom": 1, "is_likely": true, "confidence": 85, "explanation": "why this commit does or does not cause symptom 1"}]
+with one entry per symptom, in order.`
+
+// DescribeSymptoms returns the bug description of one or more symptoms, as the
+// debug prompts take it.
+func DescribeSymptoms(symptoms []string) string {
+	if len(symptoms) == 1 {
+		return symptoms[0]
+	}
+	var b strings.Builder
+	b.WriteString("An incident with several symptoms:")
+	for i, symptom := range symptoms {
+		fmt.Fprintf(&b, "\n%d. %s", i+1, symptom)
+	}
+	return b.String()
+}
+
+// symptomVerdicts orders the per-symptom verdicts of a response, one per
+// symptom. Symptoms Claude left out are unlikely, with 0 confidence.
+func symptomVerdicts(responses []symptomResponse, symptoms int) []SymptomVerdict {
+	verdicts := make([]SymptomVerdict, symptoms)
+	for i := range verdicts {
+		verdicts[i].Explanation = "Not assessed"
+	}
+	for _, resp := range responses {
+		confidence, err := resp.Confidence.Float64()
+		if resp.Symptom < 1 || resp.Symptom > symptoms || err != nil || confidence < 0 || confidence > 100 {
+			continue
+		}
+		verdicts[resp.Symptom-1] = SymptomVerdict{IsLikely: resp.IsLikely, Confidence: int(confidence), Explanation: resp.Explanation}
+	}
+	return verdicts
+}
+
+// RankBySymptom returns the analyses with a verdict on symptom i (0-based),
+// ranked by it as RankSuspects ranks them overall. analyses is left as is.
+func RankBySymptom(analyses []*CommitAnalysis, i int) []*CommitAnalysis {
+	var ranked []*CommitAnalysis
+	for _, analysis := range analyses {
+		if i < len(analysis.Symptoms) {
+			ranked = append(ranked, analysis)
+		}
+	}
+	sort.SliceStable(ranked, func(a, b int) bool {
+		va, vb := ranked[a].Symptoms[i], ranked[b].Symptoms[i]
+		if va.IsLikely != vb.IsLikely {
+			return va.IsLikely
+		}
+		return va.Confidence > vb.Confidence
+	})
+	return ranked
+}
@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/udemy/docu-jarvis-cli/internal/frontmatter"
+)
+
+// TagSelector is the prefix of the update-docs arguments that select the docs
+// with a front-matter tag, e.g. tag:api.
+const TagSelector = "tag:"
+
+const tagInstructions = `
+
+Keep a YAML front-matter block at the very top of the document with the categories it belongs to, e.g.:
+---
+tags: [api, auth]
+---
+Use one to four short lowercase tags for what the document covers (the area of the product, and the kind of document such as api, guide, or runbook). Prefer the tags other documents already use: %s. Keep the existing tags that still apply and any other front-matter keys as they are.`
+
+// DocsWithTag returns the docs under the directories tagged with tag.
+func DocsWithTag(dirs []string, tag string) ([]string, error) {
+	docs, err := FindDocs(dirs)
+	if err != nil {
+		return nil, err
+	}
+	tag = strings.ToLower(tag)
+
+	var tagged []string
+	for _, doc := range docs {
+		content, err := os.ReadFile(doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", doc, err)
+		}
+		for _, t := range frontmatter.Tags(string(content)) {
+			if t == tag {
+				tagged = append(tagged, doc)
+				break
+			}
+		}
+	}
+	return tagged, nil
+}
+
+// docTags returns the tags the docs use, sorted, for Claude to reuse. They
+// are read once and shared by every doc of the run.
+func (a *Agent) docTags() []string {
+	a.tagsOnce.Do(func() { a.tags = a.readDocTags() })
+	return a.tags
+}
+
+func (a *Agent) readDocTags() []string {
+	docs, err := a.listDocs()
+	if err != nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var tags []string
+	for _, doc := range docs {
+		content, err := os.ReadFile(doc)
+		if err != nil {
+			continue
+		}
+		for _, tag := range frontmatter.Tags(string(content)) {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// tagPrompt returns the instructions that keep the front-matter tags of a
+// document up to date.
+func (a *Agent) tagPrompt() string {
+	existing := "(none yet)"
+	if tags := a.docTags(); len(tags) > 0 {
+		existing = strings.Join(tags, ", ")
+	}
+	return fmt.Sprintf(tagInstructions, existing)
+}
@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+)
+
+// TestFilePatterns are the test files write-tests may create or edit, as
+// CODEOWNERS-style patterns: the test files of the languages it knows, and
+// the directories of their fixtures.
+var TestFilePatterns = []string{
+	"*_test.go",
+	"*.test.ts", "*.test.tsx", "*.test.js", "*.test.jsx",
+	"*.spec.ts", "*.spec.tsx", "*.spec.js", "*.spec.jsx",
+	"test_*.py", "*_test.py", "conftest.py",
+	"*Test.java", "*Test.kt",
+	"*_spec.rb", "*_test.rb",
+	"testdata/", "__tests__/",
+}
+
+// IsTestFile reports whether path matches one of TestFilePatterns.
+func IsTestFile(path string) bool {
+	path = filepath.ToSlash(path)
+	for _, pattern := range TestFilePatterns {
+		if dir, ok := strings.CutSuffix(pattern, "/"); ok {
+			if strings.HasPrefix(path, dir+"/") || strings.Contains(path, "/"+dir+"/") {
+				return true
+			}
+			continue
+		}
+		if matched, _ := filepath.Match(pattern, filepath.Base(path)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteTests has Claude write unit tests for target, a package directory or
+// source file, following conventions. It returns the test files Claude wrote.
+func (a *Agent) WriteTests(ctx context.Context, target, conventions string) ([]string, error) {
+	a.logger.Infof("Writing tests for %s", target)
+
+	prompt := fmt.Sprintf(`%s
+
+Test conventions:
+%s
+
+Write tests for: %s
+
+The codebase you will be reading through is located at: %s`, a.systemPrompt, conventions, target, a.folder)
+
+	return a.testQuery(ctx, target, prompt)
+}
+
+// FixTests has Claude fix the tests of target that failed to compile or pass
+// with output, editing only the test files.
+func (a *Agent) FixTests(ctx context.Context, target string, files []string, output string) ([]string, error) {
+	a.logger.Infof("Fixing the tests of %s", target)
+
+	prompt := fmt.Sprintf(`%s
+
+You wrote these tests for %s:
+%s
+
+Running them failed with:
+<output>
+%s
+</output>
+
+Fix the tests so that they compile and pass. If a test fails because the code under test has a bug, keep the test, skip it with the reason the framework's way, and say so in your reply. Do not change the code under test.
+
+The codebase you will be reading through is located at: %s`, a.systemPrompt, target, strings.Join(files, "\n"), output, a.folder)
+
+	return a.testQuery(ctx, target, prompt)
+}
+
+// testQuery sends a request whose edits are limited to test files, and
+// returns the test files it wrote.
+func (a *Agent) testQuery(ctx context.Context, target, prompt string) ([]string, error) {
+	request := claudecode.QueryRequest{
+		Prompt: prompt,
+		Options: &claudecode.Options{
+			AllowedTools:   a.allowedTools("Read", "Write", "Edit", "LS", "Grep", "Glob"),
+			PermissionMode: a.editPermissionMode(),
+			Cwd:            stringPtr(a.folder),
+			OutputFormat:   outputFormatPtr(claudecode.OutputFormatJSON),
+			Verbose:        boolPtr(false),
+		},
+	}
+
+	messages, err := a.query(ctx, ModeWriteTests, request)
+	if err != nil {
+		a.logger.Errorf("Error writing tests for %s: %v", target, err)
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	for _, message := range messages {
+		a.logMessage(target, message)
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	for _, msg := range messages {
+		for _, block := range msg.Content() {
+			use, ok := block.(*claudecode.ToolUseBlock)
+			if !ok || (use.Name != "Write" && use.Name != "Edit" && use.Name != "MultiEdit") {
+				continue
+			}
+			path, _ := use.Input["file_path"].(string)
+			if path == "" || !IsTestFile(path) {
+				continue
+			}
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(a.folder, path)
+			}
+			if path = filepath.Clean(path); !seen[path] {
+				seen[path] = true
+				files = append(files, path)
+			}
+		}
+	}
+	a.logger.Infof("Wrote %d test files for %s", len(files), target)
+	return files, nil
+}
@@ -0,0 +1,556 @@
+package agent
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+
+	"github.com/udemy/docu-jarvis-cli/internal/approval"
+)
+
+const (
+	// readLimit is how many lines Read returns when no limit is given.
+	readLimit = 2000
+	// searchLimit caps the matches of Grep and Glob.
+	searchLimit = 500
+)
+
+// apiTools are the tools the API providers can run, in the order they are
+// offered to Claude.
+var apiTools = []string{"Read", "Write", "Edit", "MultiEdit", "Grep", "Glob", "LS"}
+
+// skippedDirs are left out of Grep, Glob, and LS walks.
+var skippedDirs = map[string]bool{".git": true, "node_modules": true}
+
+// toolRule is a path rule of a tool, e.g. Read(//repo/**).
+type toolRule struct {
+	path    string
+	subtree bool // the rule ends in /**
+}
+
+// toolbox runs the file tools of a request for the API providers, applying
+// the request's allowed and disallowed tools as Claude Code would.
+type toolbox struct {
+	cwd      string
+	allowed  map[string][]toolRule // nil rules: the tool is allowed anywhere
+	denied   map[string][]toolRule
+	approval []string // edit roots, when edits go through the approval server
+}
+
+func newToolbox(options *claudecode.Options) *toolbox {
+	t := &toolbox{
+		allowed: make(map[string][]toolRule),
+		denied:  make(map[string][]toolRule),
+	}
+	if options.Cwd != nil {
+		t.cwd = *options.Cwd
+	}
+	if t.cwd == "" {
+		t.cwd, _ = os.Getwd()
+	}
+
+	for _, tool := range options.AllowedTools {
+		name, rule, ok := parseToolRule(tool)
+		if !ok {
+			if _, seen := t.allowed[name]; !seen {
+				t.allowed[name] = nil
+			}
+			continue
+		}
+		t.allowed[name] = append(t.allowed[name], rule)
+	}
+	for _, tool := range options.DisallowedTools {
+		name, rule, ok := parseToolRule(tool)
+		if !ok {
+			// A bare name denies the tool outright
+			delete(t.allowed, name)
+			continue
+		}
+		t.denied[name] = append(t.denied[name], rule)
+	}
+
+	if options.PermissionPromptTool != nil && *options.PermissionPromptTool == approval.PromptTool && options.MCPConfig != nil {
+		if roots, err := approval.ConfigRoots(*options.MCPConfig); err == nil {
+			t.approval = roots
+		}
+	}
+	return t
+}
+
+// parseToolRule splits Tool(path) into the tool and its rule. Paths starting
+// with // are absolute and ~ is the home directory, as in Claude Code; rules
+// use forward slashes, on Windows too.
+func parseToolRule(tool string) (string, toolRule, bool) {
+	open := strings.Index(tool, "(")
+	if open < 0 || !strings.HasSuffix(tool, ")") {
+		return tool, toolRule{}, false
+	}
+	name, pattern := tool[:open], tool[open+1:len(tool)-1]
+
+	rule := toolRule{}
+	if strings.HasSuffix(pattern, "/**") {
+		rule.subtree = true
+		pattern = strings.TrimSuffix(pattern, "/**")
+	}
+	switch {
+	case strings.HasPrefix(pattern, "//"):
+		pattern = pattern[2:]
+		if !filepath.IsAbs(filepath.FromSlash(pattern)) {
+			// Not a drive-letter path: the rule is rooted at /
+			pattern = "/" + pattern
+		}
+	case strings.HasPrefix(pattern, "~"):
+		home, _ := os.UserHomeDir()
+		pattern = home + pattern[1:]
+	}
+	rule.path = filepath.Clean(filepath.FromSlash(pattern))
+	return name, rule, true
+}
+
+func (r toolRule) matches(path string) bool {
+	if path == r.path {
+		return true
+	}
+	if !r.subtree {
+		return false
+	}
+	rel, err := filepath.Rel(r.path, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ruleTool is the tool whose rules apply to a tool: the search tools follow
+// Read, and Edit rules cover all the edit tools.
+func ruleTool(name string) string {
+	switch name {
+	case "Grep", "Glob", "LS":
+		return "Read"
+	case "Write", "MultiEdit":
+		return "Edit"
+	}
+	return name
+}
+
+func isEditTool(name string) bool {
+	return name == "Write" || name == "Edit" || name == "MultiEdit"
+}
+
+// offered reports whether Claude may call the tool at all.
+func (t *toolbox) offered(name string) bool {
+	if _, ok := t.allowed[name]; ok {
+		return true
+	}
+	return isEditTool(name) && t.approval != nil
+}
+
+// permitted reports whether the tool may touch path, with the reason to give
+// Claude when it may not. Edits that need approval ask the user.
+func (t *toolbox) permitted(name, path string, input map[string]interface{}) (bool, string) {
+	denied := fmt.Sprintf("Permission to use %s has been denied.", name)
+	for _, rule := range append(t.denied[name], t.denied[ruleTool(name)]...) {
+		if rule.matches(path) {
+			return false, denied
+		}
+	}
+
+	rules, ok := t.allowed[name]
+	if ok && rules == nil && ruleTool(name) != name {
+		// A bare search tool still follows the Read path rules
+		rules = t.allowed["Read"]
+	}
+	if ok {
+		if len(rules) == 0 {
+			return true, ""
+		}
+		for _, rule := range rules {
+			if rule.matches(path) {
+				return true, ""
+			}
+		}
+		return false, denied
+	}
+
+	if isEditTool(name) && t.approval != nil {
+		input["file_path"] = path
+		if allowed, reason := approval.Decide(t.approval, name, input); !allowed {
+			return false, reason
+		}
+		return true, ""
+	}
+	return false, denied
+}
+
+func (t *toolbox) names() []string {
+	var names []string
+	for _, name := range apiTools {
+		if t.offered(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// resolve makes path absolute and follows its symlinks, so rules match the
+// file that is actually touched.
+func (t *toolbox) resolve(path string) string {
+	if path == "" {
+		path = t.cwd
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(t.cwd, path)
+	}
+	path = filepath.Clean(path)
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	// A new file: resolve the directory it goes in
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		return filepath.Join(dir, filepath.Base(path))
+	}
+	return path
+}
+
+// run runs a tool call and returns its output, and whether it failed.
+func (t *toolbox) run(name string, input map[string]interface{}) (string, bool) {
+	if !t.offered(name) {
+		return fmt.Sprintf("Permission to use %s has been denied.", name), true
+	}
+
+	key := "path"
+	if name == "Read" || isEditTool(name) {
+		key = "file_path"
+	}
+	raw, _ := input[key].(string)
+	path := t.resolve(raw)
+	if ok, reason := t.permitted(name, path, input); !ok {
+		return reason, true
+	}
+
+	var out string
+	var err error
+	switch name {
+	case "Read":
+		out, err = readTool(path, intInput(input, "offset"), intInput(input, "limit"))
+	case "Write":
+		content, _ := input["content"].(string)
+		out, err = writeTool(path, content)
+	case "Edit":
+		out, err = editTool(path, []map[string]interface{}{input})
+	case "MultiEdit":
+		var edits []map[string]interface{}
+		list, _ := input["edits"].([]interface{})
+		for _, e := range list {
+			if edit, ok := e.(map[string]interface{}); ok {
+				edits = append(edits, edit)
+			}
+		}
+		out, err = editTool(path, edits)
+	case "Grep":
+		pattern, _ := input["pattern"].(string)
+		glob, _ := input["glob"].(string)
+		out, err = t.grepTool(path, pattern, glob)
+	case "Glob":
+		pattern, _ := input["pattern"].(string)
+		out, err = t.globTool(path, pattern)
+	case "LS":
+		out, err = t.lsTool(path)
+	default:
+		err = fmt.Errorf("unknown tool %s", name)
+	}
+	if err != nil {
+		return err.Error(), true
+	}
+	return out, false
+}
+
+func intInput(input map[string]interface{}, key string) int {
+	if n, ok := input[key].(float64); ok {
+		return int(n)
+	}
+	return 0
+}
+
+func readTool(path string, offset, limit int) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if offset < 1 {
+		offset = 1
+	}
+	if limit <= 0 {
+		limit = readLimit
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	var out strings.Builder
+	for i := offset - 1; i < len(lines) && i < offset-1+limit; i++ {
+		fmt.Fprintf(&out, "%6d\t%s\n", i+1, lines[i])
+	}
+	if out.Len() == 0 {
+		return "(empty file)", nil
+	}
+	return out.String(), nil
+}
+
+func writeTool(path, content string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("File written: %s", path), nil
+}
+
+// editTool applies the edits in order, each replacing old_string with
+// new_string; old_string must be unique unless replace_all is set.
+func editTool(path string, edits []map[string]interface{}) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	content := string(data)
+	for _, edit := range edits {
+		oldString, _ := edit["old_string"].(string)
+		newString, _ := edit["new_string"].(string)
+		replaceAll, _ := edit["replace_all"].(bool)
+		count := strings.Count(content, oldString)
+		switch {
+		case oldString == "":
+			return "", fmt.Errorf("old_string is empty")
+		case count == 0:
+			return "", fmt.Errorf("old_string not found in %s", path)
+		case count > 1 && !replaceAll:
+			return "", fmt.Errorf("old_string appears %d times in %s; give more context or set replace_all", count, path)
+		}
+		if replaceAll {
+			content = strings.ReplaceAll(content, oldString, newString)
+		} else {
+			content = strings.Replace(content, oldString, newString, 1)
+		}
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("File edited: %s", path), nil
+}
+
+// walk calls fn for each file under root, skipping skippedDirs and the paths
+// the tool may not read. A symlink is checked as the file it points to, and
+// skipped when that is not a regular file under root, so a link cannot expose
+// a denied file or one outside the repository.
+func (t *toolbox) walk(tool, root string, fn func(path string) bool) error {
+	under := toolRule{path: filepath.Clean(root), subtree: true}
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != root && skippedDirs[info.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			resolved, err := filepath.EvalSymlinks(path)
+			if err != nil || !under.matches(resolved) {
+				return nil
+			}
+			if target, err := os.Stat(resolved); err != nil || !target.Mode().IsRegular() {
+				return nil
+			}
+			if ok, _ := t.permitted(tool, resolved, nil); !ok {
+				return nil
+			}
+		}
+		if ok, _ := t.permitted(tool, path, nil); !ok {
+			return nil
+		}
+		if !fn(path) {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+}
+
+func (t *toolbox) grepTool(root, pattern, glob string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	var matches []string
+	err = t.walk("Grep", root, func(path string) bool {
+		if glob != "" {
+			if ok, _ := filepath.Match(glob, filepath.Base(path)); !ok {
+				return true
+			}
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return true
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for n := 1; scanner.Scan(); n++ {
+			if re.MatchString(scanner.Text()) {
+				matches = append(matches, fmt.Sprintf("%s:%d:%s", path, n, scanner.Text()))
+				if len(matches) == searchLimit {
+					return false
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "No matches found", nil
+	}
+	return strings.Join(matches, "\n"), nil
+}
+
+func (t *toolbox) globTool(root, pattern string) (string, error) {
+	var matches []string
+	err := t.walk("Glob", root, func(path string) bool {
+		rel, _ := filepath.Rel(root, path)
+		if matchGlob(pattern, filepath.ToSlash(rel)) {
+			matches = append(matches, path)
+		}
+		return len(matches) < searchLimit
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "No files found", nil
+	}
+	sort.Strings(matches)
+	return strings.Join(matches, "\n"), nil
+}
+
+// matchGlob matches a slash-separated path against a glob in which **
+// matches any number of directories.
+func matchGlob(pattern, path string) bool {
+	if !strings.Contains(pattern, "**") {
+		ok, _ := filepath.Match(pattern, path)
+		return ok
+	}
+	prefix, rest, _ := strings.Cut(pattern, "**")
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	path = strings.TrimPrefix(path, prefix)
+	rest = strings.TrimPrefix(rest, "/")
+	parts := strings.Split(path, "/")
+	for i := range parts {
+		if matchGlob(rest, strings.Join(parts[i:], "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *toolbox) lsTool(path string) (string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	for _, entry := range entries {
+		if skippedDirs[entry.Name()] {
+			continue
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		lines = append(lines, "- "+name)
+	}
+	if len(lines) == 0 {
+		return "(empty directory)", nil
+	}
+	return path + "\n" + strings.Join(lines, "\n"), nil
+}
+
+// definitions returns the JSON Schema definitions of the tools offered.
+func (t *toolbox) definitions() []map[string]interface{} {
+	str := func(description string) map[string]interface{} {
+		return map[string]interface{}{"type": "string", "description": description}
+	}
+	num := func(description string) map[string]interface{} {
+		return map[string]interface{}{"type": "integer", "description": description}
+	}
+	edit := map[string]interface{}{
+		"old_string":  str("The exact text to replace"),
+		"new_string":  str("The text to replace it with"),
+		"replace_all": map[string]interface{}{"type": "boolean", "description": "Replace every occurrence"},
+	}
+
+	schemas := map[string]struct {
+		description string
+		properties  map[string]interface{}
+		required    []string
+	}{
+		"Read": {"Reads a file, with line numbers.", map[string]interface{}{
+			"file_path": str("The absolute path of the file"),
+			"offset":    num("The line to start at"),
+			"limit":     num("The number of lines to read"),
+		}, []string{"file_path"}},
+		"Write": {"Writes a file, replacing it if it exists.", map[string]interface{}{
+			"file_path": str("The absolute path of the file"),
+			"content":   str("The content to write"),
+		}, []string{"file_path", "content"}},
+		"Edit": {"Replaces text in a file. old_string must be unique in the file unless replace_all is set.", map[string]interface{}{
+			"file_path":   str("The absolute path of the file"),
+			"old_string":  edit["old_string"],
+			"new_string":  edit["new_string"],
+			"replace_all": edit["replace_all"],
+		}, []string{"file_path", "old_string", "new_string"}},
+		"MultiEdit": {"Applies several edits to one file, in order.", map[string]interface{}{
+			"file_path": str("The absolute path of the file"),
+			"edits": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type":       "object",
+					"properties": edit,
+					"required":   []string{"old_string", "new_string"},
+				},
+			},
+		}, []string{"file_path", "edits"}},
+		"Grep": {"Searches file contents with a regular expression.", map[string]interface{}{
+			"pattern": str("The regular expression (Go syntax)"),
+			"path":    str("The directory or file to search, the working directory by default"),
+			"glob":    str("Only search files whose name matches this glob, e.g. *.go"),
+		}, []string{"pattern"}},
+		"Glob": {"Finds files whose path matches a glob, e.g. **/*.md.", map[string]interface{}{
+			"pattern": str("The glob, relative to path"),
+			"path":    str("The directory to search, the working directory by default"),
+		}, []string{"pattern"}},
+		"LS": {"Lists a directory.", map[string]interface{}{
+			"path": str("The absolute path of the directory"),
+		}, []string{"path"}},
+	}
+
+	var defs []map[string]interface{}
+	for _, name := range t.names() {
+		schema := schemas[name]
+		defs = append(defs, map[string]interface{}{
+			"name":        name,
+			"description": schema.description,
+			"input_schema": map[string]interface{}{
+				"type":       "object",
+				"properties": schema.properties,
+				"required":   schema.required,
+			},
+		})
+	}
+	return defs
+}
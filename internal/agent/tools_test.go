@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+)
+
+func TestToolboxPathRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		root    string
+		inside  string
+		outside string
+		windows bool
+	}{
+		{
+			name:    "workspace root",
+			root:    t.TempDir(),
+			inside:  "docs/guide.md",
+			outside: "../other/guide.md",
+		},
+		{
+			name:    "drive-letter root",
+			root:    `C:\work\repo`,
+			inside:  `C:\work\repo\docs\guide.md`,
+			outside: `C:\work\other\guide.md`,
+			windows: true,
+		},
+		{
+			name:    "other drive",
+			root:    `C:\work\repo`,
+			inside:  `C:\work\repo\README.md`,
+			outside: `D:\work\repo\README.md`,
+			windows: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.windows && runtime.GOOS != "windows" {
+				t.Skip("drive-letter paths are only absolute on Windows")
+			}
+			tools := newToolbox(&claudecode.Options{
+				AllowedTools: []string{pathRule("Read", tt.root)},
+				Cwd:          &tt.root,
+			})
+
+			for path, want := range map[string]bool{tt.inside: true, tt.outside: false, tt.root: true} {
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(tt.root, path)
+				}
+				if got, _ := tools.permitted("Read", filepath.Clean(path), nil); got != want {
+					t.Errorf("permitted(Read, %s) = %v, want %v (rule %s)", path, got, want, pathRule("Read", tt.root))
+				}
+			}
+		})
+	}
+}
+
+func TestParseToolRule(t *testing.T) {
+	tests := []struct {
+		tool    string
+		path    string
+		subtree bool
+		windows bool
+	}{
+		{tool: "Read(//repo/docs/**)", path: filepath.FromSlash("/repo/docs"), subtree: true},
+		{tool: "Edit(//repo/README.md)", path: filepath.FromSlash("/repo/README.md")},
+		{tool: "Read(//C:/work/repo/**)", path: `C:\work\repo`, subtree: true, windows: true},
+	}
+
+	for _, tt := range tests {
+		if tt.windows && runtime.GOOS != "windows" {
+			continue
+		}
+		_, rule, ok := parseToolRule(tt.tool)
+		if !ok {
+			t.Errorf("parseToolRule(%s) found no rule", tt.tool)
+			continue
+		}
+		if rule.path != tt.path || rule.subtree != tt.subtree {
+			t.Errorf("parseToolRule(%s) = %+v, want path %s, subtree %v", tt.tool, rule, tt.path, tt.subtree)
+		}
+	}
+}
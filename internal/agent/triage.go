@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+)
+
+// IssueTriage is Claude's triage of an issue.
+type IssueTriage struct {
+	Summary        string          `json:"summary"`
+	Areas          []TriageArea    `json:"root_cause_areas"`
+	RelatedCommits []RelatedCommit `json:"related_commits"`
+	Labels         []string        `json:"suggested_labels"`
+	Questions      []string        `json:"questions"`
+}
+
+// TriageArea is code suspected of causing an issue.
+type TriageArea struct {
+	File       string `json:"file"`
+	Location   string `json:"location"` // a line or function, when known
+	Reason     string `json:"reason"`
+	Confidence int    `json:"confidence"` // 0-100
+}
+
+// RelatedCommit is a recent commit that touched the suspected code.
+type RelatedCommit struct {
+	Hash   string `json:"hash"`
+	Reason string `json:"reason"`
+}
+
+// TriageIssue has Claude correlate an issue with the codebase and the recent
+// commits (as hash|author|date|subject), and suggest labels from labels.
+func (a *Agent) TriageIssue(ctx context.Context, issue string, commits, labels []string) (*IssueTriage, error) {
+	a.logger.Infof("Triaging issue against %d recent commits and %d labels", len(commits), len(labels))
+
+	var commitList strings.Builder
+	for _, commit := range commits {
+		commitList.WriteString("- " + describeCommit(commit) + "\n")
+	}
+	if len(commits) == 0 {
+		commitList.WriteString("(none)\n")
+	}
+	labelList := strings.Join(labels, ", ")
+	if labelList == "" {
+		labelList = "(none)"
+	}
+
+	prompt := fmt.Sprintf(`%s
+
+The issue:
+<issue>
+%s
+</issue>
+
+Recent commits, newest first:
+<commits>
+%s</commits>
+
+The repository's labels: %s
+
+The codebase is located at: %s`, a.systemPrompt, issue, commitList.String(), labelList, a.folder)
+
+	request := claudecode.QueryRequest{
+		Prompt: prompt,
+		Options: &claudecode.Options{
+			AllowedTools:   []string{"Read", "Grep", "Glob", "LS"},
+			PermissionMode: stringPtr("acceptEdits"),
+			Cwd:            stringPtr(a.folder),
+			OutputFormat:   outputFormatPtr(claudecode.OutputFormatJSON),
+			Verbose:        boolPtr(false),
+			MaxTurns:       intPtr(25),
+		},
+	}
+
+	messages, err := a.query(ctx, ModeTriage, request)
+	if err != nil {
+		a.logger.Errorf("Error triaging issue: %v", err)
+		return nil, fmt.Errorf("triage error: %w", err)
+	}
+
+	var lastErr error
+	for _, candidate := range jsonObjectCandidates(resultText(messages)) {
+		var triage IssueTriage
+		if err := json.Unmarshal([]byte(candidate), &triage); err != nil {
+			lastErr = fmt.Errorf("invalid JSON: %w", err)
+			continue
+		}
+		if triage.Summary == "" {
+			lastErr = fmt.Errorf("missing required field: summary")
+			continue
+		}
+		a.logger.Infof("Issue triaged: %d areas, %d related commits", len(triage.Areas), len(triage.RelatedCommits))
+		return &triage, nil
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no JSON object found")
+	}
+	a.logger.Errorf("Could not parse issue triage: %v", lastErr)
+	return nil, fmt.Errorf("Claude did not return expected JSON response: %w", lastErr)
+}
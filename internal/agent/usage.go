@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"context"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+
+	"github.com/udemy/docu-jarvis-cli/internal/usage"
+)
+
+// query runs a Claude request of mode, with the mode's config, sandboxed to
+// the workspace, with edits sent for approval when they need confirming, and
+// records its token usage for the run summary. During a batch, the request
+// goes through the batch's breaker.
+func (a *Agent) query(ctx context.Context, mode string, request claudecode.QueryRequest) ([]claudecode.Message, error) {
+	ctx = a.withMode(ctx, mode, &request)
+	if err := a.requireApproval(&request); err != nil {
+		return nil, err
+	}
+	a.sandbox(&request)
+
+	send := func() ([]claudecode.Message, error) {
+		var messages []claudecode.Message
+		var err error
+		it := itemFrom(ctx)
+		if it != nil {
+			messages, err = a.queryStream(ctx, request, it)
+		} else {
+			messages, err = a.provider.Query(ctx, request)
+		}
+		a.auditToolUse(messages)
+		recordUsage(messages)
+		turns, input, output := resultStats(messages)
+		if it != nil {
+			it.report.tokens(it.name, input, output)
+		}
+		a.logRequest(mode, it, turns, input, output, err)
+		return messages, err
+	}
+	if a.breaker != nil {
+		return a.breaker.do(ctx, send)
+	}
+	return send()
+}
+
+// logRequest logs a request of mode, with the item it was for in a batch.
+func (a *Agent) logRequest(mode string, it *item, turns, input, output int, err error) {
+	args := []any{"mode", mode, "turns", turns, "input_tokens", input, "output_tokens", output}
+	if it != nil {
+		args = append(args, "item", it.name)
+	}
+	if err != nil {
+		a.logger.Error("Request failed", append(args, "err", err)...)
+		return
+	}
+	a.logger.Info("Request", args...)
+}
+
+// recordUsage records the usage reported in a request's result message, under
+// the model named in its system message.
+func recordUsage(messages []claudecode.Message) {
+	var model string
+	for _, msg := range messages {
+		switch m := msg.(type) {
+		case *claudecode.SystemMessage:
+			if m.Model != nil {
+				model = *m.Model
+			}
+		case *claudecode.ResultMessage:
+			var input, output int
+			if m.Usage != nil {
+				input, output = m.Usage.InputTokens, m.Usage.OutputTokens
+			}
+			usage.Record(model, input, output, m.TotalCostUSD)
+		}
+	}
+}
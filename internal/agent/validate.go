@@ -0,0 +1,236 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/udemy/docu-jarvis-cli/internal/frontmatter"
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+)
+
+// minDocLength is the fewest characters of text, past the front matter, that
+// a written doc may have.
+const minDocLength = 100
+
+// outputRetries is how many times a request whose doc came out broken is
+// sent again before the doc is reverted for good.
+const outputRetries = 1
+
+var (
+	headingPattern     = regexp.MustCompile(`^#{1,6}(\s|$)`)
+	orderedItemPattern = regexp.MustCompile(`^\d+[.)]\s`)
+)
+
+// validateDoc returns why a written doc looks empty, cut off, or broken, or
+// nil when it looks whole.
+func validateDoc(content string) error {
+	body := strings.TrimPrefix(content, "\ufeff")
+	if strings.TrimSpace(strings.SplitN(body, "\n", 2)[0]) == "---" {
+		_, rest, ok := frontmatter.Split(body)
+		if !ok {
+			return errors.New("has unclosed front matter")
+		}
+		body = rest
+	}
+
+	text := strings.TrimSpace(body)
+	if text == "" {
+		return errors.New("is empty")
+	}
+	if n := len([]rune(text)); n < minDocLength {
+		return fmt.Errorf("has only %d characters", n)
+	}
+
+	var prose strings.Builder
+	fence, last, heading := "", "", ""
+	sectionEmpty := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			last = trimmed
+			continue
+		}
+		if marker := fenceMarker(trimmed); marker != "" {
+			fence, last, sectionEmpty = marker, trimmed, false
+			continue
+		}
+		if trimmed == "" {
+			continue
+		}
+		prose.WriteString(line + "\n")
+		if headingPattern.MatchString(trimmed) {
+			heading, sectionEmpty = trimmed, true
+		} else {
+			sectionEmpty = false
+		}
+		last = line
+	}
+
+	switch {
+	case fence != "":
+		return errors.New("has an unclosed code block")
+	case strings.Count(prose.String(), "<!--") > strings.Count(prose.String(), "-->"):
+		return errors.New("has an unclosed HTML comment")
+	case sectionEmpty:
+		return fmt.Errorf("ends with an empty section %q", heading)
+	case midSentence(last):
+		tail := []rune(strings.TrimSpace(last))
+		if len(tail) > 40 {
+			tail = append([]rune("..."), tail[len(tail)-40:]...)
+		}
+		return fmt.Errorf("ends mid-sentence (%q)", string(tail))
+	}
+	return nil
+}
+
+// fenceMarker returns the backticks or tildes that open a fenced code block
+// on line, or "" when it opens none.
+func fenceMarker(line string) string {
+	for _, char := range []string{"`", "~"} {
+		marker := line[:len(line)-len(strings.TrimLeft(line, char))]
+		if len(marker) >= 3 {
+			return marker
+		}
+	}
+	return ""
+}
+
+// midSentence reports whether the last line of a doc is prose cut off
+// before the end of its sentence. Lists, tables, quotes, HTML, and indented
+// code are let through, as they often end without punctuation.
+func midSentence(line string) bool {
+	if strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") {
+		return false
+	}
+	line = strings.TrimSpace(line)
+	if line == "" || strings.ContainsAny(line[:1], "|<>![-*+") || orderedItemPattern.MatchString(line) {
+		return false
+	}
+	runes := []rune(line)
+	switch end := runes[len(runes)-1]; {
+	case end == ',' || end == '(':
+		return true
+	case unicode.IsLetter(end) || unicode.IsDigit(end):
+		return len(strings.Fields(line)) >= 5
+	}
+	return false
+}
+
+// docSnapshot holds docs as they were before a request, to revert the ones
+// it broke.
+type docSnapshot struct {
+	content map[string][]byte
+	// newDir is where the request may create docs; "" when it creates none.
+	newDir string
+}
+
+func snapshotDocs(paths []string, newDir string) docSnapshot {
+	s := docSnapshot{content: make(map[string][]byte), newDir: newDir}
+	for _, path := range paths {
+		if content, err := os.ReadFile(path); err == nil {
+			s.content[filepath.Clean(path)] = content
+		}
+	}
+	return s
+}
+
+// written returns the docs of the snapshot, and the docs created in its
+// newDir, that messages wrote to.
+func (s docSnapshot) written(a *Agent, messages []claudecode.Message) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, msg := range messages {
+		for _, block := range msg.Content() {
+			use, ok := block.(*claudecode.ToolUseBlock)
+			if !ok || (use.Name != "Write" && use.Name != "Edit" && use.Name != "MultiEdit") {
+				continue
+			}
+			path, _ := use.Input["file_path"].(string)
+			if path == "" || !IsDocFile(path) {
+				continue
+			}
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(a.folder, path)
+			}
+			path = filepath.Clean(path)
+			_, existed := s.content[path]
+			if seen[path] || (!existed && (s.newDir == "" || !within(s.newDir, path))) {
+				continue
+			}
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// restore puts a doc back as it was, removing it if it was created since.
+func (s docSnapshot) restore(path string) error {
+	content, existed := s.content[path]
+	if !existed {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		return nil
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", path, err)
+	}
+	return nil
+}
+
+// checkOutput validates the docs that a request wrote. Broken docs are
+// reverted from before and the request is sent again, up to outputRetries
+// times, with the problem added to its prompt. It returns the messages of
+// every attempt.
+func (a *Agent) checkOutput(ctx context.Context, mode, name string, request claudecode.QueryRequest, before docSnapshot, messages []claudecode.Message) ([]claudecode.Message, error) {
+	prompt := request.Prompt
+	for attempt := 0; ; attempt++ {
+		var problems, reasons []string
+		for _, path := range before.written(a, messages) {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				// reverted already, or never written
+				continue
+			}
+			if previous, existed := before.content[path]; existed && string(previous) == string(content) {
+				continue
+			}
+			invalid := validateDoc(string(content))
+			if invalid == nil {
+				continue
+			}
+			if err := before.restore(path); err != nil {
+				return messages, err
+			}
+			problems = append(problems, a.docName(path)+" "+invalid.Error())
+			reasons = append(reasons, invalid.Error())
+		}
+		if len(problems) == 0 {
+			return messages, nil
+		}
+
+		problem := strings.Join(problems, "; ")
+		a.logger.Warnf("Broken output for %s (attempt %d): %s", name, attempt+1, problem)
+		if attempt >= outputRetries {
+			return messages, fmt.Errorf("%s; reverted it", problem)
+		}
+		a.status("  Warning: %s; reverted it and retrying\n", problem)
+
+		request.Prompt = prompt + fmt.Sprintf("\n\nIMPORTANT: A previous attempt at this task was reverted because the document it wrote %s. Write the complete document, ending with a finished section.", strings.Join(reasons, " and "))
+		retry, err := a.query(ctx, mode, request)
+		messages = append(messages, retry...)
+		if err != nil {
+			return messages, fmt.Errorf("query error: %w", err)
+		}
+	}
+}
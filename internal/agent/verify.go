@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	claudecode "github.com/yukifoo/claude-code-sdk-go"
+)
+
+// CommitChallenge is the outcome of arguing that a suspect commit is not the
+// cause of a bug.
+type CommitChallenge struct {
+	Refuted    bool // the case against the commit holds
+	Confidence int  // 0-100 that the commit is the cause, after the challenge
+	Argument   string
+}
+
+// ChallengeCommit asks Claude to argue that the commit of an analysis, with
+// the given diff, did not cause the bug, as a check on a verdict the analysis
+// was unsure of.
+func (a *Agent) ChallengeCommit(ctx context.Context, analysis *CommitAnalysis, diff, bugDescription string) (*CommitChallenge, error) {
+	a.logger.Infof("Challenging commit %s for bug", shortHash(analysis.CommitHash))
+	if len(diff) > maxBisectDiff {
+		diff = diff[:maxBisectDiff] + "\n... (diff truncated)"
+	}
+
+	prompt := fmt.Sprintf(`%s
+
+Codebase location: %s
+
+Suspect commit:
+- Hash: %s
+- Author: %s
+- Date: %s
+- Message: %s
+
+Bug description:
+%s
+
+The analyst's verdict (likely cause: %t, confidence %d%%):
+%s
+
+Diff of the suspect commit:
+<diff>
+%s
+</diff>`, a.systemPrompt, a.folder, analysis.CommitHash, analysis.Author, analysis.Date, analysis.CommitMsg,
+		bugDescription, analysis.IsLikely, analysis.Confidence, analysis.Explanation, diff)
+
+	request := claudecode.QueryRequest{
+		Prompt: prompt,
+		Options: &claudecode.Options{
+			AllowedTools:   []string{"Read", "Grep", "LS"},
+			PermissionMode: stringPtr("acceptEdits"),
+			Cwd:            stringPtr(a.folder),
+			OutputFormat:   outputFormatPtr(claudecode.OutputFormatJSON),
+			Verbose:        boolPtr(false),
+			MaxTurns:       intPtr(25),
+		},
+	}
+
+	messages, err := a.query(ctx, ModeDebug, request)
+	if err != nil {
+		a.logger.Errorf("Error challenging commit: %v", err)
+		return nil, fmt.Errorf("verification error: %w", err)
+	}
+
+	text := resultText(messages)
+	var lastErr error = fmt.Errorf("no JSON object found")
+	for _, candidate := range jsonObjectCandidates(text) {
+		challenge, err := decodeCommitChallenge(candidate)
+		if err == nil {
+			a.logger.Infof("Challenge of %s: refuted=%v, confidence=%d", shortHash(analysis.CommitHash), challenge.Refuted, challenge.Confidence)
+			return challenge, nil
+		}
+		lastErr = err
+	}
+
+	a.logger.Errorf("Could not extract JSON from verification: %v", lastErr)
+	return nil, fmt.Errorf("Claude did not return expected JSON response: %w", lastErr)
+}
+
+func decodeCommitChallenge(data string) (*CommitChallenge, error) {
+	var resp struct {
+		Refuted    *bool       `json:"refuted"`
+		Confidence json.Number `json:"confidence"`
+		Argument   string      `json:"argument"`
+	}
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if resp.Refuted == nil {
+		return nil, fmt.Errorf("missing required field: refuted")
+	}
+	if resp.Confidence == "" {
+		return nil, fmt.Errorf("missing required field: confidence")
+	}
+
+	confidence, err := resp.Confidence.Float64()
+	if err != nil || confidence < 0 || confidence > 100 {
+		return nil, fmt.Errorf("invalid confidence %q", resp.Confidence)
+	}
+	return &CommitChallenge{Refuted: *resp.Refuted, Confidence: int(confidence), Argument: resp.Argument}, nil
+}
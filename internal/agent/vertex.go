@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/udemy/docu-jarvis-cli/internal/netguard"
+	"github.com/udemy/docu-jarvis-cli/internal/redact"
+)
+
+const (
+	defaultVertexModel  = "claude-sonnet-4-5@20250929"
+	defaultVertexRegion = "us-east5"
+	vertexVersion       = "vertex-2023-10-16"
+)
+
+// vertexTransport calls Claude on Vertex AI, authenticated with the access
+// token of GOOGLE_OAUTH_ACCESS_TOKEN or gcloud.
+type vertexTransport struct {
+	project string
+	region  string
+}
+
+func newVertexTransport(cfg ProviderConfig) *vertexTransport {
+	return &vertexTransport{
+		project: firstSet(cfg.Project, os.Getenv("ANTHROPIC_VERTEX_PROJECT_ID"), os.Getenv("GOOGLE_CLOUD_PROJECT")),
+		region:  firstSet(cfg.Region, os.Getenv("CLOUD_ML_REGION"), defaultVertexRegion),
+	}
+}
+
+func (t *vertexTransport) check() error {
+	if err := netguard.Check("calling Claude on Vertex AI"); err != nil {
+		return err
+	}
+	if t.project == "" {
+		return fmt.Errorf("the vertex provider needs a project: set vertex_project in the config, or ANTHROPIC_VERTEX_PROJECT_ID")
+	}
+	return nil
+}
+
+func (t *vertexTransport) send(ctx context.Context, model string, body map[string]interface{}) ([]byte, error) {
+	body["anthropic_version"] = vertexVersion
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	token, err := vertexToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	host := t.region + "-aiplatform.googleapis.com"
+	if t.region == "global" {
+		host = "aiplatform.googleapis.com"
+	}
+	endpoint := fmt.Sprintf("https://%s/v1/projects/%s/locations/%s/publishers/anthropic/models/%s:rawPredict",
+		host, url.PathEscape(t.project), url.PathEscape(t.region), url.PathEscape(model))
+	return postAPI(ctx, ProviderVertex, endpoint, data, map[string]string{"Authorization": "Bearer " + token})
+}
+
+// vertexToken returns a Google Cloud access token. gcloud caches and
+// refreshes it, so it is asked for every request.
+func vertexToken(ctx context.Context) (string, error) {
+	token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+	if token == "" {
+		output, err := exec.CommandContext(ctx, "gcloud", "auth", "print-access-token").Output()
+		if err != nil {
+			return "", fmt.Errorf("failed to get a Google Cloud access token (run 'gcloud auth login', or set GOOGLE_OAUTH_ACCESS_TOKEN): %w", err)
+		}
+		token = strings.TrimSpace(string(output))
+	}
+	redact.AddSecret(token)
+	return token, nil
+}
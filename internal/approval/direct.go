@@ -0,0 +1,37 @@
+package approval
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Decide applies the approval server's rules to an edit that docu-jarvis
+// runs itself, for model providers other than Claude Code: edits outside the
+// roots are denied, and the user is asked about the rest. It returns whether
+// the edit may go ahead, and otherwise the reason to give Claude.
+func Decide(roots []string, toolName string, input map[string]interface{}) (bool, string) {
+	var args []string
+	for _, root := range roots {
+		args = append(args, "-root", root)
+	}
+	s := &server{roots: parseRoots(args)}
+	d := s.decide(permissionRequest{ToolName: toolName, Input: input})
+	return d.Behavior == "allow", d.Message
+}
+
+// ConfigRoots returns the directories an MCPConfig allows edits under.
+func ConfigRoots(mcpConfig string) ([]string, error) {
+	var config struct {
+		MCPServers map[string]struct {
+			Args []string `json:"args"`
+		} `json:"mcpServers"`
+	}
+	if err := json.Unmarshal([]byte(mcpConfig), &config); err != nil {
+		return nil, fmt.Errorf("invalid approval server config: %w", err)
+	}
+	server, ok := config.MCPServers[ServerName]
+	if !ok {
+		return nil, fmt.Errorf("approval server config has no %s server", ServerName)
+	}
+	return parseRoots(server.Args), nil
+}
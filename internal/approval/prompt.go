@@ -0,0 +1,167 @@
+package approval
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// previewLines caps how much of a change is shown before asking.
+const previewLines = 40
+
+// staleLockAge is how old a prompt lock must be before it is treated as left
+// behind by a crashed server.
+const staleLockAge = 10 * time.Minute
+
+// confirmOnTerminal shows the edit on the terminal and asks the user to
+// approve it. The server's stdin and stdout belong to Claude Code, so the
+// terminal is opened directly. Concurrent agents each run their own server;
+// a lock file keeps their prompts from interleaving.
+func confirmOnTerminal(toolName, path string, input map[string]interface{}) (bool, error) {
+	in, out, err := openTerminal()
+	if err != nil {
+		return false, err
+	}
+	defer in.Close()
+	defer out.Close()
+
+	unlock, err := lockPrompt()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	fmt.Fprintln(out, "\n"+strings.Repeat("-", 70))
+	fmt.Fprintf(out, "Claude wants to %s %s\n", describeTool(toolName), path)
+	fmt.Fprintln(out, strings.Repeat("-", 70))
+	printPreview(out, toolName, input)
+	fmt.Fprintln(out, strings.Repeat("-", 70))
+
+	reader := bufio.NewReader(in)
+	for {
+		fmt.Fprint(out, "Apply this edit? [y/n]: ")
+		answer, err := reader.ReadString('\n')
+		if err != nil && answer == "" {
+			return false, fmt.Errorf("failed to read answer: %w", err)
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			fmt.Fprintln(out, "✓ Approved")
+			return true, nil
+		case "n", "no":
+			fmt.Fprintln(out, "✗ Rejected")
+			return false, nil
+		}
+	}
+}
+
+func openTerminal() (io.ReadCloser, io.WriteCloser, error) {
+	inPath, outPath := "/dev/tty", "/dev/tty"
+	if runtime.GOOS == "windows" {
+		inPath, outPath = "CONIN$", "CONOUT$"
+	}
+
+	in, err := os.Open(inPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("no terminal to confirm edits on: %w", err)
+	}
+	out, err := os.OpenFile(outPath, os.O_WRONLY, 0)
+	if err != nil {
+		in.Close()
+		return nil, nil, fmt.Errorf("no terminal to confirm edits on: %w", err)
+	}
+	return in, out, nil
+}
+
+// lockPrompt waits for other approval servers to finish their prompt.
+func lockPrompt() (func(), error) {
+	path := filepath.Join(os.TempDir(), "docu-jarvis-approval.lock")
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create prompt lock: %w", err)
+		}
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(path)
+			continue
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+func describeTool(toolName string) string {
+	switch toolName {
+	case "Write":
+		return "write"
+	case "NotebookEdit":
+		return "edit the notebook"
+	}
+	return "edit"
+}
+
+// printPreview shows the change as removed (-) and added (+) lines.
+func printPreview(w io.Writer, toolName string, input map[string]interface{}) {
+	var lines []string
+	switch toolName {
+	case "Write":
+		content, _ := input["content"].(string)
+		lines = prefixLines("+", content)
+	case "Edit":
+		lines = editLines(input)
+	case "MultiEdit":
+		edits, _ := input["edits"].([]interface{})
+		for i, e := range edits {
+			edit, ok := e.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if i > 0 {
+				lines = append(lines, "")
+			}
+			lines = append(lines, editLines(edit)...)
+		}
+	case "NotebookEdit":
+		source, _ := input["new_source"].(string)
+		lines = prefixLines("+", source)
+	}
+
+	if len(lines) > previewLines {
+		omitted := len(lines) - previewLines
+		lines = append(lines[:previewLines], fmt.Sprintf("... (%d more lines)", omitted))
+	}
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+}
+
+func editLines(edit map[string]interface{}) []string {
+	oldString, _ := edit["old_string"].(string)
+	newString, _ := edit["new_string"].(string)
+	lines := prefixLines("-", oldString)
+	lines = append(lines, prefixLines("+", newString)...)
+	if all, _ := edit["replace_all"].(bool); all {
+		lines = append(lines, "(every occurrence)")
+	}
+	return lines
+}
+
+func prefixLines(prefix, text string) []string {
+	if text == "" {
+		return nil
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		lines = append(lines, prefix+line)
+	}
+	return lines
+}
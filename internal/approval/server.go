@@ -0,0 +1,233 @@
+package approval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ServerCommand is the hidden docu-jarvis command that runs the approval
+// server. Claude Code starts it as an MCP server over stdio and calls its tool
+// before each edit that is not already allowed.
+const ServerCommand = "__approve-edits"
+
+// ServerName and ToolName make up the tool's name in Claude Code,
+// mcp__<server>__<tool>.
+const (
+	ServerName = "docu-jarvis"
+	ToolName   = "approve_edit"
+)
+
+// PromptTool is the value for Claude Code's --permission-prompt-tool.
+const PromptTool = "mcp__" + ServerName + "__" + ToolName
+
+// editTools are the tools that modify files and need approval.
+var editTools = map[string]bool{"Write": true, "Edit": true, "MultiEdit": true, "NotebookEdit": true}
+
+// MCPConfig returns the --mcp-config JSON that starts the approval server
+// from the docu-jarvis executable, allowing edits under roots only.
+func MCPConfig(roots []string) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to find docu-jarvis executable: %w", err)
+	}
+
+	args := []string{ServerCommand}
+	for _, root := range roots {
+		args = append(args, "-root", root)
+	}
+
+	config := map[string]interface{}{
+		"mcpServers": map[string]interface{}{
+			ServerName: map[string]interface{}{
+				"command": exe,
+				"args":    args,
+			},
+		},
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// permissionRequest is the input Claude Code passes to the prompt tool.
+type permissionRequest struct {
+	ToolName string                 `json:"tool_name"`
+	Input    map[string]interface{} `json:"input"`
+}
+
+// decision is the reply Claude Code expects from the prompt tool.
+type decision struct {
+	Behavior     string                 `json:"behavior"`
+	UpdatedInput map[string]interface{} `json:"updatedInput,omitempty"`
+	Message      string                 `json:"message,omitempty"`
+}
+
+// Serve runs the approval server on in and out until in is closed. args are
+// the ServerCommand's arguments: -root <dir> for each directory edits may
+// touch.
+func Serve(in io.Reader, out io.Writer, args []string) error {
+	s := &server{roots: parseRoots(args)}
+	encoder := json.NewEncoder(out)
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var req rpcRequest
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			encoder.Encode(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: -32700, Message: "parse error"}})
+			continue
+		}
+		// Notifications get no response
+		if len(req.ID) == 0 {
+			continue
+		}
+
+		result, rpcErr := s.handle(req)
+		if err := encoder.Encode(rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr}); err != nil {
+			return fmt.Errorf("failed to write response: %w", err)
+		}
+	}
+	return scanner.Err()
+}
+
+func parseRoots(args []string) []string {
+	var roots []string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-root" && i+1 < len(args) {
+			roots = append(roots, filepath.Clean(args[i+1]))
+			i++
+		}
+	}
+	return roots
+}
+
+type server struct {
+	roots []string
+}
+
+func (s *server) handle(req rpcRequest) (interface{}, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(req.Params, &params)
+		if params.ProtocolVersion == "" {
+			params.ProtocolVersion = "2024-11-05"
+		}
+		return map[string]interface{}{
+			"protocolVersion": params.ProtocolVersion,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]string{"name": ServerName, "version": "1"},
+		}, nil
+
+	case "ping":
+		return map[string]interface{}{}, nil
+
+	case "tools/list":
+		return map[string]interface{}{
+			"tools": []interface{}{
+				map[string]interface{}{
+					"name":        ToolName,
+					"description": "Asks the docu-jarvis user to approve a file edit",
+					"inputSchema": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"tool_name": map[string]string{"type": "string"},
+							"input":     map[string]string{"type": "object"},
+						},
+						"required": []string{"tool_name", "input"},
+					},
+				},
+			},
+		}, nil
+
+	case "tools/call":
+		var params struct {
+			Name      string            `json:"name"`
+			Arguments permissionRequest `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.Name != ToolName {
+			return nil, &rpcError{Code: -32602, Message: "unknown tool call"}
+		}
+
+		text, err := json.Marshal(s.decide(params.Arguments))
+		if err != nil {
+			return nil, &rpcError{Code: -32603, Message: err.Error()}
+		}
+		return map[string]interface{}{
+			"content": []interface{}{map[string]string{"type": "text", "text": string(text)}},
+		}, nil
+	}
+
+	return nil, &rpcError{Code: -32601, Message: "method not found: " + req.Method}
+}
+
+// decide denies edits outside the roots and anything that is not an edit
+// outright, and asks the user about the rest.
+func (s *server) decide(req permissionRequest) decision {
+	if !editTools[req.ToolName] {
+		return decision{Behavior: "deny", Message: fmt.Sprintf("%s is not allowed in docu-jarvis", req.ToolName)}
+	}
+
+	path, _ := req.Input["file_path"].(string)
+	if path == "" {
+		path, _ = req.Input["notebook_path"].(string)
+	}
+	if path == "" || !s.inRoots(path) {
+		return decision{Behavior: "deny", Message: fmt.Sprintf("%s is outside the directories docu-jarvis may edit", path)}
+	}
+
+	approved, err := confirmOnTerminal(req.ToolName, path, req.Input)
+	if err != nil {
+		return decision{Behavior: "deny", Message: fmt.Sprintf("could not ask the user to approve the edit: %v", err)}
+	}
+	if !approved {
+		return decision{Behavior: "deny", Message: "The user rejected this edit. Do not retry it; continue with the next change, or stop if there is none."}
+	}
+	return decision{Behavior: "allow", UpdatedInput: req.Input}
+}
+
+func (s *server) inRoots(path string) bool {
+	if !filepath.IsAbs(path) {
+		return false
+	}
+	path = filepath.Clean(path)
+	for _, root := range s.roots {
+		if rel, err := filepath.Rel(root, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,266 @@
+// Package archive moves deprecated docs into the archive folder of their
+// docs root, with a deprecation banner, and rewrites the links to them.
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/udemy/docu-jarvis-cli/internal/frontmatter"
+	"github.com/udemy/docu-jarvis-cli/internal/links"
+)
+
+// Dir is the folder of a docs root that holds its archived docs.
+const Dir = "archive"
+
+// archivedHeading is the section of an index that lists archived docs.
+const archivedHeading = "## Archived"
+
+// indexNames are the file names of the docs that index a folder.
+var indexNames = map[string]bool{"readme.md": true, "index.md": true, "index.mdx": true, "_index.md": true, "summary.md": true}
+
+var listItem = regexp.MustCompile(`^\s*([-*+]|\d+\.)\s`)
+
+// Move is a doc to archive.
+type Move struct {
+	File   string // relative to the repository root, as are the paths below
+	To     string
+	Reason string // shown in the banner; may be empty
+}
+
+// Result lists what archiving changed.
+type Result struct {
+	Moved   []Move
+	Links   map[string]int // links rewritten per file
+	Indexes []string       // indexes whose entries moved to the Archived section
+}
+
+// Plan returns where each doc goes: the archive folder of the docs root that
+// holds it, keeping its path below the root.
+func Plan(docsRoots, files []string, reason string) ([]Move, error) {
+	var moves []Move
+	for _, file := range files {
+		root := docsRootOf(file, docsRoots)
+		if root == "" {
+			return nil, fmt.Errorf("%s is not in a docs root (%s)", file, strings.Join(docsRoots, ", "))
+		}
+		rel := strings.TrimPrefix(file, root+"/")
+		if rel == Dir || strings.HasPrefix(rel, Dir+"/") {
+			return nil, fmt.Errorf("%s is already archived", file)
+		}
+		moves = append(moves, Move{File: file, To: root + "/" + Dir + "/" + rel, Reason: reason})
+	}
+	return moves, nil
+}
+
+// Apply moves the docs, adds the banner, and rewrites the links to them in
+// the markdown files of the docs roots. With dryRun nothing is written.
+func Apply(repoRoot string, docsRoots []string, moves []Move, now time.Time, dryRun bool) (*Result, error) {
+	result := &Result{Links: make(map[string]int)}
+	for _, move := range moves {
+		if _, err := os.Stat(filepath.Join(repoRoot, move.To)); err == nil {
+			return nil, fmt.Errorf("cannot archive %s: %s already exists", move.File, move.To)
+		}
+	}
+
+	for _, move := range moves {
+		content, err := os.ReadFile(filepath.Join(repoRoot, move.File))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", move.File, err)
+		}
+		archived := addBanner(links.Rebase(string(content), move.File, move.To), move.Reason, now)
+		if !dryRun {
+			dest := filepath.Join(repoRoot, move.To)
+			if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+				return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(move.To), err)
+			}
+			if err := os.WriteFile(dest, []byte(archived), 0644); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %w", move.To, err)
+			}
+			if err := os.Remove(filepath.Join(repoRoot, move.File)); err != nil {
+				return nil, fmt.Errorf("failed to remove %s: %w", move.File, err)
+			}
+		}
+		result.Moved = append(result.Moved, move)
+	}
+
+	files, err := markdownFiles(repoRoot, docsRoots)
+	if err != nil {
+		return nil, err
+	}
+	moved := make(map[string]bool)
+	for _, move := range moves {
+		moved[move.File] = true
+	}
+	for _, file := range files {
+		// In a dry run the moved docs are still at their old path
+		if dryRun && moved[file] {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(repoRoot, file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		updated := string(content)
+		if isIndex(file) {
+			var entriesMoved bool
+			updated, entriesMoved = moveIndexEntries(updated, file, moves)
+			if entriesMoved {
+				result.Indexes = append(result.Indexes, file)
+			}
+		}
+		total := 0
+		for _, move := range moves {
+			var n int
+			updated, n = links.Retarget(updated, file, move.File, move.To)
+			total += n
+		}
+		if total == 0 {
+			continue
+		}
+		result.Links[file] = total
+
+		if !dryRun {
+			if err := os.WriteFile(filepath.Join(repoRoot, file), []byte(updated), 0644); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %w", file, err)
+			}
+		}
+	}
+	return result, nil
+}
+
+// addBanner puts the deprecation notice below the front matter and title.
+func addBanner(content, reason string, now time.Time) string {
+	banner := fmt.Sprintf("> **Deprecated:** this document was archived on %s and may no longer match the code.", now.Format("2006-01-02"))
+	if reason != "" {
+		banner += " " + strings.TrimSuffix(strings.TrimSpace(reason), ".") + "."
+	}
+
+	front, body, hasFront := frontmatter.Split(content)
+	lines := strings.Split(body, "\n")
+	at := 0
+	for at < len(lines) && strings.TrimSpace(lines[at]) == "" {
+		at++
+	}
+	if at < len(lines) && strings.HasPrefix(lines[at], "# ") {
+		at++
+	}
+	rest := strings.TrimLeft(strings.Join(lines[at:], "\n"), "\n")
+	body = strings.Join(lines[:at], "\n")
+	if body != "" {
+		body += "\n\n"
+	}
+	body += banner + "\n\n" + rest
+
+	if hasFront {
+		return "---\n" + front + "---\n" + body
+	}
+	return body
+}
+
+// moveIndexEntries moves the list items of an index that link to the moved
+// docs to its Archived section, which is added at the end when missing.
+func moveIndexEntries(content, file string, moves []Move) (string, bool) {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	var kept, entries []string
+	archivedAt := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == archivedHeading {
+			archivedAt = len(kept)
+		}
+		if archivedAt < 0 && listItem.MatchString(line) && linksToMoved(line, file, moves) {
+			entries = append(entries, line)
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if len(entries) == 0 {
+		return content, false
+	}
+
+	if archivedAt < 0 {
+		kept = append(kept, "", archivedHeading, "")
+		kept = append(kept, entries...)
+	} else {
+		// After the heading and the entries already listed
+		end := archivedAt + 1
+		for end < len(kept) && (strings.TrimSpace(kept[end]) == "" || listItem.MatchString(kept[end])) {
+			end++
+		}
+		for end > archivedAt+1 && strings.TrimSpace(kept[end-1]) == "" {
+			end--
+		}
+		insert := entries
+		if end == archivedAt+1 {
+			insert = append([]string{""}, entries...)
+		}
+		kept = append(kept[:end], append(insert, kept[end:]...)...)
+	}
+	return strings.Join(kept, "\n") + "\n", true
+}
+
+func linksToMoved(line, file string, moves []Move) bool {
+	for _, move := range moves {
+		if _, n := links.Retarget(line, file, move.File, move.To); n > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func isIndex(file string) bool {
+	return indexNames[strings.ToLower(filepath.Base(file))]
+}
+
+// docsRootOf returns the docs root that holds file, or "" when none does.
+func docsRootOf(file string, docsRoots []string) string {
+	for _, root := range docsRoots {
+		if strings.HasPrefix(file, root+"/") {
+			return root
+		}
+	}
+	return ""
+}
+
+// markdownFiles returns the markdown files of the docs roots, relative to the
+// repository root, without hidden directories and node_modules.
+func markdownFiles(repoRoot string, docsRoots []string) ([]string, error) {
+	var files []string
+	for _, root := range docsRoots {
+		dir := filepath.Join(repoRoot, root)
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if path == dir && errors.Is(err, fs.ErrNotExist) {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if d.IsDir() {
+				if path != dir && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".md", ".mdx", ".markdown":
+				rel, err := filepath.Rel(repoRoot, path)
+				if err != nil {
+					return err
+				}
+				files = append(files, filepath.ToSlash(rel))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list markdown files: %w", err)
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,168 @@
+// Package assets finds the images documentation references and the
+// screenshot placeholders Claude leaves in it, <!-- image: name | what it
+// shows -->, whose images belong in the assets folder of the docs root.
+package assets
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Dir is the folder of a docs root that holds its images.
+const Dir = "assets"
+
+// imageExts are the extensions a placeholder's image may have, in the order
+// they are looked for; new screenshots default to the first.
+var imageExts = []string{".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp"}
+
+var (
+	placeholderPattern = regexp.MustCompile(`<!--\s*image:\s*([^|>]*?)\s*(?:\|\s*(.*?)\s*)?-->`)
+	markdownImage      = regexp.MustCompile(`!\[[^\]]*\]\(\s*<?([^)\s>]+)>?(?:\s+["'][^"']*["'])?\s*\)`)
+	htmlImage          = regexp.MustCompile(`<img\s[^>]*src=["']([^"']+)["']`)
+	fencePattern       = regexp.MustCompile("^ {0,3}(```|~~~)")
+)
+
+// Placeholder marks where a screenshot belongs.
+type Placeholder struct {
+	File        string // relative to the repository root, as are the paths below
+	Line        int
+	Name        string
+	Description string
+	Asset       string // the image, or where it should be added
+	Captured    bool   // the image exists, so the placeholder can be replaced
+}
+
+// Reference is a local image a document links to.
+type Reference struct {
+	File   string
+	Line   int
+	Target string // as written in the document
+	Path   string // resolved
+}
+
+// Report lists the placeholders and image references of the documents
+// scanned.
+type Report struct {
+	Placeholders []Placeholder
+	References   int
+	Missing      []Reference
+}
+
+// Pending returns the placeholders whose image does not exist yet.
+func (r *Report) Pending() []Placeholder {
+	var pending []Placeholder
+	for _, p := range r.Placeholders {
+		if !p.Captured {
+			pending = append(pending, p)
+		}
+	}
+	return pending
+}
+
+// Scan reads the markdown files, given relative to the repository root.
+// Placeholders are resolved in the assets folder of the docs root that holds
+// their document.
+func Scan(repoRoot string, docsRoots, files []string) (*Report, error) {
+	report := &Report{}
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(repoRoot, file))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		inFence := false
+		for i, line := range strings.Split(string(content), "\n") {
+			if fencePattern.MatchString(line) {
+				inFence = !inFence
+				continue
+			}
+			if inFence {
+				continue
+			}
+
+			for _, m := range placeholderPattern.FindAllStringSubmatch(line, -1) {
+				p := Placeholder{File: file, Line: i + 1, Name: m[1], Description: m[2]}
+				p.Asset, p.Captured = findAsset(repoRoot, docsRootOf(file, docsRoots), p.Name)
+				report.Placeholders = append(report.Placeholders, p)
+			}
+
+			var targets []string
+			for _, m := range markdownImage.FindAllStringSubmatch(line, -1) {
+				targets = append(targets, m[1])
+			}
+			for _, m := range htmlImage.FindAllStringSubmatch(line, -1) {
+				targets = append(targets, m[1])
+			}
+			for _, target := range targets {
+				path, ok := resolve(file, target)
+				if !ok {
+					continue
+				}
+				report.References++
+				if _, err := os.Stat(filepath.Join(repoRoot, path)); err != nil {
+					report.Missing = append(report.Missing, Reference{File: file, Line: i + 1, Target: target, Path: path})
+				}
+			}
+		}
+	}
+	return report, nil
+}
+
+// resolve returns the path of a local image, relative to the repository
+// root; paths starting with / are taken from the root. Remote and data URLs
+// are not checked.
+func resolve(file, target string) (string, bool) {
+	if strings.Contains(target, "://") || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "data:") || strings.HasPrefix(target, "#") {
+		return "", false
+	}
+	target, _, _ = strings.Cut(target, "#")
+	target, _, _ = strings.Cut(target, "?")
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	if target == "" {
+		return "", false
+	}
+
+	var path string
+	if strings.HasPrefix(target, "/") {
+		path = filepath.Clean(strings.TrimPrefix(target, "/"))
+	} else {
+		path = filepath.Join(filepath.Dir(file), target)
+	}
+	return filepath.ToSlash(path), true
+}
+
+// docsRootOf returns the docs root that holds file, or its directory when
+// none does.
+func docsRootOf(file string, docsRoots []string) string {
+	for _, root := range docsRoots {
+		if strings.HasPrefix(file, root+"/") {
+			return root
+		}
+	}
+	return filepath.ToSlash(filepath.Dir(file))
+}
+
+// findAsset returns the image of a placeholder and whether it exists, or
+// where a new screenshot should go.
+func findAsset(repoRoot, docsRoot, name string) (string, bool) {
+	base := filepath.ToSlash(filepath.Join(docsRoot, Dir, name))
+	if filepath.Ext(name) != "" {
+		_, err := os.Stat(filepath.Join(repoRoot, base))
+		return base, err == nil
+	}
+	for _, ext := range imageExts {
+		if _, err := os.Stat(filepath.Join(repoRoot, base+ext)); err == nil {
+			return base + ext, true
+		}
+	}
+	return base + imageExts[0], false
+}
@@ -0,0 +1,155 @@
+// Package baseline records the code standard violations a repository already
+// has, so check-staging -only-new fails only on the ones a change introduces.
+package baseline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/udemy/docu-jarvis-cli/internal/agent"
+)
+
+// FileName is the baseline in the repository root, committed so every
+// checkout and CI job shares it.
+const FileName = ".docu-jarvis-baseline.json"
+
+const schemaVersion = 1
+
+// Baseline counts the known violations by fingerprint. Line numbers are left
+// out of the fingerprint since they move with every edit above them.
+type Baseline struct {
+	SchemaVersion int         `json:"schema_version"`
+	Violations    []Violation `json:"violations"`
+	path          string
+}
+
+// Violation is a rule that a file breaks Count times.
+type Violation struct {
+	Fingerprint string `json:"fingerprint"`
+	File        string `json:"file"`
+	Standard    string `json:"standard"`
+	Count       int    `json:"count"`
+}
+
+// Fingerprint identifies the violations of a standard in a file.
+func Fingerprint(finding agent.QualityFinding) string {
+	sum := sha256.Sum256([]byte(cleanFile(finding.File) + "\x00" + normalize(finding.Standard)))
+	return hex.EncodeToString(sum[:8])
+}
+
+func cleanFile(file string) string {
+	return path.Clean(strings.TrimPrefix(filepath.ToSlash(strings.TrimSpace(file)), "b/"))
+}
+
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// Load reads the baseline of the repository; a repository without one has an
+// empty baseline.
+func Load(repoRoot string) (*Baseline, error) {
+	b := &Baseline{SchemaVersion: schemaVersion, path: filepath.Join(repoRoot, FileName)}
+
+	content, err := os.ReadFile(b.path)
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+	if err := json.Unmarshal(content, b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", b.path, err)
+	}
+	if b.SchemaVersion > schemaVersion {
+		return nil, fmt.Errorf("baseline %s was written by a newer docu-jarvis (schema %d)", b.path, b.SchemaVersion)
+	}
+	b.SchemaVersion = schemaVersion
+	return b, nil
+}
+
+func (b *Baseline) Save() error {
+	sort.Slice(b.Violations, func(i, j int) bool {
+		if b.Violations[i].File != b.Violations[j].File {
+			return b.Violations[i].File < b.Violations[j].File
+		}
+		return b.Violations[i].Standard < b.Violations[j].Standard
+	})
+
+	content, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode baseline: %w", err)
+	}
+	if err := os.WriteFile(b.path, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write baseline: %w", err)
+	}
+	return nil
+}
+
+// Path returns the baseline file.
+func (b *Baseline) Path() string {
+	return b.path
+}
+
+// Record adds the findings to the baseline. Counts only go up: a review of a
+// diff sees only part of a file, so fewer findings do not mean fewer
+// violations. It returns how many violations were added.
+func (b *Baseline) Record(findings []agent.QualityFinding) int {
+	counts := make(map[string]int)
+	for _, finding := range findings {
+		counts[Fingerprint(finding)]++
+	}
+
+	added := 0
+	index := b.index()
+	for _, finding := range findings {
+		fingerprint := Fingerprint(finding)
+		if i, ok := index[fingerprint]; ok {
+			if counts[fingerprint] > b.Violations[i].Count {
+				added += counts[fingerprint] - b.Violations[i].Count
+				b.Violations[i].Count = counts[fingerprint]
+			}
+			continue
+		}
+		index[fingerprint] = len(b.Violations)
+		b.Violations = append(b.Violations, Violation{
+			Fingerprint: fingerprint,
+			File:        cleanFile(finding.File),
+			Standard:    strings.TrimSpace(finding.Standard),
+			Count:       counts[fingerprint],
+		})
+		added += counts[fingerprint]
+	}
+	return added
+}
+
+// Known returns a check of whether the baseline covers a finding: it covers
+// up to Count findings of each fingerprint, in the order they are checked.
+func (b *Baseline) Known() func(agent.QualityFinding) bool {
+	remaining := make(map[string]int)
+	for _, violation := range b.Violations {
+		remaining[violation.Fingerprint] += violation.Count
+	}
+	return func(finding agent.QualityFinding) bool {
+		fingerprint := Fingerprint(finding)
+		if remaining[fingerprint] > 0 {
+			remaining[fingerprint]--
+			return true
+		}
+		return false
+	}
+}
+
+func (b *Baseline) index() map[string]int {
+	index := make(map[string]int)
+	for i, violation := range b.Violations {
+		index[violation.Fingerprint] = i
+	}
+	return index
+}
@@ -0,0 +1,145 @@
+// Package cache remembers what update-docs last updated each doc from, in
+// ~/.docu-jarvis/cache, so that a doc whose inputs have not changed since is
+// skipped instead of costing the same tokens again. An entry is keyed by a
+// hash of the configuration, the repository, the doc's path, and its content
+// after the update, and holds a hash of the source files Claude read for it.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry records the sources a doc was updated from.
+type Entry struct {
+	Doc         string    `json:"doc"`     // relative to the repository root
+	Sources     []string  `json:"sources"` // relative to the repository root
+	SourcesHash string    `json:"sources_hash"`
+	Updated     time.Time `json:"updated"`
+}
+
+func cacheDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".docu-jarvis", "cache"), nil
+}
+
+// Key hashes the parts that identify a doc's inputs.
+func Key(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashSources hashes the content of the source files, given relative to
+// root. A missing file hashes differently from an empty one, so deleting a
+// source changes the hash.
+func HashSources(root string, sources []string) string {
+	sorted := append([]string(nil), sources...)
+	sort.Strings(sorted)
+
+	h := sha256.New()
+	for _, source := range sorted {
+		h.Write([]byte(source + "\x00"))
+		content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(source)))
+		if err != nil {
+			h.Write([]byte("missing\x00"))
+			continue
+		}
+		sum := sha256.Sum256(content)
+		h.Write(sum[:])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Fresh reports whether the entry for key exists and the sources it records
+// are unchanged in root.
+func Fresh(key, root string) bool {
+	dir, err := cacheDir()
+	if err != nil {
+		return false
+	}
+	content, err := os.ReadFile(filepath.Join(dir, key+".json"))
+	if err != nil {
+		return false
+	}
+	var entry Entry
+	if err := json.Unmarshal(content, &entry); err != nil || len(entry.Sources) == 0 {
+		return false
+	}
+	return HashSources(root, entry.Sources) == entry.SourcesHash
+}
+
+// Store saves the entry under key.
+func Store(key string, entry Entry) error {
+	dir, err := cacheDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	content, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, key+".json"), content, 0644); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// Stats returns how many entries the cache has and their total size.
+func Stats() (entries int, size int64, err error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return 0, 0, err
+	}
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+	for _, file := range files {
+		if info, err := file.Info(); err == nil && strings.HasSuffix(file.Name(), ".json") {
+			entries++
+			size += info.Size()
+		}
+	}
+	return entries, size, nil
+}
+
+// Clear removes every entry and returns how many there were.
+func Clear() (int, error) {
+	entries, _, err := Stats()
+	if err != nil {
+		return 0, err
+	}
+	dir, err := cacheDir()
+	if err != nil {
+		return 0, err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return 0, fmt.Errorf("failed to clear cache: %w", err)
+	}
+	return entries, nil
+}
+
+// Dir returns the cache directory, for showing it.
+func Dir() string {
+	dir, err := cacheDir()
+	if err != nil {
+		return "~/.docu-jarvis/cache"
+	}
+	return dir
+}
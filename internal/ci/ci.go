@@ -0,0 +1,94 @@
+// Package ci holds the CI mode of docu-jarvis: no interactive prompts, and on
+// GitHub Actions, workflow annotations and a job summary.
+package ci
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EnvVar turns CI mode on for every run, like -ci.
+const EnvVar = "DOCU_JARVIS_CI"
+
+var enabled = os.Getenv(EnvVar) != "" && os.Getenv(EnvVar) != "0"
+
+// Enable turns on CI mode for the rest of the run.
+func Enable() {
+	enabled = true
+}
+
+func Enabled() bool {
+	return enabled
+}
+
+// GitHubActions reports whether the run is a GitHub Actions job.
+func GitHubActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// Annotation is a workflow command that GitHub shows on a line of a file in
+// the job and the pull request diff.
+type Annotation struct {
+	Level   string // "error", "warning", or "notice"
+	File    string // relative to the repository root
+	Line    int    // 0 for the whole file
+	Title   string
+	Message string
+}
+
+func (a Annotation) String() string {
+	var props []string
+	if a.File != "" {
+		props = append(props, "file="+escapeProperty(a.File))
+		if a.Line > 0 {
+			props = append(props, "line="+strconv.Itoa(a.Line))
+		}
+	}
+	if a.Title != "" {
+		props = append(props, "title="+escapeProperty(a.Title))
+	}
+
+	command := "::" + a.Level
+	if len(props) > 0 {
+		command += " " + strings.Join(props, ",")
+	}
+	return command + "::" + escapeData(a.Message)
+}
+
+// Annotate writes the annotations; GitHub reads them from the job output.
+func Annotate(w io.Writer, annotations []Annotation) {
+	for _, annotation := range annotations {
+		fmt.Fprintln(w, annotation)
+	}
+}
+
+// WriteSummary appends markdown to the job summary. Outside GitHub Actions,
+// where there is no summary file, it does nothing.
+func WriteSummary(markdown string) error {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open job summary: %w", err)
+	}
+	defer f.Close()
+	if _, err := io.WriteString(f, strings.TrimRight(markdown, "\n")+"\n\n"); err != nil {
+		return fmt.Errorf("failed to write job summary: %w", err)
+	}
+	return nil
+}
+
+// escapeData escapes a message as the workflow command syntax requires.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a property value, which also cannot hold : or ,.
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
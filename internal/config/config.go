@@ -2,12 +2,20 @@ package config
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/udemy/docu-jarvis-cli/internal/settings"
 )
 
+const placeholderRepoURL = "https://github.com/your-org/your-repo.git"
+
 type Config struct {
-	RepoURL string
+	RepoURL    string   // the repository to clone, the first in Repos by default
+	Repos      []string // every configured repository
+	CloneDir   string
+	CloneDepth int
+	ReuseClone bool
+	Branch     string
 }
 
 func Load() (*Config, error) {
@@ -16,18 +24,59 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to load settings: %w", err)
 	}
 
-	repoURL := s.GetRepoURL()
-	if repoURL == "" || repoURL == "https://github.com/your-org/your-repo.git" {
-		return nil, fmt.Errorf("repository URL not configured.\n\nConfigure it:\n  docu-jarvis -config\n\nOr use environment variable:\n  export REPO_URL=\"https://github.com/your-org/your-repo.git\"")
+	var repos []string
+	for _, repoURL := range s.Repos {
+		if repoURL != "" && repoURL != placeholderRepoURL {
+			repos = append(repos, repoURL)
+		}
+	}
+	if len(repos) == 0 {
+		return nil, fmt.Errorf("repository URL not configured.\n\nConfigure it:\n  docu-jarvis config\n\nOr use environment variable:\n  export REPO_URL=\"https://github.com/your-org/your-repo.git\"")
 	}
 
 	return &Config{
-		RepoURL: repoURL,
+		RepoURL:    repos[0],
+		Repos:      repos,
+		CloneDir:   s.CloneDir,
+		CloneDepth: s.CloneDepth,
+		ReuseClone: s.ReuseClone,
+		Branch:     s.Branch,
 	}, nil
 }
 
+// SelectRepo makes the repository named by selector the one to clone. The
+// selector is a configured repository's name (e.g. "payments" for
+// .../payments.git) or URL; other URLs are used as given.
+func (c *Config) SelectRepo(selector string) error {
+	if selector == "" {
+		return nil
+	}
+
+	for _, repoURL := range c.Repos {
+		if repoURL == selector || strings.EqualFold(RepoName(repoURL), selector) {
+			c.RepoURL = repoURL
+			return nil
+		}
+	}
+
+	if strings.Contains(selector, "://") || strings.Contains(selector, "@") {
+		c.RepoURL = selector
+		return nil
+	}
+
+	var names []string
+	for _, repoURL := range c.Repos {
+		names = append(names, RepoName(repoURL))
+	}
+	return fmt.Errorf("unknown repository %q (configured: %s)", selector, strings.Join(names, ", "))
+}
+
 func (c *Config) GetRepoName() string {
-	repoURL := c.RepoURL
+	return RepoName(c.RepoURL)
+}
+
+// RepoName is the last path element of a repository URL, without .git.
+func RepoName(repoURL string) string {
 	// Extract the last part of the URL
 	parts := []rune(repoURL)
 	lastSlash := -1
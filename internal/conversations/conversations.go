@@ -0,0 +1,195 @@
+// Package conversations saves explain sessions to
+// ~/.docu-jarvis/conversations/<id>.json after every answer, so they can be
+// resumed, shown, and exported later.
+package conversations
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Roles of a Message.
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+type Message struct {
+	Role    string    `json:"role"`
+	Content string    `json:"content"`
+	Time    time.Time `json:"time"`
+}
+
+// Conversation is an explain session about a commit, a range of commits, or
+// the commits between two deploys. Its ID names what was explained by full
+// hashes, so the same commits resume the same conversation however they were
+// given on the command line.
+type Conversation struct {
+	ID         string    `json:"id"`
+	Subject    string    `json:"subject"` // as given on the command line
+	Repository string    `json:"repository,omitempty"`
+	Started    time.Time `json:"started"`
+	Updated    time.Time `json:"updated"`
+	Messages   []Message `json:"messages"`
+
+	path string
+}
+
+func conversationsDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".docu-jarvis", "conversations"), nil
+}
+
+func pathFor(id string) (string, error) {
+	dir, err := conversationsDir()
+	if err != nil {
+		return "", err
+	}
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("invalid conversation ID: %s", id)
+	}
+	return filepath.Join(dir, id+".json"), nil
+}
+
+// New starts a conversation. It is saved with its first answer.
+func New(id, subject, repository string) (*Conversation, error) {
+	path, err := pathFor(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &Conversation{ID: id, Subject: subject, Repository: repository, Started: now, Updated: now, path: path}, nil
+}
+
+// Load reads a saved conversation.
+func Load(id string) (*Conversation, error) {
+	path, err := pathFor(id)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("conversation %s not found (see 'docu-jarvis conversations list')", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read conversation %s: %w", id, err)
+	}
+
+	c := &Conversation{path: path}
+	if err := json.Unmarshal(content, c); err != nil {
+		return nil, fmt.Errorf("failed to parse conversation %s: %w", id, err)
+	}
+	return c, nil
+}
+
+// Exists reports whether a conversation is saved under the ID.
+func Exists(id string) bool {
+	path, err := pathFor(id)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// List returns every saved conversation, most recently updated first. Files
+// that cannot be parsed are skipped.
+func List() ([]*Conversation, error) {
+	dir, err := conversationsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversations: %w", err)
+	}
+
+	var conversations []*Conversation
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		c, err := Load(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		conversations = append(conversations, c)
+	}
+
+	sort.Slice(conversations, func(i, j int) bool { return conversations[i].Updated.After(conversations[j].Updated) })
+	return conversations, nil
+}
+
+// Markdown renders the conversation with each question as a heading and the
+// final answers below them.
+func (c *Conversation) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Explaining %s\n\n", c.Subject)
+	if c.Repository != "" {
+		fmt.Fprintf(&b, "- **Repository:** %s\n", c.Repository)
+	}
+	fmt.Fprintf(&b, "- **Commits:** `%s`\n", c.ID)
+	fmt.Fprintf(&b, "- **Started:** %s\n", c.Started.Local().Format("2006-01-02 15:04"))
+	fmt.Fprintf(&b, "- **Updated:** %s\n", c.Updated.Local().Format("2006-01-02 15:04"))
+
+	for _, msg := range c.Messages {
+		if msg.Role == RoleUser {
+			fmt.Fprintf(&b, "\n## %s\n", strings.Join(strings.Fields(msg.Content), " "))
+		} else {
+			fmt.Fprintf(&b, "\n%s\n", FinalAnswer(msg.Content))
+		}
+	}
+	return b.String()
+}
+
+// FinalAnswer returns the <answer> of a response that has one, dropping the
+// <analysis> that leads up to it.
+func FinalAnswer(response string) string {
+	start := strings.Index(response, "<answer>")
+	end := strings.LastIndex(response, "</answer>")
+	if start < 0 || end < start {
+		return strings.TrimSpace(response)
+	}
+	return strings.TrimSpace(response[start+len("<answer>") : end])
+}
+
+// Add appends a message.
+func (c *Conversation) Add(role, content string) {
+	c.Messages = append(c.Messages, Message{Role: role, Content: content, Time: time.Now()})
+}
+
+// Save writes the conversation, replacing the file atomically so an
+// interrupted write cannot leave it half written.
+func (c *Conversation) Save() error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create conversations directory: %w", err)
+	}
+
+	c.Updated = time.Now()
+	content, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode conversation: %w", err)
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return fmt.Errorf("failed to write conversation: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("failed to write conversation: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+// Package dates parses the dates given to debug and other history commands
+// into exact times, so a range means the same on every machine instead of
+// depending on how git reads it there.
+package dates
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats a date can be given in, besides RFC 3339 and the relative forms.
+var layouts = []struct {
+	layout  string
+	dayOnly bool
+}{
+	{"2006-01-02", true},
+	{"2006-01-02 15:04", false},
+	{"2006-01-02T15:04", false},
+	{"2006-01-02 15:04:05", false},
+	{"2006-01-02T15:04:05", false},
+}
+
+var (
+	relativePattern = regexp.MustCompile(`^(\d+)[ .]+(minute|hour|day|week|month|year)s?[ .]+ago$`)
+	// offsetPattern matches a numeric zone at the end: Z, +02:00, -0700
+	offsetPattern = regexp.MustCompile(`^(.*\d)\s*(Z|[+-]\d{2}:?\d{2})$`)
+)
+
+// Parse resolves a date such as "2024-03-01", "2024-03-01 09:30",
+// "2024-03-01T09:30:00+02:00", "2024-03-01 09:30 America/New_York",
+// "3 days ago", "yesterday", "today", or "now". Dates without a zone are in
+// loc. A day without a time is its start, or with end its last second, so
+// that a range of days includes the last one.
+func Parse(value string, now time.Time, loc *time.Location, end bool) (time.Time, error) {
+	text := strings.ToLower(strings.Join(strings.Fields(value), " "))
+	now = now.In(loc)
+	startOfDay := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+	endOfDay := func(t time.Time) time.Time {
+		return startOfDay(t).AddDate(0, 0, 1).Add(-time.Second)
+	}
+
+	switch text {
+	case "":
+		return time.Time{}, fmt.Errorf("empty date")
+	case "now":
+		return now, nil
+	case "today":
+		if end {
+			return now, nil
+		}
+		return startOfDay(now), nil
+	case "yesterday":
+		if end {
+			return endOfDay(now.AddDate(0, 0, -1)), nil
+		}
+		return startOfDay(now.AddDate(0, 0, -1)), nil
+	}
+
+	if m := relativePattern.FindStringSubmatch(text); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", value)
+		}
+		switch m[2] {
+		case "minute":
+			return now.Add(-time.Duration(n) * time.Minute), nil
+		case "hour":
+			return now.Add(-time.Duration(n) * time.Hour), nil
+		case "day":
+			return now.AddDate(0, 0, -n), nil
+		case "week":
+			return now.AddDate(0, 0, -7*n), nil
+		case "month":
+			return now.AddDate(0, -n, 0), nil
+		default:
+			return now.AddDate(-n, 0, 0), nil
+		}
+	}
+
+	if t, err := time.Parse(time.RFC3339, strings.ToUpper(strings.TrimSpace(value))); err == nil {
+		return t, nil
+	}
+
+	// A zone, by offset or by name, may follow the date
+	text = strings.TrimSpace(value)
+	zone := loc
+	if m := offsetPattern.FindStringSubmatch(text); m != nil {
+		offset, err := parseOffset(m[2])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time zone in %q: %w", value, err)
+		}
+		text, zone = strings.TrimSpace(m[1]), offset
+	} else if i := strings.LastIndex(text, " "); i > 0 && !strings.ContainsAny(text[i+1:], "0123456789") {
+		named, err := time.LoadLocation(text[i+1:])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unknown time zone %q in %q", text[i+1:], value)
+		}
+		text, zone = strings.TrimSpace(text[:i]), named
+	}
+
+	for _, l := range layouts {
+		t, err := time.ParseInLocation(l.layout, text, zone)
+		if err != nil {
+			continue
+		}
+		if l.dayOnly && end {
+			t = t.AddDate(0, 0, 1).Add(-time.Second)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (use e.g. 2024-03-01, \"2024-03-01 09:30 UTC\", or \"3 days ago\")", value)
+}
+
+func parseOffset(text string) (*time.Location, error) {
+	if text == "Z" {
+		return time.UTC, nil
+	}
+	digits := strings.ReplaceAll(text[1:], ":", "")
+	hours, _ := strconv.Atoi(digits[:2])
+	minutes, _ := strconv.Atoi(digits[2:])
+	if hours > 14 || minutes > 59 {
+		return nil, fmt.Errorf("offset %s out of range", text)
+	}
+	seconds := hours*3600 + minutes*60
+	if text[0] == '-' {
+		seconds = -seconds
+	}
+	return time.FixedZone(text, seconds), nil
+}
+
+// Range parses the from and to dates of a range, in the local time zone,
+// and checks that from comes first.
+func Range(from, to string) (time.Time, time.Time, error) {
+	now := time.Now()
+	start, err := Parse(from, now, time.Local, false)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid from-date: %w", err)
+	}
+	end, err := Parse(to, now, time.Local, true)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid to-date: %w", err)
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("the from-date (%s) must be before the to-date (%s)", Format(start), Format(end))
+	}
+	return start, end, nil
+}
+
+// Git formats a time for git's --since, --until, and --shallow-since, which
+// read ISO 8601 with its offset exactly.
+func Git(t time.Time) string {
+	return t.Format("2006-01-02T15:04:05-07:00")
+}
+
+// Format shows a resolved time to the user, with its zone.
+func Format(t time.Time) string {
+	// Zones given by offset are named by it already
+	if name, _ := t.Zone(); strings.HasPrefix(name, "+") || strings.HasPrefix(name, "-") {
+		return t.Format("2006-01-02 15:04:05 -07:00")
+	}
+	return t.Format("2006-01-02 15:04:05 MST (-07:00)")
+}
@@ -0,0 +1,191 @@
+// Package debughistory keeps the outcomes of past debug runs: the bug, the
+// commit debug blamed, and whether the user confirmed it was the cause. Later
+// runs on the repository consult the cases most like their bug.
+package debughistory
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	historyFileName = "debug_history.json"
+
+	// maxCases is how many cases are kept per repository; the oldest go
+	// first.
+	maxCases = 200
+
+	// minSimilarity is the share of their words two bug descriptions must
+	// have in common to count as similar.
+	minSimilarity = 0.2
+)
+
+// Store holds the cases, keyed by repository remote URL like the doc queue.
+type Store struct {
+	Repos map[string][]Case `json:"repos"`
+	path  string
+}
+
+// Case is the outcome of one debug run.
+type Case struct {
+	Bug         string    `json:"bug"`
+	Commit      string    `json:"commit"`
+	Subject     string    `json:"subject"`
+	Explanation string    `json:"explanation"`
+	Files       []string  `json:"files,omitempty"` // changed by the commit
+	Confirmed   bool      `json:"confirmed"`       // false: the user said it was not the cause
+	Recorded    time.Time `json:"recorded"`
+}
+
+func Load() (*Store, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	configDir := filepath.Join(homeDir, ".docu-jarvis")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	s := &Store{
+		Repos: make(map[string][]Case),
+		path:  filepath.Join(configDir, historyFileName),
+	}
+
+	content, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read debug history: %w", err)
+	}
+
+	if err := json.Unmarshal(content, s); err != nil {
+		return nil, fmt.Errorf("failed to parse debug history: %w", err)
+	}
+	if s.Repos == nil {
+		s.Repos = make(map[string][]Case)
+	}
+
+	return s, nil
+}
+
+func (s *Store) Save() error {
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode debug history: %w", err)
+	}
+
+	if err := os.WriteFile(s.path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write debug history: %w", err)
+	}
+
+	return nil
+}
+
+// Path returns the file the cases are saved in.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Record adds a case, replacing an earlier verdict on the same commit for the
+// same bug so a corrected answer does not count twice.
+func (s *Store) Record(repoURL string, c Case) {
+	if c.Recorded.IsZero() {
+		c.Recorded = time.Now()
+	}
+
+	var cases []Case
+	for _, existing := range s.Repos[repoURL] {
+		if existing.Commit != c.Commit || !strings.EqualFold(existing.Bug, c.Bug) {
+			cases = append(cases, existing)
+		}
+	}
+	cases = append(cases, c)
+	if len(cases) > maxCases {
+		cases = cases[len(cases)-maxCases:]
+	}
+	s.Repos[repoURL] = cases
+}
+
+// Cases returns the cases recorded for a repository, oldest first.
+func (s *Store) Cases(repoURL string) []Case {
+	return s.Repos[repoURL]
+}
+
+// Similar returns up to limit cases of the repository whose bug shares enough
+// words with bug, the most similar first and the newest on a tie.
+func (s *Store) Similar(repoURL, bug string, limit int) []Case {
+	words := wordSet(bug)
+	if len(words) == 0 {
+		return nil
+	}
+
+	type scored struct {
+		c     Case
+		score float64
+	}
+	var matches []scored
+	for _, c := range s.Repos[repoURL] {
+		if score := similarity(words, wordSet(c.Bug)); score >= minSimilarity {
+			matches = append(matches, scored{c, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].c.Recorded.After(matches[j].c.Recorded)
+	})
+
+	var similar []Case
+	for i, match := range matches {
+		if i == limit {
+			break
+		}
+		similar = append(similar, match.c)
+	}
+	return similar
+}
+
+// similarity is the Jaccard index of two word sets.
+func similarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for word := range a {
+		if b[word] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// stopWords are left out of bug descriptions, so that two bugs are not
+// similar just because both "fail when the user is logged in".
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "but": true,
+	"by": true, "for": true, "from": true, "has": true, "have": true, "in": true, "is": true, "it": true,
+	"its": true, "of": true, "on": true, "or": true, "that": true, "the": true, "this": true, "to": true,
+	"was": true, "were": true, "when": true, "with": true, "after": true, "not": true, "no": true,
+}
+
+func wordSet(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) > 1 && !stopWords[word] {
+			words[word] = true
+		}
+	}
+	return words
+}
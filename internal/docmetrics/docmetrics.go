@@ -0,0 +1,176 @@
+// Package docmetrics measures the documentation each update-docs run leaves
+// behind, and keeps the measures of past runs so that docs an update shrank
+// drastically or stripped of sections stand out: a sign that a prompt change
+// made the updates worse.
+package docmetrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	metricsFileName = "metrics.json"
+
+	// maxSnapshots is how many runs are kept per repository.
+	maxSnapshots = 20
+
+	// shrinkRatio is the share of its words a doc may lose in one update
+	// before it is flagged, once it has at least minWords.
+	shrinkRatio = 0.4
+	minWords    = 100
+)
+
+var (
+	fencePattern   = regexp.MustCompile("^ {0,3}(```+|~~~+)")
+	headingPattern = regexp.MustCompile(`^ {0,3}#{1,6}(?:[ \t]+|$)`)
+)
+
+// Metrics are the measures of one document.
+type Metrics struct {
+	Words       int       `json:"words"`
+	Sections    int       `json:"sections"`
+	CodeSamples int       `json:"code_samples"`
+	Verified    time.Time `json:"verified,omitempty"` // last commit that changed it
+}
+
+// Measure counts the words, headings, and code blocks of a markdown document.
+// Words in code blocks are not counted, nor are lines that look like
+// headings inside them.
+func Measure(content string) Metrics {
+	var m Metrics
+	fence := ""
+	for _, line := range strings.Split(content, "\n") {
+		if fence != "" {
+			if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if match := fencePattern.FindStringSubmatch(line); match != nil {
+			fence = match[1]
+			m.CodeSamples++
+			continue
+		}
+		if marker := headingPattern.FindString(line); marker != "" {
+			m.Sections++
+			line = line[len(marker):]
+		}
+		m.Words += len(strings.Fields(line))
+	}
+	return m
+}
+
+// Regressions describes how after, the measures of a doc once updated, fell
+// from before: losing more than shrinkRatio of its words, or sections.
+func Regressions(before, after Metrics) []string {
+	var found []string
+	if before.Words >= minWords && float64(after.Words) < float64(before.Words)*(1-shrinkRatio) {
+		found = append(found, fmt.Sprintf("shrank from %d to %d words (%d%%)", before.Words, after.Words, percentChange(before.Words, after.Words)))
+	}
+	if after.Sections < before.Sections {
+		found = append(found, fmt.Sprintf("lost %d of %d sections", before.Sections-after.Sections, before.Sections))
+	}
+	return found
+}
+
+func percentChange(before, after int) int {
+	if before == 0 {
+		return 0
+	}
+	return (after - before) * 100 / before
+}
+
+// Snapshot holds the measures of the docs a run changed, before and after.
+type Snapshot struct {
+	RunID  string             `json:"run_id,omitempty"`
+	Time   time.Time          `json:"time"`
+	Before map[string]Metrics `json:"before"`
+	After  map[string]Metrics `json:"after"`
+}
+
+// Store holds the snapshots of past runs, keyed by repository remote URL like
+// the doc queue, oldest first.
+type Store struct {
+	Repos map[string][]Snapshot `json:"repos"`
+	path  string
+}
+
+func Load() (*Store, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	configDir := filepath.Join(homeDir, ".docu-jarvis")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	s := &Store{
+		Repos: make(map[string][]Snapshot),
+		path:  filepath.Join(configDir, metricsFileName),
+	}
+
+	content, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read doc metrics: %w", err)
+	}
+
+	if err := json.Unmarshal(content, s); err != nil {
+		return nil, fmt.Errorf("failed to parse doc metrics: %w", err)
+	}
+	if s.Repos == nil {
+		s.Repos = make(map[string][]Snapshot)
+	}
+	return s, nil
+}
+
+func (s *Store) Save() error {
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode doc metrics: %w", err)
+	}
+
+	if err := os.WriteFile(s.path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write doc metrics: %w", err)
+	}
+
+	return nil
+}
+
+// Record adds the snapshot of a run, dropping the oldest beyond maxSnapshots.
+func (s *Store) Record(repoURL string, snapshot Snapshot) {
+	snapshots := append(s.Repos[repoURL], snapshot)
+	if len(snapshots) > maxSnapshots {
+		snapshots = snapshots[len(snapshots)-maxSnapshots:]
+	}
+	s.Repos[repoURL] = snapshots
+}
+
+// Trend returns the word counts of doc after each past run that changed it,
+// oldest first, starting with its count before the first of them.
+func (s *Store) Trend(repoURL, doc string) []int {
+	var words []int
+	for _, snapshot := range s.Repos[repoURL] {
+		after, ok := snapshot.After[doc]
+		if !ok {
+			continue
+		}
+		if len(words) == 0 {
+			if before, ok := snapshot.Before[doc]; ok {
+				words = append(words, before.Words)
+			}
+		}
+		words = append(words, after.Words)
+	}
+	return words
+}
@@ -0,0 +1,113 @@
+package docqueue
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const queueFileName = "doc_queue.json"
+
+// Queue holds documentation files waiting for the next update run, keyed by
+// repository remote URL so clones and local checkouts share entries.
+type Queue struct {
+	Repos map[string][]string `json:"repos"`
+	path  string
+}
+
+func Load() (*Queue, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	configDir := filepath.Join(homeDir, ".docu-jarvis")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	q := &Queue{
+		Repos: make(map[string][]string),
+		path:  filepath.Join(configDir, queueFileName),
+	}
+
+	content, err := os.ReadFile(q.path)
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read doc queue: %w", err)
+	}
+
+	if err := json.Unmarshal(content, q); err != nil {
+		return nil, fmt.Errorf("failed to parse doc queue: %w", err)
+	}
+	if q.Repos == nil {
+		q.Repos = make(map[string][]string)
+	}
+
+	return q, nil
+}
+
+func (q *Queue) Save() error {
+	content, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode doc queue: %w", err)
+	}
+
+	if err := os.WriteFile(q.path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write doc queue: %w", err)
+	}
+
+	return nil
+}
+
+// Add queues files for a repository, ignoring ones already queued.
+func (q *Queue) Add(repoURL string, files ...string) {
+	existing := make(map[string]bool)
+	for _, file := range q.Repos[repoURL] {
+		existing[file] = true
+	}
+	for _, file := range files {
+		existing[file] = true
+	}
+
+	merged := make([]string, 0, len(existing))
+	for file := range existing {
+		merged = append(merged, file)
+	}
+	sort.Strings(merged)
+
+	q.Repos[repoURL] = merged
+}
+
+func (q *Queue) Get(repoURL string) []string {
+	return q.Repos[repoURL]
+}
+
+// Remove drops files from a repository's queue, and the repository once none
+// are left.
+func (q *Queue) Remove(repoURL string, files ...string) {
+	done := make(map[string]bool)
+	for _, file := range files {
+		done[file] = true
+	}
+
+	var left []string
+	for _, file := range q.Repos[repoURL] {
+		if !done[file] {
+			left = append(left, file)
+		}
+	}
+	if len(left) == 0 {
+		delete(q.Repos, repoURL)
+		return
+	}
+	q.Repos[repoURL] = left
+}
+
+func (q *Queue) Clear(repoURL string) {
+	delete(q.Repos, repoURL)
+}
@@ -0,0 +1,251 @@
+// Package eval runs a suite of scenarios against the current prompts and
+// models and scores what Claude produces, so that a prompt or model change
+// that makes docu-jarvis worse shows up before a release. Each scenario is a
+// directory of the suite with a scenario.json and a sample repository in
+// repo/, which is copied before each run:
+//
+//	{
+//	  "kind": "update-docs",
+//	  "doc": "configuration.md",
+//	  "expect": {"contains": ["BILLING_RETRY_LIMIT"], "not_contains": ["BILLING_MAX_RETRIES"]}
+//	}
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ScenarioFileName defines a scenario, in its directory.
+const ScenarioFileName = "scenario.json"
+
+// Scenario kinds, named after the commands whose prompts they exercise.
+const (
+	KindUpdateDocs   = "update-docs"
+	KindWriteDocs    = "write-docs"
+	KindCheckStaging = "check-staging"
+	KindDocsImpact   = "docs-impact"
+)
+
+// defaultDocsDir is the docs directory of a sample repository, as for
+// repositories without docs_roots.
+const defaultDocsDir = "documentation"
+
+// Scenario is one case of a suite.
+type Scenario struct {
+	Name        string   `json:"-"` // the directory name
+	Dir         string   `json:"-"`
+	Kind        string   `json:"kind"`
+	Description string   `json:"description,omitempty"`
+	DocsDir     string   `json:"docs_dir,omitempty"`  // in repo/, default documentation
+	Doc         string   `json:"doc,omitempty"`       // update-docs: relative to the docs directory
+	Topic       string   `json:"topic,omitempty"`     // write-docs
+	Diff        string   `json:"diff,omitempty"`      // check-staging and docs-impact: a file of the scenario
+	Standards   []string `json:"standards,omitempty"` // check-staging
+	Expect      Expect   `json:"expect"`
+}
+
+// Expect are the properties the output of a scenario must have, on top of
+// the ones every scenario of its kind is checked for: a doc that is valid
+// markdown, a report that is valid JSON, and no edits outside the docs
+// directory (or none at all, for reviews).
+type Expect struct {
+	Sections     []string `json:"sections,omitempty"`      // headings the doc must have
+	Contains     []string `json:"contains,omitempty"`      // text the doc or report must have
+	NotContains  []string `json:"not_contains,omitempty"`  // text it must not have
+	Compliant    *bool    `json:"compliant,omitempty"`     // check-staging
+	MinFindings  int      `json:"min_findings,omitempty"`  // check-staging
+	AffectedDocs []string `json:"affected_docs,omitempty"` // docs-impact: docs that must be found to need an update
+}
+
+// Load reads the scenarios of the suite in dir, ordered by name. With names,
+// only those are loaded.
+func Load(dir string, names []string) ([]Scenario, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suite: %w", err)
+	}
+
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	var scenarios []Scenario
+	for _, entry := range entries {
+		if !entry.IsDir() || (len(names) > 0 && !wanted[entry.Name()]) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name(), ScenarioFileName)
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read scenario %s: %w", entry.Name(), err)
+		}
+
+		s := Scenario{Name: entry.Name(), Dir: filepath.Join(dir, entry.Name())}
+		if err := json.Unmarshal(content, &s); err != nil {
+			return nil, fmt.Errorf("failed to parse scenario %s: %w", entry.Name(), err)
+		}
+		if err := s.validate(); err != nil {
+			return nil, fmt.Errorf("invalid scenario %s: %w", entry.Name(), err)
+		}
+		delete(wanted, s.Name)
+		scenarios = append(scenarios, s)
+	}
+
+	for name := range wanted {
+		return nil, fmt.Errorf("no scenario %s in %s", name, dir)
+	}
+	sort.Slice(scenarios, func(i, j int) bool { return scenarios[i].Name < scenarios[j].Name })
+	return scenarios, nil
+}
+
+func (s *Scenario) validate() error {
+	if s.DocsDir == "" {
+		s.DocsDir = defaultDocsDir
+	}
+	switch s.Kind {
+	case KindUpdateDocs:
+		if s.Doc == "" {
+			return fmt.Errorf("%s scenarios need a doc", s.Kind)
+		}
+	case KindWriteDocs:
+		if s.Topic == "" {
+			return fmt.Errorf("%s scenarios need a topic", s.Kind)
+		}
+	case KindCheckStaging, KindDocsImpact:
+		if s.Diff == "" {
+			return fmt.Errorf("%s scenarios need a diff", s.Kind)
+		}
+	default:
+		return fmt.Errorf("unknown kind %q (must be %s, %s, %s, or %s)", s.Kind, KindUpdateDocs, KindWriteDocs, KindCheckStaging, KindDocsImpact)
+	}
+	if _, err := os.Stat(filepath.Join(s.Dir, "repo")); err != nil {
+		return fmt.Errorf("no sample repository: %w", err)
+	}
+	return nil
+}
+
+// Check is one scored property of a scenario's output.
+type Check struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail,omitempty"` // why it failed
+}
+
+// Result is the outcome of one scenario.
+type Result struct {
+	Scenario   string  `json:"scenario"`
+	Kind       string  `json:"kind"`
+	Checks     []Check `json:"checks"`
+	DurationMs int64   `json:"duration_ms"`
+}
+
+func (r *Result) check(name string, passed bool, detail string) {
+	if passed {
+		detail = ""
+	}
+	r.Checks = append(r.Checks, Check{Name: name, Passed: passed, Detail: detail})
+}
+
+// Score returns how many of the result's checks passed, out of how many.
+func (r Result) Score() (passed, total int) {
+	for _, c := range r.Checks {
+		if c.Passed {
+			passed++
+		}
+	}
+	return passed, len(r.Checks)
+}
+
+// Passed reports whether every check passed.
+func (r Result) Passed() bool {
+	passed, total := r.Score()
+	return passed == total
+}
+
+// Report holds the results of a suite run, to compare later runs with.
+type Report struct {
+	Time    time.Time `json:"time"`
+	Version string    `json:"version"`
+	Model   string    `json:"model,omitempty"` // "" for the configured models
+	Results []Result  `json:"results"`
+}
+
+// Score returns how many checks passed over every scenario, out of how many.
+func (r *Report) Score() (passed, total int) {
+	for _, result := range r.Results {
+		p, t := result.Score()
+		passed += p
+		total += t
+	}
+	return passed, total
+}
+
+func (r *Report) Save(path string) error {
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode eval report: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write eval report: %w", err)
+	}
+	return nil
+}
+
+func LoadReport(path string) (*Report, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read eval report: %w", err)
+	}
+	var r Report
+	if err := json.Unmarshal(content, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse eval report: %w", err)
+	}
+	return &r, nil
+}
+
+// Regressions describes the checks of current that fail but passed in
+// baseline, by scenario. Scenarios and checks baseline does not have are not
+// regressions.
+func Regressions(baseline, current *Report) map[string][]string {
+	passedBefore := make(map[string]map[string]bool)
+	for _, result := range baseline.Results {
+		checks := make(map[string]bool)
+		for _, c := range result.Checks {
+			checks[c.Name] = c.Passed
+		}
+		passedBefore[result.Scenario] = checks
+	}
+
+	regressions := make(map[string][]string)
+	for _, result := range current.Results {
+		for _, c := range result.Checks {
+			if !c.Passed && passedBefore[result.Scenario][c.Name] {
+				regressions[result.Scenario] = append(regressions[result.Scenario], c.Name)
+			}
+		}
+	}
+	return regressions
+}
+
+// containsFold reports whether text has each of want, ignoring case, and
+// returns the ones it lacks.
+func containsFold(text string, want []string) []string {
+	lower := strings.ToLower(text)
+	var missing []string
+	for _, w := range want {
+		if !strings.Contains(lower, strings.ToLower(w)) {
+			missing = append(missing, w)
+		}
+	}
+	return missing
+}
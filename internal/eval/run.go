@@ -0,0 +1,315 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/udemy/docu-jarvis-cli/internal/agent"
+	"github.com/udemy/docu-jarvis-cli/internal/mdlint"
+	"github.com/udemy/docu-jarvis-cli/internal/system_prompts"
+)
+
+// Options configure a suite run.
+type Options struct {
+	Model string // overrides the configured model of every mode, when set
+}
+
+// Run runs a scenario on a copy of its sample repository and scores the
+// output. An error means the scenario could not be run at all; Claude failing
+// or producing a bad output fails checks of the result instead.
+func Run(ctx context.Context, s Scenario, opts Options) (Result, error) {
+	result := Result{Scenario: s.Name, Kind: s.Kind}
+	start := time.Now()
+
+	folder, err := os.MkdirTemp("", "docu-jarvis-eval-")
+	if err != nil {
+		return result, fmt.Errorf("failed to create the scenario's repository: %w", err)
+	}
+	defer os.RemoveAll(folder)
+	if err := copyTree(filepath.Join(s.Dir, "repo"), folder); err != nil {
+		return result, fmt.Errorf("failed to copy the sample repository: %w", err)
+	}
+	docsDir := filepath.Join(folder, s.DocsDir)
+
+	before, err := snapshot(folder)
+	if err != nil {
+		return result, err
+	}
+
+	prompts := map[string]string{
+		KindUpdateDocs:   system_prompts.DocumentationUpdate,
+		KindWriteDocs:    system_prompts.DocumentationWrite,
+		KindCheckStaging: system_prompts.AssertCodeQuality,
+		KindDocsImpact:   system_prompts.DocsImpact,
+	}
+	ag, err := agent.New(prompts[s.Kind], folder)
+	if err != nil {
+		return result, fmt.Errorf("failed to create agent: %w", err)
+	}
+	ag.SetModel(opts.Model)
+	ag.SetDocsDirs([]string{docsDir})
+	ag.SetEditRoots([]string{docsDir})
+
+	switch s.Kind {
+	case KindUpdateDocs, KindWriteDocs:
+		err = runDocs(ctx, ag, s, &result, folder, docsDir, before)
+	case KindCheckStaging:
+		err = runReview(ctx, ag, s, &result)
+	case KindDocsImpact:
+		err = runImpact(ctx, ag, s, &result)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	after, err := snapshot(folder)
+	if err != nil {
+		return result, err
+	}
+	var outOfScope []string
+	for _, file := range changedFiles(before, after) {
+		inDocs := within(docsDir, filepath.Join(folder, file))
+		if s.Kind == KindCheckStaging || s.Kind == KindDocsImpact || !inDocs {
+			outOfScope = append(outOfScope, file)
+		}
+	}
+	result.check("no out-of-scope edits", len(outOfScope) == 0, "changed "+strings.Join(outOfScope, ", "))
+	result.DurationMs = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// runDocs updates the scenario's doc, or writes its topic, and checks the doc
+// that results.
+func runDocs(ctx context.Context, ag *agent.Agent, s Scenario, result *Result, folder, docsDir string, before map[string]string) error {
+	var err error
+	if s.Kind == KindUpdateDocs {
+		_, _, err = ag.UpdateSpecificDocuments(ctx, []string{filepath.Join(docsDir, s.Doc)})
+	} else {
+		_, _, err = ag.WriteDocumentation(ctx, []string{s.Topic})
+	}
+	if err == nil {
+		for _, item := range ag.Batch() {
+			if item.Error != "" {
+				err = errors.New(item.Error)
+			}
+		}
+	}
+	result.check("completed", err == nil, fmt.Sprint(err))
+	if err != nil {
+		return nil
+	}
+
+	path, err := resultDoc(s, folder, docsDir, before)
+	if err != nil {
+		return err
+	}
+	if s.Kind == KindWriteDocs {
+		result.check("doc written", path != "", "no new doc in "+s.DocsDir)
+	}
+	if path == "" {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read the doc: %w", err)
+	}
+	doc := string(content)
+
+	problems := mdlint.Check(filepath.Base(path), doc)
+	var details []string
+	for _, p := range problems {
+		details = append(details, p.String())
+	}
+	result.check("valid markdown", len(problems) == 0, strings.Join(details, "; "))
+
+	if len(s.Expect.Sections) > 0 {
+		headings := make(map[string]bool)
+		for _, line := range strings.Split(doc, "\n") {
+			if strings.HasPrefix(line, "#") {
+				headings[strings.ToLower(strings.TrimSpace(strings.TrimLeft(line, "#")))] = true
+			}
+		}
+		var missing []string
+		for _, section := range s.Expect.Sections {
+			if !headings[strings.ToLower(section)] {
+				missing = append(missing, section)
+			}
+		}
+		result.check("required sections", len(missing) == 0, "missing "+strings.Join(missing, ", "))
+	}
+	checkText(result, doc, s.Expect)
+	return nil
+}
+
+// resultDoc returns the doc an update-docs scenario updated, or the first new
+// doc a write-docs scenario wrote, or "" when it wrote none.
+func resultDoc(s Scenario, folder, docsDir string, before map[string]string) (string, error) {
+	if s.Kind == KindUpdateDocs {
+		return filepath.Join(docsDir, s.Doc), nil
+	}
+	docs, err := agent.FindDocs([]string{docsDir})
+	if err != nil {
+		return "", fmt.Errorf("failed to list docs: %w", err)
+	}
+	for _, doc := range docs {
+		rel, err := filepath.Rel(folder, doc)
+		if err != nil {
+			continue
+		}
+		if _, existed := before[filepath.ToSlash(rel)]; !existed {
+			return doc, nil
+		}
+	}
+	return "", nil
+}
+
+func runReview(ctx context.Context, ag *agent.Agent, s Scenario, result *Result) error {
+	diff, err := os.ReadFile(filepath.Join(s.Dir, s.Diff))
+	if err != nil {
+		return fmt.Errorf("failed to read the diff: %w", err)
+	}
+	report, err := ag.ReviewStagedCodeJSON(ctx, string(diff), strings.Join(s.Standards, "\n"))
+	result.check("valid JSON", err == nil, fmt.Sprint(err))
+	if err != nil {
+		return nil
+	}
+
+	if s.Expect.Compliant != nil {
+		result.check("compliance", report.Compliant == *s.Expect.Compliant,
+			fmt.Sprintf("compliant is %t, expected %t", report.Compliant, *s.Expect.Compliant))
+	}
+	if s.Expect.MinFindings > 0 {
+		result.check("findings", len(report.Findings) >= s.Expect.MinFindings,
+			fmt.Sprintf("%d findings, expected at least %d", len(report.Findings), s.Expect.MinFindings))
+	}
+	content, _ := json.Marshal(report)
+	checkText(result, string(content), s.Expect)
+	return nil
+}
+
+func runImpact(ctx context.Context, ag *agent.Agent, s Scenario, result *Result) error {
+	diff, err := os.ReadFile(filepath.Join(s.Dir, s.Diff))
+	if err != nil {
+		return fmt.Errorf("failed to read the diff: %w", err)
+	}
+	impact, err := ag.AnalyzeDocsImpact(ctx, string(diff))
+	result.check("valid JSON", err == nil, fmt.Sprint(err))
+	if err != nil {
+		return nil
+	}
+
+	if len(s.Expect.AffectedDocs) > 0 {
+		required := impact.RequiredUpdates()
+		var missing []string
+		for _, want := range s.Expect.AffectedDocs {
+			found := false
+			for _, doc := range required {
+				if strings.HasSuffix(filepath.ToSlash(doc), want) {
+					found = true
+				}
+			}
+			if !found {
+				missing = append(missing, want)
+			}
+		}
+		result.check("affected docs", len(missing) == 0,
+			fmt.Sprintf("missing %s (found %s)", strings.Join(missing, ", "), strings.Join(required, ", ")))
+	}
+	content, _ := json.Marshal(impact)
+	checkText(result, string(content), s.Expect)
+	return nil
+}
+
+// checkText checks text for the expected and forbidden strings.
+func checkText(result *Result, text string, expect Expect) {
+	if len(expect.Contains) > 0 {
+		missing := containsFold(text, expect.Contains)
+		result.check("contains", len(missing) == 0, "missing "+strings.Join(missing, ", "))
+	}
+	if len(expect.NotContains) > 0 {
+		var present []string
+		for _, s := range expect.NotContains {
+			if len(containsFold(text, []string{s})) == 0 {
+				present = append(present, s)
+			}
+		}
+		result.check("not contains", len(present) == 0, "still has "+strings.Join(present, ", "))
+	}
+}
+
+// snapshot returns the content of every file under root, by slash-separated
+// path relative to it. Sample repositories are small.
+func snapshot(root string) (map[string]string, error) {
+	files := make(map[string]string)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the scenario's repository: %w", err)
+	}
+	return files, nil
+}
+
+// changedFiles returns the files added, changed, or removed between two
+// snapshots.
+func changedFiles(before, after map[string]string) []string {
+	var changed []string
+	for file, content := range after {
+		if old, ok := before[file]; !ok || old != content {
+			changed = append(changed, file)
+		}
+	}
+	for file := range before {
+		if _, ok := after[file]; !ok {
+			changed = append(changed, file)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, 0644)
+	})
+}
+
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
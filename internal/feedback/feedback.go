@@ -0,0 +1,112 @@
+// Package feedback keeps the documentation rules learned from human review
+// comments on docu-jarvis PRs, which later runs add to their prompts.
+package feedback
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const feedbackFileName = "feedback.json"
+
+// Store holds the learned rules, keyed by repository remote URL like the doc
+// queue, and by the PR each rule came from.
+type Store struct {
+	Repos map[string][]Lesson `json:"repos"`
+	path  string
+}
+
+// Lesson is a rule distilled from the review comments on one PR.
+type Lesson struct {
+	Rule    string    `json:"rule"`
+	PR      int       `json:"pr"`
+	Learned time.Time `json:"learned"`
+}
+
+func Load() (*Store, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	configDir := filepath.Join(homeDir, ".docu-jarvis")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	s := &Store{
+		Repos: make(map[string][]Lesson),
+		path:  filepath.Join(configDir, feedbackFileName),
+	}
+
+	content, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read review feedback: %w", err)
+	}
+
+	if err := json.Unmarshal(content, s); err != nil {
+		return nil, fmt.Errorf("failed to parse review feedback: %w", err)
+	}
+	if s.Repos == nil {
+		s.Repos = make(map[string][]Lesson)
+	}
+
+	return s, nil
+}
+
+func (s *Store) Save() error {
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode review feedback: %w", err)
+	}
+
+	if err := os.WriteFile(s.path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write review feedback: %w", err)
+	}
+
+	return nil
+}
+
+// Path returns the file the rules are saved in.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Rules returns the rules learned for a repository, oldest PR first.
+func (s *Store) Rules(repoURL string) []string {
+	var rules []string
+	for _, lesson := range s.Repos[repoURL] {
+		rules = append(rules, lesson.Rule)
+	}
+	return rules
+}
+
+// Learn replaces the rules learned from a PR, so learning from a PR again
+// after more reviews does not pile up rules.
+func (s *Store) Learn(repoURL string, pr int, rules []string) {
+	var lessons []Lesson
+	for _, lesson := range s.Repos[repoURL] {
+		if lesson.PR != pr {
+			lessons = append(lessons, lesson)
+		}
+	}
+
+	now := time.Now()
+	for _, rule := range rules {
+		lessons = append(lessons, Lesson{Rule: rule, PR: pr, Learned: now})
+	}
+	sort.SliceStable(lessons, func(i, j int) bool { return lessons[i].PR < lessons[j].PR })
+
+	if len(lessons) == 0 {
+		delete(s.Repos, repoURL)
+		return
+	}
+	s.Repos[repoURL] = lessons
+}
@@ -0,0 +1,99 @@
+// Package freshness stamps the docs docu-jarvis generates with what they were
+// generated from, in their front matter:
+//
+//	generated_at: 2024-06-01T09:30:00Z
+//	generated_from: 3f2c1a...
+//	sources:
+//	  - internal/billing/invoice.go
+//
+// so that stale-docs can tell which docs describe code that changed since.
+package freshness
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/udemy/docu-jarvis-cli/internal/frontmatter"
+)
+
+// The front-matter keys of a stamp.
+const (
+	generatedAtKey   = "generated_at"
+	generatedFromKey = "generated_from"
+	sourcesKey       = "sources"
+)
+
+// Stamp records what a doc was generated from.
+type Stamp struct {
+	Sources     []string  // the source files read for it, relative to the repository root
+	Commit      string    // the commit they were read at
+	GeneratedAt time.Time // UTC, to the second
+}
+
+// Read returns the stamp of a doc. ok is false when the doc has none, or its
+// stamp lacks the commit or a valid time.
+func Read(content string) (s Stamp, ok bool) {
+	s.Commit = frontmatter.Value(content, generatedFromKey)
+	generatedAt, err := time.Parse(time.RFC3339, frontmatter.Value(content, generatedAtKey))
+	if s.Commit == "" || err != nil {
+		return Stamp{}, false
+	}
+	s.GeneratedAt = generatedAt.UTC()
+	s.Sources = frontmatter.List(content, sourcesKey)
+	return s, true
+}
+
+// Apply returns the doc stamped with s, replacing any stamp it has and
+// keeping the rest of its front matter. A doc without front matter gets it.
+func Apply(content string, s Stamp) string {
+	sources := append([]string(nil), s.Sources...)
+	sort.Strings(sources)
+
+	var b strings.Builder
+	b.WriteString(generatedAtKey + ": " + s.GeneratedAt.UTC().Format(time.RFC3339) + "\n")
+	b.WriteString(generatedFromKey + ": " + s.Commit + "\n")
+	if len(sources) == 0 {
+		b.WriteString(sourcesKey + ": []\n")
+	} else {
+		b.WriteString(sourcesKey + ":\n")
+		for _, source := range sources {
+			b.WriteString("  - " + source + "\n")
+		}
+	}
+
+	front, body, ok := frontmatter.Split(content)
+	if !ok {
+		return "---\n" + b.String() + "---\n\n" + content
+	}
+	return "---\n" + withoutStamp(front) + b.String() + "---\n" + body
+}
+
+// withoutStamp removes the stamp keys, and the list items under them, from
+// front matter.
+func withoutStamp(front string) string {
+	var kept []string
+	inStamp := false
+	for _, line := range strings.SplitAfter(front, "\n") {
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "-") {
+			if !inStamp {
+				kept = append(kept, line)
+			}
+			continue
+		}
+		key, _, _ := strings.Cut(line, ":")
+		key = strings.TrimSpace(key)
+		inStamp = key == generatedAtKey || key == generatedFromKey || key == sourcesKey
+		if !inStamp {
+			kept = append(kept, line)
+		}
+	}
+	front = strings.Join(kept, "")
+	if front != "" && !strings.HasSuffix(front, "\n") {
+		front += "\n"
+	}
+	return front
+}
@@ -0,0 +1,122 @@
+// Package frontmatter reads the tags, and other lists and values, of the YAML
+// front matter that starts a markdown document. Only the forms docs use for
+// tags are supported:
+//
+//	tags: [api, auth]
+//	tags: api, auth
+//	tags:
+//	  - api
+//	  - auth
+package frontmatter
+
+import (
+	"strings"
+)
+
+const delimiter = "---"
+
+// Split returns the front matter of a document, without its delimiters, and
+// the rest of the document. ok is false when the document has none.
+func Split(content string) (front, body string, ok bool) {
+	content = strings.TrimPrefix(content, "\ufeff")
+	lines := strings.SplitAfter(content, "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != delimiter {
+		return "", content, false
+	}
+	for i := 1; i < len(lines); i++ {
+		if trimmed := strings.TrimSpace(lines[i]); trimmed == delimiter || trimmed == "..." {
+			return strings.Join(lines[1:i], ""), strings.Join(lines[i+1:], ""), true
+		}
+	}
+	return "", content, false
+}
+
+// Tags returns the tags of a document, lowercased, in the order they are
+// listed. It returns nil when the document has no front matter or no tags.
+func Tags(content string) []string {
+	var tags []string
+	for _, tag := range List(content, "tags") {
+		tags = appendTag(tags, strings.ToLower(tag))
+	}
+	return tags
+}
+
+// List returns the items of a top-level list of the front matter, unquoted,
+// in any of the forms tags are written in. It returns nil when the document
+// has no front matter or no such key.
+func List(content, key string) []string {
+	front, _, ok := Split(content)
+	if !ok {
+		return nil
+	}
+
+	var items []string
+	inList := false
+	for _, line := range strings.Split(front, "\n") {
+		if inList {
+			trimmed := strings.TrimSpace(line)
+			if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+				items = appendTag(items, stripComment(strings.TrimPrefix(trimmed, "-")))
+				continue
+			}
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			break
+		}
+
+		k, value, found := strings.Cut(line, ":")
+		if !found || strings.TrimSpace(k) != key || strings.HasPrefix(k, " ") {
+			continue
+		}
+		value = stripComment(value)
+		if value == "" {
+			inList = true
+			continue
+		}
+		value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+		for _, item := range strings.Split(value, ",") {
+			items = appendTag(items, item)
+		}
+		break
+	}
+	return items
+}
+
+// Value returns a top-level scalar of the front matter, unquoted, or "" when
+// the document has no front matter or no such key.
+func Value(content, key string) string {
+	front, _, ok := Split(content)
+	if !ok {
+		return ""
+	}
+	for _, line := range strings.Split(front, "\n") {
+		k, value, found := strings.Cut(line, ":")
+		if found && k == key {
+			return strings.Trim(stripComment(value), `"'`)
+		}
+	}
+	return ""
+}
+
+// appendTag adds a tag or list item, unquoted, unless it is empty or already
+// listed.
+func appendTag(tags []string, tag string) []string {
+	tag = strings.Trim(strings.TrimSpace(tag), `"'`)
+	if tag == "" {
+		return tags
+	}
+	for _, existing := range tags {
+		if existing == tag {
+			return tags
+		}
+	}
+	return append(tags, tag)
+}
+
+func stripComment(value string) string {
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,150 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/udemy/docu-jarvis-cli/internal/dates"
+)
+
+// maxChangesDiff caps the diff of Changes; the list of changed files is
+// always complete.
+const maxChangesDiff = 200 << 10
+
+// emptyTree is git's empty tree, the base of a change that starts at the
+// first commit.
+const emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// Changes is the net change of the checked-out branch since a commit or date.
+type Changes struct {
+	Base  string   // the commit the change starts from
+	Files []string // changed files, relative to the repository root
+	Diff  string
+}
+
+// ChangesSince returns the changes made on the checked-out branch since a
+// commit, branch, or tag, or since a date such as 2024-06-01 or "2 weeks ago".
+// For a branch, the changes since it was branched off count.
+func (r *Repo) ChangesSince(since string) (*Changes, error) {
+	if r.localPath == "" {
+		return nil, fmt.Errorf("repository not cloned")
+	}
+
+	base, err := r.changesBase(since)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := r.git(append([]string{"diff", "--name-only", base, "HEAD"}, r.pathspec()...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the changes since %s: %w", since, err)
+	}
+	changes := &Changes{Base: base}
+	if files == "" {
+		return changes, nil
+	}
+	changes.Files = strings.Split(files, "\n")
+
+	if changes.Diff, err = r.git(append([]string{"diff", base, "HEAD"}, r.pathspec()...)...); err != nil {
+		return nil, fmt.Errorf("failed to diff the changes since %s: %w", since, err)
+	}
+	if len(changes.Diff) > maxChangesDiff {
+		changes.Diff = changes.Diff[:maxChangesDiff] + "\n... (diff truncated; files changed: " + strings.Join(changes.Files, ", ") + ")"
+	}
+	return changes, nil
+}
+
+// changesBase resolves since to the commit the changes start from: the merge
+// base for a revision, or the parent of the first commit after a date.
+func (r *Repo) changesBase(since string) (string, error) {
+	if _, err := r.git("rev-parse", "--verify", "--quiet", since+"^{commit}"); err == nil || fullHashPattern.MatchString(since) {
+		commit, err := r.EnsureCommit(since)
+		if err != nil {
+			return "", err
+		}
+		if err := r.unshallow(since); err != nil {
+			return "", err
+		}
+		base, err := r.git("merge-base", commit, "HEAD")
+		if err != nil {
+			return "", fmt.Errorf("%s has no history in common with HEAD: %w", since, err)
+		}
+		return base, nil
+	}
+
+	date, err := dates.Parse(since, time.Now(), time.Local, false)
+	if err != nil {
+		return "", fmt.Errorf("%q is not a commit, branch, tag, or date: %w", since, err)
+	}
+	if err := r.EnsureHistorySince(dates.Git(date)); err != nil {
+		return "", err
+	}
+	commits, err := r.git("rev-list", "--reverse", "--since="+dates.Git(date), "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to list the commits since %s: %w", since, err)
+	}
+	if commits == "" {
+		return r.git("rev-parse", "HEAD")
+	}
+	first, err := r.EnsureCommit(strings.SplitN(commits, "\n", 2)[0])
+	if err != nil {
+		return "", err
+	}
+	if parent, err := r.git("rev-parse", "--verify", "--quiet", first+"^"); err == nil {
+		return parent, nil
+	}
+	return emptyTree, nil
+}
+
+// SourceChanges are the commits that changed the sources of a doc after it
+// was generated.
+type SourceChanges struct {
+	Commits []Commit // newest first, without bodies
+	Files   []string // the sources they changed
+	ByDate  bool     // the commits after the generation time, its commit not being in the history
+}
+
+// SourceChangesSince returns the commits of the checked-out branch after
+// commit that changed files, relative to the repository root. When commit is
+// not in the branch's history, as when the PR that added it was squashed,
+// the commits after generatedAt are used.
+func (r *Repo) SourceChangesSince(commit string, generatedAt time.Time, files []string) (*SourceChanges, error) {
+	if r.localPath == "" {
+		return nil, fmt.Errorf("repository not cloned")
+	}
+	changes := &SourceChanges{}
+	if len(files) == 0 {
+		return changes, nil
+	}
+
+	// Records are separated by \x1e and start with the hash and subject
+	args := []string{"log", "--no-merges", "--format=%x1e%H%x1f%s", "--name-only"}
+	if _, err := r.git("merge-base", "--is-ancestor", commit, "HEAD"); err == nil {
+		args = append(args, commit+"..HEAD")
+	} else {
+		changes.ByDate = true
+		args = append(args, "--since="+generatedAt.Format(time.RFC3339), "HEAD")
+	}
+	output, err := r.git(append(append(args, "--"), files...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the history of the sources: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, record := range strings.Split(output, "\x1e") {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		hash, subject, ok := strings.Cut(lines[0], "\x1f")
+		if !ok {
+			continue
+		}
+		changes.Commits = append(changes.Commits, Commit{Hash: hash, Subject: subject})
+		for _, file := range lines[1:] {
+			if file = strings.TrimSpace(file); file != "" && !seen[file] {
+				seen[file] = true
+				changes.Files = append(changes.Files, file)
+			}
+		}
+	}
+	return changes, nil
+}
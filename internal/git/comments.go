@@ -0,0 +1,184 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// commentMarker starts the comments docu-jarvis posts, so GetPRComments can
+// tell them from people's.
+const commentMarker = "**docu-jarvis:**"
+
+// fileCommentHeader starts the comment posted on each changed doc.
+const fileCommentHeader = commentMarker + " what changed in this doc, and why\n\n"
+
+// fileCommenter is a HostingProvider that can comment on a file of a PR, not
+// on a line of it.
+type fileCommenter interface {
+	commentOnFile(ctx context.Context, prURL, commit, path, body string) error
+}
+
+// postFileComments comments on the files of the PR committed last that have
+// a comment. Failures are reported but do not fail the PR.
+func (r *Repo) postFileComments(provider HostingProvider, prURL string, comments map[string]string) {
+	commenter, ok := provider.(fileCommenter)
+	if !ok {
+		fmt.Printf("Warning: file comments are not supported on %s, skipping them\n", provider.Name())
+		return
+	}
+	if prURL == "" {
+		fmt.Println("Warning: the PR URL is unknown, skipping file comments")
+		return
+	}
+
+	commit, err := r.git("rev-parse", "HEAD")
+	if err != nil {
+		fmt.Printf("Warning: failed to resolve HEAD, skipping file comments: %v\n", err)
+		return
+	}
+	changed, err := r.git("diff-tree", "--no-commit-id", "--name-only", "-r", "HEAD")
+	if err != nil {
+		fmt.Printf("Warning: failed to list the PR's files, skipping file comments: %v\n", err)
+		return
+	}
+
+	var files []string
+	for _, file := range strings.Split(changed, "\n") {
+		if comments[file] != "" {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+
+	posted := 0
+	for _, file := range files {
+		if err := commenter.commentOnFile(context.Background(), prURL, commit, file, fileCommentHeader+comments[file]); err != nil {
+			fmt.Printf("Warning: failed to comment on %s: %v\n", file, err)
+			continue
+		}
+		posted++
+	}
+	if posted > 0 {
+		fmt.Printf("Commented on %d changed docs with what changed\n", posted)
+	}
+}
+
+// prNumber returns the number that follows marker in the web URL of a PR,
+// e.g. 12 in https://github.com/o/r/pull/12 for "/pull/".
+func prNumber(prURL, marker string) (int, error) {
+	_, rest, found := strings.Cut(prURL, marker)
+	if !found {
+		return 0, fmt.Errorf("cannot find the PR number in %s", prURL)
+	}
+	rest, _, _ = strings.Cut(rest, "/")
+	n, err := strconv.Atoi(rest)
+	if err != nil {
+		return 0, fmt.Errorf("cannot find the PR number in %s", prURL)
+	}
+	return n, nil
+}
+
+func (p *GitHubProvider) commentOnFile(ctx context.Context, prURL, commit, path, body string) error {
+	number, err := prNumber(prURL, "/pull/")
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("repos/%s/%s/pulls/%d/comments", p.owner, p.repo, number)
+	comment := map[string]string{
+		"body":         body,
+		"commit_id":    commit,
+		"path":         path,
+		"subject_type": "file",
+	}
+
+	if p.token == "" {
+		payload, err := json.Marshal(comment)
+		if err != nil {
+			return err
+		}
+		cmd := exec.CommandContext(ctx, "gh", "api", "--hostname", p.host, "-X", "POST", endpoint, "--input", "-")
+		cmd.Stdin = strings.NewReader(string(payload))
+		if _, err := cmd.Output(); err != nil {
+			return fmt.Errorf("gh api failed: %w", err)
+		}
+		return nil
+	}
+
+	headers := map[string]string{
+		"Authorization": "Bearer " + p.token,
+		"Accept":        "application/vnd.github+json",
+	}
+	var created interface{}
+	return postJSON(ctx, p.Name(), p.apiURL+"/"+endpoint, headers, comment, &created)
+}
+
+// commentOnFile starts a discussion on the file in the changes of the merge
+// request, which needs the merge request's diff refs. GitLab computes them
+// shortly after the merge request is opened.
+func (p *GitLabProvider) commentOnFile(ctx context.Context, prURL, commit, path, body string) error {
+	if p.token == "" {
+		return fmt.Errorf("gitlab_token not configured (or set GITLAB_TOKEN)")
+	}
+	iid, err := prNumber(prURL, "/merge_requests/")
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{"PRIVATE-TOKEN": p.token}
+	endpoint := fmt.Sprintf("%s/projects/%s/merge_requests/%d", p.apiURL, url.PathEscape(p.project), iid)
+
+	var mr struct {
+		DiffRefs *struct {
+			BaseSHA  string `json:"base_sha"`
+			HeadSHA  string `json:"head_sha"`
+			StartSHA string `json:"start_sha"`
+		} `json:"diff_refs"`
+	}
+	for attempt := 0; mr.DiffRefs == nil || mr.DiffRefs.HeadSHA != commit; attempt++ {
+		if attempt == 5 {
+			return fmt.Errorf("GitLab has not computed the merge request's changes yet")
+		}
+		if attempt > 0 {
+			time.Sleep(time.Second)
+		}
+		if err := getJSON(ctx, p.Name(), endpoint, headers, &mr); err != nil {
+			return err
+		}
+	}
+
+	var created interface{}
+	return postJSON(ctx, p.Name(), endpoint+"/discussions", headers,
+		map[string]interface{}{
+			"body": body,
+			"position": map[string]string{
+				"position_type": "file",
+				"base_sha":      mr.DiffRefs.BaseSHA,
+				"head_sha":      mr.DiffRefs.HeadSHA,
+				"start_sha":     mr.DiffRefs.StartSHA,
+				"old_path":      path,
+				"new_path":      path,
+			},
+		}, &created)
+}
+
+// commentOnFile posts an inline comment without a line, which Bitbucket
+// shows on the file.
+func (p *BitbucketProvider) commentOnFile(ctx context.Context, prURL, commit, path, body string) error {
+	id, err := prNumber(prURL, "/pull-requests/")
+	if err != nil {
+		return err
+	}
+	var created interface{}
+	return postJSON(ctx, p.Name(), fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/%s/pullrequests/%d/comments", p.workspace, p.repo, id),
+		map[string]string{"Authorization": p.auth()},
+		map[string]interface{}{
+			"content": map[string]string{"raw": body},
+			"inline":  map[string]string{"path": path},
+		}, &created)
+}
@@ -0,0 +1,107 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxDeployDiff caps the net diff in a DeployDivergence report. The commit
+// lists, and the files each commit touched, are always complete.
+const maxDeployDiff = 200 << 10
+
+// DeployDivergence is what differs between the commits deployed to
+// production and staging.
+type DeployDivergence struct {
+	Prod, Staging string // full hashes
+	StagingOnly   int    // commits on staging that are not on production
+	ProdOnly      int    // commits on production that are not on staging
+	Report        string // the commits of each side and the net diff, for Claude
+	DiffTruncated bool
+}
+
+// DeployDivergence compares the commits deployed to production and staging:
+// the commits each has that the other lacks, with the files they touched,
+// and the net diff from production to staging.
+func (r *Repo) DeployDivergence(prod, staging string) (*DeployDivergence, error) {
+	if r.localPath == "" {
+		return nil, fmt.Errorf("repository not cloned")
+	}
+
+	d := &DeployDivergence{}
+	var err error
+	if d.Prod, err = r.EnsureCommit(prod); err != nil {
+		return nil, err
+	}
+	if d.Staging, err = r.EnsureCommit(staging); err != nil {
+		return nil, err
+	}
+	if err := r.unshallow(prod + "..." + staging); err != nil {
+		return nil, err
+	}
+
+	count := func(revRange string) (int, error) {
+		out, err := r.git(append([]string{"rev-list", "--count", revRange}, r.pathspec()...)...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count the commits of %s: %w", revRange, err)
+		}
+		return strconv.Atoi(out)
+	}
+	if d.StagingOnly, err = count(d.Prod + ".." + d.Staging); err != nil {
+		return nil, err
+	}
+	if d.ProdOnly, err = count(d.Staging + ".." + d.Prod); err != nil {
+		return nil, err
+	}
+
+	log := func(revRange string) (string, error) {
+		args := append([]string{"log", "--date=short", "--format=%n%h %ad %an: %s", "--name-status", revRange}, r.pathspec()...)
+		out, err := r.git(args...)
+		if err != nil {
+			return "", fmt.Errorf("failed to list the commits of %s: %w", revRange, err)
+		}
+		return out, nil
+	}
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "Production: %s\nStaging:    %s\n", d.Prod, d.Staging)
+	if base, err := r.git("merge-base", d.Prod, d.Staging); err == nil {
+		fmt.Fprintf(&report, "Merge base: %s\n", base)
+	}
+
+	fmt.Fprintf(&report, "\nCommits on staging that are not on production (%d), newest first, with the files they touched:\n", d.StagingOnly)
+	if d.StagingOnly > 0 {
+		commits, err := log(d.Prod + ".." + d.Staging)
+		if err != nil {
+			return nil, err
+		}
+		report.WriteString(commits + "\n")
+	}
+	fmt.Fprintf(&report, "\nCommits on production that are not on staging (%d), newest first:\n", d.ProdOnly)
+	if d.ProdOnly > 0 {
+		commits, err := log(d.Staging + ".." + d.Prod)
+		if err != nil {
+			return nil, err
+		}
+		report.WriteString(commits + "\n")
+	}
+
+	if d.StagingOnly+d.ProdOnly > 0 {
+		stat, err := r.git(append([]string{"diff", "--stat", d.Prod, d.Staging}, r.pathspec()...)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to diff production and staging: %w", err)
+		}
+		diff, err := r.git(append([]string{"diff", d.Prod, d.Staging}, r.pathspec()...)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to diff production and staging: %w", err)
+		}
+		if len(diff) > maxDeployDiff {
+			diff = diff[:maxDeployDiff] + "\n... (diff truncated)"
+			d.DiffTruncated = true
+		}
+		fmt.Fprintf(&report, "\nNet diff from production to staging:\n%s\n\n%s\n", stat, diff)
+	}
+
+	d.Report = report.String()
+	return d, nil
+}
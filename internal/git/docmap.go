@@ -0,0 +1,89 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DocMapFileName maps code to the docs that cover it, in the repository root.
+// Each line is a CODEOWNERS-style pattern followed by the docs, relative to
+// the repository root, that describe the files it matches:
+//
+//	internal/billing/  docs/billing.md docs/api/invoices.md
+//	*.proto            docs/api/reference.md
+const DocMapFileName = ".docu-jarvis-docmap"
+
+// DocMap is a parsed DocMapFileName.
+type DocMap struct {
+	rules []docMapRule
+}
+
+type docMapRule struct {
+	pattern *regexp.Regexp
+	docs    []string
+}
+
+// LoadDocMap reads the repository's DocMapFileName. It returns nil when the
+// repository has none.
+func (r *Repo) LoadDocMap() (*DocMap, error) {
+	file, err := os.Open(filepath.Join(r.localPath, DocMapFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", DocMapFileName, err)
+	}
+	defer file.Close()
+
+	m := &DocMap{}
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("invalid %s: line %d: expected a pattern followed by docs", DocMapFileName, lineNum)
+		}
+		m.rules = append(m.rules, docMapRule{pattern: codeownersPattern(fields[0]), docs: fields[1:]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", DocMapFileName, err)
+	}
+	return m, nil
+}
+
+// Docs returns the docs covering the files, from every rule that matches, and
+// the files no rule matches.
+func (m *DocMap) Docs(files []string) (docs, unmapped []string) {
+	seen := make(map[string]bool)
+	for _, file := range files {
+		mapped := false
+		for _, rule := range m.rules {
+			if !rule.pattern.MatchString(file) {
+				continue
+			}
+			mapped = true
+			for _, doc := range rule.docs {
+				if !seen[doc] {
+					seen[doc] = true
+					docs = append(docs, doc)
+				}
+			}
+		}
+		if !mapped {
+			unmapped = append(unmapped, file)
+		}
+	}
+	sort.Strings(docs)
+	return docs, unmapped
+}
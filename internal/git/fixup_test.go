@@ -0,0 +1,115 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestRepo creates a repository with a commit per subject and returns it
+// with the commit hashes, oldest first.
+func newTestRepo(t *testing.T, subjects ...string) (*Repo, []string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+
+	run("init", "--quiet")
+	// The rebase's exec lines commit too, so the identity goes in the config
+	run("config", "user.name", "Test")
+	run("config", "user.email", "test@example.com")
+	run("config", "commit.gpgsign", "false")
+	var hashes []string
+	for i, subject := range subjects {
+		if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte(strings.Repeat("x", i+1)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		run("add", "file.txt")
+		run("commit", "--quiet", "-m", subject)
+		hashes = append(hashes, run("rev-parse", "HEAD"))
+	}
+
+	repo, err := OpenLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo, hashes
+}
+
+func TestFixupBase(t *testing.T) {
+	repo, hashes := newTestRepo(t, "first", "second", "third", "fourth")
+
+	tests := []struct {
+		revRange string
+		base     string
+		wantErr  string
+	}{
+		{revRange: hashes[0] + "..HEAD", base: hashes[0]},
+		{revRange: hashes[0] + "..", base: hashes[0]},
+		{revRange: hashes[1] + ".." + hashes[3], base: hashes[1]},
+		// A commit after the end of the range would be dropped by the rebase
+		{revRange: hashes[0] + ".." + hashes[2], wantErr: "ends at HEAD"},
+		{revRange: "HEAD~2", wantErr: "needs a range"},
+		{revRange: hashes[0] + "...HEAD", wantErr: "needs a range"},
+		{revRange: "..HEAD", wantErr: "needs a range"},
+		{revRange: hashes[0] + "..nonexistent", wantErr: "failed to resolve"},
+	}
+
+	for _, tt := range tests {
+		base, err := repo.FixupBase(tt.revRange)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("FixupBase(%q) error = %v, want it to contain %q", tt.revRange, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("FixupBase(%q) unexpected error: %v", tt.revRange, err)
+			continue
+		}
+		if base != tt.base {
+			t.Errorf("FixupBase(%q) = %s, want %s", tt.revRange, base, tt.base)
+		}
+	}
+}
+
+func TestFixupTodoKeepsEveryCommit(t *testing.T) {
+	repo, hashes := newTestRepo(t, "first", "second", "third", "fourth")
+
+	base, err := repo.FixupBase(hashes[1] + "..HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	commits, err := repo.GetCommitMessages(base + "..HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	todoPath, err := WriteRewordTodo(commits, map[string]string{hashes[2]: "fix: third"}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.RebaseWithTodo(base, todoPath); err != nil {
+		t.Fatal(err)
+	}
+	log, err := repo.git("log", "--format=%s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "fourth\nfix: third\nsecond\nfirst"; log != want {
+		t.Errorf("history after the rewrite:\n%s\nwant:\n%s", log, want)
+	}
+}
@@ -4,14 +4,53 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/udemy/docu-jarvis-cli/internal/netguard"
+	"github.com/udemy/docu-jarvis-cli/internal/redact"
+	"github.com/udemy/docu-jarvis-cli/internal/settings"
 )
 
 type Repo struct {
 	url       string
 	localPath string
+	local     bool
+	scope     string
+	branch    string
+	prBaseRef string
+	prOptions PROptions
+	docsRoots []string
+	editAllow []string // patterns the agent may edit, the docs roots when empty
+	cloneOpts CloneOptions
+	baseline  *EditGuard // local checkouts: the first guard, what was changed before the run
+}
+
+type CloneOptions struct {
+	Dir   string // parent directory for clones, defaults to DefaultCloneDir
+	Depth int    // shallow clone depth, 0 for a full clone
+	Reuse bool   // fetch and reset an existing clone instead of re-cloning
+}
+
+var fullHashPattern = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+
+type Commit struct {
+	Hash    string
+	Subject string
+	Body    string
+}
+
+// Message returns the full commit message.
+func (c Commit) Message() string {
+	if c.Body == "" {
+		return c.Subject
+	}
+	return c.Subject + "\n\n" + c.Body
 }
 
 func NewRepo(url string) *Repo {
@@ -20,18 +59,271 @@ func NewRepo(url string) *Repo {
 	}
 }
 
+// OpenLocal uses an existing working tree instead of cloning. The path may be
+// anywhere inside the checkout; the repository root is resolved with git.
+func OpenLocal(path string) (*Repo, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	cmd.Dir = absPath
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("not a git repository: %s", absPath)
+	}
+
+	// git prints forward slashes on Windows too
+	return &Repo{
+		localPath: filepath.Clean(strings.TrimSpace(string(output))),
+		local:     true,
+	}, nil
+}
+
+func (r *Repo) IsLocal() bool {
+	return r.local
+}
+
+// SetScope restricts clones, diffs, and history to a directory within the
+// repository, given relative to the repository root (e.g. "services/payments").
+func (r *Repo) SetScope(scope string) {
+	r.scope = filepath.ToSlash(scope)
+}
+
+func (r *Repo) GetScope() string {
+	return r.scope
+}
+
+// SetBranch selects the branch to clone and analyze, and the base for PRs.
+// Empty means the remote's default branch.
+func (r *Repo) SetBranch(branch string) {
+	r.branch = branch
+}
+
+func (r *Repo) GetBranch() string {
+	return r.branch
+}
+
+// historyRef is the revision that history is read from: the selected branch,
+// or HEAD. Local checkouts may only have the remote-tracking branch.
+func (r *Repo) historyRef() string {
+	if r.branch == "" {
+		return "HEAD"
+	}
+
+	for _, ref := range []string{r.branch, "origin/" + r.branch} {
+		cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+		cmd.Dir = r.localPath
+		if cmd.Run() == nil {
+			return ref
+		}
+	}
+	return r.branch
+}
+
+// SetPRBase sets the branch PRs target when it differs from the branch that
+// is cloned and analyzed.
+func (r *Repo) SetPRBase(base string) {
+	r.prBaseRef = base
+}
+
+// prBase is the branch PRs target: the one set with SetPRBase, the selected
+// branch, or the remote's default branch, falling back to main.
+func (r *Repo) prBase() string {
+	if r.prBaseRef != "" {
+		return r.prBaseRef
+	}
+	if r.branch != "" {
+		return r.branch
+	}
+
+	if base := r.remoteDefaultBranch(); base != "" {
+		return base
+	}
+	fmt.Println("Warning: could not find the default branch of origin, targeting main (set base_branch or -pr-base to change it)")
+	return "main"
+}
+
+func (r *Repo) hostingProvider() (HostingProvider, *settings.Settings, error) {
+	remoteURL, err := r.GetRemoteURL()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s, err := settings.LoadForRepo(r.localPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	provider, err := NewHostingProvider(remoteURL, s)
+	if err != nil {
+		return nil, nil, err
+	}
+	return provider, s, nil
+}
+
+// ContainsCommit reports whether the commit is part of the selected branch's
+// history.
+func (r *Repo) ContainsCommit(hash string) (bool, error) {
+	if r.localPath == "" {
+		return false, fmt.Errorf("repository not cloned")
+	}
+
+	cmd := exec.Command("git", "merge-base", "--is-ancestor", hash, r.historyRef())
+	cmd.Dir = r.localPath
+	err := cmd.Run()
+	if err == nil {
+		return true, nil
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to check commit %s: %w", hash, err)
+}
+
+// GetScopedPath returns the directory that agents should work in.
+func (r *Repo) GetScopedPath() string {
+	if r.scope == "" {
+		return r.localPath
+	}
+	return filepath.Join(r.localPath, r.scope)
+}
+
+// pathspec limits git commands to the scope. It uses the :(top) magic so the
+// scope is always relative to the repository root, not the working directory.
+func (r *Repo) pathspec() []string {
+	if r.scope == "" {
+		return nil
+	}
+	return []string{"--", ":(top)" + r.scope}
+}
+
+// SetDocsRoots sets the documentation directories, given relative to the
+// repository root (e.g. "docs", "website/docs").
+func (r *Repo) SetDocsRoots(roots []string) {
+	r.docsRoots = nil
+	for _, root := range roots {
+		r.docsRoots = append(r.docsRoots, filepath.ToSlash(root))
+	}
+}
+
+// SetEditAllowlist sets the paths the agent may edit, as CODEOWNERS-style
+// patterns (e.g. "docs/**", "*.md"). Without any, it may edit the docs roots.
+func (r *Repo) SetEditAllowlist(patterns []string) {
+	r.editAllow = patterns
+}
+
+// EditAllowlist returns the patterns of the paths the agent may edit.
+func (r *Repo) EditAllowlist() []string {
+	if len(r.editAllow) > 0 {
+		return r.editAllow
+	}
+	var patterns []string
+	for _, root := range r.GetDocsRoots() {
+		patterns = append(patterns, filepath.ToSlash(root)+"/**")
+	}
+	return patterns
+}
+
+// GetDocsRoots returns the docs roots that fall inside the scope, in
+// configured order. Without configured roots (or none inside the scope) it
+// falls back to documentation/ under the scope.
+func (r *Repo) GetDocsRoots() []string {
+	var roots []string
+	for _, root := range r.docsRoots {
+		if r.scope == "" || root == r.scope || strings.HasPrefix(root, r.scope+"/") {
+			roots = append(roots, root)
+		}
+	}
+
+	if len(roots) == 0 {
+		return []string{path.Join(r.scope, "documentation")}
+	}
+	return roots
+}
+
+// GetDocsDirs returns the absolute paths of GetDocsRoots. New documentation
+// goes to the first one.
+func (r *Repo) GetDocsDirs() []string {
+	var dirs []string
+	for _, root := range r.GetDocsRoots() {
+		dirs = append(dirs, filepath.Join(r.localPath, root))
+	}
+	return dirs
+}
+
+// docsPathspec lists the docs roots that exist in the working tree, so that
+// git add does not fail on a root that has not been created yet.
+func (r *Repo) docsPathspec() []string {
+	args := []string{"--"}
+	for _, root := range r.GetDocsRoots() {
+		if _, err := os.Stat(filepath.Join(r.localPath, root)); err == nil {
+			args = append(args, ":(top)"+root)
+		}
+	}
+	return args
+}
+
+func (r *Repo) SetCloneOptions(opts CloneOptions) {
+	r.cloneOpts = opts
+}
+
+// DefaultCloneDir is where repositories are cloned without a clone_dir: /tmp,
+// or the user's temp directory on Windows, which has no /tmp.
+func DefaultCloneDir() string {
+	if runtime.GOOS == "windows" {
+		return os.TempDir()
+	}
+	return "/tmp"
+}
+
 func (r *Repo) Clone(repoName string) (string, error) {
-	targetDir := filepath.Join("/tmp", repoName)
+	if err := netguard.Check("cloning the repository"); err != nil {
+		return "", err
+	}
+
+	cloneDir := r.cloneOpts.Dir
+	if cloneDir == "" {
+		cloneDir = DefaultCloneDir()
+	}
+	if err := os.MkdirAll(cloneDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create clone directory: %w", err)
+	}
+
+	targetDir := filepath.Join(cloneDir, repoName)
 
 	if _, err := os.Stat(targetDir); err == nil {
+		if r.cloneOpts.Reuse {
+			err := r.refreshClone(targetDir)
+			if err == nil {
+				r.localPath = targetDir
+				fmt.Printf("Reusing existing clone at: %s\n", targetDir)
+				return targetDir, nil
+			}
+			fmt.Printf("Could not reuse existing clone (%v), cloning fresh\n", err)
+		}
+
 		fmt.Printf("Removing existing directory: %s\n", targetDir)
 		if err := os.RemoveAll(targetDir); err != nil {
 			return "", fmt.Errorf("failed to remove existing directory: %w", err)
 		}
 	}
 
-	fmt.Printf("Cloning %s to %s\n", r.url, targetDir)
-	cmd := exec.Command("git", "clone", r.url, targetDir)
+	args := []string{"clone"}
+	if r.cloneOpts.Depth > 0 {
+		args = append(args, "--depth", fmt.Sprintf("%d", r.cloneOpts.Depth), "--no-single-branch")
+	}
+	if r.scope != "" {
+		args = append(args, "--filter=blob:none", "--sparse")
+	}
+	if r.branch != "" {
+		args = append(args, "--branch", r.branch)
+	}
+	args = append(args, r.url, targetDir)
+
+	fmt.Printf("Cloning %s to %s\n", redact.String(r.url), targetDir)
+	cmd := exec.Command("git", args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
@@ -39,6 +331,17 @@ func (r *Repo) Clone(repoName string) (string, error) {
 		return "", fmt.Errorf("failed to clone repository: %w", err)
 	}
 
+	if r.scope != "" {
+		fmt.Printf("Checking out scope: %s\n", r.scope)
+		sparseCmd := exec.Command("git", "sparse-checkout", "set", r.scope)
+		sparseCmd.Dir = targetDir
+		sparseCmd.Stdout = os.Stdout
+		sparseCmd.Stderr = os.Stderr
+		if err := sparseCmd.Run(); err != nil {
+			return "", fmt.Errorf("failed to set sparse checkout: %w", err)
+		}
+	}
+
 	r.localPath = targetDir
 	fmt.Printf("Successfully cloned repository to: %s\n", targetDir)
 	fmt.Printf("Local path set to: %s\n", r.localPath)
@@ -46,6 +349,71 @@ func (r *Repo) Clone(repoName string) (string, error) {
 	return targetDir, nil
 }
 
+// refreshClone brings an existing clone up to date with the selected branch
+// (or the remote's default branch), discarding any local changes left behind
+// by a previous run.
+func (r *Repo) refreshClone(dir string) error {
+	runIn := func(args ...string) (string, error) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		output, err := cmd.Output()
+		return strings.TrimSpace(string(output)), err
+	}
+
+	originURL, err := runIn("remote", "get-url", "origin")
+	if err != nil {
+		return fmt.Errorf("not a git clone")
+	}
+	if originURL != r.url {
+		return fmt.Errorf("existing clone points at %s", originURL)
+	}
+
+	fmt.Println("Fetching latest changes...")
+	fetchArgs := []string{"fetch", "--prune", "origin"}
+	if r.cloneOpts.Depth > 0 {
+		fetchArgs = append(fetchArgs, "--depth", fmt.Sprintf("%d", r.cloneOpts.Depth))
+	}
+	if _, err := runIn(fetchArgs...); err != nil {
+		return fmt.Errorf("fetch failed: %w", err)
+	}
+
+	defaultRef := "origin/" + r.branch
+	if r.branch == "" {
+		defaultRef, err = runIn("symbolic-ref", "--short", "refs/remotes/origin/HEAD")
+		if err != nil {
+			if _, err := runIn("remote", "set-head", "origin", "--auto"); err != nil {
+				return fmt.Errorf("failed to determine default branch: %w", err)
+			}
+			if defaultRef, err = runIn("symbolic-ref", "--short", "refs/remotes/origin/HEAD"); err != nil {
+				return fmt.Errorf("failed to determine default branch: %w", err)
+			}
+		}
+	} else if _, err := runIn("rev-parse", "--verify", "--quiet", defaultRef); err != nil {
+		return fmt.Errorf("branch %s not found on origin", r.branch)
+	}
+	branch := strings.TrimPrefix(defaultRef, "origin/")
+
+	if _, err := runIn("checkout", "--force", "-B", branch, defaultRef); err != nil {
+		return fmt.Errorf("failed to check out %s: %w", branch, err)
+	}
+	if _, err := runIn("reset", "--hard", defaultRef); err != nil {
+		return fmt.Errorf("failed to reset to %s: %w", defaultRef, err)
+	}
+	if _, err := runIn("clean", "-fdx"); err != nil {
+		return fmt.Errorf("failed to clean working tree: %w", err)
+	}
+
+	if r.scope != "" {
+		if _, err := runIn("sparse-checkout", "set", r.scope); err != nil {
+			return fmt.Errorf("failed to set sparse checkout: %w", err)
+		}
+	} else if _, err := runIn("sparse-checkout", "disable"); err != nil {
+		return fmt.Errorf("failed to disable sparse checkout: %w", err)
+	}
+
+	return nil
+}
+
 func (r *Repo) GetLocalPath() string {
 	return r.localPath
 }
@@ -54,13 +422,88 @@ func (r *Repo) SetLocalPath(path string) {
 	r.localPath = path
 }
 
-func (r *Repo) CreatePR() error {
+// GetRemoteURL returns the URL the repository was cloned from, or the origin
+// remote for local checkouts.
+func (r *Repo) GetRemoteURL() (string, error) {
+	if r.url != "" {
+		return r.url, nil
+	}
+	if r.localPath == "" {
+		return "", fmt.Errorf("repository not cloned")
+	}
+
+	cmd := exec.Command("git", "remote", "get-url", "origin")
+	cmd.Dir = r.localPath
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get origin remote URL: %w", err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (r *Repo) GetCurrentBranch() (string, error) {
+	if r.localPath == "" {
+		return "", fmt.Errorf("repository not cloned")
+	}
+
+	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
+	cmd.Dir = r.localPath
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current branch: %w", err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// CreatePR commits the documentation changes on a new branch, pushes it, and
+// opens a PR with the configured metadata, filled in from run.
+func (r *Repo) CreatePR(run PRRun) error {
 	if r.localPath == "" {
 		return fmt.Errorf("repository not cloned")
 	}
+	if err := netguard.Check("pushing the branch and opening a pull request"); err != nil {
+		return err
+	}
+
+	pathspec := r.docsPathspec()
+	if run.PathsOnly {
+		pathspec = []string{"--"}
+	}
+	for _, path := range run.Paths {
+		pathspec = append(pathspec, ":(top)"+path)
+	}
+	if len(pathspec) == 1 {
+		fmt.Println("No documentation directories found")
+		return nil
+	}
+	if r.local && r.baseline != nil {
+		files, err := r.baseline.runChanges(r, pathspec)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			fmt.Println("No changes to commit in documentation directory")
+			return nil
+		}
+		pathspec = []string{"--"}
+		for _, file := range files {
+			pathspec = append(pathspec, ":(top)"+file)
+		}
+	}
+
+	provider, s, err := r.hostingProvider()
+	if err != nil {
+		return err
+	}
+	remoteURL, err := r.GetRemoteURL()
+	if err != nil {
+		return err
+	}
 
 	now := time.Now()
-	branchName := fmt.Sprintf("docu-jarvis_%02d/%02d/%d_%02d_%02d",
+	branchName := fmt.Sprintf("%s%s%02d/%02d/%d_%02d_%02d", run.BranchPrefix, branchMarker,
 		now.Day(), now.Month(), now.Year(), now.Hour(), now.Minute())
 
 	originalDir, err := os.Getwd()
@@ -73,51 +516,49 @@ func (r *Repo) CreatePR() error {
 		return fmt.Errorf("failed to change directory: %w", err)
 	}
 
-	if err := runCommand("git", "config", "user.name", "Docu Jarvis"); err != nil {
-		return fmt.Errorf("failed to set git user.name: %w", err)
-	}
-
-	if err := runCommand("git", "config", "user.email", "docu-jarvis@automation.local"); err != nil {
-		return fmt.Errorf("failed to set git user.email: %w", err)
-	}
-
-	if err := runCommand("git", "checkout", "-b", branchName); err != nil {
-		return fmt.Errorf("failed to create branch: %w", err)
-	}
+	// Local checkouts keep the user's own identity and return to their branch
+	if r.local {
+		originalBranch, err := r.GetCurrentBranch()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := runCommand("git", "checkout", originalBranch); err != nil {
+				fmt.Printf("Warning: failed to switch back to branch %s: %v\n", originalBranch, err)
+			}
+		}()
+	} else {
+		if err := runCommand("git", "config", "user.name", "Docu Jarvis"); err != nil {
+			return fmt.Errorf("failed to set git user.name: %w", err)
+		}
 
-	if err := runCommand("git", "add", "documentation/"); err != nil {
-		return fmt.Errorf("failed to add documentation: %w", err)
+		if err := runCommand("git", "config", "user.email", "docu-jarvis@automation.local"); err != nil {
+			return fmt.Errorf("failed to set git user.email: %w", err)
+		}
 	}
 
-	cmd := exec.Command("git", "diff", "--cached", "--quiet")
-	if err := cmd.Run(); err == nil {
-		fmt.Println("No changes to commit in documentation directory")
-		return nil
+	base := r.prBase()
+	split := firstNonEmpty(r.prOptions.Split, s.PRSplit)
+	if !r.confirmPush(remoteURL, base, split != "") {
+		fmt.Printf("Not pushed: the changes are left uncommitted in %s\n", r.localPath)
+		return ErrPushDeclined
 	}
 
-	commitMessage := "docs: automated documentation improvements by docu-jarvis"
-	if err := runCommand("git", "commit", "-m", commitMessage); err != nil {
-		return fmt.Errorf("failed to commit changes: %w", err)
+	if split != "" {
+		return r.createSplitPRs(provider, s, run, split, branchName, base, remoteURL, pathspec)
 	}
 
-	fmt.Printf("Pushing branch: %s\n", branchName)
-	if err := runCommand("git", "push", "origin", branchName); err != nil {
-		return fmt.Errorf("failed to push branch: %w", err)
+	pr := r.pullRequest(s, run, branchName, base, remoteURL)
+	if docs, err := r.changedDocs(pathspec); err == nil {
+		r.addSuggestedReviewers(&pr, provider, s.PRSuggestReviewers, docs)
 	}
 
-	prTitle := "Documentation Update"
-	prDescription := "Automated docu-jarvis suggestions"
-
-	if err := runCommand("gh", "pr", "create",
-		"--title", prTitle,
-		"--body", prDescription,
-		"--head", branchName,
-		"--base", "main"); err != nil {
-		return fmt.Errorf("failed to create PR: %w", err)
+	if err := runCommand("git", "checkout", "-b", branchName); err != nil {
+		return fmt.Errorf("failed to create branch: %w", err)
 	}
 
-	fmt.Printf("Successfully created PR with branch: %s\n", branchName)
-	return nil
+	_, err = r.commitAndOpenPR(provider, pr, pathspec)
+	return err
 }
 
 func (r *Repo) HasChanges() (bool, error) {
@@ -135,7 +576,12 @@ func (r *Repo) HasChanges() (bool, error) {
 		return false, fmt.Errorf("failed to change directory: %w", err)
 	}
 
-	cmd := exec.Command("git", "status", "--porcelain", "documentation/")
+	pathspec := r.docsPathspec()
+	if len(pathspec) == 1 {
+		return false, nil
+	}
+
+	cmd := exec.Command("git", append([]string{"status", "--porcelain"}, pathspec...)...)
 	output, err := cmd.Output()
 	if err != nil {
 		return false, fmt.Errorf("failed to check git status: %w", err)
@@ -144,7 +590,34 @@ func (r *Repo) HasChanges() (bool, error) {
 	return len(strings.TrimSpace(string(output))) > 0, nil
 }
 
-func (r *Repo) GetCommitsBetweenDates(fromDate, toDate string) ([]string, error) {
+// ChangedDocs returns the changed and added files in the docs roots, relative
+// to the repository root.
+func (r *Repo) ChangedDocs() ([]string, error) {
+	if r.localPath == "" {
+		return nil, fmt.Errorf("repository not cloned")
+	}
+	pathspec := r.docsPathspec()
+	if len(pathspec) == 1 {
+		return nil, nil
+	}
+
+	files, err := r.changedDocs(pathspec)
+	if err != nil {
+		return nil, err
+	}
+	var existing []string
+	for _, file := range files {
+		if _, err := os.Stat(filepath.Join(r.localPath, file)); err == nil {
+			existing = append(existing, file)
+		}
+	}
+	return existing, nil
+}
+
+// GetCommitsBetweenDates lists the commits between two dates, newest first,
+// as hash|author|date|subject. A commit that repeats an older one's change,
+// such as a cherry-pick onto a release branch, is left out.
+func (r *Repo) GetCommitsBetweenDates(fromDate, toDate string, opts HistoryOptions) ([]string, error) {
 	if r.localPath == "" {
 		return nil, fmt.Errorf("repository not cloned")
 	}
@@ -159,10 +632,21 @@ func (r *Repo) GetCommitsBetweenDates(fromDate, toDate string) ([]string, error)
 		return nil, fmt.Errorf("failed to change directory: %w", err)
 	}
 
+	refs, err := r.historyRefs(opts.Branches)
+	if err != nil {
+		return nil, err
+	}
+	logArgs := []string{"--since=" + fromDate, "--until=" + toDate}
+	if opts.FirstParent {
+		logArgs = append(logArgs, "--first-parent")
+	}
+	logArgs = append(append(logArgs, refs...), r.pathspec()...)
+
 	// Format: hash|author|date|subject
 	gitLogFormat := "--pretty=format:%H|%an|%ai|%s"
 
-	cmd := exec.Command("git", "log", gitLogFormat, "--since="+fromDate, "--until="+toDate)
+	args := append([]string{"log", gitLogFormat}, logArgs...)
+	cmd := exec.Command("git", args...)
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get git log: %w", err)
@@ -180,6 +664,15 @@ func (r *Repo) GetCommitsBetweenDates(fromDate, toDate string) ([]string, error)
 		}
 	}
 
+	ids, err := r.patchIDs(logArgs)
+	if err != nil {
+		fmt.Printf("Warning: failed to find cherry-picked duplicates, analyzing every commit: %v\n", err)
+		return commits, nil
+	}
+	commits, dropped := dropDuplicatePatches(commits, ids)
+	if dropped > 0 {
+		fmt.Printf("Skipping %d commits that repeat an older commit's change (cherry-picks)\n", dropped)
+	}
 	return commits, nil
 }
 
@@ -198,7 +691,8 @@ func (r *Repo) GetStagedDiff() (string, error) {
 		return "", fmt.Errorf("failed to change directory: %w", err)
 	}
 
-	cmd := exec.Command("git", "diff", "--cached")
+	args := append([]string{"diff", "--cached"}, r.pathspec()...)
+	cmd := exec.Command("git", args...)
 	output, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to get staged diff: %w", err)
@@ -211,6 +705,148 @@ func (r *Repo) GetStagedDiff() (string, error) {
 	return string(output), nil
 }
 
+func (r *Repo) isShallow() bool {
+	output, err := r.git("rev-parse", "--is-shallow-repository")
+	return err == nil && output == "true"
+}
+
+// EnsureCommit makes sure a commit is available locally and returns its full
+// hash. Commits that a shallow or stale clone does not have yet are fetched
+// from origin: full hashes directly, short ones by fetching the rest of the
+// history. A shallow boundary commit is deepened so its diff is correct.
+func (r *Repo) EnsureCommit(hash string) (string, error) {
+	if r.localPath == "" {
+		return "", fmt.Errorf("repository not cloned")
+	}
+
+	resolve := func() (string, error) {
+		return r.git("rev-parse", "--verify", "--quiet", hash+"^{commit}")
+	}
+
+	full, err := resolve()
+	if err != nil {
+		if err := netguard.Check(fmt.Sprintf("fetching commit %s (not found locally)", hash)); err != nil {
+			return "", err
+		}
+		shallow := r.isShallow()
+		fmt.Printf("Commit %s not found locally, fetching from origin...\n", hash)
+
+		var fetchErr error
+		if fullHashPattern.MatchString(hash) {
+			fetchArgs := []string{"fetch", "--quiet", "origin", hash}
+			if shallow {
+				fetchArgs = append(fetchArgs, "--depth", "2")
+			}
+			_, fetchErr = r.git(fetchArgs...)
+		}
+
+		if full, err = resolve(); err != nil {
+			fetchArgs := []string{"fetch", "--quiet", "origin"}
+			if shallow {
+				fetchArgs = append(fetchArgs, "--unshallow")
+			}
+			if _, err := r.git(fetchArgs...); err != nil {
+				return "", fmt.Errorf("commit %s not found locally and fetching from origin failed: %w", hash, err)
+			}
+			if full, err = resolve(); err != nil {
+				if fetchErr != nil {
+					return "", fmt.Errorf("commit %s not found after fetching all history from origin, and fetching it directly failed: %w", hash, fetchErr)
+				}
+				return "", fmt.Errorf("commit %s does not exist (not found after fetching all history from origin)", hash)
+			}
+		}
+	}
+
+	// A commit on the shallow boundary looks like it has no parent
+	if r.isShallow() && !netguard.Disabled() {
+		if _, err := r.git("rev-parse", "--verify", "--quiet", full+"^"); err != nil {
+			if boundary, _ := os.ReadFile(filepath.Join(r.gitDir(), "shallow")); strings.Contains(string(boundary), full) {
+				if _, err := r.git("fetch", "--quiet", "--deepen=1", "origin"); err != nil {
+					return "", fmt.Errorf("failed to fetch the parent of shallow boundary commit %s: %w", hash, err)
+				}
+			}
+		}
+	}
+
+	return full, nil
+}
+
+// EnsureHistorySince deepens a shallow clone so it includes every commit
+// after the date. Full clones are left alone.
+func (r *Repo) EnsureHistorySince(date string) error {
+	if r.localPath == "" {
+		return fmt.Errorf("repository not cloned")
+	}
+	if !r.isShallow() {
+		return nil
+	}
+
+	if err := netguard.Check("fetching history of a shallow clone"); err != nil {
+		return err
+	}
+	fmt.Printf("Shallow clone, fetching history since %s...\n", date)
+	if _, err := r.git("fetch", "--quiet", "--shallow-since="+date, "origin"); err != nil {
+		return fmt.Errorf("failed to fetch history since %s: %w", date, err)
+	}
+	return nil
+}
+
+func (r *Repo) gitDir() string {
+	dir, err := r.git("rev-parse", "--path-format=absolute", "--git-dir")
+	if err != nil {
+		return filepath.Join(r.localPath, ".git")
+	}
+	return dir
+}
+
+// runMarkerFile, in the git directory, holds the ID of the update-docs run
+// that last used the checkout.
+const runMarkerFile = "docu-jarvis-run"
+
+// MarkRun records that the run is using the checkout, so a resumed run can
+// tell whether a later run has reused (and reset) it since.
+func (r *Repo) MarkRun(id string) error {
+	if err := os.WriteFile(filepath.Join(r.gitDir(), runMarkerFile), []byte(id+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to mark checkout: %w", err)
+	}
+	return nil
+}
+
+// MarkedRun returns the ID of the run that last used the checkout, if any.
+func (r *Repo) MarkedRun() string {
+	content, err := os.ReadFile(filepath.Join(r.gitDir(), runMarkerFile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// LastChanged returns the time of the last commit that changed each of the
+// paths. Paths that were never committed are left out; in a shallow clone,
+// history before the clone's depth is not seen.
+func (r *Repo) LastChanged(paths []string) (map[string]time.Time, error) {
+	if r.localPath == "" {
+		return nil, fmt.Errorf("repository not cloned")
+	}
+
+	changed := make(map[string]time.Time, len(paths))
+	for _, path := range paths {
+		out, err := r.git("log", "-1", "--format=%ct", "--", path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history of %s: %w", path, err)
+		}
+		if out == "" {
+			continue
+		}
+		seconds, err := strconv.ParseInt(out, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected commit time %q for %s", out, path)
+		}
+		changed[path] = time.Unix(seconds, 0)
+	}
+	return changed, nil
+}
+
 func (r *Repo) GetCommitDiff(commitHash string) (string, error) {
 	if r.localPath == "" {
 		return "", fmt.Errorf("repository not cloned")
@@ -226,7 +862,8 @@ func (r *Repo) GetCommitDiff(commitHash string) (string, error) {
 		return "", fmt.Errorf("failed to change directory: %w", err)
 	}
 
-	cmd := exec.Command("git", "show", commitHash, "--format=fuller")
+	args := append([]string{"show", commitHash, "--format=fuller"}, r.pathspec()...)
+	cmd := exec.Command("git", args...)
 	output, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to get commit diff: %w", err)
@@ -239,6 +876,256 @@ func (r *Repo) GetCommitDiff(commitHash string) (string, error) {
 	return string(output), nil
 }
 
+func (r *Repo) GetBranchDiff(baseBranch string) (string, error) {
+	if r.localPath == "" {
+		return "", fmt.Errorf("repository not cloned")
+	}
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(r.localPath); err != nil {
+		return "", fmt.Errorf("failed to change directory: %w", err)
+	}
+
+	args := append([]string{"diff", baseBranch + "...HEAD"}, r.pathspec()...)
+	cmd := exec.Command("git", args...)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get branch diff against %s: %w", baseBranch, err)
+	}
+
+	if len(output) == 0 {
+		return "", fmt.Errorf("no changes between %s and HEAD", baseBranch)
+	}
+
+	return string(output), nil
+}
+
+func (r *Repo) GetPRDiff(prNumber string) (string, error) {
+	if r.localPath == "" {
+		return "", fmt.Errorf("repository not cloned")
+	}
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(r.localPath); err != nil {
+		return "", fmt.Errorf("failed to change directory: %w", err)
+	}
+
+	if err := netguard.Check("fetching the PR diff"); err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command("gh", "pr", "diff", prNumber)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get diff for PR %s: %w", prNumber, err)
+	}
+
+	if len(output) == 0 {
+		return "", fmt.Errorf("PR %s has no changes", prNumber)
+	}
+
+	return string(output), nil
+}
+
+// CommentOnPR posts a comment on a pull request. An empty prNumber targets
+// the PR for the current branch.
+func (r *Repo) CommentOnPR(prNumber, body string) error {
+	if r.localPath == "" {
+		return fmt.Errorf("repository not cloned")
+	}
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(r.localPath); err != nil {
+		return fmt.Errorf("failed to change directory: %w", err)
+	}
+
+	if err := netguard.Check("commenting on the PR"); err != nil {
+		return err
+	}
+
+	args := []string{"pr", "comment"}
+	if prNumber != "" {
+		args = append(args, prNumber)
+	}
+	args = append(args, "--body-file", "-")
+
+	cmd := exec.Command("gh", args...)
+	cmd.Stdin = strings.NewReader(body)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to comment on PR: %w", err)
+	}
+
+	return nil
+}
+
+// GetCommitMessages returns the commits in a revision range (e.g. "main..HEAD"),
+// oldest first, with their full messages.
+func (r *Repo) GetCommitMessages(revRange string) ([]Commit, error) {
+	if r.localPath == "" {
+		return nil, fmt.Errorf("repository not cloned")
+	}
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current directory: %w", err)
+	}
+	defer os.Chdir(originalDir)
+
+	if err := os.Chdir(r.localPath); err != nil {
+		return nil, fmt.Errorf("failed to change directory: %w", err)
+	}
+
+	// Fields are separated by \x1f and records by \x1e since bodies span lines
+	args := append([]string{"log", "--reverse", "--no-merges", "--pretty=format:%H%x1f%s%x1f%b%x1e", revRange}, r.pathspec()...)
+	cmd := exec.Command("git", args...)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git log for %s: %w", revRange, err)
+	}
+
+	var commits []Commit
+	for _, record := range strings.Split(string(output), "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, "\x1f", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		commits = append(commits, Commit{
+			Hash:    fields[0],
+			Subject: fields[1],
+			Body:    strings.TrimSpace(fields[2]),
+		})
+	}
+
+	return commits, nil
+}
+
+// WriteRewordTodo writes a rebase todo list to dir that picks every commit and
+// amends the message of those in rewrites (keyed by full hash). It returns
+// the path of the todo file.
+func WriteRewordTodo(commits []Commit, rewrites map[string]string, dir string) (string, error) {
+	var todo strings.Builder
+	for i, commit := range commits {
+		todo.WriteString(fmt.Sprintf("pick %s %s\n", commit.Hash, commit.Subject))
+
+		message, ok := rewrites[commit.Hash]
+		if !ok {
+			continue
+		}
+
+		msgPath := filepath.Join(dir, fmt.Sprintf("message-%d.txt", i))
+		if err := os.WriteFile(msgPath, []byte(message+"\n"), 0644); err != nil {
+			return "", fmt.Errorf("failed to write commit message file: %w", err)
+		}
+		todo.WriteString(fmt.Sprintf("exec git commit --amend --quiet -F %s\n", shellPath(msgPath)))
+	}
+
+	todoPath := filepath.Join(dir, "git-rebase-todo")
+	if err := os.WriteFile(todoPath, []byte(todo.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write rebase todo: %w", err)
+	}
+
+	return todoPath, nil
+}
+
+// FixupBase returns the commit a rewrite of the messages in revRange rebases
+// onto: the merge base of the range's start and HEAD. The range must end at
+// HEAD, as the rebase replays every commit after its base and a commit after
+// the range's end would be dropped from the todo.
+func (r *Repo) FixupBase(revRange string) (string, error) {
+	start, end, ok := strings.Cut(revRange, "..")
+	if !ok || strings.HasPrefix(end, ".") || start == "" {
+		return "", fmt.Errorf("-fixup needs a range like main..HEAD, got %s", revRange)
+	}
+	if end == "" {
+		end = "HEAD"
+	}
+
+	endHash, err := r.git("rev-parse", "--verify", end+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", end, err)
+	}
+	head, err := r.git("rev-parse", "--verify", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	if endHash != head {
+		return "", fmt.Errorf("-fixup needs a range that ends at HEAD, but %s is not HEAD; check out %s first", end, end)
+	}
+
+	base, err := r.git("merge-base", start, "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to find merge base with %s: %w", start, err)
+	}
+	return base, nil
+}
+
+// RebaseWithTodo runs an interactive rebase from the merge base of base and
+// HEAD using a prepared todo list instead of opening an editor.
+func (r *Repo) RebaseWithTodo(base, todoPath string) error {
+	if r.localPath == "" {
+		return fmt.Errorf("repository not cloned")
+	}
+
+	mergeBaseCmd := exec.Command("git", "merge-base", base, "HEAD")
+	mergeBaseCmd.Dir = r.localPath
+	output, err := mergeBaseCmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to find merge base with %s: %w", base, err)
+	}
+
+	cmd := exec.Command("git", "rebase", "-i", strings.TrimSpace(string(output)))
+	cmd.Dir = r.localPath
+	cmd.Env = append(os.Environ(), "GIT_SEQUENCE_EDITOR=cp "+shellPath(todoPath))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("rebase failed (run 'git rebase --abort' to undo): %w", err)
+	}
+
+	return nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellPath quotes a path for the shell git runs editors and exec lines in,
+// which is the sh bundled with Git on Windows and wants forward slashes.
+func shellPath(path string) string {
+	return shellQuote(filepath.ToSlash(path))
+}
+
+// git runs a git command in the repository and returns its trimmed output.
+func (r *Repo) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.localPath
+	output, err := cmd.Output()
+	return strings.TrimSpace(string(output)), err
+}
+
 func runCommand(name string, args ...string) error {
 	cmd := exec.Command(name, args...)
 	cmd.Stdout = os.Stdout
@@ -0,0 +1,344 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// EditGuard records the working tree before an agent run, so that the edits
+// the agent made outside the allow-list can be found and reverted after it.
+// Changes that were there before the run, such as uncommitted work in a local
+// checkout, are kept as they were.
+type EditGuard struct {
+	root     string
+	patterns []*regexp.Regexp
+	before   map[string]string // changed paths: the blob of their content, "" when missing
+}
+
+// RevertedEdit is an edit outside the allow-list that Enforce undid.
+type RevertedEdit struct {
+	Path   string
+	Change string // created, modified, or deleted
+}
+
+// GuardEdits starts an EditGuard for the repository's edit allow-list. The
+// content of every changed file is stored in the object database, so it can
+// be restored whatever the agent does to it. In a local checkout the first
+// guard is kept, so that the PR commits only what the run changed.
+func (r *Repo) GuardEdits() (*EditGuard, error) {
+	root, err := r.git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the repository root: %w", err)
+	}
+	g := &EditGuard{root: root, before: make(map[string]string)}
+	for _, pattern := range r.EditAllowlist() {
+		g.patterns = append(g.patterns, codeownersPattern(pattern))
+	}
+
+	changed, err := g.changedFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range changed {
+		blob, err := g.fileBlob(path, true)
+		if err != nil {
+			return nil, err
+		}
+		g.before[path] = blob
+	}
+	if r.local && r.baseline == nil {
+		r.baseline = g
+	}
+	return g, nil
+}
+
+// runChanges returns the files in pathspec, relative to the repository root,
+// that changed since the guard started. Files that only hold changes from
+// before it are left out; those the run changed on top of them are kept.
+func (g *EditGuard) runChanges(r *Repo, pathspec []string) ([]string, error) {
+	files, err := r.changedDocs(pathspec)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for _, file := range files {
+		original, wasChanged := g.before[file]
+		if !wasChanged {
+			changed = append(changed, file)
+			continue
+		}
+		current, err := g.fileBlob(file, false)
+		if err != nil {
+			return nil, err
+		}
+		if current == original {
+			fmt.Printf("Leaving %s out of the PR: its changes were there before the run\n", file)
+			continue
+		}
+		fmt.Printf("Warning: %s had uncommitted changes before the run, they go into the PR with the run's\n", file)
+		changed = append(changed, file)
+	}
+	return changed, nil
+}
+
+// Enforce reverts the edits made since GuardEdits outside the allow-list and
+// returns them.
+func (g *EditGuard) Enforce() ([]RevertedEdit, error) {
+	changed, err := g.changedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	paths := changed
+	for path := range g.before {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var reverted []RevertedEdit
+	for i, path := range paths {
+		if (i > 0 && paths[i-1] == path) || g.allowed(path) {
+			continue
+		}
+
+		var current, original string
+		if blob, ok := g.before[path]; ok {
+			original = blob
+			if current, err = g.fileBlob(path, true); err != nil {
+				return reverted, err
+			}
+		} else {
+			// The file was as committed, or missing when it is not
+			original, _ = g.git("rev-parse", "--verify", "--quiet", "HEAD:"+path)
+			if current, err = g.committedBlob(path); err != nil {
+				return reverted, err
+			}
+		}
+		if current == original {
+			continue
+		}
+
+		_, wasChanged := g.before[path]
+		if err := g.restore(path, original, wasChanged); err != nil {
+			return reverted, fmt.Errorf("failed to revert %s: %w", path, err)
+		}
+		change := "modified"
+		switch {
+		case current == "":
+			change = "deleted"
+		case original == "":
+			change = "created"
+		}
+		reverted = append(reverted, RevertedEdit{Path: path, Change: change})
+	}
+	return reverted, nil
+}
+
+// Revert puts files, relative to the repository root, back as they were at
+// GuardEdits, whether the allow-list covers them or not.
+func (g *EditGuard) Revert(paths []string) error {
+	for _, path := range paths {
+		original, wasChanged := g.before[path]
+		if !wasChanged {
+			original, _ = g.git("rev-parse", "--verify", "--quiet", "HEAD:"+path)
+		}
+		if err := g.restore(path, original, wasChanged); err != nil {
+			return fmt.Errorf("failed to revert %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func (g *EditGuard) allowed(path string) bool {
+	for _, pattern := range g.patterns {
+		if pattern.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// changedFiles lists the files of the working tree that differ from HEAD,
+// untracked ones included. Submodules are left alone.
+func (g *EditGuard) changedFiles() ([]string, error) {
+	cmd := exec.Command("git", "status", "--porcelain", "-z", "-uall", "--no-renames")
+	cmd.Dir = g.root
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check git status: %w", err)
+	}
+	var paths []string
+	for _, entry := range strings.Split(string(output), "\x00") {
+		if len(entry) <= 3 {
+			continue
+		}
+		path := entry[3:]
+		if info, err := os.Lstat(filepath.Join(g.root, path)); err == nil && info.IsDir() {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// fileBlob returns the blob hash of a file's exact content, or "" when it is
+// missing. With write the content is stored, so it can be restored.
+func (g *EditGuard) fileBlob(path string, write bool) (string, error) {
+	if _, err := os.Lstat(filepath.Join(g.root, path)); os.IsNotExist(err) {
+		return "", nil
+	}
+	args := []string{"hash-object", "--no-filters"}
+	if write {
+		args = append(args, "-w")
+	}
+	blob, err := g.git(append(args, "--", path)...)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return blob, nil
+}
+
+// committedBlob returns the blob a file would be committed as, to compare
+// with HEAD, or "" when it is missing.
+func (g *EditGuard) committedBlob(path string) (string, error) {
+	if _, err := os.Lstat(filepath.Join(g.root, path)); os.IsNotExist(err) {
+		return "", nil
+	}
+	blob, err := g.git("hash-object", "--path="+path, "--", path)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return blob, nil
+}
+
+// restore puts a file back as it was: as committed, unless it had changes
+// before the run, whose blob holds its exact content. A file that was missing
+// is removed, with the directories it leaves empty.
+func (g *EditGuard) restore(path, blob string, wasChanged bool) error {
+	full := filepath.Join(g.root, path)
+	switch {
+	case blob == "":
+		if err := os.Remove(full); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for dir := filepath.Dir(full); dir != g.root && strings.HasPrefix(dir, g.root); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+		return nil
+	case !wasChanged:
+		_, err := g.git("checkout", "HEAD", "--", path)
+		return err
+	}
+
+	cmd := exec.Command("git", "cat-file", "blob", blob)
+	cmd.Dir = g.root
+	content, err := cmd.Output()
+	if err != nil {
+		return err
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(full); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(full, content, mode)
+}
+
+func (g *EditGuard) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = g.root
+	output, err := cmd.Output()
+	return strings.TrimSpace(string(output)), err
+}
+
+// EditRoots returns the directories Claude's edit tools are limited to while
+// it runs, as absolute paths: each allow-list pattern up to its first
+// wildcard, or the repository root for patterns that match at any depth.
+// EditGuard checks the exact patterns after the run.
+func (r *Repo) EditRoots() []string {
+	root, err := r.git("rev-parse", "--show-toplevel")
+	if err != nil {
+		root = r.localPath
+	}
+
+	var roots []string
+	for _, pattern := range r.EditAllowlist() {
+		dir := root
+		// A slash anywhere but at the end anchors the pattern to the root
+		if strings.Contains(strings.TrimSuffix(pattern, "/"), "/") {
+			var literal []string
+			for _, part := range strings.Split(strings.Trim(pattern, "/"), "/") {
+				if strings.ContainsAny(part, "*?[") {
+					break
+				}
+				literal = append(literal, part)
+			}
+			dir = filepath.Join(append([]string{root}, literal...)...)
+		}
+		roots = appendMissing(roots, dir)
+	}
+	return roots
+}
+
+// checkCommit fails when a commit of addArgs would take along changes outside
+// the edit allow-list and addArgs: changes staged outside them, or, in a
+// clone, where nothing else is expected to change, any change outside them.
+func (r *Repo) checkCommit(addArgs []string) error {
+	var patterns []*regexp.Regexp
+	for _, pattern := range r.EditAllowlist() {
+		patterns = append(patterns, codeownersPattern(pattern))
+	}
+	for _, arg := range addArgs {
+		if path, ok := strings.CutPrefix(arg, ":(top)"); ok {
+			patterns = append(patterns, codeownersPattern("/"+strings.Trim(path, "/")))
+		}
+	}
+
+	cmd := exec.Command("git", "status", "--porcelain", "-z", "-uall", "--no-renames")
+	cmd.Dir = r.localPath
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to check git status: %w", err)
+	}
+
+	var outside []string
+	for _, entry := range strings.Split(string(output), "\x00") {
+		if len(entry) <= 3 {
+			continue
+		}
+		staged, path := entry[0] != ' ' && entry[0] != '?', entry[3:]
+		if !staged && r.local {
+			// Uncommitted work in the user's checkout stays out of the commit
+			continue
+		}
+		allowed := false
+		for _, pattern := range patterns {
+			if pattern.MatchString(path) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			outside = append(outside, path)
+		}
+	}
+	if len(outside) == 0 {
+		return nil
+	}
+	if r.local {
+		return fmt.Errorf("not committing: %s staged outside the allowed paths (%s); unstage them first",
+			strings.Join(outside, ", "), strings.Join(r.EditAllowlist(), ", "))
+	}
+	return fmt.Errorf("not committing: %s changed outside the allowed paths (%s)",
+		strings.Join(outside, ", "), strings.Join(r.EditAllowlist(), ", "))
+}
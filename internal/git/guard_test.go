@@ -0,0 +1,64 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunChangesLeavesOutEarlierWork(t *testing.T) {
+	repo, _ := newTestRepo(t, "first")
+	dir := repo.GetLocalPath()
+	write := func(file, content string) {
+		t.Helper()
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, file := range []string{"documentation/setup.md", "documentation/api.md", "documentation/faq.md"} {
+		write(file, "# "+file+"\n")
+	}
+	for _, args := range [][]string{{"add", "documentation"}, {"commit", "--quiet", "-m", "docs"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", args[0], err, output)
+		}
+	}
+
+	// The user's uncommitted work, before the run
+	write("documentation/setup.md", "# Setup\n\nDraft by the user.\n")
+	write("documentation/api.md", "# API\n\nDraft by the user.\n")
+	write("documentation/notes.md", "# Notes\n")
+
+	guard, err := repo.GuardEdits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.baseline != guard {
+		t.Fatal("the first guard of a local checkout is not kept")
+	}
+	if later, err := repo.GuardEdits(); err != nil || repo.baseline == later {
+		t.Fatalf("a later guard replaced the first one (err %v)", err)
+	}
+
+	// The run
+	write("documentation/api.md", "# API\n\nDraft by the user.\n\nUpdated by the run.\n")
+	write("documentation/faq.md", "# FAQ\n\nUpdated by the run.\n")
+	write("documentation/new.md", "# New\n")
+
+	files, err := guard.runChanges(repo, repo.docsPathspec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(files, ","), "documentation/api.md,documentation/faq.md,documentation/new.md"; got != want {
+		t.Errorf("runChanges() = %s, want %s", got, want)
+	}
+}
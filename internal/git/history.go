@@ -0,0 +1,101 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// HistoryOptions select the history GetCommitsBetweenDates reads.
+type HistoryOptions struct {
+	// Branches are read instead of the selected branch. Names may be globs,
+	// such as release/*, which match local and remote-tracking branches.
+	Branches []string
+	// FirstParent follows only the first parent of merges, so a merged
+	// branch counts as its merge commit.
+	FirstParent bool
+}
+
+// historyRefs returns the git log arguments naming the branches to read.
+func (r *Repo) historyRefs(branches []string) ([]string, error) {
+	if len(branches) == 0 {
+		return []string{r.historyRef()}, nil
+	}
+
+	var refs []string
+	for _, branch := range branches {
+		if strings.ContainsAny(branch, "*?[") {
+			refs = append(refs, "--branches="+branch, "--remotes=origin/"+branch)
+			continue
+		}
+		ref := r.branchRef(branch)
+		if ref == "" {
+			return nil, fmt.Errorf("branch %s not found in the clone", branch)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// patchIDs maps the commits git log args lists to their patch IDs, which are
+// the same for commits that make the same change, such as a cherry-pick and
+// its original. Merges and empty commits have none.
+func (r *Repo) patchIDs(logArgs []string) (map[string]string, error) {
+	logCmd := exec.Command("git", append([]string{"log", "-p", "--no-color", "--no-decorate", "--pretty=medium"}, logArgs...)...)
+	logCmd.Dir = r.localPath
+	patches, err := logCmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	patchCmd := exec.Command("git", "patch-id", "--stable")
+	patchCmd.Dir = r.localPath
+	patchCmd.Stdin = patches
+
+	if err := logCmd.Start(); err != nil {
+		return nil, err
+	}
+	output, err := patchCmd.Output()
+	if waitErr := logCmd.Wait(); err == nil {
+		err = waitErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			ids[fields[1]] = fields[0]
+		}
+	}
+	return ids, nil
+}
+
+// dropDuplicatePatches removes the commits of a newest-first git log whose
+// change an older commit already made, keeping the original of each
+// cherry-pick. It returns the commits left and how many were dropped.
+func dropDuplicatePatches(commits []string, ids map[string]string) ([]string, int) {
+	seen := make(map[string]bool)
+	keep := make([]bool, len(commits))
+	dropped := 0
+	for i := len(commits) - 1; i >= 0; i-- {
+		hash, _, _ := strings.Cut(commits[i], "|")
+		if id, ok := ids[hash]; ok {
+			if seen[id] {
+				dropped++
+				continue
+			}
+			seen[id] = true
+		}
+		keep[i] = true
+	}
+
+	var unique []string
+	for i, commit := range commits {
+		if keep[i] {
+			unique = append(unique, commit)
+		}
+	}
+	return unique, dropped
+}
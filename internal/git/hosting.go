@@ -0,0 +1,409 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/udemy/docu-jarvis-cli/internal/netguard"
+	"github.com/udemy/docu-jarvis-cli/internal/settings"
+)
+
+type PullRequest struct {
+	Title     string
+	Body      string
+	Head      string // branch with the changes
+	Base      string // branch to merge into
+	Labels    []string
+	Assignees []string
+	Reviewers []string // usernames; GitHub teams as org/team
+	Draft     bool
+	// FileComments are posted on the files of the PR once it is open, by
+	// path relative to the repository root
+	FileComments map[string]string
+
+	commitMessage string // "" for the docs commit message
+}
+
+// HostingProvider opens pull requests (merge requests on GitLab) on the
+// platform hosting the repository.
+type HostingProvider interface {
+	Name() string
+	// CreatePullRequest opens the PR and returns its web URL.
+	CreatePullRequest(ctx context.Context, pr PullRequest) (string, error)
+}
+
+// NewHostingProvider picks the provider from the hosting_provider setting, or
+// from the remote URL's host. Unrecognized hosts are treated as GitHub
+// (Enterprise), so self-hosted GitLab and Bitbucket need hosting_provider.
+func NewHostingProvider(remoteURL string, s *settings.Settings) (HostingProvider, error) {
+	host, path, err := parseRemoteURL(remoteURL)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.ToLower(s.HostingProvider)
+	if name == "" {
+		switch {
+		case strings.Contains(host, "gitlab"):
+			name = "gitlab"
+		case strings.Contains(host, "bitbucket"):
+			name = "bitbucket"
+		default:
+			name = "github"
+		}
+	}
+
+	switch name {
+	case "github":
+		owner, repo, ok := strings.Cut(path, "/")
+		if !ok || strings.Contains(repo, "/") {
+			return nil, fmt.Errorf("cannot parse GitHub owner/repo from %s", remoteURL)
+		}
+		apiURL := "https://api.github.com"
+		if host != "github.com" {
+			// GitHub Enterprise Server
+			apiURL = "https://" + host + "/api/v3"
+		}
+		return &GitHubProvider{host: host, owner: owner, repo: repo, apiURL: apiURL, token: s.GetGitHubToken()}, nil
+
+	case "gitlab":
+		return &GitLabProvider{apiURL: "https://" + host + "/api/v4", project: path, token: s.GetGitLabToken()}, nil
+
+	case "bitbucket":
+		workspace, repo, ok := strings.Cut(path, "/")
+		if !ok || strings.Contains(repo, "/") {
+			return nil, fmt.Errorf("cannot parse Bitbucket workspace/repo from %s", remoteURL)
+		}
+		return &BitbucketProvider{workspace: workspace, repo: repo, token: s.GetBitbucketToken()}, nil
+	}
+
+	return nil, fmt.Errorf("unknown hosting_provider: %s (must be github, gitlab, or bitbucket)", s.HostingProvider)
+}
+
+// parseRemoteURL splits https://host/path, ssh://git@host/path, and
+// git@host:path remotes into the host (with the port for HTTPS) and the
+// repository path without .git.
+func parseRemoteURL(remoteURL string) (string, string, error) {
+	var host, path string
+
+	if strings.Contains(remoteURL, "://") {
+		u, err := url.Parse(remoteURL)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid remote URL %s: %w", remoteURL, err)
+		}
+		host, path = u.Hostname(), u.Path
+		// HTTPS remotes share a port with the web UI and API; SSH ones do not
+		if u.Scheme == "https" {
+			host = u.Host
+		}
+	} else if at, rest, ok := strings.Cut(remoteURL, ":"); ok {
+		host, path = at, rest
+		if i := strings.LastIndex(host, "@"); i >= 0 {
+			host = host[i+1:]
+		}
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || path == "" {
+		return "", "", fmt.Errorf("cannot parse remote URL: %s", remoteURL)
+	}
+
+	return strings.ToLower(host), path, nil
+}
+
+// postJSON sends body as JSON and decodes a 2xx response into out.
+func postJSON(ctx context.Context, provider, endpoint string, headers map[string]string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return sendJSON(ctx, provider, "POST", endpoint, headers, bytes.NewReader(payload), out)
+}
+
+// getJSON decodes the 2xx response of a GET into out.
+func getJSON(ctx context.Context, provider, endpoint string, headers map[string]string, out interface{}) error {
+	return sendJSON(ctx, provider, "GET", endpoint, headers, nil, out)
+}
+
+// hostingTimeout caps one hosting API call, so a stalled API cannot hang a
+// run.
+const hostingTimeout = time.Minute
+
+var hostingClient = &http.Client{Timeout: hostingTimeout}
+
+func sendJSON(ctx context.Context, provider, method, endpoint string, headers map[string]string, payload io.Reader, out interface{}) error {
+	if err := netguard.Check("calling the " + provider + " API"); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, payload)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := hostingClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s API error %d: %s", provider, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type GitHubProvider struct {
+	host   string
+	owner  string
+	repo   string
+	apiURL string
+	token  string
+}
+
+func (p *GitHubProvider) Name() string {
+	return "GitHub"
+}
+
+// CreatePullRequest uses the REST API when a token is configured and falls
+// back to the gh CLI, with its own login, otherwise.
+func (p *GitHubProvider) CreatePullRequest(ctx context.Context, pr PullRequest) (string, error) {
+	if p.token == "" {
+		args := []string{"pr", "create",
+			"--repo", p.host + "/" + p.owner + "/" + p.repo,
+			"--title", pr.Title,
+			"--body", pr.Body,
+			"--head", pr.Head,
+			"--base", pr.Base}
+		for _, label := range pr.Labels {
+			args = append(args, "--label", label)
+		}
+		for _, assignee := range pr.Assignees {
+			args = append(args, "--assignee", assignee)
+		}
+		for _, reviewer := range pr.Reviewers {
+			args = append(args, "--reviewer", reviewer)
+		}
+		if pr.Draft {
+			args = append(args, "--draft")
+		}
+		cmd := exec.CommandContext(ctx, "gh", args...)
+		cmd.Stderr = os.Stderr
+		output, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("gh pr create failed: %w", err)
+		}
+		return strings.TrimSpace(string(output)), nil
+	}
+
+	headers := map[string]string{
+		"Authorization": "Bearer " + p.token,
+		"Accept":        "application/vnd.github+json",
+	}
+
+	var created struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	err := postJSON(ctx, p.Name(), fmt.Sprintf("%s/repos/%s/%s/pulls", p.apiURL, p.owner, p.repo),
+		headers,
+		map[string]interface{}{
+			"title": pr.Title,
+			"body":  pr.Body,
+			"head":  pr.Head,
+			"base":  pr.Base,
+			"draft": pr.Draft,
+		}, &created)
+	if err != nil {
+		return "", err
+	}
+
+	// Labels can only be added once the PR exists, through the issues API
+	if len(pr.Labels) > 0 {
+		var labels []interface{}
+		err := postJSON(ctx, p.Name(), fmt.Sprintf("%s/repos/%s/%s/issues/%d/labels", p.apiURL, p.owner, p.repo, created.Number),
+			headers, map[string][]string{"labels": pr.Labels}, &labels)
+		if err != nil {
+			fmt.Printf("Warning: failed to add labels to PR: %v\n", err)
+		}
+	}
+
+	if len(pr.Assignees) > 0 {
+		var issue interface{}
+		err := postJSON(ctx, p.Name(), fmt.Sprintf("%s/repos/%s/%s/issues/%d/assignees", p.apiURL, p.owner, p.repo, created.Number),
+			headers, map[string][]string{"assignees": pr.Assignees}, &issue)
+		if err != nil {
+			fmt.Printf("Warning: failed to add assignees to PR: %v\n", err)
+		}
+	}
+
+	if len(pr.Reviewers) > 0 {
+		// Teams are requested by slug, without the organization
+		reviewers := map[string][]string{"reviewers": {}, "team_reviewers": {}}
+		for _, reviewer := range pr.Reviewers {
+			if _, team, ok := strings.Cut(reviewer, "/"); ok {
+				reviewers["team_reviewers"] = append(reviewers["team_reviewers"], team)
+			} else {
+				reviewers["reviewers"] = append(reviewers["reviewers"], reviewer)
+			}
+		}
+		var requested interface{}
+		err := postJSON(ctx, p.Name(), fmt.Sprintf("%s/repos/%s/%s/pulls/%d/requested_reviewers", p.apiURL, p.owner, p.repo, created.Number),
+			headers, reviewers, &requested)
+		if err != nil {
+			fmt.Printf("Warning: failed to request reviewers for PR: %v\n", err)
+		}
+	}
+
+	return created.HTMLURL, nil
+}
+
+type GitLabProvider struct {
+	apiURL  string
+	project string // namespace path, e.g. group/subgroup/repo
+	token   string
+}
+
+func (p *GitLabProvider) Name() string {
+	return "GitLab"
+}
+
+func (p *GitLabProvider) CreatePullRequest(ctx context.Context, pr PullRequest) (string, error) {
+	if p.token == "" {
+		return "", fmt.Errorf("gitlab_token not configured (or set GITLAB_TOKEN)")
+	}
+
+	headers := map[string]string{"PRIVATE-TOKEN": p.token}
+
+	// Drafts are marked by the title
+	title := pr.Title
+	if pr.Draft {
+		title = "Draft: " + title
+	}
+
+	var created struct {
+		WebURL string `json:"web_url"`
+	}
+	err := postJSON(ctx, p.Name(), fmt.Sprintf("%s/projects/%s/merge_requests", p.apiURL, url.PathEscape(p.project)),
+		headers,
+		map[string]interface{}{
+			"title":         title,
+			"description":   pr.Body,
+			"source_branch": pr.Head,
+			"target_branch": pr.Base,
+			"labels":        strings.Join(pr.Labels, ","),
+			"assignee_ids":  p.userIDs(ctx, headers, "assignee", pr.Assignees),
+			"reviewer_ids":  p.userIDs(ctx, headers, "reviewer", pr.Reviewers),
+		}, &created)
+	if err != nil {
+		return "", err
+	}
+
+	return created.WebURL, nil
+}
+
+// userIDs looks up the IDs of the GitLab users, which merge requests take
+// instead of usernames. Users that cannot be found are skipped.
+func (p *GitLabProvider) userIDs(ctx context.Context, headers map[string]string, role string, usernames []string) []int {
+	ids := []int{}
+	for _, username := range usernames {
+		var users []struct {
+			ID int `json:"id"`
+		}
+		err := getJSON(ctx, p.Name(), fmt.Sprintf("%s/users?username=%s", p.apiURL, url.QueryEscape(strings.TrimPrefix(username, "@"))), headers, &users)
+		if err != nil || len(users) == 0 {
+			fmt.Printf("Warning: GitLab user %s not found, not adding them as %s\n", username, role)
+			continue
+		}
+		ids = append(ids, users[0].ID)
+	}
+	return ids
+}
+
+// BitbucketProvider targets Bitbucket Cloud.
+type BitbucketProvider struct {
+	workspace string
+	repo      string
+	// token is an access token, or username:app_password for basic auth
+	token string
+}
+
+func (p *BitbucketProvider) Name() string {
+	return "Bitbucket"
+}
+
+func (p *BitbucketProvider) CreatePullRequest(ctx context.Context, pr PullRequest) (string, error) {
+	if p.token == "" {
+		return "", fmt.Errorf("bitbucket_token not configured (or set BITBUCKET_TOKEN)")
+	}
+
+	if len(pr.Labels) > 0 {
+		fmt.Println("Warning: Bitbucket pull requests do not support labels, skipping them")
+	}
+	if len(pr.Assignees) > 0 {
+		fmt.Println("Warning: Bitbucket pull requests do not support assignees, skipping them")
+	}
+
+	// Reviewers are identified by UUID ({...}) or account ID
+	reviewers := []map[string]string{}
+	for _, reviewer := range pr.Reviewers {
+		if strings.HasPrefix(reviewer, "{") {
+			reviewers = append(reviewers, map[string]string{"uuid": reviewer})
+		} else {
+			reviewers = append(reviewers, map[string]string{"account_id": reviewer})
+		}
+	}
+
+	branch := func(name string) map[string]interface{} {
+		return map[string]interface{}{"branch": map[string]string{"name": name}}
+	}
+
+	var created struct {
+		Links struct {
+			HTML struct {
+				Href string `json:"href"`
+			} `json:"html"`
+		} `json:"links"`
+	}
+	err := postJSON(ctx, p.Name(), fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/%s/pullrequests", p.workspace, p.repo),
+		map[string]string{"Authorization": p.auth()},
+		map[string]interface{}{
+			"title":       pr.Title,
+			"description": pr.Body,
+			"source":      branch(pr.Head),
+			"destination": branch(pr.Base),
+			"reviewers":   reviewers,
+			"draft":       pr.Draft,
+		}, &created)
+	if err != nil {
+		return "", err
+	}
+
+	return created.Links.HTML.Href, nil
+}
+
+// auth is the Authorization header for the token.
+func (p *BitbucketProvider) auth() string {
+	if strings.Contains(p.token, ":") {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(p.token))
+	}
+	return "Bearer " + p.token
+}
@@ -0,0 +1,140 @@
+package git
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/udemy/docu-jarvis-cli/internal/netguard"
+)
+
+// IssueInfo is a GitHub issue with its discussion.
+type IssueInfo struct {
+	Number   int
+	URL      string
+	Title    string
+	Body     string
+	Author   string
+	State    string
+	Labels   []string
+	Comments []IssueComment
+}
+
+// IssueComment is a comment on an issue.
+type IssueComment struct {
+	Author string
+	Body   string
+}
+
+// GetIssue looks up an issue given as a number (in this repository's GitHub
+// remote) or a URL, using the gh CLI.
+func (r *Repo) GetIssue(issue string) (*IssueInfo, error) {
+	if r.localPath == "" {
+		return nil, fmt.Errorf("repository not cloned")
+	}
+
+	if err := netguard.Check("looking up the issue"); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("gh", "issue", "view", issue, "--json", "number,url,title,body,author,state,labels,comments")
+	cmd.Dir = r.localPath
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find issue %s: %w", issue, err)
+	}
+
+	type author struct {
+		Login string `json:"login"`
+	}
+	var view struct {
+		Number int    `json:"number"`
+		URL    string `json:"url"`
+		Title  string `json:"title"`
+		Body   string `json:"body"`
+		Author author `json:"author"`
+		State  string `json:"state"`
+		Labels []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+		Comments []struct {
+			Author author `json:"author"`
+			Body   string `json:"body"`
+		} `json:"comments"`
+	}
+	if err := json.Unmarshal(output, &view); err != nil {
+		return nil, fmt.Errorf("unexpected gh output for issue %s: %w", issue, err)
+	}
+
+	info := &IssueInfo{
+		Number: view.Number,
+		URL:    view.URL,
+		Title:  view.Title,
+		Body:   view.Body,
+		Author: view.Author.Login,
+		State:  view.State,
+	}
+	for _, label := range view.Labels {
+		info.Labels = append(info.Labels, label.Name)
+	}
+	for _, c := range view.Comments {
+		info.Comments = append(info.Comments, IssueComment{Author: c.Author.Login, Body: c.Body})
+	}
+	return info, nil
+}
+
+// RepoLabels returns the names of the labels the issue's repository has.
+func (r *Repo) RepoLabels(issue *IssueInfo) ([]string, error) {
+	if err := netguard.Check("listing the labels"); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("gh", "label", "list", "--repo", issueRepo(issue.URL), "--limit", "500", "--json", "name")
+	cmd.Dir = r.localPath
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list labels: %w", err)
+	}
+
+	var labels []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(output, &labels); err != nil {
+		return nil, fmt.Errorf("unexpected gh output for labels: %w", err)
+	}
+	names := make([]string, len(labels))
+	for i, label := range labels {
+		names[i] = label.Name
+	}
+	return names, nil
+}
+
+// PostIssueComment comments on the issue.
+func (r *Repo) PostIssueComment(issue *IssueInfo, body string) error {
+	if err := netguard.Check("commenting on the issue"); err != nil {
+		return err
+	}
+
+	cmd := exec.Command("gh", "issue", "comment", strconv.Itoa(issue.Number), "--repo", issueRepo(issue.URL), "--body-file", "-")
+	cmd.Dir = r.localPath
+	cmd.Stdin = strings.NewReader(body)
+	cmd.Stderr = os.Stderr
+	if _, err := cmd.Output(); err != nil {
+		return fmt.Errorf("failed to comment on issue #%d: %w", issue.Number, err)
+	}
+	return nil
+}
+
+// issueRepo turns https://host/owner/repo/issues/123 into the
+// https://host/owner/repo form gh's --repo takes.
+func issueRepo(issueURL string) string {
+	if i := strings.Index(issueURL, "/issues/"); i >= 0 {
+		return issueURL[:i]
+	}
+	return issueURL
+}
@@ -0,0 +1,159 @@
+package git
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/udemy/docu-jarvis-cli/internal/settings"
+)
+
+// Defaults for the pr_title and pr_body templates.
+const (
+	DefaultPRTitle = "Documentation Update"
+	DefaultPRBody  = "Automated docu-jarvis suggestions\n\n{summary}"
+)
+
+// PROptions overrides the PR settings of the config, e.g. from flags. Empty
+// fields keep the configured value.
+type PROptions struct {
+	Title     string
+	Body      string
+	Base      string
+	Labels    []string
+	Assignees []string
+	Reviewers []string
+	Draft     bool
+	Split     string // SplitDir or SplitCodeowners for one PR per area, "" for one PR
+	// SuggestReviewers requests the recent contributors to the code behind
+	// each doc as reviewers
+	SuggestReviewers bool
+	// DigestComments comments on each changed doc with what was changed and why
+	DigestComments bool
+	// AutoApprove opens the PR with every change, without asking to review
+	// each changed doc first
+	AutoApprove bool
+	// Yes pushes the PR's branch without asking to confirm first
+	Yes bool
+}
+
+// PRRun describes the run whose changes the PR holds, for the templates.
+type PRRun struct {
+	Command   string
+	RunID     string
+	Succeeded int
+	Failed    int
+	Summary   string // markdown list of the run's documents or topics
+	// Digests is what was changed in each doc and why, by path relative to
+	// the repository root
+	Digests map[string]string
+	// Paths are files or folders outside the docs roots, relative to the
+	// repository root, that the PR commits too
+	Paths []string
+	// PathsOnly commits Paths alone, leaving the docs roots out
+	PathsOnly bool
+	// BranchPrefix goes before the name of the PR's branch, e.g. "tests/"
+	BranchPrefix string
+	// CommitMessage replaces the message of the docs commit
+	CommitMessage string
+}
+
+// SetPROptions sets the PR metadata that overrides the config.
+func (r *Repo) SetPROptions(opts PROptions) {
+	r.prOptions = opts
+	if opts.Base != "" {
+		r.prBaseRef = opts.Base
+	}
+}
+
+// GetPROptions returns the options set with SetPROptions.
+func (r *Repo) GetPROptions() PROptions {
+	return r.prOptions
+}
+
+// pullRequest builds the PR from the config, the options set with
+// SetPROptions, and the run.
+func (r *Repo) pullRequest(s *settings.Settings, run PRRun, head, base, remoteURL string) PullRequest {
+	opts := r.prOptions
+
+	title := firstNonEmpty(opts.Title, s.PRTitle, DefaultPRTitle)
+	body := firstNonEmpty(opts.Body, s.PRBody, DefaultPRBody)
+	labels := s.PRLabels
+	if len(opts.Labels) > 0 {
+		labels = opts.Labels
+	}
+	assignees := s.PRAssignees
+	if len(opts.Assignees) > 0 {
+		assignees = opts.Assignees
+	}
+	reviewers := s.PRReviewers
+	if len(opts.Reviewers) > 0 {
+		reviewers = opts.Reviewers
+	}
+
+	repoName := ""
+	if _, repoPath, err := parseRemoteURL(remoteURL); err == nil {
+		repoName = path.Base(repoPath)
+	}
+	vars := map[string]string{
+		"command":   run.Command,
+		"repo":      repoName,
+		"branch":    head,
+		"base":      base,
+		"date":      time.Now().Format("2006-01-02"),
+		"run_id":    run.RunID,
+		"succeeded": fmt.Sprint(run.Succeeded),
+		"failed":    fmt.Sprint(run.Failed),
+		"total":     fmt.Sprint(run.Succeeded + run.Failed),
+		"summary":   run.Summary,
+	}
+
+	title = strings.TrimSpace(ExpandPRTemplate(title, vars))
+	if title == "" {
+		title = DefaultPRTitle
+	}
+
+	var fileComments map[string]string
+	if opts.DigestComments || s.PRDigestComments {
+		fileComments = run.Digests
+	}
+
+	return PullRequest{
+		Title:     title,
+		Body:      strings.TrimSpace(ExpandPRTemplate(body, vars)),
+		Head:      head,
+		Base:      base,
+		Labels:    labels,
+		Assignees: assignees,
+		Reviewers: reviewers,
+		Draft:     opts.Draft || s.PRDraft,
+
+		FileComments: fileComments,
+
+		commitMessage: run.CommitMessage,
+	}
+}
+
+// ExpandPRTemplate replaces the {name} placeholders of a PR title or body
+// template: {command}, {repo}, {branch}, {base}, {date}, {run_id},
+// {succeeded}, {failed}, {total}, and {summary}. Unknown placeholders are
+// left as they are.
+func ExpandPRTemplate(tmpl string, vars map[string]string) string {
+	var pairs []string
+	for name, value := range vars {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	// One-line values, such as flags, can still hold line breaks
+	pairs = append(pairs, `\n`, "\n")
+	return strings.NewReplacer(pairs...).Replace(tmpl)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/udemy/docu-jarvis-cli/internal/ci"
+	"github.com/udemy/docu-jarvis-cli/internal/redact"
+)
+
+// branchMarker starts the name of the branches docu-jarvis pushes, after the
+// run's branch prefix.
+const branchMarker = "docu-jarvis_"
+
+// protectionMessages are what the hosting providers say, in lowercase, when
+// they reject a push because of branch protection or repository rules.
+var protectionMessages = []string{
+	"gh006",                              // GitHub protected branch
+	"gh013",                              // GitHub repository rules
+	"protected branch",                   // GitHub and GitLab
+	"not allowed to push",                // GitLab
+	"permission denied to update branch", // Bitbucket
+	"branch permission",                  // Bitbucket
+	"pre-receive hook declined",
+}
+
+// remoteDefaultBranch returns the default branch of origin, as recorded in
+// refs/remotes/origin/HEAD. Clones made by some tools, and repositories whose
+// default branch was renamed, lack it or have it stale, so it is asked from
+// origin when missing. It returns "" when origin cannot tell.
+func (r *Repo) remoteDefaultBranch() string {
+	symbolicRef := func() string {
+		output, err := r.git("symbolic-ref", "--short", "refs/remotes/origin/HEAD")
+		if err != nil {
+			return ""
+		}
+		return strings.TrimPrefix(output, "origin/")
+	}
+
+	if base := symbolicRef(); base != "" {
+		return base
+	}
+	if _, err := r.git("remote", "set-head", "origin", "--auto"); err != nil {
+		return ""
+	}
+	return symbolicRef()
+}
+
+// ErrPushDeclined is returned by CreatePR when the user declines the push,
+// so callers keep what a pushed PR would settle, such as the doc queue.
+var ErrPushDeclined = errors.New("push declined, nothing was pushed")
+
+// confirmPush asks before anything is pushed to origin, unless the Yes option
+// is set or in CI mode, where nobody can answer.
+func (r *Repo) confirmPush(remoteURL, base string, split bool) bool {
+	what := "a branch"
+	if split {
+		what = "one branch per PR"
+	}
+	fmt.Printf("\nAbout to push %s to %s and open a PR into %s.\n", what, redact.String(remoteURL), base)
+	fmt.Print("Continue? [y/n]: ")
+	if r.prOptions.Yes || ci.Enabled() {
+		if r.prOptions.Yes {
+			fmt.Println("y (-yes)")
+		} else {
+			fmt.Println("y (CI mode)")
+		}
+		return true
+	}
+
+	var answer string
+	fmt.Scanln(&answer)
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// pushBranch pushes branch to origin, showing git's output. A push that
+// branch protection or repository rules rejected fails with an error saying
+// so.
+func (r *Repo) pushBranch(branch string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "push", "origin", branch)
+	cmd.Dir = r.localPath
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+	if err := cmd.Run(); err != nil {
+		output := strings.ToLower(stderr.String())
+		for _, message := range protectionMessages {
+			if strings.Contains(output, message) {
+				prefix := branch
+				if i := strings.Index(branch, branchMarker); i >= 0 {
+					prefix = branch[:i+len(branchMarker)]
+				}
+				return fmt.Errorf("origin rejected branch %s: branch protection or a repository rule forbids the push "+
+					"(see the remote's message above); ask a repository admin to let this account push the branches starting with %s", branch, prefix)
+			}
+		}
+		return fmt.Errorf("failed to push branch: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,352 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/udemy/docu-jarvis-cli/internal/netguard"
+)
+
+// PRInfo identifies a GitHub pull request.
+type PRInfo struct {
+	Number  int
+	URL     string
+	HeadSHA string
+	Host    string
+	Owner   string
+	Repo    string
+}
+
+// ReviewComment is an inline review comment on a line of the PR's new code.
+type ReviewComment struct {
+	Path string
+	Line int
+	Body string
+}
+
+// GetPRInfo looks up a pull request given as a number (in this repository's
+// GitHub remote) or a URL, using the gh CLI.
+func (r *Repo) GetPRInfo(pr string) (*PRInfo, error) {
+	if r.localPath == "" {
+		return nil, fmt.Errorf("repository not cloned")
+	}
+
+	if err := netguard.Check("looking up the PR"); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("gh", "pr", "view", pr, "--json", "number,url,headRefOid")
+	cmd.Dir = r.localPath
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find PR %s: %w", pr, err)
+	}
+
+	var view struct {
+		Number     int    `json:"number"`
+		URL        string `json:"url"`
+		HeadRefOid string `json:"headRefOid"`
+	}
+	if err := json.Unmarshal(output, &view); err != nil {
+		return nil, fmt.Errorf("unexpected gh output for PR %s: %w", pr, err)
+	}
+
+	// https://host/owner/repo/pull/123
+	u, err := url.Parse(view.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PR URL %s: %w", view.URL, err)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 4 || parts[2] != "pull" {
+		return nil, fmt.Errorf("cannot parse PR URL: %s", view.URL)
+	}
+
+	return &PRInfo{
+		Number:  view.Number,
+		URL:     view.URL,
+		HeadSHA: view.HeadRefOid,
+		Host:    u.Host,
+		Owner:   parts[0],
+		Repo:    parts[1],
+	}, nil
+}
+
+// PostPRReview submits a review with a summary body and inline comments. The
+// comments must be on lines that are part of the PR's diff (see DiffLines),
+// or GitHub rejects the whole review.
+func (r *Repo) PostPRReview(pr *PRInfo, body string, comments []ReviewComment) error {
+	if err := netguard.Check("posting the review"); err != nil {
+		return err
+	}
+
+	type apiComment struct {
+		Path string `json:"path"`
+		Line int    `json:"line"`
+		Side string `json:"side"`
+		Body string `json:"body"`
+	}
+	review := struct {
+		CommitID string       `json:"commit_id"`
+		Body     string       `json:"body"`
+		Event    string       `json:"event"`
+		Comments []apiComment `json:"comments"`
+	}{
+		CommitID: pr.HeadSHA,
+		Body:     body,
+		Event:    "COMMENT",
+		Comments: []apiComment{},
+	}
+	for _, c := range comments {
+		review.Comments = append(review.Comments, apiComment{Path: c.Path, Line: c.Line, Side: "RIGHT", Body: c.Body})
+	}
+
+	payload, err := json.Marshal(review)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("gh", "api", "--hostname", pr.Host, "-X", "POST",
+		fmt.Sprintf("repos/%s/%s/pulls/%d/reviews", pr.Owner, pr.Repo, pr.Number),
+		"--input", "-")
+	cmd.Dir = r.localPath
+	cmd.Stdin = strings.NewReader(string(payload))
+	cmd.Stderr = os.Stderr
+	if _, err := cmd.Output(); err != nil {
+		return fmt.Errorf("failed to post review on PR #%d: %w", pr.Number, err)
+	}
+
+	return nil
+}
+
+// DiffLines returns, per file, the new-side line numbers a unified diff shows
+// (added and context lines), which are the lines a review can comment on.
+func DiffLines(diff string) map[string]map[int]bool {
+	return diffLines(diff, true)
+}
+
+// AddedLines returns, per file, the new-side line numbers a unified diff adds.
+func AddedLines(diff string) map[string]map[int]bool {
+	return diffLines(diff, false)
+}
+
+// diffLines walks the hunks by their line counts, so an added line such as
+// "+++ x" inside a hunk is not taken for a file header.
+func diffLines(diff string, withContext bool) map[string]map[int]bool {
+	lines := make(map[string]map[int]bool)
+	var file string
+	var next, oldLeft, newLeft int
+
+	for _, line := range strings.Split(diff, "\n") {
+		if oldLeft > 0 || newLeft > 0 {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				if file != "" && next > 0 {
+					lines[file][next] = true
+				}
+				next++
+				newLeft--
+			case strings.HasPrefix(line, " "):
+				if withContext && file != "" && next > 0 {
+					lines[file][next] = true
+				}
+				next++
+				oldLeft--
+				newLeft--
+			case strings.HasPrefix(line, "-"):
+				oldLeft--
+			case strings.HasPrefix(line, "\\"):
+			default:
+				// A truncated hunk; what follows is headers again
+				oldLeft, newLeft = 0, 0
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			file = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+			if file == "/dev/null" {
+				file = ""
+			} else if lines[file] == nil {
+				lines[file] = make(map[int]bool)
+			}
+		case strings.HasPrefix(line, "@@ "):
+			// @@ -a,b +c,d @@
+			next, oldLeft, newLeft = 0, 0, 0
+			fields := strings.Fields(line)
+			if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+				continue
+			}
+			_, oldLeft = hunkRange(fields[1][1:])
+			next, newLeft = hunkRange(fields[2][1:])
+		}
+	}
+
+	return lines
+}
+
+// hunkRange parses the start,count of a hunk header; a missing count is 1.
+func hunkRange(spec string) (int, int) {
+	startText, countText, hasCount := strings.Cut(spec, ",")
+	start, err := strconv.Atoi(startText)
+	if err != nil {
+		return 0, 0
+	}
+	count := 1
+	if hasCount {
+		if count, err = strconv.Atoi(countText); err != nil {
+			return 0, 0
+		}
+	}
+	return start, count
+}
+
+// PRComment is what a person said on a PR: an inline review comment (with a
+// Path), the body of a review, or a comment on the conversation.
+type PRComment struct {
+	Author string
+	Path   string
+	Line   int
+	Body   string
+}
+
+// GetPRComments returns the comments people left on a pull request, oldest
+// first within each kind. Bots and the comments docu-jarvis posted itself are
+// left out.
+func (r *Repo) GetPRComments(pr *PRInfo) ([]PRComment, error) {
+	if err := netguard.Check("fetching the PR's comments"); err != nil {
+		return nil, err
+	}
+
+	type apiComment struct {
+		User struct {
+			Login string `json:"login"`
+			Type  string `json:"type"`
+		} `json:"user"`
+		Path         string `json:"path"`
+		Line         int    `json:"line"`
+		OriginalLine int    `json:"original_line"`
+		Body         string `json:"body"`
+	}
+
+	var comments []PRComment
+	for _, endpoint := range []string{
+		fmt.Sprintf("repos/%s/%s/pulls/%d/comments", pr.Owner, pr.Repo, pr.Number),
+		fmt.Sprintf("repos/%s/%s/pulls/%d/reviews", pr.Owner, pr.Repo, pr.Number),
+		fmt.Sprintf("repos/%s/%s/issues/%d/comments", pr.Owner, pr.Repo, pr.Number),
+	} {
+		cmd := exec.Command("gh", "api", "--hostname", pr.Host, "--paginate", endpoint)
+		cmd.Dir = r.localPath
+		cmd.Stderr = os.Stderr
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch comments of PR #%d: %w", pr.Number, err)
+		}
+
+		// --paginate prints one JSON array per page
+		decoder := json.NewDecoder(strings.NewReader(string(output)))
+		for decoder.More() {
+			var page []apiComment
+			if err := decoder.Decode(&page); err != nil {
+				return nil, fmt.Errorf("unexpected gh output for PR #%d: %w", pr.Number, err)
+			}
+			for _, c := range page {
+				body := strings.TrimSpace(c.Body)
+				if !byPerson(c.User.Type, body) {
+					continue
+				}
+				line := c.Line
+				if line == 0 {
+					line = c.OriginalLine
+				}
+				comments = append(comments, PRComment{Author: c.User.Login, Path: c.Path, Line: line, Body: body})
+			}
+		}
+	}
+
+	return comments, nil
+}
+
+// byPerson reports whether a comment was written by a person: it is not
+// empty, nor from a bot, nor one docu-jarvis posted.
+func byPerson(userType, body string) bool {
+	return body != "" && userType != "Bot" && !strings.HasPrefix(body, commentMarker) && !strings.Contains(body, "_Generated by docu-jarvis_")
+}
+
+// reviewCommentLister is implemented by the providers that can list the
+// review comments on a repository's PRs.
+type reviewCommentLister interface {
+	recentReviewComments(ctx context.Context, limit int) ([]PRComment, error)
+}
+
+// RecentReviewComments returns up to limit of the latest inline review
+// comments people left on the repository's PRs, newest first. Only GitHub has
+// an API for them.
+func (r *Repo) RecentReviewComments(ctx context.Context, limit int) ([]PRComment, error) {
+	if err := netguard.Check("fetching review comments"); err != nil {
+		return nil, err
+	}
+	provider, _, err := r.hostingProvider()
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := provider.(reviewCommentLister)
+	if !ok {
+		return nil, fmt.Errorf("reading review comments is not supported on %s", provider.Name())
+	}
+	return lister.recentReviewComments(ctx, limit)
+}
+
+func (p *GitHubProvider) recentReviewComments(ctx context.Context, limit int) ([]PRComment, error) {
+	var page []struct {
+		User struct {
+			Login string `json:"login"`
+			Type  string `json:"type"`
+		} `json:"user"`
+		Path string `json:"path"`
+		Line int    `json:"line"`
+		Body string `json:"body"`
+	}
+	// One page of the most recent comments, over-fetched for the ones left out
+	endpoint := fmt.Sprintf("repos/%s/%s/pulls/comments?sort=created&direction=desc&per_page=100", p.owner, p.repo)
+
+	if p.token == "" {
+		cmd := exec.CommandContext(ctx, "gh", "api", "--hostname", p.host, endpoint)
+		cmd.Stderr = os.Stderr
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("gh api failed: %w", err)
+		}
+		if err := json.Unmarshal(output, &page); err != nil {
+			return nil, fmt.Errorf("unexpected gh output for review comments: %w", err)
+		}
+	} else {
+		headers := map[string]string{
+			"Authorization": "Bearer " + p.token,
+			"Accept":        "application/vnd.github+json",
+		}
+		if err := getJSON(ctx, p.Name(), p.apiURL+"/"+endpoint, headers, &page); err != nil {
+			return nil, err
+		}
+	}
+
+	var comments []PRComment
+	for _, c := range page {
+		body := strings.TrimSpace(c.Body)
+		if !byPerson(c.User.Type, body) {
+			continue
+		}
+		comments = append(comments, PRComment{Author: c.User.Login, Path: c.Path, Line: c.Line, Body: body})
+		if len(comments) == limit {
+			break
+		}
+	}
+	return comments, nil
+}
@@ -0,0 +1,281 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// reviewerHistory is how far back contributions count.
+	reviewerHistory = "180.days.ago"
+	// reviewersPerDoc is how many contributors are suggested for each doc.
+	reviewersPerDoc = 2
+	// maxSuggestedReviewers caps the suggestions for one PR.
+	maxSuggestedReviewers = 5
+	// maxSourceAreas caps the code paths taken from one doc.
+	maxSourceAreas = 20
+)
+
+// reviewerResolver is implemented by the providers that can tell which
+// account authored a commit, as reviewers are requested by account.
+type reviewerResolver interface {
+	commitAuthor(ctx context.Context, commit, email string) (string, error)
+	// currentUser is the account the PR is opened as, which cannot review it.
+	currentUser(ctx context.Context) (string, error)
+}
+
+// contributor is an author in the history of a doc's source areas.
+type contributor struct {
+	email   string
+	commits int
+	latest  string // their most recent commit, to look the account up by
+}
+
+var (
+	backtickPath = regexp.MustCompile("`([A-Za-z0-9_.@-]+(?:/[A-Za-z0-9_.@-]+)+/?)`")
+	markdownLink = regexp.MustCompile(`\]\(([^)#\s]+)`)
+)
+
+// sourceAreas returns the code paths a doc refers to, in backticks or links,
+// that exist in the repository outside the docs roots.
+func (r *Repo) sourceAreas(doc string) []string {
+	content, err := os.ReadFile(filepath.Join(r.localPath, doc))
+	if err != nil {
+		return nil
+	}
+
+	var candidates []string
+	for _, m := range backtickPath.FindAllStringSubmatch(string(content), -1) {
+		candidates = append(candidates, m[1], path.Join(r.scope, m[1]))
+	}
+	for _, m := range markdownLink.FindAllStringSubmatch(string(content), -1) {
+		if !strings.Contains(m[1], "://") && !strings.HasPrefix(m[1], "mailto:") {
+			candidates = append(candidates, path.Join(path.Dir(doc), m[1]))
+		}
+	}
+
+	seen := make(map[string]bool)
+	var areas []string
+	for _, candidate := range candidates {
+		area := path.Clean(strings.TrimPrefix(candidate, "/"))
+		if seen[area] || strings.HasPrefix(area, "..") || r.inDocsRoot(area) {
+			continue
+		}
+		seen[area] = true
+		if _, err := os.Stat(filepath.Join(r.localPath, area)); err == nil {
+			areas = append(areas, area)
+		}
+		if len(areas) == maxSourceAreas {
+			break
+		}
+	}
+	return areas
+}
+
+func (r *Repo) inDocsRoot(path string) bool {
+	for _, root := range r.GetDocsRoots() {
+		if path == root || strings.HasPrefix(path, root+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// contributors returns the recent authors of the paths, most commits first.
+func (r *Repo) contributors(paths []string, exclude map[string]bool) ([]contributor, error) {
+	args := append([]string{"log", "--no-merges", "--since=" + reviewerHistory, "--format=%H %ae", r.historyRef(), "--"}, paths...)
+	out, err := r.git(args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	byEmail := make(map[string]*contributor)
+	var order []*contributor
+	for _, line := range strings.Split(out, "\n") {
+		hash, email, ok := strings.Cut(line, " ")
+		email = strings.ToLower(email)
+		if !ok || exclude[email] {
+			continue
+		}
+		c := byEmail[email]
+		if c == nil {
+			// Commits are newest first, so the first one is the latest
+			c = &contributor{email: email, latest: hash}
+			byEmail[email] = c
+			order = append(order, c)
+		}
+		c.commits++
+	}
+
+	sort.SliceStable(order, func(i, j int) bool { return order[i].commits > order[j].commits })
+	contributors := make([]contributor, len(order))
+	for i, c := range order {
+		contributors[i] = *c
+	}
+	return contributors, nil
+}
+
+// suggestReviewers returns the accounts of the top recent contributors to
+// the code each doc describes (or to the doc itself, when it refers to no
+// code), without the account opening the PR, the user's git email, and bots.
+func (r *Repo) suggestReviewers(ctx context.Context, provider HostingProvider, docs []string) []string {
+	resolver, ok := provider.(reviewerResolver)
+	if !ok {
+		fmt.Printf("Warning: reviewer suggestions are not supported on %s, skipping them\n", provider.Name())
+		return nil
+	}
+
+	exclude := map[string]bool{"docu-jarvis@automation.local": true}
+	if email, err := r.git("config", "user.email"); err == nil && email != "" {
+		exclude[strings.ToLower(email)] = true
+	}
+	author, err := resolver.currentUser(ctx)
+	if err != nil {
+		fmt.Printf("Warning: could not look up the account opening the PR: %v\n", err)
+	}
+
+	accounts := make(map[string]string) // email -> account, "" when unknown
+	var reviewers []string
+	for _, doc := range docs {
+		areas := r.sourceAreas(doc)
+		if len(areas) == 0 {
+			areas = []string{doc}
+		}
+
+		contributors, err := r.contributors(areas, exclude)
+		if err != nil {
+			fmt.Printf("Warning: could not suggest reviewers for %s: %v\n", doc, err)
+			continue
+		}
+
+		suggested := 0
+		for _, c := range contributors {
+			if suggested == reviewersPerDoc || len(reviewers) == maxSuggestedReviewers {
+				break
+			}
+			account, known := accounts[c.email]
+			if !known {
+				account, err = resolver.commitAuthor(ctx, c.latest, c.email)
+				if err != nil || strings.HasSuffix(account, "[bot]") || strings.EqualFold(account, author) {
+					account = ""
+				}
+				accounts[c.email] = account
+			}
+			if account == "" {
+				continue
+			}
+			suggested++
+			if !containsFold(reviewers, account) {
+				reviewers = append(reviewers, account)
+				fmt.Printf("  Suggested reviewer: %s (%d recent commits to the code behind %s)\n", account, c.commits, doc)
+			}
+		}
+	}
+	return reviewers
+}
+
+// addSuggestedReviewers adds the reviewers suggested for the docs to the PR,
+// when pr_suggest_reviewers or -suggest-reviewers asks for them.
+func (r *Repo) addSuggestedReviewers(pr *PullRequest, provider HostingProvider, suggest bool, docs []string) {
+	if !suggest && !r.prOptions.SuggestReviewers {
+		return
+	}
+	fmt.Println("Suggesting reviewers from recent git history...")
+	pr.Reviewers = appendMissing(pr.Reviewers, r.suggestReviewers(context.Background(), provider, docs)...)
+}
+
+// commitAuthor looks up the GitHub account GitHub linked to the commit.
+func (p *GitHubProvider) commitAuthor(ctx context.Context, commit, email string) (string, error) {
+	endpoint := fmt.Sprintf("repos/%s/%s/commits/%s", p.owner, p.repo, commit)
+
+	if p.token == "" {
+		cmd := exec.CommandContext(ctx, "gh", "api", "--hostname", p.host, endpoint, "--jq", ".author.login // empty")
+		output, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("gh api failed: %w", err)
+		}
+		return strings.TrimSpace(string(output)), nil
+	}
+
+	var found struct {
+		Author *struct {
+			Login string `json:"login"`
+		} `json:"author"`
+	}
+	headers := map[string]string{
+		"Authorization": "Bearer " + p.token,
+		"Accept":        "application/vnd.github+json",
+	}
+	if err := getJSON(ctx, p.Name(), p.apiURL+"/"+endpoint, headers, &found); err != nil {
+		return "", err
+	}
+	if found.Author == nil {
+		return "", nil
+	}
+	return found.Author.Login, nil
+}
+
+// currentUser returns the login of the token's user, or of the gh CLI's.
+func (p *GitHubProvider) currentUser(ctx context.Context) (string, error) {
+	if p.token == "" {
+		cmd := exec.CommandContext(ctx, "gh", "api", "--hostname", p.host, "user", "--jq", ".login")
+		output, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("gh api failed: %w", err)
+		}
+		return strings.TrimSpace(string(output)), nil
+	}
+
+	var user struct {
+		Login string `json:"login"`
+	}
+	headers := map[string]string{
+		"Authorization": "Bearer " + p.token,
+		"Accept":        "application/vnd.github+json",
+	}
+	if err := getJSON(ctx, p.Name(), p.apiURL+"/user", headers, &user); err != nil {
+		return "", err
+	}
+	return user.Login, nil
+}
+
+// commitAuthor finds the GitLab user with the author's email, which only
+// works for public emails (or for administrators).
+func (p *GitLabProvider) commitAuthor(ctx context.Context, commit, email string) (string, error) {
+	if p.token == "" {
+		return "", fmt.Errorf("gitlab_token not configured (or set GITLAB_TOKEN)")
+	}
+
+	var users []struct {
+		Username string `json:"username"`
+	}
+	err := getJSON(ctx, p.Name(), fmt.Sprintf("%s/users?search=%s", p.apiURL, url.QueryEscape(email)),
+		map[string]string{"PRIVATE-TOKEN": p.token}, &users)
+	if err != nil || len(users) != 1 {
+		return "", err
+	}
+	return users[0].Username, nil
+}
+
+// currentUser returns the username of the token's user.
+func (p *GitLabProvider) currentUser(ctx context.Context) (string, error) {
+	if p.token == "" {
+		return "", fmt.Errorf("gitlab_token not configured (or set GITLAB_TOKEN)")
+	}
+
+	var user struct {
+		Username string `json:"username"`
+	}
+	if err := getJSON(ctx, p.Name(), p.apiURL+"/user", map[string]string{"PRIVATE-TOKEN": p.token}, &user); err != nil {
+		return "", err
+	}
+	return user.Username, nil
+}
@@ -0,0 +1,166 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/udemy/docu-jarvis-cli/internal/netguard"
+)
+
+// maxRangeCommits caps the commits of a range, as all of their diffs go into
+// the conversation.
+const maxRangeCommits = 50
+
+var lastCommitsPattern = regexp.MustCompile(`^HEAD~\d+$`)
+
+// ResolveCommits returns the full hashes of the commits a revision names,
+// oldest first: one commit, a range (A..B or A...B), HEAD~N for the last N
+// commits, or a branch for its commits that are not on the selected branch.
+// Commits outside the scope are left out of ranges.
+func (r *Repo) ResolveCommits(rev string) ([]string, error) {
+	if r.localPath == "" {
+		return nil, fmt.Errorf("repository not cloned")
+	}
+
+	if lastCommitsPattern.MatchString(rev) || strings.Contains(rev, "..") {
+		revRange, err := r.rangeEnds(rev)
+		if err != nil {
+			return nil, err
+		}
+		commits, err := r.rangeCommits(revRange, rev)
+		if err == nil && len(commits) == 0 {
+			return nil, fmt.Errorf("no commits in %s", rev)
+		}
+		return commits, err
+	}
+
+	if branch := r.branchRef(rev); branch != "" {
+		return r.branchCommits(rev, branch)
+	}
+	full, err := r.EnsureCommit(rev)
+	if err != nil {
+		// Fetching the missing commit also fetches new branches
+		if branch := r.branchRef(rev); branch != "" {
+			return r.branchCommits(rev, branch)
+		}
+		return nil, err
+	}
+	return []string{full}, nil
+}
+
+// rangeEnds makes sure both ends of a range are available, naming branches
+// that only exist on origin by their full ref.
+func (r *Repo) rangeEnds(rev string) (string, error) {
+	if lastCommitsPattern.MatchString(rev) {
+		return rev + "..HEAD", nil
+	}
+
+	sep := ".."
+	if strings.Contains(rev, "...") {
+		sep = "..."
+	}
+	from, to, _ := strings.Cut(rev, sep)
+	ends := []string{from, to}
+	for i, end := range ends {
+		if end == "" {
+			continue
+		}
+		if branch := r.branchRef(end); branch != "" {
+			ends[i] = branch
+		} else if _, err := r.EnsureCommit(end); err != nil {
+			return "", err
+		}
+	}
+	return ends[0] + sep + ends[1], nil
+}
+
+// branchRef returns the local or origin ref of a branch, or "" when there is
+// no such branch.
+func (r *Repo) branchRef(name string) string {
+	for _, ref := range []string{"refs/heads/" + name, "refs/remotes/origin/" + name} {
+		cmd := exec.Command("git", "show-ref", "--verify", "--quiet", ref)
+		cmd.Dir = r.localPath
+		if cmd.Run() == nil {
+			return ref
+		}
+	}
+	return ""
+}
+
+func (r *Repo) branchCommits(name, ref string) ([]string, error) {
+	commits, err := r.rangeCommits(r.historyRef()+".."+ref, name)
+	if err == nil && len(commits) == 0 {
+		base := r.branch
+		if base == "" {
+			base, _ = r.GetCurrentBranch()
+		}
+		return nil, fmt.Errorf("branch %s has no commits that are not on %s; give a range instead (e.g., %s~3..%s)", name, base, name, name)
+	}
+	return commits, err
+}
+
+// rangeCommits lists the commits of a range, fetching the full history of a
+// shallow clone first so the range is complete.
+func (r *Repo) rangeCommits(revRange, rev string) ([]string, error) {
+	if err := r.unshallow(rev); err != nil {
+		return nil, err
+	}
+
+	args := append([]string{"rev-list", "--reverse", revRange}, r.pathspec()...)
+	output, err := r.git(args...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid commit range %s: %w", rev, err)
+	}
+	if output == "" {
+		return nil, nil
+	}
+
+	commits := strings.Split(output, "\n")
+	if len(commits) > maxRangeCommits {
+		return nil, fmt.Errorf("%s has %d commits; explain takes at most %d, so narrow the range", rev, len(commits), maxRangeCommits)
+	}
+	return commits, nil
+}
+
+// unshallow fetches the full history of a shallow clone, so that a range
+// between two commits is complete.
+func (r *Repo) unshallow(rev string) error {
+	if !r.isShallow() {
+		return nil
+	}
+	if err := netguard.Check("fetching the history of a shallow clone for a commit range"); err != nil {
+		return err
+	}
+	fmt.Println("Shallow clone, fetching the full history for the range...")
+	if _, err := r.git("fetch", "--quiet", "--unshallow", "origin"); err != nil {
+		return fmt.Errorf("failed to fetch history for %s: %w", rev, err)
+	}
+	return nil
+}
+
+// CommitFiles returns the files a commit changed.
+func (r *Repo) CommitFiles(hash string) ([]string, error) {
+	output, err := r.git("diff-tree", "--no-commit-id", "--name-only", "-r", "--root", hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the files of commit %s: %w", hash, err)
+	}
+	if output == "" {
+		return nil, nil
+	}
+	return strings.Split(output, "\n"), nil
+}
+
+// GetCommitsDiff returns the diffs of the commits, in the order given.
+func (r *Repo) GetCommitsDiff(hashes []string) (string, error) {
+	var diffs []string
+	for _, hash := range hashes {
+		diff, err := r.GetCommitDiff(hash)
+		if err != nil {
+			return "", err
+		}
+		diffs = append(diffs, diff)
+	}
+	return strings.Join(diffs, "\n"), nil
+}
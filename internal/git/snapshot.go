@@ -0,0 +1,184 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// HeadCommit returns the hash of the commit checked out.
+func (r *Repo) HeadCommit() (string, error) {
+	hash, err := r.git("rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	return hash, nil
+}
+
+// DocsChanges returns the changes of the docs roots from base to the working
+// tree, new files included, as a binary patch. The index is left as it is: a
+// temporary one is used to pick up the new files.
+func (r *Repo) DocsChanges(base string) (string, error) {
+	pathspec := r.docsPathspec()
+	if len(pathspec) == 1 {
+		return "", nil
+	}
+
+	index, err := os.CreateTemp("", "docu-jarvis-index-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary index: %w", err)
+	}
+	index.Close()
+	defer os.Remove(index.Name())
+
+	steps := [][]string{
+		{"read-tree", base},
+		append([]string{"add", "-A"}, pathspec...),
+		append([]string{"diff", "--cached", "--binary", "--no-renames", base}, pathspec...),
+	}
+	var patch []byte
+	for _, args := range steps {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = r.localPath
+		cmd.Env = append(os.Environ(), "GIT_INDEX_FILE="+index.Name())
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if patch, err = cmd.Output(); err != nil {
+			return "", fmt.Errorf("failed to diff documentation (git %s): %s", args[0], strings.TrimSpace(stderr.String()))
+		}
+	}
+	return string(patch), nil
+}
+
+// RevertChanges undoes a patch from DocsChanges in the working tree and
+// returns the files it touches. Where the docs were edited since, the patch is
+// merged with the edits; when they conflict, the files are restored and it
+// fails. With dryRun the files are restored either way.
+func (r *Repo) RevertChanges(patch string, dryRun bool) ([]string, error) {
+	file, err := os.CreateTemp("", "docu-jarvis-*.patch")
+	if err != nil {
+		return nil, fmt.Errorf("failed to write patch: %w", err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(patch); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write patch: %w", err)
+	}
+	file.Close()
+
+	apply := func(args ...string) (string, error) {
+		cmd := exec.Command("git", append(append([]string{"apply", "-R"}, args...), file.Name())...)
+		cmd.Dir = r.localPath
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("%s", strings.TrimSpace(stderr.String()))
+		}
+		return string(out), nil
+	}
+
+	numstat, err := apply("--numstat")
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+	var files []string
+	for _, line := range strings.Split(strings.TrimSpace(numstat), "\n") {
+		if fields := strings.SplitN(line, "\t", 3); len(fields) == 3 {
+			files = append(files, fields[2])
+		}
+	}
+
+	if _, err := apply("--3way"); err != nil {
+		r.restore(files)
+		return nil, fmt.Errorf("the changes conflict with later edits to the docs: %w", err)
+	}
+	if dryRun {
+		r.restore(files)
+	}
+	return files, nil
+}
+
+// restore puts files back to their state at HEAD, in the index and the
+// working tree.
+func (r *Repo) restore(files []string) {
+	for _, file := range files {
+		if _, err := r.git("cat-file", "-e", "HEAD:"+file); err == nil {
+			r.git("checkout", "HEAD", "--", file)
+			continue
+		}
+		r.git("rm", "-q", "--cached", "--ignore-unmatch", "--", file)
+		os.Remove(filepath.Join(r.localPath, file))
+	}
+}
+
+// PendingDocs returns the changed, added, and deleted files in the docs
+// roots, relative to the repository root.
+func (r *Repo) PendingDocs() ([]string, error) {
+	pathspec := r.docsPathspec()
+	if len(pathspec) == 1 {
+		return nil, nil
+	}
+	return r.changedDocs(pathspec)
+}
+
+// DocDiff returns the diff of a file from PendingDocs against HEAD, or the
+// whole file as added when it is new.
+func (r *Repo) DocDiff(file string) (string, error) {
+	args := []string{"diff", "HEAD", "--", file}
+	if _, err := r.git("cat-file", "-e", "HEAD:"+file); err != nil {
+		// git takes /dev/null as the empty file on every platform
+		args = []string{"diff", "--no-index", "--", "/dev/null", file}
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.localPath
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	// --no-index exits with 1 when the files differ
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 && stderr.Len() == 0 {
+		err = nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to diff %s: %s", file, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// HeadVersion returns the content of file, relative to the repository root,
+// at HEAD. It returns "" when the file is new.
+func (r *Repo) HeadVersion(file string) (string, error) {
+	if _, err := r.git("cat-file", "-e", "HEAD:"+file); err != nil {
+		return "", nil
+	}
+	content, err := r.git("show", "HEAD:"+file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s at HEAD: %w", file, err)
+	}
+	return content, nil
+}
+
+// DiffFiles returns the diff of two files anywhere on disk, "" when they are
+// the same.
+func DiffFiles(from, to string) (string, error) {
+	cmd := exec.Command("git", "diff", "--no-index", "--", from, to)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	// --no-index exits with 1 when the files differ
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 && stderr.Len() == 0 {
+		err = nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to diff %s and %s: %s", from, to, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// DiscardDocs undoes the changes of files from PendingDocs.
+func (r *Repo) DiscardDocs(files []string) {
+	r.restore(files)
+}
@@ -0,0 +1,383 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/udemy/docu-jarvis-cli/internal/settings"
+)
+
+// Ways of splitting the documentation changes into several PRs.
+const (
+	SplitDir        = "dir"        // one PR per docs subdirectory
+	SplitCodeowners = "codeowners" // one PR per set of CODEOWNERS owners
+)
+
+// codeownersFiles are where GitHub and GitLab look for CODEOWNERS, in order.
+var codeownersFiles = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// ParseSplit validates a -split-prs or pr_split value; "" keeps a single PR.
+func ParseSplit(split string) (string, error) {
+	switch split {
+	case "", SplitDir, SplitCodeowners:
+		return split, nil
+	}
+	return "", fmt.Errorf("invalid split %q (must be %s or %s)", split, SplitDir, SplitCodeowners)
+}
+
+// prGroup is the part of the changes that goes into one PR.
+type prGroup struct {
+	name   string   // docs directory, or the owners
+	files  []string // relative to the repository root
+	owners []string // reviewers, from CODEOWNERS
+}
+
+// changedDocs returns the changed, added, and deleted files in the docs
+// roots, relative to the repository root.
+func (r *Repo) changedDocs(pathspec []string) ([]string, error) {
+	// Not r.git: the status of the first entry starts with a space
+	cmd := exec.Command("git", append([]string{"status", "--porcelain", "-z", "--untracked-files=all"}, pathspec...)...)
+	cmd.Dir = r.localPath
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changed docs: %w", err)
+	}
+
+	var files []string
+	entries := strings.Split(string(out), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		files = append(files, entry[3:])
+		// A rename is followed by its original path, which must go with it
+		if entry[0] == 'R' || entry[0] == 'C' {
+			if i+1 < len(entries) && entries[i+1] != "" {
+				files = append(files, entries[i+1])
+			}
+			i++
+		}
+	}
+	return files, nil
+}
+
+// groupByDir puts the files of each first-level directory of a docs root in
+// one group, and the files directly in the root in another.
+func groupByDir(files, roots []string) []prGroup {
+	byName := make(map[string]*prGroup)
+	var names []string
+	for _, file := range files {
+		name := filepath.ToSlash(filepath.Dir(file))
+		for _, root := range roots {
+			root = filepath.ToSlash(root)
+			if rest, ok := strings.CutPrefix(file, root+"/"); ok {
+				name = root
+				if dir, _, nested := strings.Cut(rest, "/"); nested {
+					name = root + "/" + dir
+				}
+				break
+			}
+		}
+		if byName[name] == nil {
+			byName[name] = &prGroup{name: name}
+			names = append(names, name)
+		}
+		byName[name].files = append(byName[name].files, file)
+	}
+	return sortedGroups(byName, names)
+}
+
+// groupByOwners puts the files with the same CODEOWNERS owners in one group.
+func groupByOwners(files []string, rules []codeownersRule) []prGroup {
+	byName := make(map[string]*prGroup)
+	var names []string
+	for _, file := range files {
+		owners := ownersOf(file, rules)
+		name := strings.Join(owners, " ")
+		if name == "" {
+			name = "unowned"
+		}
+		if byName[name] == nil {
+			byName[name] = &prGroup{name: name, owners: owners}
+			names = append(names, name)
+		}
+		byName[name].files = append(byName[name].files, file)
+	}
+	return sortedGroups(byName, names)
+}
+
+func sortedGroups(byName map[string]*prGroup, names []string) []prGroup {
+	sort.Strings(names)
+	groups := make([]prGroup, 0, len(names))
+	for _, name := range names {
+		groups = append(groups, *byName[name])
+	}
+	return groups
+}
+
+type codeownersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// loadCodeowners reads the repository's CODEOWNERS file. GitLab sections and
+// their default owners are not supported; section headers are skipped.
+func (r *Repo) loadCodeowners() ([]codeownersRule, string, error) {
+	for _, name := range codeownersFiles {
+		file, err := os.Open(filepath.Join(r.localPath, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		defer file.Close()
+
+		var rules []codeownersRule
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+				continue
+			}
+			if i := strings.Index(line, " #"); i >= 0 {
+				line = line[:i]
+			}
+			fields := strings.Fields(line)
+			rules = append(rules, codeownersRule{pattern: codeownersPattern(fields[0]), owners: fields[1:]})
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, "", fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		return rules, name, nil
+	}
+	return nil, "", fmt.Errorf("no CODEOWNERS file found (looked in %s)", strings.Join(codeownersFiles, ", "))
+}
+
+// codeownersPattern turns a CODEOWNERS (gitignore-style) pattern into a regexp
+// matching the paths it covers, including everything under a directory. As
+// in CODEOWNERS, * does not cross a slash, so docs/* covers docs/a.md but not
+// docs/guides/b.md; docs/** covers both.
+func codeownersPattern(pattern string) *regexp.Regexp {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	trimmed := strings.Trim(pattern, "/")
+	// A slash anywhere but at the end anchors the pattern to the root
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+
+	var re strings.Builder
+	re.WriteString("^")
+	if !anchored {
+		re.WriteString("(.*/)?")
+	}
+	for i := 0; i < len(trimmed); i++ {
+		switch c := trimmed[i]; {
+		case strings.HasPrefix(trimmed[i:], "**/"):
+			re.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(trimmed[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	last := trimmed[strings.LastIndex(trimmed, "/")+1:]
+	switch {
+	case dirOnly:
+		re.WriteString("/.*$")
+	case strings.Contains(last, "*") && last != "**":
+		// A wildcard in the last part matches files, not the trees below
+		re.WriteString("$")
+	default:
+		re.WriteString("(/.*)?$")
+	}
+	return regexp.MustCompile(re.String())
+}
+
+// ownersOf returns the owners of the last rule matching the file, as
+// CODEOWNERS gives later rules precedence.
+func ownersOf(file string, rules []codeownersRule) []string {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].pattern.MatchString(file) {
+			return rules[i].owners
+		}
+	}
+	return nil
+}
+
+// reviewersFromOwners turns CODEOWNERS owners into reviewers: @user and
+// @org/team lose the @, and email addresses are dropped.
+func reviewersFromOwners(owners []string) []string {
+	var reviewers []string
+	for _, owner := range owners {
+		if !strings.HasPrefix(owner, "@") {
+			continue
+		}
+		reviewers = append(reviewers, strings.TrimPrefix(owner, "@"))
+	}
+	return reviewers
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// branchSlug turns a group name into a branch name suffix.
+func branchSlug(name string) string {
+	slug := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(slug) > 40 {
+		slug = strings.TrimRight(slug[:40], "-")
+	}
+	if slug == "" {
+		slug = "docs"
+	}
+	return slug
+}
+
+// createSplitPRs opens one PR per group of changed docs, each on its own
+// branch from the current commit, and carries on past groups that fail.
+func (r *Repo) createSplitPRs(provider HostingProvider, s *settings.Settings, run PRRun, split, branchName, base, remoteURL string, pathspec []string) error {
+	files, err := r.changedDocs(pathspec)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Println("No changes to commit in documentation directory")
+		return nil
+	}
+
+	var groups []prGroup
+	by := "docs directory"
+	switch split {
+	case SplitDir:
+		groups = groupByDir(files, r.GetDocsRoots())
+	case SplitCodeowners:
+		rules, name, err := r.loadCodeowners()
+		if err != nil {
+			return err
+		}
+		groups = groupByOwners(files, rules)
+		by = name
+	}
+
+	start, err := r.git("rev-parse", "HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	fmt.Printf("Splitting %d changed docs into %d PRs by %s\n", len(files), len(groups), by)
+
+	var failed []string
+	for i, group := range groups {
+		fmt.Printf("\n[%d/%d] %s (%d files)\n", i+1, len(groups), group.name, len(group.files))
+
+		branch := branchName + "_" + branchSlug(group.name)
+		pr := r.pullRequest(s, run, branch, base, remoteURL)
+		pr.Title = fmt.Sprintf("%s (%s)", pr.Title, group.name)
+		pr.Body += fmt.Sprintf("\n\n---\nPart %d of %d, split by %s. This PR holds:\n", i+1, len(groups), by)
+		for _, file := range group.files {
+			pr.Body += fmt.Sprintf("- `%s`\n", file)
+		}
+		pr.Reviewers = appendMissing(pr.Reviewers, reviewersFromOwners(group.owners)...)
+		r.addSuggestedReviewers(&pr, provider, s.PRSuggestReviewers, group.files)
+
+		// Nothing staged for another group may end up in this one
+		if err := runCommand("git", "reset", "-q"); err != nil {
+			return fmt.Errorf("failed to reset the index: %w", err)
+		}
+		if err := runCommand("git", "checkout", "-q", "-b", branch, start); err != nil {
+			fmt.Printf("OH NO!!!!  Failed to create branch %s: %v\n", branch, err)
+			failed = append(failed, group.name)
+			continue
+		}
+
+		if _, err := r.commitAndOpenPR(provider, pr, append([]string{"-A", "--"}, group.files...)); err != nil {
+			fmt.Printf("OH NO!!!!  %s: %v\n", group.name, err)
+			failed = append(failed, group.name)
+		}
+	}
+
+	fmt.Printf("\nPRs created: %d/%d\n", len(groups)-len(failed), len(groups))
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to create the PRs for: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// commitAndOpenPR commits what addArgs adds on the current branch, pushes it
+// as pr.Head, and opens the PR. It reports false when there was nothing to
+// commit.
+func (r *Repo) commitAndOpenPR(provider HostingProvider, pr PullRequest, addArgs []string) (bool, error) {
+	if err := runCommand("git", append([]string{"add"}, addArgs...)...); err != nil {
+		return false, fmt.Errorf("failed to add documentation: %w", err)
+	}
+	if err := r.checkCommit(addArgs); err != nil {
+		return false, err
+	}
+
+	if _, err := r.git("diff", "--cached", "--quiet"); err == nil {
+		fmt.Println("No changes to commit in documentation directory")
+		return false, nil
+	}
+
+	commitMessage := "docs: automated documentation improvements by docu-jarvis"
+	if pr.commitMessage != "" {
+		commitMessage = pr.commitMessage
+	}
+	if err := runCommand("git", "commit", "-m", commitMessage); err != nil {
+		return false, fmt.Errorf("failed to commit changes: %w", err)
+	}
+
+	fmt.Printf("Pushing branch: %s\n", pr.Head)
+	if err := r.pushBranch(pr.Head); err != nil {
+		return false, err
+	}
+
+	prURL, err := provider.CreatePullRequest(context.Background(), pr)
+	if err != nil {
+		return false, fmt.Errorf("failed to create PR: %w", err)
+	}
+
+	kind := "PR"
+	if pr.Draft {
+		kind = "draft PR"
+	}
+	fmt.Printf("Successfully created %s with branch: %s (base: %s)\n", kind, pr.Head, pr.Base)
+	if prURL != "" {
+		fmt.Printf("PR: %s\n", prURL)
+	}
+	if len(pr.FileComments) > 0 {
+		r.postFileComments(provider, prURL, pr.FileComments)
+	}
+	return true, nil
+}
+
+// appendMissing returns a copy of list with the values it does not hold yet.
+func appendMissing(list []string, values ...string) []string {
+	list = append([]string(nil), list...)
+	for _, value := range values {
+		if !containsFold(list, value) {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+func containsFold(list []string, value string) bool {
+	for _, existing := range list {
+		if strings.EqualFold(existing, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// WorktreesDir is the directory in a clone's git directory that holds the
+// worktrees of its workspace.
+const WorktreesDir = "docu-jarvis-worktrees"
+
+// Workspace checks out branches of one repository side by side as git
+// worktrees of a single clone, so runs on other branches neither need a full
+// clone per branch nor switch the cached clone away from its default branch.
+// The worktrees share the clone's objects. A worktree left by an earlier run
+// is replaced when its branch is checked out again.
+type Workspace struct {
+	base  *Repo
+	dir   string
+	mu    sync.Mutex
+	trees map[string]*Repo
+}
+
+// NewWorkspace creates a workspace on top of a cloned or local repository.
+// Worktrees are kept inside its git directory, never in the working tree.
+func NewWorkspace(base *Repo) (*Workspace, error) {
+	if base.localPath == "" {
+		return nil, fmt.Errorf("repository not cloned")
+	}
+
+	cmd := exec.Command("git", "rev-parse", "--path-format=absolute", "--git-common-dir")
+	cmd.Dir = base.localPath
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find git directory: %w", err)
+	}
+
+	dir := filepath.Join(strings.TrimSpace(string(output)), WorktreesDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create worktree directory: %w", err)
+	}
+
+	return &Workspace{
+		base:  base,
+		dir:   dir,
+		trees: make(map[string]*Repo),
+	}, nil
+}
+
+// Checkout returns a Repo for the branch, adding a worktree for it on first
+// use. The worktree has a detached HEAD at origin/<branch> (or the local
+// branch for local checkouts), so the same branch can be checked out in the
+// base repository too. Missing branches are fetched, which also covers
+// shallow and single-branch clones.
+func (w *Workspace) Checkout(branch string) (*Repo, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if tree, ok := w.trees[branch]; ok {
+		return tree, nil
+	}
+
+	ref, err := w.resolveBranch(branch)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(w.dir, strings.NewReplacer("/", "-", "\\", "-").Replace(branch))
+
+	// A previous run may have left the worktree behind
+	w.git("worktree", "remove", "--force", path)
+	os.RemoveAll(path)
+	w.git("worktree", "prune")
+
+	if _, err := w.git("worktree", "add", "--detach", "--no-checkout", path, ref); err != nil {
+		return nil, fmt.Errorf("failed to add worktree for %s: %w", branch, err)
+	}
+
+	tree := &Repo{
+		url:       w.base.url,
+		localPath: path,
+		scope:     w.base.scope,
+		branch:    branch,
+		docsRoots: w.base.docsRoots,
+		cloneOpts: w.base.cloneOpts,
+	}
+
+	if tree.scope != "" {
+		if _, err := tree.git("sparse-checkout", "set", tree.scope); err != nil {
+			return nil, fmt.Errorf("failed to set sparse checkout for %s: %w", branch, err)
+		}
+	}
+	if _, err := tree.git("reset", "--hard", "--quiet", ref); err != nil {
+		return nil, fmt.Errorf("failed to check out %s: %w", branch, err)
+	}
+
+	w.trees[branch] = tree
+	return tree, nil
+}
+
+func (w *Workspace) resolveBranch(branch string) (string, error) {
+	candidates := []string{"origin/" + branch}
+	if w.base.local {
+		candidates = []string{branch, "origin/" + branch}
+	}
+
+	for _, ref := range candidates {
+		if _, err := w.git("rev-parse", "--verify", "--quiet", ref+"^{commit}"); err == nil {
+			return ref, nil
+		}
+	}
+
+	fetchArgs := []string{"fetch", "origin", fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", branch, branch)}
+	if w.base.cloneOpts.Depth > 0 {
+		fetchArgs = append(fetchArgs, "--depth", fmt.Sprintf("%d", w.base.cloneOpts.Depth))
+	}
+	if _, err := w.git(fetchArgs...); err != nil {
+		return "", fmt.Errorf("branch %s not found on origin", branch)
+	}
+	return "origin/" + branch, nil
+}
+
+func (w *Workspace) git(args ...string) (string, error) {
+	return w.base.git(args...)
+}
@@ -0,0 +1,67 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkspaceCheckout(t *testing.T) {
+	repo, _ := newTestRepo(t, "first")
+	branch := func(args ...string) {
+		t.Helper()
+		if output, err := exec.Command("git", append([]string{"-C", repo.GetLocalPath()}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	branch("checkout", "--quiet", "-b", "feature/login")
+	if err := os.WriteFile(filepath.Join(repo.GetLocalPath(), "file.txt"), []byte("feature"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	branch("commit", "--quiet", "-am", "feature")
+	branch("checkout", "--quiet", "-")
+
+	workspace, err := NewWorkspace(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := workspace.Checkout("feature/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.GetLocalPath() == repo.GetLocalPath() {
+		t.Fatal("the worktree is the base checkout")
+	}
+	assertFile(t, filepath.Join(tree.GetLocalPath(), "file.txt"), "feature")
+	assertFile(t, filepath.Join(repo.GetLocalPath(), "file.txt"), "x")
+
+	again, err := workspace.Checkout("feature/login")
+	if err != nil || again != tree {
+		t.Errorf("second Checkout = %v, %v; want the same worktree", again, err)
+	}
+
+	// A later run replaces the worktree an earlier one left behind
+	later, err := NewWorkspace(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := later.Checkout("feature/login"); err != nil {
+		t.Fatalf("checking out over a leftover worktree: %v", err)
+	}
+
+	if _, err := workspace.Checkout("missing"); err == nil {
+		t.Error("expected an error for a missing branch")
+	}
+}
+
+func assertFile(t *testing.T, path, want string) {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != want {
+		t.Errorf("%s = %q, want %q", path, content, want)
+	}
+}
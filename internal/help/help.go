@@ -4,24 +4,98 @@ import "fmt"
 
 func PrintUsage() {
 	fmt.Println("Docu-Jarvis CLI - AI-powered documentation tool")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis <command> [flags] [arguments]")
 	fmt.Println("\nCommands:")
-	fmt.Println("  -update-docs <files>      Update existing documentation")
-	fmt.Println("  -write-docs <topics>      Write new documentation")
-	fmt.Println("  -debug <from> <to> <bug>  Find which commit caused a bug")
-	fmt.Println("  -check-staging [settings] Review staged code quality")
-	fmt.Println("  -explain <commit> [question] Explain a commit interactively")
-	fmt.Println("  -config                   Edit configuration (repo URL, code standards)")
-	fmt.Println("  -version                  Show version and check for updates")
-	fmt.Println("  -update                   Update to the latest version")
-	fmt.Println("  -help [command]           Show help")
+	fmt.Println("  update-docs <files>          Update existing documentation")
+	fmt.Println("  write-docs <topics>          Write new documentation")
+	fmt.Println("  write-tests <package|file>   Write unit tests and open a PR with them")
+	fmt.Println("  write-api-docs               Document the HTTP API as endpoint docs or an OpenAPI spec")
+	fmt.Println("  audit-docs                   Report undocumented code, stale docs, and topics to write")
+	fmt.Println("  docs-gap                     Compare the docs structure to a reference layout")
+	fmt.Println("  lint-docs <files>            Check docs against the style guide and fix them")
+	fmt.Println("  archive-docs <files|tag>     Move deprecated docs to the archive and fix links")
+	fmt.Println("  check-assets                 Check that the images the docs reference exist")
+	fmt.Println("  stale-docs                   List the docs whose source files changed since they were generated")
+	fmt.Println("  export-site                  Generate an MkDocs or Docusaurus site for the docs")
+	fmt.Println("  debug <from> <to> <bug>      Find which commit caused a bug")
+	fmt.Println("  check-staging [settings]     Review staged code quality")
+	fmt.Println("  explain <commit> [question]  Explain a commit interactively")
+	fmt.Println("  review-checklist <source>    Generate a reviewer checklist (staged, branch, pr)")
+	fmt.Println("  review-pr <number|url>       Review a PR against code standards and comment on it")
+	fmt.Println("  triage <number|url>          Comment on a GitHub issue with suspected causes and labels")
+	fmt.Println("  check-commits <range>        Check commit messages against conventions")
+	fmt.Println("  squash-summary [base]        Write a squash-merge message for the current branch")
+	fmt.Println("  changelog <from> <to>        Write a changelog entry for a range of commits")
+	fmt.Println("  eval [suite]                 Score the prompts and models on a suite of scenarios")
+	fmt.Println("  runs [list|show <id>]        Inspect past update-docs runs")
+	fmt.Println("  cache [status|clear]         Inspect or clear the update-docs cache")
+	fmt.Println("  conversations                List, show, or export saved explain conversations")
+	fmt.Println("  rollback-run <id>            Open a PR reverting a run's docs changes")
+	fmt.Println("  serve                        Update docs from GitHub push webhooks")
+	fmt.Println("  schedule                     Add, list, remove, or export recurring runs")
+	fmt.Println("  daemon                       Run the scheduled jobs as they come due")
+	fmt.Println("  usage                        Show Claude token usage and cost over time")
+	fmt.Println("  logs tail                    Show the latest run's log")
+	fmt.Println("  purge                        Remove old logs, run state, clones, and sessions")
+	fmt.Println("  config                       Edit configuration (repo URL, code standards)")
+	fmt.Println("  auth [login|logout|status]   Keep tokens in the OS keychain instead of the config")
+	fmt.Println("  version                      Show version and check for updates")
+	fmt.Println("  update                       Update to the latest version")
+	fmt.Println("  help [command]               Show help")
 	fmt.Println("\nFirst Time Setup:")
-	fmt.Println("  docu-jarvis -config       Configure repo URL and GitHub token")
+	fmt.Println("  docu-jarvis config           Configure repo URL")
+	fmt.Println("  docu-jarvis auth login       Store your GitHub token in the OS keychain")
 	fmt.Println("\nFor detailed help on a command:")
-	fmt.Println("  docu-jarvis -help update-docs")
-	fmt.Println("  docu-jarvis -help write-docs")
-	fmt.Println("  docu-jarvis -help debug")
-	fmt.Println("  docu-jarvis -help check-staging")
-	fmt.Println("  docu-jarvis -help explain")
+	fmt.Println("  docu-jarvis help update-docs")
+	fmt.Println("  docu-jarvis help write-docs")
+	fmt.Println("  docu-jarvis help write-tests")
+	fmt.Println("  docu-jarvis help write-api-docs")
+	fmt.Println("  docu-jarvis help audit-docs")
+	fmt.Println("  docu-jarvis help docs-gap")
+	fmt.Println("  docu-jarvis help lint-docs")
+	fmt.Println("  docu-jarvis help archive-docs")
+	fmt.Println("  docu-jarvis help check-assets")
+	fmt.Println("  docu-jarvis help stale-docs")
+	fmt.Println("  docu-jarvis help export-site")
+	fmt.Println("  docu-jarvis help debug")
+	fmt.Println("  docu-jarvis help check-staging")
+	fmt.Println("  docu-jarvis help explain")
+	fmt.Println("  docu-jarvis help review-checklist")
+	fmt.Println("  docu-jarvis help review-pr")
+	fmt.Println("  docu-jarvis help triage")
+	fmt.Println("  docu-jarvis help check-commits")
+	fmt.Println("  docu-jarvis help squash-summary")
+	fmt.Println("  docu-jarvis help changelog")
+	fmt.Println("  docu-jarvis help eval")
+	fmt.Println("  docu-jarvis help runs")
+	fmt.Println("  docu-jarvis help cache")
+	fmt.Println("  docu-jarvis help conversations")
+	fmt.Println("  docu-jarvis help rollback-run")
+	fmt.Println("  docu-jarvis help serve")
+	fmt.Println("  docu-jarvis help schedule")
+	fmt.Println("  docu-jarvis help daemon")
+	fmt.Println("  docu-jarvis help usage")
+	fmt.Println("  docu-jarvis help logs")
+	fmt.Println("  docu-jarvis help purge")
+	fmt.Println("  docu-jarvis help auth")
+	fmt.Println("\nMonorepos:")
+	fmt.Println("  Most commands accept -scope <dir> to restrict cloning, docs, history,")
+	fmt.Println("  and the agent to one directory (e.g., -scope services/payments).")
+	fmt.Println("\nRestricted Environments:")
+	fmt.Println("  -no-network (or DOCU_JARVIS_NO_NETWORK=1) fails fast instead of cloning,")
+	fmt.Println("  fetching, calling GitHub, or checking for updates. Claude is only called")
+	fmt.Println("  when ANTHROPIC_BASE_URL points at a model backend on localhost.")
+	fmt.Println("\nLogging:")
+	fmt.Println("  Each run logs to its own file in ~/.docu-jarvis/logs (see 'docu-jarvis")
+	fmt.Println("  help logs'). -v also prints the log to stderr, and -vv adds the debug")
+	fmt.Println("  records, such as each tool Claude uses, to both.")
+	fmt.Println("\nCI:")
+	fmt.Println("  -ci (or DOCU_JARVIS_CI=1) never waits for input: prompts take their")
+	fmt.Println("  safe default, explain-commit skips its conversation, and there is no")
+	fmt.Println("  update check or dashboard. On GitHub Actions, check-staging also")
+	fmt.Println("  annotates its findings and writes a job summary.")
+	fmt.Println("\nThe old flag style (e.g. 'docu-jarvis -update-docs all') still works but is deprecated.")
 	fmt.Println()
 }
 
@@ -31,34 +105,153 @@ func PrintUpdateDocsHelp() {
 	fmt.Println("  Updates existing documentation by analyzing the current codebase and ensuring")
 	fmt.Println("  documentation accurately reflects the actual implementation.")
 	fmt.Println("\nUsage:")
-	fmt.Println("  docu-jarvis -update-docs <files>")
-	fmt.Println("  docu-jarvis -update-docs <files> -custom \"your custom prompt\"")
+	fmt.Println("  docu-jarvis update-docs <files>")
+	fmt.Println("  docu-jarvis update-docs <files> -custom \"your custom prompt\"")
+	fmt.Println("  docu-jarvis update-docs <files> -local <path>")
 	fmt.Println("\nArguments:")
-	fmt.Println("  all              Update all markdown files in documentation/")
-	fmt.Println("  <file.md>        Update a specific file (e.g., 'api.md')")
+	fmt.Println("  all              Update all .md and .mdx files in the docs roots, including")
+	fmt.Println("                   nested folders (documentation/ by default)")
+	fmt.Println("  <file.md>        Update a specific file (e.g., 'api.md' or 'guides/setup.mdx')")
 	fmt.Println("  <files>          Update multiple files, comma-separated (e.g., 'api.md,db.md')")
+	fmt.Println("  tag:<name>       Update the docs tagged <name> in their front matter")
+	fmt.Println("                   (e.g., 'tag:api', or 'tag:api,tag:auth' for either)")
+	fmt.Println("  queued           Update the docs queued by 'check-staging -queue-docs'")
+	fmt.Println("                   (failed and rejected docs stay queued)")
+	fmt.Println("  changed          Update only the docs covering the code changed since -since:")
+	fmt.Println("                   those .docu-jarvis-docmap lists for it, or without one,")
+	fmt.Println("                   those Claude finds the diff requires updating")
 	fmt.Println("\nOptional Flags:")
 	fmt.Println("  -custom \"prompt\" Use a custom prompt instead of the default update instructions")
 	fmt.Println("                   Useful for specific update requirements or formatting")
+	fmt.Println("  -local <path>    Use an existing checkout instead of cloning (e.g., '.')")
+	fmt.Println("                   The PR is created from a new branch in that checkout, with")
+	fmt.Println("                   only the files the run changed")
+	fmt.Println("  -branch <name>   Clone this branch and target it with the PR (default: the")
+	fmt.Println("                   'branch' config key, then the repo's default branch)")
+	fmt.Println("                   With -local, only the PR base is changed")
+	fmt.Println("  -repo <name|url> Use this configured repository (by name or URL) instead of")
+	fmt.Println("                   the first 'repo' in the config")
+	fmt.Println("  -all-repos       Run the update in every configured repository in turn,")
+	fmt.Println("                   with a PR for each; failures do not stop the others")
+	fmt.Println("  -dry-run         Print a proposed diff and summary per file without")
+	fmt.Println("                   modifying files, committing, or creating a PR; the repo is")
+	fmt.Println("                   still cloned or fetched unless -local is given")
+	fmt.Println("  -confirm-edits   Show each proposed edit and ask before applying it; edits")
+	fmt.Println("                   outside the docs directories are denied without asking")
+	fmt.Println("  -auto-approve    Open the PR with every change; by default each changed doc's")
+	fmt.Println("                   diff is shown first, to accept, reject, or edit (for CI)")
+	fmt.Println("  -docs-dir <dirs> Docs directories relative to the repository root, comma-")
+	fmt.Println("                   separated (e.g., 'docs,wiki'); overrides docs_roots")
+	fmt.Println("  -since <ref|date>")
+	fmt.Println("                   With 'changed': the commit, branch, tag, or date (e.g.,")
+	fmt.Println("                   '2024-06-01' or '2 weeks ago') to look for changes since")
+	fmt.Println("  -resume <id>     Retry only the failed and pending documents of a previous")
+	fmt.Println("                   run, with its settings (see 'docu-jarvis runs list')")
+	fmt.Println("  -canary <fraction|files>")
+	fmt.Println("                   Update a fraction (e.g., 0.1 or 10%) or list of the docs")
+	fmt.Println("                   first, and compare them with the previous runs' updates")
+	fmt.Println("                   before the rest; for after an upgrade or a prompt change")
+	fmt.Println("  -no-cache        Update every doc, even those whose content and sources are")
+	fmt.Println("                   unchanged since their last update (see 'docu-jarvis help")
+	fmt.Println("                   cache')")
+	fmt.Println("  -compare         Instead of updating docs, update the doc given with -file")
+	fmt.Println("                   with two variants in turn, then show the diff of their")
+	fmt.Println("                   results and Claude's judgement of them; the doc is")
+	fmt.Println("                   restored and the results are saved to a temp directory")
+	fmt.Println("  -prompt-a, -prompt-b <file|name>")
+	fmt.Println("                   With -compare: each variant's prompt, a file or an")
+	fmt.Println("                   embedded prompt (e.g., 'documentation_update.txt');")
+	fmt.Println("                   default: the update prompt")
+	fmt.Println("  -model-a, -model-b <model>")
+	fmt.Println("                   With -compare: each variant's model (default: the")
+	fmt.Println("                   configured one)")
+	fmt.Println("  -learn-from-pr <number|url>")
+	fmt.Println("                   Instead of updating docs, turn the review comments on a")
+	fmt.Println("                   past docu-jarvis PR (GitHub) into rules that later")
+	fmt.Println("                   update-docs, write-docs, and lint-docs runs follow; with")
+	fmt.Println("                   -dry-run, show the rules without saving them")
+	fmt.Println("  -concurrency <n> Update at most n files at once (default: all of them)")
+	fmt.Println("  -order <order>   Order files are started in, so the most valuable results")
+	fmt.Println("                   land first: given (as listed, default), smallest (smallest")
+	fmt.Println("                   first), or stale (last changed longest ago first)")
+	fmt.Println("  -no-tui          Print a line per started and finished file instead of the")
+	fmt.Println("                   progress dashboard shown on a terminal")
+	fmt.Println("  -output json     Print the end-of-run summary (status, duration, turns,")
+	fmt.Println("                   tokens, and error class per file) as JSON on stdout;")
+	fmt.Println("                   progress goes to stderr. With -all-repos, a JSON array of")
+	fmt.Println("                   the summaries of each repository")
+	fmt.Println("\nPull Request Flags (override the pr_* config keys):")
+	fmt.Println("  -pr-title <tmpl> PR title template (default: 'Documentation Update')")
+	fmt.Println("  -pr-body <tmpl>  PR body template; {summary} is the list of documents the")
+	fmt.Println("                   run updated (see 'docu-jarvis help config')")
+	fmt.Println("  -pr-base <name>  Branch the PR targets")
+	fmt.Println("  -pr-labels, -pr-assignees, -pr-reviewers <list>")
+	fmt.Println("                   Comma-separated; GitHub teams as org/team")
+	fmt.Println("  -split-prs <by>  One PR per docs subdirectory (dir) or per CODEOWNERS")
+	fmt.Println("                   owners (codeowners), who are requested as reviewers")
+	fmt.Println("  -suggest-reviewers")
+	fmt.Println("                   Request the recent contributors to the code each doc")
+	fmt.Println("                   describes as reviewers (GitHub and GitLab)")
+	fmt.Println("  -digest-comments Comment on each changed doc in the PR with what Claude")
+	fmt.Println("                   changed and why")
+	fmt.Println("  -draft           Open the PR as a draft")
+	fmt.Println("  -yes             Push the PR's branch without asking first (CI mode never")
+	fmt.Println("                   asks)")
 	fmt.Println("\nNote:")
-	fmt.Println("  - You can omit the .md extension (e.g., 'api' works like 'api.md')")
-	fmt.Println("  - Multiple files are processed concurrently for speed")
-	fmt.Println("  - Only documentation files are modified, never source code")
+	fmt.Println("  - You can omit the extension (e.g., 'api' works like 'api.md' or 'api.mdx')")
+	fmt.Println("  - Files are looked up in each docs root (docs_roots or -docs-dir), and bare")
+	fmt.Println("    names also match files in nested folders")
+	fmt.Println("  - Multiple files are processed concurrently for speed; with -concurrency,")
+	fmt.Println("    an interrupted run has finished the first files in -order")
+	fmt.Println("  - Only documentation files are modified, never source code: edits outside")
+	fmt.Println("    the docs roots (or the edit_allowlist config key) are denied, reverted")
+	fmt.Println("    after the run if any got through, and refused at commit time")
+	fmt.Println("  - Claude keeps a 'tags: [...]' list in each doc's front matter, reusing the")
+	fmt.Println("    tags other docs have, so tag:<name> selects a category of docs")
+	fmt.Println("  - Docs that describe a feature removed from the code are left unchanged and")
+	fmt.Println("    proposed for 'archive-docs' in the output and the PR body")
+	fmt.Println("  - The archive folder of each docs root is skipped by 'all'")
+	fmt.Println("  - Before the PR is opened, the Go, Python, and shell samples of the changed")
+	fmt.Println("    docs are compiled or syntax-checked; broken ones are listed in the PR body")
+	fmt.Println("  - The words, sections, and code samples of each changed doc are compared")
+	fmt.Println("    with those before the update and past runs (~/.docu-jarvis/metrics.json);")
+	fmt.Println("    docs that lost sections or 40% of their words are flagged in the PR body")
+	fmt.Println("  - Rules learned with -learn-from-pr are kept per repository in")
+	fmt.Println("    ~/.docu-jarvis/feedback.json; learning from a PR again replaces its rules")
 	fmt.Println("\nExamples:")
 	fmt.Println("  # Standard update")
-	fmt.Println("  docu-jarvis -update-docs all")
-	fmt.Println("  docu-jarvis -update-docs api")
-	fmt.Println("  docu-jarvis -update-docs \"api.md,database.md,setup.md\"")
+	fmt.Println("  docu-jarvis update-docs all")
+	fmt.Println("  docu-jarvis update-docs api")
+	fmt.Println("  docu-jarvis update-docs \"api.md,database.md,setup.md\"")
+	fmt.Println("  docu-jarvis update-docs all -concurrency 4 -order stale")
+	fmt.Println()
+	fmt.Println("  # Only the docs affected by recent changes")
+	fmt.Println("  docu-jarvis update-docs changed -since v2.3.0")
+	fmt.Println("  docu-jarvis update-docs changed -since \"2 weeks ago\"")
 	fmt.Println()
 	fmt.Println("  # Custom prompt update")
-	fmt.Println("  docu-jarvis -update-docs api -custom \"Add more code examples and simplify explanations\"")
-	fmt.Println("  docu-jarvis -update-docs all -custom \"Update all diagrams to use mermaid syntax\"")
+	fmt.Println("  docu-jarvis update-docs api -custom \"Add more code examples and simplify explanations\"")
+	fmt.Println("  docu-jarvis update-docs all -custom \"Update all diagrams to use mermaid syntax\"")
+	fmt.Println()
+	fmt.Println("  # Preview changes without touching the repository")
+	fmt.Println("  docu-jarvis update-docs api -dry-run")
+	fmt.Println()
+	fmt.Println("  # Try a new prompt or version on a tenth of the docs first")
+	fmt.Println("  docu-jarvis update-docs all -canary 10%")
+	fmt.Println()
+	fmt.Println("  # Compare an edited prompt with the embedded one on one doc")
+	fmt.Println("  docu-jarvis update-docs -compare -prompt-b my_update.txt -file api.md")
+	fmt.Println()
+	fmt.Println("  # Other configured repositories")
+	fmt.Println("  docu-jarvis update-docs all -repo payments-service")
+	fmt.Println("  docu-jarvis update-docs all -all-repos")
 	fmt.Println("\nWhat it does:")
-	fmt.Println("  1. Clones your repository to /tmp")
+	fmt.Println("  1. Clones your repository to clone_dir (default /tmp)")
 	fmt.Println("  2. Reads the documentation file(s)")
 	fmt.Println("  3. Analyzes related code in the codebase")
 	fmt.Println("  4. Updates documentation to match current implementation (or per custom prompt)")
-	fmt.Println("  5. Creates a pull request with changes")
+	fmt.Println("  5. Checks the code samples of the changed docs")
+	fmt.Println("  6. Creates a pull request with changes")
 	fmt.Println()
 }
 
@@ -68,26 +261,70 @@ func PrintWriteDocsHelp() {
 	fmt.Println("  Generates new comprehensive documentation for specified topics by analyzing")
 	fmt.Println("  the codebase and creating structured markdown files.")
 	fmt.Println("\nUsage:")
-	fmt.Println("  docu-jarvis -write-docs <topics>")
+	fmt.Println("  docu-jarvis write-docs <topics>")
+	fmt.Println("  docu-jarvis write-docs <topics> -local <path>")
 	fmt.Println("\nArguments:")
 	fmt.Println("  <topic>          A single topic to document (e.g., 'API Authentication')")
 	fmt.Println("  <topics>         Multiple topics, comma-separated (e.g., 'API,Database,Cache')")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -local <path>    Use an existing checkout instead of cloning (e.g., '.')")
+	fmt.Println("  -branch <name>   Clone this branch and target it with the PR (default: the")
+	fmt.Println("                   'branch' config key, then the repo's default branch)")
+	fmt.Println("  -repo <name|url> Use this configured repository (by name or URL) instead of")
+	fmt.Println("                   the first 'repo' in the config")
+	fmt.Println("  -dry-run         Print the proposed documentation without writing files or creating a PR")
+	fmt.Println("                   (the repo is still cloned or fetched unless -local is given)")
+	fmt.Println("  -confirm-edits   Show each proposed edit and ask before applying it; edits")
+	fmt.Println("                   outside the docs directories are denied without asking")
+	fmt.Println("  -auto-approve    Open the PR with every change; by default each changed doc's")
+	fmt.Println("                   diff is shown first, to accept, reject, or edit (for CI)")
+	fmt.Println("  -docs-dir <dirs> Docs directories relative to the repository root, comma-")
+	fmt.Println("                   separated; overrides docs_roots")
+	fmt.Println("  -concurrency <n> Write at most n topics at once, in the order given")
+	fmt.Println("                   (default: all of them)")
+	fmt.Println("  -category <kind> Kind of topic: general (default), or an interface with its")
+	fmt.Println("                   own structure that is found through its schemas, producers,")
+	fmt.Println("                   and consumers: events, webhooks, or queues")
+	fmt.Println("  -no-tui          Print a line per started and finished topic instead of the")
+	fmt.Println("                   progress dashboard shown on a terminal")
+	fmt.Println("  -output json     Print the end-of-run summary per topic as JSON on stdout;")
+	fmt.Println("                   progress goes to stderr")
+	fmt.Println("\nPull Request Flags (override the pr_* config keys):")
+	fmt.Println("  -pr-title <tmpl> PR title template (default: 'Documentation Update')")
+	fmt.Println("  -pr-body <tmpl>  PR body template; {summary} is the list of documents the")
+	fmt.Println("                   run updated (see 'docu-jarvis help config')")
+	fmt.Println("  -pr-base <name>  Branch the PR targets")
+	fmt.Println("  -pr-labels, -pr-assignees, -pr-reviewers <list>")
+	fmt.Println("                   Comma-separated; GitHub teams as org/team")
+	fmt.Println("  -split-prs <by>  One PR per docs subdirectory (dir) or per CODEOWNERS")
+	fmt.Println("                   owners (codeowners), who are requested as reviewers")
+	fmt.Println("  -suggest-reviewers")
+	fmt.Println("                   Request the recent contributors to the code each doc")
+	fmt.Println("                   describes as reviewers (GitHub and GitLab)")
+	fmt.Println("  -draft           Open the PR as a draft")
+	fmt.Println("  -yes             Push the PR's branch without asking first (CI mode never")
+	fmt.Println("                   asks)")
 	fmt.Println("\nNote:")
 	fmt.Println("  - Topics can be descriptive phrases (e.g., 'Payment Processing Flow')")
 	fmt.Println("  - Multiple topics are processed concurrently")
 	fmt.Println("  - Checks for existing documentation and prompts before overwriting")
-	fmt.Println("  - Files are created in documentation/ folder with appropriate names")
+	fmt.Println("  - Files are created in the first docs root inside the scope")
+	fmt.Println("    (documentation/ unless docs_roots or -docs-dir is set)")
+	fmt.Println("  - Go, Python, and shell samples are checked before the PR is opened, and broken")
+	fmt.Println("    ones are listed in the PR body")
 	fmt.Println("\nExamples:")
-	fmt.Println("  docu-jarvis -write-docs \"API Authentication\"")
-	fmt.Println("  docu-jarvis -write-docs \"Subscription Management\"")
-	fmt.Println("  docu-jarvis -write-docs \"API,Database Schema,Caching Strategy\"")
+	fmt.Println("  docu-jarvis write-docs \"API Authentication\"")
+	fmt.Println("  docu-jarvis write-docs \"Subscription Management\"")
+	fmt.Println("  docu-jarvis write-docs \"API,Database Schema,Caching Strategy\"")
+	fmt.Println("  docu-jarvis write-docs \"API Authentication\" -local .")
+	fmt.Println("  docu-jarvis write-docs \"Order events\" -category events")
 	fmt.Println("\nWhat it does:")
-	fmt.Println("  1. Clones your repository to /tmp")
+	fmt.Println("  1. Clones your repository to clone_dir (default /tmp)")
 	fmt.Println("  2. Checks if documentation already exists for the topic")
 	fmt.Println("  3. If exists, prompts: update, write new, or skip")
 	fmt.Println("  4. Analyzes codebase to understand the topic")
 	fmt.Println("  5. Generates comprehensive documentation following best practices")
-	fmt.Println("  6. Creates markdown file in documentation/ folder")
+	fmt.Println("  6. Creates markdown file in the docs root")
 	fmt.Println("  7. Creates a pull request with new documentation")
 	fmt.Println("\nDocumentation Structure:")
 	fmt.Println("  Each document includes:")
@@ -102,31 +339,187 @@ func PrintWriteDocsHelp() {
 	fmt.Println()
 }
 
+func PrintWriteTestsHelp() {
+	fmt.Println("Docu-Jarvis - Write Tests Mode")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Writes unit tests for a package directory or source file (Go, TypeScript,")
+	fmt.Println("  JavaScript, Python, Java, Kotlin, Ruby) following the configured test")
+	fmt.Println("  conventions, and opens a pull request with them on a tests/ branch.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis write-tests <package-or-file>...")
+	fmt.Println("  docu-jarvis write-tests <package-or-file>... -local <path>")
+	fmt.Println("\nArguments:")
+	fmt.Println("  <package>        A package directory (e.g., 'internal/billing')")
+	fmt.Println("  <file>           A source file (e.g., 'src/cart.ts'); both are relative to")
+	fmt.Println("                   the scope, and several can be given")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -local <path>    Use an existing checkout instead of cloning (e.g., '.')")
+	fmt.Println("  -branch <name>   Clone this branch and target it with the PR (default: the")
+	fmt.Println("                   'branch' config key, then the repo's default branch)")
+	fmt.Println("  -repo <name|url> Use this configured repository (by name or URL) instead of")
+	fmt.Println("                   the first 'repo' in the config")
+	fmt.Println("  -verify          Run go test on the packages of the new Go tests; failing")
+	fmt.Println("                   tests are sent back to Claude once, then left out of the PR")
+	fmt.Println("\nPull Request Flags (override the pr_* config keys):")
+	fmt.Println("  -pr-title <tmpl> PR title template (default: 'Unit tests')")
+	fmt.Println("  -pr-body <tmpl>  PR body template; {summary} lists the tests of each target")
+	fmt.Println("  -pr-base <name>  Branch the PR targets")
+	fmt.Println("  -pr-labels, -pr-assignees, -pr-reviewers <list>")
+	fmt.Println("                   Comma-separated; GitHub teams as org/team")
+	fmt.Println("  -draft           Open the PR as a draft")
+	fmt.Println("  -yes             Push the PR's branch without asking first (CI mode never")
+	fmt.Println("                   asks)")
+	fmt.Println("\nNote:")
+	fmt.Println("  - Conventions come from test_conventions in the config or the repository's")
+	fmt.Println("    .docu-jarvis.toml; without any, table-driven tests with the language's")
+	fmt.Println("    standard framework are written")
+	fmt.Println("  - Claude may only create and edit test files (e.g. *_test.go, *.test.ts,")
+	fmt.Println("    test_*.py) and testdata/; edits to anything else are reverted")
+	fmt.Println("  - -verify only runs Go tests; tests in other languages are not run")
+	fmt.Println("  - -verify runs go test with GOPROXY=off, GOTOOLCHAIN=local, and GOWORK=off,")
+	fmt.Println("    so the module's dependencies must already be downloaded")
+	fmt.Println("\nExamples:")
+	fmt.Println("  docu-jarvis write-tests internal/billing")
+	fmt.Println("  docu-jarvis write-tests internal/billing/invoice.go -verify")
+	fmt.Println("  docu-jarvis write-tests src/cart.ts src/checkout.ts -local .")
+	fmt.Println("\nWhat it does:")
+	fmt.Println("  1. Clones your repository to clone_dir (default /tmp)")
+	fmt.Println("  2. Reads each target, the code it uses, and the existing tests")
+	fmt.Println("  3. Writes unit tests next to the code, following the test conventions")
+	fmt.Println("  4. With -verify, runs go test and has Claude fix failing tests once")
+	fmt.Println("  5. Creates a pull request with the tests on a tests/ branch")
+	fmt.Println()
+}
+
+func PrintWriteAPIDocsHelp() {
+	fmt.Println("Docu-Jarvis - Write API Docs Mode")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Finds the HTTP routes of the codebase (Gin, Echo, net/http, Express, FastAPI)")
+	fmt.Println("  and documents them, as endpoint docs in the docs root or as an OpenAPI 3")
+	fmt.Println("  spec, in a pull request.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis write-api-docs")
+	fmt.Println("  docu-jarvis write-api-docs -format openapi")
+	fmt.Println("  docu-jarvis write-api-docs -local <path>")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -format <format> markdown (default): endpoint docs with parameters, bodies,")
+	fmt.Println("                   responses, and examples; openapi: an OpenAPI 3 spec")
+	fmt.Println("  -output <file>   File to write, relative to the first docs root (default:")
+	fmt.Println("                   api-reference.md, or openapi.yaml; .json for a JSON spec)")
+	fmt.Println("  -local <path>    Use an existing checkout instead of cloning (e.g., '.')")
+	fmt.Println("  -branch <name>   Clone this branch and target it with the PR (default: the")
+	fmt.Println("                   'branch' config key, then the repo's default branch)")
+	fmt.Println("  -repo <name|url> Use this configured repository (by name or URL) instead of")
+	fmt.Println("                   the first 'repo' in the config")
+	fmt.Println("  -dry-run         Print the proposed file without writing it or creating a PR")
+	fmt.Println("  -auto-approve    Open the PR without showing the diff first (for CI)")
+	fmt.Println("  -docs-dir <dirs> Docs directories relative to the repository root, comma-")
+	fmt.Println("                   separated (e.g., 'docs,wiki'); overrides docs_roots")
+	fmt.Println("\nPull Request Flags (override the pr_* config keys):")
+	fmt.Println("  -pr-title <tmpl> PR title template (default: 'API documentation')")
+	fmt.Println("  -pr-body <tmpl>  PR body template; {summary} names the file written")
+	fmt.Println("  -pr-base <name>  Branch the PR targets")
+	fmt.Println("  -pr-labels, -pr-assignees, -pr-reviewers <list>")
+	fmt.Println("                   Comma-separated; GitHub teams as org/team")
+	fmt.Println("  -draft           Open the PR as a draft")
+	fmt.Println("  -yes             Push the PR's branch without asking first (CI mode never")
+	fmt.Println("                   asks)")
+	fmt.Println("\nNote:")
+	fmt.Println("  - Routes are found by reading the source, so Claude works out group")
+	fmt.Println("    prefixes and looks for routes registered in other ways")
+	fmt.Println("  - An existing file is updated to match the code rather than rewritten")
+	fmt.Println("  - A spec must be OpenAPI 3 with paths, or no PR is opened; the routes found")
+	fmt.Println("    that it leaves out are listed in the PR body")
+	fmt.Println("\nExamples:")
+	fmt.Println("  docu-jarvis write-api-docs")
+	fmt.Println("  docu-jarvis write-api-docs -format openapi -output api/openapi.json")
+	fmt.Println("  docu-jarvis write-api-docs -dry-run -local .")
+	fmt.Println("\nWhat it does:")
+	fmt.Println("  1. Clones your repository to clone_dir (default /tmp)")
+	fmt.Println("  2. Finds the routes the code registers with the supported frameworks")
+	fmt.Println("  3. Reads their handlers and payload types, and writes the docs or spec")
+	fmt.Println("  4. Checks the spec, and shows the diff for your approval")
+	fmt.Println("  5. Creates a pull request with the file")
+	fmt.Println()
+}
+
 func PrintDebugHelp() {
 	fmt.Println("Docu-Jarvis - Debug Mode")
 	fmt.Println("\nDescription:")
 	fmt.Println("  Analyzes git commits within a date range to identify which commit")
 	fmt.Println("  likely introduced a specific bug using AI-powered code analysis.")
 	fmt.Println("\nUsage:")
-	fmt.Println("  docu-jarvis -debug <from-date> <to-date> <bug-description>")
+	fmt.Println("  docu-jarvis debug <from-date> <to-date> <bug-description>")
+	fmt.Println("  docu-jarvis debug -incident \"<description>\" -since <time>")
 	fmt.Println("\nArguments:")
 	fmt.Println("  <from-date>        Start date (format: YYYY-MM-DD)")
 	fmt.Println("  <to-date>          End date (format: YYYY-MM-DD)")
 	fmt.Println("  <bug-description>  Description of the bug to investigate")
 	fmt.Println("\nDate Format:")
-	fmt.Println("  - Use ISO format: YYYY-MM-DD (e.g., '2024-11-01')")
-	fmt.Println("  - Can also use relative dates: '2 weeks ago', 'yesterday'")
+	fmt.Println("  - Use ISO format: YYYY-MM-DD (e.g., '2024-11-01'); a bare date is the whole")
+	fmt.Println("    day in local time, so the to-date is included")
+	fmt.Println("  - Add a time and a zone: '2024-11-01 09:00', '2024-11-01T09:00:00+02:00',")
+	fmt.Println("    '2024-11-01 09:00 UTC', '2024-11-01 09:00 America/New_York'")
+	fmt.Println("  - Can also use relative dates: '2 weeks ago', 'yesterday', 'today', 'now'")
 	fmt.Println("  - From date should be earlier than to date")
+	fmt.Println("  - The exact resolved range is printed before the analysis starts")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -branch <name>     Analyze this branch's history (default: the 'branch'")
+	fmt.Println("                     config key, then the repo's default branch)")
+	fmt.Println("  -repo <name|url>   Use this configured repository (by name or URL) instead")
+	fmt.Println("                     of the first 'repo' in the config")
+	fmt.Println("  -bisect            Binary-search the range instead of analyzing every commit:")
+	fmt.Println("                     Claude checks the midpoint commit's diff, decides whether")
+	fmt.Println("                     the bug is already present, and halves the range. Needs")
+	fmt.Println("                     about log2(n) requests; low-confidence steps ask you to")
+	fmt.Println("                     confirm. The newest commit is assumed to have the bug")
+	fmt.Println("  -verify            When the verdict's confidence is below 80%, have Claude")
+	fmt.Println("                     argue that each of the top 3 suspects is NOT the cause;")
+	fmt.Println("                     their confidence is revised and refuted ones are flagged")
+	fmt.Println("  -branches <list>   Comma-separated branches to search instead of the selected")
+	fmt.Println("                     one; globs are allowed (e.g., 'main,release/*'). Not")
+	fmt.Println("                     with -bisect")
+	fmt.Println("  -first-parent      Follow only the first parent of merges, so a merged")
+	fmt.Println("                     branch counts as its merge commit")
+	fmt.Println("  -concurrency <n>   Analyze at most n commits at once (default: 8, 0 for all)")
+	fmt.Println("  -stop-at <percent> Stop once a commit is the likely culprit with at least this")
+	fmt.Println("                     confidence; the outstanding requests are canceled, which")
+	fmt.Println("                     saves cost on long ranges. Not with -bisect or -incident")
+	fmt.Println("  -symptom <desc>    Another symptom of the bug (repeatable). Each commit is")
+	fmt.Println("                     judged against every symptom in one request, and each")
+	fmt.Println("                     symptom gets its own suspects next to the combined")
+	fmt.Println("                     verdict. Also with -incident; not with -bisect")
+	fmt.Println("  -symptoms-file <f> Read more symptoms from a file, one per line")
+	fmt.Println("  -no-history        Neither consult nor record the outcomes of past debug runs")
+	fmt.Println("  Commits that repeat an older commit's change (cherry-picks, e.g. onto release")
+	fmt.Println("  branches) are analyzed once, as the original commit")
+	fmt.Println("\nIncident Mode:")
+	fmt.Println("  -incident <desc>   Run the whole workflow for an outage in one command:")
+	fmt.Println("                     collect the commits since -since, triage them, verify the")
+	fmt.Println("                     top 3 suspects, discuss them with Claude (one answer in")
+	fmt.Println("                     -ci), and write a markdown incident report")
+	fmt.Println("  -since <time>      How far back to look (e.g., '6 hours ago', '2024-11-07 09:00')")
+	fmt.Println("  -report <file>     Where to write the report (default: incident-<time>.md)")
 	fmt.Println("\nExamples:")
-	fmt.Println("  docu-jarvis -debug \"2024-11-01\" \"2024-11-07\" \"null pointer in payment processing\"")
-	fmt.Println("  docu-jarvis -debug \"2024-10-15\" \"2024-10-20\" \"subscription not being created\"")
-	fmt.Println("  docu-jarvis -debug \"1 week ago\" \"today\" \"API returns 500 error\"")
+	fmt.Println("  docu-jarvis debug \"2024-11-01\" \"2024-11-07\" \"null pointer in payment processing\"")
+	fmt.Println("  docu-jarvis debug \"2024-10-15\" \"2024-10-20\" \"subscription not being created\"")
+	fmt.Println("  docu-jarvis debug \"1 week ago\" \"today\" \"API returns 500 error\"")
+	fmt.Println("  docu-jarvis debug -stop-at 90 \"2 months ago\" \"today\" \"checkout button does nothing\"")
+	fmt.Println("  docu-jarvis debug \"1 week ago\" \"today\" \"API returns 500 error\" -branch release/2.3")
+	fmt.Println("  docu-jarvis debug \"3 months ago\" \"today\" \"exports are missing rows\" -bisect")
+	fmt.Println("  docu-jarvis debug \"1 week ago\" \"today\" \"API returns 500 error\" -verify")
+	fmt.Println("  docu-jarvis debug \"1 week ago\" \"today\" \"API returns 500 error\" -branches 'main,release/*'")
+	fmt.Println("  docu-jarvis debug -incident \"checkout returns 500s\" -since \"6 hours ago\"")
+	fmt.Println("  docu-jarvis debug \"2 days ago\" \"now\" \"checkout returns 500s\" -symptom \"emails are late\"")
 	fmt.Println("\nWhat it does:")
-	fmt.Println("  1. Clones your repository to /tmp")
+	fmt.Println("  1. Clones your repository to clone_dir (default /tmp)")
 	fmt.Println("  2. Retrieves all commits between the specified dates")
 	fmt.Println("  3. Analyzes each commit concurrently with Claude AI")
 	fmt.Println("  4. Identifies which commit likely caused the bug (with confidence score)")
 	fmt.Println("  5. Explains what in the commit introduced the bug")
+	fmt.Println("  6. Asks whether it was the cause, and keeps your answer in")
+	fmt.Println("     ~/.docu-jarvis/debug_history.json; later runs on similar bugs")
+	fmt.Println("     are told where those were traced to")
 	fmt.Println("\nOutput:")
 	fmt.Println("  Shows the commit hash, author, date, message, confidence percentage,")
 	fmt.Println("  and detailed explanation of what caused the bug.")
@@ -139,24 +532,75 @@ func PrintCheckStagingHelp() {
 	fmt.Println("  Reviews code in git staging area against your configured code quality")
 	fmt.Println("  standards using AI-powered analysis.")
 	fmt.Println("\nUsage:")
-	fmt.Println("  docu-jarvis -check-staging          Review staged code")
-	fmt.Println("  docu-jarvis -check-staging settings Edit code standards")
+	fmt.Println("  docu-jarvis check-staging          Review staged code")
+	fmt.Println("  docu-jarvis check-staging settings Edit code standards")
 	fmt.Println("\nArguments:")
 	fmt.Println("  (none)       Review currently staged code")
 	fmt.Println("  settings     Edit your code quality standards")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -docs-impact  Also report which documentation files the change affects")
+	fmt.Println("                and warn when a doc obviously needs an update")
+	fmt.Println("  -queue-docs   Queue the docs that need updating for the next")
+	fmt.Println("                'docu-jarvis update-docs queued' run (implies -docs-impact)")
+	fmt.Println("  -output       Output format: text (default) or json. With json, a report")
+	fmt.Println("                is printed on stdout, progress goes to stderr, and the exit")
+	fmt.Println("                status is 2 for MAJOR_ISSUES or NON_COMPLIANT")
+	fmt.Println("  -ci           CI mode: exit status 2 for MAJOR_ISSUES or NON_COMPLIANT;")
+	fmt.Println("                on GitHub Actions, findings become ::error/::warning")
+	fmt.Println("                annotations and the review goes to the job summary")
+	fmt.Println("  -docs-dir     Docs directories for -docs-impact, comma-separated and")
+	fmt.Println("                relative to the repository root; overrides docs_roots")
+	fmt.Println("  -only-new     Report and fail only on violations the staged changes")
+	fmt.Println("                introduce: findings on lines they did not add, and those")
+	fmt.Println("                recorded in the baseline, are left out. Exit status 2 for")
+	fmt.Println("                new critical or major findings")
+	fmt.Println("  -passes       Run N independent reviews and count only the findings most")
+	fmt.Println("                of them agree on; the others are listed separately. Cuts")
+	fmt.Println("                false positives when gating CI, at N times the cost")
+	fmt.Println("  -update-baseline")
+	fmt.Println("                Record the violations the review finds as pre-existing in")
+	fmt.Println("                .docu-jarvis-baseline.json in the repository root (by file")
+	fmt.Println("                and standard); commit it to share it")
+	fmt.Println("  -infer-standards")
+	fmt.Println("                Draft code standards from the conventions of the codebase")
+	fmt.Println("                and recent review comments on its GitHub PRs, open them in")
+	fmt.Println("                your editor, and save them to the config")
 	fmt.Println("\nSetting Up Standards:")
-	fmt.Println("  First time: Run 'docu-jarvis -check-staging settings' to configure")
+	fmt.Println("  First time: Run 'docu-jarvis check-staging settings' to configure")
 	fmt.Println("  your code standards. These are saved to ~/.docu-jarvis-settings.txt")
+	fmt.Println("  No standards yet? 'docu-jarvis check-staging -infer-standards' drafts them")
+	fmt.Println("  Shared standards: set code_standards_source = <repo>#<file>, e.g.")
+	fmt.Println("  git@github.com:org/eng-standards.git#go.md, to use a file kept in a git")
+	fmt.Println("  repository too. It is fetched at most hourly and cached in ~/.docu-jarvis/standards")
 	fmt.Println("\nExamples:")
 	fmt.Println("  # First, configure your standards")
-	fmt.Println("  docu-jarvis -check-staging settings")
+	fmt.Println("  docu-jarvis check-staging settings")
+	fmt.Println()
+	fmt.Println("  # Or draft them from the codebase and its reviews")
+	fmt.Println("  docu-jarvis check-staging -infer-standards")
 	fmt.Println()
 	fmt.Println("  # Then review your staged code")
 	fmt.Println("  git add .")
-	fmt.Println("  docu-jarvis -check-staging")
+	fmt.Println("  docu-jarvis check-staging")
+	fmt.Println()
+	fmt.Println("  # Also check which docs the change affects")
+	fmt.Println("  docu-jarvis check-staging -queue-docs")
+	fmt.Println()
+	fmt.Println("  # Gate a CI job on the review")
+	fmt.Println("  docu-jarvis check-staging -output json > review.json")
+	fmt.Println()
+	fmt.Println("  # Accept the violations a legacy file already has, then gate on new ones")
+	fmt.Println("  git add legacy/handler.go && docu-jarvis check-staging -update-baseline")
+	fmt.Println("  docu-jarvis check-staging -only-new")
+	fmt.Println()
+	fmt.Println("  # Fail only on findings at least 2 of 3 reviews agree on")
+	fmt.Println("  docu-jarvis check-staging -passes 3 -ci")
+	fmt.Println()
+	fmt.Println("  # Annotate a GitHub Actions pull request job")
+	fmt.Println("  git reset --soft origin/main && docu-jarvis check-staging -ci")
 	fmt.Println()
 	fmt.Println("  # Update standards later")
-	fmt.Println("  docu-jarvis -check-staging settings")
+	fmt.Println("  docu-jarvis check-staging settings")
 	fmt.Println("\nWhat it does:")
 	fmt.Println("  1. Loads your code standards from ~/.docu-jarvis-settings.txt")
 	fmt.Println("  2. Gets the diff of staged changes (git diff --cached)")
@@ -172,23 +616,48 @@ func PrintCheckStagingHelp() {
 func PrintExplainHelp() {
 	fmt.Println("Docu-Jarvis - Explain Commit Mode")
 	fmt.Println("\nDescription:")
-	fmt.Println("  Provides an interactive AI-powered explanation of a specific commit.")
+	fmt.Println("  Provides an interactive AI-powered explanation of a specific commit, or")
+	fmt.Println("  of several commits at once, such as a whole feature branch.")
 	fmt.Println("  Have a conversation with Claude to understand what changed and why.")
 	fmt.Println("\nUsage:")
-	fmt.Println("  docu-jarvis -explain <commit-hash>")
-	fmt.Println("  docu-jarvis -explain <commit-hash> \"initial question\"")
+	fmt.Println("  docu-jarvis explain <commit-hash>")
+	fmt.Println("  docu-jarvis explain <commit-hash> \"initial question\"")
+	fmt.Println("  docu-jarvis explain -diff-deploys <prod-sha> <staging-sha> [\"initial question\"]")
 	fmt.Println("\nArguments:")
-	fmt.Println("  <commit-hash>       The commit hash (full or short)")
+	fmt.Println("  <commit-hash>       The commit hash (full or short), or several commits:")
+	fmt.Println("                        abc123..def456  the commits after abc123 up to def456")
+	fmt.Println("                        HEAD~N          the last N commits")
+	fmt.Println("                        <branch>        the branch's commits that are not on")
+	fmt.Println("                                        the selected branch")
+	fmt.Println("                      Ranges hold at most 50 commits")
 	fmt.Println("  \"initial question\"  Optional first question to ask")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -resume             Continue the saved conversation about the commits (see")
+	fmt.Println("                      'docu-jarvis conversations') instead of starting over")
+	fmt.Println("  -diff-deploys       Explain what differs between the commits deployed to")
+	fmt.Println("                      production and staging: every commit between them,")
+	fmt.Println("                      grouped by risk area (migrations, config, auth, ...)")
+	fmt.Println("  -branch <name>      Check out this branch as the codebase for context")
+	fmt.Println("                      (default: the 'branch' config key, then the default branch)")
+	fmt.Println("  -repo <name|url>    Use this configured repository (by name or URL) instead")
+	fmt.Println("                      of the first 'repo' in the config")
 	fmt.Println("\nExamples:")
 	fmt.Println("  # Get general explanation of a commit")
-	fmt.Println("  docu-jarvis -explain abc123")
+	fmt.Println("  docu-jarvis explain abc123")
 	fmt.Println()
 	fmt.Println("  # Start with a specific question")
-	fmt.Println("  docu-jarvis -explain abc123 \"What files were changed?\"")
-	fmt.Println("  docu-jarvis -explain abc123 \"Why was this refactoring needed?\"")
+	fmt.Println("  docu-jarvis explain abc123 \"What files were changed?\"")
+	fmt.Println("  docu-jarvis explain abc123 \"Why was this refactoring needed?\"")
+	fmt.Println()
+	fmt.Println("  # Explain a feature branch, or the last 5 commits")
+	fmt.Println("  docu-jarvis explain feature/billing \"How do these changes fit together?\"")
+	fmt.Println("  docu-jarvis explain HEAD~5")
+	fmt.Println()
+	fmt.Println("  # What's different between prod and staging right now?")
+	fmt.Println("  docu-jarvis explain -diff-deploys 1a2b3c4 5d6e7f8")
+	fmt.Println("  docu-jarvis explain -diff-deploys 1a2b3c4 5d6e7f8 \"Could any of these slow down checkout?\"")
 	fmt.Println("\nWhat it does:")
-	fmt.Println("  1. Clones your repository to /tmp")
+	fmt.Println("  1. Clones your repository to clone_dir (default /tmp)")
 	fmt.Println("  2. Fetches the commit details and diff")
 	fmt.Println("  3. Starts an interactive conversation with Claude AI")
 	fmt.Println("  4. Maintains conversation context for follow-up questions")
@@ -199,6 +668,15 @@ func PrintExplainHelp() {
 	fmt.Println("  - Request clarification on specific changes")
 	fmt.Println("  - Explore why certain decisions were made")
 	fmt.Println("  - Type 'exit' or 'quit' to end the conversation")
+	fmt.Println("\nCommands:")
+	fmt.Println("  /diff               Print the diff again")
+	fmt.Println("  /files              List the changed files")
+	fmt.Println("  /show <file>        Print a changed file as it is in the checkout (a path, or")
+	fmt.Println("                      the end of one, e.g. /show handler.go)")
+	fmt.Println("  /save <path>        Save the conversation as markdown")
+	fmt.Println("  /clear              Forget the conversation so far, in the saved one too,")
+	fmt.Println("                      and start over")
+	fmt.Println("  /help               List the commands")
 	fmt.Println("\nExample Conversation:")
 	fmt.Println("  You: What was the main change in this commit?")
 	fmt.Println("  Claude: This commit refactored the authentication system...")
@@ -208,3 +686,818 @@ func PrintExplainHelp() {
 	fmt.Println()
 }
 
+func PrintReviewChecklistHelp() {
+	fmt.Println("Docu-Jarvis - Review Checklist Mode")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Generates a reviewer checklist tailored to a specific change, pointing")
+	fmt.Println("  human reviewers at the risks that are easy to miss.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis review-checklist staged")
+	fmt.Println("  docu-jarvis review-checklist branch [base-branch]")
+	fmt.Println("  docu-jarvis review-checklist pr <pr-number>")
+	fmt.Println("\nSources:")
+	fmt.Println("  staged           Currently staged changes (git diff --cached)")
+	fmt.Println("  branch [base]    Changes on the current branch since base (default: main)")
+	fmt.Println("  pr <number>      Changes in an open pull request (requires gh)")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -post            Post the checklist as a PR comment (branch or pr only)")
+	fmt.Println("                   For branch, the PR for the current branch is used")
+	fmt.Println("\nExamples:")
+	fmt.Println("  docu-jarvis review-checklist staged")
+	fmt.Println("  docu-jarvis review-checklist branch develop")
+	fmt.Println("  docu-jarvis review-checklist -post pr 123")
+	fmt.Println("\nWhat it does:")
+	fmt.Println("  1. Collects the diff from the chosen source in the current repository")
+	fmt.Println("  2. Analyzes the change and its context with Claude AI")
+	fmt.Println("  3. Prints a markdown checklist of items for reviewers to verify")
+	fmt.Println("  4. Optionally posts the checklist as a comment on the PR")
+	fmt.Println()
+}
+
+func PrintReviewPRHelp() {
+	fmt.Println("Docu-Jarvis - Review PR Mode")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Reviews an open pull request against your configured code standards and")
+	fmt.Println("  posts the findings as a PR review, with inline comments on the lines they")
+	fmt.Println("  refer to.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis review-pr <pr-number>")
+	fmt.Println("  docu-jarvis review-pr <pr-url>")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -dry-run         Print the findings without posting a review")
+	fmt.Println("\nExamples:")
+	fmt.Println("  docu-jarvis review-pr 123")
+	fmt.Println("  docu-jarvis review-pr https://github.com/acme/api/pull/123 -dry-run")
+	fmt.Println("\nWhat it does:")
+	fmt.Println("  1. Fetches the PR's diff with the GitHub CLI (gh)")
+	fmt.Println("  2. Reviews it with Claude AI against the code_standards config")
+	fmt.Println("  3. Posts a review: findings on changed lines become inline comments,")
+	fmt.Println("     the rest are listed in the review body")
+	fmt.Println("\nNote:")
+	fmt.Println("  Run it from a checkout of the repository; Claude reads surrounding code")
+	fmt.Println("  from the working tree, which may differ from the PR's branch.")
+	fmt.Println()
+}
+
+func PrintTriageHelp() {
+	fmt.Println("Docu-Jarvis - Triage Mode")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Triages a GitHub issue: Claude reads the issue and its comments, looks for")
+	fmt.Println("  the code involved and the recent commits that changed it, and comments on")
+	fmt.Println("  the issue with the suspected root-cause areas, the related commits, and")
+	fmt.Println("  labels to apply, picked from the repository's labels.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis triage <issue-number>")
+	fmt.Println("  docu-jarvis triage <issue-url>")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -since <time>    How far back to look for related commits (default: 30 days")
+	fmt.Println("                   ago); takes the same forms as debug's dates")
+	fmt.Println("  -dry-run         Print the triage without commenting on the issue")
+	fmt.Println("  -scope <dir>     Only consider this directory's history")
+	fmt.Println("\nExamples:")
+	fmt.Println("  docu-jarvis triage 482")
+	fmt.Println("  docu-jarvis triage https://github.com/acme/api/issues/482 -dry-run")
+	fmt.Println("  docu-jarvis triage 482 -since \"2 weeks ago\"")
+	fmt.Println("\nNote:")
+	fmt.Println("  - Run it from a checkout of the repository; the issue is fetched and the")
+	fmt.Println("    comment posted with the GitHub CLI (gh)")
+	fmt.Println("  - Labels are suggested in the comment, not applied")
+	fmt.Println("  - At most the 200 latest commits are considered")
+	fmt.Println()
+}
+
+func PrintCheckCommitsHelp() {
+	fmt.Println("Docu-Jarvis - Check Commits Mode")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Validates every commit message in a range against your configured commit")
+	fmt.Println("  conventions and suggests rewrites for the ones that don't comply.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis check-commits <range>")
+	fmt.Println("  docu-jarvis check-commits <range> -fixup")
+	fmt.Println("\nArguments:")
+	fmt.Println("  <range>          A git revision range (e.g., 'main..HEAD')")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -fixup           Generate a git rebase todo that rewrites the non-compliant")
+	fmt.Println("                   messages and offer to run it; the range must end at")
+	fmt.Println("                   HEAD (e.g. 'main..HEAD'), as the rebase replays every")
+	fmt.Println("                   commit after it")
+	fmt.Println("\nSetting Up Conventions:")
+	fmt.Println("  Add 'commit_conventions = ...' lines to your config (docu-jarvis config).")
+	fmt.Println("  Conventional Commits are used when none are configured.")
+	fmt.Println("\nExamples:")
+	fmt.Println("  docu-jarvis check-commits main..HEAD")
+	fmt.Println("  docu-jarvis check-commits origin/main..HEAD -fixup")
+	fmt.Println("\nWhat it does:")
+	fmt.Println("  1. Collects the commit messages in the range from the current repository")
+	fmt.Println("  2. Checks each message against the conventions with Claude AI")
+	fmt.Println("  3. Shows violations and a suggested rewrite per commit")
+	fmt.Println("  4. With -fixup, rewrites the messages using git rebase")
+	fmt.Println("\nExit Status:")
+	fmt.Println("  Non-zero when any commit does not follow the conventions")
+	fmt.Println()
+}
+
+func PrintSquashSummaryHelp() {
+	fmt.Println("Docu-Jarvis - Squash Summary Mode")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Reads every commit on the current branch and writes the commit message")
+	fmt.Println("  to use when squash-merging it, following your commit conventions.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis squash-summary [base-branch]")
+	fmt.Println("\nArguments:")
+	fmt.Println("  [base-branch]    Branch the current branch will be merged into (default: main)")
+	fmt.Println("\nNote:")
+	fmt.Println("  - Uses 'commit_conventions' from your config, or Conventional Commits")
+	fmt.Println("  - Ticket references (e.g., PAY-123, #42) in the branch name and commits")
+	fmt.Println("    are included in the message")
+	fmt.Println("\nExamples:")
+	fmt.Println("  docu-jarvis squash-summary")
+	fmt.Println("  docu-jarvis squash-summary develop")
+	fmt.Println("\nWhat it does:")
+	fmt.Println("  1. Collects the commits on the current branch since the base branch")
+	fmt.Println("  2. Finds ticket references in the branch name and commit messages")
+	fmt.Println("  3. Combines the commits into a single message with Claude AI")
+	fmt.Println()
+}
+
+func PrintChangelogHelp() {
+	fmt.Println("Docu-Jarvis - Changelog Mode")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Generates release notes for the commits between two refs, grouped into")
+	fmt.Println("  breaking changes, features, bug fixes, and other changes.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis changelog <from-ref> <to-ref>")
+	fmt.Println("\nArguments:")
+	fmt.Println("  <from-ref>       Previous release (tag, branch, or commit), excluded")
+	fmt.Println("  <to-ref>         New release (tag, branch, or commit), included")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -stdout          Print the entry instead of writing it to a file")
+	fmt.Println("  -file <path>     Changelog file to prepend the entry to (default: CHANGELOG.md)")
+	fmt.Println("  -title <text>    Release heading for the entry (default: <to-ref>)")
+	fmt.Println("\nExamples:")
+	fmt.Println("  docu-jarvis changelog v2.1.0 v2.2.0")
+	fmt.Println("  docu-jarvis changelog v2.2.0 HEAD -title v2.3.0")
+	fmt.Println("  docu-jarvis changelog v2.1.0 v2.2.0 -stdout")
+	fmt.Println("\nWhat it does:")
+	fmt.Println("  1. Collects the commits between the two refs in the current repository")
+	fmt.Println("  2. Groups and summarizes the changes with Claude AI")
+	fmt.Println("  3. Adds the entry to the top of CHANGELOG.md (or prints it)")
+	fmt.Println()
+}
+
+func PrintEvalHelp() {
+	fmt.Println("Docu-Jarvis - Eval Mode")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Runs a suite of scenarios against the current prompts and models and scores")
+	fmt.Println("  the output, to catch regressions before a release. Each scenario is a")
+	fmt.Println("  directory of the suite with a scenario.json and a sample repository in")
+	fmt.Println("  repo/, which is copied for each run:")
+	fmt.Println("    {")
+	fmt.Println("      \"kind\": \"update-docs\",")
+	fmt.Println("      \"doc\": \"configuration.md\",")
+	fmt.Println("      \"expect\": {\"contains\": [\"BILLING_RETRY_LIMIT\"]}")
+	fmt.Println("    }")
+	fmt.Println("  Kinds:")
+	fmt.Println("    update-docs    Update \"doc\", relative to the docs directory")
+	fmt.Println("    write-docs     Write a doc about \"topic\"")
+	fmt.Println("    check-staging  Review the \"diff\" file against \"standards\"")
+	fmt.Println("    docs-impact    Find the docs the \"diff\" file requires updating")
+	fmt.Println("  Every scenario is checked for completing (or returning valid JSON) and for")
+	fmt.Println("  making no edits outside the docs directory (none at all for reviews); docs")
+	fmt.Println("  are checked for valid markdown. \"expect\" adds checks:")
+	fmt.Println("    sections       Headings the doc must have")
+	fmt.Println("    contains       Text the doc or report must have (ignoring case)")
+	fmt.Println("    not_contains   Text it must not have")
+	fmt.Println("    compliant      Whether the review must find the diff compliant")
+	fmt.Println("    min_findings   How many findings the review must have at least")
+	fmt.Println("    affected_docs  Docs the impact analysis must say need an update")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis eval [suite dir]")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -scenario <names> Run only these scenarios, comma-separated")
+	fmt.Println("  -model <name>     Run every scenario with this model instead of the")
+	fmt.Println("                    configured ones")
+	fmt.Println("  -save <file>      Write the report of this run to a file")
+	fmt.Println("  -baseline <file>  Compare with a saved report and fail only on checks that")
+	fmt.Println("                    passed in it")
+	fmt.Println("\nExamples:")
+	fmt.Println("  docu-jarvis eval")
+	fmt.Println("  docu-jarvis eval evals -scenario update-renamed-env-var")
+	fmt.Println("  docu-jarvis eval -save eval-1.4.0.json")
+	fmt.Println("  docu-jarvis eval -model claude-sonnet-4-5 -baseline eval-1.4.0.json")
+	fmt.Println("\nNote:")
+	fmt.Println("  - The suite directory defaults to evals, which has a sample suite")
+	fmt.Println("  - Scenarios call Claude and cost tokens like the commands they exercise")
+	fmt.Println("  - Without -baseline, eval fails when any check fails")
+	fmt.Println()
+}
+
+func PrintServeHelp() {
+	fmt.Println("Docu-Jarvis - Serve Mode")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Runs an HTTP server for GitHub push webhooks. When a push to a configured")
+	fmt.Println("  repository changes watched code, an update-docs run is queued for it: the")
+	fmt.Println("  docs the pushed commits affect are updated and a PR is opened.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis serve [flags]")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -addr <addr>     Address to listen on (default: localhost:8080); :8080")
+	fmt.Println("                   accepts connections from other hosts, as GitHub's do")
+	fmt.Println("  -paths <list>    Comma-separated directories or globs whose changes")
+	fmt.Println("                   trigger an update; overrides watch_paths (default: any)")
+	fmt.Println("  -dry-run         Show the docs updates without writing files or creating PRs")
+	fmt.Println("\nEndpoints:")
+	fmt.Println("  POST /webhook    The GitHub webhook (content type application/json)")
+	fmt.Println("  GET  /status     Server status and job counts")
+	fmt.Println("  GET  /jobs       Recent jobs, newest first")
+	fmt.Println("  GET  /jobs/<id>  One job")
+	fmt.Println("  The GET endpoints need the webhook secret as a bearer token")
+	fmt.Println("  (Authorization: Bearer <secret>).")
+	fmt.Println("\nSetting Up:")
+	fmt.Println("  Set webhook_secret in the config (or DOCU_JARVIS_WEBHOOK_SECRET, or")
+	fmt.Println("  'docu-jarvis auth login -provider webhook') to the secret of the webhook;")
+	fmt.Println("  deliveries without a valid X-Hub-Signature-256 are rejected. Only pushes")
+	fmt.Println("  to the configured branch, or the repository's default branch, are handled.")
+	fmt.Println("  Jobs run one at a time; pushes to a branch whose job has not started yet")
+	fmt.Println("  are added to it. Job history is kept in memory.")
+	fmt.Println("\nExamples:")
+	fmt.Println("  docu-jarvis serve")
+	fmt.Println("  docu-jarvis serve -addr :9000 -paths internal/api,cmd")
+	fmt.Println()
+}
+
+func PrintScheduleHelp() {
+	fmt.Println("Docu-Jarvis - Schedule")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Keeps recurring docu-jarvis runs, e.g. a weekly update-docs, in")
+	fmt.Println("  ~/.docu-jarvis/schedule.json. They are run by 'docu-jarvis daemon', or by")
+	fmt.Println("  cron, a systemd timer, or launchd from the definitions 'schedule export'")
+	fmt.Println("  prints. Jobs run in CI mode, in the directory they were added from, so")
+	fmt.Println("  they never wait for an answer and open their PRs without review.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis schedule add \"<cron>\" <command> [args...]")
+	fmt.Println("  docu-jarvis schedule list")
+	fmt.Println("  docu-jarvis schedule remove <id>")
+	fmt.Println("  docu-jarvis schedule export [-format crontab|systemd|launchd] [-dir <path>]")
+	fmt.Println("\nSchedules:")
+	fmt.Println("  Five cron fields in local time: minute, hour, day of month, month, and day")
+	fmt.Println("  of week (0 or 7 is Sunday), with *, lists, ranges, steps, and month and day")
+	fmt.Println("  names; or @hourly, @daily, @weekly, @monthly, or @yearly. Quote them.")
+	fmt.Println("\nExport Flags:")
+	fmt.Println("  -format <name>   crontab, systemd, or launchd (default: launchd on macOS,")
+	fmt.Println("                   crontab elsewhere)")
+	fmt.Println("  -dir <path>      Write the definitions to files in this directory and show")
+	fmt.Println("                   how to install them, instead of printing them")
+	fmt.Println("\nExamples:")
+	fmt.Println("  # Update all docs every Monday at 9:00")
+	fmt.Println("  docu-jarvis schedule add \"0 9 * * 1\" update-docs all")
+	fmt.Println()
+	fmt.Println("  # Audit the docs on the first of the month")
+	fmt.Println("  docu-jarvis schedule add @monthly audit-docs -local .")
+	fmt.Println()
+	fmt.Println("  # Install the schedule in your crontab")
+	fmt.Println("  (crontab -l; docu-jarvis schedule export) | crontab -")
+	fmt.Println()
+}
+
+func PrintDaemonHelp() {
+	fmt.Println("Docu-Jarvis - Daemon Mode")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Runs the jobs of 'docu-jarvis schedule' as they come due, one at a time, each")
+	fmt.Println("  as a docu-jarvis process of its own in CI mode. The schedule is read again")
+	fmt.Println("  every minute, so jobs added or removed meanwhile are picked up. Runs missed")
+	fmt.Println("  while another job was running are run once it finishes.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis daemon")
+	fmt.Println()
+}
+
+func PrintAuditDocsHelp() {
+	fmt.Println("Docu-Jarvis - Docs Audit Mode")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Scans the codebase (packages, exported APIs, routes, environment variables,")
+	fmt.Println("  and config keys) and the documentation, and reports what is undocumented,")
+	fmt.Println("  which docs reference code that no longer exists, and a prioritized list of")
+	fmt.Println("  topics to write. Nothing is modified.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis audit-docs")
+	fmt.Println("  docu-jarvis audit-docs -local <path>")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -local <path>    Audit an existing checkout instead of cloning (e.g., '.')")
+	fmt.Println("  -branch <name>   Clone and audit this branch")
+	fmt.Println("  -repo <name|url> Use this configured repository (by name or URL) instead of")
+	fmt.Println("                   the first 'repo' in the config")
+	fmt.Println("  -scope <dir>     Audit only this directory of the repository")
+	fmt.Println("  -docs-dir <dirs> Docs directories relative to the repository root, comma-")
+	fmt.Println("                   separated; overrides docs_roots")
+	fmt.Println("  -output json     Print the report as JSON on stdout (progress goes to stderr)")
+	fmt.Println("\nExamples:")
+	fmt.Println("  docu-jarvis audit-docs")
+	fmt.Println("  docu-jarvis audit-docs -local . -scope services/payments")
+	fmt.Println("  docu-jarvis audit-docs -output json > audit.json")
+	fmt.Println("\nWhat it does:")
+	fmt.Println("  1. Clones your repository to clone_dir (default /tmp), or uses -local")
+	fmt.Println("  2. Inventories the codebase and reads the documentation with Claude AI")
+	fmt.Println("  3. Reports coverage, undocumented areas, and stale references")
+	fmt.Println("  4. Suggests topics, high priority first, with the write-docs command to run")
+	fmt.Println()
+}
+
+func PrintDocsGapHelp() {
+	fmt.Println("Docu-Jarvis - Docs Gap Mode")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Compares the structure of the documentation to a reference information")
+	fmt.Println("  architecture with Claude AI, and reports which of its categories the docs")
+	fmt.Println("  cover, partly cover, or miss, with suggested topics to fill the gaps.")
+	fmt.Println("  Nothing is modified.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis docs-gap")
+	fmt.Println("  docu-jarvis docs-gap -template <preset|url|path>")
+	fmt.Println("\nTemplates:")
+	fmt.Println("  diataxis         Tutorials, how-to guides, reference, explanation (default)")
+	fmt.Println("  good-docs        The Good Docs Project: README, quickstart, installation,")
+	fmt.Println("                   concepts, reference, troubleshooting, contributing, ...")
+	fmt.Println("  service          Overview, architecture, API, configuration, deployment,")
+	fmt.Println("                   runbooks, and decision records of a backend service")
+	fmt.Println("  <url>            Another project's table of contents, e.g. the raw URL of")
+	fmt.Println("                   its SUMMARY.md, mkdocs.yml, or sidebars file")
+	fmt.Println("  <path>           A local table of contents file, or a docs directory whose")
+	fmt.Println("                   tree of markdown files is the reference")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -template <ref>  Reference layout (default: diataxis)")
+	fmt.Println("  -local <path>    Compare an existing checkout instead of cloning (e.g., '.')")
+	fmt.Println("  -branch <name>   Clone and compare this branch")
+	fmt.Println("  -repo <name|url> Use this configured repository (by name or URL) instead of")
+	fmt.Println("                   the first 'repo' in the config")
+	fmt.Println("  -scope <dir>     Compare only the docs roots in this directory")
+	fmt.Println("  -docs-dir <dirs> Docs directories relative to the repository root, comma-")
+	fmt.Println("                   separated; overrides docs_roots")
+	fmt.Println("  -output json     Print the report as JSON on stdout; progress goes to stderr")
+	fmt.Println("\nExamples:")
+	fmt.Println("  docu-jarvis docs-gap -local .")
+	fmt.Println("  docu-jarvis docs-gap -template good-docs")
+	fmt.Println("  docu-jarvis docs-gap -template https://raw.githubusercontent.com/org/project/main/mkdocs.yml")
+	fmt.Println("\nNote:")
+	fmt.Println("  - The report ends with the write-docs command for the suggested topics")
+	fmt.Println()
+}
+
+func PrintLintDocsHelp() {
+	fmt.Println("Docu-Jarvis - Lint Docs Mode")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Checks each document against your docs style guide with Claude AI: heading")
+	fmt.Println("  structure, required sections such as Overview and Examples, terminology, and")
+	fmt.Println("  formatting. Internal links to files and headings that do not exist are")
+	fmt.Println("  reported too. Prints a report per file; with -fix, Claude then fixes the")
+	fmt.Println("  findings and a PR is created.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis lint-docs <files>")
+	fmt.Println("  docu-jarvis lint-docs <files> -fix")
+	fmt.Println("\nArguments:")
+	fmt.Println("  all              Lint all .md and .mdx files in the docs roots")
+	fmt.Println("  <file.md>        Lint a specific file (e.g., 'api.md' or 'guides/setup.mdx')")
+	fmt.Println("  <file1,file2>    Lint multiple files (comma-separated)")
+	fmt.Println("  tag:<name>       Lint the docs tagged <name> in their front matter")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -fix             Fix the findings in place and create a PR")
+	fmt.Println("  -confirm-edits   With -fix, show each proposed edit and ask before applying it")
+	fmt.Println("  -auto-approve    With -fix, open the PR without reviewing each changed doc's diff")
+	fmt.Println("  -local <path>    Lint an existing checkout instead of cloning (e.g., '.')")
+	fmt.Println("  -branch <name>   Clone and lint this branch")
+	fmt.Println("  -repo <name|url> Use this configured repository (by name or URL) instead of")
+	fmt.Println("                   the first 'repo' in the config")
+	fmt.Println("  -scope <dir>     Lint only the docs roots in this directory")
+	fmt.Println("  -docs-dir <dirs> Docs directories relative to the repository root, comma-")
+	fmt.Println("                   separated; overrides docs_roots")
+	fmt.Println("  -concurrency <n> Lint at most n files at once (default: all of them)")
+	fmt.Println("  -no-tui          Print a line per started and finished file instead of the")
+	fmt.Println("                   progress dashboard shown on a terminal")
+	fmt.Println("  -output json     Print the report as JSON on stdout; progress goes to stderr")
+	fmt.Println("  -pr-title, -pr-body, -pr-base, -pr-labels, -pr-assignees, -pr-reviewers,")
+	fmt.Println("  -draft, -yes     PR settings for -fix, as for update-docs")
+	fmt.Println("\nStyle Guide:")
+	fmt.Println("  Add 'docs_style = ...' lines to your config (docu-jarvis config), one rule")
+	fmt.Println("  per line, or a docs_style list to .docu-jarvis.toml. Without them a default")
+	fmt.Println("  guide is used: one title, no skipped heading levels, Overview and Examples")
+	fmt.Println("  sections, consistent names, and formatted code.")
+	fmt.Println("\nExamples:")
+	fmt.Println("  docu-jarvis lint-docs all -local .")
+	fmt.Println("  docu-jarvis lint-docs api.md,setup.md -fix")
+	fmt.Println("  docu-jarvis lint-docs all -output json > lint.json")
+	fmt.Println("\nExit Status:")
+	fmt.Println("  Non-zero when errors remain or a document could not be linted; warnings")
+	fmt.Println("  alone do not fail")
+	fmt.Println()
+}
+
+func PrintArchiveDocsHelp() {
+	fmt.Println("Docu-Jarvis - Archive Docs Mode")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Moves deprecated docs to the archive folder of their docs root (e.g.,")
+	fmt.Println("  documentation/archive/) with a deprecation banner, and creates a PR. Links")
+	fmt.Println("  to them in the other docs are pointed to the new location, and index")
+	fmt.Println("  entries (README.md, index.md, SUMMARY.md) move to an 'Archived' section.")
+	fmt.Println("  update-docs proposes docs for archiving when their feature was removed.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis archive-docs <files>")
+	fmt.Println("  docu-jarvis archive-docs tag:<name> -reason \"why\"")
+	fmt.Println("\nArguments:")
+	fmt.Println("  <file.md>        Archive a specific file (e.g., 'legacy-api.md')")
+	fmt.Println("  <files>          Archive multiple files, comma-separated")
+	fmt.Println("  tag:<name>       Archive the docs tagged <name> in their front matter")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -reason <text>   Why the docs are archived, added to the banner")
+	fmt.Println("  -dry-run         Show what would move and which links change without")
+	fmt.Println("                   modifying files or creating a PR")
+	fmt.Println("  -local <path>    Use an existing checkout instead of cloning (e.g., '.')")
+	fmt.Println("  -branch <name>   Clone this branch and target it with the PR")
+	fmt.Println("  -repo <name|url> Use this configured repository (by name or URL) instead of")
+	fmt.Println("                   the first 'repo' in the config")
+	fmt.Println("  -scope <dir>     Restrict the docs roots to this directory")
+	fmt.Println("  -docs-dir <dirs> Docs directories relative to the repository root, comma-")
+	fmt.Println("                   separated; overrides docs_roots")
+	fmt.Println("  -pr-title, -pr-body, -pr-base, -pr-labels, -pr-assignees, -pr-reviewers,")
+	fmt.Println("  -draft, -yes     PR settings, as for update-docs")
+	fmt.Println("\nExamples:")
+	fmt.Println("  docu-jarvis archive-docs legacy-api.md -reason \"Replaced by the v2 API\"")
+	fmt.Println("  docu-jarvis archive-docs tag:v1 -local . -dry-run")
+	fmt.Println("\nNote:")
+	fmt.Println("  - Only links inside the docs roots are updated; the PR only touches docs")
+	fmt.Println("  - Archived docs are skipped by 'update-docs all' and 'lint-docs all'")
+	fmt.Println()
+}
+
+func PrintStaleDocsHelp() {
+	fmt.Println("Docu-Jarvis - Stale Docs Mode")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Lists the docs whose source files changed since they were generated.")
+	fmt.Println("  update-docs and write-docs stamp each doc they change with the source files")
+	fmt.Println("  Claude read for it, the commit it was generated from, and when, in its")
+	fmt.Println("  front matter:")
+	fmt.Println("    generated_at: 2024-06-01T09:30:00Z")
+	fmt.Println("    generated_from: 3f2c1a...")
+	fmt.Println("    sources:")
+	fmt.Println("      - internal/billing/invoice.go")
+	fmt.Println("  A doc is stale when a commit after that one changed one of its sources.")
+	fmt.Println("  Nothing is modified.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis stale-docs")
+	fmt.Println("  docu-jarvis stale-docs -local <path>")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -local <path>    Check an existing checkout instead of cloning (e.g., '.')")
+	fmt.Println("  -branch <name>   Clone and check this branch")
+	fmt.Println("  -repo <name|url> Use this configured repository (by name or URL) instead of")
+	fmt.Println("                   the first 'repo' in the config")
+	fmt.Println("  -scope <dir>     Check only the docs roots in this directory")
+	fmt.Println("  -docs-dir <dirs> Docs directories relative to the repository root, comma-")
+	fmt.Println("                   separated; overrides docs_roots")
+	fmt.Println("  -strict          Fail when any doc is stale, e.g. in CI")
+	fmt.Println("\nExamples:")
+	fmt.Println("  docu-jarvis stale-docs -local .")
+	fmt.Println("  docu-jarvis stale-docs -local . -strict")
+	fmt.Println("\nNote:")
+	fmt.Println("  - When a doc's commit is not in the branch's history, as after a squash")
+	fmt.Println("    merge, the commits after its generation time are checked instead")
+	fmt.Println("  - A stamped doc update-docs checks is stamped again even when Claude left it")
+	fmt.Println("    unchanged, so it stops being listed")
+	fmt.Println("  - Docs without a stamp are listed separately")
+	fmt.Println()
+}
+
+func PrintExportSiteHelp() {
+	fmt.Println("Docu-Jarvis - Export Site Mode")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Generates a ready-to-build static site for the documentation folder: the")
+	fmt.Println("  MkDocs or Docusaurus config with a nav of every doc, an index page when the")
+	fmt.Println("  folder has none, and a front-matter title for the docs without one. Run it")
+	fmt.Println("  again after docs are added or moved to update the nav.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis export-site -local . [-format mkdocs|docusaurus]")
+	fmt.Println("  docu-jarvis export-site -format docusaurus -pr")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -format <name>   mkdocs (default) writes mkdocs.yml at the repository root;")
+	fmt.Println("                   docusaurus writes the site to website/")
+	fmt.Println("  -name <title>    Site title (default: the repository name)")
+	fmt.Println("  -pr              Open a PR with the site and the docs' front matter")
+	fmt.Println("  -force           Replace site config files that export-site did not write")
+	fmt.Println("  -dry-run         List the files that would be written without writing them")
+	fmt.Println("  -local <path>    Write into an existing checkout instead of cloning (e.g., '.')")
+	fmt.Println("  -branch <name>   Clone this branch and target it with the PR")
+	fmt.Println("  -repo <name|url> Use this configured repository (by name or URL) instead of")
+	fmt.Println("                   the first 'repo' in the config")
+	fmt.Println("  -scope <dir>     Restrict the docs roots to this directory")
+	fmt.Println("  -docs-dir <dir>  The documentation folder to export; overrides docs_roots")
+	fmt.Println("  -pr-title, -pr-body, -pr-base, -pr-labels, -pr-assignees, -pr-reviewers,")
+	fmt.Println("  -draft, -yes     PR settings, as for update-docs")
+	fmt.Println("\nExamples:")
+	fmt.Println("  docu-jarvis export-site -local . -dry-run")
+	fmt.Println("  docu-jarvis export-site -local . -format docusaurus -name \"Billing Docs\"")
+	fmt.Println("  docu-jarvis export-site -format mkdocs -pr -pr-labels docs")
+	fmt.Println("\nNote:")
+	fmt.Println("  - Only the first docs root is exported; archived docs are left out of the nav")
+	fmt.Println("  - MkDocs builds .md files only; Docusaurus builds .md and .mdx")
+	fmt.Println("  - Without -pr or -dry-run, -local is required so the files land in your checkout")
+	fmt.Println()
+}
+
+func PrintCheckAssetsHelp() {
+	fmt.Println("Docu-Jarvis - Check Assets Mode")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Checks that every image the documentation references exists, and lists the")
+	fmt.Println("  screenshot placeholders update-docs and write-docs leave where a UI flow")
+	fmt.Println("  needs one:")
+	fmt.Println("    <!-- image: settings-page | The Settings page with SSO expanded -->")
+	fmt.Println("  A placeholder's image goes in the assets folder of its docs root, e.g.")
+	fmt.Println("  documentation/assets/settings-page.png (or .jpg, .gif, .svg, .webp).")
+	fmt.Println("  Nothing is modified.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis check-assets")
+	fmt.Println("  docu-jarvis check-assets -local <path>")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -local <path>    Check an existing checkout instead of cloning (e.g., '.')")
+	fmt.Println("  -branch <name>   Clone and check this branch")
+	fmt.Println("  -repo <name|url> Use this configured repository (by name or URL) instead of")
+	fmt.Println("                   the first 'repo' in the config")
+	fmt.Println("  -scope <dir>     Check only the docs roots in this directory")
+	fmt.Println("  -docs-dir <dirs> Docs directories relative to the repository root, comma-")
+	fmt.Println("                   separated; overrides docs_roots")
+	fmt.Println("  -strict          Also fail when placeholders have no image, e.g. in CI")
+	fmt.Println("\nExamples:")
+	fmt.Println("  docu-jarvis check-assets -local .")
+	fmt.Println("  docu-jarvis check-assets -local . -strict")
+	fmt.Println("\nNote:")
+	fmt.Println("  - Fails when a referenced image is missing; remote images are not checked")
+	fmt.Println("  - PRs from update-docs and write-docs include a checklist of the screenshots")
+	fmt.Println("    their docs need")
+	fmt.Println()
+}
+
+func PrintConfigHelp() {
+	fmt.Println("Docu-Jarvis - Config")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Opens the Docu-Jarvis config file in your editor ($EDITOR, $VISUAL, vim, or nano).")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis config")
+	fmt.Println("\nConfig file:")
+	fmt.Println("  ~/.docu-jarvis/config")
+	fmt.Println("\nPer-repository config:")
+	fmt.Println("  A .docu-jarvis.toml (or .docu-jarvis.yaml) in the repository root overrides")
+	fmt.Println("  docs_roots, code_standards, code_standards_source, commit_conventions,")
+	fmt.Println("  docs_style, base_branch, and the pr_* keys. Command-line flags override both files.")
+	fmt.Println("\nPull request templates:")
+	fmt.Println("  pr_title and pr_body (and -pr-title and -pr-body) can use these placeholders:")
+	fmt.Println("    {command}    update-docs or write-docs")
+	fmt.Println("    {repo}       Repository name")
+	fmt.Println("    {branch}     Branch with the changes")
+	fmt.Println("    {base}       Branch the PR targets")
+	fmt.Println("    {date}       Today, as YYYY-MM-DD")
+	fmt.Println("    {run_id}     update-docs run ID (see 'docu-jarvis runs')")
+	fmt.Println("    {succeeded}, {failed}, {total}")
+	fmt.Println("                 Number of documents or topics")
+	fmt.Println("    {summary}    Markdown list of the documents or topics and their result")
+	fmt.Println("  \\n starts a new line. The default body is the line 'Automated docu-jarvis")
+	fmt.Println("  suggestions' followed by {summary}.")
+	fmt.Println("\nModel providers:")
+	fmt.Println("  provider = claude-code   Run Claude through the Claude Code CLI (default)")
+	fmt.Println("  provider = anthropic     Call the Anthropic API with anthropic_api_key or")
+	fmt.Println("                           ANTHROPIC_API_KEY")
+	fmt.Println("  provider = bedrock       Call Claude on AWS Bedrock in aws_region (or AWS_REGION),")
+	fmt.Println("                           with credentials from the environment or the AWS CLI")
+	fmt.Println("  provider = vertex        Call Claude on Vertex AI in vertex_project and")
+	fmt.Println("                           vertex_region (default: us-east5), with credentials")
+	fmt.Println("                           from gcloud or GOOGLE_OAUTH_ACCESS_TOKEN")
+	fmt.Println("  provider = local         Call a local model (Ollama, vLLM, LM Studio) on the")
+	fmt.Println("                           OpenAI-compatible API at local_url (default:")
+	fmt.Println("                           http://localhost:11434/v1), with OPENAI_API_KEY if set.")
+	fmt.Println("                           local_tools = native uses the model's tool calling;")
+	fmt.Println("                           prompt (default) reads the files the request names")
+	fmt.Println("                           into the prompt and describes the tools in it instead")
+	fmt.Println("  model sets the model in the provider's naming. The API providers do not need")
+	fmt.Println("  the Claude Code CLI: docu-jarvis runs the file tools (Read, Write, Edit, Grep,")
+	fmt.Println("  Glob, LS) itself, limited to the workspace like Claude Code.")
+	fmt.Println("\nPer-mode model and limits (sections at the end of the config):")
+	fmt.Println("  [modes.debug]            The requests of one mode use this model, max_turns,")
+	fmt.Println("  model = claude-opus-4-1  and max_tokens per response (API providers only)")
+	fmt.Println("  max_turns = 40           instead of the defaults. Modes: update-docs,")
+	fmt.Println("                           write-docs, check-existing-docs, audit-docs, docs-gap,")
+	fmt.Println("                           lint-docs, debug, explain, review-code, docs-impact,")
+	fmt.Println("                           review-checklist, check-commits, squash-summary,")
+	fmt.Println("                           changelog, review-feedback, infer-standards, write-tests,")
+	fmt.Println("                           write-api-docs, compare-judge, triage")
+	fmt.Println()
+}
+
+func PrintAuthHelp() {
+	fmt.Println("Docu-Jarvis - Auth")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Keeps tokens in the OS keychain instead of in plaintext in")
+	fmt.Println("  ~/.docu-jarvis/config: the macOS Keychain, the Secret Service on Linux")
+	fmt.Println("  (through secret-tool, from libsecret), or the Windows Credential Manager.")
+	fmt.Println("  Tokens not in the keychain are still read from the config file, and the")
+	fmt.Println("  environment variables override both.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis auth login [-provider <name>]    Store a token, read from the")
+	fmt.Println("                                              terminal without echo or from stdin")
+	fmt.Println("  docu-jarvis auth logout [-provider <name>]   Remove a token from the keychain")
+	fmt.Println("  docu-jarvis auth status                      Show where each token comes from")
+	fmt.Println("\nProviders:")
+	fmt.Println("  github      github_token (or GITHUB_TOKEN), the default")
+	fmt.Println("  gitlab      gitlab_token (or GITLAB_TOKEN)")
+	fmt.Println("  bitbucket   bitbucket_token (or BITBUCKET_TOKEN)")
+	fmt.Println("  anthropic   anthropic_api_key (or ANTHROPIC_API_KEY)")
+	fmt.Println("  webhook     webhook_secret of 'docu-jarvis serve' (or DOCU_JARVIS_WEBHOOK_SECRET)")
+	fmt.Println("\nNotes:")
+	fmt.Println("  login comments out the token's plaintext line in the config file.")
+	fmt.Println("  The keys stored are listed in ~/.docu-jarvis/keychain, without their values.")
+	fmt.Println("\nExamples:")
+	fmt.Println("  docu-jarvis auth login")
+	fmt.Println("  docu-jarvis auth login -provider gitlab")
+	fmt.Println("  echo \"$TOKEN\" | docu-jarvis auth login -provider anthropic")
+	fmt.Println()
+}
+
+func PrintRunsHelp() {
+	fmt.Println("Docu-Jarvis - Runs")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Lists past update-docs runs and the result of each document. Every run")
+	fmt.Println("  (except dry runs) saves its state to ~/.docu-jarvis/runs/<id>.json as each")
+	fmt.Println("  document finishes, so failed or interrupted runs can be resumed.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis runs list          List runs, newest first")
+	fmt.Println("  docu-jarvis runs show <id>     Show each document's result and error")
+	fmt.Println("\nResuming:")
+	fmt.Println("  docu-jarvis update-docs -resume <id>")
+	fmt.Println("  Only the failed and pending documents are updated, in the run's checkout,")
+	fmt.Println("  which still holds the other documents' edits, and one PR is created for all")
+	fmt.Println("  of them. If the checkout is gone or a later run reused it, every document")
+	fmt.Println("  is updated again.")
+	fmt.Println("\nRolling Back:")
+	fmt.Println("  docu-jarvis rollback-run <id>")
+	fmt.Println("  Opens a PR that reverts the docs changes of a run's PR after it was merged.")
+	fmt.Println()
+}
+
+func PrintCacheHelp() {
+	fmt.Println("Docu-Jarvis - Cache")
+	fmt.Println("\nDescription:")
+	fmt.Println("  update-docs skips the docs whose content and sources are unchanged since it")
+	fmt.Println("  last updated them, with the same docu-jarvis version, prompt, and model, so")
+	fmt.Println("  repeated runs do not spend tokens on them again. After each run, it records")
+	fmt.Println("  in ~/.docu-jarvis/cache the source files Claude read for each doc and a hash")
+	fmt.Println("  of their content. Docs Claude read no sources for are never skipped.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis cache status       Show how many docs are cached")
+	fmt.Println("  docu-jarvis cache clear        Remove every entry, so all docs are updated again")
+	fmt.Println("\nBypassing:")
+	fmt.Println("  docu-jarvis update-docs -no-cache all")
+	fmt.Println("  Updates every doc, and refreshes their entries. Dry runs skip cached docs but")
+	fmt.Println("  record nothing, and -canary runs ignore the cache.")
+	fmt.Println()
+}
+
+func PrintConversationsHelp() {
+	fmt.Println("Docu-Jarvis - Conversations")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Lists, shows, and exports saved explain conversations. Every explain session")
+	fmt.Println("  is saved to ~/.docu-jarvis/conversations/<id>.json after each answer, where")
+	fmt.Println("  the ID is the commit, the range (first..last), or the two deploys")
+	fmt.Println("  (prod...staging) it explains, so exiting does not lose it.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis conversations list              List conversations, latest first")
+	fmt.Println("  docu-jarvis conversations show <id>         Print a conversation")
+	fmt.Println("  docu-jarvis conversations export <id>       Print it as markdown, to share")
+	fmt.Println("\nExport Flags:")
+	fmt.Println("  -format <format>  markdown (default) or json, the saved transcript")
+	fmt.Println("  -file <path>      Write the export to a file instead of stdout")
+	fmt.Println("\nResuming:")
+	fmt.Println("  docu-jarvis explain <commit> -resume [\"question\"]")
+	fmt.Println("  docu-jarvis explain -diff-deploys <prod-sha> <staging-sha> -resume")
+	fmt.Println("  Claude gets the whole conversation back and carries on where it stopped.")
+	fmt.Println("  Without -resume, explaining the same commits starts over and replaces the")
+	fmt.Println("  saved conversation.")
+	fmt.Println()
+}
+
+func PrintRollbackRunHelp() {
+	fmt.Println("Docu-Jarvis - Rollback Run")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Opens a PR that reverts the documentation changes of an update-docs run,")
+	fmt.Println("  for when the team decides the generated content was wrong after its PR")
+	fmt.Println("  was merged. Before opening its PR, every run saves the commit it started")
+	fmt.Println("  from and the patch of its docs changes to ~/.docu-jarvis/runs/<id>.json.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis rollback-run [flags] <id>")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -dry-run         Check that the changes still revert cleanly, and list the")
+	fmt.Println("                   files, without modifying files or creating a PR")
+	fmt.Println("  -pr-title, -pr-body, -pr-base, -pr-labels, -pr-assignees, -pr-reviewers,")
+	fmt.Println("  -draft, -yes     PR settings, as for update-docs")
+	fmt.Println("\nWhat it does:")
+	fmt.Println("  1. Clones the run's repository again (or uses its local checkout)")
+	fmt.Println("  2. Reverts the run's patch on the latest docs; if the docs were edited")
+	fmt.Println("     since in a way that conflicts, nothing is changed and it fails")
+	fmt.Println("  3. Opens a PR restoring the files to their state before the run")
+	fmt.Println("\nExamples:")
+	fmt.Println("  docu-jarvis runs list")
+	fmt.Println("  docu-jarvis rollback-run -dry-run 20261014-093012-4821")
+	fmt.Println("  docu-jarvis rollback-run 20261014-093012-4821")
+	fmt.Println()
+}
+
+func PrintLogsHelp() {
+	fmt.Println("Docu-Jarvis - Logs")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Every run logs what it asks Claude and what comes back to its own file,")
+	fmt.Println("  ~/.docu-jarvis/logs/docu-jarvis-<time>-<pid>.log, as JSON records with a")
+	fmt.Println("  level, a message, and details such as the mode, doc, and run ID. A file")
+	fmt.Println("  that grows past log_max_mb moves to a backup (.1.log, of 3 kept).")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis logs tail [flags] [file]")
+	fmt.Println("  Prints the last records of the latest run's log, or of the file given.")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -n <count>       Number of records to show (default: 50)")
+	fmt.Println("  -f               Keep printing records as they are written, until Ctrl+C")
+	fmt.Println("  -level <level>   Only show records at this level or above: debug, info,")
+	fmt.Println("                   warn, or error")
+	fmt.Println("  -json            Print the records as they are stored")
+	fmt.Println("\nVerbosity:")
+	fmt.Println("  -v               Also print every log record to stderr while a command runs")
+	fmt.Println("  -vv              Log debug records too, such as each message and tool use,")
+	fmt.Println("                   and print them to stderr")
+	fmt.Println("\nConfiguration (~/.docu-jarvis/config):")
+	fmt.Println("  log_level = info   Leave out records below this level: debug, info, warn,")
+	fmt.Println("                     or error (default: info)")
+	fmt.Println("  log_max_mb = 10    Size at which a run's log moves to a backup, 0 for never")
+	fmt.Println("                     (default: 10)")
+	fmt.Println("\nExamples:")
+	fmt.Println("  docu-jarvis -vv update-docs api.md")
+	fmt.Println("  docu-jarvis logs tail -f")
+	fmt.Println("  docu-jarvis logs tail -level warn -n 200")
+	fmt.Println()
+}
+
+func PrintPurgeHelp() {
+	fmt.Println("Docu-Jarvis - Purge")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Removes data docu-jarvis has kept longer than the retention settings allow.")
+	fmt.Println("  The same pruning runs automatically, at most once a day, when a command")
+	fmt.Println("  that calls Claude starts.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis purge")
+	fmt.Println("  docu-jarvis purge -all")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -all             Remove everything regardless of age: logs, run state, saved")
+	fmt.Println("                   conversations, usage history, the release and update")
+	fmt.Println("                   caches, the debug history, clones of the configured")
+	fmt.Println("                   repos, and Claude Code sessions run in them.")
+	fmt.Println("                   The config and the doc queue are kept")
+	fmt.Println("  -dry-run         List what would be removed without removing it")
+	fmt.Println("\nConfiguration (~/.docu-jarvis/config):")
+	fmt.Println("  retention_days = 30     Prune logs, run state, conversations, usage history,")
+	fmt.Println("                          clones, and sessions older than this; 0 keeps them")
+	fmt.Println("                          (default: 30)")
+	fmt.Println("  retention_log_mb = 50   Remove the oldest run logs beyond this total size;")
+	fmt.Println("                          0 for no limit (default: 50)")
+	fmt.Println("\nSessions:")
+	fmt.Println("  Claude Code keeps session transcripts, which contain the code it read, in")
+	fmt.Println("  ~/.claude/projects. Only the ones for directories inside docu-jarvis's clones")
+	fmt.Println("  are pruned; sessions for -local checkouts are left alone.")
+	fmt.Println()
+}
+
+func PrintUsageCommandHelp() {
+	fmt.Println("Docu-Jarvis - Usage")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Shows Claude token usage and cost for past runs, by day, command, and model.")
+	fmt.Println("  Every command that calls Claude prints a usage summary when it finishes and")
+	fmt.Println("  records it in ~/.docu-jarvis/usage.jsonl.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis usage")
+	fmt.Println("\nOptional Flags:")
+	fmt.Println("  -days <n>        Number of days of history to show (default: 30)")
+	fmt.Println("\nCosts:")
+	fmt.Println("  Costs are the ones reported by Claude Code. When a request has no reported")
+	fmt.Println("  cost, it is estimated from the model's list price and marked as estimated.")
+	fmt.Println()
+}
+
+func PrintVersionHelp() {
+	fmt.Println("Docu-Jarvis - Version")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Shows the installed version, how it was installed (homebrew, go install, or")
+	fmt.Println("  manual), and checks GitHub for a newer release.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis version")
+	fmt.Println()
+}
+
+func PrintUpdateHelp() {
+	fmt.Println("Docu-Jarvis - Update")
+	fmt.Println("\nDescription:")
+	fmt.Println("  Downloads the latest release and replaces the running binary. Homebrew and")
+	fmt.Println("  go install installs are upgraded with 'brew upgrade docu-jarvis' or")
+	fmt.Println("  'go install github.com/udemy/docu-jarvis-cli/cmd/docu-jarvis@latest' instead.")
+	fmt.Println("\nUsage:")
+	fmt.Println("  docu-jarvis update")
+	fmt.Println()
+}
@@ -0,0 +1,331 @@
+// Package links finds the internal links of markdown documents that point to
+// files or headings that do not exist, and rewrites links when documents move.
+package links
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var (
+	inlineLink     = regexp.MustCompile(`\[[^\]]*\]\(\s*<?([^)\s>]+)>?(?:\s+["'][^"']*["'])?\s*\)`)
+	referenceLink  = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s*<?(\S+?)>?(?:\s+["'(].*)?$`)
+	htmlLink       = regexp.MustCompile(`<a\s[^>]*href=["']([^"']+)["']`)
+	htmlAnchor     = regexp.MustCompile(`<[a-zA-Z][^>]*\s(?:id|name)=["']([^"']+)["']`)
+	headingPattern = regexp.MustCompile(`^ {0,3}#{1,6}\s+(.*?)(?:\s+#+)?\s*$`)
+	fencePattern   = regexp.MustCompile("^ {0,3}(```|~~~)")
+	codeSpan       = regexp.MustCompile("`+[^`]*`+")
+)
+
+// Broken is a link to a file or heading that does not exist.
+type Broken struct {
+	File   string // relative to the repository root
+	Line   int
+	Target string // as written in the document
+	Reason string
+}
+
+func (b Broken) String() string {
+	return fmt.Sprintf("%s:%d: %s (%s)", b.File, b.Line, b.Target, b.Reason)
+}
+
+// Check returns the broken internal links of a markdown file, given relative
+// to the repository root. Links with a scheme, such as https: or mailto:, are
+// not checked; paths starting with / are taken from the root.
+func Check(repoRoot, file string) ([]Broken, error) {
+	content, err := os.ReadFile(filepath.Join(repoRoot, file))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	anchorCache := map[string]map[string]bool{file: Anchors(string(content))}
+	var broken []Broken
+	inFence := false
+	for i, line := range strings.Split(string(content), "\n") {
+		if fencePattern.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		line = codeSpan.ReplaceAllString(line, "")
+
+		var targets []string
+		for _, m := range inlineLink.FindAllStringSubmatch(line, -1) {
+			targets = append(targets, m[1])
+		}
+		if m := referenceLink.FindStringSubmatch(line); m != nil {
+			targets = append(targets, m[1])
+		}
+		for _, m := range htmlLink.FindAllStringSubmatch(line, -1) {
+			targets = append(targets, m[1])
+		}
+
+		for _, target := range targets {
+			if reason := checkTarget(repoRoot, file, target, anchorCache); reason != "" {
+				broken = append(broken, Broken{File: file, Line: i + 1, Target: target, Reason: reason})
+			}
+		}
+	}
+	return broken, nil
+}
+
+// checkTarget returns why a link is broken, or "" when it is not or is not
+// checked.
+func checkTarget(repoRoot, file, target string, anchorCache map[string]map[string]bool) string {
+	if isExternal(target) {
+		return ""
+	}
+	path, anchor, _ := strings.Cut(target, "#")
+
+	linked := file
+	if path != "" {
+		linked = resolvePath(file, path)
+		info, err := os.Stat(filepath.Join(repoRoot, linked))
+		if err != nil {
+			return "file not found"
+		}
+		if info.IsDir() || !isMarkdown(linked) {
+			return ""
+		}
+	}
+	if anchor == "" {
+		return ""
+	}
+
+	anchors, ok := anchorCache[linked]
+	if !ok {
+		content, err := os.ReadFile(filepath.Join(repoRoot, linked))
+		if err != nil {
+			return ""
+		}
+		anchors = Anchors(string(content))
+		anchorCache[linked] = anchors
+	}
+	if unescaped, err := url.PathUnescape(anchor); err == nil {
+		anchor = unescaped
+	}
+	if !anchors[strings.ToLower(anchor)] {
+		return "no heading #" + anchor
+	}
+	return ""
+}
+
+// isExternal reports whether a link has a scheme or host.
+func isExternal(target string) bool {
+	if strings.HasPrefix(target, "//") {
+		return true
+	}
+	colon := strings.Index(target, ":")
+	return colon > 0 && !strings.ContainsAny(target[:colon], "/#?")
+}
+
+func isMarkdown(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".mdx", ".markdown":
+		return true
+	}
+	return false
+}
+
+// Anchors returns the anchors of a markdown document: the slugs GitHub gives
+// its headings, and the ids and names of its HTML elements.
+func Anchors(content string) map[string]bool {
+	anchors := make(map[string]bool)
+	for _, heading := range Headings(content) {
+		anchors[heading.Anchor] = true
+	}
+	inFence := false
+	for _, line := range strings.Split(content, "\n") {
+		if fencePattern.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		for _, m := range htmlAnchor.FindAllStringSubmatch(line, -1) {
+			anchors[strings.ToLower(m[1])] = true
+		}
+	}
+	return anchors
+}
+
+// Heading is a heading of a markdown document.
+type Heading struct {
+	Line   int // 1-based
+	Level  int
+	Anchor string // the slug GitHub gives it, numbered when repeated
+}
+
+// Headings returns the headings of a markdown document outside code blocks,
+// in order.
+func Headings(content string) []Heading {
+	var headings []Heading
+	seen := make(map[string]int)
+	inFence := false
+	for i, line := range strings.Split(content, "\n") {
+		if fencePattern.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		m := headingPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		slug := Slug(m[1])
+		anchor := slug
+		if n := seen[slug]; n > 0 {
+			anchor = fmt.Sprintf("%s-%d", slug, n)
+		}
+		seen[slug]++
+		level := len(strings.TrimSpace(line)) - len(strings.TrimLeft(strings.TrimSpace(line), "#"))
+		headings = append(headings, Heading{Line: i + 1, Level: level, Anchor: anchor})
+	}
+	return headings
+}
+
+var inlineMarkup = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)|<[^>]+>`)
+
+// Slug returns the anchor GitHub gives a heading: lowercase, with spaces
+// turned into hyphens and punctuation other than - and _ dropped.
+func Slug(heading string) string {
+	heading = inlineMarkup.ReplaceAllString(heading, "$1")
+	var slug strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(heading)) {
+		switch {
+		case r == ' ':
+			slug.WriteRune('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			slug.WriteRune(r)
+		}
+	}
+	return slug.String()
+}
+
+// Retarget rewrites the links of a document at file that point to from so
+// they point to to; all three are relative to the repository root. It returns
+// the new content and the number of links rewritten.
+func Retarget(content, file, from, to string) (string, int) {
+	return rewrite(content, func(target string) (string, bool) {
+		path, anchor, found := strings.Cut(target, "#")
+		if path == "" || isExternal(target) || resolvePath(file, path) != from {
+			return "", false
+		}
+		if strings.HasPrefix(path, "/") {
+			path = "/" + to
+		} else {
+			path = relativePath(file, to)
+		}
+		if found {
+			path += "#" + anchor
+		}
+		return path, true
+	})
+}
+
+// Rebase rewrites the relative links of a document moved from oldFile to
+// newFile so they still point to the same files.
+func Rebase(content, oldFile, newFile string) string {
+	rebased, _ := rewrite(content, func(target string) (string, bool) {
+		path, anchor, found := strings.Cut(target, "#")
+		if path == "" || isExternal(target) || strings.HasPrefix(path, "/") {
+			return "", false
+		}
+		resolved := resolvePath(oldFile, path)
+		if resolved == oldFile {
+			resolved = newFile
+		}
+		path = relativePath(newFile, resolved)
+		if found {
+			path += "#" + anchor
+		}
+		return path, true
+	})
+	return rebased
+}
+
+// rewrite replaces the link targets of a document, outside code, for which
+// replace returns true.
+func rewrite(content string, replace func(target string) (string, bool)) (string, int) {
+	lines := strings.Split(content, "\n")
+	changed := 0
+	inFence := false
+	for i, line := range lines {
+		if fencePattern.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		code := codeSpan.FindAllStringIndex(line, -1)
+		inCode := func(pos int) bool {
+			for _, span := range code {
+				if pos >= span[0] && pos < span[1] {
+					return true
+				}
+			}
+			return false
+		}
+
+		var spans [][]int
+		for _, m := range inlineLink.FindAllStringSubmatchIndex(line, -1) {
+			spans = append(spans, m[2:4])
+		}
+		if m := referenceLink.FindStringSubmatchIndex(line); m != nil {
+			spans = append(spans, m[2:4])
+		}
+		for _, m := range htmlLink.FindAllStringSubmatchIndex(line, -1) {
+			spans = append(spans, m[2:4])
+		}
+
+		// Replaced from the end so earlier offsets stay valid
+		sort.Slice(spans, func(a, b int) bool { return spans[a][0] > spans[b][0] })
+		for _, span := range spans {
+			if inCode(span[0]) {
+				continue
+			}
+			if target, ok := replace(line[span[0]:span[1]]); ok && target != line[span[0]:span[1]] {
+				line = line[:span[0]] + target + line[span[1]:]
+				changed++
+			}
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n"), changed
+}
+
+// resolvePath returns the file a link path points to, relative to the
+// repository root.
+func resolvePath(file, path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		path = unescaped
+	}
+	if strings.HasPrefix(path, "/") {
+		return filepath.ToSlash(filepath.Clean(strings.TrimPrefix(path, "/")))
+	}
+	return filepath.ToSlash(filepath.Join(filepath.Dir(file), path))
+}
+
+// relativePath returns the link from the document at file to target.
+func relativePath(file, target string) string {
+	rel, err := filepath.Rel(filepath.Dir(file), target)
+	if err != nil {
+		return target
+	}
+	rel = filepath.ToSlash(rel)
+	return strings.ReplaceAll(rel, " ", "%20")
+}
@@ -0,0 +1,240 @@
+// Package logging writes the docu-jarvis log: JSON records (log/slog) in one
+// file per run in ~/.docu-jarvis/logs, rotated when it grows past log_max_mb,
+// at the log_level of the config. -v also prints the records to stderr, and
+// -vv adds the debug records to both.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/udemy/docu-jarvis-cli/internal/redact"
+	"github.com/udemy/docu-jarvis-cli/internal/settings"
+)
+
+// Prefix starts the name of every run's log file, as in
+// docu-jarvis-20261014-093012-4821.log.
+const Prefix = "docu-jarvis-"
+
+// maxBackups is how many rotated files a run's log keeps, as
+// docu-jarvis-<id>.1.log (the newest) to docu-jarvis-<id>.3.log.
+const maxBackups = 3
+
+// Logger writes records to the run's log. The printf-style methods are for
+// messages without details worth filtering on; pass those as slog attributes.
+type Logger struct {
+	*slog.Logger
+}
+
+func (l *Logger) Debugf(format string, args ...any) { l.logf(slog.LevelDebug, format, args...) }
+func (l *Logger) Infof(format string, args ...any)  { l.logf(slog.LevelInfo, format, args...) }
+func (l *Logger) Warnf(format string, args ...any)  { l.logf(slog.LevelWarn, format, args...) }
+func (l *Logger) Errorf(format string, args ...any) { l.logf(slog.LevelError, format, args...) }
+
+func (l *Logger) logf(level slog.Level, format string, args ...any) {
+	if l.Enabled(context.Background(), level) {
+		l.Log(context.Background(), level, fmt.Sprintf(format, args...))
+	}
+}
+
+// With returns a logger that adds the attributes to every record.
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{l.Logger.With(args...)}
+}
+
+var (
+	verbosity int
+	openOnce  sync.Once
+	handler   slog.Handler
+	openErr   error
+	path      string
+)
+
+// SetVerbosity sets how much is printed to stderr: 1 for -v, the info records
+// and above, 2 for -vv, the debug records too. Call it before the first New.
+func SetVerbosity(v int) {
+	verbosity = v
+}
+
+// New returns a logger of the run's log, which is opened the first time.
+func New() (*Logger, error) {
+	openOnce.Do(open)
+	if openErr != nil {
+		return nil, openErr
+	}
+	return &Logger{slog.New(handler)}, nil
+}
+
+// Path returns the run's log file, or "" before New.
+func Path() string {
+	return path
+}
+
+func open() {
+	level, maxSize := slog.LevelInfo, int64(settings.DefaultLogMaxMB)<<20
+	if s, err := settings.Load(); err == nil {
+		level, _ = ParseLevel(s.LogLevel)
+		maxSize = int64(s.LogMaxMB) << 20
+	}
+	if verbosity >= 2 {
+		level = slog.LevelDebug
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		openErr = err
+		return
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		openErr = fmt.Errorf("failed to create log directory: %w", err)
+		return
+	}
+	now := time.Now()
+	path = filepath.Join(dir, fmt.Sprintf("%s%s-%d.log", Prefix, now.Format("20060102-150405"), os.Getpid()%10000))
+	file, err := openRotating(path, maxSize)
+	if err != nil {
+		openErr = err
+		return
+	}
+
+	// Prompts and tool output can contain tokens and personal data
+	handlers := []slog.Handler{slog.NewJSONHandler(redact.Writer(file), &slog.HandlerOptions{Level: level})}
+	if verbosity >= 1 {
+		handlers = append(handlers, slog.NewTextHandler(redact.Writer(os.Stderr), &slog.HandlerOptions{Level: level}))
+	}
+	handler = tee(handlers)
+}
+
+// Dir returns the log directory.
+func Dir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".docu-jarvis", "logs"), nil
+}
+
+// ParseLevel reads a log_level: debug, info, warn, or error. An invalid level
+// is info.
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("invalid log level %q (must be debug, info, warn, or error)", level)
+}
+
+// tee sends each record to every handler that takes its level.
+type tee []slog.Handler
+
+func (t tee) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t tee) Handle(ctx context.Context, r slog.Record) error {
+	var firstErr error
+	for _, h := range t {
+		if h.Enabled(ctx, r.Level) {
+			if err := h.Handle(ctx, r.Clone()); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func (t tee) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(tee, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (t tee) WithGroup(name string) slog.Handler {
+	handlers := make(tee, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
+
+// rotatingFile is a log file that is moved aside, to the first backup, when a
+// write would take it past maxSize.
+type rotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64 // 0 never rotates
+	file    *os.File
+	size    int64
+}
+
+func openRotating(path string, maxSize int64) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, maxSize: maxSize}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to create log file: %w", err)
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *rotatingFile) rotate() error {
+	f.file.Close()
+	// Renaming onto an existing file fails on Windows
+	os.Remove(Backup(f.path, maxBackups))
+	for i := maxBackups - 1; i >= 1; i-- {
+		os.Rename(Backup(f.path, i), Backup(f.path, i+1))
+	}
+	// When the file cannot be moved, it keeps growing
+	os.Rename(f.path, Backup(f.path, 1))
+	return f.open()
+}
+
+// Backup returns the path of a log file's nth backup.
+func Backup(path string, n int) string {
+	return fmt.Sprintf("%s.%d.log", strings.TrimSuffix(path, ".log"), n)
+}
@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Record is a record of a log file, as read back.
+type Record struct {
+	Time  time.Time
+	Level slog.Level
+	Msg   string
+	Attrs map[string]any
+}
+
+// ParseRecord reads a line of a log file. Lines from before the log was
+// structured are not records.
+func ParseRecord(line string) (Record, bool) {
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return Record{}, false
+	}
+
+	var r Record
+	if t, ok := fields[slog.TimeKey].(string); ok {
+		r.Time, _ = time.Parse(time.RFC3339Nano, t)
+	}
+	if level, ok := fields[slog.LevelKey].(string); ok {
+		if err := r.Level.UnmarshalText([]byte(level)); err != nil {
+			return Record{}, false
+		}
+	}
+	r.Msg, _ = fields[slog.MessageKey].(string)
+	delete(fields, slog.TimeKey)
+	delete(fields, slog.LevelKey)
+	delete(fields, slog.MessageKey)
+	r.Attrs = fields
+	return r, !r.Time.IsZero()
+}
+
+// String formats the record for reading, with its attributes by name.
+func (r Record) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %-5s %s", r.Time.Local().Format("2006-01-02 15:04:05"), r.Level, r.Msg)
+
+	keys := make([]string, 0, len(r.Attrs))
+	for key := range r.Attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := fmt.Sprint(r.Attrs[key])
+		if strings.ContainsAny(value, " \t\n\"") {
+			value = fmt.Sprintf("%q", value)
+		}
+		fmt.Fprintf(&b, " %s=%s", key, value)
+	}
+	return b.String()
+}
+
+var backupName = regexp.MustCompile(`\.\d+\.log$`)
+
+// Files returns the runs' log files, latest first, without their backups.
+func Files() ([]string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, Prefix+"*.log"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list log files: %w", err)
+	}
+
+	modified := make(map[string]time.Time, len(matches))
+	var files []string
+	for _, match := range matches {
+		if backupName.MatchString(match) {
+			continue
+		}
+		if info, err := os.Stat(match); err == nil {
+			modified[match] = info.ModTime()
+			files = append(files, match)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return modified[files[i]].After(modified[files[j]])
+	})
+	return files, nil
+}
@@ -0,0 +1,254 @@
+// Package mdlint checks the structure of markdown documents so that they
+// render: heading levels, table syntax, closed code blocks, and front matter.
+// The problems that have one sure fix are fixed by Fix.
+package mdlint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Problem is a structural error in a document.
+type Problem struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+}
+
+var (
+	fencePattern     = regexp.MustCompile("^ {0,3}(```+|~~~+)")
+	headingPattern   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+|$)`)
+	delimiterPattern = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	frontKeyPattern  = regexp.MustCompile(`^[A-Za-z0-9_."'-][^:]*:(\s|$)`)
+)
+
+// doc is a document split into lines, with the front matter, if any, ahead
+// of body.
+type doc struct {
+	file  string
+	lines []string
+	body  int // index of the first line after the front matter
+}
+
+func parse(file, content string) (*doc, []Problem) {
+	d := &doc{file: file, lines: strings.Split(content, "\n")}
+	if strings.TrimSpace(strings.TrimPrefix(d.lines[0], "\ufeff")) != "---" {
+		return d, nil
+	}
+	for i := 1; i < len(d.lines); i++ {
+		if trimmed := strings.TrimSpace(d.lines[i]); trimmed == "---" || trimmed == "..." {
+			d.body = i + 1
+			return d, d.checkFrontMatter(d.lines[1:i])
+		}
+	}
+	return d, []Problem{{file, 1, "front matter is not closed with ---"}}
+}
+
+// checkFrontMatter checks the YAML of the front matter for the mistakes that
+// make site generators reject it. It is not a full YAML parser.
+func (d *doc) checkFrontMatter(lines []string) []Problem {
+	var problems []Problem
+	keys := make(map[string]bool)
+	for i, line := range lines {
+		number := i + 2
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+		case strings.HasPrefix(line, "\t"):
+			problems = append(problems, Problem{d.file, number, "front matter is indented with a tab"})
+			continue
+		case strings.HasPrefix(line, " "):
+			// a nested value or list item
+			continue
+		case !frontKeyPattern.MatchString(line):
+			problems = append(problems, Problem{d.file, number, fmt.Sprintf("front matter line %q is not \"key: value\"", trimmed)})
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, ":")
+		key = strings.Trim(strings.TrimSpace(key), `"'`)
+		if keys[key] {
+			problems = append(problems, Problem{d.file, number, fmt.Sprintf("front matter repeats the key %q", key)})
+		}
+		keys[key] = true
+
+		value = strings.TrimSpace(value)
+		for _, quote := range []string{`"`, "'"} {
+			if strings.HasPrefix(value, quote) && (len(value) == 1 || !strings.HasSuffix(value, quote)) {
+				problems = append(problems, Problem{d.file, number, fmt.Sprintf("front matter value of %q has an unclosed quote", key)})
+			}
+		}
+	}
+	return problems
+}
+
+// Check returns the structural problems of a document.
+func Check(file, content string) []Problem {
+	_, problems := fix(file, content)
+	return problems
+}
+
+// Fix returns the document with skipped heading levels, unclosed code blocks,
+// and tables with a missing or mismatched delimiter row fixed, and the
+// problems it could not fix.
+func Fix(file, content string) (string, []Problem) {
+	fixed, _ := fix(file, content)
+	_, problems := fix(file, fixed)
+	return fixed, problems
+}
+
+// fix returns the fixed document and every problem of the original.
+func fix(file, content string) (string, []Problem) {
+	d, problems := parse(file, content)
+	lines := d.lines
+
+	fence, fenceLine := "", 0
+	level := 0
+	for i := d.body; i < len(lines); i++ {
+		line := lines[i]
+		if fence != "" {
+			if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if m := fencePattern.FindStringSubmatch(line); m != nil {
+			fence, fenceLine = m[1], i+1
+			continue
+		}
+
+		if m := headingPattern.FindStringSubmatch(line); m != nil {
+			found := len(m[1])
+			if level > 0 && found > level+1 {
+				problems = append(problems, Problem{file, i + 1, fmt.Sprintf("heading level jumps from %d to %d", level, found)})
+				lines[i] = strings.Replace(line, m[1], strings.Repeat("#", level+1), 1)
+				found = level + 1
+			}
+			level = found
+			continue
+		}
+
+		if isTableRow(line) && (i == d.body || strings.TrimSpace(lines[i-1]) == "") {
+			var tableProblems []Problem
+			lines, tableProblems = fixTable(file, lines, i)
+			problems = append(problems, tableProblems...)
+			for i+1 < len(lines) && isTableRow(lines[i+1]) {
+				i++
+			}
+		}
+	}
+
+	if fence != "" {
+		problems = append(problems, Problem{file, fenceLine, "code block is not closed"})
+		if strings.TrimSpace(lines[len(lines)-1]) == "" {
+			lines = append(lines[:len(lines)-1], fence, "")
+		} else {
+			lines = append(lines, fence)
+		}
+	}
+	return strings.Join(lines, "\n"), problems
+}
+
+func isTableRow(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "|") && len(trimmed) > 1
+}
+
+// fixTable checks the table whose header row is lines[start]. A missing
+// delimiter row is added, and one with the wrong number of cells rebuilt, as
+// neither renders as a table; rows with more cells than the header lose them
+// when rendered, which has no sure fix.
+func fixTable(file string, lines []string, start int) ([]string, []Problem) {
+	if start+1 >= len(lines) || !isTableRow(lines[start+1]) {
+		return lines, nil
+	}
+	var problems []Problem
+	columns := len(cells(lines[start]))
+
+	delimiter := lines[start+1]
+	if !delimiterPattern.MatchString(delimiter) {
+		problems = append(problems, Problem{file, start + 1, "table has no delimiter row (| --- |) after its header"})
+		lines = append(lines[:start+1], append([]string{delimiterRow(nil, columns)}, lines[start+1:]...)...)
+	} else if n := len(cells(delimiter)); n != columns {
+		problems = append(problems, Problem{file, start + 2, fmt.Sprintf("table delimiter row has %d cells, its header %d", n, columns)})
+		lines[start+1] = delimiterRow(cells(delimiter), columns)
+	}
+
+	for i := start + 2; i < len(lines) && isTableRow(lines[i]); i++ {
+		if n := len(cells(lines[i])); n > columns {
+			problems = append(problems, Problem{file, i + 1, fmt.Sprintf("table row has %d cells, its header %d", n, columns)})
+		}
+	}
+	return lines, problems
+}
+
+// delimiterRow builds a delimiter row of columns cells, keeping the alignment
+// of the existing ones.
+func delimiterRow(existing []string, columns int) string {
+	row := make([]string, columns)
+	for i := range row {
+		row[i] = "---"
+		if i < len(existing) {
+			row[i] = existing[i]
+		}
+	}
+	return "| " + strings.Join(row, " | ") + " |"
+}
+
+// cells splits a table row on the pipes that are not escaped. As in GFM, code
+// spans do not protect pipes.
+func cells(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, `\|`) {
+		row = row[:len(row)-1]
+	}
+
+	var result []string
+	var cell strings.Builder
+	for i := 0; i < len(row); i++ {
+		switch c := row[i]; {
+		case c == '\\' && i+1 < len(row):
+			cell.WriteByte(c)
+			cell.WriteByte(row[i+1])
+			i++
+		case c == '|':
+			result = append(result, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(c)
+		}
+	}
+	return append(result, strings.TrimSpace(cell.String()))
+}
+
+// FixFiles fixes the documents, given relative to root, in place. It returns
+// the documents it changed and the problems it could not fix.
+func FixFiles(root string, files []string) ([]string, []Problem, error) {
+	var changed []string
+	var problems []Problem
+	for _, file := range files {
+		path := filepath.Join(root, file)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		fixed, left := Fix(file, string(content))
+		if fixed != string(content) {
+			if err := os.WriteFile(path, []byte(fixed), 0644); err != nil {
+				return nil, nil, fmt.Errorf("failed to write %s: %w", file, err)
+			}
+			changed = append(changed, file)
+		}
+		problems = append(problems, left...)
+	}
+	return changed, problems, nil
+}
@@ -0,0 +1,71 @@
+package netguard
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// EnvVar turns the guard on for every run, like -no-network.
+const EnvVar = "DOCU_JARVIS_NO_NETWORK"
+
+var disabled = os.Getenv(EnvVar) != "" && os.Getenv(EnvVar) != "0"
+
+// Disable turns off network access for the rest of the run. Claude Code,
+// which inherits the environment, is told to skip its own non-essential
+// traffic (telemetry, update checks).
+func Disable() {
+	disabled = true
+	os.Setenv("CLAUDE_CODE_DISABLE_NONESSENTIAL_TRAFFIC", "1")
+}
+
+func Disabled() bool {
+	return disabled
+}
+
+// Check fails when network access is disabled. what describes the operation
+// that needs it, e.g. "cloning the repository".
+func Check(what string) error {
+	if !disabled {
+		return nil
+	}
+	return fmt.Errorf("%s needs network access, which is disabled by -no-network (or %s)", what, EnvVar)
+}
+
+// CheckClaude fails when network access is disabled and Claude Code would
+// reach a remote API, i.e. unless ANTHROPIC_BASE_URL points at a model
+// backend on this machine.
+func CheckClaude() error {
+	if !disabled {
+		return nil
+	}
+	if base := os.Getenv("ANTHROPIC_BASE_URL"); base != "" && isLoopback(base) {
+		return nil
+	}
+	return fmt.Errorf("calling Claude needs network access, which is disabled by -no-network (or %s); "+
+		"set ANTHROPIC_BASE_URL to a model backend on localhost to run without it", EnvVar)
+}
+
+// CheckLocal fails when network access is disabled and rawURL is not on this
+// machine.
+func CheckLocal(what, rawURL string) error {
+	if !disabled || isLoopback(rawURL) {
+		return nil
+	}
+	return fmt.Errorf("%s at %s needs network access, which is disabled by -no-network (or %s)", what, rawURL, EnvVar)
+}
+
+func isLoopback(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
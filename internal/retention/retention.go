@@ -0,0 +1,468 @@
+package retention
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pruneInterval is how often the automatic pruning on startup runs.
+const pruneInterval = 24 * time.Hour
+
+// logTimeLayout is the timestamp log.LstdFlags put at the start of each line,
+// before the log was made of JSON records.
+const logTimeLayout = "2006/01/02 15:04:05"
+
+// Policy limits how long docu-jarvis keeps what it writes. Zero values keep
+// data forever.
+type Policy struct {
+	MaxAge     time.Duration
+	MaxLogSize int64 // bytes
+}
+
+// Removal is one file or directory that was (or, in a dry run, would be)
+// removed or trimmed.
+type Removal struct {
+	Kind string
+	Path string
+	Size int64 // bytes freed
+}
+
+type Report struct {
+	Removals []Removal
+}
+
+// Freed is the total size freed.
+func (r *Report) Freed() int64 {
+	var total int64
+	for _, removal := range r.Removals {
+		total += removal.Size
+	}
+	return total
+}
+
+func (r *Report) add(kind, path string, size int64) {
+	r.Removals = append(r.Removals, Removal{Kind: kind, Path: path, Size: size})
+}
+
+// Store is where docu-jarvis keeps data: its own directory, the clones of the
+// configured repositories, and the Claude Code projects directory that holds
+// the session transcripts of runs in those clones.
+type Store struct {
+	Dir       string // ~/.docu-jarvis
+	Clones    []string
+	ClaudeDir string // ~/.claude/projects
+	dryRun    bool
+	now       time.Time
+	report    *Report
+}
+
+// NewStore returns the store in the user's home directory.
+func NewStore(clones []string) (*Store, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return &Store{
+		Dir:       filepath.Join(homeDir, ".docu-jarvis"),
+		Clones:    clones,
+		ClaudeDir: filepath.Join(homeDir, ".claude", "projects"),
+	}, nil
+}
+
+// PruneIfDue applies the policy when it has not been applied in the last
+// pruneInterval, so startup stays fast.
+func (s *Store) PruneIfDue(p Policy) (*Report, error) {
+	stamp := filepath.Join(s.Dir, "last_prune")
+	if info, err := os.Stat(stamp); err == nil && time.Since(info.ModTime()) < pruneInterval {
+		return &Report{}, nil
+	}
+
+	report, err := s.Prune(p, false)
+	if err != nil {
+		return report, err
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err == nil {
+		os.WriteFile(stamp, []byte(time.Now().Format(time.RFC3339)+"\n"), 0644)
+	}
+	return report, nil
+}
+
+// Prune removes data older than the policy's age and keeps the logs within
+// its size. With dryRun, nothing is changed and the report lists what would be.
+func (s *Store) Prune(p Policy, dryRun bool) (*Report, error) {
+	s.dryRun, s.now, s.report = dryRun, time.Now(), &Report{}
+
+	var cutoff time.Time
+	if p.MaxAge > 0 {
+		cutoff = s.now.Add(-p.MaxAge)
+	}
+
+	logs, _ := filepath.Glob(filepath.Join(s.Dir, "logs", "*.log"))
+	for _, path := range logs {
+		if err := s.trimLog(path, cutoff, 0); err != nil {
+			return s.report, err
+		}
+	}
+	if err := s.capLogs(logs, p.MaxLogSize); err != nil {
+		return s.report, err
+	}
+
+	if cutoff.IsZero() {
+		return s.report, nil
+	}
+
+	runs, _ := filepath.Glob(filepath.Join(s.Dir, "runs", "*.json"))
+	for _, path := range runs {
+		if info, err := os.Stat(path); err == nil && info.ModTime().Before(cutoff) {
+			if err := s.remove("run state", path); err != nil {
+				return s.report, err
+			}
+		}
+	}
+
+	saved, _ := filepath.Glob(filepath.Join(s.Dir, "conversations", "*.json"))
+	for _, path := range saved {
+		if info, err := os.Stat(path); err == nil && info.ModTime().Before(cutoff) {
+			if err := s.remove("conversation", path); err != nil {
+				return s.report, err
+			}
+		}
+	}
+
+	if err := s.trimUsage(filepath.Join(s.Dir, "usage.jsonl"), cutoff); err != nil {
+		return s.report, err
+	}
+
+	for _, clone := range s.Clones {
+		if info, err := os.Stat(clone); err == nil && info.IsDir() && lastUsed(clone).Before(cutoff) {
+			if err := s.remove("clone", clone); err != nil {
+				return s.report, err
+			}
+		}
+	}
+
+	for _, project := range s.sessionProjects() {
+		sessions, _ := filepath.Glob(filepath.Join(project, "*.jsonl"))
+		for _, path := range sessions {
+			if info, err := os.Stat(path); err == nil && info.ModTime().Before(cutoff) {
+				if err := s.remove("session", path); err != nil {
+					return s.report, err
+				}
+			}
+		}
+	}
+
+	return s.report, nil
+}
+
+// PurgeAll removes every log, run state, saved conversation, usage record,
+// doc metric, cache, debug history, clone, and session transcript. The config and the doc
+// queue are kept.
+func (s *Store) PurgeAll(dryRun bool) (*Report, error) {
+	s.dryRun, s.now, s.report = dryRun, time.Now(), &Report{}
+
+	targets := []struct{ kind, path string }{
+		{"logs", filepath.Join(s.Dir, "logs")},
+		{"run state", filepath.Join(s.Dir, "runs")},
+		{"conversations", filepath.Join(s.Dir, "conversations")},
+		{"usage history", filepath.Join(s.Dir, "usage.jsonl")},
+		{"doc metrics", filepath.Join(s.Dir, "metrics.json")},
+		{"release cache", filepath.Join(s.Dir, "release_cache.json")},
+		{"standards cache", filepath.Join(s.Dir, "standards")},
+		{"update cache", filepath.Join(s.Dir, "cache")},
+		{"debug history", filepath.Join(s.Dir, "debug_history.json")},
+	}
+	for _, clone := range s.Clones {
+		targets = append(targets, struct{ kind, path string }{"clone", clone})
+	}
+	for _, project := range s.sessionProjects() {
+		targets = append(targets, struct{ kind, path string }{"sessions", project})
+	}
+
+	for _, target := range targets {
+		if _, err := os.Stat(target.path); err != nil {
+			continue
+		}
+		if err := s.remove(target.kind, target.path); err != nil {
+			return s.report, err
+		}
+	}
+	return s.report, nil
+}
+
+func (s *Store) remove(kind, path string) error {
+	size := diskUsage(path)
+	if !s.dryRun {
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+	s.report.add(kind, path, size)
+	return nil
+}
+
+// capLogs removes the oldest log files until the logs together fit in
+// maxSize, then drops the oldest lines of the newest one if it alone does not.
+func (s *Store) capLogs(logs []string, maxSize int64) error {
+	if maxSize <= 0 {
+		return nil
+	}
+
+	type logFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []logFile
+	var total int64
+	for _, path := range logs {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		// In a dry run the file still holds what trimLog would have dropped
+		size := info.Size() - s.freed(path)
+		if size <= 0 {
+			continue
+		}
+		files = append(files, logFile{path, size, info.ModTime()})
+		total += size
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	for i, file := range files {
+		if total <= maxSize {
+			break
+		}
+		if i == len(files)-1 {
+			return s.trimLog(file.path, time.Time{}, maxSize)
+		}
+		if err := s.remove("log", file.path); err != nil {
+			return err
+		}
+		total -= file.size
+	}
+	return nil
+}
+
+// freed is what the report has already freed from path.
+func (s *Store) freed(path string) int64 {
+	var size int64
+	for _, removal := range s.report.Removals {
+		if removal.Path == path {
+			size += removal.Size
+		}
+	}
+	return size
+}
+
+// trimLog drops log lines older than cutoff, then the oldest lines until the
+// file fits in maxSize. A file last written before cutoff is removed. Lines
+// without a timestamp (continuations of a multi-line entry) go with the entry
+// before them.
+func (s *Store) trimLog(path string, cutoff time.Time, maxSize int64) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	if cutoff.IsZero() && (maxSize <= 0 || info.Size() <= maxSize) {
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	start := 0
+	if !cutoff.IsZero() {
+		for start < len(lines) {
+			t, ok := logLineTime(lines[start])
+			if ok && !t.Before(cutoff) {
+				break
+			}
+			start++
+		}
+	}
+
+	kept := int64(0)
+	for _, line := range lines[start:] {
+		kept += int64(len(line))
+	}
+	for maxSize > 0 && kept > maxSize && start < len(lines) {
+		kept -= int64(len(lines[start]))
+		start++
+		// Do not leave a continuation line at the top
+		for start < len(lines) {
+			if _, ok := logLineTime(lines[start]); ok {
+				break
+			}
+			kept -= int64(len(lines[start]))
+			start++
+		}
+	}
+
+	if start == len(lines) && info.ModTime().Before(cutoff) {
+		return s.remove("log", path)
+	}
+	freed := info.Size() - kept
+	if freed <= 0 {
+		return nil
+	}
+	if !s.dryRun {
+		if err := os.WriteFile(path, bytes.Join(lines[start:], nil), 0644); err != nil {
+			return fmt.Errorf("failed to trim %s: %w", path, err)
+		}
+	}
+	s.report.add("log lines", path, freed)
+	return nil
+}
+
+func logLineTime(line []byte) (time.Time, bool) {
+	if bytes.HasPrefix(line, []byte("{")) {
+		var record struct {
+			Time time.Time `json:"time"`
+		}
+		err := json.Unmarshal(bytes.TrimSpace(line), &record)
+		return record.Time, err == nil && !record.Time.IsZero()
+	}
+	if len(line) < len(logTimeLayout) {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(logTimeLayout, string(line[:len(logTimeLayout)]), time.Local)
+	return t, err == nil
+}
+
+// trimUsage drops usage records older than cutoff. Lines that cannot be
+// parsed are kept.
+func (s *Store) trimUsage(path string, cutoff time.Time) error {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var kept []byte
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		var record struct {
+			Time time.Time `json:"time"`
+		}
+		if json.Unmarshal(bytes.TrimSpace(line), &record) == nil && !record.Time.IsZero() && record.Time.Before(cutoff) {
+			continue
+		}
+		kept = append(kept, line...)
+	}
+
+	freed := int64(len(content) - len(kept))
+	if freed == 0 {
+		return nil
+	}
+	if !s.dryRun {
+		if err := os.WriteFile(path, kept, 0644); err != nil {
+			return fmt.Errorf("failed to trim %s: %w", path, err)
+		}
+	}
+	s.report.add("usage records", path, freed)
+	return nil
+}
+
+// sessionProjects returns the Claude Code project directories of sessions run
+// in the clones or any directory inside them. Claude Code names a project
+// directory after its working directory, with every character other than a
+// letter or digit replaced by a dash. Names are matched exactly, against the
+// clones and their directories, since a prefix would also match the sessions
+// of other repositories, e.g. /src/app-admin for /src/app.
+func (s *Store) sessionProjects() []string {
+	entries, err := os.ReadDir(s.ClaudeDir)
+	if err != nil {
+		return nil
+	}
+
+	// Branch runs work in worktrees kept in the clone's git directory
+	var roots []string
+	for _, clone := range s.Clones {
+		worktrees, _ := filepath.Glob(filepath.Join(clone, ".git", "docu-jarvis-worktrees", "*"))
+		roots = append(append(roots, clone), worktrees...)
+	}
+
+	names := make(map[string]bool)
+	for _, root := range roots {
+		names[projectName(root)] = true
+		filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			if d.Name() == ".git" || d.Name() == "node_modules" {
+				return filepath.SkipDir
+			}
+			names[projectName(path)] = true
+			return nil
+		})
+	}
+
+	var projects []string
+	for _, entry := range entries {
+		if entry.IsDir() && names[entry.Name()] {
+			projects = append(projects, filepath.Join(s.ClaudeDir, entry.Name()))
+		}
+	}
+	sort.Strings(projects)
+	return projects
+}
+
+func projectName(dir string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '-'
+	}, filepath.Clean(dir))
+}
+
+// lastUsed is when a clone was last checked out or fetched.
+func lastUsed(clone string) time.Time {
+	var latest time.Time
+	for _, path := range []string{clone, filepath.Join(clone, ".git", "index"), filepath.Join(clone, ".git", "FETCH_HEAD"), filepath.Join(clone, ".git", "HEAD")} {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+func diskUsage(path string) int64 {
+	var total int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && !d.IsDir() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
+
+// FormatSize formats a size in bytes for display.
+func FormatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,272 @@
+// Package routes finds the HTTP routes a codebase registers, for the
+// frameworks write-api-docs knows: Gin, Echo, and net/http in Go, Express in
+// JavaScript and TypeScript, and FastAPI in Python. It reads the source as
+// text, so routes built at run time, and the prefixes of route groups, are
+// left for Claude to work out.
+package routes
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Frameworks names the frameworks routes are found for.
+const (
+	Gin     = "Gin"
+	Echo    = "Echo"
+	NetHTTP = "net/http"
+	Express = "Express"
+	FastAPI = "FastAPI"
+)
+
+// Route is an HTTP route registered in the source.
+type Route struct {
+	Method    string // upper case; ANY when the route takes every method
+	Path      string // as written in the source
+	File      string // relative to the root, with forward slashes
+	Line      int
+	Framework string
+}
+
+func (r Route) String() string {
+	return fmt.Sprintf("%s %s (%s:%d, %s)", r.Method, r.Path, r.File, r.Line, r.Framework)
+}
+
+// matcher finds the routes of one framework in the files that import it.
+type matcher struct {
+	framework  string
+	extensions []string
+	imports    *regexp.Regexp
+	route      *regexp.Regexp // method and path submatches, or path only
+}
+
+var matchers = []matcher{
+	{
+		framework:  Gin,
+		extensions: []string{".go"},
+		imports:    regexp.MustCompile(`"github\.com/gin-gonic/gin"`),
+		route:      regexp.MustCompile(`\.(GET|POST|PUT|PATCH|DELETE|HEAD|OPTIONS|Any)\(\s*"([^"]*)"`),
+	},
+	{
+		framework:  Echo,
+		extensions: []string{".go"},
+		imports:    regexp.MustCompile(`"github\.com/labstack/echo(/v\d+)?"`),
+		route:      regexp.MustCompile(`\.(GET|POST|PUT|PATCH|DELETE|HEAD|OPTIONS|Any)\(\s*"([^"]*)"`),
+	},
+	{
+		framework:  NetHTTP,
+		extensions: []string{".go"},
+		imports:    regexp.MustCompile(`"net/http"`),
+		route:      regexp.MustCompile(`\bHandle(?:Func)?\(\s*"([^"]+)"`),
+	},
+	{
+		framework:  Express,
+		extensions: []string{".js", ".mjs", ".cjs", ".ts"},
+		imports:    regexp.MustCompile(`require\(\s*['"]express['"]\s*\)|from\s+['"]express['"]`),
+		route:      regexp.MustCompile("\\b(?:app|router|\\w+Router)\\.(get|post|put|patch|delete|head|options|all)\\(\\s*['\"`]([^'\"`]+)['\"`]"),
+	},
+	{
+		framework:  FastAPI,
+		extensions: []string{".py"},
+		imports:    regexp.MustCompile(`(?m)^\s*(from\s+fastapi\b|import\s+fastapi\b)`),
+		route:      regexp.MustCompile(`@\w+\.(get|post|put|patch|delete|head|options)\(\s*['"]([^'"]+)['"]`),
+	},
+}
+
+// skipDirs are the directories that hold dependencies or generated code
+// rather than the codebase's own routes.
+var skipDirs = map[string]bool{
+	".git": true, "vendor": true, "node_modules": true, "testdata": true,
+	"dist": true, "build": true, "__pycache__": true, ".venv": true, "venv": true,
+}
+
+// Detect returns the routes registered in the source files under root,
+// leaving out test files, ordered by file and line.
+func Detect(root string) ([]Route, error) {
+	var found []Route
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && (skipDirs[d.Name()] || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isTest(d.Name()) {
+			return nil
+		}
+
+		var candidates []matcher
+		for _, m := range matchers {
+			for _, ext := range m.extensions {
+				if filepath.Ext(path) == ext {
+					candidates = append(candidates, m)
+				}
+			}
+		}
+		if len(candidates) == 0 {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		routes, err := scan(path, filepath.ToSlash(rel), candidates)
+		if err != nil {
+			return err
+		}
+		found = append(found, routes...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look for routes: %w", err)
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].File != found[j].File {
+			return found[i].File < found[j].File
+		}
+		return found[i].Line < found[j].Line
+	})
+	return found, nil
+}
+
+func isTest(name string) bool {
+	return strings.HasSuffix(name, "_test.go") || strings.HasPrefix(name, "test_") ||
+		strings.Contains(name, ".test.") || strings.Contains(name, ".spec.")
+}
+
+// scan finds the routes of the frameworks file imports. A Go file that
+// imports Gin or Echo is not scanned for net/http routes, whose Handle
+// methods they share.
+func scan(path, file string, candidates []matcher) ([]Route, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	var active []matcher
+	for _, m := range candidates {
+		if m.imports.Match(content) {
+			active = append(active, m)
+		}
+	}
+	if len(active) > 1 && active[len(active)-1].framework == NetHTTP {
+		active = active[:len(active)-1]
+	}
+	if len(active) == 0 {
+		return nil, nil
+	}
+
+	var routes []Route
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		for _, m := range active {
+			for _, match := range m.route.FindAllStringSubmatch(text, -1) {
+				routes = append(routes, newRoute(m.framework, match, file, line))
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return routes, nil
+}
+
+func newRoute(framework string, match []string, file string, line int) Route {
+	r := Route{Method: "ANY", File: file, Line: line, Framework: framework}
+	if len(match) == 2 {
+		// net/http: Go 1.22 patterns may start with the method
+		r.Path = match[1]
+		if method, path, ok := strings.Cut(match[1], " "); ok && strings.HasPrefix(path, "/") {
+			r.Method, r.Path = method, path
+		}
+		return r
+	}
+
+	r.Path = match[2]
+	if method := strings.ToUpper(match[1]); method != "ANY" && method != "ALL" {
+		r.Method = method
+	}
+	return r
+}
+
+// OpenAPIPath converts a route path to an OpenAPI path template: ":id" and
+// "*path" parameters become "{id}" and "{path}", and the "{id...}" wildcards
+// and "{$}" anchors of net/http lose their suffix.
+func OpenAPIPath(path string) string {
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		switch {
+		case strings.HasPrefix(segment, ":"), strings.HasPrefix(segment, "*") && len(segment) > 1:
+			segment = "{" + strings.TrimSuffix(segment[1:], "?") + "}"
+		case segment == "{$}":
+			segment = ""
+		case strings.HasSuffix(segment, "...}"):
+			segment = strings.TrimSuffix(segment, "...}") + "}"
+		}
+		segments = append(segments, segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// Missing returns the routes whose path is not in spec, an OpenAPI document.
+// Paths are matched as the text of a YAML or JSON key, so a route registered
+// under a group prefix is found by the end of its path the source writes.
+func Missing(spec string, routes []Route) []Route {
+	var missing []Route
+	for _, r := range routes {
+		path := OpenAPIPath(r.Path)
+		if path == "" || path == "/" || strings.Contains(spec, path+":") || strings.Contains(spec, path+`"`) || strings.Contains(spec, path+"'") {
+			continue
+		}
+		missing = append(missing, r)
+	}
+	return missing
+}
+
+// CheckSpec checks that spec, in JSON or YAML, is an OpenAPI 3 document with
+// paths. YAML is not parsed, only its top-level keys are looked at.
+func CheckSpec(spec string) error {
+	version, hasPaths := "", false
+	if trimmed := strings.TrimSpace(spec); strings.HasPrefix(trimmed, "{") {
+		var doc struct {
+			OpenAPI string                     `json:"openapi"`
+			Paths   map[string]json.RawMessage `json:"paths"`
+		}
+		if err := json.Unmarshal([]byte(trimmed), &doc); err != nil {
+			return fmt.Errorf("the spec is not valid JSON: %w", err)
+		}
+		version, hasPaths = doc.OpenAPI, len(doc.Paths) > 0
+	} else {
+		for _, line := range strings.Split(spec, "\n") {
+			if key, value, ok := strings.Cut(strings.TrimRight(line, "\r"), ":"); ok && key == "openapi" {
+				version = strings.Trim(strings.TrimSpace(value), `"'`)
+			} else if ok && key == "paths" {
+				hasPaths = true
+			}
+		}
+	}
+
+	if !strings.HasPrefix(version, "3.") {
+		if version == "" {
+			return fmt.Errorf("the spec has no openapi version")
+		}
+		return fmt.Errorf("the spec is OpenAPI %s, not 3.x", version)
+	}
+	if !hasPaths {
+		return fmt.Errorf("the spec has no paths")
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package runstate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	Pending   Status = "pending"
+	Succeeded Status = "succeeded"
+	Failed    Status = "failed"
+)
+
+type FileResult struct {
+	Status   Status    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+	Summary  string    `json:"summary,omitempty"` // what was changed, in one sentence
+	Finished time.Time `json:"finished,omitempty"`
+}
+
+// Run is the state of one update-docs run, saved to
+// ~/.docu-jarvis/runs/<id>.json after every file so an interrupted or
+// partly failed run can be resumed, and with the docs changes of its PR so
+// they can be rolled back. Files are keyed by their path relative to the
+// codebase folder.
+type Run struct {
+	ID           string                 `json:"id"`
+	Command      string                 `json:"command"`
+	RepoURL      string                 `json:"repo_url"`
+	LocalPath    string                 `json:"local_path"`
+	Local        bool                   `json:"local"` // LocalPath is the user's checkout, not a clone
+	Scope        string                 `json:"scope,omitempty"`
+	Branch       string                 `json:"branch,omitempty"`
+	DocsRoots    []string               `json:"docs_roots,omitempty"`
+	CustomPrompt string                 `json:"custom_prompt,omitempty"`
+	Config       string                 `json:"config,omitempty"`      // docu-jarvis version, prompt digest, and model
+	BaseCommit   string                 `json:"base_commit,omitempty"` // HEAD when the run started
+	Changes      string                 `json:"changes,omitempty"`     // patch of the docs from BaseCommit, saved before the PR
+	Started      time.Time              `json:"started"`
+	Updated      time.Time              `json:"updated"`
+	Files        map[string]*FileResult `json:"files"`
+
+	mu   sync.Mutex
+	path string
+}
+
+func runsDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".docu-jarvis", "runs"), nil
+}
+
+// New creates the state for a run and saves it. The caller fills in the
+// repository fields before the first file is recorded.
+func New(command string) (*Run, error) {
+	dir, err := runsDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create runs directory: %w", err)
+	}
+
+	now := time.Now()
+	id := fmt.Sprintf("%s-%d", now.Format("20060102-150405"), os.Getpid()%10000)
+	r := &Run{
+		ID:      id,
+		Command: command,
+		Started: now,
+		Updated: now,
+		Files:   make(map[string]*FileResult),
+		path:    filepath.Join(dir, id+".json"),
+	}
+	return r, r.Save()
+}
+
+// Load reads the state of a previous run.
+func Load(id string) (*Run, error) {
+	dir, err := runsDir()
+	if err != nil {
+		return nil, err
+	}
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return nil, fmt.Errorf("invalid run ID: %s", id)
+	}
+
+	path := filepath.Join(dir, id+".json")
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("run %s not found (see 'docu-jarvis runs list')", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run %s: %w", id, err)
+	}
+
+	r := &Run{path: path}
+	if err := json.Unmarshal(content, r); err != nil {
+		return nil, fmt.Errorf("failed to parse run %s: %w", id, err)
+	}
+	if r.Files == nil {
+		r.Files = make(map[string]*FileResult)
+	}
+	return r, nil
+}
+
+// List returns every saved run, newest first. Files that cannot be parsed
+// are skipped.
+func List() ([]*Run, error) {
+	dir, err := runsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	var runs []*Run
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		r, err := Load(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		runs = append(runs, r)
+	}
+
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Started.After(runs[j].Started) })
+	return runs, nil
+}
+
+// Save writes the state, replacing the file atomically so an interrupted
+// write cannot leave it half written.
+func (r *Run) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.save()
+}
+
+func (r *Run) save() error {
+	r.Updated = time.Now()
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode run state: %w", err)
+	}
+
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return fmt.Errorf("failed to write run state: %w", err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return fmt.Errorf("failed to write run state: %w", err)
+	}
+	return nil
+}
+
+// Start marks files as pending, keeping the result of ones already recorded.
+func (r *Run) Start(files []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, file := range files {
+		if r.Files[file] == nil || r.Files[file].Status != Succeeded {
+			r.Files[file] = &FileResult{Status: Pending}
+		}
+	}
+	return r.save()
+}
+
+// Record saves the result of one file, with a summary of its changes. It is
+// safe to call from concurrent workers.
+func (r *Run) Record(file, summary string, err error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := &FileResult{Status: Succeeded, Summary: summary, Finished: time.Now()}
+	if err != nil {
+		result.Status = Failed
+		result.Error = err.Error()
+	}
+	r.Files[file] = result
+	return r.save()
+}
+
+// Reset marks every file as pending again, for when the edits of the files
+// that succeeded were lost.
+func (r *Run) Reset() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for file := range r.Files {
+		r.Files[file] = &FileResult{Status: Pending}
+	}
+	return r.save()
+}
+
+// Remaining returns the files that failed or never finished, sorted.
+func (r *Run) Remaining() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var files []string
+	for file, result := range r.Files {
+		if result.Status != Succeeded {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// FileNames returns every file in the run, sorted.
+func (r *Run) FileNames() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var files []string
+	for file := range r.Files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Counts returns how many files succeeded, failed, and are still pending.
+func (r *Run) Counts() (succeeded, failed, pending int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, result := range r.Files {
+		switch result.Status {
+		case Succeeded:
+			succeeded++
+		case Failed:
+			failed++
+		default:
+			pending++
+		}
+	}
+	return succeeded, failed, pending
+}
@@ -0,0 +1,407 @@
+// Package samples checks the code samples of documentation: Go samples are
+// parsed (and vetted when they are whole programs), Python samples are
+// compiled, and shell samples are syntax-checked with bash.
+package samples
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checkTimeout caps the check of one sample.
+const checkTimeout = 30 * time.Second
+
+// Languages that are checked.
+const (
+	Go     = "go"
+	Python = "python"
+	Shell  = "shell"
+)
+
+// languages maps the info strings of fences to the languages checked.
+var languages = map[string]string{
+	"go":      Go,
+	"golang":  Go,
+	"python":  Python,
+	"python3": Python,
+	"py":      Python,
+	"pycon":   Python,
+	"sh":      Shell,
+	"bash":    Shell,
+	"shell":   Shell,
+	"zsh":     Shell,
+	"console": Shell,
+}
+
+// Block is a fenced code sample in a language that is checked.
+type Block struct {
+	File     string
+	Line     int // line of the first line of code in File
+	Language string
+	Code     string
+}
+
+// Problem is a sample that does not compile or parse.
+type Problem struct {
+	Block
+	Line    int // line of the error in File
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d (%s): %s", p.File, p.Line, p.Language, p.Message)
+}
+
+// Report is the result of checking samples.
+type Report struct {
+	Checked  int
+	Problems []Problem
+	Skipped  []string // languages whose tools are not installed
+}
+
+var fencePattern = regexp.MustCompile("^ {0,3}(```+|~~~+)\\s*([^`\\s]*)")
+
+// Extract returns the samples of a markdown document.
+func Extract(file, content string) []Block {
+	var blocks []Block
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		m := fencePattern.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		fence, info := m[1], strings.ToLower(strings.Trim(m[2], "{}."))
+
+		start := i + 1
+		end := start
+		for end < len(lines) && !isClosingFence(lines[end], fence) {
+			end++
+		}
+		if lang, ok := languages[info]; ok {
+			code := strings.Join(lines[start:min(end, len(lines))], "\n")
+			if info == "console" || info == "pycon" {
+				code = stripPrompts(code)
+			}
+			blocks = append(blocks, Block{File: file, Line: start + 1, Language: lang, Code: code})
+		}
+		i = end
+	}
+	return blocks
+}
+
+func isClosingFence(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == ""
+}
+
+var promptPattern = regexp.MustCompile(`^\s*(\$|>>>|\.\.\.)( |$)`)
+
+// stripPrompts keeps the commands of a terminal session and blanks their
+// output, so line numbers still match.
+func stripPrompts(code string) string {
+	lines := strings.Split(code, "\n")
+	continued := false
+	for i, line := range lines {
+		if m := promptPattern.FindStringIndex(line); m != nil {
+			lines[i] = line[m[1]:]
+		} else if !continued {
+			lines[i] = ""
+		}
+		continued = strings.HasSuffix(strings.TrimSpace(lines[i]), "\\")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ExtractFiles returns the samples of the markdown files, relative to root.
+func ExtractFiles(root string, files []string) ([]Block, error) {
+	var blocks []Block
+	for _, file := range files {
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".md", ".mdx", ".markdown":
+		default:
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(root, file))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		blocks = append(blocks, Extract(file, string(content))...)
+	}
+	return blocks, nil
+}
+
+// Check checks each sample with the tool for its language. Samples whose
+// tool is not installed are skipped and their language is reported.
+func Check(ctx context.Context, blocks []Block) Report {
+	var report Report
+	skipped := make(map[string]bool)
+	for _, block := range blocks {
+		var line int
+		var message string
+		var ok bool
+		switch block.Language {
+		case Go:
+			line, message, ok = checkGo(ctx, block.Code)
+		case Python:
+			line, message, ok = checkPython(ctx, block.Code)
+		case Shell:
+			line, message, ok = checkShell(ctx, block.Code)
+		}
+		if !ok {
+			skipped[block.Language] = true
+			continue
+		}
+		report.Checked++
+		if message != "" {
+			// Errors at the end of input are reported past the last line
+			line = min(max(line, 1), strings.Count(block.Code, "\n")+1)
+			report.Problems = append(report.Problems, Problem{Block: block, Line: block.Line + line - 1, Message: message})
+		}
+	}
+	for lang := range skipped {
+		report.Skipped = append(report.Skipped, lang)
+	}
+	sort.Strings(report.Skipped)
+	return report
+}
+
+var (
+	packagePattern = regexp.MustCompile(`(?m)^package \w+`)
+	declPattern    = regexp.MustCompile(`^(func|type|var|const|import)\b`)
+)
+
+// checkGo parses a Go sample: a whole file, top-level declarations, or
+// statements. Whole programs that only import the standard library are also
+// vetted. Lines that are only "..." stand for elided code.
+func checkGo(ctx context.Context, code string) (int, string, bool) {
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		if t := strings.TrimSpace(line); t == "..." || t == "…" {
+			lines[i] = ""
+		}
+	}
+	code = strings.Join(lines, "\n")
+
+	if packagePattern.MatchString(code) {
+		file, err := parser.ParseFile(token.NewFileSet(), "sample.go", code, parser.AllErrors)
+		if err != nil {
+			line, message := goError(err, 0)
+			return line, message, true
+		}
+		if file.Name.Name != "main" || !stdlibOnly(file) {
+			return 0, "", true
+		}
+		line, message := vetGo(ctx, code)
+		return line, message, true
+	}
+
+	// Declarations, or else the statements of a function body
+	declErr := parseGo("package sample\n" + code)
+	if declErr == nil {
+		return 0, "", true
+	}
+	stmtErr := parseGo("package sample\nfunc _() {\n" + code + "\n}")
+	if stmtErr == nil {
+		return 0, "", true
+	}
+	err, offset := stmtErr, 2
+	if declPattern.MatchString(strings.TrimSpace(code)) {
+		err, offset = declErr, 1
+	}
+	line, message := goError(err, offset)
+	return line, message, true
+}
+
+func parseGo(src string) error {
+	_, err := parser.ParseFile(token.NewFileSet(), "sample.go", src, parser.AllErrors)
+	return err
+}
+
+// goError returns the line and message of the first parse error, with the
+// lines added around the sample taken off.
+func goError(err error, offset int) (int, string) {
+	if list, ok := err.(scanner.ErrorList); ok && len(list) > 0 {
+		return list[0].Pos.Line - offset, list[0].Msg
+	}
+	return 0, err.Error()
+}
+
+func stdlibOnly(file *ast.File) bool {
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		first, _, _ := strings.Cut(path, "/")
+		if strings.Contains(first, ".") {
+			return false
+		}
+	}
+	return true
+}
+
+var vetLinePattern = regexp.MustCompile(`(?m)^\.?/?main\.go:(\d+)(?::\d+)?: (.*)$`)
+
+// vetGo builds and vets a program in a module of its own, without network
+// access or toolchain downloads. The sample passes when go is not installed.
+func vetGo(ctx context.Context, code string) (int, string) {
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		return 0, ""
+	}
+	dir, err := os.MkdirTemp("", "docu-jarvis-sample-")
+	if err != nil {
+		return 0, ""
+	}
+	defer os.RemoveAll(dir)
+	goMod := fmt.Sprintf("module sample\n\ngo %s\n", localGoVersion(ctx, goCmd))
+	if os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0644) != nil ||
+		os.WriteFile(filepath.Join(dir, "main.go"), []byte(code), 0644) != nil {
+		return 0, ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, goCmd, "vet", ".")
+	cmd.Dir = dir
+	cmd.Env = append(GoEnv(), "GOFLAGS=-mod=mod")
+	output, err := cmd.CombinedOutput()
+	if err == nil || ctx.Err() != nil {
+		return 0, ""
+	}
+	if m := vetLinePattern.FindStringSubmatch(string(output)); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return line, m[2]
+	}
+	return 0, firstLine(string(output))
+}
+
+// GoEnv returns the environment go commands run with on behalf of the user:
+// no module downloads, no toolchain downloads, and no go.work of an enclosing
+// directory.
+func GoEnv() []string {
+	return append(os.Environ(), "GOPROXY=off", "GOTOOLCHAIN=local", "GOWORK=off")
+}
+
+var goVersionPattern = regexp.MustCompile(`^go(\d+\.\d+(?:\.\d+)?)`)
+
+// localGoVersion returns the version of the installed go, which the sample
+// module declares so that it neither needs a newer toolchain nor loses
+// language features. Development builds fall back to the oldest supported
+// version.
+func localGoVersion(ctx context.Context, goCmd string) string {
+	output, err := exec.CommandContext(ctx, goCmd, "env", "GOVERSION").Output()
+	if err == nil {
+		if m := goVersionPattern.FindStringSubmatch(strings.TrimSpace(string(output))); m != nil {
+			return m[1]
+		}
+	}
+	return "1.21"
+}
+
+const pythonCheck = `import ast, sys
+try:
+    ast.parse(sys.stdin.read())
+except SyntaxError as e:
+    print("%d:%s" % (e.lineno or 0, e.msg))
+    sys.exit(1)`
+
+// checkPython parses a Python sample with the installed Python 3.
+func checkPython(ctx context.Context, code string) (int, string, bool) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		return 0, "", false
+	}
+	output, failed := runCheck(ctx, dedent(code), python, "-c", pythonCheck)
+	if !failed {
+		return 0, "", true
+	}
+	lineText, message, ok := strings.Cut(strings.TrimSpace(output), ":")
+	line, err := strconv.Atoi(lineText)
+	if !ok || err != nil {
+		return 0, firstLine(output), true
+	}
+	return line, message, true
+}
+
+var (
+	shellErrorPattern = regexp.MustCompile(`line (\d+): (.*)`)
+	// placeholderPattern matches placeholders such as <commit> or <repo-url>,
+	// which bash would read as redirections.
+	placeholderPattern = regexp.MustCompile(`<[A-Za-z][\w.:/-]*>`)
+)
+
+// checkShell checks the syntax of a shell sample with bash -n.
+func checkShell(ctx context.Context, code string) (int, string, bool) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		return 0, "", false
+	}
+	code = placeholderPattern.ReplaceAllString(code, "PLACEHOLDER")
+	output, failed := runCheck(ctx, code, bash, "-n")
+	if !failed {
+		return 0, "", true
+	}
+	if m := shellErrorPattern.FindStringSubmatch(output); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return line, m[2], true
+	}
+	return 0, firstLine(output), true
+}
+
+// runCheck runs a checker with the sample on stdin, and returns its output
+// and whether the sample failed.
+func runCheck(ctx context.Context, code string, name string, args ...string) (string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = strings.NewReader(code)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	return output.String(), err != nil && ctx.Err() == nil
+}
+
+// dedent removes the indentation all lines share, as Python samples are
+// sometimes indented inside lists.
+func dedent(code string) string {
+	lines := strings.Split(code, "\n")
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent <= 0 {
+		return code
+	}
+	for i, line := range lines {
+		if len(line) >= indent {
+			lines[i] = line[indent:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
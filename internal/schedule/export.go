@@ -0,0 +1,245 @@
+package schedule
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Formats of Export.
+const (
+	FormatCrontab = "crontab"
+	FormatSystemd = "systemd"
+	FormatLaunchd = "launchd"
+)
+
+// launchdLimit caps the start times of a launchd job, which lists each one.
+const launchdLimit = 500
+
+// File is an exported definition and the name to install it as.
+type File struct {
+	Name    string
+	Content string
+}
+
+// Export returns the definitions that run the jobs with the docu-jarvis
+// binary exe in CI mode, in the given format. pathEnv is the PATH they get,
+// so that git and the claude CLI are found; logDir holds the output of the
+// crontab and launchd jobs, which systemd keeps in its journal.
+func Export(format string, jobs []Job, exe, pathEnv, logDir string) ([]File, error) {
+	switch format {
+	case FormatCrontab:
+		return []File{{Name: "crontab", Content: crontab(jobs, exe, pathEnv, logDir)}}, nil
+	case FormatSystemd, FormatLaunchd:
+	default:
+		return nil, fmt.Errorf("invalid format %q (must be %s, %s, or %s)", format, FormatCrontab, FormatSystemd, FormatLaunchd)
+	}
+
+	var files []File
+	for _, job := range jobs {
+		spec, err := ParseSpec(job.Spec)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %w", job.ID, err)
+		}
+		if format == FormatSystemd {
+			files = append(files, systemdUnits(job, spec, exe, pathEnv)...)
+			continue
+		}
+		plist, err := launchdPlist(job, spec, exe, pathEnv, logDir)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %w", job.ID, err)
+		}
+		files = append(files, File{Name: launchdLabel(job) + ".plist", Content: plist})
+	}
+	return files, nil
+}
+
+func crontab(jobs []Job, exe, pathEnv, logDir string) string {
+	var b strings.Builder
+	b.WriteString("# docu-jarvis schedule, from 'docu-jarvis schedule export'\n")
+	fmt.Fprintf(&b, "PATH=%s\n", pathEnv)
+	for _, job := range jobs {
+		command := "cd " + shellQuote(job.Dir) + " && " + shellQuote(exe) + " -ci"
+		for _, arg := range job.Args {
+			command += " " + shellQuote(arg)
+		}
+		command += " >> " + shellQuote(filepath.Join(logDir, "schedule-"+job.ID+".log")) + " 2>&1"
+		fmt.Fprintf(&b, "\n# %s: %s\n", job.ID, strings.Join(job.Args, " "))
+		// cron turns an unescaped % into a newline
+		fmt.Fprintf(&b, "%s %s\n", job.Spec, strings.ReplaceAll(command, "%", `\%`))
+	}
+	return b.String()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func systemdUnits(job Job, spec Spec, exe, pathEnv string) []File {
+	name := "docu-jarvis-schedule-" + job.ID
+	description := systemdEscape("docu-jarvis " + strings.Join(job.Args, " "))
+
+	command := systemdQuote(exe) + " -ci"
+	for _, arg := range job.Args {
+		command += " " + systemdQuote(arg)
+	}
+	service := fmt.Sprintf("[Unit]\nDescription=%s\n\n[Service]\nType=oneshot\nWorkingDirectory=%s\nEnvironment=%s\nExecStart=%s\n",
+		description, systemdEscape(job.Dir), systemdQuote("PATH="+pathEnv), command)
+
+	var timer strings.Builder
+	fmt.Fprintf(&timer, "[Unit]\nDescription=%s on %s\n\n[Timer]\n", description, job.Spec)
+	for _, calendar := range onCalendar(spec) {
+		fmt.Fprintf(&timer, "OnCalendar=%s\n", calendar)
+	}
+	timer.WriteString("Persistent=true\n\n[Install]\nWantedBy=timers.target\n")
+
+	return []File{
+		{Name: name + ".service", Content: service},
+		{Name: name + ".timer", Content: timer.String()},
+	}
+}
+
+// systemdEscape escapes the specifiers of a unit file value.
+func systemdEscape(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
+
+func systemdQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", "$$").Replace(systemdEscape(s))
+	return `"` + s + `"`
+}
+
+// onCalendar returns the OnCalendar values of a spec. systemd requires both
+// day fields to match, so a spec restricting both takes one value for each.
+func onCalendar(spec Spec) []string {
+	clock := fmt.Sprintf("%s:%s:00", calendarField(spec.hour, 0, 23, "%02d"), calendarField(spec.minute, 0, 59, "%02d"))
+	month := calendarField(spec.month, 1, 12, "%02d")
+	dom := calendarField(spec.dom, 1, 31, "%02d")
+
+	var days []string
+	for _, v := range values(spec.dow, 0, 6) {
+		days = append(days, strings.ToUpper(dayNames[v][:1])+dayNames[v][1:])
+	}
+	weekdays := strings.Join(days, ",")
+	if len(days) == 7 {
+		weekdays = ""
+	}
+
+	if !spec.domAny && !spec.dowAny {
+		return []string{
+			fmt.Sprintf("%s *-%s-* %s", weekdays, month, clock),
+			fmt.Sprintf("*-%s-%s %s", month, dom, clock),
+		}
+	}
+	return []string{strings.TrimSpace(fmt.Sprintf("%s *-%s-%s %s", weekdays, month, dom, clock))}
+}
+
+// calendarField is "*" for a field that matches every value, or the list of
+// values it matches.
+func calendarField(bits uint64, min, max int, format string) string {
+	matched := values(bits, min, max)
+	if len(matched) == max-min+1 {
+		return "*"
+	}
+	var parts []string
+	for _, v := range matched {
+		parts = append(parts, fmt.Sprintf(format, v))
+	}
+	return strings.Join(parts, ",")
+}
+
+func launchdLabel(job Job) string {
+	return "com.docu-jarvis.schedule-" + job.ID
+}
+
+func launchdPlist(job Job, spec Spec, exe, pathEnv, logDir string) (string, error) {
+	intervals, err := calendarIntervals(spec)
+	if err != nil {
+		return "", err
+	}
+	logFile := filepath.Join(logDir, "schedule-"+job.ID+".log")
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	b.WriteString("<plist version=\"1.0\">\n<dict>\n")
+	fmt.Fprintf(&b, "\t<key>Label</key>\n\t<string>%s</string>\n", xmlEscape(launchdLabel(job)))
+	b.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, arg := range append([]string{exe, "-ci"}, job.Args...) {
+		fmt.Fprintf(&b, "\t\t<string>%s</string>\n", xmlEscape(arg))
+	}
+	b.WriteString("\t</array>\n")
+	fmt.Fprintf(&b, "\t<key>WorkingDirectory</key>\n\t<string>%s</string>\n", xmlEscape(job.Dir))
+	fmt.Fprintf(&b, "\t<key>EnvironmentVariables</key>\n\t<dict>\n\t\t<key>PATH</key>\n\t\t<string>%s</string>\n\t</dict>\n", xmlEscape(pathEnv))
+	b.WriteString("\t<key>StartCalendarInterval</key>\n\t<array>\n")
+	for _, interval := range intervals {
+		b.WriteString("\t\t<dict>\n")
+		for _, key := range []string{"Month", "Day", "Weekday", "Hour", "Minute"} {
+			if v, ok := interval[key]; ok {
+				fmt.Fprintf(&b, "\t\t\t<key>%s</key>\n\t\t\t<integer>%d</integer>\n", key, v)
+			}
+		}
+		b.WriteString("\t\t</dict>\n")
+	}
+	b.WriteString("\t</array>\n")
+	fmt.Fprintf(&b, "\t<key>StandardOutPath</key>\n\t<string>%s</string>\n", xmlEscape(logFile))
+	fmt.Fprintf(&b, "\t<key>StandardErrorPath</key>\n\t<string>%s</string>\n", xmlEscape(logFile))
+	b.WriteString("</dict>\n</plist>\n")
+	return b.String(), nil
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// calendarIntervals lists the StartCalendarInterval entries of a spec.
+// launchd has no ranges or steps, so each combination of the restricted
+// fields is an entry of its own.
+func calendarIntervals(spec Spec) ([]map[string]int, error) {
+	type field struct {
+		key      string
+		bits     uint64
+		min, max int
+	}
+	minute := field{"Minute", spec.minute, 0, 59}
+	hour := field{"Hour", spec.hour, 0, 23}
+	month := field{"Month", spec.month, 1, 12}
+	dom := field{"Day", spec.dom, 1, 31}
+	dow := field{"Weekday", spec.dow, 0, 6}
+
+	sets := [][]field{{minute, hour, month, dom, dow}}
+	if !spec.domAny && !spec.dowAny {
+		sets = [][]field{{minute, hour, month, dom}, {minute, hour, month, dow}}
+	}
+
+	var intervals []map[string]int
+	for _, fields := range sets {
+		combos := []map[string]int{{}}
+		for _, f := range fields {
+			matched := values(f.bits, f.min, f.max)
+			if len(matched) == f.max-f.min+1 {
+				continue
+			}
+			var next []map[string]int
+			for _, combo := range combos {
+				for _, v := range matched {
+					entry := map[string]int{f.key: v}
+					for k, existing := range combo {
+						entry[k] = existing
+					}
+					next = append(next, entry)
+				}
+			}
+			if len(intervals)+len(next) > launchdLimit {
+				return nil, fmt.Errorf("the schedule has too many start times for launchd; run it with 'docu-jarvis daemon' instead")
+			}
+			combos = next
+		}
+		intervals = append(intervals, combos...)
+	}
+	return intervals, nil
+}
@@ -0,0 +1,120 @@
+// Package schedule keeps the recurring docu-jarvis runs of
+// ~/.docu-jarvis/schedule.json, for the daemon command or the crontab,
+// systemd timer, and launchd definitions exported from it.
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const scheduleFileName = "schedule.json"
+
+// Job is a recurring run: docu-jarvis Args, run in Dir on Spec.
+type Job struct {
+	ID        string    `json:"id"`
+	Spec      string    `json:"spec"`
+	Args      []string  `json:"args"`
+	Dir       string    `json:"dir"`
+	Created   time.Time `json:"created"`
+	LastRun   time.Time `json:"last_run,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Next returns the job's next run after t, or the zero time when its spec
+// is invalid.
+func (j Job) Next(t time.Time) time.Time {
+	spec, err := ParseSpec(j.Spec)
+	if err != nil {
+		return time.Time{}
+	}
+	return spec.Next(t)
+}
+
+type Schedule struct {
+	Jobs []Job `json:"jobs"`
+	path string
+}
+
+func Load() (*Schedule, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	configDir := filepath.Join(homeDir, ".docu-jarvis")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	s := &Schedule{path: filepath.Join(configDir, scheduleFileName)}
+	content, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule: %w", err)
+	}
+	if err := json.Unmarshal(content, s); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule: %w", err)
+	}
+	return s, nil
+}
+
+func (s *Schedule) Save() error {
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode schedule: %w", err)
+	}
+	if err := os.WriteFile(s.path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write schedule: %w", err)
+	}
+	return nil
+}
+
+// Add schedules args to run in dir on spec and returns the new job.
+func (s *Schedule) Add(spec string, args []string, dir string) (Job, error) {
+	if _, err := ParseSpec(spec); err != nil {
+		return Job{}, err
+	}
+	highest := 0
+	for _, job := range s.Jobs {
+		if n, err := strconv.Atoi(job.ID); err == nil && n > highest {
+			highest = n
+		}
+	}
+	job := Job{
+		ID:      strconv.Itoa(highest + 1),
+		Spec:    spec,
+		Args:    args,
+		Dir:     dir,
+		Created: time.Now(),
+	}
+	s.Jobs = append(s.Jobs, job)
+	return job, nil
+}
+
+// Remove deletes a job and reports whether it was there.
+func (s *Schedule) Remove(id string) bool {
+	for i, job := range s.Jobs {
+		if job.ID == id {
+			s.Jobs = append(s.Jobs[:i], s.Jobs[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Find returns the job with the given ID.
+func (s *Schedule) Find(id string) *Job {
+	for i := range s.Jobs {
+		if s.Jobs[i].ID == id {
+			return &s.Jobs[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// keychain is the secret store of one OS, driven through its command-line
+// tool so no cgo is needed.
+type keychain interface {
+	name() string
+	available() error
+	get(keys []string) (map[string]string, error)
+	set(key, value string) error
+	remove(key string) error
+}
+
+func backend() keychain {
+	switch runtime.GOOS {
+	case "darwin":
+		return macKeychain{}
+	case "windows":
+		return windowsCredentials{}
+	default:
+		return secretService{}
+	}
+}
+
+// run runs a keychain tool with stdin and returns its trimmed output, or its
+// error output in the error.
+func run(stdin string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// macKeychain stores generic passwords in the login keychain with security.
+type macKeychain struct{}
+
+// errSecItemNotFound is the exit status of security for a missing item.
+const errSecItemNotFound = 44
+
+func (macKeychain) name() string { return "macOS Keychain" }
+
+func (macKeychain) available() error {
+	if _, err := exec.LookPath("security"); err != nil {
+		return fmt.Errorf("the security tool of macOS was not found")
+	}
+	return nil
+}
+
+func (macKeychain) get(keys []string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, key := range keys {
+		value, err := run("", "security", "find-generic-password", "-s", Service, "-a", key, "-w")
+		if err == nil && value != "" {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+func (macKeychain) set(key, value string) error {
+	// security only takes the password as an argument, so the command goes
+	// to its interactive mode on stdin to keep the secret out of the process
+	// table
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("%s must be a single line", key)
+	}
+	command := strings.Join([]string{"add-generic-password", "-U", "-s", securityQuote(Service), "-a", securityQuote(key),
+		"-l", securityQuote(Service + " " + key), "-w", securityQuote(value)}, " ")
+
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(command + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	// interactive mode reports a failed command on stderr but still exits 0
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		if err == nil {
+			err = errors.New("security failed")
+		}
+		return fmt.Errorf("%w: %s", err, msg)
+	}
+	return err
+}
+
+// securityQuote quotes an argument for the interactive mode of security.
+func securityQuote(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+func (macKeychain) remove(key string) error {
+	_, err := run("", "security", "delete-generic-password", "-s", Service, "-a", key)
+	if err != nil && exitCode(err) != errSecItemNotFound {
+		return err
+	}
+	return nil
+}
+
+// secretService stores secrets with secret-tool (libsecret), which GNOME
+// Keyring and KWallet provide on Linux desktops.
+type secretService struct{}
+
+func (secretService) name() string { return "Secret Service keyring" }
+
+func (secretService) available() error {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return fmt.Errorf("secret-tool was not found; install libsecret-tools (Debian, Ubuntu) or libsecret (Fedora, Arch)")
+	}
+	return nil
+}
+
+func (secretService) get(keys []string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, key := range keys {
+		value, err := run("", "secret-tool", "lookup", "service", Service, "key", key)
+		if err == nil && value != "" {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+func (secretService) set(key, value string) error {
+	_, err := run(value, "secret-tool", "store", "--label", Service+" "+key, "service", Service, "key", key)
+	return err
+}
+
+func (secretService) remove(key string) error {
+	// clear succeeds when nothing matches
+	_, err := run("", "secret-tool", "clear", "service", Service, "key", key)
+	return err
+}
+
+// windowsCredentials stores generic credentials in the Windows Credential
+// Manager, through its API from PowerShell since cmdkey cannot read them back.
+type windowsCredentials struct{}
+
+// credentialScript defines the credential functions; the operation and
+// targets come from the environment and the secret from stdin.
+const credentialScript = `$ErrorActionPreference = 'Stop'
+Add-Type -TypeDefinition @'
+using System;
+using System.Runtime.InteropServices;
+using System.Text;
+public static class DocuJarvisCredentials {
+	[StructLayout(LayoutKind.Sequential, CharSet = CharSet.Unicode)]
+	struct Credential {
+		public int Flags; public int Type; public string TargetName; public string Comment;
+		public System.Runtime.InteropServices.ComTypes.FILETIME LastWritten;
+		public int CredentialBlobSize; public IntPtr CredentialBlob; public int Persist;
+		public int AttributeCount; public IntPtr Attributes; public string TargetAlias; public string UserName;
+	}
+	[DllImport("advapi32.dll", CharSet = CharSet.Unicode, SetLastError = true)]
+	static extern bool CredReadW(string target, int type, int flags, out IntPtr credential);
+	[DllImport("advapi32.dll", CharSet = CharSet.Unicode, SetLastError = true)]
+	static extern bool CredWriteW(ref Credential credential, int flags);
+	[DllImport("advapi32.dll", CharSet = CharSet.Unicode, SetLastError = true)]
+	static extern bool CredDeleteW(string target, int type, int flags);
+	[DllImport("advapi32.dll")]
+	static extern void CredFree(IntPtr credential);
+	public static string Read(string target) {
+		IntPtr p;
+		if (!CredReadW(target, 1, 0, out p)) { return null; }
+		try {
+			Credential c = (Credential)Marshal.PtrToStructure(p, typeof(Credential));
+			byte[] blob = new byte[c.CredentialBlobSize];
+			Marshal.Copy(c.CredentialBlob, blob, 0, blob.Length);
+			return Encoding.UTF8.GetString(blob);
+		} finally { CredFree(p); }
+	}
+	public static void Write(string target, string secret) {
+		byte[] blob = Encoding.UTF8.GetBytes(secret);
+		Credential c = new Credential();
+		c.Type = 1; c.Persist = 2; c.TargetName = target; c.UserName = "docu-jarvis";
+		c.CredentialBlobSize = blob.Length;
+		c.CredentialBlob = Marshal.AllocHGlobal(blob.Length);
+		try {
+			Marshal.Copy(blob, 0, c.CredentialBlob, blob.Length);
+			if (!CredWriteW(ref c, 0)) { throw new System.ComponentModel.Win32Exception(); }
+		} finally { Marshal.FreeHGlobal(c.CredentialBlob); }
+	}
+	public static void Delete(string target) {
+		if (!CredDeleteW(target, 1, 0) && Marshal.GetLastWin32Error() != 1168) { throw new System.ComponentModel.Win32Exception(); }
+	}
+}
+'@
+$targets = $env:DOCU_JARVIS_CREDENTIALS -split ','
+switch ($env:DOCU_JARVIS_CREDENTIAL_OP) {
+	'read' { foreach ($t in $targets) { $v = [DocuJarvisCredentials]::Read($t); if ($v) { Write-Output "$t=$v" } } }
+	'write' { [DocuJarvisCredentials]::Write($targets[0], [Console]::In.ReadToEnd()) }
+	'delete' { [DocuJarvisCredentials]::Delete($targets[0]) }
+}
+`
+
+func (windowsCredentials) name() string { return "Windows Credential Manager" }
+
+func (windowsCredentials) available() error {
+	if _, err := exec.LookPath("powershell"); err != nil {
+		return fmt.Errorf("PowerShell was not found")
+	}
+	return nil
+}
+
+// target is the name of the credential of a key.
+func (windowsCredentials) target(key string) string {
+	return Service + ":" + key
+}
+
+func (w windowsCredentials) credentials(op, stdin string, keys ...string) (string, error) {
+	var targets []string
+	for _, key := range keys {
+		targets = append(targets, w.target(key))
+	}
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", credentialScript)
+	cmd.Env = append(os.Environ(), "DOCU_JARVIS_CREDENTIAL_OP="+op, "DOCU_JARVIS_CREDENTIALS="+strings.Join(targets, ","))
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// get reads every key in one PowerShell run, which takes a second to start.
+func (w windowsCredentials) get(keys []string) (map[string]string, error) {
+	out, err := w.credentials("read", "", keys...)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		target, value, found := strings.Cut(strings.TrimRight(line, "\r"), "=")
+		if key, ok := strings.CutPrefix(target, Service+":"); found && ok && value != "" {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+func (w windowsCredentials) set(key, value string) error {
+	_, err := w.credentials("write", value, key)
+	return err
+}
+
+func (w windowsCredentials) remove(key string) error {
+	_, err := w.credentials("delete", "", key)
+	return err
+}
@@ -0,0 +1,162 @@
+// Package secrets keeps tokens in the OS keychain: the macOS Keychain, the
+// Secret Service on Linux (through secret-tool), or the Windows Credential
+// Manager. The config file is still read for the tokens the keychain does not
+// hold.
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Service is the name the secrets are stored under in the keychain.
+const Service = "docu-jarvis"
+
+// indexFile, in ~/.docu-jarvis, lists the keys stored in the keychain, so the
+// keychain is only asked for those and nothing runs for users who never
+// logged in.
+const indexFile = "keychain"
+
+var (
+	mu     sync.Mutex
+	loaded map[string]string
+)
+
+// Name returns the keychain of this system, e.g. "macOS Keychain".
+func Name() string {
+	return backend().name()
+}
+
+// Available fails when there is no keychain to store secrets in, e.g. when
+// secret-tool is not installed.
+func Available() error {
+	return backend().available()
+}
+
+// Load returns the secrets stored with Store, by key. It is read once per run;
+// keys the keychain cannot return are left out.
+func Load() map[string]string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if loaded == nil {
+		loaded = make(map[string]string)
+		keys, err := Stored()
+		if err == nil && len(keys) > 0 && backend().available() == nil {
+			if values, err := backend().get(keys); err == nil {
+				loaded = values
+			}
+		}
+	}
+
+	secrets := make(map[string]string, len(loaded))
+	for key, value := range loaded {
+		secrets[key] = value
+	}
+	return secrets
+}
+
+// Store saves the secret in the keychain under key, e.g. "github_token".
+func Store(key, value string) error {
+	if err := Available(); err != nil {
+		return err
+	}
+	if err := backend().set(key, value); err != nil {
+		return fmt.Errorf("failed to store %s in the %s: %w", key, Name(), err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if loaded != nil {
+		loaded[key] = value
+	}
+	return updateIndex(key, true)
+}
+
+// Delete removes the secret from the keychain. Removing one that is not
+// stored is not an error.
+func Delete(key string) error {
+	if err := Available(); err != nil {
+		return err
+	}
+	if err := backend().remove(key); err != nil {
+		return fmt.Errorf("failed to remove %s from the %s: %w", key, Name(), err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	delete(loaded, key)
+	return updateIndex(key, false)
+}
+
+// Stored returns the keys stored in the keychain, sorted.
+func Stored() ([]string, error) {
+	path, err := indexPath()
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keychain index: %w", err)
+	}
+
+	var keys []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if key := strings.TrimSpace(line); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func updateIndex(key string, stored bool) error {
+	keys, err := Stored()
+	if err != nil {
+		return err
+	}
+
+	var updated []string
+	for _, existing := range keys {
+		if existing != key {
+			updated = append(updated, existing)
+		}
+	}
+	if stored {
+		updated = append(updated, key)
+	}
+	sort.Strings(updated)
+
+	path, err := indexPath()
+	if err != nil {
+		return err
+	}
+	if len(updated) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to update keychain index: %w", err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to update keychain index: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(updated, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to update keychain index: %w", err)
+	}
+	return nil
+}
+
+func indexPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".docu-jarvis", indexFile), nil
+}
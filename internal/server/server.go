@@ -0,0 +1,468 @@
+// Package server receives GitHub push webhooks and queues a docs update for
+// each push that changes watched code in a configured repository.
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/udemy/docu-jarvis-cli/internal/redact"
+)
+
+// Job states.
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// maxJobs is how many finished jobs the status endpoints keep.
+const maxJobs = 100
+
+// maxPayload caps webhook bodies; GitHub sends at most 25 MB.
+const maxPayload = 25 << 20
+
+const zeroCommit = "0000000000000000000000000000000000000000"
+
+// Job is a docs update for the pushes to a branch. Pushes that arrive while
+// it is still queued are merged into it.
+type Job struct {
+	ID       int        `json:"id"`
+	Repo     string     `json:"repo"` // as configured
+	Branch   string     `json:"branch"`
+	Before   string     `json:"before"` // zeroCommit for a new branch
+	After    string     `json:"after"`
+	Commits  []string   `json:"commits"`
+	Paths    []string   `json:"paths"` // the watched paths changed
+	Status   string     `json:"status"`
+	Result   string     `json:"result,omitempty"`
+	Error    string     `json:"error,omitempty"`
+	Created  time.Time  `json:"created"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
+
+// NewBranch reports whether the job's first push created the branch, so there
+// is no commit before it to diff against.
+func (j *Job) NewBranch() bool {
+	return j.Before == "" || j.Before == zeroCommit
+}
+
+// Runner updates the docs for a job and returns a one-line result.
+type Runner func(ctx context.Context, job Job) (string, error)
+
+// Options configure a Server.
+type Options struct {
+	Secret     string   // of the GitHub webhook
+	Repos      []string // repository URLs to handle pushes for
+	Branch     string   // branch to watch; "" for each repository's default branch
+	WatchPaths []string // directories or globs; none for every path
+}
+
+// Server queues a job for each relevant push and runs the jobs one at a time.
+type Server struct {
+	opts    Options
+	run     Runner
+	started time.Time
+
+	mu     sync.Mutex
+	jobs   []*Job // oldest first
+	nextID int
+	wake   chan struct{}
+}
+
+func New(opts Options, run Runner) (*Server, error) {
+	if opts.Secret == "" {
+		return nil, fmt.Errorf("webhook secret not configured: set webhook_secret or DOCU_JARVIS_WEBHOOK_SECRET, or store it with 'docu-jarvis auth login -provider webhook'")
+	}
+	if len(opts.Repos) == 0 {
+		return nil, fmt.Errorf("no repositories configured")
+	}
+	return &Server{
+		opts:    opts,
+		run:     run,
+		started: time.Now(),
+		nextID:  1,
+		wake:    make(chan struct{}, 1),
+	}, nil
+}
+
+// Handler serves the webhook at /webhook and the status at /status, /jobs,
+// and /jobs/<id>.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/webhook", s.handleWebhook)
+	mux.HandleFunc("/status", s.requireSecret(s.handleStatus))
+	mux.HandleFunc("/jobs", s.requireSecret(s.handleJobs))
+	mux.HandleFunc("/jobs/", s.requireSecret(s.handleJob))
+	return mux
+}
+
+// requireSecret serves only requests that send the webhook secret as a
+// bearer token, as jobs name repositories, commits, and errors.
+func (s *Server) requireSecret(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !hmac.Equal([]byte(token), []byte(s.opts.Secret)) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="docu-jarvis"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid bearer token"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Work runs the queued jobs until ctx is done.
+func (s *Server) Work(ctx context.Context) {
+	for {
+		job := s.next()
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.wake:
+				continue
+			}
+		}
+
+		fmt.Printf("[job %d] Updating docs for %s@%s (%d commits)\n", job.ID, redact.String(job.Repo), job.Branch, len(job.Commits))
+		result, err := s.run(ctx, *job)
+
+		s.mu.Lock()
+		now := time.Now()
+		job.Finished = &now
+		if err != nil {
+			job.Status = StatusFailed
+			job.Error = err.Error()
+		} else {
+			job.Status = StatusSucceeded
+			job.Result = result
+		}
+		s.prune()
+		s.mu.Unlock()
+
+		if err != nil {
+			fmt.Printf("[job %d] OH NO!!!!  Failed: %v\n", job.ID, err)
+		} else {
+			fmt.Printf("[job %d] ✓ %s\n", job.ID, result)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// next marks the oldest queued job as running and returns it.
+func (s *Server) next() *Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, job := range s.jobs {
+		if job.Status == StatusQueued {
+			now := time.Now()
+			job.Status = StatusRunning
+			job.Started = &now
+			return job
+		}
+	}
+	return nil
+}
+
+// prune drops the oldest finished jobs beyond maxJobs.
+func (s *Server) prune() {
+	finished := 0
+	for _, job := range s.jobs {
+		if job.Finished != nil {
+			finished++
+		}
+	}
+	var kept []*Job
+	for _, job := range s.jobs {
+		if job.Finished != nil && finished > maxJobs {
+			finished--
+			continue
+		}
+		kept = append(kept, job)
+	}
+	s.jobs = kept
+}
+
+// pushEvent is the part of a GitHub push payload the server uses.
+type pushEvent struct {
+	Ref     string `json:"ref"`
+	Before  string `json:"before"`
+	After   string `json:"after"`
+	Deleted bool   `json:"deleted"`
+	Commits []struct {
+		ID       string   `json:"id"`
+		Added    []string `json:"added"`
+		Removed  []string `json:"removed"`
+		Modified []string `json:"modified"`
+	} `json:"commits"`
+	Repository struct {
+		FullName      string `json:"full_name"`
+		CloneURL      string `json:"clone_url"`
+		SSHURL        string `json:"ssh_url"`
+		HTMLURL       string `json:"html_url"`
+		DefaultBranch string `json:"default_branch"`
+	} `json:"repository"`
+}
+
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "use POST"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPayload))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "failed to read body"})
+		return
+	}
+	if !validSignature(s.opts.Secret, body, r.Header.Get("X-Hub-Signature-256")) {
+		fmt.Printf("Warning: rejected a webhook delivery with a bad signature from %s\n", r.RemoteAddr)
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid signature"})
+		return
+	}
+
+	switch event := r.Header.Get("X-GitHub-Event"); event {
+	case "ping":
+		writeJSON(w, http.StatusOK, map[string]string{"status": "pong"})
+		return
+	case "push":
+	default:
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "ignored", "reason": fmt.Sprintf("%s events are not handled", event)})
+		return
+	}
+
+	var push pushEvent
+	if err := json.Unmarshal(body, &push); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid push payload"})
+		return
+	}
+
+	job, reason := s.enqueue(push)
+	if job == nil {
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "ignored", "reason": reason})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// validSignature checks the X-Hub-Signature-256 header, an HMAC-SHA256 of the
+// body keyed with the webhook secret.
+func validSignature(secret string, body []byte, header string) bool {
+	signature, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// enqueue queues a job for the push, or merges it into the branch's queued
+// job, and returns it; or the reason the push was ignored.
+func (s *Server) enqueue(push pushEvent) (*Job, string) {
+	repoURL := s.matchRepo(push)
+	if repoURL == "" {
+		return nil, fmt.Sprintf("%s is not a configured repository", push.Repository.FullName)
+	}
+
+	branch, ok := strings.CutPrefix(push.Ref, "refs/heads/")
+	if !ok {
+		return nil, fmt.Sprintf("%s is not a branch", push.Ref)
+	}
+	watched := s.opts.Branch
+	if watched == "" {
+		watched = push.Repository.DefaultBranch
+	}
+	if branch != watched {
+		return nil, fmt.Sprintf("only pushes to %s are handled", watched)
+	}
+	if push.Deleted || push.After == zeroCommit {
+		return nil, "the branch was deleted"
+	}
+
+	var commits []string
+	changed := make(map[string]bool)
+	for _, commit := range push.Commits {
+		commits = append(commits, commit.ID)
+		for _, files := range [][]string{commit.Added, commit.Removed, commit.Modified} {
+			for _, file := range files {
+				if s.watched(file) {
+					changed[file] = true
+				}
+			}
+		}
+	}
+	if len(changed) == 0 {
+		return nil, "no watched paths changed"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if job.Status == StatusQueued && job.Repo == repoURL && job.Branch == branch {
+			job.After = push.After
+			job.Commits = append(job.Commits, commits...)
+			job.Paths = mergePaths(job.Paths, changed)
+			fmt.Printf("[job %d] Added %d commits pushed to %s@%s\n", job.ID, len(commits), redact.String(repoURL), branch)
+			copied := *job
+			return &copied, ""
+		}
+	}
+
+	job := &Job{
+		ID:      s.nextID,
+		Repo:    repoURL,
+		Branch:  branch,
+		Before:  push.Before,
+		After:   push.After,
+		Commits: commits,
+		Paths:   mergePaths(nil, changed),
+		Status:  StatusQueued,
+		Created: time.Now(),
+	}
+	s.nextID++
+	s.jobs = append(s.jobs, job)
+	fmt.Printf("[job %d] Queued: %d watched files changed in %s@%s\n", job.ID, len(job.Paths), redact.String(repoURL), branch)
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	copied := *job
+	return &copied, ""
+}
+
+func mergePaths(paths []string, changed map[string]bool) []string {
+	for _, p := range paths {
+		changed[p] = true
+	}
+	merged := make([]string, 0, len(changed))
+	for p := range changed {
+		merged = append(merged, p)
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+// matchRepo returns the configured repository the push is for, or "".
+func (s *Server) matchRepo(push pushEvent) string {
+	for _, repoURL := range s.opts.Repos {
+		key := repoKey(repoURL)
+		for _, url := range []string{push.Repository.CloneURL, push.Repository.SSHURL, push.Repository.HTMLURL} {
+			if url != "" && repoKey(url) == key {
+				return repoURL
+			}
+		}
+	}
+	return ""
+}
+
+// repoKey reduces the HTTPS and SSH URLs of a repository to host/owner/repo.
+func repoKey(url string) string {
+	key := strings.ToLower(strings.TrimSpace(url))
+	if i := strings.Index(key, "://"); i >= 0 {
+		key = key[i+3:]
+	}
+	if i := strings.Index(key, "@"); i >= 0 {
+		key = key[i+1:]
+	}
+	key = strings.Replace(key, ":", "/", 1)
+	return strings.TrimSuffix(strings.TrimSuffix(key, "/"), ".git")
+}
+
+// watched reports whether a change to the file triggers a docs update.
+func (s *Server) watched(file string) bool {
+	if len(s.opts.WatchPaths) == 0 {
+		return true
+	}
+	for _, pattern := range s.opts.WatchPaths {
+		pattern = strings.Trim(pattern, "/")
+		if file == pattern || strings.HasPrefix(file, pattern+"/") {
+			return true
+		}
+		if matched, _ := path.Match(pattern, file); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	counts := map[string]int{StatusQueued: 0, StatusRunning: 0, StatusSucceeded: 0, StatusFailed: 0}
+	for _, job := range s.jobs {
+		counts[job.Status]++
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  "ok",
+		"started": s.started,
+		"repos":   s.opts.Repos,
+		"jobs":    counts,
+	})
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	jobs := make([]Job, 0, len(s.jobs))
+	for i := len(s.jobs) - 1; i >= 0; i-- {
+		jobs = append(jobs, *s.jobs[i])
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/jobs/"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no such job"})
+		return
+	}
+
+	var found *Job
+	s.mu.Lock()
+	for _, job := range s.jobs {
+		if job.ID == id {
+			copied := *job
+			found = &copied
+		}
+	}
+	s.mu.Unlock()
+
+	if found == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no such job"})
+		return
+	}
+	writeJSON(w, http.StatusOK, found)
+}
+
+// writeJSON responds with v, masking any tokens in the repository URLs.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	content, _ := json.MarshalIndent(v, "", "  ")
+	io.WriteString(w, redact.String(string(content))+"\n")
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testSecret = "webhook-secret"
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	s, err := New(Options{
+		Secret:     testSecret,
+		Repos:      []string{"https://github.com/acme/api.git"},
+		WatchPaths: []string{"src", "*.go"},
+	}, func(ctx context.Context, job Job) (string, error) { return "updated", nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func sign(body string) string {
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// pushPayload is a push to acme/api, whose default branch is main, with one
+// commit per changed file.
+func pushPayload(ref, after string, files ...string) string {
+	var commits []map[string]interface{}
+	for i, file := range files {
+		commits = append(commits, map[string]interface{}{"id": after + "-" + string(rune('a'+i)), "modified": []string{file}})
+	}
+	payload, _ := json.Marshal(map[string]interface{}{
+		"ref":     ref,
+		"before":  "1111111111111111111111111111111111111111",
+		"after":   after,
+		"commits": commits,
+		"repository": map[string]string{
+			"full_name":      "acme/api",
+			"clone_url":      "https://github.com/acme/api.git",
+			"ssh_url":        "git@github.com:acme/api.git",
+			"default_branch": "main",
+		},
+	})
+	return string(payload)
+}
+
+// deliver posts a webhook delivery and decodes the JSON response into out.
+func deliver(t *testing.T, ts *httptest.Server, event, body, signature string, out interface{}) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/webhook", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-GitHub-Event", event)
+	if signature != "" {
+		req.Header.Set("X-Hub-Signature-256", signature)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("failed to decode the response: %v", err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestWebhookSignature(t *testing.T) {
+	_, ts := newTestServer(t)
+	body := `{"zen": "Keep it logically awesome."}`
+
+	tests := []struct {
+		name      string
+		signature string
+		want      int
+	}{
+		{name: "valid", signature: sign(body), want: http.StatusOK},
+		{name: "signed with another secret", signature: "sha256=" + strings.Repeat("ab", sha256.Size), want: http.StatusUnauthorized},
+		{name: "not hex", signature: "sha256=not-hex", want: http.StatusUnauthorized},
+		{name: "SHA-1 header", signature: "sha1=" + strings.TrimPrefix(sign(body), "sha256="), want: http.StatusUnauthorized},
+		{name: "missing", want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := deliver(t, ts, "ping", body, tt.signature, nil); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusEndpointsNeedToken(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{name: "jobs without token", path: "/jobs", want: http.StatusUnauthorized},
+		{name: "jobs with wrong token", path: "/jobs", token: "guess", want: http.StatusUnauthorized},
+		{name: "jobs with token", path: "/jobs", token: testSecret, want: http.StatusOK},
+		{name: "status without token", path: "/status", want: http.StatusUnauthorized},
+		{name: "job without token", path: "/jobs/1", want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, ts.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("GET %s status = %d, want %d", tt.path, resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookIgnoresPushes(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		body   string
+		reason string
+	}{
+		{
+			name:   "unwatched branch",
+			body:   pushPayload("refs/heads/feature", "2222", "src/api.go"),
+			reason: "only pushes to main are handled",
+		},
+		{
+			name:   "tag",
+			body:   pushPayload("refs/tags/v1.0.0", "2222", "src/api.go"),
+			reason: "refs/tags/v1.0.0 is not a branch",
+		},
+		{
+			name:   "unwatched paths",
+			body:   pushPayload("refs/heads/main", "2222", "docs/guide.md", "scripts/build.sh"),
+			reason: "no watched paths changed",
+		},
+		{
+			name:   "deleted branch",
+			body:   pushPayload("refs/heads/main", zeroCommit, "src/api.go"),
+			reason: "the branch was deleted",
+		},
+		{
+			name:   "other repository",
+			body:   strings.ReplaceAll(pushPayload("refs/heads/main", "2222", "src/api.go"), "acme/api", "acme/web"),
+			reason: "acme/web is not a configured repository",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var response map[string]string
+			if got := deliver(t, ts, "push", tt.body, sign(tt.body), &response); got != http.StatusAccepted {
+				t.Fatalf("status = %d, want %d", got, http.StatusAccepted)
+			}
+			if response["status"] != "ignored" || response["reason"] != tt.reason {
+				t.Errorf("response = %v, want ignored because %q", response, tt.reason)
+			}
+		})
+	}
+}
+
+func TestWebhookMergesIntoQueuedJob(t *testing.T) {
+	s, ts := newTestServer(t)
+
+	first := pushPayload("refs/heads/main", "2222", "src/api.go", "README.md")
+	var job Job
+	if got := deliver(t, ts, "push", first, sign(first), &job); got != http.StatusAccepted {
+		t.Fatalf("first push status = %d, want %d", got, http.StatusAccepted)
+	}
+	if job.ID != 1 || job.Status != StatusQueued {
+		t.Fatalf("first push job = %+v, want queued job 1", job)
+	}
+
+	second := pushPayload("refs/heads/main", "3333", "main.go", "src/api.go")
+	if got := deliver(t, ts, "push", second, sign(second), &job); got != http.StatusAccepted {
+		t.Fatalf("second push status = %d, want %d", got, http.StatusAccepted)
+	}
+	if job.ID != 1 {
+		t.Errorf("second push job ID = %d, want it merged into job 1", job.ID)
+	}
+	if job.Before != "1111111111111111111111111111111111111111" || job.After != "3333" {
+		t.Errorf("job range = %s..%s, want the first push's before and the second's after", job.Before, job.After)
+	}
+	if got := strings.Join(job.Commits, ","); got != "2222-a,2222-b,3333-a,3333-b" {
+		t.Errorf("job commits = %s, want both pushes' commits in order", got)
+	}
+	if got := strings.Join(job.Paths, ","); got != "main.go,src/api.go" {
+		t.Errorf("job paths = %s, want the watched paths of both pushes", got)
+	}
+
+	s.mu.Lock()
+	jobs := len(s.jobs)
+	s.mu.Unlock()
+	if jobs != 1 {
+		t.Errorf("server has %d jobs, want 1", jobs)
+	}
+
+	// A running job is not merged into
+	if running := s.next(); running == nil || running.ID != 1 {
+		t.Fatalf("next() = %+v, want job 1", running)
+	}
+	third := pushPayload("refs/heads/main", "4444", "src/handlers.go")
+	if got := deliver(t, ts, "push", third, sign(third), &job); got != http.StatusAccepted {
+		t.Fatalf("third push status = %d, want %d", got, http.StatusAccepted)
+	}
+	if job.ID != 2 || job.Status != StatusQueued {
+		t.Errorf("third push job = %+v, want a new queued job 2", job)
+	}
+}
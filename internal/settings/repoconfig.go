@@ -0,0 +1,494 @@
+package settings
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// RepoConfigFileName is the per-repository config, read from the repository
+// root. Its values override the global config; command-line flags override both.
+const RepoConfigFileName = ".docu-jarvis.toml"
+
+// repoConfigFileNames are tried in order; the YAML forms hold the same keys.
+var repoConfigFileNames = []string{RepoConfigFileName, ".docu-jarvis.yaml", ".docu-jarvis.yml"}
+
+// RepoConfig holds the settings a repository can override. Only the parts of
+// TOML and YAML needed for it are supported: top-level keys with string or
+// string list values.
+//
+//	docs_roots = ["docs", "website/docs"]
+//	edit_allowlist = ["docs/**", "README.md"]
+//	code_standards = [
+//	  "All exported functions have doc comments",
+//	  "Handle all errors explicitly",
+//	]
+//	code_standards_source = "git@github.com:org/eng-standards.git#go.md"
+//	commit_conventions = ["Reference a Jira ticket in the subject"]
+//	docs_style = ["Every document has an Examples section"]
+//	test_conventions = ["Use testify's require for assertions"]
+//	base_branch = "develop"
+//	pr_labels = ["documentation"]
+//	pr_title = "docs: update {total} documents"
+//	pr_reviewers = ["my-org/docs-team"]
+//	pr_draft = true
+type RepoConfig struct {
+	DocsRoots          []string
+	EditAllowlist      []string
+	CodeStandards      []string
+	StandardsSource    string
+	CommitConventions  []string
+	DocsStyle          []string
+	TestConventions    []string
+	BaseBranch         string
+	PRLabels           []string
+	PRTitle            string
+	PRBody             string
+	PRAssignees        []string
+	PRReviewers        []string
+	PRDraft            *bool // nil when the repository does not set it
+	PRSplit            string
+	PRSuggestReviewers *bool
+	path               string
+}
+
+// LoadForRepo loads the global config and applies the repository's
+// .docu-jarvis.toml on top of it, if there is one.
+func LoadForRepo(repoRoot string) (*Settings, error) {
+	s, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := LoadRepoConfig(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	if rc != nil {
+		s.applyRepoConfig(rc)
+	}
+
+	return s, nil
+}
+
+// LoadRepoConfig reads .docu-jarvis.toml (or .yaml) from repoRoot. It
+// returns nil when the repository has none.
+func LoadRepoConfig(repoRoot string) (*RepoConfig, error) {
+	var path string
+	var content []byte
+	for _, name := range repoConfigFileNames {
+		data, err := os.ReadFile(filepath.Join(repoRoot, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		path, content = filepath.Join(repoRoot, name), data
+		break
+	}
+	if path == "" {
+		return nil, nil
+	}
+
+	name := filepath.Base(path)
+	parse := parseTOML
+	if strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml") {
+		parse = parseYAML
+	}
+
+	values, err := parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+
+	rc := &RepoConfig{path: path}
+	for key, value := range values {
+		switch key {
+		case docsRootsKey:
+			for _, v := range value.list() {
+				root, err := cleanDocsRoot(v)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s: %w", name, err)
+				}
+				rc.DocsRoots = append(rc.DocsRoots, root)
+			}
+		case editAllowlistKey:
+			rc.EditAllowlist = value.list()
+		case codeStandardsKey:
+			rc.CodeStandards = value.list()
+		case commitConventionKey:
+			rc.CommitConventions = value.list()
+		case docsStyleKey:
+			rc.DocsStyle = value.list()
+		case testConventionsKey:
+			rc.TestConventions = value.list()
+		case baseBranchKey, prTitleKey, prBodyKey, prSplitKey, standardsSourceKey:
+			if value.isArray || len(value.items) != 1 {
+				return nil, fmt.Errorf("invalid %s: %s must be a string", name, key)
+			}
+			switch key {
+			case standardsSourceKey:
+				rc.StandardsSource = value.items[0]
+			case baseBranchKey:
+				rc.BaseBranch = value.items[0]
+			case prTitleKey:
+				rc.PRTitle = value.items[0]
+			case prBodyKey:
+				rc.PRBody = value.items[0]
+			default:
+				if split := value.items[0]; split != "dir" && split != "codeowners" {
+					return nil, fmt.Errorf("invalid %s: %s must be dir or codeowners", name, key)
+				}
+				rc.PRSplit = value.items[0]
+			}
+		case prLabelsKey:
+			rc.PRLabels = value.list()
+		case prAssigneesKey:
+			rc.PRAssignees = value.list()
+		case prReviewersKey:
+			rc.PRReviewers = value.list()
+		case prDraftKey, prSuggestKey:
+			if value.isArray || len(value.items) != 1 {
+				return nil, fmt.Errorf("invalid %s: %s must be true or false", name, key)
+			}
+			enabled, err := strconv.ParseBool(value.items[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %s must be true or false", name, key)
+			}
+			if key == prDraftKey {
+				rc.PRDraft = &enabled
+			} else {
+				rc.PRSuggestReviewers = &enabled
+			}
+		default:
+			return nil, fmt.Errorf("invalid %s: unknown key %q", name, key)
+		}
+	}
+
+	return rc, nil
+}
+
+func (s *Settings) applyRepoConfig(rc *RepoConfig) {
+	if len(rc.DocsRoots) > 0 {
+		s.DocsRoots = rc.DocsRoots
+	}
+	if len(rc.EditAllowlist) > 0 {
+		s.EditAllowlist = rc.EditAllowlist
+	}
+	if len(rc.CodeStandards) > 0 {
+		s.CodeStandards = strings.Join(rc.CodeStandards, "\n")
+	}
+	if rc.StandardsSource != "" {
+		s.StandardsSource = rc.StandardsSource
+	}
+	if len(rc.CommitConventions) > 0 {
+		s.CommitConventions = strings.Join(rc.CommitConventions, "\n")
+	}
+	if len(rc.DocsStyle) > 0 {
+		s.DocsStyle = strings.Join(rc.DocsStyle, "\n")
+	}
+	if len(rc.TestConventions) > 0 {
+		s.TestConventions = strings.Join(rc.TestConventions, "\n")
+	}
+	if rc.BaseBranch != "" {
+		s.BaseBranch = rc.BaseBranch
+	}
+	if len(rc.PRLabels) > 0 {
+		s.PRLabels = rc.PRLabels
+	}
+	if rc.PRTitle != "" {
+		s.PRTitle = rc.PRTitle
+	}
+	if rc.PRBody != "" {
+		s.PRBody = rc.PRBody
+	}
+	if len(rc.PRAssignees) > 0 {
+		s.PRAssignees = rc.PRAssignees
+	}
+	if len(rc.PRReviewers) > 0 {
+		s.PRReviewers = rc.PRReviewers
+	}
+	if rc.PRDraft != nil {
+		s.PRDraft = *rc.PRDraft
+	}
+	if rc.PRSplit != "" {
+		s.PRSplit = rc.PRSplit
+	}
+	if rc.PRSuggestReviewers != nil {
+		s.PRSuggestReviewers = *rc.PRSuggestReviewers
+	}
+	s.repoConfigPath = rc.path
+}
+
+// configValue is a string or a list of strings.
+type configValue struct {
+	items   []string
+	isArray bool
+}
+
+func (v configValue) list() []string {
+	return v.items
+}
+
+func parseTOML(content string) (map[string]configValue, error) {
+	values := make(map[string]configValue)
+	lines := strings.Split(content, "\n")
+
+	for i := 0; i < len(lines); i++ {
+		lineNum := i + 1
+		line := strings.TrimSpace(stripComment(lines[i]))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return nil, fmt.Errorf("line %d: tables are not supported", lineNum)
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNum)
+		}
+		key := strings.TrimSpace(parts[0])
+		raw := strings.TrimSpace(parts[1])
+
+		// Arrays may span several lines until the closing bracket
+		if strings.HasPrefix(raw, "[") {
+			for !strings.HasSuffix(raw, "]") && i+1 < len(lines) {
+				i++
+				raw += " " + strings.TrimSpace(stripComment(lines[i]))
+			}
+		}
+
+		value, err := parseTOMLValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", lineNum, key, err)
+		}
+		if _, exists := values[key]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key %q", lineNum, key)
+		}
+		values[key] = value
+	}
+
+	return values, nil
+}
+
+func parseTOMLValue(raw string) (configValue, error) {
+	// Booleans are kept as strings, like every other value
+	if raw == "true" || raw == "false" {
+		return configValue{items: []string{raw}}, nil
+	}
+
+	if !strings.HasPrefix(raw, "[") {
+		s, rest, err := parseTOMLString(raw)
+		if err != nil {
+			return configValue{}, err
+		}
+		if strings.TrimSpace(rest) != "" {
+			return configValue{}, fmt.Errorf("unexpected text after value: %s", rest)
+		}
+		return configValue{items: []string{s}}, nil
+	}
+
+	if !strings.HasSuffix(raw, "]") {
+		return configValue{}, fmt.Errorf("unterminated array")
+	}
+
+	value := configValue{isArray: true}
+	rest := strings.TrimSpace(raw[1 : len(raw)-1])
+	for rest != "" {
+		item, remaining, err := parseTOMLString(rest)
+		if err != nil {
+			return configValue{}, err
+		}
+		value.items = append(value.items, item)
+
+		remaining = strings.TrimSpace(remaining)
+		if remaining != "" && !strings.HasPrefix(remaining, ",") {
+			return configValue{}, fmt.Errorf("expected , between array items")
+		}
+		rest = strings.TrimSpace(strings.TrimPrefix(remaining, ","))
+	}
+
+	return value, nil
+}
+
+// tomlEscapes are the single-character escapes of TOML basic strings.
+var tomlEscapes = map[byte]byte{'b': '\b', 't': '\t', 'n': '\n', 'f': '\f', 'r': '\r', 'e': 0x1b, '"': '"', '\\': '\\'}
+
+// parseTOMLString reads a basic ("...") or literal ('...') string from the
+// start of raw and returns it with the remaining text. Literal strings are
+// taken as they are; basic strings take the TOML escapes.
+func parseTOMLString(raw string) (string, string, error) {
+	if strings.HasPrefix(raw, "'") {
+		end := strings.Index(raw[1:], "'")
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated string")
+		}
+		return raw[1 : end+1], raw[end+2:], nil
+	}
+
+	if !strings.HasPrefix(raw, `"`) {
+		return "", "", fmt.Errorf("expected a quoted string or an array of strings")
+	}
+
+	var s strings.Builder
+	for i := 1; i < len(raw); i++ {
+		switch raw[i] {
+		case '"':
+			return s.String(), raw[i+1:], nil
+		case '\\':
+			if i+1 == len(raw) {
+				return "", "", fmt.Errorf("unterminated string")
+			}
+			i++
+			if r, ok := tomlEscapes[raw[i]]; ok {
+				s.WriteByte(r)
+				continue
+			}
+			digits := map[byte]int{'x': 2, 'u': 4, 'U': 8}[raw[i]]
+			if digits == 0 {
+				return "", "", fmt.Errorf("invalid escape \\%c in string", raw[i])
+			}
+			if i+digits >= len(raw) {
+				return "", "", fmt.Errorf("invalid escape \\%s in string", raw[i:])
+			}
+			code, err := strconv.ParseUint(raw[i+1:i+1+digits], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(code)) {
+				return "", "", fmt.Errorf("invalid escape \\%s in string", raw[i:i+1+digits])
+			}
+			s.WriteRune(rune(code))
+			i += digits
+		default:
+			s.WriteByte(raw[i])
+		}
+	}
+	return "", "", fmt.Errorf("unterminated string")
+}
+
+// stripComment drops a # comment that starts the line or follows whitespace,
+// outside a quoted value. Only a value that starts with a quote is quoted, so
+// the apostrophe in "title: Bob's docs # note" does not hide the comment.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		startsValue := i == 0 || strings.IndexByte(" \t=[,", line[i-1]) >= 0
+		switch {
+		case quote == 0 && (c == '"' || c == '\'') && startsValue:
+			quote = c
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && c == '#' && startsValue:
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// quotedFlowItem matches a flow list with an item that starts with a quote.
+var quotedFlowItem = regexp.MustCompile(`[\[,]\s*["']`)
+
+// parseYAML reads top-level "key: value" pairs, where a value is a scalar, a
+// flow list ([a, b]), or a block list of "- item" lines below the key.
+func parseYAML(content string) (map[string]configValue, error) {
+	values := make(map[string]configValue)
+	var listKey string
+
+	for i, rawLine := range strings.Split(content, "\n") {
+		lineNum := i + 1
+		line := strings.TrimRight(stripComment(rawLine), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if listKey == "" || line == trimmed {
+				return nil, fmt.Errorf("line %d: list item without a key", lineNum)
+			}
+			item, err := parseYAMLScalar(strings.TrimSpace(strings.TrimPrefix(trimmed, "-")))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s: %w", lineNum, listKey, err)
+			}
+			value := values[listKey]
+			value.items = append(value.items, item)
+			values[listKey] = value
+			continue
+		}
+
+		if line != trimmed {
+			return nil, fmt.Errorf("line %d: nested mappings are not supported", lineNum)
+		}
+
+		parts := strings.SplitN(trimmed, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d: expected key: value", lineNum)
+		}
+		key := strings.TrimSpace(parts[0])
+		raw := strings.TrimSpace(parts[1])
+		if _, exists := values[key]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key %q", lineNum, key)
+		}
+
+		listKey = ""
+		switch {
+		case raw == "":
+			listKey = key
+			values[key] = configValue{isArray: true}
+		case strings.HasPrefix(raw, "["):
+			if !strings.HasSuffix(raw, "]") {
+				return nil, fmt.Errorf("line %d: %s: unterminated list", lineNum, key)
+			}
+			// Quoted items may contain commas, and read the same as in TOML
+			if quotedFlowItem.MatchString(raw) {
+				value, err := parseTOMLValue(raw)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %s: %w", lineNum, key, err)
+				}
+				values[key] = value
+				continue
+			}
+			value := configValue{isArray: true}
+			for _, item := range strings.Split(raw[1:len(raw)-1], ",") {
+				if strings.TrimSpace(item) == "" {
+					continue
+				}
+				parsed, err := parseYAMLScalar(strings.TrimSpace(item))
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %s: %w", lineNum, key, err)
+				}
+				value.items = append(value.items, parsed)
+			}
+			values[key] = value
+		default:
+			parsed, err := parseYAMLScalar(raw)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s: %w", lineNum, key, err)
+			}
+			values[key] = configValue{items: []string{parsed}}
+		}
+	}
+
+	return values, nil
+}
+
+func parseYAMLScalar(raw string) (string, error) {
+	if strings.HasPrefix(raw, `"`) || strings.HasPrefix(raw, "'") {
+		s, rest, err := parseTOMLString(raw)
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(rest) != "" {
+			return "", fmt.Errorf("unexpected text after value: %s", rest)
+		}
+		return s, nil
+	}
+	return raw, nil
+}
@@ -0,0 +1,167 @@
+package settings
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStripComment(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{`title: Bob's docs # note`, `title: Bob's docs `},
+		{`title = Bob's docs # note`, `title = Bob's docs `},
+		{`# a whole line`, ``},
+		{`pr_title = "docs # {total}" # note`, `pr_title = "docs # {total}" `},
+		{`pr_title="docs # {total}"`, `pr_title="docs # {total}"`},
+		{`pr_title = 'docs # {total}'`, `pr_title = 'docs # {total}'`},
+		{`pr_title = "say \"# hi\"" # note`, `pr_title = "say \"# hi\"" `},
+		{`pr_title: issue#42 fixes`, `pr_title: issue#42 fixes`},
+		{`pr_labels = ["docs", "it's # fine"] # note`, `pr_labels = ["docs", "it's # fine"] `},
+		{`- Bob's item # note`, `- Bob's item `},
+		{`pr_labels: [Bob's, docs] # note`, `pr_labels: [Bob's, docs] `},
+	}
+
+	for _, tt := range tests {
+		if got := stripComment(tt.line); got != tt.want {
+			t.Errorf("stripComment(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]configValue
+		wantErr bool
+	}{
+		{
+			name:    "apostrophe in an unquoted value",
+			content: "pr_title: Bob's docs # note\n",
+			want:    map[string]configValue{"pr_title": {items: []string{"Bob's docs"}}},
+		},
+		{
+			name:    "hash inside a quoted value",
+			content: "pr_title: \"docs # {total}\" # note\n",
+			want:    map[string]configValue{"pr_title": {items: []string{"docs # {total}"}}},
+		},
+		{
+			name:    "hash without whitespace",
+			content: "base_branch: release#2\n",
+			want:    map[string]configValue{"base_branch": {items: []string{"release#2"}}},
+		},
+		{
+			name:    "block list with comments",
+			content: "docs_style: # rules\n  - Bob's rule # first\n  - \"Use # sparingly\"\n",
+			want:    map[string]configValue{"docs_style": {items: []string{"Bob's rule", "Use # sparingly"}, isArray: true}},
+		},
+		{
+			name:    "flow list",
+			content: "pr_labels: [docs, Bob's] # note\n",
+			want:    map[string]configValue{"pr_labels": {items: []string{"docs", "Bob's"}, isArray: true}},
+		},
+		{
+			name:    "text after a quoted value",
+			content: "pr_title: \"docs\" extra\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML(tt.content)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]configValue
+		wantErr bool
+	}{
+		{
+			name:    "hash inside a quoted value",
+			content: "pr_title = \"docs # {total}\" # note\n",
+			want:    map[string]configValue{"pr_title": {items: []string{"docs # {total}"}}},
+		},
+		{
+			name:    "apostrophe inside a quoted value",
+			content: "pr_title = \"Bob's docs\" # note\n",
+			want:    map[string]configValue{"pr_title": {items: []string{"Bob's docs"}}},
+		},
+		{
+			name:    "multi-line array with comments",
+			content: "docs_style = [\n  \"Bob's rule\", # first\n  'Use # sparingly',\n]\n",
+			want:    map[string]configValue{"docs_style": {items: []string{"Bob's rule", "Use # sparingly"}, isArray: true}},
+		},
+		{
+			name:    "literal string keeps backslashes",
+			content: "docs_roots = ['C:\\docs', '\\\\server\\share\\docs']\n",
+			want:    map[string]configValue{"docs_roots": {items: []string{`C:\docs`, `\\server\share\docs`}, isArray: true}},
+		},
+		{
+			name:    "basic string escapes",
+			content: "pr_title = \"C:\\\\docs\\t\\\"{total}\\\"\\n\"\n",
+			want:    map[string]configValue{"pr_title": {items: []string{"C:\\docs\t\"{total}\"\n"}}},
+		},
+		{
+			name:    "unicode and escape-character escapes",
+			content: "pr_title = \"\\u00e9t\\u00e9 \\U0001F4DA \\e[1m \\x41\"\n",
+			want:    map[string]configValue{"pr_title": {items: []string{"été 📚 \x1b[1m A"}}},
+		},
+		{
+			name:    "Go-only escape",
+			content: "pr_title = \"bell\\a\"\n",
+			wantErr: true,
+		},
+		{
+			name:    "escape beyond unicode",
+			content: "pr_title = \"\\U00110000\"\n",
+			wantErr: true,
+		},
+		{
+			name:    "short unicode escape",
+			content: "pr_title = \"\\u00e\"\n",
+			wantErr: true,
+		},
+		{
+			name:    "unquoted value",
+			content: "pr_title = Bob's docs # note\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTOML(tt.content)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
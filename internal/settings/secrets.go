@@ -0,0 +1,118 @@
+package settings
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/udemy/docu-jarvis-cli/internal/secrets"
+)
+
+// Secret is a token that can be kept in the OS keychain with auth login
+// instead of in plaintext in the config file.
+type Secret struct {
+	Name   string // the -provider of auth, e.g. "github"
+	Key    string // the config key, e.g. "github_token"
+	EnvVar string // overrides both the keychain and the config file
+}
+
+var Secrets = []Secret{
+	{Name: "github", Key: githubTokenKey, EnvVar: "GITHUB_TOKEN"},
+	{Name: "gitlab", Key: gitlabTokenKey, EnvVar: "GITLAB_TOKEN"},
+	{Name: "bitbucket", Key: bitbucketTokenKey, EnvVar: "BITBUCKET_TOKEN"},
+	{Name: "anthropic", Key: anthropicAPIKeyKey, EnvVar: "ANTHROPIC_API_KEY"},
+	{Name: "webhook", Key: webhookSecretKey, EnvVar: "DOCU_JARVIS_WEBHOOK_SECRET"},
+}
+
+// FindSecret returns the secret of an auth -provider.
+func FindSecret(name string) (Secret, bool) {
+	for _, secret := range Secrets {
+		if secret.Name == strings.ToLower(name) {
+			return secret, true
+		}
+	}
+	return Secret{}, false
+}
+
+// Where a secret's value comes from; see SecretSource.
+const (
+	SourceEnv      = "environment"
+	SourceKeychain = "OS keychain"
+	SourceFile     = "config file (plaintext)"
+	SourceNone     = "not set"
+)
+
+func (s *Settings) secretField(key string) *string {
+	switch key {
+	case githubTokenKey:
+		return &s.GitHubToken
+	case gitlabTokenKey:
+		return &s.GitLabToken
+	case bitbucketTokenKey:
+		return &s.BitbucketToken
+	case anthropicAPIKeyKey:
+		return &s.AnthropicAPIKey
+	case webhookSecretKey:
+		return &s.WebhookSecret
+	}
+	return nil
+}
+
+// applyKeychain replaces the config file's tokens with those stored in the
+// keychain.
+func (s *Settings) applyKeychain() {
+	stored := secrets.Load()
+	for _, secret := range Secrets {
+		if value, ok := stored[secret.Key]; ok {
+			*s.secretField(secret.Key) = value
+			if s.keychainKeys == nil {
+				s.keychainKeys = make(map[string]bool)
+			}
+			s.keychainKeys[secret.Key] = true
+		}
+	}
+}
+
+// SecretSource returns where the secret used comes from, e.g. SourceKeychain.
+func (s *Settings) SecretSource(secret Secret) string {
+	value := *s.secretField(secret.Key)
+	switch {
+	case os.Getenv(secret.EnvVar) != "":
+		return SourceEnv
+	case s.keychainKeys[secret.Key]:
+		return SourceKeychain
+	case value != "" && value != githubTokenPlaceholder:
+		return SourceFile
+	}
+	return SourceNone
+}
+
+// RemovePlaintext comments out the secret's lines in the config file, once
+// it is in the keychain, and reports whether there were any. The placeholder
+// of the template is left alone.
+func (s *Settings) RemovePlaintext(secret Secret) (bool, error) {
+	content, err := os.ReadFile(s.configPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	removed := false
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+		value = strings.TrimSpace(value)
+		if !found || strings.TrimSpace(key) != secret.Key || value == "" || value == githubTokenPlaceholder {
+			continue
+		}
+		lines[i] = fmt.Sprintf("# %s is stored in the %s (docu-jarvis auth login -provider %s)", secret.Key, secrets.Name(), secret.Name)
+		removed = true
+	}
+	if !removed {
+		return false, nil
+	}
+
+	if err := os.WriteFile(s.configPath, []byte(strings.Join(lines, "\n")), 0600); err != nil {
+		return false, fmt.Errorf("failed to write config: %w", err)
+	}
+	return true, nil
+}
@@ -5,22 +5,164 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/udemy/docu-jarvis-cli/internal/redact"
 )
 
 const (
 	configDirName       = ".docu-jarvis"
 	configFileName      = "config"
 	codeStandardsKey    = "code_standards"
+	standardsSourceKey  = "code_standards_source"
 	repoURLKey          = "repo"
 	githubTokenKey      = "github_token"
+	commitConventionKey = "commit_conventions"
+	docsStyleKey        = "docs_style"
+	testConventionsKey  = "test_conventions"
+	cloneDirKey         = "clone_dir"
+	cloneDepthKey       = "clone_depth"
+	reuseCloneKey       = "reuse_clone"
+	docsRootsKey        = "docs_roots"
+	editAllowlistKey    = "edit_allowlist"
+	keepAnchorsKey      = "keep_anchors"
+	branchKey           = "branch"
+	hostingProviderKey  = "hosting_provider"
+	gitlabTokenKey      = "gitlab_token"
+	bitbucketTokenKey   = "bitbucket_token"
+	baseBranchKey       = "base_branch"
+	prLabelsKey         = "pr_labels"
+	prTitleKey          = "pr_title"
+	prBodyKey           = "pr_body"
+	prAssigneesKey      = "pr_assignees"
+	prReviewersKey      = "pr_reviewers"
+	prDraftKey          = "pr_draft"
+	prSplitKey          = "pr_split"
+	prSuggestKey        = "pr_suggest_reviewers"
+	prDigestKey         = "pr_digest_comments"
+	retentionDaysKey    = "retention_days"
+	retentionLogMBKey   = "retention_log_mb"
+	logLevelKey         = "log_level"
+	logMaxMBKey         = "log_max_mb"
+	providerKey         = "provider"
+	modelKey            = "model"
+	anthropicAPIKeyKey  = "anthropic_api_key"
+	awsRegionKey        = "aws_region"
+	vertexProjectKey    = "vertex_project"
+	vertexRegionKey     = "vertex_region"
+	localURLKey         = "local_url"
+	localToolsKey       = "local_tools"
+	webhookSecretKey    = "webhook_secret"
+	watchPathsKey       = "watch_paths"
+	maxTurnsKey         = "max_turns"
+	maxTokensKey        = "max_tokens"
+)
+
+// providers are the valid provider values; agent.NewProvider implements them.
+var providers = []string{"claude-code", "anthropic", "bedrock", "vertex", "local"}
+
+// logLevels are the valid log_level values.
+var logLevels = []string{"debug", "info", "warn", "error"}
+
+// modes are the valid [modes.<name>] sections; the agent.Mode* constants name
+// them.
+var modes = []string{
+	"update-docs", "write-docs", "check-existing-docs", "audit-docs", "docs-gap", "lint-docs",
+	"debug", "explain", "review-code", "docs-impact", "review-checklist", "check-commits",
+	"squash-summary", "changelog", "review-feedback", "infer-standards", "write-tests",
+	"write-api-docs", "compare-judge", "triage",
+}
+
+// ModeSettings override the model and limits of one mode's requests, from a
+// [modes.<name>] section. Zero values keep the defaults.
+type ModeSettings struct {
+	Model     string
+	MaxTurns  int
+	MaxTokens int
+}
+
+// Retention defaults, used when the config does not set them.
+const (
+	DefaultRetentionDays  = 30
+	DefaultRetentionLogMB = 50
+	DefaultLogMaxMB       = 10
 )
 
+// githubTokenPlaceholder is the value written by the config template.
+const githubTokenPlaceholder = "ghp_your_token_here"
+
+// DefaultCommitConventions is used by -check-commits when none are configured.
+const DefaultCommitConventions = `Subject follows Conventional Commits: <type>(<optional scope>): <description>
+Type is one of: feat, fix, docs, style, refactor, perf, test, build, ci, chore, revert
+Breaking changes are marked with ! after the type/scope or a BREAKING CHANGE: footer
+Description is in the imperative mood, lowercase, and has no trailing period
+Subject line is at most 72 characters
+Body, if present, is separated from the subject by a blank line`
+
+// DefaultDocsStyle is used by lint-docs when no style guide is configured.
+const DefaultDocsStyle = `The document has exactly one top-level (#) heading, its title
+Heading levels are not skipped (no ### directly under #)
+The document has an "Overview" section near the top that says what it covers
+Documents about an API, command, or configuration have an "Examples" section
+Product, command, and flag names are spelled and capitalized consistently
+Code, commands, file names, and flags are formatted as inline code or code blocks
+Code blocks name their language`
+
+// DefaultTestConventions is used by write-tests when none are configured.
+const DefaultTestConventions = `Tests use the standard test framework of the language and the assertion helpers the repository already uses
+Table-driven tests cover the cases of a function in one test
+Each test name says the behaviour it checks
+Tests do not touch the network, sleep, or depend on the current time`
+
 type Settings struct {
-	RepoURL       string
-	CodeStandards string
-	GitHubToken   string
-	configPath    string
+	RepoURL            string   // the first of Repos
+	Repos              []string // every configured repo, in order
+	CodeStandards      string
+	StandardsSource    string // <repo>#<file> of shared code standards
+	CommitConventions  string
+	DocsStyle          string
+	TestConventions    string
+	GitHubToken        string
+	GitLabToken        string
+	BitbucketToken     string
+	HostingProvider    string
+	CloneDir           string
+	CloneDepth         int
+	ReuseClone         bool
+	DocsRoots          []string
+	EditAllowlist      []string // paths the agent may edit, the docs roots when empty
+	KeepAnchors        bool     // keep the heading anchors of updated docs
+	Branch             string
+	BaseBranch         string // PR base, when it differs from Branch
+	PRLabels           []string
+	PRTitle            string // title template, see git.ExpandPRTemplate
+	PRBody             string // body template
+	PRAssignees        []string
+	PRReviewers        []string
+	PRDraft            bool
+	PRSplit            string // "dir" or "codeowners" for one PR per area
+	PRSuggestReviewers bool
+	PRDigestComments   bool
+	RetentionDays      int    // 0 keeps data forever
+	RetentionLogMB     int    // total size of the logs, 0 for no limit
+	LogLevel           string // debug, info, warn, or error
+	LogMaxMB           int    // size a run's log is rotated at, 0 for never
+	Provider           string // "" for Claude Code
+	Model              string
+	AnthropicAPIKey    string
+	AWSRegion          string
+	VertexProject      string
+	VertexRegion       string
+	LocalURL           string // OpenAI-compatible endpoint of the local model
+	LocalTools         string // "native" or "prompt"
+	WebhookSecret      string
+	WatchPaths         []string // paths whose changes serve updates docs for
+	Modes              map[string]ModeSettings
+	configPath         string
+	repoConfigPath     string
+	keychainKeys       map[string]bool // the secrets read from the OS keychain
 }
 
 func Load() (*Settings, error) {
@@ -41,18 +183,159 @@ func Load() (*Settings, error) {
 # Lines starting with # are comments
 
 # Repository URL (required for documentation commands)
+# Add one repo line per repository to work on several; the first is the default,
+# and -repo <name|url> or update-docs -all-repos select others.
 repo = https://github.com/your-org/your-repo.git
 
 # GitHub Personal Access Token (required for private repos and updates)
 # Create at: https://github.com/settings/tokens with 'repo' scope
+# 'docu-jarvis auth login' keeps it in the OS keychain instead of this file
 github_token = ghp_your_token_here
 
+# Pull request hosting (optional)
+# Detected from the repository URL; set it for self-hosted GitLab or Bitbucket:
+# hosting_provider = gitlab
+# GitLab token with 'api' scope (or set GITLAB_TOKEN)
+# gitlab_token = glpat-your_token_here
+# Bitbucket Cloud access token, or username:app_password (or set BITBUCKET_TOKEN)
+# bitbucket_token = your_token_here
+
+# Clone settings (optional)
+# Directory that repositories are cloned into (default: /tmp, or %TEMP% on Windows)
+# clone_dir = ~/.docu-jarvis/repos
+# Shallow clone depth, 0 for a full clone (default: 0)
+# clone_depth = 50
+# Reuse an existing clone with git fetch + reset instead of re-cloning (default: false)
+# reuse_clone = true
+# Branch to clone and analyze, also the base for PRs (default: the remote's default branch)
+# branch = develop
+# Branch PRs target, when it differs from the branch above
+# base_branch = main
+# Labels added to documentation PRs (one per line)
+# pr_labels = documentation
+# PR title and body; {summary} is the list of documents the run updated, see
+# 'docu-jarvis help config' for the other placeholders (pr_body: one line each)
+# pr_title = docs: update {total} documents in {repo}
+# pr_body = Automated docu-jarvis suggestions
+# pr_body = {summary}
+# Assignees and reviewers (one per line); GitHub teams as org/team
+# pr_assignees = octocat
+# pr_reviewers = my-org/docs-team
+# Open PRs as drafts (default: false)
+# pr_draft = true
+# Split the changes into one PR per docs subdirectory (dir) or per CODEOWNERS
+# owners (codeowners), so each team reviews only its docs (default: one PR)
+# pr_split = codeowners
+# Request the most active recent contributors to the code each changed doc
+# describes as reviewers (default: false)
+# pr_suggest_reviewers = true
+# Comment on each changed doc in the PR with what was changed and why
+# (default: false)
+# pr_digest_comments = true
+
+# Documentation directories, relative to the repository root (one per line)
+# New topics are written to the first root inside the -scope, if any.
+# Defaults to documentation/ when not set:
+# docs_roots = docs
+# docs_roots = website/docs
+# docs_roots = services/payments/docs
+# Paths Claude may edit, as CODEOWNERS-style patterns (one per line). Edits
+# anywhere else are reverted after each run, with a warning listing them.
+# Defaults to the docs roots when not set:
+# edit_allowlist = docs/**
+# edit_allowlist = README.md
+# Keep the anchors of existing headings when update-docs renames or removes
+# them, so deep links keep working (default: false)
+# keep_anchors = true
+
+# Model provider (optional)
+# How docu-jarvis reaches Claude: claude-code (the Claude Code CLI), anthropic
+# (the Anthropic API), bedrock (AWS Bedrock), or vertex (Google Cloud Vertex AI).
+# The API providers do not need the Claude Code CLI (default: claude-code)
+# provider = anthropic
+# Model to use, in the provider's naming (default: the provider's Claude Sonnet)
+# model = claude-sonnet-4-5
+# Anthropic API key (or set ANTHROPIC_API_KEY)
+# anthropic_api_key = sk-ant-your_key_here
+# AWS region for bedrock (or set AWS_REGION); credentials come from the AWS CLI
+# aws_region = us-east-1
+# Google Cloud project and region for vertex (or set ANTHROPIC_VERTEX_PROJECT_ID
+# and CLOUD_ML_REGION); credentials come from gcloud (default region: us-east5)
+# vertex_project = my-project
+# vertex_region = us-east5
+# Local model for provider = local, e.g. on Ollama, through an OpenAI-compatible
+# API; set model to its name (default: llama3.1 at http://localhost:11434/v1)
+# local_url = http://gpu-box.internal:8000/v1
+# How the local model uses the file tools: native tool calling, or prompt for
+# models without it, which get the files the request names in the prompt (default: prompt)
+# local_tools = native
+
+# Webhook server (optional, used by 'docu-jarvis serve')
+# Secret of the GitHub webhook, to verify deliveries (or set DOCU_JARVIS_WEBHOOK_SECRET)
+# webhook_secret = your_webhook_secret
+# Paths whose changes trigger a docs update, as directories or globs (one per
+# line). Defaults to any change when not set:
+# watch_paths = internal/api
+# watch_paths = cmd/*.go
+
+# Data retention (optional)
+# Logs, run state, saved conversations, usage history, clones in clone_dir, and
+# Claude Code session transcripts older than this many days are pruned on startup,
+# 0 keeps them (default: 30)
+# retention_days = 30
+# Maximum size of all logs together in MB, the oldest run logs are removed
+# first, 0 for no limit (default: 50)
+# retention_log_mb = 50
+
+# Logging (optional)
+# Each run logs to its own file in ~/.docu-jarvis/logs ('docu-jarvis logs tail'
+# shows the latest). Records below this level are left out: debug, info, warn,
+# or error; -vv logs debug records for one run (default: info)
+# log_level = debug
+# Size in MB at which a run's log moves to a backup, of which 3 are kept, 0 for
+# never (default: 10)
+# log_max_mb = 10
+
 # Code Quality Standards (one per line, used by -check-staging)
 # Uncomment and customize these or add your own:
 # code_standards = All functions must have documentation comments
 # code_standards = Use meaningful variable names
 # code_standards = Handle all errors explicitly
 # code_standards = No magic numbers - use named constants
+# Or share one list across repositories: a file in a git repository, fetched at
+# most hourly; code_standards lines here are added to it
+# code_standards_source = git@github.com:your-org/eng-standards.git#go.md
+
+# Commit Message Conventions (one per line, used by -check-commits)
+# Defaults to Conventional Commits when not set:
+# commit_conventions = Subject follows Conventional Commits: <type>(<scope>): <description>
+# commit_conventions = Reference a Jira ticket in the subject, e.g. (PAY-123)
+
+# Docs Style Guide (one rule per line, used by lint-docs)
+# Defaults to a basic guide (one title, Overview and Examples sections) when not set:
+# docs_style = Every document has "Overview" and "Examples" sections
+# docs_style = Write "sign in", never "log in" or "login"
+
+# Test Conventions (one per line, used by write-tests)
+# Defaults to table-driven tests with the language's standard framework when not set:
+# test_conventions = Use testify's require for assertions
+# test_conventions = Name tests Test<Function>_<Case>
+
+# Per-mode model and limits (optional)
+# A [modes.<name>] section sets the model, max_turns, and max_tokens (per
+# response, API providers only) of one kind of request, e.g. a cheap model for
+# checking whether write-docs topics exist and a strong one for debug. Modes:
+# update-docs, write-docs, check-existing-docs, audit-docs, docs-gap, lint-docs,
+# debug, explain, review-code (check-staging and review-pr), docs-impact,
+# review-checklist, check-commits, squash-summary, changelog, review-feedback,
+# infer-standards, write-tests, write-api-docs, compare-judge (update-docs
+# -compare), triage. Every key after a section is part of it, so keep the
+# sections at the end of the file.
+# [modes.check-existing-docs]
+# model = claude-haiku-4-5
+# [modes.debug]
+# model = claude-opus-4-1
+# max_turns = 40
 `
 		if err := os.WriteFile(configPath, []byte(template), 0644); err != nil {
 			return nil, fmt.Errorf("failed to create config template: %w", err)
@@ -65,63 +348,356 @@ github_token = ghp_your_token_here
 	}
 
 	settings := &Settings{
-		configPath: configPath,
+		RetentionDays:  DefaultRetentionDays,
+		RetentionLogMB: DefaultRetentionLogMB,
+		LogLevel:       "info",
+		LogMaxMB:       DefaultLogMaxMB,
+		configPath:     configPath,
 	}
 
 	var codeStandardsLines []string
+	var commitConventionLines []string
+	var docsStyleLines []string
+	var testConventionLines []string
+	var prBodyLines []string
+	mode := "" // the [modes.<name>] section the line is in
 	lines := strings.Split(string(content), "\n")
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
-		
+
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
 
+		if strings.HasPrefix(line, "[") {
+			name, ok := strings.CutPrefix(strings.TrimSuffix(line, "]"), "[modes.")
+			valid := false
+			for _, m := range modes {
+				valid = valid || name == m
+			}
+			if !ok || !strings.HasSuffix(line, "]") || !valid {
+				return nil, fmt.Errorf("invalid section %s (must be [modes.<name>], where name is one of %s)", line, strings.Join(modes, ", "))
+			}
+			mode = name
+			continue
+		}
+
 		if strings.Contains(line, "=") {
 			parts := strings.SplitN(line, "=", 2)
 			if len(parts) != 2 {
 				continue
 			}
-			
+
 			key := strings.TrimSpace(parts[0])
 			value := strings.TrimSpace(parts[1])
+			if mode != "" {
+				if err := settings.setMode(mode, key, value); err != nil {
+					return nil, err
+				}
+				continue
+			}
 
 			switch key {
 			case repoURLKey:
-				settings.RepoURL = value
+				if settings.RepoURL == "" {
+					settings.RepoURL = value
+				}
+				settings.Repos = append(settings.Repos, value)
 			case githubTokenKey:
 				settings.GitHubToken = value
+			case gitlabTokenKey:
+				settings.GitLabToken = value
+			case bitbucketTokenKey:
+				settings.BitbucketToken = value
+			case hostingProviderKey:
+				settings.HostingProvider = value
 			case codeStandardsKey:
 				codeStandardsLines = append(codeStandardsLines, value)
+			case commitConventionKey:
+				commitConventionLines = append(commitConventionLines, value)
+			case docsStyleKey:
+				docsStyleLines = append(docsStyleLines, value)
+			case testConventionsKey:
+				testConventionLines = append(testConventionLines, value)
+			case cloneDirKey:
+				settings.CloneDir = expandHome(value, homeDir)
+			case cloneDepthKey:
+				depth, err := strconv.Atoi(value)
+				if err != nil || depth < 0 {
+					return nil, fmt.Errorf("invalid %s: %q (must be a non-negative integer)", cloneDepthKey, value)
+				}
+				settings.CloneDepth = depth
+			case reuseCloneKey:
+				reuse, err := strconv.ParseBool(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s: %q (must be true or false)", reuseCloneKey, value)
+				}
+				settings.ReuseClone = reuse
+			case keepAnchorsKey:
+				keep, err := strconv.ParseBool(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s: %q (must be true or false)", keepAnchorsKey, value)
+				}
+				settings.KeepAnchors = keep
+			case standardsSourceKey:
+				settings.StandardsSource = value
+			case branchKey:
+				settings.Branch = value
+			case baseBranchKey:
+				settings.BaseBranch = value
+			case prLabelsKey:
+				settings.PRLabels = append(settings.PRLabels, value)
+			case prTitleKey:
+				settings.PRTitle = value
+			case prBodyKey:
+				prBodyLines = append(prBodyLines, value)
+			case prAssigneesKey:
+				settings.PRAssignees = append(settings.PRAssignees, value)
+			case prReviewersKey:
+				settings.PRReviewers = append(settings.PRReviewers, value)
+			case prDraftKey, prSuggestKey, prDigestKey:
+				enabled, err := strconv.ParseBool(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s: %q (must be true or false)", key, value)
+				}
+				switch key {
+				case prDraftKey:
+					settings.PRDraft = enabled
+				case prSuggestKey:
+					settings.PRSuggestReviewers = enabled
+				default:
+					settings.PRDigestComments = enabled
+				}
+			case prSplitKey:
+				if value != "dir" && value != "codeowners" {
+					return nil, fmt.Errorf("invalid %s: %q (must be dir or codeowners)", prSplitKey, value)
+				}
+				settings.PRSplit = value
+			case retentionDaysKey, retentionLogMBKey:
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("invalid %s: %q (must be a non-negative integer)", key, value)
+				}
+				if key == retentionDaysKey {
+					settings.RetentionDays = n
+				} else {
+					settings.RetentionLogMB = n
+				}
+			case logLevelKey:
+				valid := false
+				for _, level := range logLevels {
+					valid = valid || value == level
+				}
+				if !valid {
+					return nil, fmt.Errorf("invalid %s: %q (must be one of %s)", logLevelKey, value, strings.Join(logLevels, ", "))
+				}
+				settings.LogLevel = value
+			case logMaxMBKey:
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("invalid %s: %q (must be a non-negative integer)", key, value)
+				}
+				settings.LogMaxMB = n
+			case providerKey:
+				valid := false
+				for _, p := range providers {
+					valid = valid || value == p
+				}
+				if !valid {
+					return nil, fmt.Errorf("invalid %s: %q (must be one of %s)", providerKey, value, strings.Join(providers, ", "))
+				}
+				settings.Provider = value
+			case modelKey:
+				settings.Model = value
+			case anthropicAPIKeyKey:
+				settings.AnthropicAPIKey = value
+			case awsRegionKey:
+				settings.AWSRegion = value
+			case vertexProjectKey:
+				settings.VertexProject = value
+			case vertexRegionKey:
+				settings.VertexRegion = value
+			case localURLKey:
+				settings.LocalURL = value
+			case localToolsKey:
+				if value != "native" && value != "prompt" {
+					return nil, fmt.Errorf("invalid %s: %q (must be native or prompt)", localToolsKey, value)
+				}
+				settings.LocalTools = value
+			case webhookSecretKey:
+				settings.WebhookSecret = value
+			case watchPathsKey:
+				settings.WatchPaths = append(settings.WatchPaths, value)
+			case docsRootsKey:
+				root, err := cleanDocsRoot(value)
+				if err != nil {
+					return nil, err
+				}
+				settings.DocsRoots = append(settings.DocsRoots, root)
+			case editAllowlistKey:
+				settings.EditAllowlist = append(settings.EditAllowlist, value)
 			}
 		}
 	}
 
 	settings.CodeStandards = strings.Join(codeStandardsLines, "\n")
+	settings.CommitConventions = strings.Join(commitConventionLines, "\n")
+	settings.DocsStyle = strings.Join(docsStyleLines, "\n")
+	settings.TestConventions = strings.Join(testConventionLines, "\n")
+	settings.PRBody = strings.Join(prBodyLines, "\n")
+	settings.applyKeychain()
+
+	// Tokens are masked wherever they appear, whatever their format
+	redact.AddSecret(settings.GetGitHubToken())
+	redact.AddSecret(settings.GetGitLabToken())
+	redact.AddSecret(settings.GetBitbucketToken())
+	redact.AddSecret(settings.AnthropicAPIKey)
+	redact.AddSecret(settings.GetWebhookSecret())
 
 	return settings, nil
 }
 
+func expandHome(path, homeDir string) string {
+	if path == "~" {
+		return homeDir
+	}
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(homeDir, path[2:])
+	}
+	return path
+}
+
+// ParseDocsRoots reads a comma-separated list of docs roots, as given to
+// -docs-dir.
+func ParseDocsRoots(value string) ([]string, error) {
+	var roots []string
+	for _, part := range strings.Split(value, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		root, err := cleanDocsRoot(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, root)
+	}
+	return roots, nil
+}
+
+func cleanDocsRoot(value string) (string, error) {
+	root := filepath.ToSlash(filepath.Clean(value))
+	if filepath.IsAbs(root) || root == "." || root == ".." || strings.HasPrefix(root, "../") {
+		return "", fmt.Errorf("invalid %s: %q (must be a directory inside the repository)", docsRootsKey, value)
+	}
+	return root, nil
+}
+
 func (s *Settings) GetPath() string {
 	return s.configPath
 }
 
+// GetRepoConfigPath returns the .docu-jarvis.toml applied by LoadForRepo, if any.
+func (s *Settings) GetRepoConfigPath() string {
+	return s.repoConfigPath
+}
+
 func (s *Settings) IsEmpty() bool {
-	return strings.TrimSpace(s.CodeStandards) == ""
+	return strings.TrimSpace(s.CodeStandards) == "" && s.StandardsSource == ""
 }
 
 func (s *Settings) GetRepoURL() string {
 	return s.RepoURL
 }
 
+// GetCommitConventions returns the configured commit conventions, falling back
+// to Conventional Commits.
+func (s *Settings) GetCommitConventions() string {
+	if strings.TrimSpace(s.CommitConventions) == "" {
+		return DefaultCommitConventions
+	}
+	return s.CommitConventions
+}
+
+// GetDocsStyle returns the configured docs style guide, falling back to
+// DefaultDocsStyle.
+func (s *Settings) GetDocsStyle() string {
+	if strings.TrimSpace(s.DocsStyle) == "" {
+		return DefaultDocsStyle
+	}
+	return s.DocsStyle
+}
+
+// setMode sets a key of the [modes.<name>] section of mode.
+func (s *Settings) setMode(mode, key, value string) error {
+	m := s.Modes[mode]
+	switch key {
+	case modelKey:
+		m.Model = value
+	case maxTurnsKey, maxTokensKey:
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid %s in [modes.%s]: %q (must be a positive integer)", key, mode, value)
+		}
+		if key == maxTurnsKey {
+			m.MaxTurns = n
+		} else {
+			m.MaxTokens = n
+		}
+	default:
+		return fmt.Errorf("invalid key %q in [modes.%s] (must be %s, %s, or %s)", key, mode, modelKey, maxTurnsKey, maxTokensKey)
+	}
+	if s.Modes == nil {
+		s.Modes = make(map[string]ModeSettings)
+	}
+	s.Modes[mode] = m
+	return nil
+}
+
+// GetTestConventions returns the configured test conventions, falling back
+// to DefaultTestConventions.
+func (s *Settings) GetTestConventions() string {
+	if strings.TrimSpace(s.TestConventions) == "" {
+		return DefaultTestConventions
+	}
+	return s.TestConventions
+}
+
 func (s *Settings) GetGitHubToken() string {
 	if envToken := os.Getenv("GITHUB_TOKEN"); envToken != "" {
 		return envToken
 	}
+	if s.GitHubToken == githubTokenPlaceholder {
+		return ""
+	}
 	return s.GitHubToken
 }
 
-func (s *Settings) InteractiveEdit() error {
+// GetWebhookSecret returns the secret that serve verifies webhook deliveries
+// with.
+func (s *Settings) GetWebhookSecret() string {
+	if envSecret := os.Getenv("DOCU_JARVIS_WEBHOOK_SECRET"); envSecret != "" {
+		return envSecret
+	}
+	return s.WebhookSecret
+}
+
+func (s *Settings) GetGitLabToken() string {
+	if envToken := os.Getenv("GITLAB_TOKEN"); envToken != "" {
+		return envToken
+	}
+	return s.GitLabToken
+}
+
+func (s *Settings) GetBitbucketToken() string {
+	if envToken := os.Getenv("BITBUCKET_TOKEN"); envToken != "" {
+		return envToken
+	}
+	return s.BitbucketToken
+}
+
+// Editor returns the editor to open files in: $EDITOR, $VISUAL, or the first
+// of vim, nano, and vi that is installed.
+func Editor() string {
 	editor := os.Getenv("EDITOR")
 	if editor == "" {
 		editor = os.Getenv("VISUAL")
@@ -135,14 +711,12 @@ func (s *Settings) InteractiveEdit() error {
 			editor = "vi"
 		}
 	}
+	return editor
+}
 
-	fmt.Printf("\nOpening Docu-Jarvis config in %s...\n", editor)
-	fmt.Printf("File: %s\n", s.configPath)
-	fmt.Println("\nEdit the configuration, then save and exit.")
-	fmt.Println("Format: key = value")
-	fmt.Println()
-
-	cmd := exec.Command(editor, s.configPath)
+// EditFile opens a file in the editor and waits for it to exit.
+func EditFile(path string) error {
+	cmd := exec.Command(Editor(), path)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -150,6 +724,19 @@ func (s *Settings) InteractiveEdit() error {
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("editor exited with error: %w", err)
 	}
+	return nil
+}
+
+func (s *Settings) InteractiveEdit() error {
+	fmt.Printf("\nOpening Docu-Jarvis config in %s...\n", Editor())
+	fmt.Printf("File: %s\n", s.configPath)
+	fmt.Println("\nEdit the configuration, then save and exit.")
+	fmt.Println("Format: key = value")
+	fmt.Println()
+
+	if err := EditFile(s.configPath); err != nil {
+		return err
+	}
 
 	reloaded, err := Load()
 	if err != nil {
@@ -161,8 +748,10 @@ func (s *Settings) InteractiveEdit() error {
 	fmt.Println("\n✓ Configuration updated!")
 	fmt.Println("\nCurrent settings:")
 	fmt.Println(strings.Repeat("-", 60))
-	if s.RepoURL != "" {
-		fmt.Printf("Repository: %s\n", s.RepoURL)
+	if len(s.Repos) > 1 {
+		fmt.Printf("Repositories: %s\n", redact.String(strings.Join(s.Repos, ", ")))
+	} else if s.RepoURL != "" {
+		fmt.Printf("Repository: %s\n", redact.String(s.RepoURL))
 	} else {
 		fmt.Println("Repository: (not configured)")
 	}
@@ -171,8 +760,108 @@ func (s *Settings) InteractiveEdit() error {
 	} else {
 		fmt.Println("\nCode Standards: (not configured)")
 	}
+	if s.CommitConventions != "" {
+		fmt.Printf("\nCommit Conventions:\n%s\n", s.CommitConventions)
+	} else {
+		fmt.Println("\nCommit Conventions: (default: Conventional Commits)")
+	}
+	if s.DocsStyle != "" {
+		fmt.Printf("\nDocs Style:\n%s\n", s.DocsStyle)
+	} else {
+		fmt.Println("\nDocs Style: (default)")
+	}
+	if s.TestConventions != "" {
+		fmt.Printf("\nTest Conventions:\n%s\n", s.TestConventions)
+	} else {
+		fmt.Println("\nTest Conventions: (default)")
+	}
+	if len(s.DocsRoots) > 0 {
+		fmt.Printf("\nDocs Roots: %s\n", strings.Join(s.DocsRoots, ", "))
+	} else {
+		fmt.Println("\nDocs Roots: (default: documentation)")
+	}
+	if len(s.EditAllowlist) > 0 {
+		fmt.Printf("Edit Allowlist: %s\n", strings.Join(s.EditAllowlist, ", "))
+	} else {
+		fmt.Println("Edit Allowlist: (default: the docs roots)")
+	}
+	if len(s.Modes) > 0 {
+		var names []string
+		for name := range s.Modes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Println("\nModes:")
+		for _, name := range names {
+			m := s.Modes[name]
+			var set []string
+			if m.Model != "" {
+				set = append(set, "model "+m.Model)
+			}
+			if m.MaxTurns > 0 {
+				set = append(set, fmt.Sprintf("max_turns %d", m.MaxTurns))
+			}
+			if m.MaxTokens > 0 {
+				set = append(set, fmt.Sprintf("max_tokens %d", m.MaxTokens))
+			}
+			fmt.Printf("  %s: %s\n", name, strings.Join(set, ", "))
+		}
+	}
 	fmt.Println(strings.Repeat("-", 60))
 
 	return nil
 }
 
+// SaveCodeStandards replaces the code_standards lines of the config file.
+// The new lines go where the old ones were, or after the template's
+// commented examples.
+func (s *Settings) SaveCodeStandards(standards []string) error {
+	content, err := os.ReadFile(s.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var kept []string
+	at, example, section := -1, -1, -1
+	for _, line := range strings.Split(strings.TrimRight(string(content), "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if section < 0 && strings.HasPrefix(trimmed, "[") {
+			section = len(kept)
+		}
+		key, _, found := strings.Cut(strings.TrimPrefix(trimmed, "#"), "=")
+		if found && strings.TrimSpace(key) == codeStandardsKey {
+			if !strings.HasPrefix(trimmed, "#") {
+				if at < 0 {
+					at = len(kept)
+				}
+				continue
+			}
+			example = len(kept) + 1
+		}
+		kept = append(kept, line)
+	}
+
+	var lines []string
+	for _, standard := range standards {
+		lines = append(lines, fmt.Sprintf("%s = %s", codeStandardsKey, standard))
+	}
+	switch {
+	case at < 0 && example >= 0:
+		at = example
+	case at < 0 && section >= 0:
+		// Keys after a [modes.<name>] header would belong to it
+		header := []string{"# Code Quality Standards (one per line, used by -check-staging)"}
+		kept = append(kept[:section], append(header, kept[section:]...)...)
+		at = section + 1
+	case at < 0:
+		kept = append(kept, "", "# Code Quality Standards (one per line, used by -check-staging)")
+		at = len(kept)
+	}
+	kept = append(kept[:at], append(lines, kept[at:]...)...)
+
+	if err := os.WriteFile(s.configPath, []byte(strings.Join(kept, "\n")+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	s.CodeStandards = strings.Join(standards, "\n")
+	return nil
+}
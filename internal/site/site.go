@@ -0,0 +1,479 @@
+// Package site generates a static documentation site for a docs root: the
+// MkDocs or Docusaurus configuration with its navigation, an index page, and
+// the front matter the docs are missing.
+package site
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/udemy/docu-jarvis-cli/internal/frontmatter"
+)
+
+// Formats of Build.
+const (
+	FormatMkDocs     = "mkdocs"
+	FormatDocusaurus = "docusaurus"
+)
+
+// generatedMarker marks the files Build may replace on the next export.
+const generatedMarker = "Generated by docu-jarvis export-site"
+
+// websiteDir holds the Docusaurus site, next to the docs it builds.
+const websiteDir = "website"
+
+// indexNames are the docs a site uses as the home page, in order of
+// preference.
+var indexNames = []string{"index.md", "README.md", "readme.md"}
+
+var numberPrefix = regexp.MustCompile(`^\d+[-_. ]+`)
+
+// Options selects what Build generates.
+type Options struct {
+	Format   string
+	Name     string   // the site's title
+	DocsRoot string   // relative to the repository root
+	Docs     []string // the docs of the root, relative to the repository root
+	Force    bool     // replace config files that export-site did not write
+}
+
+// File is a file Build writes, relative to the repository root.
+type File struct {
+	Path    string
+	Content string
+	Exists  bool
+}
+
+// page is a doc of the navigation, with its path relative to the docs root.
+type page struct {
+	title string
+	path  string
+	id    string // the Docusaurus doc ID
+}
+
+// section is a folder of the navigation.
+type section struct {
+	title    string
+	index    *page
+	pages    []page
+	sections map[string]*section
+}
+
+// Build returns the files of the site: the config, the index page when the
+// docs root has none, and the docs that get front matter. Files that would
+// not change are left out.
+func Build(repoRoot string, opts Options) ([]File, error) {
+	if opts.Format != FormatMkDocs && opts.Format != FormatDocusaurus {
+		return nil, fmt.Errorf("invalid format %q (must be %s or %s)", opts.Format, FormatMkDocs, FormatDocusaurus)
+	}
+	root := filepath.ToSlash(filepath.Clean(opts.DocsRoot))
+	if root == "." && opts.Format == FormatMkDocs {
+		return nil, fmt.Errorf("MkDocs cannot use the repository root as its docs folder; choose a docs directory with -docs-dir")
+	}
+
+	var files []File
+	add := func(file string, content string) error {
+		existing, err := os.ReadFile(filepath.Join(repoRoot, file))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		if err == nil && string(existing) == content {
+			return nil
+		}
+		files = append(files, File{Path: file, Content: content, Exists: err == nil})
+		return nil
+	}
+
+	tree := &section{sections: make(map[string]*section)}
+	home := ""
+	for _, name := range indexNames {
+		rel := path.Join(root, name)
+		content, err := os.ReadFile(filepath.Join(repoRoot, rel))
+		if err == nil && !strings.Contains(string(content), generatedMarker) {
+			home = name
+			break
+		}
+	}
+
+	for _, doc := range opts.Docs {
+		doc = filepath.ToSlash(doc)
+		rel := strings.TrimPrefix(doc, root+"/")
+		if root == "." {
+			rel = doc
+		}
+		if opts.Format == FormatMkDocs && path.Ext(rel) != ".md" {
+			// MkDocs only builds markdown
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(repoRoot, doc))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", doc, err)
+		}
+		if rel == "index.md" && strings.Contains(string(content), generatedMarker) {
+			// A generated index is written again below
+			continue
+		}
+		p := page{title: docTitle(string(content), rel), path: rel, id: docID(string(content), rel)}
+
+		updated := addFrontMatter(string(content), p.title, opts.Format == FormatDocusaurus && rel == home)
+		if err := add(doc, updated); err != nil {
+			return nil, err
+		}
+		tree.insert(p)
+	}
+
+	if home == "" {
+		index := indexPage(opts.Name, tree, opts.Format == FormatDocusaurus)
+		if err := add(path.Join(root, "index.md"), index); err != nil {
+			return nil, err
+		}
+		tree.insert(page{title: "Home", path: "index.md", id: "index"})
+	}
+
+	config := map[string]string{}
+	if opts.Format == FormatMkDocs {
+		config["mkdocs.yml"] = mkdocsConfig(opts.Name, root, tree)
+	} else {
+		docsPath := root
+		if docsPath == "." {
+			docsPath = ""
+		}
+		config[websiteDir+"/docusaurus.config.js"] = docusaurusConfig(opts.Name, "../"+docsPath)
+		config[websiteDir+"/sidebars.js"] = docusaurusSidebars(tree)
+		config[websiteDir+"/package.json"] = docusaurusPackage(opts.Name)
+		config[websiteDir+"/.gitignore"] = "# " + generatedMarker + "\nnode_modules/\n.docusaurus/\nbuild/\n"
+	}
+	names := make([]string, 0, len(config))
+	for name := range config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		existing, err := os.ReadFile(filepath.Join(repoRoot, name))
+		if err == nil && !opts.Force && !strings.Contains(string(existing), generatedMarker) {
+			return nil, fmt.Errorf("%s exists and was not written by export-site; use -force to replace it", name)
+		}
+		if err := add(name, config[name]); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// Write writes the files of Build.
+func Write(repoRoot string, files []File) error {
+	for _, file := range files {
+		dest := filepath.Join(repoRoot, file.Path)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(file.Path), err)
+		}
+		if err := os.WriteFile(dest, []byte(file.Content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.Path, err)
+		}
+	}
+	return nil
+}
+
+// insert adds a page to the folder of its path.
+func (s *section) insert(p page) {
+	dirs := strings.Split(path.Dir(p.path), "/")
+	current := s
+	if path.Dir(p.path) != "." {
+		for _, dir := range dirs {
+			next, ok := current.sections[dir]
+			if !ok {
+				next = &section{title: titleize(dir), sections: make(map[string]*section)}
+				current.sections[dir] = next
+			}
+			current = next
+		}
+	}
+	if isIndex(path.Base(p.path)) && current.index == nil {
+		current.index = &p
+		return
+	}
+	current.pages = append(current.pages, p)
+}
+
+// sorted returns the pages and the subfolders of a section, each by name.
+func (s *section) sorted() ([]page, []*section) {
+	pages := append([]page(nil), s.pages...)
+	sort.Slice(pages, func(i, j int) bool { return pages[i].path < pages[j].path })
+	names := make([]string, 0, len(s.sections))
+	for name := range s.sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sections := make([]*section, 0, len(names))
+	for _, name := range names {
+		sections = append(sections, s.sections[name])
+	}
+	return pages, sections
+}
+
+func isIndex(name string) bool {
+	lower := strings.ToLower(name)
+	return lower == "index.md" || lower == "index.mdx" || lower == "readme.md" || lower == "readme.mdx"
+}
+
+// docTitle returns the front-matter title of a doc, its first top-level
+// heading, or a title made from its file name.
+func docTitle(content, rel string) string {
+	front, body, ok := frontmatter.Split(content)
+	if ok {
+		if title := frontValue(front, "title"); title != "" {
+			return title
+		}
+	}
+	inCode := false
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+			continue
+		}
+		if title, found := strings.CutPrefix(line, "# "); found && !inCode {
+			return strings.TrimSpace(title)
+		}
+	}
+	name := strings.TrimSuffix(path.Base(rel), path.Ext(rel))
+	if isIndex(path.Base(rel)) && path.Dir(rel) != "." {
+		name = path.Base(path.Dir(rel))
+	}
+	return titleize(name)
+}
+
+// docID returns the ID Docusaurus gives a doc: its path without the
+// extension and number prefixes, with the last part replaced by the id of
+// its front matter.
+func docID(content, rel string) string {
+	parts := strings.Split(strings.TrimSuffix(rel, path.Ext(rel)), "/")
+	for i, part := range parts {
+		parts[i] = numberPrefix.ReplaceAllString(part, "")
+	}
+	if front, _, ok := frontmatter.Split(content); ok {
+		if id := frontValue(front, "id"); id != "" {
+			parts[len(parts)-1] = id
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// frontValue returns a top-level scalar of front matter, unquoted.
+func frontValue(front, key string) string {
+	for _, line := range strings.Split(front, "\n") {
+		k, value, found := strings.Cut(line, ":")
+		if found && k == key {
+			return strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	return ""
+}
+
+// addFrontMatter gives a doc a title, and with home the slug of the
+// Docusaurus home page, unless its front matter already has them.
+func addFrontMatter(content, title string, home bool) string {
+	front, body, ok := frontmatter.Split(content)
+	var fields []string
+	if !ok || frontValue(front, "title") == "" {
+		fields = append(fields, "title: "+strconv.Quote(title))
+	}
+	if home && (!ok || frontValue(front, "slug") == "") {
+		fields = append(fields, "slug: /")
+	}
+	if len(fields) == 0 {
+		return content
+	}
+	if !ok {
+		return "---\n" + strings.Join(fields, "\n") + "\n---\n\n" + content
+	}
+	return "---\n" + strings.Join(fields, "\n") + "\n" + front + "---\n" + body
+}
+
+// titleize turns a file or folder name into a title, e.g. "01-getting_started"
+// into "Getting started".
+func titleize(name string) string {
+	name = numberPrefix.ReplaceAllString(name, "")
+	name = strings.TrimSpace(strings.NewReplacer("-", " ", "_", " ").Replace(name))
+	if name == "" {
+		return "Docs"
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// indexPage is the home page of a docs root without one: the site name and a
+// list of the docs by folder.
+func indexPage(name string, tree *section, docusaurus bool) string {
+	var b strings.Builder
+	b.WriteString("---\ntitle: " + strconv.Quote(name) + "\n")
+	if docusaurus {
+		b.WriteString("slug: /\n")
+	}
+	b.WriteString("---\n\n<!-- " + generatedMarker + "; run it again to update the list. -->\n\n")
+	fmt.Fprintf(&b, "# %s\n", name)
+
+	var list func(s *section, depth int)
+	list = func(s *section, depth int) {
+		indent := strings.Repeat("  ", depth)
+		pages, sections := s.sorted()
+		for _, p := range pages {
+			fmt.Fprintf(&b, "%s- [%s](%s)\n", indent, p.title, p.path)
+		}
+		for _, sub := range sections {
+			if sub.index != nil {
+				fmt.Fprintf(&b, "%s- [%s](%s)\n", indent, sub.title, sub.index.path)
+			} else {
+				fmt.Fprintf(&b, "%s- %s\n", indent, sub.title)
+			}
+			list(sub, depth+1)
+		}
+	}
+	if len(tree.pages) > 0 || len(tree.sections) > 0 {
+		b.WriteString("\n")
+		list(tree, 0)
+	}
+	return b.String()
+}
+
+func mkdocsConfig(name, root string, tree *section) string {
+	var b strings.Builder
+	b.WriteString("# " + generatedMarker + "; run it again to update the nav.\n")
+	fmt.Fprintf(&b, "site_name: %s\n", strconv.Quote(name))
+	fmt.Fprintf(&b, "docs_dir: %s\n", strconv.Quote(root))
+	b.WriteString("site_dir: site\n")
+	b.WriteString("theme:\n  name: readthedocs\n")
+	b.WriteString("nav:\n")
+
+	var nav func(s *section, depth int)
+	nav = func(s *section, depth int) {
+		indent := strings.Repeat("    ", depth) + "  "
+		if s.index != nil {
+			fmt.Fprintf(&b, "%s- %s: %s\n", indent, strconv.Quote(s.index.title), strconv.Quote(s.index.path))
+		}
+		pages, sections := s.sorted()
+		for _, p := range pages {
+			fmt.Fprintf(&b, "%s- %s: %s\n", indent, strconv.Quote(p.title), strconv.Quote(p.path))
+		}
+		for _, sub := range sections {
+			fmt.Fprintf(&b, "%s- %s:\n", indent, strconv.Quote(sub.title))
+			nav(sub, depth+1)
+		}
+	}
+	nav(tree, 0)
+	return b.String()
+}
+
+// jsString quotes a string for JavaScript, in single quotes like the rest of
+// the generated code.
+func jsString(s string) string {
+	quoted, _ := json.Marshal(s)
+	inner := strings.ReplaceAll(string(quoted[1:len(quoted)-1]), `\"`, `"`)
+	return "'" + strings.ReplaceAll(inner, "'", `\'`) + "'"
+}
+
+func docusaurusConfig(name, docsPath string) string {
+	return "// " + generatedMarker + "; run it again to update the site.\n" +
+		"// @ts-check\n\n" +
+		"/** @type {import('@docusaurus/types').Config} */\n" +
+		"module.exports = {\n" +
+		"  title: " + jsString(name) + ",\n" +
+		"  url: 'https://example.com',\n" +
+		"  baseUrl: '/',\n" +
+		"  onBrokenLinks: 'warn',\n" +
+		"  onBrokenMarkdownLinks: 'warn',\n" +
+		"  // .md files are plain markdown, .mdx files MDX\n" +
+		"  markdown: { format: 'detect' },\n" +
+		"  presets: [\n" +
+		"    [\n" +
+		"      'classic',\n" +
+		"      {\n" +
+		"        docs: {\n" +
+		"          path: " + jsString(strings.TrimSuffix(docsPath, "/")) + ",\n" +
+		"          routeBasePath: '/',\n" +
+		"          sidebarPath: require.resolve('./sidebars.js'),\n" +
+		"        },\n" +
+		"        blog: false,\n" +
+		"      },\n" +
+		"    ],\n" +
+		"  ],\n" +
+		"  themeConfig: {\n" +
+		"    navbar: { title: " + jsString(name) + " },\n" +
+		"  },\n" +
+		"};\n"
+}
+
+func docusaurusSidebars(tree *section) string {
+	var b strings.Builder
+	b.WriteString("// " + generatedMarker + "; run it again to update the sidebar.\n\n")
+	b.WriteString("/** @type {import('@docusaurus/plugin-content-docs').SidebarsConfig} */\n")
+	b.WriteString("module.exports = {\n  docs: [\n")
+
+	var items func(s *section, depth int)
+	items = func(s *section, depth int) {
+		indent := strings.Repeat("  ", depth+2)
+		pages, sections := s.sorted()
+		for _, p := range pages {
+			fmt.Fprintf(&b, "%s{ type: 'doc', id: %s, label: %s },\n", indent, jsString(p.id), jsString(p.title))
+		}
+		for _, sub := range sections {
+			fmt.Fprintf(&b, "%s{\n%s  type: 'category',\n%s  label: %s,\n", indent, indent, indent, jsString(sub.title))
+			if sub.index != nil {
+				fmt.Fprintf(&b, "%s  link: { type: 'doc', id: %s },\n", indent, jsString(sub.index.id))
+			}
+			fmt.Fprintf(&b, "%s  items: [\n", indent)
+			items(sub, depth+2)
+			fmt.Fprintf(&b, "%s  ],\n%s},\n", indent, indent)
+		}
+	}
+	if tree.index != nil {
+		fmt.Fprintf(&b, "    { type: 'doc', id: %s, label: %s },\n", jsString(tree.index.id), jsString(tree.index.title))
+	}
+	items(tree, 0)
+	b.WriteString("  ],\n};\n")
+	return b.String()
+}
+
+// docusaurusPackage is the package.json of the site. JSON has no comments,
+// so the description marks it as generated.
+func docusaurusPackage(name string) string {
+	pkg := map[string]interface{}{
+		"name":        packageName(name),
+		"private":     true,
+		"description": generatedMarker,
+		"scripts": map[string]string{
+			"start": "docusaurus start",
+			"build": "docusaurus build",
+			"serve": "docusaurus serve",
+		},
+		"dependencies": map[string]string{
+			"@docusaurus/core":           "^3.5.2",
+			"@docusaurus/preset-classic": "^3.5.2",
+			"@mdx-js/react":              "^3.0.0",
+			"clsx":                       "^2.0.0",
+			"prism-react-renderer":       "^2.3.0",
+			"react":                      "^18.2.0",
+			"react-dom":                  "^18.2.0",
+		},
+	}
+	content, _ := json.MarshalIndent(pkg, "", "  ")
+	return string(content) + "\n"
+}
+
+var packageNameInvalid = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// packageName makes an npm package name of the site name.
+func packageName(name string) string {
+	slug := strings.Trim(packageNameInvalid.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if slug == "" {
+		return "docs-site"
+	}
+	return slug + "-docs"
+}
@@ -0,0 +1,171 @@
+// Package standards reads code standards shared across repositories from a
+// file in a git repository, e.g. git@github.com:org/eng-standards.git#go.md,
+// keeping a clone of it in ~/.docu-jarvis/standards.
+package standards
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/udemy/docu-jarvis-cli/internal/netguard"
+	"github.com/udemy/docu-jarvis-cli/internal/redact"
+)
+
+// RefreshInterval is how long a clone is used before it is fetched again.
+const RefreshInterval = time.Hour
+
+// Source is a file of standards in a git repository.
+type Source struct {
+	Repo string
+	File string // relative to the repository root
+}
+
+// ParseSource parses <repo>#<file>.
+func ParseSource(value string) (Source, error) {
+	i := strings.LastIndex(value, "#")
+	if i < 0 {
+		return Source{}, fmt.Errorf("invalid code_standards_source %q (must be <repo>#<file>, e.g. git@github.com:org/eng-standards.git#go.md)", redact.String(value))
+	}
+	source := Source{Repo: strings.TrimSpace(value[:i]), File: filepath.Clean(strings.TrimSpace(value[i+1:]))}
+	if source.Repo == "" || source.File == "." || filepath.IsAbs(source.File) || strings.HasPrefix(source.File, "..") {
+		return Source{}, fmt.Errorf("invalid code_standards_source %q (must be <repo>#<file>, with the file relative to the repository root)", redact.String(value))
+	}
+	// The repository goes on the git command line, so it must not look like
+	// an option such as --upload-pack
+	if strings.HasPrefix(source.Repo, "-") {
+		return Source{}, fmt.Errorf("invalid code_standards_source %q (the repository must not start with -)", redact.String(value))
+	}
+	return source, nil
+}
+
+func (s Source) String() string {
+	return redact.String(s.Repo) + "#" + filepath.ToSlash(s.File)
+}
+
+// Load returns the standards of the source. The clone is refreshed when it is
+// older than RefreshInterval; when that fails, the cached copy is used with
+// a warning.
+func Load(source Source) ([]string, error) {
+	dir, err := cloneDir(source.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := refresh(source.Repo, dir); err != nil {
+		if _, statErr := os.Stat(filepath.Join(dir, ".git")); statErr != nil {
+			return nil, fmt.Errorf("failed to fetch code standards from %s: %w", redact.String(source.Repo), err)
+		}
+		fmt.Printf("Warning: using cached code standards, refreshing %s failed: %v\n", redact.String(source.Repo), err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, source.File))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read code standards %s: %w", source, err)
+	}
+	standards := Parse(string(content))
+	if len(standards) == 0 {
+		return nil, fmt.Errorf("no code standards in %s", source)
+	}
+	return standards, nil
+}
+
+// Parse returns the standards of a file: the items of its lists, or, in a
+// file without lists, each line that is not a heading or a comment.
+func Parse(content string) []string {
+	var items, lines []string
+	inCode := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode || line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "<!--") {
+			continue
+		}
+		if item, ok := listItem(line); ok {
+			items = append(items, item)
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if len(items) > 0 {
+		return items
+	}
+	return lines
+}
+
+// listItem returns the text of a "- ", "* ", or "1. " list item.
+func listItem(line string) (string, bool) {
+	for _, bullet := range []string{"- ", "* ", "+ "} {
+		if strings.HasPrefix(line, bullet) {
+			return strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, bullet), "[ ] ")), true
+		}
+	}
+	digits := len(line) - len(strings.TrimLeft(line, "0123456789"))
+	if digits > 0 && strings.HasPrefix(line[digits:], ". ") {
+		return strings.TrimSpace(line[digits+2:]), true
+	}
+	return "", false
+}
+
+func cloneDir(repo string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	sum := sha256.Sum256([]byte(repo))
+	return filepath.Join(homeDir, ".docu-jarvis", "standards", hex.EncodeToString(sum[:8])), nil
+}
+
+// refresh clones the repository, or fetches it when the clone is older than
+// RefreshInterval. The clone directory's time records the last fetch.
+func refresh(repo, dir string) error {
+	info, err := os.Stat(filepath.Join(dir, ".git"))
+	if err == nil {
+		if stat, err := os.Stat(dir); err == nil && time.Since(stat.ModTime()) < RefreshInterval {
+			return nil
+		}
+	}
+	if err := netguard.Check("fetching the shared code standards"); err != nil {
+		return err
+	}
+
+	if err != nil || !info.IsDir() {
+		os.RemoveAll(dir)
+		if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+			return fmt.Errorf("failed to create standards cache: %w", err)
+		}
+		if err := git("", "clone", "--quiet", "--depth", "1", "--", repo, dir); err != nil {
+			os.RemoveAll(dir)
+			return err
+		}
+	} else {
+		if err := git(dir, "fetch", "--quiet", "--depth", "1", "origin", "HEAD"); err != nil {
+			return err
+		}
+		if err := git(dir, "reset", "--quiet", "--hard", "FETCH_HEAD"); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	return os.Chtimes(dir, now, now)
+}
+
+func git(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	// Fail instead of waiting for credentials nobody will type
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s failed: %w: %s", args[0], err, redact.String(strings.TrimSpace(string(output))))
+	}
+	return nil
+}
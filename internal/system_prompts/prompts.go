@@ -4,24 +4,138 @@ import (
 	_ "embed"
 )
 
+//go:embed api_reference.txt
+var APIReference string
+
+//go:embed assert_code_quality.txt
 var AssertCodeQuality string
+
+//go:embed changelog_writer.txt
+var ChangelogWriter string
+
+//go:embed commit_conventions.txt
+var CommitConventions string
+
+//go:embed commit_explainer.txt
 var CommitExplainer string
+
+//go:embed compare_judge.txt
+var CompareJudge string
+
+//go:embed debug_analysis.txt
 var DebugAnalysis string
+
+//go:embed debug_bisect.txt
+var DebugBisect string
+
+//go:embed debug_verify.txt
+var DebugVerify string
+
+//go:embed deploy_diff.txt
+var DeployDiff string
+
+//go:embed docs_audit.txt
+var DocsAudit string
+
+//go:embed docs_gap.txt
+var DocsGap string
+
+//go:embed docs_impact.txt
+var DocsImpact string
+
+//go:embed docs_lint.txt
+var DocsLint string
+
+//go:embed documentation_update.txt
 var DocumentationUpdate string
+
+//go:embed documentation_write.txt
 var DocumentationWrite string
 
+//go:embed infer_standards.txt
+var InferStandards string
+
+//go:embed interface_events.txt
+var InterfaceEvents string
+
+//go:embed interface_queues.txt
+var InterfaceQueues string
+
+//go:embed interface_webhooks.txt
+var InterfaceWebhooks string
+
+//go:embed issue_triage.txt
+var IssueTriage string
+
+//go:embed openapi_spec.txt
+var OpenAPISpec string
+
+//go:embed review_checklist.txt
+var ReviewChecklist string
+
+//go:embed review_feedback.txt
+var ReviewFeedback string
+
+//go:embed squash_summary.txt
+var SquashSummary string
+
+//go:embed test_write.txt
+var TestWrite string
+
 func GetPrompt(name string) string {
 	switch name {
+	case "api_reference.txt":
+		return APIReference
 	case "assert_code_quality.txt":
 		return AssertCodeQuality
+	case "changelog_writer.txt":
+		return ChangelogWriter
+	case "commit_conventions.txt":
+		return CommitConventions
 	case "commit_explainer.txt":
 		return CommitExplainer
+	case "compare_judge.txt":
+		return CompareJudge
 	case "debug_analysis.txt":
 		return DebugAnalysis
+	case "debug_bisect.txt":
+		return DebugBisect
+	case "debug_verify.txt":
+		return DebugVerify
+	case "deploy_diff.txt":
+		return DeployDiff
+	case "docs_audit.txt":
+		return DocsAudit
+	case "docs_gap.txt":
+		return DocsGap
+	case "docs_impact.txt":
+		return DocsImpact
+	case "docs_lint.txt":
+		return DocsLint
 	case "documentation_update.txt":
 		return DocumentationUpdate
 	case "documentation_write.txt":
 		return DocumentationWrite
+	case "infer_standards.txt":
+		return InferStandards
+	case "interface_events.txt":
+		return InterfaceEvents
+	case "interface_queues.txt":
+		return InterfaceQueues
+	case "interface_webhooks.txt":
+		return InterfaceWebhooks
+	case "issue_triage.txt":
+		return IssueTriage
+	case "openapi_spec.txt":
+		return OpenAPISpec
+	case "review_checklist.txt":
+		return ReviewChecklist
+	case "review_feedback.txt":
+		return ReviewFeedback
+	case "squash_summary.txt":
+		return SquashSummary
+	case "test_write.txt":
+		return TestWrite
 	default:
 		return ""
 	}
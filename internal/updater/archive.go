@@ -0,0 +1,163 @@
+package updater
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+const binaryName = "docu-jarvis"
+
+// archiveFormats are the archive extensions a release binary may be packed in.
+var archiveFormats = []string{".tar.gz", ".tgz", ".zip"}
+
+// osAliases and archAliases are the other names release tools use for GOOS
+// and GOARCH, e.g. GoReleaser's darwin_x86_64 or uname's aarch64.
+var (
+	osAliases = map[string][]string{
+		"darwin":  {"darwin", "macos", "mac", "osx"},
+		"windows": {"windows", "win"},
+	}
+	archAliases = map[string][]string{
+		"amd64": {"amd64", "x86_64", "x64"},
+		"arm64": {"arm64", "aarch64"},
+		"386":   {"386", "i386", "x86"},
+	}
+)
+
+// platformAsset reports whether a release asset is the docu-jarvis binary for
+// this platform, such as docu-jarvis-darwin-arm64, docu-jarvis_linux_amd64.tar.gz,
+// or docu-jarvis_2.3.0_Windows_x86_64.zip, and which archive format it is
+// packed in, if any.
+func platformAsset(name string) (archive string, ok bool) {
+	lower := strings.ToLower(name)
+	for _, format := range archiveFormats {
+		if strings.HasSuffix(lower, format) {
+			archive = format
+			lower = strings.TrimSuffix(lower, format)
+			break
+		}
+	}
+	lower = strings.TrimSuffix(lower, ".exe")
+
+	// Signatures, checksums, and other files named after the binary
+	if ext := path.Ext(lower); !strings.ContainsAny(ext, "-_") && strings.IndexFunc(ext, unicode.IsLetter) >= 0 {
+		return "", false
+	}
+	if !strings.HasPrefix(lower, binaryName) {
+		return "", false
+	}
+	// x86_64 would otherwise split into two fields
+	rest := strings.ReplaceAll(lower[len(binaryName):], "x86_64", "x64")
+	fields := strings.FieldsFunc(rest, func(r rune) bool { return r == '-' || r == '_' })
+
+	return archive, hasAlias(fields, runtime.GOOS, osAliases) && hasAlias(fields, runtime.GOARCH, archAliases)
+}
+
+func hasAlias(fields []string, name string, aliases map[string][]string) bool {
+	names := aliases[name]
+	if names == nil {
+		names = []string{name}
+	}
+	for _, field := range fields {
+		for _, n := range names {
+			if field == n {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// extractBinary extracts the docu-jarvis binary from an archive into a new
+// temporary file in dir, and returns its path.
+func extractBinary(archivePath, format, dir string) (string, error) {
+	out, err := os.CreateTemp(dir, binaryName+".extract-*")
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	var found bool
+	if format == ".zip" {
+		found, err = extractFromZip(archivePath, out)
+	} else {
+		found, err = extractFromTarGz(archivePath, out)
+	}
+	if err == nil && !found {
+		err = fmt.Errorf("archive has no %s binary", binaryName)
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("failed to extract %s: %w", binaryName, err)
+	}
+	return out.Name(), nil
+}
+
+// isBinaryEntry matches the binary at any depth in the archive, so both flat
+// archives and ones with a top-level directory work.
+func isBinaryEntry(name string) bool {
+	base := path.Base(strings.ReplaceAll(name, `\`, "/"))
+	return base == binaryName || base == binaryName+".exe"
+}
+
+func extractFromTarGz(archivePath string, out io.Writer) (bool, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return false, err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if header.Typeflag == tar.TypeReg && isBinaryEntry(header.Name) {
+			_, err := io.Copy(out, tr)
+			return err == nil, err
+		}
+	}
+}
+
+func extractFromZip(archivePath string, out io.Writer) (bool, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return false, err
+	}
+	defer zr.Close()
+
+	for _, file := range zr.File {
+		if !file.Mode().IsRegular() || !isBinaryEntry(file.Name) {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return false, err
+		}
+		_, err = io.Copy(out, rc)
+		rc.Close()
+		return err == nil, err
+	}
+	return false, nil
+}
@@ -0,0 +1,188 @@
+package updater
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/udemy/docu-jarvis-cli/internal/netguard"
+)
+
+const (
+	maxAttempts = 4
+	// baseBackoff doubles after each failed attempt.
+	baseBackoff = time.Second
+	// maxRetryWait is the longest rate-limit wait worth sitting through; longer
+	// ones are remembered and later checks are skipped until the limit resets.
+	maxRetryWait = 30 * time.Second
+)
+
+// releaseCache keeps the last /releases/latest response so repeated checks can
+// send If-None-Match and get a 304, which GitHub does not count against the
+// rate limit.
+type releaseCache struct {
+	ETag             string          `json:"etag"`
+	Body             json.RawMessage `json:"body"`
+	RateLimitedUntil time.Time       `json:"rate_limited_until,omitempty"`
+}
+
+func releaseCachePath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".docu-jarvis", "release_cache.json"), nil
+}
+
+// loadReleaseCache returns an empty cache when there is none or it cannot be read.
+func loadReleaseCache() *releaseCache {
+	cache := &releaseCache{}
+	path, err := releaseCachePath()
+	if err != nil {
+		return cache
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, cache); err != nil {
+		return &releaseCache{}
+	}
+	return cache
+}
+
+func (c *releaseCache) save() error {
+	path, err := releaseCachePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// fetchRelease GETs url and returns the response body, using the cache for
+// conditional requests. Network errors, 5xx responses, and rate limits are
+// retried with exponential backoff.
+func fetchRelease(ctx context.Context, url, token string) ([]byte, bool, error) {
+	if err := netguard.Check("checking for updates"); err != nil {
+		return nil, false, err
+	}
+	cache := loadReleaseCache()
+	if time.Now().Before(cache.RateLimitedUntil) {
+		return nil, false, fmt.Errorf("GitHub API rate limit exceeded, retrying after %s", cache.RateLimitedUntil.Local().Format("Jan 2 15:04"))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if cache.ETag != "" && len(cache.Body) > 0 {
+		req.Header.Set("If-None-Match", cache.ETag)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			wait := baseBackoff << (attempt - 1)
+			if rl, ok := lastErr.(*rateLimitError); ok && rl.wait > 0 {
+				wait = rl.wait
+			}
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return nil, false, ctx.Err()
+			}
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusNotModified:
+			return cache.Body, true, nil
+
+		case resp.StatusCode == http.StatusOK:
+			cache.ETag = resp.Header.Get("ETag")
+			cache.Body = body
+			cache.RateLimitedUntil = time.Time{}
+			cache.save()
+			return body, true, nil
+
+		case resp.StatusCode == http.StatusNotFound:
+			return nil, false, nil
+
+		case isRateLimited(resp):
+			wait := rateLimitWait(resp)
+			if wait > maxRetryWait {
+				cache.RateLimitedUntil = time.Now().Add(wait)
+				cache.save()
+				return nil, false, fmt.Errorf("GitHub API rate limit exceeded, retrying after %s", cache.RateLimitedUntil.Local().Format("Jan 2 15:04"))
+			}
+			lastErr = &rateLimitError{wait: wait}
+
+		case resp.StatusCode >= 500:
+			lastErr = fmt.Errorf("GitHub API error %d: %s", resp.StatusCode, string(body))
+
+		default:
+			return nil, false, fmt.Errorf("GitHub API error %d: %s", resp.StatusCode, string(body))
+		}
+	}
+
+	return nil, false, fmt.Errorf("giving up after %d attempts: %w", maxAttempts, lastErr)
+}
+
+type rateLimitError struct {
+	wait time.Duration
+}
+
+func (e *rateLimitError) Error() string {
+	return "GitHub API rate limit exceeded"
+}
+
+// isRateLimited recognizes both the primary limit (403 with no requests
+// remaining) and secondary limits (429, or 403 with Retry-After).
+func isRateLimited(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return resp.StatusCode == http.StatusForbidden &&
+		(resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.Header.Get("Retry-After") != "")
+}
+
+// rateLimitWait reads how long GitHub asks us to wait, or 0 when it does not say.
+func rateLimitWait(resp *http.Response) time.Duration {
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		if wait := time.Until(time.Unix(reset, 0)); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+)
+
+// InstallMethod is how the running binary was installed, which decides how it
+// should be upgraded.
+type InstallMethod string
+
+const (
+	InstallManual   InstallMethod = "manual"
+	InstallHomebrew InstallMethod = "homebrew"
+	InstallGo       InstallMethod = "go install"
+)
+
+const modulePath = "github.com/udemy/docu-jarvis-cli"
+
+// installMethod can be set at build time, e.g. by the Homebrew formula:
+//
+//	go build -ldflags "-X github.com/udemy/docu-jarvis-cli/internal/updater.installMethod=homebrew"
+var installMethod string
+
+// DetectInstallMethod works out how the binary was installed from the build
+// stamp, its location (Homebrew's Cellar, GOBIN, or GOPATH/bin), or its build
+// info, in that order.
+func DetectInstallMethod() InstallMethod {
+	switch InstallMethod(installMethod) {
+	case InstallHomebrew, InstallGo, InstallManual:
+		return InstallMethod(installMethod)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return InstallManual
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+
+	if strings.Contains(filepath.ToSlash(exe), "/Cellar/docu-jarvis/") {
+		return InstallHomebrew
+	}
+
+	dir := filepath.Dir(exe)
+	for _, goBin := range goBinDirs() {
+		if dir == goBin {
+			return InstallGo
+		}
+	}
+
+	// go install module@version builds from the module cache, which records a
+	// checksum for the main module; builds from a checkout do not
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Path == modulePath && info.Main.Sum != "" {
+		return InstallGo
+	}
+
+	return InstallManual
+}
+
+func goBinDirs() []string {
+	var dirs []string
+	if gobin := os.Getenv("GOBIN"); gobin != "" {
+		dirs = append(dirs, filepath.Clean(gobin))
+	}
+	gopath := os.Getenv("GOPATH")
+	if gopath == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			gopath = filepath.Join(home, "go")
+		}
+	}
+	for _, p := range filepath.SplitList(gopath) {
+		dirs = append(dirs, filepath.Join(p, "bin"))
+	}
+	return dirs
+}
+
+// UpgradeCommand is the command that upgrades a binary installed with method.
+func UpgradeCommand(method InstallMethod) string {
+	switch method {
+	case InstallHomebrew:
+		return "brew upgrade docu-jarvis"
+	case InstallGo:
+		return "go install " + modulePath + "/cmd/docu-jarvis@latest"
+	}
+	return "docu-jarvis update"
+}
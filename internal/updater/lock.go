@@ -0,0 +1,97 @@
+package updater
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// staleLockAge is how old an update lock must be before it is treated as left
+// behind by a crashed process.
+const staleLockAge = 10 * time.Minute
+
+// startupBinary is the executable as it was when this process started.
+var startupBinary = statExecutable()
+
+type binaryInfo struct {
+	path string
+	info os.FileInfo
+}
+
+func statExecutable() *binaryInfo {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil
+	}
+	info, err := os.Stat(exe)
+	if err != nil {
+		return nil
+	}
+	return &binaryInfo{path: exe, info: info}
+}
+
+// BinaryReplaced reports whether the executable on disk is no longer the one
+// this process started from, e.g. because another docu-jarvis process updated
+// it during the run.
+func BinaryReplaced() bool {
+	if startupBinary == nil {
+		return false
+	}
+	info, err := os.Stat(startupBinary.path)
+	if err != nil {
+		return false
+	}
+	return !os.SameFile(startupBinary.info, info) || !info.ModTime().Equal(startupBinary.info.ModTime())
+}
+
+// updateLock is held while the binary is downloaded and replaced, so that
+// concurrent updates cannot interleave their renames.
+type updateLock struct {
+	path string
+}
+
+// acquireUpdateLock creates <exe>.lock, waiting up to timeout for another
+// update to finish. Locks older than staleLockAge are removed.
+func acquireUpdateLock(exe string, timeout time.Duration) (*updateLock, error) {
+	path := exe + ".lock"
+	deadline := time.Now().Add(timeout)
+
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return &updateLock{path: path}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create update lock: %w", err)
+		}
+
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(path)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("another docu-jarvis update is in progress (%s); remove %s if it is stale", lockOwner(path), path)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+func (l *updateLock) release() {
+	os.Remove(l.path)
+}
+
+func lockOwner(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "unknown process"
+	}
+	if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+		return fmt.Sprintf("pid %d", pid)
+	}
+	return "unknown process"
+}
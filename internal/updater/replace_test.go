@@ -0,0 +1,89 @@
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeBinary(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertContent(t *testing.T, path, want string) {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	if string(content) != want {
+		t.Errorf("%s = %q, want %q", filepath.Base(path), content, want)
+	}
+}
+
+func setGOOS(t *testing.T, name string) {
+	t.Helper()
+	saved := goos
+	goos = name
+	t.Cleanup(func() { goos = saved })
+}
+
+func TestReplaceBinaryWindows(t *testing.T) {
+	setGOOS(t, "windows")
+	dir := t.TempDir()
+	target := filepath.Join(dir, "docu-jarvis.exe")
+	oldPath := target + oldBinarySuffix
+
+	writeBinary(t, target, "v1")
+	writeBinary(t, filepath.Join(dir, "new"), "v2")
+	if err := replaceBinary(filepath.Join(dir, "new"), target); err != nil {
+		t.Fatalf("first update: %v", err)
+	}
+	assertContent(t, target, "v2")
+	assertContent(t, oldPath, "v1")
+
+	// A second update before the old binary was removed replaces it.
+	writeBinary(t, filepath.Join(dir, "new"), "v3")
+	if err := replaceBinary(filepath.Join(dir, "new"), target); err != nil {
+		t.Fatalf("second update: %v", err)
+	}
+	assertContent(t, target, "v3")
+	assertContent(t, oldPath, "v2")
+
+	removeOldBinary(target)
+	if _, err := os.Stat(oldPath); !os.IsNotExist(err) {
+		t.Errorf("%s still exists after removeOldBinary: %v", oldPath, err)
+	}
+	assertContent(t, target, "v3")
+}
+
+func TestReplaceBinaryWindowsRestoresOnFailure(t *testing.T) {
+	setGOOS(t, "windows")
+	dir := t.TempDir()
+	target := filepath.Join(dir, "docu-jarvis.exe")
+	writeBinary(t, target, "v1")
+
+	if err := replaceBinary(filepath.Join(dir, "missing"), target); err == nil {
+		t.Fatal("expected an error for a missing new binary")
+	}
+	assertContent(t, target, "v1")
+}
+
+func TestReplaceBinaryUnix(t *testing.T) {
+	setGOOS(t, "linux")
+	dir := t.TempDir()
+	target := filepath.Join(dir, "docu-jarvis")
+	writeBinary(t, target, "v1")
+	writeBinary(t, filepath.Join(dir, "new"), "v2")
+
+	if err := replaceBinary(filepath.Join(dir, "new"), target); err != nil {
+		t.Fatal(err)
+	}
+	assertContent(t, target, "v2")
+	if _, err := os.Stat(target + oldBinarySuffix); !os.IsNotExist(err) {
+		t.Errorf("old binary kept outside Windows: %v", err)
+	}
+}
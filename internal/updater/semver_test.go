@@ -0,0 +1,96 @@
+package updater
+
+import "testing"
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		in   string
+		ok   bool
+		core [3]int
+		pre  int // number of prerelease identifiers
+	}{
+		{in: "2.10.0", ok: true, core: [3]int{2, 10, 0}},
+		{in: "v2.3.0", ok: true, core: [3]int{2, 3, 0}},
+		{in: "V1.0.0", ok: true, core: [3]int{1, 0, 0}},
+		{in: " v1.2.3 ", ok: true, core: [3]int{1, 2, 3}},
+		{in: "2.3", ok: true, core: [3]int{2, 3, 0}},
+		{in: "2", ok: true, core: [3]int{2, 0, 0}},
+		{in: "v2.3.0-rc.1", ok: true, core: [3]int{2, 3, 0}, pre: 2},
+		{in: "1.0.0+build.5", ok: true, core: [3]int{1, 0, 0}},
+		{in: "", ok: false},
+		{in: "dev", ok: false},
+		{in: "1.2.3.4", ok: false},
+		{in: "1.x.0", ok: false},
+		{in: "1.-2.0", ok: false},
+		{in: "1.2.3-", ok: false},
+		{in: "1.2.3-rc..1", ok: false},
+	}
+
+	for _, tt := range tests {
+		v, ok := parseSemver(tt.in)
+		if ok != tt.ok {
+			t.Errorf("parseSemver(%q) ok = %v, want %v", tt.in, ok, tt.ok)
+			continue
+		}
+		if ok && (v.core != tt.core || len(v.prerelease) != tt.pre) {
+			t.Errorf("parseSemver(%q) = %v %v, want %v with %d prerelease identifiers", tt.in, v.core, v.prerelease, tt.core, tt.pre)
+		}
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.10.0", "1.9.0", 1},
+		{"1.9.9", "1.10.0", -1},
+		{"2.0.0", "10.0.0", -1},
+		{"1.0.10", "1.0.2", 1},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"1", "1.0.0", 0},
+		{"1.2", "1.2.1", -1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0", "1.0.0-rc.1", 1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-beta", "1.0.0-alpha", 1},
+		{"1.0.0-rc.1", "0.9.0", 1},
+		{"1.0.0+build.1", "1.0.0+build.2", 0},
+	}
+
+	for _, tt := range tests {
+		a, okA := parseSemver(tt.a)
+		b, okB := parseSemver(tt.b)
+		if !okA || !okB {
+			t.Fatalf("failed to parse %q or %q", tt.a, tt.b)
+		}
+		if got := a.compare(b); got != tt.want {
+			t.Errorf("compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestReleaseLessOrEqual(t *testing.T) {
+	tests := []struct {
+		release, current string
+		want             bool
+	}{
+		{"v1.10.0", "v1.9.0", false},
+		{"v1.9.0", "v1.10.0", true},
+		{"v1.2.0", "1.2.0", true},
+		{"v1.2.0", "v1.2.0-rc.1", false},
+		// Unparseable versions fall back to comparing strings
+		{"nightly-b", "nightly-a", false},
+		{"nightly-a", "nightly-a", true},
+	}
+
+	for _, tt := range tests {
+		r := &Release{Version: tt.release}
+		if got := r.LessOrEqual(tt.current); got != tt.want {
+			t.Errorf("Release{%q}.LessOrEqual(%q) = %v, want %v", tt.release, tt.current, got, tt.want)
+		}
+	}
+}
@@ -2,14 +2,19 @@ package updater
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
 	"time"
 
+	"github.com/udemy/docu-jarvis-cli/internal/netguard"
 	"github.com/udemy/docu-jarvis-cli/internal/settings"
 )
 
@@ -23,11 +28,22 @@ type Release struct {
 	Version      string
 	AssetURL     string
 	AssetName    string
+	Archive      string // archive format of the asset, e.g. ".tar.gz", or "" for a bare binary
+	ChecksumsURL string
+	SignatureURL string
 	ReleaseNotes string
 }
 
+// LessOrEqual reports whether the release is not newer than version, comparing
+// them as semantic versions. Versions that do not parse are compared as
+// strings.
 func (r *Release) LessOrEqual(version string) bool {
-	return r.Version <= version
+	latest, ok1 := parseSemver(r.Version)
+	current, ok2 := parseSemver(version)
+	if !ok1 || !ok2 {
+		return r.Version <= version
+	}
+	return latest.compare(current) <= 0
 }
 
 type AuthenticatedGitHubSource struct {
@@ -37,29 +53,10 @@ type AuthenticatedGitHubSource struct {
 func (s *AuthenticatedGitHubSource) GetLatestRelease(ctx context.Context) (*Release, bool, error) {
 	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/latest", owner, repo)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, false, err
-	}
-
-	if s.token != "" {
-		req.Header.Set("Authorization", "Bearer "+s.token)
-	}
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
+	body, found, err := fetchRelease(ctx, url, s.token)
+	if err != nil || !found {
 		return nil, false, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == 404 {
-		return nil, false, nil
-	}
-
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, false, fmt.Errorf("GitHub API error %d: %s", resp.StatusCode, string(body))
-	}
 
 	var ghRelease struct {
 		TagName string `json:"tag_name"`
@@ -72,28 +69,39 @@ func (s *AuthenticatedGitHubSource) GetLatestRelease(ctx context.Context) (*Rele
 		} `json:"assets"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&ghRelease); err != nil {
+	if err := json.Unmarshal(body, &ghRelease); err != nil {
 		return nil, false, err
 	}
 
-	var assetURL, assetName string
+	release := &Release{
+		Version:      ghRelease.TagName,
+		ReleaseNotes: ghRelease.Body,
+	}
+
+	// The binary for this platform, preferring a bare binary to an archive,
+	// or else a plain docu-jarvis asset
+	var fallbackURL string
 	for _, asset := range ghRelease.Assets {
-		if asset.Name == "docu-jarvis" {
-			assetURL = asset.URL
-			assetName = asset.Name
-			break
+		switch archive, ok := platformAsset(asset.Name); {
+		case ok && (release.AssetURL == "" || release.Archive != "" && archive == ""):
+			release.AssetURL = asset.URL
+			release.AssetName = asset.Name
+			release.Archive = archive
+		case asset.Name == binaryName:
+			fallbackURL = asset.URL
+		case asset.Name == checksumsAsset:
+			release.ChecksumsURL = asset.URL
+		case asset.Name == signatureAsset:
+			release.SignatureURL = asset.URL
 		}
 	}
-
-	if assetURL == "" {
-		return nil, false, fmt.Errorf("binary 'docu-jarvis' not found in release assets")
+	if release.AssetURL == "" && fallbackURL != "" {
+		release.AssetURL = fallbackURL
+		release.AssetName = binaryName
 	}
 
-	release := &Release{
-		Version:      ghRelease.TagName,
-		AssetURL:     assetURL,
-		AssetName:    assetName,
-		ReleaseNotes: ghRelease.Body,
+	if release.AssetURL == "" {
+		return nil, false, fmt.Errorf("no docu-jarvis binary for %s/%s found in release assets", runtime.GOOS, runtime.GOARCH)
 	}
 
 	return release, true, nil
@@ -124,6 +132,11 @@ func CheckForUpdates(currentVersion string) (*Release, bool, error) {
 }
 
 func UpdateToLatest(currentVersion string) error {
+	// Package managers own their files; replacing the binary would confuse them
+	if method := DetectInstallMethod(); method != InstallManual {
+		return fmt.Errorf("docu-jarvis was installed with %s, run '%s' instead", method, UpgradeCommand(method))
+	}
+
 	s, err := settings.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load settings: %w", err)
@@ -150,7 +163,19 @@ func UpdateToLatest(currentVersion string) error {
 		return fmt.Errorf("could not locate executable path: %w", err)
 	}
 
-	if err := downloadAndReplace(context.Background(), latest.AssetURL, exe, s.GetGitHubToken()); err != nil {
+	lock, err := acquireUpdateLock(exe, 2*time.Minute)
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+
+	// Another process may have finished the same update while we waited
+	if BinaryReplaced() {
+		fmt.Println("docu-jarvis was already updated by another process")
+		return nil
+	}
+
+	if err := downloadAndReplace(context.Background(), latest, exe, s.GetGitHubToken()); err != nil {
 		return fmt.Errorf("error updating binary: %w", err)
 	}
 
@@ -158,8 +183,26 @@ func UpdateToLatest(currentVersion string) error {
 	return nil
 }
 
-func downloadAndReplace(ctx context.Context, url, targetPath, token string) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// oldBinarySuffix names the binary replaced by an update on Windows.
+const oldBinarySuffix = ".old"
+
+// goos is the operating system binaries are replaced for; tests set it to
+// exercise the Windows flow elsewhere.
+var goos = runtime.GOOS
+
+// downloadAndReplace downloads the release's binary and replaces targetPath
+// with it, unless its SHA-256 does not match the release's checksums.
+func downloadAndReplace(ctx context.Context, release *Release, targetPath, token string) error {
+	if err := netguard.Check("downloading the update"); err != nil {
+		return err
+	}
+
+	expected, err := expectedChecksum(ctx, release, token)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", release.AssetURL, nil)
 	if err != nil {
 		return err
 	}
@@ -180,18 +223,25 @@ func downloadAndReplace(ctx context.Context, url, targetPath, token string) erro
 		return fmt.Errorf("download failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
-	tmpFile := targetPath + ".tmp"
-	out, err := os.Create(tmpFile)
+	// A unique temp file next to the target, so the rename stays atomic
+	out, err := os.CreateTemp(filepath.Dir(targetPath), filepath.Base(targetPath)+".tmp-*")
 	if err != nil {
 		return err
 	}
 	defer out.Close()
+	tmpFile := out.Name()
 
-	if _, err := io.Copy(out, resp.Body); err != nil {
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(out, hash), resp.Body); err != nil {
 		os.Remove(tmpFile)
 		return err
 	}
 
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != expected {
+		os.Remove(tmpFile)
+		return fmt.Errorf("checksum mismatch for %s (expected %s, got %s), refusing to install it", release.AssetName, expected, actual)
+	}
+
 	if err := out.Sync(); err != nil {
 		os.Remove(tmpFile)
 		return err
@@ -199,12 +249,21 @@ func downloadAndReplace(ctx context.Context, url, targetPath, token string) erro
 
 	out.Close()
 
+	if release.Archive != "" {
+		extracted, err := extractBinary(tmpFile, release.Archive, filepath.Dir(targetPath))
+		os.Remove(tmpFile)
+		if err != nil {
+			return err
+		}
+		tmpFile = extracted
+	}
+
 	if err := os.Chmod(tmpFile, 0755); err != nil {
 		os.Remove(tmpFile)
 		return err
 	}
 
-	if err := os.Rename(tmpFile, targetPath); err != nil {
+	if err := replaceBinary(tmpFile, targetPath); err != nil {
 		os.Remove(tmpFile)
 		return err
 	}
@@ -212,6 +271,43 @@ func downloadAndReplace(ctx context.Context, url, targetPath, token string) erro
 	return nil
 }
 
+// replaceBinary moves newPath over targetPath. Windows cannot replace a
+// running executable but can rename it, so there the old binary is moved
+// aside first and left for RemoveOldBinary.
+func replaceBinary(newPath, targetPath string) error {
+	if goos != "windows" {
+		return os.Rename(newPath, targetPath)
+	}
+
+	oldPath := targetPath + oldBinarySuffix
+	if err := os.Remove(oldPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s left by the last update: %w", oldPath, err)
+	}
+	if err := os.Rename(targetPath, oldPath); err != nil {
+		return fmt.Errorf("failed to move the running binary aside: %w", err)
+	}
+	if err := os.Rename(newPath, targetPath); err != nil {
+		os.Rename(oldPath, targetPath)
+		return err
+	}
+	return nil
+}
+
+// RemoveOldBinary deletes the binary that an update on Windows moved aside,
+// once the process that ran it has exited.
+func RemoveOldBinary() {
+	if goos != "windows" {
+		return
+	}
+	if exe, err := os.Executable(); err == nil {
+		removeOldBinary(exe)
+	}
+}
+
+func removeOldBinary(exe string) {
+	os.Remove(exe + oldBinarySuffix)
+}
+
 func AutoCheckForUpdates(currentVersion string, silent bool) {
 	latest, hasUpdate, err := CheckForUpdates(currentVersion)
 	if err != nil {
@@ -231,7 +327,47 @@ func AutoCheckForUpdates(currentVersion string, silent bool) {
 	if !silent {
 		fmt.Printf("\n OH YES! New version available: %s (current: %s)\n", latest.Version, currentVersion)
 		fmt.Printf("Release notes: %s\n", latest.ReleaseNotes)
-		fmt.Println("\nRun 'docu-jarvis -update' to upgrade")
+		fmt.Printf("\nRun '%s' to upgrade\n", UpgradeCommand(DetectInstallMethod()))
+	}
+}
+
+// BackgroundCheck is an update check running alongside a command.
+type BackgroundCheck struct {
+	done      chan struct{}
+	current   string
+	latest    *Release
+	hasUpdate bool
+}
+
+// StartBackgroundCheck checks for a newer release without blocking the
+// command and records the check time once it completes.
+func StartBackgroundCheck(currentVersion string) *BackgroundCheck {
+	c := &BackgroundCheck{done: make(chan struct{}), current: currentVersion}
+	go func() {
+		defer close(c.done)
+		latest, hasUpdate, err := CheckForUpdates(currentVersion)
+		if err != nil {
+			return
+		}
+		c.latest, c.hasUpdate = latest, hasUpdate
+		UpdateLastCheckTime()
+	}()
+	return c
+}
+
+// Wait gives the check up to timeout to finish, then prints the notice to w
+// when a new version is available. A check that is still running is dropped
+// and retried on the next run.
+func (c *BackgroundCheck) Wait(w io.Writer, timeout time.Duration) {
+	select {
+	case <-c.done:
+	case <-time.After(timeout):
+		return
+	}
+
+	if c.hasUpdate {
+		fmt.Fprintf(w, "\n OH YES! New version available: %s (current: %s)\n", c.latest.Version, c.current)
+		fmt.Fprintf(w, "Run '%s' to upgrade\n", UpgradeCommand(DetectInstallMethod()))
 	}
 }
 
@@ -240,6 +376,10 @@ func GetCurrentVersion() string {
 }
 
 func ShouldCheckForUpdates() bool {
+	if netguard.Disabled() {
+		return false
+	}
+
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return true
@@ -0,0 +1,145 @@
+package updater
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Names of the release assets that make updates verifiable. checksums.txt is
+// the `shasum -a 256` output for the binaries, signed with minisign.
+const (
+	checksumsAsset = "checksums.txt"
+	signatureAsset = checksumsAsset + ".minisig"
+)
+
+// signingKey is the minisign public key (the base64 line of the .pub file)
+// releases are signed with. When set, an update is only installed if
+// checksums.txt carries a valid signature by it. It is set at build time:
+//
+//	go build -ldflags "-X github.com/udemy/docu-jarvis-cli/internal/updater.signingKey=RWQ..."
+var signingKey = ""
+
+// minisign signature and key algorithms. Only legacy signatures (minisign -S
+// -l), which sign the file itself, can be checked with the standard library;
+// prehashed ones sign its BLAKE2b hash.
+const (
+	minisignLegacy    = "Ed"
+	minisignPrehashed = "ED"
+)
+
+// expectedChecksum downloads the release's checksums, verifies their
+// signature when a signing key is built in, and returns the SHA-256 listed
+// for the release's binary.
+func expectedChecksum(ctx context.Context, release *Release, token string) (string, error) {
+	if release.ChecksumsURL == "" {
+		return "", fmt.Errorf("release %s has no %s, refusing to install an unverified binary", release.Version, checksumsAsset)
+	}
+
+	checksums, err := fetchAsset(ctx, release.ChecksumsURL, token)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", checksumsAsset, err)
+	}
+
+	if signingKey != "" {
+		if release.SignatureURL == "" {
+			return "", fmt.Errorf("release %s has no %s, refusing to install an unsigned binary", release.Version, signatureAsset)
+		}
+		signature, err := fetchAsset(ctx, release.SignatureURL, token)
+		if err != nil {
+			return "", fmt.Errorf("failed to download %s: %w", signatureAsset, err)
+		}
+		if err := verifySignature(checksums, signature, signingKey); err != nil {
+			return "", fmt.Errorf("%s signature is invalid: %w", checksumsAsset, err)
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		// <hex digest>  <name>, with a * before binary-mode names
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != release.AssetName {
+			continue
+		}
+		if _, err := hex.DecodeString(fields[0]); err != nil || len(fields[0]) != sha256.Size*2 {
+			return "", fmt.Errorf("%s has a malformed checksum for %s", checksumsAsset, release.AssetName)
+		}
+		return strings.ToLower(fields[0]), nil
+	}
+	return "", fmt.Errorf("%s has no checksum for %s", checksumsAsset, release.AssetName)
+}
+
+// fetchAsset downloads a small release asset into memory.
+func fetchAsset(ctx context.Context, url, token string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/octet-stream")
+	if token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("download failed with status %d: %s", resp.StatusCode, string(body))
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// verifySignature checks a minisign signature of message: the signature
+// itself, and the global signature that binds its trusted comment.
+func verifySignature(message, signature []byte, publicKey string) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(key) != 2+8+ed25519.PublicKeySize || string(key[:2]) != minisignLegacy {
+		return fmt.Errorf("malformed signing key")
+	}
+	keyID, pub := key[2:10], ed25519.PublicKey(key[10:])
+
+	lines := strings.Split(strings.ReplaceAll(string(signature), "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return fmt.Errorf("malformed signature file")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(sig) != 2+8+ed25519.SignatureSize {
+		return fmt.Errorf("malformed signature")
+	}
+	switch string(sig[:2]) {
+	case minisignLegacy:
+	case minisignPrehashed:
+		return fmt.Errorf("prehashed signatures are not supported, sign releases with 'minisign -S -l'")
+	default:
+		return fmt.Errorf("unknown signature algorithm %q", sig[:2])
+	}
+	if !bytes.Equal(sig[2:10], keyID) {
+		return fmt.Errorf("signed with a different key")
+	}
+	if !ed25519.Verify(pub, message, sig[10:]) {
+		return fmt.Errorf("signature does not match")
+	}
+
+	globalSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return fmt.Errorf("malformed trusted comment signature")
+	}
+	trusted := strings.TrimPrefix(lines[2], "trusted comment: ")
+	if !ed25519.Verify(pub, append(append([]byte{}, sig[10:]...), trusted...), globalSig) {
+		return fmt.Errorf("trusted comment signature does not match")
+	}
+	return nil
+}
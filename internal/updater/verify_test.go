@@ -0,0 +1,223 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var (
+	testKeyID  = []byte("jarvis01")
+	testSecret = ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+)
+
+const testChecksums = "" +
+	"3f786850e387550fdab836ed7e6dc881de23001b3f786850e387550fdab836ed  docu-jarvis-darwin-arm64.tar.gz\n" +
+	"89e6c98d92887913cadf06b2adb97f26cde4849b89e6c98d92887913cadf06b2 *docu-jarvis-windows-amd64.exe\n"
+
+// testPublicKey is the minisign public key line of testSecret, under keyID.
+func testPublicKey(keyID []byte) string {
+	key := append([]byte(minisignLegacy), keyID...)
+	return base64.StdEncoding.EncodeToString(append(key, testSecret.Public().(ed25519.PublicKey)...))
+}
+
+// testSignature is a minisign signature file for message. tamper runs on the
+// signature parts before they are encoded.
+func testSignature(message []byte, tamper func(sig, globalSig []byte, trusted *string)) []byte {
+	trusted := "timestamp:1700000000\tfile:checksums.txt"
+	sig := append(append([]byte(minisignLegacy), testKeyID...), ed25519.Sign(testSecret, message)...)
+	globalSig := ed25519.Sign(testSecret, append(append([]byte{}, sig[10:]...), trusted...))
+	if tamper != nil {
+		tamper(sig, globalSig, &trusted)
+	}
+	return []byte("untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(sig) + "\n" +
+		"trusted comment: " + trusted + "\n" +
+		base64.StdEncoding.EncodeToString(globalSig) + "\n")
+}
+
+func TestVerifySignature(t *testing.T) {
+	message := []byte(testChecksums)
+
+	tests := []struct {
+		name      string
+		message   []byte
+		signature []byte
+		key       string
+		wantErr   string
+	}{
+		{
+			name:      "valid",
+			message:   message,
+			signature: testSignature(message, nil),
+			key:       testPublicKey(testKeyID),
+		},
+		{
+			name:      "CRLF signature file",
+			message:   message,
+			signature: bytes.ReplaceAll(testSignature(message, nil), []byte("\n"), []byte("\r\n")),
+			key:       testPublicKey(testKeyID),
+		},
+		{
+			name:      "tampered checksums",
+			message:   []byte(strings.Replace(testChecksums, "3f78", "0000", 1)),
+			signature: testSignature(message, nil),
+			key:       testPublicKey(testKeyID),
+			wantErr:   "signature does not match",
+		},
+		{
+			name:      "wrong key ID",
+			message:   message,
+			signature: testSignature(message, nil),
+			key:       testPublicKey([]byte("other-id")),
+			wantErr:   "signed with a different key",
+		},
+		{
+			name:    "prehashed signature",
+			message: message,
+			signature: testSignature(message, func(sig, _ []byte, _ *string) {
+				copy(sig, minisignPrehashed)
+			}),
+			key:     testPublicKey(testKeyID),
+			wantErr: "prehashed signatures are not supported",
+		},
+		{
+			name:    "edited trusted comment",
+			message: message,
+			signature: testSignature(message, func(_, _ []byte, trusted *string) {
+				*trusted = "timestamp:1700000000\tfile:checksums.txt\tverified"
+			}),
+			key:     testPublicKey(testKeyID),
+			wantErr: "trusted comment signature does not match",
+		},
+		{
+			name:    "bad trusted comment signature",
+			message: message,
+			signature: testSignature(message, func(_, globalSig []byte, _ *string) {
+				globalSig[0] ^= 0xff
+			}),
+			key:     testPublicKey(testKeyID),
+			wantErr: "trusted comment signature does not match",
+		},
+		{
+			name:      "malformed key",
+			message:   message,
+			signature: testSignature(message, nil),
+			key:       "RWQnotakey",
+			wantErr:   "malformed signing key",
+		},
+		{
+			name:      "malformed signature file",
+			message:   message,
+			signature: []byte("untrusted comment: truncated\n"),
+			key:       testPublicKey(testKeyID),
+			wantErr:   "malformed signature file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifySignature(tt.message, tt.signature, tt.key)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifySignature() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("verifySignature() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExpectedChecksum(t *testing.T) {
+	assets := map[string][]byte{
+		"/" + checksumsAsset: []byte(testChecksums),
+		"/" + signatureAsset: testSignature([]byte(testChecksums), nil),
+		"/tampered.txt":      []byte(strings.Replace(testChecksums, "3f78", "0000", 1)),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := assets[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	saved := signingKey
+	signingKey = testPublicKey(testKeyID)
+	t.Cleanup(func() { signingKey = saved })
+
+	release := func(asset, checksums string) *Release {
+		return &Release{
+			Version:      "v1.4.0",
+			AssetName:    asset,
+			ChecksumsURL: server.URL + "/" + checksums,
+			SignatureURL: server.URL + "/" + signatureAsset,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		release *Release
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "listed asset",
+			release: release("docu-jarvis-darwin-arm64.tar.gz", checksumsAsset),
+			want:    "3f786850e387550fdab836ed7e6dc881de23001b3f786850e387550fdab836ed",
+		},
+		{
+			name:    "binary-mode name",
+			release: release("docu-jarvis-windows-amd64.exe", checksumsAsset),
+			want:    "89e6c98d92887913cadf06b2adb97f26cde4849b89e6c98d92887913cadf06b2",
+		},
+		{
+			name:    "asset not listed",
+			release: release("docu-jarvis-linux-amd64.tar.gz", checksumsAsset),
+			wantErr: "has no checksum for docu-jarvis-linux-amd64.tar.gz",
+		},
+		{
+			name:    "tampered checksums",
+			release: release("docu-jarvis-darwin-arm64.tar.gz", "tampered.txt"),
+			wantErr: "signature is invalid: signature does not match",
+		},
+		{
+			name:    "no signature",
+			release: &Release{Version: "v1.4.0", AssetName: "docu-jarvis-darwin-arm64.tar.gz", ChecksumsURL: server.URL + "/" + checksumsAsset},
+			wantErr: "refusing to install an unsigned binary",
+		},
+		{
+			name:    "no checksums",
+			release: &Release{Version: "v1.4.0", AssetName: "docu-jarvis-darwin-arm64.tar.gz"},
+			wantErr: "refusing to install an unverified binary",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expectedChecksum(context.Background(), tt.release, "")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expectedChecksum() error = %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("expectedChecksum() = %s, want %s", got, tt.want)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expectedChecksum() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
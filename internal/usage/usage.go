@@ -0,0 +1,243 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// price is the list price in USD per million tokens.
+type price struct {
+	input  float64
+	output float64
+}
+
+// modelPrices is matched against the model name by family. It is only used
+// when Claude Code does not report the cost of a request itself.
+var modelPrices = []struct {
+	family string
+	price  price
+}{
+	{"opus", price{input: 15, output: 75}},
+	{"sonnet", price{input: 3, output: 15}},
+	{"haiku", price{input: 0.80, output: 4}},
+}
+
+// defaultPrice is used for models that match no family.
+var defaultPrice = price{input: 3, output: 15}
+
+type ModelUsage struct {
+	Requests     int     `json:"requests"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+	// Estimated is true when any request's cost came from modelPrices
+	// rather than being reported by Claude Code.
+	Estimated bool `json:"estimated"`
+}
+
+// Run is one line of ~/.docu-jarvis/usage.jsonl.
+type Run struct {
+	Time       time.Time              `json:"time"`
+	Command    string                 `json:"command"`
+	DurationMs int64                  `json:"duration_ms"`
+	Models     map[string]*ModelUsage `json:"models"`
+}
+
+// Total sums the usage of every model in the run.
+func (r *Run) Total() ModelUsage {
+	var total ModelUsage
+	for _, m := range r.Models {
+		total.Add(*m)
+	}
+	return total
+}
+
+// Add adds other's usage to m.
+func (m *ModelUsage) Add(other ModelUsage) {
+	m.Requests += other.Requests
+	m.InputTokens += other.InputTokens
+	m.OutputTokens += other.OutputTokens
+	m.CostUSD += other.CostUSD
+	m.Estimated = m.Estimated || other.Estimated
+}
+
+var (
+	mu      sync.Mutex
+	started = time.Now()
+	models  = make(map[string]*ModelUsage)
+)
+
+// Record adds one Claude request to the current run. It is safe to call from
+// concurrent agents. cost is the cost reported by Claude Code, if any.
+func Record(model string, inputTokens, outputTokens int, cost *float64) {
+	if model == "" {
+		model = "unknown"
+	}
+
+	request := ModelUsage{
+		Requests:     1,
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+	}
+	if cost != nil {
+		request.CostUSD = *cost
+	} else {
+		p := priceFor(model)
+		request.CostUSD = (float64(inputTokens)*p.input + float64(outputTokens)*p.output) / 1e6
+		request.Estimated = true
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if models[model] == nil {
+		models[model] = &ModelUsage{}
+	}
+	models[model].Add(request)
+}
+
+func priceFor(model string) price {
+	model = strings.ToLower(model)
+	for _, mp := range modelPrices {
+		if strings.Contains(model, mp.family) {
+			return mp.price
+		}
+	}
+	return defaultPrice
+}
+
+// Finish prints a summary of the run to w and appends it to the usage
+// history. Runs that made no Claude requests are skipped.
+func Finish(w io.Writer, command string) error {
+	mu.Lock()
+	run := &Run{
+		Time:       time.Now(),
+		Command:    command,
+		DurationMs: time.Since(started).Milliseconds(),
+		Models:     models,
+	}
+	mu.Unlock()
+
+	if len(run.Models) == 0 {
+		return nil
+	}
+
+	total := run.Total()
+	fmt.Fprintln(w, "\n"+strings.Repeat("-", 70))
+	fmt.Fprintf(w, "Claude usage: %d requests, %s input / %s output tokens, %s\n",
+		total.Requests, formatTokens(total.InputTokens), formatTokens(total.OutputTokens), FormatCost(total))
+	if len(run.Models) > 1 {
+		for _, name := range sortedModels(run.Models) {
+			m := run.Models[name]
+			fmt.Fprintf(w, "  %-32s %3d requests  %s\n", name, m.Requests, FormatCost(*m))
+		}
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+
+	return appendHistory(run)
+}
+
+// FormatCost formats the cost in dollars, marked when it is an estimate.
+func FormatCost(m ModelUsage) string {
+	if m.Estimated {
+		return fmt.Sprintf("~$%.4f (estimated)", m.CostUSD)
+	}
+	return fmt.Sprintf("$%.4f", m.CostUSD)
+}
+
+func formatTokens(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1e3)
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+func sortedModels(m map[string]*ModelUsage) []string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func historyPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".docu-jarvis", "usage.jsonl"), nil
+}
+
+func appendHistory(run *Run) error {
+	path, err := historyPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create usage directory: %w", err)
+	}
+
+	line, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open usage history: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write usage history: %w", err)
+	}
+	return nil
+}
+
+// LoadHistory returns the recorded runs since the given time, oldest first.
+// Lines that cannot be parsed are skipped.
+func LoadHistory(since time.Time) ([]Run, error) {
+	path, err := historyPath()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage history: %w", err)
+	}
+	defer f.Close()
+
+	var runs []Run
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var run Run
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			continue
+		}
+		if !run.Time.Before(since) {
+			runs = append(runs, run)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage history: %w", err)
+	}
+
+	return runs, nil
+}
@@ -0,0 +1,204 @@
+// Package debug finds the commits likely to have caused a bug, as
+// `docu-jarvis debug` does, for programs that want the analyses themselves
+// rather than the command's output:
+//
+//	commits, err := debug.CommitsBetween(ctx, "/srv/checkouts/billing", since, time.Now())
+//	...
+//	result, err := debug.Analyze(ctx, "/srv/checkouts/billing", commits, "Invoices are charged twice", debug.Options{
+//		Concurrency: 4,
+//		Progress:    func(p debug.Progress) { log.Printf("%d/%d commits", p.Completed, p.Total) },
+//	})
+//
+// It uses the same Claude provider and mode settings as the command, read
+// from the settings of the user running it, and prints nothing.
+package debug
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/udemy/docu-jarvis-cli/internal/agent"
+	"github.com/udemy/docu-jarvis-cli/internal/settings"
+	"github.com/udemy/docu-jarvis-cli/internal/system_prompts"
+)
+
+// Commit is a commit to analyze.
+type Commit struct {
+	Hash    string
+	Author  string
+	Date    string // as git prints it, e.g. 2024-06-01 09:30:00 +0200
+	Subject string
+}
+
+// Analysis is Claude's verdict on one commit.
+type Analysis struct {
+	Commit      Commit
+	IsLikely    bool // whether the commit likely caused the bug
+	Confidence  int  // 0-100
+	Explanation string
+}
+
+// Failure is a commit that could not be analyzed.
+type Failure struct {
+	Commit Commit
+	Err    error
+}
+
+// Result holds the analysis of every commit, likely culprits first, then by
+// confidence.
+type Result struct {
+	Analyses []Analysis
+	Failed   []Failure
+	// Skipped counts the commits left unanalyzed because one reached
+	// Options.StopAt.
+	Skipped int
+}
+
+// Best returns the top-ranked analysis. Analyze only returns results with
+// at least one.
+func (r *Result) Best() Analysis {
+	return r.Analyses[0]
+}
+
+// Likely returns the analyses of the commits that likely caused the bug.
+func (r *Result) Likely() []Analysis {
+	var likely []Analysis
+	for _, analysis := range r.Analyses {
+		if analysis.IsLikely {
+			likely = append(likely, analysis)
+		}
+	}
+	return likely
+}
+
+// Progress reports one commit's analysis as it completes, in completion
+// order. Exactly one of Analysis and Err is set.
+type Progress struct {
+	Completed int
+	Total     int
+	Commit    Commit
+	Analysis  *Analysis
+	Err       error
+}
+
+// Options configure Analyze. The zero value analyzes every commit at once
+// with the configured model.
+type Options struct {
+	Concurrency int    // commits analyzed at once, 0 for all of them
+	StopAt      int    // stop once a likely culprit has this confidence, 0 to analyze every commit
+	Model       string // overrides the configured debug model, when set
+	// Progress is called after each commit, from the goroutine that called
+	// Analyze.
+	Progress func(Progress)
+}
+
+// Analyze asks Claude whether each commit caused the bug, reading the code of
+// the checkout in folder. It fails when ctx is done, or when no commit could
+// be analyzed.
+func Analyze(ctx context.Context, folder string, commits []Commit, bugDescription string, opts Options) (*Result, error) {
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("no commits to analyze")
+	}
+
+	s, err := settings.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+	if _, err := agent.Configure(s); err != nil {
+		return nil, fmt.Errorf("failed to configure the Claude provider: %w", err)
+	}
+
+	ag, err := agent.New(system_prompts.DebugAnalysis, folder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create agent: %w", err)
+	}
+	ag.SetModel(opts.Model)
+
+	byLine := make(map[string]Commit, len(commits))
+	lines := make([]string, len(commits))
+	for i, c := range commits {
+		lines[i] = strings.Join([]string{c.Hash, c.Author, c.Date, c.Subject}, "|")
+		byLine[lines[i]] = c
+	}
+
+	result := &Result{}
+	completed := 0
+	ag.SetSuspectOptions(agent.SuspectOptions{
+		Concurrency: opts.Concurrency,
+		StopAt:      opts.StopAt,
+		Progress: func(done, total int, r agent.CommitAnalysisResult) {
+			completed = done
+			p := Progress{Completed: done, Total: total, Commit: byLine[r.Commit], Err: r.Error}
+			if r.Error == nil && r.Analysis == nil {
+				p.Err = fmt.Errorf("no analysis returned")
+			}
+			if p.Err != nil {
+				result.Failed = append(result.Failed, Failure{Commit: p.Commit, Err: p.Err})
+			} else {
+				analysis := toAnalysis(p.Commit, r.Analysis)
+				p.Analysis = &analysis
+			}
+			if opts.Progress != nil {
+				opts.Progress(p)
+			}
+		},
+	})
+
+	suspects, err := ag.AnalyzeBugSuspects(ctx, lines, bugDescription)
+	if err != nil {
+		return nil, err
+	}
+	for _, suspect := range suspects {
+		result.Analyses = append(result.Analyses, toAnalysis(commitOf(commits, suspect.CommitHash), suspect))
+	}
+	result.Skipped = len(commits) - completed
+	return result, nil
+}
+
+// toAnalysis converts an analysis of the agent, keeping the commit's git
+// metadata over what Claude reported.
+func toAnalysis(c Commit, a *agent.CommitAnalysis) Analysis {
+	if c.Hash == "" {
+		c = Commit{Hash: a.CommitHash, Author: a.Author, Date: a.Date, Subject: a.CommitMsg}
+	}
+	return Analysis{Commit: c, IsLikely: a.IsLikely, Confidence: a.Confidence, Explanation: a.Explanation}
+}
+
+// commitOf returns the commit with hash, which Claude may have abbreviated,
+// or the zero Commit.
+func commitOf(commits []Commit, hash string) Commit {
+	if hash == "" {
+		return Commit{}
+	}
+	for _, c := range commits {
+		if strings.HasPrefix(c.Hash, hash) {
+			return c
+		}
+	}
+	return Commit{}
+}
+
+// CommitsBetween returns the commits of the checkout in folder made between
+// from and to on its current branch, newest first.
+func CommitsBetween(ctx context.Context, folder string, from, to time.Time) ([]Commit, error) {
+	cmd := exec.CommandContext(ctx, "git", "log", "--pretty=format:%H|%an|%ai|%s",
+		"--since="+from.Format(time.RFC3339), "--until="+to.Format(time.RFC3339))
+	cmd.Dir = folder
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git log: %w", err)
+	}
+
+	var commits []Commit
+	for _, line := range strings.Split(string(output), "\n") {
+		parts := strings.SplitN(line, "|", 4)
+		if len(parts) < 4 {
+			continue
+		}
+		commits = append(commits, Commit{Hash: parts[0], Author: parts[1], Date: parts[2], Subject: parts[3]})
+	}
+	return commits, nil
+}